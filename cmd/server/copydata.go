@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+)
+
+// copyDataUsage describes the copy-data subcommand
+const copyDataUsage = `usage: DATABASE_URL=postgres://... server copy-data [sqlite-path]
+
+Copies every card, statement and scheduler record from the SQLite database
+(default DATABASE_PATH or ./credit_cards.db) into the empty Postgres database
+at DATABASE_URL. Both databases are migrated to the latest schema first.`
+
+// runCopyData implements the "copy-data" subcommand, moving the SQLite
+// database at args[0] into the Postgres database at url
+func runCopyData(args []string, url string, out io.Writer) error {
+	if url == "" {
+		return fmt.Errorf("DATABASE_URL must be set\n\n%s", copyDataUsage)
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("%s", copyDataUsage)
+	}
+
+	srcPath := databasePath()
+	if len(args) == 1 {
+		srcPath = args[0]
+	}
+
+	src, err := database.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := database.OpenPostgres(url)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	return copyData(src, dst, srcPath, out)
+}
+
+// copyData migrates both databases and copies src into dst
+func copyData(src, dst *sql.DB, srcName string, out io.Writer) error {
+	for _, db := range []*sql.DB{src, dst} {
+		if _, err := database.MigrateUp(db, 0, clock.Real{}); err != nil {
+			return err
+		}
+	}
+
+	results, err := database.CopyData(context.Background(), src, dst)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Copied %s:\n", srcName)
+	for _, r := range results {
+		fmt.Fprintf(out, "  %-20s %d row(s)\n", r.Table, r.Rows)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/followup"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/notify"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ofx"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ynab"
+)
+
+// importOFXUsage describes the import-ofx subcommand
+const importOFXUsage = `usage: server import-ofx [--dry-run] <file>...
+
+Creates or updates statements from OFX or QFX files, matching each account
+to the card with the same last four digits. With --dry-run nothing is stored
+and the changes are only listed. Statements entered or updated are followed
+up the same way as uploads through the API: autopay payments are scheduled, statements
+are announced on Discord and scheduled payments are synced to YNAB.`
+
+// runImportOFX implements the "import-ofx" subcommand against db, following
+// up on the statements entered with the integrations in cfg and the holidays
+// of cal
+func runImportOFX(args []string, db *sql.DB, cfg *config.Config, clk clock.Clock, cal *holidays.Calendar, out io.Writer) error {
+	dryRun := false
+	var paths []string
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run", "-n":
+			dryRun = true
+		default:
+			if len(arg) > 1 && arg[0] == '-' {
+				return fmt.Errorf("unknown flag %q\n\n%s", arg, importOFXUsage)
+			}
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("%s", importOFXUsage)
+	}
+
+	var list []importer.Statement
+	for _, path := range paths {
+		statements, err := parseOFXFile(path)
+		if err != nil {
+			return err
+		}
+		list = append(list, statements...)
+	}
+
+	ctx := context.Background()
+	cards := repository.NewCardRepository(db)
+	statements := repository.NewStatementRepository(db)
+	notifier := notify.NewNotifier(db, notify.NewDiscordClient(cfg.DiscordWebhookURL), clk, notify.WithCalendar(cal))
+	syncer := ynab.NewSyncer(db, ynab.NewClient(cfg.YNAB.Token), cfg.YNAB, clk)
+	followups := followup.New(statements, notifier, syncer, clk)
+
+	results, err := importer.New(cards, statements, clk).Import(ctx, list, dryRun)
+	for _, result := range results {
+		followups.Imported(ctx, result)
+	}
+	// Let the announcements and YNAB syncs finish before exiting
+	followups.Wait()
+
+	printImportResults(out, results, dryRun)
+	return err
+}
+
+// parseOFXFile reads the statements of one file
+func parseOFXFile(path string) ([]importer.Statement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parsed, err := ofx.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var list []importer.Statement
+	for _, stmt := range parsed {
+		list = append(list, importer.Statement{
+			LastFour:       stmt.LastFour,
+			StatementDate:  stmt.StatementDate,
+			DueDate:        stmt.DueDate,
+			Amount:         stmt.ClosingBalance,
+			MinimumPayment: stmt.MinimumPayment,
+		})
+	}
+	return list, nil
+}
+
+// printImportResults lists one line per imported statement
+func printImportResults(out io.Writer, results []importer.Result, dryRun bool) {
+	if dryRun {
+		fmt.Fprintln(out, "Dry run: nothing was stored")
+	}
+	for _, r := range results {
+		card := r.CardName
+		if card == "" {
+			card = "*" + r.LastFour
+		}
+		detail := r.Reason
+		if detail == "" {
+			detail = "due " + r.DueDate
+		}
+		fmt.Fprintf(out, "  %-10s %-24s %s %12s  %s\n", r.Action, card, r.StatementDate, r.Amount.Display(), detail)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+)
+
+const testQFX = `<?xml version="1.0"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTENDTRNRS><CCSTMTENDRS>
+<CCACCTFROM><ACCTID>5555444433331234</ACCTID></CCACCTFROM>
+<CCCLOSING><DTCLOSE>20241110</DTCLOSE><DTPMTDUE>20241205</DTPMTDUE><BALCLOSE>-99.99</BALCLOSE></CCCLOSING>
+</CCSTMTENDRS></CCSTMTENDTRNRS></CREDITCARDMSGSRSV1></OFX>
+`
+
+func TestRunImportOFX(t *testing.T) {
+	tmpDB := "./test_import_cmd.db"
+	qfxPath := "./test_import_cmd.qfx"
+	defer os.Remove(tmpDB)
+	defer os.Remove(qfxPath)
+
+	if err := os.WriteFile(qfxPath, []byte(testQFX), 0o644); err != nil {
+		t.Fatalf("Failed to write QFX file: %v", err)
+	}
+
+	db, err := database.Open(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := database.MigrateUp(db, 0, clock.Real{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, autopay)
+		VALUES ('Visa', '1234', 10, 25, 'statement_balance')
+	`); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	cfg := &config.Config{}
+	clk := clock.NewFixed(time.Date(2024, 11, 12, 9, 0, 0, 0, time.UTC))
+
+	var out bytes.Buffer
+	if err := runImportOFX([]string{"--dry-run", qfxPath}, db, cfg, clk, holidays.NewCalendar(holidays.DefaultRegions, nil), &out); err != nil {
+		t.Fatalf("import-ofx --dry-run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Dry run") || !strings.Contains(out.String(), "create") {
+		t.Errorf("Unexpected dry run output:\n%s", out.String())
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM statements").Scan(&count)
+	if count != 0 {
+		t.Fatalf("Expected the dry run to store nothing, got %d statements", count)
+	}
+
+	out.Reset()
+	if err := runImportOFX([]string{qfxPath}, db, cfg, clk, holidays.NewCalendar(holidays.DefaultRegions, nil), &out); err != nil {
+		t.Fatalf("import-ofx failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Visa") || !strings.Contains(out.String(), "$99.99") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+	db.QueryRow("SELECT COUNT(*) FROM statements").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 statement, got %d", count)
+	}
+
+	// The card pays itself, so its payment is scheduled on the due date
+	var scheduled string
+	db.QueryRow("SELECT COALESCE(scheduled_payment_date, '') FROM statements").Scan(&scheduled)
+	if scheduled != "2024-12-05" {
+		t.Errorf("Expected autopay to be scheduled on 2024-12-05, got %q", scheduled)
+	}
+}
+
+func TestRunImportOFX_InvalidArguments(t *testing.T) {
+	var out bytes.Buffer
+	for _, args := range [][]string{nil, {"--force", "a.ofx"}, {"./does-not-exist.ofx"}} {
+		if err := runImportOFX(args, nil, nil, nil, nil, &out); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
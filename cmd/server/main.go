@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -10,22 +12,57 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
 	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
 	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
 	"github.com/morey-tech/credit-card-payment-tracker/pkg/handlers"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ingest"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/notify"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/scheduler"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ynab"
 )
 
 func main() {
-	// Load application configuration
-	cfg, err := config.LoadConfig("")
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+	// Run a subcommand instead of the server if one was given
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			db, name, err := openDatabase()
+			if err != nil {
+				log.Fatalf("Failed to open database: %v", err)
+			}
+			defer db.Close()
+			if err := runMigrate(os.Args[2:], db, name, os.Stdout); err != nil {
+				log.Fatalf("Migrate failed: %v", err)
+			}
+			return
+		case "import-ofx":
+			db, cfg, clk, cal, err := setup()
+			if err != nil {
+				log.Fatalf("Import failed: %v", err)
+			}
+			defer db.Close()
+			if err := runImportOFX(os.Args[2:], db, cfg, clk, cal, os.Stdout); err != nil {
+				log.Fatalf("Import failed: %v", err)
+			}
+			return
+		case "copy-data":
+			if err := runCopyData(os.Args[2:], os.Getenv("DATABASE_URL"), os.Stdout); err != nil {
+				log.Fatalf("Copy failed: %v", err)
+			}
+			return
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
 	}
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Invalid configuration: %v", err)
+	db, cfg, clk, cal, err := setup()
+	if err != nil {
+		log.Fatalf("Failed to start: %v", err)
 	}
+	defer db.Close()
 
 	log.Printf("Configuration loaded successfully")
 	if cfg.DiscordWebhookURL != "" {
@@ -33,6 +70,12 @@ func main() {
 	} else {
 		log.Printf("Discord webhook not configured (notifications disabled)")
 	}
+	if cfg.YNAB.Enabled() {
+		log.Printf("YNAB budget configured (%d cards mapped)", len(cfg.YNAB.Cards))
+	}
+	if cfg.IMAP.Enabled() {
+		log.Printf("Statement mailbox configured at %s", cfg.IMAP.Address)
+	}
 
 	// Get configuration from environment variables
 	port := os.Getenv("PORT")
@@ -40,64 +83,191 @@ func main() {
 		port = "8080"
 	}
 
-	dbPath := os.Getenv("DATABASE_PATH")
-	if dbPath == "" {
-		dbPath = "./credit_cards.db"
-	}
+	cards := repository.NewCardRepository(db)
+	statements := repository.NewStatementRepository(db)
 
-	// Initialize database
-	if err := database.InitDB(dbPath); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
-	}
-	defer database.Close()
+	// Set up Discord notifications
+	notifier := notify.NewNotifier(db, notify.NewDiscordClient(cfg.DiscordWebhookURL), clk, notify.WithCalendar(cal))
+
+	// Set up YNAB sync of scheduled payments
+	ynabSyncer := ynab.NewSyncer(db, ynab.NewClient(cfg.YNAB.Token), cfg.YNAB, clk)
+
+	// Set up reading statement emails, uploaded or from the mailbox
+	ingester := ingest.New(cards, statements, repository.NewSuggestionRepository(db), clk)
+	mailbox := ingest.NewMailbox(db, cfg.IMAP, ingester)
+
+	h := handlers.New(cards, statements, notifier, clk,
+		handlers.WithCalendar(cal),
+		handlers.WithHolidays(repository.NewHolidayRepository(db)),
+		handlers.WithIncome(repository.NewIncomeRepository(db)),
+		handlers.WithFundingAccounts(repository.NewFundingAccountRepository(db)),
+		handlers.WithAttachments(repository.NewAttachmentRepository(db)),
+		handlers.WithIngest(ingester),
+		handlers.WithYNAB(ynabSyncer),
+	)
 
 	// Set up HTTP routes using ServeMux
 	mux := http.NewServeMux()
 
 	// API routes
-	mux.HandleFunc("/api/health", handlers.HealthCheck)
+	mux.HandleFunc("/api/health", h.HealthCheck)
 	mux.HandleFunc("/api/v1/cards", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			handlers.CreateCard(w, r)
+			h.CreateCard(w, r)
 		} else {
-			handlers.GetCards(w, r)
+			h.GetCards(w, r)
 		}
 	})
 	mux.HandleFunc("/api/v1/cards/", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			handlers.GetCardByID(w, r)
+			h.GetCardByID(w, r)
 		case http.MethodPut:
-			handlers.UpdateCard(w, r)
+			h.UpdateCard(w, r)
 		case http.MethodDelete:
-			handlers.DeleteCard(w, r)
+			h.DeleteCard(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 	mux.HandleFunc("/api/v1/statements", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			handlers.CreateStatement(w, r)
+			h.CreateStatement(w, r)
 		} else {
-			handlers.GetStatements(w, r)
+			h.GetStatements(w, r)
 		}
 	})
 	mux.HandleFunc("/api/v1/statements/", func(w http.ResponseWriter, r *http.Request) {
-		// Check if this is a schedule request
+		// Check for the schedule, history, payments, attachments and
+		// recommendation sub-resources
 		if len(r.URL.Path) > len("/api/v1/statements/") {
 			pathParts := strings.Split(r.URL.Path, "/")
 			if len(pathParts) >= 6 && pathParts[5] == "schedule" {
-				handlers.SchedulePayment(w, r)
+				h.SchedulePayment(w, r)
+				return
+			}
+			if len(pathParts) >= 6 && pathParts[5] == "recommendation" {
+				h.GetRecommendation(w, r)
+				return
+			}
+			if len(pathParts) >= 6 && pathParts[5] == "history" {
+				h.GetStatementHistory(w, r)
 				return
 			}
+			if len(pathParts) >= 6 && pathParts[5] == "attachments" {
+				h.GetStatementAttachments(w, r)
+				return
+			}
+			if len(pathParts) >= 6 && pathParts[5] == "payments" {
+				switch r.Method {
+				case http.MethodPost:
+					h.CreatePayment(w, r)
+				case http.MethodDelete:
+					h.DeletePayment(w, r)
+				default:
+					h.GetPayments(w, r)
+				}
+				return
+			}
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.GetStatementByID(w, r)
+		case http.MethodPatch:
+			h.PatchStatement(w, r)
+		case http.MethodDelete:
+			h.DeleteStatement(w, r)
+		default:
+			h.UpdateStatement(w, r)
+		}
+	})
+	mux.HandleFunc("/api/v1/dashboard", h.GetDashboard)
+	mux.HandleFunc("/api/v1/holidays", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h.UploadHolidays(w, r)
+		case http.MethodDelete:
+			h.DeleteHolidays(w, r)
+		default:
+			h.GetHolidays(w, r)
+		}
+	})
+	mux.HandleFunc("/api/v1/income", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			h.CreateIncomeSchedule(w, r)
+		} else {
+			h.GetIncomeSchedules(w, r)
+		}
+	})
+	mux.HandleFunc("/api/v1/income/", h.DeleteIncomeSchedule)
+	mux.HandleFunc("/api/v1/plan", h.GetPlan)
+	mux.HandleFunc("/api/v1/import/ofx", h.ImportOFX)
+	mux.HandleFunc("/api/v1/import/csv", h.ImportCSV)
+	mux.HandleFunc("/api/v1/import/eml", h.ImportEmail)
+	mux.HandleFunc("/api/v1/export/csv", h.ExportCSV)
+	mux.HandleFunc("/api/v1/attachments", h.UploadAttachment)
+	mux.HandleFunc("/api/v1/attachments/", func(w http.ResponseWriter, r *http.Request) {
+		// Check for the proposal and confirm actions
+		pathParts := strings.Split(r.URL.Path, "/")
+		if len(pathParts) >= 6 && pathParts[5] == "proposal" {
+			h.GetAttachmentProposal(w, r)
+			return
+		}
+		if len(pathParts) >= 6 && pathParts[5] == "confirm" {
+			h.ConfirmAttachment(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.GetAttachment(w, r)
+		case http.MethodDelete:
+			h.DeleteAttachment(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/v1/suggestions", h.GetSuggestions)
+	mux.HandleFunc("/api/v1/suggestions/", func(w http.ResponseWriter, r *http.Request) {
+		// Check for the accept and dismiss actions
+		pathParts := strings.Split(r.URL.Path, "/")
+		if len(pathParts) >= 6 && pathParts[5] == "accept" {
+			h.AcceptSuggestion(w, r)
+			return
+		}
+		if len(pathParts) >= 6 && pathParts[5] == "dismiss" {
+			h.DismissSuggestion(w, r)
+			return
+		}
+		h.GetSuggestion(w, r)
+	})
+	mux.HandleFunc("/api/v1/funding-accounts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			h.CreateFundingAccount(w, r)
+		} else {
+			h.GetFundingAccounts(w, r)
+		}
+	})
+	mux.HandleFunc("/api/v1/funding-accounts/", func(w http.ResponseWriter, r *http.Request) {
+		// Check for the outflows sub-resource
+		pathParts := strings.Split(r.URL.Path, "/")
+		if len(pathParts) >= 6 && pathParts[5] == "outflows" {
+			h.GetFundingAccountOutflows(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateFundingAccount(w, r)
+		case http.MethodDelete:
+			h.DeleteFundingAccount(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-		handlers.UpdateStatement(w, r)
 	})
 	mux.HandleFunc("/api/settings", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPut {
-			handlers.UpdateSettings(w, r)
+			h.UpdateSettings(w, r)
 		} else {
-			handlers.GetSettings(w, r)
+			h.GetSettings(w, r)
 		}
 	})
 
@@ -131,6 +301,18 @@ func main() {
 		}
 	}()
 
+	// Start background scheduler for statement and payment reminders
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.New(db, notifier, clk,
+			scheduler.WithCalendar(cal),
+			scheduler.WithYNAB(ynabSyncer),
+			scheduler.WithMailbox(mailbox),
+		).Run(schedulerCtx)
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -142,6 +324,14 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Stop the scheduler and wait for any in-flight run to finish
+	stopScheduler()
+	select {
+	case <-schedulerDone:
+	case <-ctx.Done():
+		log.Println("Scheduler did not stop before shutdown timeout")
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
@@ -149,11 +339,77 @@ func main() {
 	log.Println("Server stopped")
 }
 
+// setup loads and validates the configuration, sets up the clock and the
+// calendar of the holiday regions from the environment and opens the
+// database, checking its schema version and applying pending migrations
+// dated by the clock. The server and the
+// subcommands that act like it share it.
+func setup() (*sql.DB, *config.Config, clock.Clock, *holidays.Calendar, error) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Set up the clock, optionally frozen at FREEZE_TIME for reproducing
+	// date-dependent behaviour
+	clk, err := clock.FromEnv()
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid clock configuration: %w", err)
+	}
+	if fixed, ok := clk.(*clock.Fixed); ok {
+		log.Printf("Clock frozen at %s", fixed.Now().Format(time.RFC3339))
+	}
+
+	// Set the regions whose federal holidays aren't business days
+	regions, err := holidays.RegionsFromEnv()
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid holiday configuration: %w", err)
+	}
+	cal := holidays.NewCalendar(regions, nil)
+	log.Printf("Holiday regions: %v", regions)
+
+	// Initialize database: Postgres when DATABASE_URL is set, SQLite otherwise
+	var db *sql.DB
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		if db, err = database.InitPostgres(url, clk); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("failed to initialize database: %w", err)
+		}
+		log.Printf("Using Postgres database")
+	} else if db, err = database.InitDB(databasePath(), clk); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return db, cfg, clk, cal, nil
+}
+
+// databasePath returns the SQLite database path from DATABASE_PATH
+func databasePath() string {
+	if dbPath := os.Getenv("DATABASE_PATH"); dbPath != "" {
+		return dbPath
+	}
+	return "./credit_cards.db"
+}
+
+// openDatabase opens the Postgres database at DATABASE_URL if it is set and
+// the SQLite database at DATABASE_PATH otherwise. The returned name describes
+// the database for log output without exposing credentials.
+func openDatabase() (*sql.DB, string, error) {
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		db, err := database.OpenPostgres(url)
+		return db, "Postgres (DATABASE_URL)", err
+	}
+	dbPath := databasePath()
+	db, err := database.Open(dbPath)
+	return db, dbPath, err
+}
+
 // corsMiddleware adds CORS headers for local development
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == "OPTIONS" {
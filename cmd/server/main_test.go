@@ -31,8 +31,8 @@ func TestCorsMiddleware(t *testing.T) {
 			t.Errorf("Expected Access-Control-Allow-Origin '*', got '%s'", origin)
 		}
 
-		if methods := resp.Header.Get("Access-Control-Allow-Methods"); methods != "GET, POST, PUT, DELETE, OPTIONS" {
-			t.Errorf("Expected Access-Control-Allow-Methods 'GET, POST, PUT, DELETE, OPTIONS', got '%s'", methods)
+		if methods := resp.Header.Get("Access-Control-Allow-Methods"); methods != "GET, POST, PUT, PATCH, DELETE, OPTIONS" {
+			t.Errorf("Expected Access-Control-Allow-Methods 'GET, POST, PUT, PATCH, DELETE, OPTIONS', got '%s'", methods)
 		}
 
 		if headers := resp.Header.Get("Access-Control-Allow-Headers"); headers != "Content-Type, Authorization" {
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+)
+
+// migrateUsage describes the migrate subcommand
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  status          list migrations and whether they have been applied
+  up [version]    apply pending migrations, optionally stopping at version
+  down [steps]    revert the most recent migrations (default 1)`
+
+// runMigrate implements the "migrate" subcommand against db, which is
+// described by name in the status output
+func runMigrate(args []string, db *sql.DB, name string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	var err error
+
+	switch args[0] {
+	case "status":
+		statuses, err := database.Status(db)
+		if err != nil {
+			return err
+		}
+		version, err := database.SchemaVersion(db)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Database: %s\n", name)
+		fmt.Fprintf(out, "Schema version: %d (binary supports %d)\n\n", version, database.LatestVersion())
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%4d  %-40s %s\n", s.Version, s.Name, state)
+		}
+		if version > database.LatestVersion() {
+			fmt.Fprintf(out, "\nWARNING: %v\n", database.ErrSchemaTooNew)
+		}
+		return nil
+
+	case "up":
+		target := 0
+		if len(args) > 1 {
+			target, err = strconv.Atoi(args[1])
+			if err != nil || target < 1 {
+				return fmt.Errorf("invalid version %q", args[1])
+			}
+		}
+		count, err := database.MigrateUp(db, target, clock.Real{})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Applied %d migration(s)\n", count)
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		count, err := database.MigrateDown(db, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Reverted %d migration(s)\n", count)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], migrateUsage)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+)
+
+func TestRunMigrate(t *testing.T) {
+	tmpDB := "./test_migrate_cmd.db"
+	defer os.Remove(tmpDB)
+
+	db, err := database.Open(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var out bytes.Buffer
+
+	// Fresh database: everything pending
+	if err := runMigrate([]string{"status"}, db, tmpDB, &out); err != nil {
+		t.Fatalf("migrate status failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Schema version: 0") || !strings.Contains(out.String(), "pending") {
+		t.Errorf("Expected pending migrations in status output, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runMigrate([]string{"up"}, db, tmpDB, &out); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Applied") {
+		t.Errorf("Unexpected up output: %s", out.String())
+	}
+
+	out.Reset()
+	if err := runMigrate([]string{"down", "2"}, db, tmpDB, &out); err != nil {
+		t.Fatalf("migrate down failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Reverted 2 migration(s)") {
+		t.Errorf("Unexpected down output: %s", out.String())
+	}
+}
+
+func TestRunMigrate_InvalidArguments(t *testing.T) {
+	tmpDB := "./test_migrate_cmd_invalid.db"
+	defer os.Remove(tmpDB)
+
+	db, err := database.Open(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var out bytes.Buffer
+	testCases := [][]string{
+		{},
+		{"sideways"},
+		{"up", "abc"},
+		{"down", "0"},
+	}
+
+	for _, args := range testCases {
+		if err := runMigrate(args, db, tmpDB, &out); err == nil {
+			t.Errorf("Expected error for args %v", args)
+		}
+	}
+}
@@ -3,6 +3,7 @@ module github.com/morey-tech/credit-card-payment-tracker
 go 1.24.6
 
 require (
+	github.com/lib/pq v1.12.3
 	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.42.2
 )
@@ -0,0 +1,91 @@
+package clock
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FreezeEnvVar names the environment variable that freezes the server clock
+const FreezeEnvVar = "FREEZE_TIME"
+
+// Clock provides the current time. All date logic goes through a Clock so it
+// can be pinned to a fixed date in tests and when reproducing issues.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is a Clock backed by the system time
+type Real struct{}
+
+// Now returns the current system time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed is a Clock that always returns the same time until it is changed
+type Fixed struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewFixed returns a Clock frozen at t
+func NewFixed(t time.Time) *Fixed {
+	return &Fixed{now: t}
+}
+
+// Now returns the frozen time
+func (f *Fixed) Now() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.now
+}
+
+// Set moves the clock to t
+func (f *Fixed) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
+
+// Advance moves the clock forward by d
+func (f *Fixed) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Parse parses a freeze value, either a date (YYYY-MM-DD, frozen at noon
+// local time) or an RFC 3339 timestamp
+func Parse(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return d.Add(12 * time.Hour), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: must be YYYY-MM-DD or RFC 3339", value)
+}
+
+// FromEnv returns a Fixed clock when FREEZE_TIME is set and the real clock
+// otherwise
+func FromEnv() (Clock, error) {
+	value := os.Getenv(FreezeEnvVar)
+	if value == "" {
+		return Real{}, nil
+	}
+
+	t, err := Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", FreezeEnvVar, err)
+	}
+	return NewFixed(t), nil
+}
+
+// Today returns the calendar date of c.Now() as midnight UTC, so it compares
+// cleanly with dates parsed from YYYY-MM-DD strings
+func Today(c Clock) time.Time {
+	now := c.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,107 @@
+package clock
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRealClock(t *testing.T) {
+	before := time.Now()
+	got := Real{}.Now()
+	after := time.Now()
+
+	if got.Before(before) || got.After(after) {
+		t.Errorf("Expected real clock time between %v and %v, got %v", before, after, got)
+	}
+}
+
+func TestFixedClock(t *testing.T) {
+	start := time.Date(2024, time.February, 28, 9, 0, 0, 0, time.UTC)
+	c := NewFixed(start)
+
+	if !c.Now().Equal(start) {
+		t.Errorf("Expected %v, got %v", start, c.Now())
+	}
+
+	c.Advance(24 * time.Hour)
+	if got := c.Now().Format("2006-01-02"); got != "2024-02-29" {
+		t.Errorf("Expected 2024-02-29 after advancing a day, got %s", got)
+	}
+
+	c.Set(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+	if got := c.Now().Format("2006-01-02"); got != "2025-03-01" {
+		t.Errorf("Expected 2025-03-01 after Set, got %s", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name      string
+		value     string
+		expected  string
+		expectErr bool
+	}{
+		{"date only", "2024-02-28", "2024-02-28", false},
+		{"RFC 3339", "2024-02-28T23:30:00Z", "2024-02-28", false},
+		{"invalid", "Feb 28", "", true},
+		{"invalid date", "2024-02-30", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.value)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.Format("2006-01-02") != tc.expected {
+				t.Errorf("Expected date %s, got %s", tc.expected, got.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	os.Unsetenv(FreezeEnvVar)
+	c, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+	if _, ok := c.(Real); !ok {
+		t.Errorf("Expected Real clock without %s, got %T", FreezeEnvVar, c)
+	}
+
+	os.Setenv(FreezeEnvVar, "2024-02-28")
+	defer os.Unsetenv(FreezeEnvVar)
+
+	c, err = FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv failed: %v", err)
+	}
+	if got := c.Now().Format("2006-01-02"); got != "2024-02-28" {
+		t.Errorf("Expected frozen date 2024-02-28, got %s", got)
+	}
+
+	os.Setenv(FreezeEnvVar, "not-a-date")
+	if _, err := FromEnv(); err == nil {
+		t.Error("Expected error for invalid freeze value")
+	}
+}
+
+func TestToday(t *testing.T) {
+	c := NewFixed(time.Date(2024, time.February, 29, 23, 59, 0, 0, time.Local))
+
+	today := Today(c)
+	if today.Format("2006-01-02") != "2024-02-29" {
+		t.Errorf("Expected 2024-02-29, got %s", today.Format("2006-01-02"))
+	}
+	if today.Hour() != 0 || today.Location() != time.UTC {
+		t.Errorf("Expected midnight UTC, got %v", today)
+	}
+}
@@ -11,6 +11,51 @@ import (
 // Config holds application configuration
 type Config struct {
 	DiscordWebhookURL string `yaml:"discord_webhook_url"`
+	// YNAB is only set in the config file; the settings API neither shows
+	// nor changes it, so the token isn't exposed
+	YNAB YNABConfig `yaml:"ynab,omitempty" json:"-"`
+	// IMAP is likewise only set in the config file, to keep its password
+	// out of the settings API
+	IMAP IMAPConfig `yaml:"imap,omitempty" json:"-"`
+}
+
+// YNABConfig connects the tracker to a YNAB budget. Scheduled payments are
+// synced for cards that have an account in Cards.
+type YNABConfig struct {
+	// Token is a YNAB personal access token
+	Token    string `yaml:"token,omitempty"`
+	BudgetID string `yaml:"budget_id,omitempty"`
+	// PaymentAccountID is the YNAB account payments come from, e.g. checking
+	PaymentAccountID string `yaml:"payment_account_id,omitempty"`
+	// FundingAccounts maps funding account IDs to YNAB accounts, for
+	// statements paid from somewhere other than PaymentAccountID
+	FundingAccounts map[int]string `yaml:"funding_accounts,omitempty"`
+	// Cards maps card IDs to their YNAB credit card accounts
+	Cards map[int]string `yaml:"cards,omitempty"`
+}
+
+// Enabled reports whether a token and budget are configured
+func (c YNABConfig) Enabled() bool {
+	return c.Token != "" && c.BudgetID != ""
+}
+
+// IMAPConfig is a mailbox issuers' statement emails are delivered to. The
+// scheduler reads its unread messages and marks the statement emails read.
+type IMAPConfig struct {
+	// Address is the server's host:port, e.g. imap.example.com:993
+	Address  string `yaml:"address,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// Mailbox is the folder to read, e.g. one a mail filter moves statement
+	// emails into
+	Mailbox string `yaml:"mailbox,omitempty"`
+	// Insecure connects without TLS, e.g. to a mail bridge on the same host
+	Insecure bool `yaml:"insecure,omitempty"`
+}
+
+// Enabled reports whether a mailbox is configured
+func (c IMAPConfig) Enabled() bool {
+	return c.Address != ""
 }
 
 // LoadConfig loads configuration from a YAML file
@@ -85,5 +130,21 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// YNAB needs both a token and a budget, or neither
+	if (c.YNAB.Token == "") != (c.YNAB.BudgetID == "") {
+		return fmt.Errorf("ynab token and budget_id must be set together")
+	}
+
+	// A mailbox needs an address, someone to log in as and a folder to read
+	if c.IMAP.Enabled() && c.IMAP.Username == "" {
+		return fmt.Errorf("imap username is required with an address")
+	}
+	if c.IMAP.Enabled() && c.IMAP.Mailbox == "" {
+		return fmt.Errorf("imap mailbox is required with an address")
+	}
+	if !c.IMAP.Enabled() && c.IMAP.Username != "" {
+		return fmt.Errorf("imap address is required with a username")
+	}
+
 	return nil
 }
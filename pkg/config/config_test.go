@@ -289,3 +289,87 @@ func TestRoundTrip(t *testing.T) {
 		t.Errorf("Round trip failed: expected %q, got %q", original.DiscordWebhookURL, loaded.DiscordWebhookURL)
 	}
 }
+
+func TestLoadConfig_YNAB(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	testConfig := `
+ynab:
+  token: "secret"
+  budget_id: "budget-1"
+  payment_account_id: "checking"
+  funding_accounts:
+    2: "savings"
+  cards:
+    1: "visa-account"
+`
+	if err := os.WriteFile(configPath, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	if !cfg.YNAB.Enabled() || cfg.YNAB.PaymentAccountID != "checking" {
+		t.Errorf("Unexpected YNAB config %+v", cfg.YNAB)
+	}
+	if cfg.YNAB.Cards[1] != "visa-account" || cfg.YNAB.FundingAccounts[2] != "savings" {
+		t.Errorf("Expected the account mappings, got %+v", cfg.YNAB)
+	}
+
+	// A token without a budget can't sync anything
+	cfg.YNAB.BudgetID = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for a token without a budget")
+	}
+}
+
+func TestLoadConfig_IMAP(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	testConfig := `
+imap:
+  address: "127.0.0.1:1143"
+  username: "statements@example.com"
+  password: "secret"
+  mailbox: "Statements"
+  insecure: true
+`
+	if err := os.WriteFile(configPath, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	if !cfg.IMAP.Enabled() || !cfg.IMAP.Insecure || cfg.IMAP.Mailbox != "Statements" {
+		t.Errorf("Unexpected IMAP config %+v", cfg.IMAP)
+	}
+
+	// The folder to read must be named
+	cfg.IMAP.Mailbox = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for an address without a mailbox")
+	}
+
+	// An address without a username can't log in
+	cfg.IMAP.Mailbox = "Statements"
+	cfg.IMAP.Username = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for an address without a username")
+	}
+	cfg.IMAP = IMAPConfig{Username: "statements@example.com"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for a username without an address")
+	}
+}
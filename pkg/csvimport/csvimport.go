@@ -0,0 +1,386 @@
+// Package csvimport reads cards and statements from CSV files with a header
+// row. Columns are matched to fields by name, or by a mapping for files
+// that name them differently, and dates are read in a given format or one
+// detected from the file.
+package csvimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// The kinds of file an import reads
+const (
+	Cards      = "cards"
+	Statements = "statements"
+)
+
+// Columns lists the fields each kind reads, in the order they are exported.
+// Statements are matched to cards by card_id, or by last_four and
+// card_name.
+var Columns = map[string][]string{
+	Cards: {"name", "last_four", "statement_date", "due_date", "credit_limit", "statement_day_rule",
+		"payment_policy", "payment_lead_days", "autopay", "autopay_amount"},
+	Statements: {"card_id", "card_name", "last_four", "statement_date", "due_date", "amount",
+		"minimum_payment", "current_balance", "status"},
+}
+
+// required lists the columns each kind can't be imported without
+var required = map[string][]string{
+	Cards:      {"name", "last_four", "statement_date", "due_date"},
+	Statements: {"statement_date", "due_date", "amount"},
+}
+
+// dateColumns are the columns holding dates
+var dateColumns = []string{"statement_date", "due_date"}
+
+// DateFormat is a date format by its display name and Go layout
+type DateFormat struct {
+	Name   string
+	Layout string
+}
+
+// DateFormats are the date formats an import recognises, in the order they
+// are tried when detecting the format. Month-first comes before day-first,
+// so a file where both fit every date is read month-first unless the format
+// is given.
+var DateFormats = []DateFormat{
+	{"YYYY-MM-DD", "2006-1-2"},
+	{"MM/DD/YYYY", "1/2/2006"},
+	{"DD/MM/YYYY", "2/1/2006"},
+	{"YYYY/MM/DD", "2006/1/2"},
+	{"DD.MM.YYYY", "2.1.2006"},
+	{"MMM D, YYYY", "Jan 2, 2006"},
+	{"D MMM YYYY", "2 Jan 2006"},
+	{"DD-MMM-YYYY", "2-Jan-2006"},
+}
+
+// File is a CSV file read for import
+type File struct {
+	Kind string
+	// Rows are the records after the header
+	Rows [][]string
+	// DateFormat is the format dates are read in, given or detected
+	DateFormat DateFormat
+	// columns is the index of each field's column
+	columns map[string]int
+}
+
+// Card is a card row with its values parsed, still to be validated as a
+// card
+type Card struct {
+	Name             string
+	LastFour         string
+	StatementDate    string
+	DueDate          string
+	CreditLimit      models.Money
+	StatementDayRule string
+	PaymentPolicy    string
+	PaymentLeadDays  int
+	Autopay          string
+	AutopayAmount    models.Money
+}
+
+// ValidKind reports whether kind is a kind of file an import reads
+func ValidKind(kind string) bool {
+	_, ok := Columns[kind]
+	return ok
+}
+
+// Read reads a file of the given kind. mapping names the column holding a
+// field, by field, for files that don't name it after the field.
+// dateFormat names one of DateFormats, or is empty to detect the format
+// from the file. The errors describe what is wrong with the file.
+func Read(r io.Reader, kind string, mapping map[string]string, dateFormat string) (*File, error) {
+	if !ValidKind(kind) {
+		return nil, errors.New("type must be cards or statements")
+	}
+	for field := range mapping {
+		if !containsString(Columns[kind], field) {
+			return nil, fmt.Errorf("unknown field %q in column mapping", field)
+		}
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("CSV file is empty")
+	}
+
+	f := &File{Kind: kind, Rows: records[1:]}
+	f.columns, err = header(records[0], kind, mapping)
+	if err != nil {
+		return nil, err
+	}
+	f.DateFormat, err = f.detectDateFormat(dateFormat)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Line returns the line number of row i in the file, counting the header
+// as line 1
+func (f *File) Line(i int) int {
+	return i + 2
+}
+
+// Card reads row i as a card. The error lists every value that can't be
+// read.
+func (f *File) Card(i int) (Card, error) {
+	get := f.getter(i)
+	card := Card{
+		Name:             get("name"),
+		LastFour:         get("last_four"),
+		StatementDayRule: get("statement_day_rule"),
+		PaymentPolicy:    get("payment_policy"),
+		Autopay:          get("autopay"),
+	}
+
+	var errs []error
+	var err error
+	card.StatementDate, err = f.parseDate(get("statement_date"))
+	errs = append(errs, fieldError("statement_date", err))
+	card.DueDate, err = f.parseDate(get("due_date"))
+	errs = append(errs, fieldError("due_date", err))
+	card.CreditLimit, err = ParseMoney(get("credit_limit"))
+	errs = append(errs, fieldError("credit_limit", err))
+	card.AutopayAmount, err = ParseMoney(get("autopay_amount"))
+	errs = append(errs, fieldError("autopay_amount", err))
+	if s := get("payment_lead_days"); s != "" {
+		card.PaymentLeadDays, err = strconv.Atoi(s)
+		errs = append(errs, fieldError("payment_lead_days", err))
+	}
+	return card, joinErrors(errs)
+}
+
+// Statement reads row i as a statement of the card it names among cards,
+// and returns the card along with it. The error says which card can't be
+// found or lists every value that can't be read.
+func (f *File) Statement(i int, cards []models.CreditCard) (models.Statement, models.CreditCard, error) {
+	get := f.getter(i)
+	card, err := MatchCard(cards, get("card_id"), get("last_four"), get("card_name"))
+	if err != nil {
+		return models.Statement{}, card, err
+	}
+
+	stmt := models.Statement{CardID: card.ID, Status: get("status")}
+	var errs []error
+	stmt.StatementDate, err = f.parseDate(get("statement_date"))
+	errs = append(errs, fieldError("statement_date", err))
+	stmt.DueDate, err = f.parseDate(get("due_date"))
+	errs = append(errs, fieldError("due_date", err))
+	stmt.Amount, err = ParseMoney(get("amount"))
+	errs = append(errs, fieldError("amount", err))
+	stmt.MinimumPayment, err = ParseMoney(get("minimum_payment"))
+	errs = append(errs, fieldError("minimum_payment", err))
+	if s := get("current_balance"); s != "" {
+		balance, err := ParseMoney(s)
+		errs = append(errs, fieldError("current_balance", err))
+		stmt.CurrentBalance = &balance
+	}
+	return stmt, card, joinErrors(errs)
+}
+
+// MatchCard finds the card a statement row belongs to. Rows name it by
+// last_four, card_name or both, falling back to card_id, so exports can be
+// imported into another tracker.
+func MatchCard(cards []models.CreditCard, id, lastFour, name string) (models.CreditCard, error) {
+	if lastFour == "" && name == "" {
+		if id == "" {
+			return models.CreditCard{}, errors.New("card_id, last_four or card_name is required")
+		}
+		cardID, err := strconv.Atoi(id)
+		if err != nil {
+			return models.CreditCard{}, fmt.Errorf("card_id: invalid ID %q", id)
+		}
+		for _, card := range cards {
+			if card.ID == cardID {
+				return card, nil
+			}
+		}
+		return models.CreditCard{}, errors.New("card_id does not refer to an existing card")
+	}
+
+	var matches []models.CreditCard
+	for _, card := range cards {
+		if (lastFour == "" || card.LastFour == lastFour) && (name == "" || strings.EqualFold(card.Name, name)) {
+			matches = append(matches, card)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return models.CreditCard{}, errors.New("no card matches last_four and card_name")
+	case 1:
+		return matches[0], nil
+	default:
+		return models.CreditCard{}, errors.New("several cards match; add card_name to tell them apart")
+	}
+}
+
+// ParseMoney reads an amount the way statements write it, e.g.
+// "$1,250.75". An empty value is zero.
+func ParseMoney(value string) (models.Money, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	return models.ParseDisplayMoney(value)
+}
+
+// NormalizeHeader lower-cases a column name and joins its words with
+// underscores, so "Statement Date" matches statement_date
+func NormalizeHeader(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), "_")
+}
+
+// header finds the column of each field in the header row
+func header(names []string, kind string, mapping map[string]string) (map[string]int, error) {
+	index := map[string]int{}
+	for i, name := range names {
+		index[NormalizeHeader(name)] = i
+	}
+
+	columns := map[string]int{}
+	for _, field := range Columns[kind] {
+		name, mapped := mapping[field]
+		if mapped {
+			name = NormalizeHeader(name)
+		} else {
+			name = field
+		}
+		i, ok := index[name]
+		if !ok {
+			if mapped {
+				return nil, fmt.Errorf("column %q mapped to %s is not in the header", name, field)
+			}
+			continue
+		}
+		columns[field] = i
+	}
+
+	for _, field := range required[kind] {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("missing required column %s", field)
+		}
+	}
+	if kind == Statements {
+		_, id := columns["card_id"]
+		_, lastFour := columns["last_four"]
+		_, name := columns["card_name"]
+		if !id && !lastFour && !name {
+			return nil, errors.New("missing a card_id, last_four or card_name column")
+		}
+	}
+	return columns, nil
+}
+
+// detectDateFormat returns the named date format, or the first format that
+// reads every date in the file. A file without readable dates uses
+// YYYY-MM-DD and its rows report the bad dates.
+func (f *File) detectDateFormat(name string) (DateFormat, error) {
+	if name != "" {
+		var names []string
+		for _, format := range DateFormats {
+			if strings.EqualFold(format.Name, name) {
+				return format, nil
+			}
+			names = append(names, format.Name)
+		}
+		return DateFormats[0], fmt.Errorf("date_format must be one of %s", strings.Join(names, ", "))
+	}
+
+	var dates []string
+	for i := range f.Rows {
+		get := f.getter(i)
+		for _, field := range dateColumns {
+			if value := get(field); value != "" {
+				dates = append(dates, value)
+			}
+		}
+	}
+
+formats:
+	for _, format := range DateFormats {
+		for _, date := range dates {
+			if _, err := time.Parse(format.Layout, date); err != nil {
+				continue formats
+			}
+		}
+		if len(dates) > 0 {
+			return format, nil
+		}
+	}
+	return DateFormats[0], nil
+}
+
+// getter returns a function reading a field of row i, or "" if the file
+// has no such column
+func (f *File) getter(i int) func(string) string {
+	record := f.Rows[i]
+	return func(field string) string {
+		i, ok := f.columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+}
+
+// parseDate converts a date in the file's format to YYYY-MM-DD. An empty
+// value stays empty for validation to report as missing.
+func (f *File) parseDate(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	date, err := time.Parse(f.DateFormat.Layout, value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", value)
+	}
+	return date.Format(models.DateFormat), nil
+}
+
+// fieldError prefixes err with the field it is about, or returns nil
+func fieldError(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", field, err)
+}
+
+// joinErrors combines the non-nil errors into one, or returns nil
+func joinErrors(errs []error) error {
+	var messages []string
+	for _, err := range errs {
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package csvimport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func TestRead_DetectsDateFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		dates  string
+		format string
+	}{
+		{"iso", "2024-11-15,2024-12-10", "YYYY-MM-DD"},
+		{"month first", "11/15/2024,12/10/2024", "MM/DD/YYYY"},
+		{"day first", "15/11/2024,10/12/2024", "DD/MM/YYYY"},
+		// Both fit, so month-first wins
+		{"ambiguous", "11/05/2024,12/01/2024", "MM/DD/YYYY"},
+		{"month name", `"Nov 15, 2024","Dec 10, 2024"`, "MMM D, YYYY"},
+		{"unreadable", "soon,later", "YYYY-MM-DD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "statement_date,due_date,amount,last_four\n" + tt.dates + ",100.00,1234\n"
+			f, err := Read(strings.NewReader(data), Statements, nil, "")
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if f.DateFormat.Name != tt.format {
+				t.Errorf("Expected %s, got %s", tt.format, f.DateFormat.Name)
+			}
+		})
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		mapping    map[string]string
+		dateFormat string
+		data       string
+	}{
+		{"unknown kind", "payments", nil, "", "name\n"},
+		{"empty file", Cards, nil, "", ""},
+		{"missing required column", Cards, nil, "", "name,last_four,statement_date\n"},
+		{"no card column", Statements, nil, "", "statement_date,due_date,amount\n"},
+		{"unknown mapped field", Cards, map[string]string{"color": "Colour"}, "", "name\n"},
+		{"mapped column not in header", Statements, map[string]string{"amount": "Balance"},
+			"", "statement_date,due_date,amount,last_four\n"},
+		{"unknown date format", Cards, nil, "YY-MM", "name,last_four,statement_date,due_date\n"},
+		{"malformed", Cards, nil, "", "name,\"last_four\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(tt.data), tt.kind, tt.mapping, tt.dateFormat); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestFile_Card(t *testing.T) {
+	data := "Card Name,Last Four,Statement Date,Due Date,Credit Limit,Payment Lead Days,Autopay\n" +
+		"Visa,1234,15/11/2024,10/12/2024,\"$5,000.00\",3,minimum\n" +
+		"Amex,5678,2024-11-20,soon,lots,two,\n"
+	f, err := Read(strings.NewReader(data), Cards, map[string]string{"name": "card name"}, "DD/MM/YYYY")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	card, err := f.Card(0)
+	if err != nil {
+		t.Fatalf("Card failed: %v", err)
+	}
+	want := Card{
+		Name:            "Visa",
+		LastFour:        "1234",
+		StatementDate:   "2024-11-15",
+		DueDate:         "2024-12-10",
+		CreditLimit:     models.MustParseMoney("5000.00"),
+		PaymentLeadDays: 3,
+		Autopay:         "minimum",
+	}
+	if card != want {
+		t.Errorf("Expected %+v, got %+v", want, card)
+	}
+	if f.Line(0) != 2 {
+		t.Errorf("Expected the first row on line 2, got %d", f.Line(0))
+	}
+
+	// Every bad value is reported
+	_, err = f.Card(1)
+	if err == nil {
+		t.Fatal("Expected an error for the second row")
+	}
+	for _, field := range []string{"statement_date", "due_date", "credit_limit", "payment_lead_days"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected %s in %q", field, err)
+		}
+	}
+}
+
+func TestFile_Statement(t *testing.T) {
+	cards := []models.CreditCard{
+		{ID: 1, Name: "Visa", LastFour: "1234"},
+		{ID: 2, Name: "Visa Business", LastFour: "1234"},
+		{ID: 3, Name: "Amex", LastFour: "5678"},
+	}
+	data := "card_id,card_name,last_four,statement_date,due_date,amount,minimum_payment,current_balance\n" +
+		",visa business,1234,2024-11-15,2024-12-10,$250.00,$25.00,$300.00\n" +
+		"3,,,2024-11-20,2024-12-15,\"1,000.00\",,\n" +
+		",,1234,2024-11-15,2024-12-10,10.00,,\n" +
+		"9,,,2024-11-15,2024-12-10,10.00,,\n" +
+		",,5678,2024-11-15,2024-12-10,1.2.3,,\n"
+	f, err := Read(strings.NewReader(data), Statements, nil, "")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	stmt, card, err := f.Statement(0, cards)
+	if err != nil {
+		t.Fatalf("Statement failed: %v", err)
+	}
+	if card.ID != 2 || stmt.CardID != 2 || stmt.Amount.String() != "250.00" ||
+		stmt.MinimumPayment.String() != "25.00" || stmt.CurrentBalance == nil || stmt.CurrentBalance.String() != "300.00" {
+		t.Errorf("Unexpected statement %+v for card %d", stmt, card.ID)
+	}
+
+	stmt, card, err = f.Statement(1, cards)
+	if err != nil || card.ID != 3 || stmt.Amount.String() != "1000.00" || stmt.CurrentBalance != nil {
+		t.Errorf("Expected the card_id row to match Amex, got %+v for card %d: %v", stmt, card.ID, err)
+	}
+
+	for i, want := range []string{"several cards match", "does not refer to an existing card", "amount"} {
+		if _, _, err := f.Statement(i+2, cards); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Row %d: expected an error about %q, got %v", i+2, want, err)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{"", "0.00", false},
+		{"  ", "0.00", false},
+		{"$1,250.75", "1250.75", false},
+		{"-$25.00", "-25.00", false},
+		{"12", "12.00", false},
+		{"--5", "", true},
+		{"abc", "", true},
+		{"1.234", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.value)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseMoney(%q): expected an error, got %s", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("ParseMoney(%q) = %s, %v; want %s", tt.value, got, err, tt.want)
+		}
+	}
+}
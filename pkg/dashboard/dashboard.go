@@ -0,0 +1,267 @@
+// Package dashboard works out what needs doing on a given day from the cards
+// and statements, so the web UI, the API and any other client agree on the
+// dates
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+)
+
+// Dashboard summarises the cards and statements as of Date
+type Dashboard struct {
+	Date string `json:"date"`
+	// UpcomingStatements lists every card's next predicted statement, soonest first
+	UpcomingStatements []UpcomingStatement `json:"upcoming_statements"`
+	// StatementsNeeded lists cards whose latest predicted statement hasn't
+	// been entered yet
+	StatementsNeeded []StatementNeeded `json:"statements_needed"`
+	// MissingAmounts lists expected placeholders whose statement has been
+	// released but whose amount hasn't been entered, oldest first
+	MissingAmounts []MissingAmount `json:"missing_amounts"`
+	// UnscheduledPayments lists unpaid statements without a scheduled payment,
+	// earliest due first
+	UnscheduledPayments []Payment `json:"unscheduled_payments"`
+	// ScheduledPayments lists unpaid statements with a scheduled payment, in
+	// the order they are scheduled
+	ScheduledPayments []Payment `json:"scheduled_payments"`
+}
+
+// UpcomingStatement is a card's next predicted statement
+type UpcomingStatement struct {
+	CardID          int    `json:"card_id"`
+	CardName        string `json:"card_name"`
+	LastFour        string `json:"last_four"`
+	StatementDate   string `json:"statement_date"`
+	ExpectedDueDate string `json:"expected_due_date"`
+	DaysUntil       int    `json:"days_until"`
+}
+
+// StatementNeeded is a card whose statement should be entered
+type StatementNeeded struct {
+	CardID                int    `json:"card_id"`
+	CardName              string `json:"card_name"`
+	LastFour              string `json:"last_four"`
+	ExpectedStatementDate string `json:"expected_statement_date"`
+	ExpectedDueDate       string `json:"expected_due_date"`
+}
+
+// MissingAmount is an expected placeholder waiting for its amount
+type MissingAmount struct {
+	StatementID   int    `json:"statement_id"`
+	CardID        int    `json:"card_id"`
+	CardName      string `json:"card_name"`
+	LastFour      string `json:"last_four"`
+	StatementDate string `json:"statement_date"`
+	DueDate       string `json:"due_date"`
+	DaysUntilDue  int    `json:"days_until_due"`
+}
+
+// Payment is an unpaid statement with its computed dates
+type Payment struct {
+	StatementID            int          `json:"statement_id"`
+	CardID                 int          `json:"card_id"`
+	CardName               string       `json:"card_name"`
+	LastFour               string       `json:"last_four"`
+	Status                 string       `json:"status"`
+	StatementDate          string       `json:"statement_date"`
+	DueDate                string       `json:"due_date"`
+	DaysUntilDue           int          `json:"days_until_due"`
+	RecommendedPaymentDate string       `json:"recommended_payment_date"`
+	ScheduledPaymentDate   *string      `json:"scheduled_payment_date,omitempty"`
+	Amount                 models.Money `json:"amount"`
+	MinimumPayment         models.Money `json:"minimum_payment"`
+	PaidAmount             models.Money `json:"paid_amount"`
+	RemainingBalance       models.Money `json:"remaining_balance"`
+	// Recommendation explains how RecommendedPaymentDate was chosen
+	Recommendation *recommend.Recommendation `json:"recommendation,omitempty"`
+	// Autopay is the card's autopay mode when the issuer pays automatically
+	Autopay string `json:"autopay,omitempty"`
+	// AutopayAmount is what autopay will pull on the due date
+	AutopayAmount *models.Money `json:"autopay_amount,omitempty"`
+	// AutopayShortfall is the remaining balance autopay leaves unpaid
+	AutopayShortfall models.Money `json:"autopay_shortfall,omitempty"`
+	// Warnings flag a scheduled payment date that is too close to or after
+	// the due date, or an autopay that needs checking or falls short
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Build computes the dashboard for today, recommending payment dates on
+// business days of cal and, for cards paid on payday, on paydays. Statements
+// for cards that aren't in cards are ignored.
+func Build(cards []models.CreditCard, statements []models.Statement, today time.Time, cal *holidays.Calendar, paydays recommend.Paydays) Dashboard {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	todayStr := today.Format(models.DateFormat)
+
+	d := Dashboard{
+		Date:                todayStr,
+		UpcomingStatements:  []UpcomingStatement{},
+		StatementsNeeded:    []StatementNeeded{},
+		MissingAmounts:      []MissingAmount{},
+		UnscheduledPayments: []Payment{},
+		ScheduledPayments:   []Payment{},
+	}
+
+	cardsByID := map[int]models.CreditCard{}
+	latestStatement := map[int]string{}
+	for _, card := range cards {
+		cardsByID[card.ID] = card
+	}
+	for _, stmt := range statements {
+		if stmt.StatementDate > latestStatement[stmt.CardID] {
+			latestStatement[stmt.CardID] = stmt.StatementDate
+		}
+	}
+
+	for _, card := range cards {
+		next := card.NextStatementDate(today)
+		d.UpcomingStatements = append(d.UpcomingStatements, UpcomingStatement{
+			CardID:          card.ID,
+			CardName:        card.Name,
+			LastFour:        card.LastFour,
+			StatementDate:   next.Format(models.DateFormat),
+			ExpectedDueDate: card.ExpectedDueDate(next).Format(models.DateFormat),
+			DaysUntil:       daysBetween(today, next),
+		})
+
+		// A statement entered shortly before the predicted date counts, the
+		// same as for the scheduler's statement expected alerts
+		last := card.LastStatementDate(today)
+		earliest := last.AddDate(0, 0, -models.StatementEntryGraceDays).Format(models.DateFormat)
+		if latestStatement[card.ID] < earliest {
+			d.StatementsNeeded = append(d.StatementsNeeded, StatementNeeded{
+				CardID:                card.ID,
+				CardName:              card.Name,
+				LastFour:              card.LastFour,
+				ExpectedStatementDate: last.Format(models.DateFormat),
+				ExpectedDueDate:       card.ExpectedDueDate(last).Format(models.DateFormat),
+			})
+		}
+	}
+
+	for _, stmt := range statements {
+		card, ok := cardsByID[stmt.CardID]
+		if !ok {
+			continue
+		}
+		if stmt.Status == models.StatusExpected {
+			if stmt.StatementDate <= todayStr {
+				d.MissingAmounts = append(d.MissingAmounts, newMissingAmount(card, stmt, today))
+			}
+			continue
+		}
+		stmt.EvaluateOverdue(todayStr)
+		if stmt.Status == models.StatusPaid {
+			continue
+		}
+
+		payment := newPayment(card, stmt, today, cal, paydays)
+		if stmt.ScheduledPaymentDate != nil {
+			d.ScheduledPayments = append(d.ScheduledPayments, payment)
+		} else {
+			d.UnscheduledPayments = append(d.UnscheduledPayments, payment)
+		}
+	}
+
+	sort.SliceStable(d.UpcomingStatements, func(i, j int) bool {
+		a, b := d.UpcomingStatements[i], d.UpcomingStatements[j]
+		if a.StatementDate != b.StatementDate {
+			return a.StatementDate < b.StatementDate
+		}
+		return a.CardName < b.CardName
+	})
+	sort.SliceStable(d.MissingAmounts, func(i, j int) bool {
+		a, b := d.MissingAmounts[i], d.MissingAmounts[j]
+		if a.StatementDate != b.StatementDate {
+			return a.StatementDate < b.StatementDate
+		}
+		return a.CardName < b.CardName
+	})
+	sort.SliceStable(d.UnscheduledPayments, func(i, j int) bool {
+		a, b := d.UnscheduledPayments[i], d.UnscheduledPayments[j]
+		if a.DueDate != b.DueDate {
+			return a.DueDate < b.DueDate
+		}
+		return a.StatementID < b.StatementID
+	})
+	sort.SliceStable(d.ScheduledPayments, func(i, j int) bool {
+		a, b := d.ScheduledPayments[i], d.ScheduledPayments[j]
+		if *a.ScheduledPaymentDate != *b.ScheduledPaymentDate {
+			return *a.ScheduledPaymentDate < *b.ScheduledPaymentDate
+		}
+		return a.StatementID < b.StatementID
+	})
+
+	return d
+}
+
+// newMissingAmount describes an expected placeholder as of today
+func newMissingAmount(card models.CreditCard, stmt models.Statement, today time.Time) MissingAmount {
+	missing := MissingAmount{
+		StatementID:   stmt.ID,
+		CardID:        card.ID,
+		CardName:      card.Name,
+		LastFour:      card.LastFour,
+		StatementDate: stmt.StatementDate,
+		DueDate:       stmt.DueDate,
+	}
+	if dueDate, err := time.Parse(models.DateFormat, stmt.DueDate); err == nil {
+		missing.DaysUntilDue = daysBetween(today, dueDate)
+	}
+	return missing
+}
+
+// newPayment describes an unpaid statement as of today
+func newPayment(card models.CreditCard, stmt models.Statement, today time.Time, cal *holidays.Calendar, paydays recommend.Paydays) Payment {
+	payment := Payment{
+		StatementID:          stmt.ID,
+		CardID:               card.ID,
+		CardName:             card.Name,
+		LastFour:             card.LastFour,
+		Status:               stmt.Status,
+		StatementDate:        stmt.StatementDate,
+		DueDate:              stmt.DueDate,
+		ScheduledPaymentDate: stmt.ScheduledPaymentDate,
+		Amount:               stmt.Amount,
+		MinimumPayment:       stmt.MinimumPayment,
+		PaidAmount:           stmt.PaidAmount,
+		RemainingBalance:     stmt.RemainingBalance,
+	}
+	if dueDate, err := time.Parse(models.DateFormat, stmt.DueDate); err == nil {
+		payment.DaysUntilDue = daysBetween(today, dueDate)
+	}
+	if rec, err := recommend.ForStatement(card, stmt, cal, paydays); err == nil {
+		payment.RecommendedPaymentDate = rec.PaymentDate
+		payment.Recommendation = &rec
+		if stmt.ScheduledPaymentDate != nil && !card.HasAutopay() {
+			payment.Warnings = rec.Check(*stmt.ScheduledPaymentDate)
+		}
+	}
+
+	// Autopay runs on the due date, so there is no lead time to warn about;
+	// only whether it ran and whether it covers the balance
+	if card.HasAutopay() {
+		amount := card.AutopayPayment(stmt)
+		payment.Autopay = card.Autopay
+		payment.AutopayAmount = &amount
+		payment.AutopayShortfall = card.AutopayShortfall(stmt)
+		if stmt.DueDate <= today.Format(models.DateFormat) {
+			payment.Warnings = append(payment.Warnings, fmt.Sprintf(
+				"Autopay should have run on %s; check that it went through and record the payment.", stmt.DueDate))
+		}
+		if warning := card.AutopayWarning(stmt); warning != "" {
+			payment.Warnings = append(payment.Warnings, warning)
+		}
+	}
+	return payment
+}
+
+// daysBetween returns the whole days from one midnight UTC to another
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
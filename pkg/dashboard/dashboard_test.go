@@ -0,0 +1,187 @@
+package dashboard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestBuild(t *testing.T) {
+	today := time.Date(2024, 11, 20, 15, 30, 0, 0, time.UTC)
+	cards := []models.CreditCard{
+		{ID: 1, Name: "Amex Cobalt", LastFour: "1234", StatementDay: 28, DaysUntilDue: 25},
+		{ID: 2, Name: "Visa Infinite", LastFour: "5678", StatementDay: 15, DaysUntilDue: 21},
+		{ID: 3, Name: "Mastercard", LastFour: "9012", StatementDay: 31, DaysUntilDue: 20},
+	}
+	statements := []models.Statement{
+		// Visa's November statement was entered, Mastercard's October one wasn't
+		{ID: 10, CardID: 2, StatementDate: "2024-11-15", DueDate: "2024-12-06", Amount: 50000, Status: models.StatusPending},
+		{ID: 11, CardID: 1, StatementDate: "2024-10-28", DueDate: "2024-11-22", Amount: 12000, Status: models.StatusPending, ScheduledPaymentDate: strPtr("2024-11-21")},
+		{ID: 12, CardID: 1, StatementDate: "2024-09-28", DueDate: "2024-10-23", Amount: 8000, Status: models.StatusPaid},
+		{ID: 13, CardID: 3, StatementDate: "2024-09-30", DueDate: "2024-10-20", Amount: 3000, Status: models.StatusPending},
+		{ID: 14, CardID: 99, StatementDate: "2024-11-01", DueDate: "2024-11-25", Amount: 100, Status: models.StatusPending},
+	}
+
+	d := Build(cards, statements, today, nil, nil)
+
+	if d.Date != "2024-11-20" {
+		t.Errorf("Expected date 2024-11-20, got %s", d.Date)
+	}
+
+	// Upcoming statements are ordered by date, Mastercard's clamped to the 30th
+	wantUpcoming := []struct {
+		cardID    int
+		date, due string
+		daysUntil int
+	}{
+		{1, "2024-11-28", "2024-12-23", 8},
+		{3, "2024-11-30", "2024-12-20", 10},
+		{2, "2024-12-15", "2025-01-05", 25},
+	}
+	if len(d.UpcomingStatements) != len(wantUpcoming) {
+		t.Fatalf("Expected %d upcoming statements, got %d", len(wantUpcoming), len(d.UpcomingStatements))
+	}
+	for i, want := range wantUpcoming {
+		got := d.UpcomingStatements[i]
+		if got.CardID != want.cardID || got.StatementDate != want.date || got.ExpectedDueDate != want.due || got.DaysUntil != want.daysUntil {
+			t.Errorf("Upcoming %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	// Amex's October statement and Visa's November one are in; Mastercard's
+	// October 31st statement is missing
+	if len(d.StatementsNeeded) != 1 {
+		t.Fatalf("Expected 1 statement needed, got %+v", d.StatementsNeeded)
+	}
+	needed := d.StatementsNeeded[0]
+	if needed.CardID != 3 || needed.ExpectedStatementDate != "2024-10-31" || needed.ExpectedDueDate != "2024-11-20" {
+		t.Errorf("Unexpected statement needed %+v", needed)
+	}
+
+	// Paid statements and statements for unknown cards are left out
+	if len(d.UnscheduledPayments) != 2 {
+		t.Fatalf("Expected 2 unscheduled payments, got %+v", d.UnscheduledPayments)
+	}
+	overdue := d.UnscheduledPayments[0]
+	if overdue.StatementID != 13 || overdue.Status != models.StatusOverdue || overdue.DaysUntilDue != -31 {
+		t.Errorf("Expected overdue statement 13 first, got %+v", overdue)
+	}
+	visa := d.UnscheduledPayments[1]
+	if visa.StatementID != 10 || visa.RecommendedPaymentDate != "2024-11-29" || visa.DaysUntilDue != 16 {
+		t.Errorf("Unexpected payment %+v", visa)
+	}
+
+	if len(d.ScheduledPayments) != 1 {
+		t.Fatalf("Expected 1 scheduled payment, got %+v", d.ScheduledPayments)
+	}
+	scheduled := d.ScheduledPayments[0]
+	if scheduled.StatementID != 11 || *scheduled.ScheduledPaymentDate != "2024-11-21" || scheduled.CardName != "Amex Cobalt" {
+		t.Errorf("Unexpected scheduled payment %+v", scheduled)
+	}
+	// The day before the due date leaves too little time for the payment
+	if len(scheduled.Warnings) != 1 {
+		t.Errorf("Expected a warning for a payment scheduled the day before it is due, got %v", scheduled.Warnings)
+	}
+	if len(visa.Warnings) != 0 {
+		t.Errorf("Expected no warnings for an unscheduled payment, got %v", visa.Warnings)
+	}
+}
+
+func TestBuild_StatementEnteredEarly(t *testing.T) {
+	today := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+	cards := []models.CreditCard{{ID: 1, Name: "Visa", StatementDay: 15, DaysUntilDue: 21}}
+
+	// A statement that closed a few days before the predicted date still
+	// counts for the cycle
+	statements := []models.Statement{
+		{ID: 1, CardID: 1, StatementDate: "2024-11-12", DueDate: "2024-12-03", Status: models.StatusPaid},
+	}
+	if d := Build(cards, statements, today, nil, nil); len(d.StatementsNeeded) != 0 {
+		t.Errorf("Expected no statements needed, got %+v", d.StatementsNeeded)
+	}
+
+	statements[0].StatementDate = "2024-11-07"
+	if d := Build(cards, statements, today, nil, nil); len(d.StatementsNeeded) != 1 {
+		t.Errorf("Expected 1 statement needed, got %+v", d.StatementsNeeded)
+	}
+}
+
+func TestBuild_MissingAmounts(t *testing.T) {
+	today := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+	cards := []models.CreditCard{
+		{ID: 1, Name: "Visa", StatementDay: 15, DaysUntilDue: 21},
+		{ID: 2, Name: "Amex", StatementDay: 8, DaysUntilDue: 25},
+	}
+	statements := []models.Statement{
+		{ID: 1, CardID: 1, StatementDate: "2024-11-15", DueDate: "2024-12-06", Status: models.StatusExpected},
+		{ID: 2, CardID: 2, StatementDate: "2024-11-08", DueDate: "2024-12-03", Status: models.StatusExpected},
+		{ID: 3, CardID: 2, StatementDate: "2024-10-08", DueDate: "2024-11-02", Amount: 5000, Status: models.StatusPaid},
+	}
+
+	d := Build(cards, statements, today, nil, nil)
+
+	// Placeholders count as the cycle's statement, but wait for an amount
+	// rather than a payment
+	if len(d.StatementsNeeded) != 0 {
+		t.Errorf("Expected no statements needed, got %+v", d.StatementsNeeded)
+	}
+	if len(d.UnscheduledPayments) != 0 || len(d.ScheduledPayments) != 0 {
+		t.Errorf("Expected no payments for placeholders, got %+v and %+v", d.UnscheduledPayments, d.ScheduledPayments)
+	}
+	if len(d.MissingAmounts) != 2 {
+		t.Fatalf("Expected 2 missing amounts, got %+v", d.MissingAmounts)
+	}
+	if amex := d.MissingAmounts[0]; amex.StatementID != 2 || amex.CardName != "Amex" || amex.DaysUntilDue != 13 {
+		t.Errorf("Expected Amex's placeholder first, got %+v", amex)
+	}
+}
+
+func TestBuild_Empty(t *testing.T) {
+	d := Build(nil, nil, time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC), nil, nil)
+	if d.UpcomingStatements == nil || d.StatementsNeeded == nil || d.MissingAmounts == nil || d.UnscheduledPayments == nil || d.ScheduledPayments == nil {
+		t.Errorf("Expected empty lists rather than nil, got %+v", d)
+	}
+}
+
+func TestBuild_Autopay(t *testing.T) {
+	today := time.Date(2024, 11, 22, 0, 0, 0, 0, time.UTC)
+	cards := []models.CreditCard{
+		{ID: 1, Name: "Amex", StatementDay: 28, DaysUntilDue: 25, Autopay: models.AutopayStatementBalance},
+		{ID: 2, Name: "Visa", StatementDay: 15, DaysUntilDue: 21, Autopay: models.AutopayMinimum},
+	}
+	statements := []models.Statement{
+		// Due today, so autopay should have run
+		{ID: 1, CardID: 1, StatementDate: "2024-10-28", DueDate: "2024-11-22", Amount: 12000, Status: models.StatusPending, ScheduledPaymentDate: strPtr("2024-11-22")},
+		{ID: 2, CardID: 2, StatementDate: "2024-11-15", DueDate: "2024-12-06", Amount: 50000, MinimumPayment: 2500, Status: models.StatusPending, ScheduledPaymentDate: strPtr("2024-12-06")},
+	}
+	for i := range statements {
+		statements[i].SetPaidAmount(0)
+	}
+
+	d := Build(cards, statements, today, nil, nil)
+	if len(d.ScheduledPayments) != 2 {
+		t.Fatalf("Expected 2 scheduled payments, got %+v", d.ScheduledPayments)
+	}
+
+	amex := d.ScheduledPayments[0]
+	if amex.Autopay != models.AutopayStatementBalance || amex.AutopayAmount.String() != "120.00" || amex.AutopayShortfall != 0 {
+		t.Errorf("Expected autopay to cover the Amex balance, got %+v", amex)
+	}
+	if len(amex.Warnings) != 1 || !strings.Contains(amex.Warnings[0], "check that it went through") {
+		t.Errorf("Expected only a check that autopay ran, got %v", amex.Warnings)
+	}
+
+	// Scheduled on the due date without a lead time warning, but flagged
+	// for only paying the minimum
+	visa := d.ScheduledPayments[1]
+	if visa.AutopayAmount.String() != "25.00" || visa.AutopayShortfall.String() != "475.00" {
+		t.Errorf("Expected a 475.00 shortfall, got %+v", visa)
+	}
+	if len(visa.Warnings) != 1 || visa.Warnings[0] != "Autopay pays $25.00, leaving $475.00 of the statement balance to pay separately." {
+		t.Errorf("Expected a shortfall warning, got %v", visa.Warnings)
+	}
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// copyTables lists the tables moved by CopyData, parents before children.
+// schema_migrations is not copied; the destination is migrated on its own.
+var copyTables = []string{
+	"funding_accounts",
+	"credit_cards",
+	"statements",
+	"statement_status_changes",
+	"payments",
+	"statement_alerts",
+	"expected_cycles",
+	"scheduler_state",
+	"holidays",
+	"income_schedules",
+	"ynab_transactions",
+	"attachments",
+	"statement_suggestions",
+}
+
+// CopyResult reports how many rows CopyData copied per table
+type CopyResult struct {
+	Table string
+	Rows  int
+}
+
+// CopyData copies every row from src into dst, keeping IDs, in a single
+// transaction. Both databases must be fully migrated and dst must be empty.
+func CopyData(ctx context.Context, src, dst *sql.DB) ([]CopyResult, error) {
+	for _, db := range []*sql.DB{src, dst} {
+		version, err := SchemaVersion(db)
+		if err != nil {
+			return nil, err
+		}
+		if version != LatestVersion() {
+			return nil, fmt.Errorf("both databases must be at schema version %d, found %d", LatestVersion(), version)
+		}
+	}
+
+	var existing int
+	if err := dst.QueryRowContext(ctx, "SELECT COUNT(*) FROM credit_cards").Scan(&existing); err != nil {
+		return nil, fmt.Errorf("failed to check destination: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("destination already has %d card(s); refusing to copy into a non-empty database", existing)
+	}
+
+	tx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dialect := DialectOf(dst)
+	results := make([]CopyResult, 0, len(copyTables))
+	for _, table := range copyTables {
+		n, err := copyTable(ctx, src, tx, dialect, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+		results = append(results, CopyResult{Table: table, Rows: n})
+	}
+
+	// Explicit IDs don't advance Postgres sequences, so move them past the
+	// copied rows
+	if dialect == Postgres {
+		for _, table := range []string{"credit_cards", "statements", "statement_status_changes", "payments", "statement_alerts", "expected_cycles", "holidays", "income_schedules", "funding_accounts", "attachments", "statement_suggestions"} {
+			_, err := tx.ExecContext(ctx, fmt.Sprintf(
+				"SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE((SELECT MAX(id) FROM %s), 0) + 1, false)",
+				table, table))
+			if err != nil {
+				return nil, fmt.Errorf("failed to reset %s sequence: %w", table, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit copy: %w", err)
+	}
+	return results, nil
+}
+
+// copyTable copies every column of every row in table
+func copyTable(ctx context.Context, src *sql.DB, tx *sql.Tx, dialect Dialect, table string) (int, error) {
+	rows, err := src.QueryContext(ctx, "SELECT * FROM "+table)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	insert := dialect.Rebind(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(columns, ", "), placeholders))
+
+	count := 0
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+		if _, err := tx.ExecContext(ctx, insert, values...); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
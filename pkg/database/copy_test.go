@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+)
+
+func TestCopyTablesCoverSchema(t *testing.T) {
+	tmpDB := "./test_copy_tables.db"
+	defer os.Remove(tmpDB)
+
+	db, err := InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations'
+	`)
+	if err != nil {
+		t.Fatalf("Failed to list tables: %v", err)
+	}
+	defer rows.Close()
+
+	copied := map[string]int{}
+	for i, table := range copyTables {
+		copied[table] = i + 1
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		rows.Scan(&table)
+		tables = append(tables, table)
+	}
+	rows.Close()
+
+	for _, table := range tables {
+		if copied[table] == 0 {
+			t.Errorf("Table %s is not copied by CopyData", table)
+			continue
+		}
+		// Foreign keys are checked as rows are inserted, so parents must be
+		// copied first
+		parents, err := db.Query("SELECT \"table\" FROM pragma_foreign_key_list(?)", table)
+		if err != nil {
+			t.Fatalf("Failed to list foreign keys of %s: %v", table, err)
+		}
+		for parents.Next() {
+			var parent string
+			parents.Scan(&parent)
+			if parent != table && copied[parent] > copied[table] {
+				t.Errorf("Table %s is copied before its parent %s", table, parent)
+			}
+		}
+		parents.Close()
+	}
+}
+
+func TestCopyData(t *testing.T) {
+	srcPath := "./test_copy_src.db"
+	dstPath := "./test_copy_dst.db"
+	defer os.Remove(srcPath)
+	defer os.Remove(dstPath)
+
+	src, err := Open(srcPath)
+	if err != nil {
+		t.Fatalf("Failed to open source: %v", err)
+	}
+	defer src.Close()
+	dst, err := Open(dstPath)
+	if err != nil {
+		t.Fatalf("Failed to open destination: %v", err)
+	}
+	defer dst.Close()
+
+	if _, err := MigrateUp(src, 0, clock.Real{}); err != nil {
+		t.Fatalf("Failed to migrate source: %v", err)
+	}
+	if _, err := MigrateUp(dst, 0, clock.Real{}); err != nil {
+		t.Fatalf("Failed to migrate destination: %v", err)
+	}
+
+	frozen := clock.NewFixed(time.Date(2024, time.November, 20, 12, 0, 0, 0, time.UTC))
+	if err := LoadSampleData(src, frozen); err != nil {
+		t.Fatalf("Failed to load sample data: %v", err)
+	}
+
+	results, err := CopyData(context.Background(), src, dst)
+	if err != nil {
+		t.Fatalf("CopyData failed: %v", err)
+	}
+	if results[1].Table != "credit_cards" || results[1].Rows != 6 {
+		t.Errorf("Expected 6 cards copied, got %+v", results[1])
+	}
+	if results[2].Table != "statements" || results[2].Rows != 9 {
+		t.Errorf("Expected 9 statements copied, got %+v", results[2])
+	}
+
+	// IDs and amounts survive the copy
+	var srcSum, dstSum int64
+	src.QueryRow("SELECT SUM(id * amount_cents) FROM statements").Scan(&srcSum)
+	dst.QueryRow("SELECT SUM(id * amount_cents) FROM statements").Scan(&dstSum)
+	if srcSum != dstSum {
+		t.Errorf("Expected copied statements to match, got %d and %d", srcSum, dstSum)
+	}
+
+	// A second copy would duplicate data
+	if _, err := CopyData(context.Background(), src, dst); err == nil {
+		t.Error("Expected CopyData to refuse a non-empty destination")
+	}
+}
+
+func TestCopyData_AutopayFundingAccount(t *testing.T) {
+	srcPath := "./test_copy_autopay_src.db"
+	dstPath := "./test_copy_autopay_dst.db"
+	defer os.Remove(srcPath)
+	defer os.Remove(dstPath)
+
+	src, err := Open(srcPath)
+	if err != nil {
+		t.Fatalf("Failed to open source: %v", err)
+	}
+	defer src.Close()
+	dst, err := Open(dstPath)
+	if err != nil {
+		t.Fatalf("Failed to open destination: %v", err)
+	}
+	defer dst.Close()
+	for _, db := range []*sql.DB{src, dst} {
+		if _, err := MigrateUp(db, 0, clock.Real{}); err != nil {
+			t.Fatalf("Failed to migrate: %v", err)
+		}
+	}
+
+	if err := execAllDB(src,
+		`INSERT INTO funding_accounts (id, name) VALUES (3, 'Chequing')`,
+		`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due, autopay, autopay_funding_account_id)
+			VALUES (1, 'Visa', '1234', 15, 25, 'statement_balance', 3)`,
+		`INSERT INTO statements (card_id, statement_date, due_date, amount_cents, funding_account_id)
+			VALUES (1, '2024-11-15', '2024-12-10', 10000, 3)`,
+	); err != nil {
+		t.Fatalf("Failed to insert rows: %v", err)
+	}
+
+	if _, err := CopyData(context.Background(), src, dst); err != nil {
+		t.Fatalf("CopyData failed: %v", err)
+	}
+	var accountID int
+	if err := dst.QueryRow("SELECT autopay_funding_account_id FROM credit_cards WHERE id = 1").Scan(&accountID); err != nil || accountID != 3 {
+		t.Errorf("Expected the card's autopay account to be copied, got %d (%v)", accountID, err)
+	}
+}
+
+// execAllDB runs each statement against db
+func execAllDB(db *sql.DB, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCopyDataRequiresMigratedDatabases(t *testing.T) {
+	srcPath := "./test_copy_unmigrated_src.db"
+	dstPath := "./test_copy_unmigrated_dst.db"
+	defer os.Remove(srcPath)
+	defer os.Remove(dstPath)
+
+	src, _ := Open(srcPath)
+	defer src.Close()
+	dst, _ := Open(dstPath)
+	defer dst.Close()
+
+	if _, err := MigrateUp(src, 0, clock.Real{}); err != nil {
+		t.Fatalf("Failed to migrate source: %v", err)
+	}
+
+	if _, err := CopyData(context.Background(), src, dst); err == nil {
+		t.Error("Expected CopyData to refuse an unmigrated destination")
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Dialect identifies the SQL flavour spoken by a database connection
+type Dialect string
+
+const (
+	// SQLite is the default, file-based backend
+	SQLite Dialect = "sqlite"
+	// Postgres is selected by setting DATABASE_URL
+	Postgres Dialect = "postgres"
+)
+
+// DialectOf returns the dialect of db based on its driver. A nil db is
+// treated as SQLite.
+func DialectOf(db *sql.DB) Dialect {
+	if db == nil {
+		return SQLite
+	}
+	if _, ok := db.Driver().(*pq.Driver); ok {
+		return Postgres
+	}
+	return SQLite
+}
+
+// Rebind rewrites the ? placeholders in query into the dialect's bind
+// syntax. Question marks inside quoted strings are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM cards WHERE id = ?", "SELECT * FROM cards WHERE id = $1"},
+		{"UPDATE s SET a = ?, b = ? WHERE id = ?", "UPDATE s SET a = $1, b = $2 WHERE id = $3"},
+		{"SELECT '?' FROM t WHERE x = ?", "SELECT '?' FROM t WHERE x = $1"},
+		{`SELECT "odd?column" FROM t WHERE x = ?`, `SELECT "odd?column" FROM t WHERE x = $1`},
+	}
+
+	for _, tc := range testCases {
+		if got := Postgres.Rebind(tc.query); got != tc.expected {
+			t.Errorf("Rebind(%q): expected %q, got %q", tc.query, tc.expected, got)
+		}
+		if got := SQLite.Rebind(tc.query); got != tc.query {
+			t.Errorf("SQLite Rebind should not change %q, got %q", tc.query, got)
+		}
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	if DialectOf(nil) != SQLite {
+		t.Error("Expected nil db to be treated as SQLite")
+	}
+
+	tmpDB := "./test_dialect.db"
+	defer os.Remove(tmpDB)
+
+	db, err := Open(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if DialectOf(db) != SQLite {
+		t.Errorf("Expected SQLite dialect, got %s", DialectOf(db))
+	}
+}
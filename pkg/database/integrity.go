@@ -0,0 +1,204 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Orphan is a row whose foreign key points at a row that does not exist
+type Orphan struct {
+	Table  string `json:"table"`
+	RowID  int64  `json:"row_id"`
+	Parent string `json:"parent"`
+
+	// fk identifies the foreign key within the table's foreign_key_list
+	fk int
+}
+
+// IntegrityReport is the result of CheckIntegrity
+type IntegrityReport struct {
+	Orphans []Orphan `json:"orphans"`
+}
+
+// HasOrphans reports whether any orphaned rows were found
+func (r IntegrityReport) HasOrphans() bool {
+	return len(r.Orphans) > 0
+}
+
+// String summarises the orphans per table, e.g. "statements: 3 (card_id -> credit_cards)"
+func (r IntegrityReport) String() string {
+	if !r.HasOrphans() {
+		return "no orphaned rows"
+	}
+
+	counts := map[string]int{}
+	for _, o := range r.Orphans {
+		counts[o.Table+" -> "+o.Parent]++
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %d", key, counts[key]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CheckIntegrity verifies the database file and lists rows whose foreign keys
+// point at missing parents. Orphans can exist in databases written before
+// foreign keys were enforced.
+func CheckIntegrity(db *sql.DB) (IntegrityReport, error) {
+	var report IntegrityReport
+
+	var result string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&result); err != nil {
+		return report, fmt.Errorf("failed to run quick_check: %w", err)
+	}
+	if result != "ok" {
+		return report, fmt.Errorf("database is corrupt: %s", result)
+	}
+
+	rows, err := db.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return report, fmt.Errorf("failed to run foreign_key_check: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o Orphan
+		var rowID sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&o.Table, &rowID, &o.Parent, &fkid); err != nil {
+			return report, fmt.Errorf("failed to scan foreign_key_check row: %w", err)
+		}
+		o.RowID = rowID.Int64
+		o.fk = fkid
+		report.Orphans = append(report.Orphans, o)
+	}
+
+	return report, rows.Err()
+}
+
+// RepairOrphans fixes every row reported by CheckIntegrity. A reference
+// that may be empty and isn't ON DELETE CASCADE, such as a statement's
+// funding account, is set to NULL, as deleting the parent would have done;
+// other orphans are deleted, cascading as usual. It returns how many rows
+// were deleted and how many had references cleared.
+func RepairOrphans(db *sql.DB) (removed, cleared int, err error) {
+	report, err := CheckIntegrity(db)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin repair: %w", err)
+	}
+	defer tx.Rollback()
+
+	keys := map[string]map[int]*foreignKey{}
+	for _, o := range report.Orphans {
+		if keys[o.Table] == nil {
+			if keys[o.Table], err = foreignKeys(tx, o.Table); err != nil {
+				return 0, 0, err
+			}
+		}
+
+		// Table and column names come from SQLite itself, not user input
+		fk := keys[o.Table][o.fk]
+		if fk != nil && !fk.required() {
+			sets := make([]string, len(fk.columns))
+			for i, column := range fk.columns {
+				sets[i] = fmt.Sprintf("%q = NULL", column)
+			}
+			result, err := tx.Exec(fmt.Sprintf("UPDATE %q SET %s WHERE rowid = ?", o.Table, strings.Join(sets, ", ")), o.RowID)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to clear orphaned reference in %s: %w", o.Table, err)
+			}
+			n, _ := result.RowsAffected()
+			cleared += int(n)
+			continue
+		}
+
+		result, err := tx.Exec(fmt.Sprintf("DELETE FROM %q WHERE rowid = ?", o.Table), o.RowID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to delete orphan from %s: %w", o.Table, err)
+		}
+		n, _ := result.RowsAffected()
+		removed += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit repair: %w", err)
+	}
+	return removed, cleared, nil
+}
+
+// foreignKey is one foreign key of a table
+type foreignKey struct {
+	columns  []string
+	onDelete string
+	notNull  bool
+}
+
+// required reports whether rows can't exist without the parent, so an
+// orphan has to be deleted rather than have its reference cleared
+func (fk *foreignKey) required() bool {
+	return fk.onDelete == "CASCADE" || fk.notNull
+}
+
+// foreignKeys returns the foreign keys of table by their ID in
+// foreign_key_check
+func foreignKeys(tx *sql.Tx, table string) (map[int]*foreignKey, error) {
+	notNull := map[string]bool{}
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%q)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	for rows.Next() {
+		var cid, pk int
+		var name, typ string
+		var required bool
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &required, &dflt, &pk); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan columns of %s: %w", table, err)
+		}
+		notNull[name] = required
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(fmt.Sprintf("PRAGMA foreign_key_list(%q)", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	keys := map[int]*foreignKey{}
+	for rows.Next() {
+		var id, seq int
+		var parent, from, onUpdate, onDelete, match string
+		var to sql.NullString
+		if err := rows.Scan(&id, &seq, &parent, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign keys of %s: %w", table, err)
+		}
+		fk := keys[id]
+		if fk == nil {
+			fk = &foreignKey{onDelete: strings.ToUpper(onDelete)}
+			keys[id] = fk
+		}
+		fk.columns = append(fk.columns, from)
+		fk.notNull = fk.notNull || notNull[from]
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+)
+
+func TestOpenAppliesPragmas(t *testing.T) {
+	tmpDB := "./test_pragmas.db"
+	defer os.Remove(tmpDB)
+
+	db, err := Open(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var foreignKeys int
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		t.Fatalf("Failed to read foreign_keys: %v", err)
+	}
+	if foreignKeys != 1 {
+		t.Errorf("Expected foreign_keys to be on, got %d", foreignKeys)
+	}
+
+	var journalMode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("Failed to read journal_mode: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Errorf("Expected journal_mode wal, got %s", journalMode)
+	}
+
+	var busyTimeout int64
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		t.Fatalf("Failed to read busy_timeout: %v", err)
+	}
+	if busyTimeout != BusyTimeout.Milliseconds() {
+		t.Errorf("Expected busy_timeout %d, got %d", BusyTimeout.Milliseconds(), busyTimeout)
+	}
+}
+
+func TestDeleteCardCascades(t *testing.T) {
+	tmpDB := "./test_cascade.db"
+	defer os.Remove(tmpDB)
+
+	db, err := InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	db.Exec("INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due) VALUES (1, 'Test Card', '1234', 15, 25)")
+	db.Exec("INSERT INTO statements (card_id, statement_date, due_date, amount_cents) VALUES (1, '2024-11-15', '2024-12-10', 10000)")
+
+	if _, err := db.Exec("DELETE FROM credit_cards WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to delete card: %v", err)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM statements").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected statements to be deleted with their card, got %d", count)
+	}
+
+	// Inserting a statement for a missing card is rejected
+	_, err = db.Exec("INSERT INTO statements (card_id, statement_date, due_date, amount_cents) VALUES (42, '2024-11-15', '2024-12-10', 10000)")
+	if err == nil {
+		t.Error("Expected foreign key violation for a missing card")
+	}
+}
+
+// insertOrphans writes two statements for a card that does not exist, as
+// older databases without foreign keys allowed
+func insertOrphans(t *testing.T, db *sql.DB) {
+	db.Exec("INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due) VALUES (1, 'Test Card', '1234', 15, 25)")
+	db.Exec("INSERT INTO statements (card_id, statement_date, due_date, amount_cents) VALUES (1, '2024-11-15', '2024-12-10', 10000)")
+
+	if _, err := db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatalf("Failed to disable foreign keys: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err := db.Exec("INSERT INTO statements (card_id, statement_date, due_date, amount_cents) VALUES (99, '2024-11-15', '2024-12-10', 10000)")
+		if err != nil {
+			t.Fatalf("Failed to insert orphan: %v", err)
+		}
+	}
+	db.Exec("PRAGMA foreign_keys = ON")
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	tmpDB := "./test_integrity.db"
+	defer os.Remove(tmpDB)
+
+	db, err := InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	report, err := CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if report.HasOrphans() {
+		t.Errorf("Expected no orphans in a new database, got %s", report)
+	}
+
+	insertOrphans(t, db)
+
+	report, err = CheckIntegrity(db)
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if len(report.Orphans) != 2 {
+		t.Fatalf("Expected 2 orphans, got %d", len(report.Orphans))
+	}
+	if report.Orphans[0].Table != "statements" || report.Orphans[0].Parent != "credit_cards" {
+		t.Errorf("Unexpected orphan %+v", report.Orphans[0])
+	}
+	if report.String() != "statements -> credit_cards: 2" {
+		t.Errorf("Unexpected report summary %q", report.String())
+	}
+}
+
+func TestRepairOrphans(t *testing.T) {
+	tmpDB := "./test_repair.db"
+	defer os.Remove(tmpDB)
+
+	db, err := InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	insertOrphans(t, db)
+	// A statement whose funding account is missing is kept without one
+	db.Exec("PRAGMA foreign_keys = OFF")
+	if _, err := db.Exec("INSERT INTO statements (id, card_id, statement_date, due_date, amount_cents, funding_account_id) VALUES (50, 1, '2024-10-15', '2024-11-10', 5000, 77)"); err != nil {
+		t.Fatalf("Failed to insert orphan: %v", err)
+	}
+	db.Exec("PRAGMA foreign_keys = ON")
+	db.Close()
+
+	// Repair happens on startup when requested
+	os.Setenv("REPAIR_ORPHANS", "true")
+	defer os.Unsetenv("REPAIR_ORPHANS")
+
+	db, err = InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM statements").Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected only the valid statements to remain, got %d", count)
+	}
+	var fundingAccountID sql.NullInt64
+	if err := db.QueryRow("SELECT funding_account_id FROM statements WHERE id = 50").Scan(&fundingAccountID); err != nil {
+		t.Fatalf("Expected the statement with a missing funding account to be kept: %v", err)
+	}
+	if fundingAccountID.Valid {
+		t.Errorf("Expected the missing funding account to be cleared, got %d", fundingAccountID.Int64)
+	}
+
+	report, _ := CheckIntegrity(db)
+	if report.HasOrphans() {
+		t.Errorf("Expected no orphans after repair, got %s", report)
+	}
+}
@@ -0,0 +1,292 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+)
+
+// ErrSchemaTooNew is returned when the database has migrations applied that
+// this binary does not know about
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// Migration is a numbered schema change. Up and Down each run in their own
+// transaction together with the schema_migrations bookkeeping.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// migrationsFor returns the migration list written for the dialect
+func migrationsFor(d Dialect) []Migration {
+	if d == Postgres {
+		return postgresMigrations
+	}
+	return sqliteMigrations
+}
+
+// Migrations returns the migrations known to this binary for db, in order
+func Migrations(db *sql.DB) []Migration {
+	return append([]Migration{}, migrationsFor(DialectOf(db))...)
+}
+
+// LatestVersion returns the highest migration version known to this binary
+func LatestVersion() int {
+	if len(sqliteMigrations) == 0 {
+		return 0
+	}
+	return sqliteMigrations[len(sqliteMigrations)-1].Version
+}
+
+// ensureMigrationsTable creates the schema_migrations table if needed
+func ensureMigrationsTable(db *sql.DB) error {
+	timestampType := "DATETIME"
+	if DialectOf(db) == Postgres {
+		timestampType = "TIMESTAMPTZ"
+	}
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at ` + timestampType + ` NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations returns the applied versions and when they were applied
+func appliedMigrations(db *sql.DB) (map[int]time.Time, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// SchemaVersion returns the highest applied migration version
+func SchemaVersion(db *sql.DB) (int, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// CheckSchemaVersion returns ErrSchemaTooNew if the database has been
+// migrated by a newer binary
+func CheckSchemaVersion(db *sql.DB) error {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > LatestVersion() {
+		return fmt.Errorf("%w: database is at version %d, binary supports up to %d",
+			ErrSchemaTooNew, version, LatestVersion())
+	}
+	return nil
+}
+
+// Status reports every known migration and whether it has been applied
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := migrationsFor(DialectOf(db))
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies every pending migration up to and including target,
+// recording when each was applied with clk. A target of 0 applies all pending
+// migrations. It returns the number applied.
+func MigrateUp(db *sql.DB, target int, clk clock.Clock) (int, error) {
+	if err := CheckSchemaVersion(db); err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrationsFor(DialectOf(db)) {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		log.Printf("Running migration %d: %s", m.Version, m.Name)
+		if err := runMigration(db, m, true, clk); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown reverts the most recently applied migrations, newest first.
+// It returns the number reverted.
+func MigrateDown(db *sql.DB, steps int) (int, error) {
+	if err := CheckSchemaVersion(db); err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	migrations := migrationsFor(DialectOf(db))
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		log.Printf("Reverting migration %d: %s", m.Version, m.Name)
+		if err := runMigration(db, m, false, nil); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// runMigration applies or reverts a single migration in a transaction. clk
+// dates applied migrations and is unused when reverting.
+func runMigration(db *sql.DB, m Migration, up bool, clk clock.Clock) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.Version, err)
+	}
+	defer tx.Rollback()
+
+	if up {
+		if err := m.Up(tx); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec(DialectOf(db).Rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			m.Version, m.Name, clk.Now().UTC())
+	} else {
+		if m.Down == nil {
+			return fmt.Errorf("migration %d (%s) cannot be reverted", m.Version, m.Name)
+		}
+		if err := m.Down(tx); err != nil {
+			return fmt.Errorf("reverting migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec(DialectOf(db).Rebind("DELETE FROM schema_migrations WHERE version = ?"), m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// execAll runs each statement in order
+func execAll(tx *sql.Tx, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnExists reports whether table has a column named column
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	var count int
+	err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for %s.%s column: %w", table, column, err)
+	}
+	return count > 0, nil
+}
+
+// addColumnIfMissing adds a column unless it already exists
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// convertColumn replaces column with a new column described by definition,
+// filled from the expression. Nothing happens if column no longer exists.
+func convertColumn(tx *sql.Tx, table, column, definition, expression string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || !exists {
+		return err
+	}
+
+	var newColumn string
+	fmt.Sscanf(definition, "%s", &newColumn)
+
+	return execAll(tx,
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, definition),
+		fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NOT NULL", table, newColumn, expression, column),
+		fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column),
+	)
+}
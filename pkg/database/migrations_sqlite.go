@@ -0,0 +1,406 @@
+package database
+
+import "database/sql"
+
+// sqliteMigrations lists every schema change in order for SQLite. Versions
+// must be unique and increasing; never renumber or edit a migration once
+// released. postgresMigrations must mirror this list.
+//
+// Migrations 1-4 also bring databases created before schema_migrations
+// existed up to date, so they check for columns before changing them.
+var sqliteMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_cards_and_statements",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS credit_cards (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL,
+					last_four TEXT NOT NULL,
+					statement_day INTEGER NOT NULL,
+					days_until_due INTEGER NOT NULL,
+					credit_limit REAL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				)`,
+				`CREATE TABLE IF NOT EXISTS statements (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					card_id INTEGER NOT NULL,
+					statement_date TEXT NOT NULL,
+					due_date TEXT NOT NULL,
+					amount REAL NOT NULL,
+					status TEXT NOT NULL DEFAULT 'pending',
+					notified_statement BOOLEAN DEFAULT 0,
+					notified_payment BOOLEAN DEFAULT 0,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (card_id) REFERENCES credit_cards(id) ON DELETE CASCADE
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_statements_card_id ON statements(card_id)`,
+				`CREATE INDEX IF NOT EXISTS idx_statements_status ON statements(status)`,
+				`CREATE INDEX IF NOT EXISTS idx_statements_due_date ON statements(due_date)`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS statements`,
+				`DROP TABLE IF EXISTS credit_cards`,
+			)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_statement_review_columns",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "statements", "reviewed_at", "DATETIME"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "statements", "scheduled_payment_date", "TEXT")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`ALTER TABLE statements DROP COLUMN scheduled_payment_date`,
+				`ALTER TABLE statements DROP COLUMN reviewed_at`,
+			)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_scheduler_tables",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS statement_alerts (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					card_id INTEGER NOT NULL,
+					statement_date TEXT NOT NULL,
+					sent_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					UNIQUE (card_id, statement_date),
+					FOREIGN KEY (card_id) REFERENCES credit_cards(id) ON DELETE CASCADE
+				)`,
+				`CREATE TABLE IF NOT EXISTS scheduler_state (
+					key TEXT PRIMARY KEY,
+					value TEXT NOT NULL,
+					updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				)`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS scheduler_state`,
+				`DROP TABLE IF EXISTS statement_alerts`,
+			)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "money_to_integer_cents",
+		Up: func(tx *sql.Tx) error {
+			// Rounding to the nearest cent is exact for amounts entered with
+			// two decimal places
+			if err := convertColumn(tx, "statements", "amount", "amount_cents INTEGER NOT NULL DEFAULT 0",
+				"CAST(ROUND(amount * 100) AS INTEGER)"); err != nil {
+				return err
+			}
+			return convertColumn(tx, "credit_cards", "credit_limit", "credit_limit_cents INTEGER",
+				"CAST(ROUND(credit_limit * 100) AS INTEGER)")
+		},
+		Down: func(tx *sql.Tx) error {
+			if err := convertColumn(tx, "statements", "amount_cents", "amount REAL NOT NULL DEFAULT 0",
+				"amount_cents / 100.0"); err != nil {
+				return err
+			}
+			return convertColumn(tx, "credit_cards", "credit_limit_cents", "credit_limit REAL",
+				"credit_limit_cents / 100.0")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_statement_status_tracking",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "statements", "status_changed_at", "DATETIME"); err != nil {
+				return err
+			}
+			if err := addColumnIfMissing(tx, "statements", "status_changed_by", "TEXT"); err != nil {
+				return err
+			}
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS statement_status_changes (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					statement_id INTEGER NOT NULL,
+					from_status TEXT NOT NULL,
+					to_status TEXT NOT NULL,
+					changed_by TEXT NOT NULL,
+					changed_at DATETIME NOT NULL,
+					FOREIGN KEY (statement_id) REFERENCES statements(id) ON DELETE CASCADE
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_statement_status_changes_statement_id ON statement_status_changes(statement_id)`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS statement_status_changes`,
+				`ALTER TABLE statements DROP COLUMN status_changed_by`,
+				`ALTER TABLE statements DROP COLUMN status_changed_at`,
+			)
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_payments",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS payments (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					statement_id INTEGER NOT NULL,
+					amount_cents INTEGER NOT NULL,
+					payment_date TEXT NOT NULL,
+					source_account TEXT NOT NULL DEFAULT '',
+					confirmation_number TEXT NOT NULL DEFAULT '',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY (statement_id) REFERENCES statements(id) ON DELETE CASCADE
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_payments_statement_id ON payments(statement_id)`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS payments`)
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_statement_minimum_payment",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "statements", "minimum_payment_cents", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			if err := addColumnIfMissing(tx, "statements", "current_balance_cents", "INTEGER"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "statements", "notified_minimum", "BOOLEAN DEFAULT FALSE")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`ALTER TABLE statements DROP COLUMN notified_minimum`,
+				`ALTER TABLE statements DROP COLUMN current_balance_cents`,
+				`ALTER TABLE statements DROP COLUMN minimum_payment_cents`,
+			)
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add_card_statement_day_rule",
+		Up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "credit_cards", "statement_day_rule", "TEXT NOT NULL DEFAULT 'fixed'")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `ALTER TABLE credit_cards DROP COLUMN statement_day_rule`)
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create_holidays",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS holidays (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					date TEXT NOT NULL,
+					name TEXT NOT NULL,
+					source TEXT NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					UNIQUE (source, date, name)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_holidays_date ON holidays(date)`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS holidays`)
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add_card_payment_policy",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "credit_cards", "payment_policy", "TEXT NOT NULL DEFAULT 'lead_time'"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "credit_cards", "payment_lead_days", "INTEGER NOT NULL DEFAULT 5")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`ALTER TABLE credit_cards DROP COLUMN payment_lead_days`,
+				`ALTER TABLE credit_cards DROP COLUMN payment_policy`,
+			)
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create_income_schedules",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, `CREATE TABLE IF NOT EXISTS income_schedules (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				frequency TEXT NOT NULL,
+				anchor_date TEXT NOT NULL,
+				second_day INTEGER NOT NULL DEFAULT 0,
+				amount_cents INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS income_schedules`)
+		},
+	},
+	{
+		Version: 12,
+		Name:    "create_funding_accounts",
+		Up: func(tx *sql.Tx) error {
+			err := execAll(tx, `CREATE TABLE IF NOT EXISTS funding_accounts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				institution TEXT NOT NULL DEFAULT '',
+				last_four TEXT NOT NULL DEFAULT '',
+				balance_cents INTEGER,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`)
+			if err != nil {
+				return err
+			}
+			if err := addColumnIfMissing(tx, "statements", "funding_account_id", "INTEGER REFERENCES funding_accounts(id) ON DELETE SET NULL"); err != nil {
+				return err
+			}
+			return execAll(tx, `CREATE INDEX IF NOT EXISTS idx_statements_funding_account_id ON statements(funding_account_id)`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_statements_funding_account_id`,
+				`ALTER TABLE statements DROP COLUMN funding_account_id`,
+				`DROP TABLE IF EXISTS funding_accounts`,
+			)
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_card_autopay",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "credit_cards", "autopay", "TEXT NOT NULL DEFAULT 'none'"); err != nil {
+				return err
+			}
+			if err := addColumnIfMissing(tx, "credit_cards", "autopay_amount_cents", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "credit_cards", "autopay_funding_account_id", "INTEGER REFERENCES funding_accounts(id) ON DELETE SET NULL")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`ALTER TABLE credit_cards DROP COLUMN autopay_funding_account_id`,
+				`ALTER TABLE credit_cards DROP COLUMN autopay_amount_cents`,
+				`ALTER TABLE credit_cards DROP COLUMN autopay`,
+			)
+		},
+	},
+	{
+		Version: 14,
+		Name:    "create_ynab_transactions",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, `CREATE TABLE IF NOT EXISTS ynab_transactions (
+				statement_id INTEGER PRIMARY KEY REFERENCES statements(id) ON DELETE CASCADE,
+				scheduled_transaction_id TEXT NOT NULL DEFAULT '',
+				synced_at DATETIME,
+				last_error TEXT NOT NULL DEFAULT '',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS ynab_transactions`)
+		},
+	},
+	{
+		Version: 15,
+		Name:    "create_attachments",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS attachments (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					statement_id INTEGER REFERENCES statements(id) ON DELETE CASCADE,
+					filename TEXT NOT NULL DEFAULT '',
+					content_type TEXT NOT NULL DEFAULT '',
+					size_bytes INTEGER NOT NULL DEFAULT 0,
+					sha256 TEXT NOT NULL DEFAULT '',
+					data BLOB NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_attachments_statement_id ON attachments(statement_id)`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_attachments_statement_id`,
+				`DROP TABLE IF EXISTS attachments`,
+			)
+		},
+	},
+	{
+		Version: 16,
+		Name:    "create_statement_suggestions",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS statement_suggestions (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					status TEXT NOT NULL DEFAULT 'pending',
+					source TEXT NOT NULL DEFAULT '',
+					message_id TEXT NOT NULL DEFAULT '',
+					sender TEXT NOT NULL DEFAULT '',
+					subject TEXT NOT NULL DEFAULT '',
+					received_at DATETIME,
+					issuer TEXT NOT NULL DEFAULT '',
+					card_id INTEGER REFERENCES credit_cards(id) ON DELETE SET NULL,
+					last_four TEXT NOT NULL DEFAULT '',
+					statement_date TEXT NOT NULL DEFAULT '',
+					due_date TEXT NOT NULL DEFAULT '',
+					amount_cents INTEGER NOT NULL DEFAULT 0,
+					minimum_payment_cents INTEGER NOT NULL DEFAULT 0,
+					reason TEXT NOT NULL DEFAULT '',
+					statement_id INTEGER REFERENCES statements(id) ON DELETE SET NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_statement_suggestions_status ON statement_suggestions(status)`,
+				// Each email is only read once
+				`CREATE UNIQUE INDEX IF NOT EXISTS idx_statement_suggestions_message_id ON statement_suggestions(message_id) WHERE message_id <> ''`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_statement_suggestions_message_id`,
+				`DROP INDEX IF EXISTS idx_statement_suggestions_status`,
+				`DROP TABLE IF EXISTS statement_suggestions`,
+			)
+		},
+	},
+	{
+		Version: 17,
+		Name:    "create_expected_cycles",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS expected_cycles (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					card_id INTEGER NOT NULL,
+					statement_date TEXT NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					UNIQUE (card_id, statement_date),
+					FOREIGN KEY (card_id) REFERENCES credit_cards(id) ON DELETE CASCADE
+				)`,
+				// Placeholders added before cycles were recorded
+				`INSERT OR IGNORE INTO expected_cycles (card_id, statement_date, created_at)
+					SELECT card_id, statement_date, created_at FROM statements WHERE status = 'expected'`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, `DROP TABLE IF EXISTS expected_cycles`)
+		},
+	},
+}
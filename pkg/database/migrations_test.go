@@ -0,0 +1,233 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, m := range sqliteMigrations {
+		if m.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %d", i, i+1, m.Version)
+		}
+		if m.Name == "" || m.Up == nil || m.Down == nil {
+			t.Errorf("Migration %d is missing a name, Up or Down", m.Version)
+		}
+	}
+}
+
+func TestPostgresMigrationsMirrorSQLite(t *testing.T) {
+	if len(postgresMigrations) != len(sqliteMigrations) {
+		t.Fatalf("Expected %d Postgres migrations, got %d", len(sqliteMigrations), len(postgresMigrations))
+	}
+	for i, m := range postgresMigrations {
+		want := sqliteMigrations[i]
+		if m.Version != want.Version || m.Name != want.Name {
+			t.Errorf("Postgres migration %d (%s) does not match SQLite migration %d (%s)",
+				m.Version, m.Name, want.Version, want.Name)
+		}
+		if m.Up == nil || m.Down == nil {
+			t.Errorf("Postgres migration %d is missing Up or Down", m.Version)
+		}
+	}
+}
+
+func TestInitDBAppliesAllMigrations(t *testing.T) {
+	tmpDB := "./test_migrations_init.db"
+	defer os.Remove(tmpDB)
+
+	db, err := InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != LatestVersion() {
+		t.Errorf("Expected schema version %d, got %d", LatestVersion(), version)
+	}
+
+	statuses, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied || s.AppliedAt == nil {
+			t.Errorf("Expected migration %d (%s) to be applied", s.Version, s.Name)
+		}
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	tmpDB := "./test_migrations_down_up.db"
+	defer os.Remove(tmpDB)
+
+	db, err := InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit_cents)
+		VALUES ('Test Card', '1234', 15, 25, 500010)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents)
+		VALUES (1, '2024-11-15', '2024-12-10', 125075)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert statement: %v", err)
+	}
+
+	// Revert everything back to before the money migration
+	steps := LatestVersion() - 3
+	count, err := MigrateDown(db, steps)
+	if err != nil {
+		t.Fatalf("MigrateDown failed: %v", err)
+	}
+	if count != steps {
+		t.Errorf("Expected %d migrations reverted, got %d", steps, count)
+	}
+
+	var amount float64
+	if err := db.QueryRow("SELECT amount FROM statements WHERE id = 1").Scan(&amount); err != nil {
+		t.Fatalf("Expected REAL amount column after down migration: %v", err)
+	}
+	if amount != 1250.75 {
+		t.Errorf("Expected amount 1250.75, got %v", amount)
+	}
+
+	version, _ := SchemaVersion(db)
+	if version != 3 {
+		t.Errorf("Expected schema version 3, got %d", version)
+	}
+
+	// Apply them again
+	count, err = MigrateUp(db, 0, clock.Real{})
+	if err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	if count != steps {
+		t.Errorf("Expected %d migrations applied, got %d", steps, count)
+	}
+
+	var cents int64
+	if err := db.QueryRow("SELECT amount_cents FROM statements WHERE id = 1").Scan(&cents); err != nil {
+		t.Fatalf("Failed to query amount_cents: %v", err)
+	}
+	if cents != 125075 {
+		t.Errorf("Expected 125075 cents after round trip, got %d", cents)
+	}
+}
+
+func TestMigrateUpToTarget(t *testing.T) {
+	tmpDB := "./test_migrations_target.db"
+	defer os.Remove(tmpDB)
+
+	db, err := Open(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+	count, err := MigrateUp(db, 2, clock.NewFixed(now))
+	if err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 migrations applied, got %d", count)
+	}
+
+	version, _ := SchemaVersion(db)
+	if version != 2 {
+		t.Errorf("Expected schema version 2, got %d", version)
+	}
+
+	statuses, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range statuses[:2] {
+		if s.AppliedAt == nil || !s.AppliedAt.Equal(now) {
+			t.Errorf("Expected migration %d applied at %v from the clock, got %v", s.Version, now, s.AppliedAt)
+		}
+	}
+}
+
+func TestInitDBRefusesNewerSchema(t *testing.T) {
+	tmpDB := "./test_migrations_newer.db"
+	defer os.Remove(tmpDB)
+
+	db, err := InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from_the_future', CURRENT_TIMESTAMP)",
+		LatestVersion()+1)
+	if err != nil {
+		t.Fatalf("Failed to insert future migration: %v", err)
+	}
+	db.Close()
+
+	if _, err := InitDB(tmpDB, clock.Real{}); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	tmpDB := "./test_migrations_rollback.db"
+	defer os.Remove(tmpDB)
+
+	db, err := Open(tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Temporarily add a migration that fails halfway through
+	original := sqliteMigrations
+	defer func() { sqliteMigrations = original }()
+	sqliteMigrations = append(append([]Migration{}, original...), Migration{
+		Version: LatestVersion() + 1,
+		Name:    "broken",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"CREATE TABLE half_done (id INTEGER)",
+				"THIS IS NOT SQL",
+			)
+		},
+		Down: func(tx *sql.Tx) error { return nil },
+	})
+
+	count, err := MigrateUp(db, 0, clock.Real{})
+	if err == nil {
+		t.Fatal("Expected broken migration to fail")
+	}
+	if count != len(original) {
+		t.Errorf("Expected %d migrations applied before the failure, got %d", len(original), count)
+	}
+
+	var tables int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='half_done'").Scan(&tables)
+	if tables != 0 {
+		t.Error("Expected partial migration to be rolled back")
+	}
+
+	version, _ := SchemaVersion(db)
+	if version != len(original) {
+		t.Errorf("Expected schema version %d, got %d", len(original), version)
+	}
+}
@@ -0,0 +1,72 @@
+// Package pgtest connects integration tests to a Postgres server named by
+// TEST_DATABASE_URL. Each test gets its own schema, dropped when it ends.
+// Tests are skipped when TEST_DATABASE_URL is not set.
+package pgtest
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+)
+
+// EnvVar names the Postgres server used by integration tests
+const EnvVar = "TEST_DATABASE_URL"
+
+// Open returns a connection whose search_path is a fresh, empty schema
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(EnvVar)
+	if dsn == "" {
+		t.Skipf("%s not set; skipping Postgres integration test", EnvVar)
+	}
+
+	admin, err := database.OpenPostgres(dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+
+	schema := fmt.Sprintf("tracker_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatalf("Failed to create schema %s: %v", schema, err)
+	}
+
+	db, err := database.OpenPostgres(withSearchPath(dsn, schema))
+	if err != nil {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+		t.Fatalf("Failed to connect to schema %s: %v", schema, err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Logf("Failed to drop schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	return db
+}
+
+// withSearchPath adds a search_path run-time parameter to a URL or
+// key=value connection string
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/lib/pq"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+)
+
+// OpenPostgres connects to the Postgres database at url without changing
+// its schema
+func OpenPostgres(url string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// InitPostgres connects to Postgres and applies pending migrations like
+// InitDB. The caller closes the returned connection.
+func InitPostgres(url string, clk clock.Clock) (*sql.DB, error) {
+	db, err := OpenPostgres(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := setup(db, clk); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,128 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database/pgtest"
+)
+
+func TestPostgresMigrations(t *testing.T) {
+	db := pgtest.Open(t)
+
+	if database.DialectOf(db) != database.Postgres {
+		t.Fatalf("Expected Postgres dialect, got %s", database.DialectOf(db))
+	}
+
+	count, err := database.MigrateUp(db, 0, clock.Real{})
+	if err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	if count != database.LatestVersion() {
+		t.Errorf("Expected %d migrations applied, got %d", database.LatestVersion(), count)
+	}
+
+	if _, err := db.Exec("INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit_cents) VALUES ('Test Card', '1234', 15, 25, 500010)"); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO statements (card_id, statement_date, due_date, amount_cents) VALUES (1, '2024-11-15', '2024-12-10', 125075)"); err != nil {
+		t.Fatalf("Failed to insert statement: %v", err)
+	}
+
+	// Round trip the money migration, reverting everything after it too
+	steps := database.LatestVersion() - 3
+	if count, err := database.MigrateDown(db, steps); err != nil || count != steps {
+		t.Fatalf("Expected %d migrations reverted, got %d: %v", steps, count, err)
+	}
+	if version, _ := database.SchemaVersion(db); version != 3 {
+		t.Errorf("Expected schema version 3, got %d", version)
+	}
+	var amount float64
+	if err := db.QueryRow("SELECT amount FROM statements WHERE id = 1").Scan(&amount); err != nil {
+		t.Fatalf("Expected amount column after down migration: %v", err)
+	}
+	if amount != 1250.75 {
+		t.Errorf("Expected amount 1250.75, got %v", amount)
+	}
+
+	if _, err := database.MigrateUp(db, 0, clock.Real{}); err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	var cents int64
+	db.QueryRow("SELECT amount_cents FROM statements WHERE id = 1").Scan(&cents)
+	if cents != 125075 {
+		t.Errorf("Expected 125075 cents after round trip, got %d", cents)
+	}
+
+	// Foreign keys cascade
+	if _, err := db.Exec("DELETE FROM credit_cards WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to delete card: %v", err)
+	}
+	var remaining int
+	db.QueryRow("SELECT COUNT(*) FROM statements").Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected statements to cascade, got %d", remaining)
+	}
+
+	// Everything can be reverted
+	if _, err := database.MigrateDown(db, database.LatestVersion()); err != nil {
+		t.Fatalf("MigrateDown to zero failed: %v", err)
+	}
+	version, _ := database.SchemaVersion(db)
+	if version != 0 {
+		t.Errorf("Expected schema version 0, got %d", version)
+	}
+}
+
+func TestPostgresCopyData(t *testing.T) {
+	dst := pgtest.Open(t)
+
+	srcPath := "./test_pg_copy_src.db"
+	defer os.Remove(srcPath)
+	src, err := database.Open(srcPath)
+	if err != nil {
+		t.Fatalf("Failed to open source: %v", err)
+	}
+	defer src.Close()
+
+	if _, err := database.MigrateUp(src, 0, clock.Real{}); err != nil {
+		t.Fatalf("Failed to migrate source: %v", err)
+	}
+	if _, err := database.MigrateUp(dst, 0, clock.Real{}); err != nil {
+		t.Fatalf("Failed to migrate destination: %v", err)
+	}
+
+	frozen := clock.NewFixed(time.Date(2024, time.November, 20, 12, 0, 0, 0, time.UTC))
+	if err := database.LoadSampleData(src, frozen); err != nil {
+		t.Fatalf("Failed to load sample data: %v", err)
+	}
+
+	if _, err := database.CopyData(context.Background(), src, dst); err != nil {
+		t.Fatalf("CopyData failed: %v", err)
+	}
+
+	var cards, statements, paid int
+	dst.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&cards)
+	dst.QueryRow("SELECT COUNT(*) FROM statements").Scan(&statements)
+	dst.QueryRow("SELECT COUNT(*) FROM statements WHERE notified_payment = TRUE").Scan(&paid)
+	if cards != 6 || statements != 9 {
+		t.Errorf("Expected 6 cards and 9 statements, got %d and %d", cards, statements)
+	}
+	if paid == 0 {
+		t.Error("Expected boolean flags to be copied")
+	}
+
+	// New rows continue after the copied IDs
+	var id int
+	err = dst.QueryRow("INSERT INTO credit_cards (name, last_four, statement_day, days_until_due) VALUES ('New Card', '0000', 1, 21) RETURNING id").Scan(&id)
+	if err != nil {
+		t.Fatalf("Failed to insert card after copy: %v", err)
+	}
+	if id != 7 {
+		t.Errorf("Expected new card ID 7, got %d", id)
+	}
+}
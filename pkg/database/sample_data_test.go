@@ -3,6 +3,10 @@ package database
 import (
 	"os"
 	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
 )
 
 func TestLoadSampleData(t *testing.T) {
@@ -11,21 +15,21 @@ func TestLoadSampleData(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Initialize database
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Load sample data
-	err = LoadSampleData(DB)
+	err = LoadSampleData(db, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to load sample data: %v", err)
 	}
 
 	// Verify credit cards were inserted
 	var cardCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&cardCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&cardCount)
 	if err != nil {
 		t.Fatalf("Failed to count credit cards: %v", err)
 	}
@@ -37,10 +41,10 @@ func TestLoadSampleData(t *testing.T) {
 	// Verify TD Aeroplan Visa card details
 	var name, lastFour string
 	var statementDay, daysUntilDue int
-	var creditLimit float64
+	var creditLimit models.Money
 
-	err = DB.QueryRow(`
-		SELECT name, last_four, statement_day, days_until_due, credit_limit
+	err = db.QueryRow(`
+		SELECT name, last_four, statement_day, days_until_due, credit_limit_cents
 		FROM credit_cards
 		WHERE name = 'TD Aeroplan Visa'
 	`).Scan(&name, &lastFour, &statementDay, &daysUntilDue, &creditLimit)
@@ -58,13 +62,13 @@ func TestLoadSampleData(t *testing.T) {
 	if daysUntilDue != 25 {
 		t.Errorf("Expected days_until_due 25, got %d", daysUntilDue)
 	}
-	if creditLimit != 5000.00 {
-		t.Errorf("Expected credit_limit 5000.00, got %.2f", creditLimit)
+	if creditLimit != models.MustParseMoney("5000.00") {
+		t.Errorf("Expected credit_limit 5000.00, got %s", creditLimit)
 	}
 
 	// Verify Amex Cobalt card details
-	err = DB.QueryRow(`
-		SELECT name, last_four, statement_day, days_until_due, credit_limit
+	err = db.QueryRow(`
+		SELECT name, last_four, statement_day, days_until_due, credit_limit_cents
 		FROM credit_cards
 		WHERE name = 'Amex Cobalt'
 	`).Scan(&name, &lastFour, &statementDay, &daysUntilDue, &creditLimit)
@@ -82,8 +86,8 @@ func TestLoadSampleData(t *testing.T) {
 	if daysUntilDue != 25 {
 		t.Errorf("Expected days_until_due 25, got %d", daysUntilDue)
 	}
-	if creditLimit != 10000.00 {
-		t.Errorf("Expected credit_limit 10000.00, got %.2f", creditLimit)
+	if creditLimit != models.MustParseMoney("10000.00") {
+		t.Errorf("Expected credit_limit 10000.00, got %s", creditLimit)
 	}
 }
 
@@ -93,21 +97,21 @@ func TestLoadSampleDataStatements(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Initialize database
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Load sample data
-	err = LoadSampleData(DB)
+	err = LoadSampleData(db, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to load sample data: %v", err)
 	}
 
 	// Verify statements were inserted
 	var stmtCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM statements").Scan(&stmtCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM statements").Scan(&stmtCount)
 	if err != nil {
 		t.Fatalf("Failed to count statements: %v", err)
 	}
@@ -118,7 +122,7 @@ func TestLoadSampleDataStatements(t *testing.T) {
 
 	// Verify at least one pending statement exists
 	var pendingCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM statements WHERE status = 'pending'").Scan(&pendingCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM statements WHERE status = 'pending'").Scan(&pendingCount)
 	if err != nil {
 		t.Fatalf("Failed to count pending statements: %v", err)
 	}
@@ -129,7 +133,7 @@ func TestLoadSampleDataStatements(t *testing.T) {
 
 	// Verify at least one paid statement exists
 	var paidCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM statements WHERE status = 'paid'").Scan(&paidCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM statements WHERE status = 'paid'").Scan(&paidCount)
 	if err != nil {
 		t.Fatalf("Failed to count paid statements: %v", err)
 	}
@@ -145,34 +149,34 @@ func TestLoadSampleDataIdempotency(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Initialize database
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Load sample data first time
-	err = LoadSampleData(DB)
+	err = LoadSampleData(db, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to load sample data first time: %v", err)
 	}
 
 	// Count cards after first load
 	var firstCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&firstCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&firstCount)
 	if err != nil {
 		t.Fatalf("Failed to count credit cards: %v", err)
 	}
 
 	// Load sample data second time
-	err = LoadSampleData(DB)
+	err = LoadSampleData(db, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to load sample data second time: %v", err)
 	}
 
 	// Count cards after second load
 	var secondCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&secondCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&secondCount)
 	if err != nil {
 		t.Fatalf("Failed to count credit cards: %v", err)
 	}
@@ -189,21 +193,21 @@ func TestLoadSampleDataForeignKeys(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Initialize database
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Load sample data
-	err = LoadSampleData(DB)
+	err = LoadSampleData(db, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to load sample data: %v", err)
 	}
 
 	// Verify all statements have valid card_id references
 	var invalidCount int
-	err = DB.QueryRow(`
+	err = db.QueryRow(`
 		SELECT COUNT(*)
 		FROM statements s
 		LEFT JOIN credit_cards c ON s.card_id = c.id
@@ -225,21 +229,21 @@ func TestLoadSampleDataAmounts(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Initialize database
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Load sample data
-	err = LoadSampleData(DB)
+	err = LoadSampleData(db, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to load sample data: %v", err)
 	}
 
 	// Verify all statement amounts are positive
 	var negativeCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM statements WHERE amount <= 0").Scan(&negativeCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM statements WHERE amount_cents <= 0").Scan(&negativeCount)
 	if err != nil {
 		t.Fatalf("Failed to check statement amounts: %v", err)
 	}
@@ -250,7 +254,7 @@ func TestLoadSampleDataAmounts(t *testing.T) {
 
 	// Verify statement amounts are realistic (between $1 and $100,000)
 	var unrealisticCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM statements WHERE amount < 1 OR amount > 100000").Scan(&unrealisticCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM statements WHERE amount_cents < 100 OR amount_cents > 10000000").Scan(&unrealisticCount)
 	if err != nil {
 		t.Fatalf("Failed to check realistic amounts: %v", err)
 	}
@@ -259,3 +263,40 @@ func TestLoadSampleDataAmounts(t *testing.T) {
 		t.Errorf("Found %d statements with unrealistic amounts", unrealisticCount)
 	}
 }
+
+func TestLoadSampleDataFixedClock(t *testing.T) {
+	// Create a temporary database file
+	tmpDB := "./test_sample_data_clock.db"
+	defer os.Remove(tmpDB)
+
+	db, err := InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Freeze the clock at the end of a leap February
+	clk := clock.NewFixed(time.Date(2024, time.February, 28, 12, 0, 0, 0, time.UTC))
+	if err := LoadSampleData(db, clk); err != nil {
+		t.Fatalf("Failed to load sample data: %v", err)
+	}
+
+	// Sample statement dates are relative to the frozen clock
+	var statementDate, dueDate string
+	err = db.QueryRow(`
+		SELECT s.statement_date, s.due_date
+		FROM statements s
+		JOIN credit_cards c ON c.id = s.card_id
+		WHERE c.name = 'Amex Cobalt' AND s.status = 'pending'
+	`).Scan(&statementDate, &dueDate)
+	if err != nil {
+		t.Fatalf("Failed to query Amex Cobalt statement: %v", err)
+	}
+
+	if statementDate != "2024-02-28" {
+		t.Errorf("Expected statement_date 2024-02-28, got %s", statementDate)
+	}
+	if dueDate != "2024-03-23" {
+		t.Errorf("Expected due_date 2024-03-23, got %s", dueDate)
+	}
+}
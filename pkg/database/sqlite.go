@@ -4,149 +4,109 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"time"
 
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
 	_ "modernc.org/sqlite"
 )
 
-// DB holds the database connection
-var DB *sql.DB
+// BusyTimeout is how long a connection waits for a lock before failing with SQLITE_BUSY
+const BusyTimeout = 5 * time.Second
 
-// InitDB initializes the SQLite database and creates tables
-func InitDB(dbPath string) error {
-	var err error
-	DB, err = sql.Open("sqlite", dbPath)
+// connectionPragmas are applied by the driver to every new connection, so they
+// hold even if the pool reconnects
+var connectionPragmas = []string{
+	"foreign_keys(1)",
+	"journal_mode(WAL)",
+	fmt.Sprintf("busy_timeout(%d)", BusyTimeout.Milliseconds()),
+}
+
+// dataSourceName appends the connection pragmas to dbPath
+func dataSourceName(dbPath string) string {
+	params := url.Values{}
+	for _, pragma := range connectionPragmas {
+		params.Add("_pragma", pragma)
+	}
+	return dbPath + "?" + params.Encode()
+}
+
+// Open opens the SQLite database at dbPath without changing its schema
+func Open(dbPath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dataSourceName(dbPath))
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Set connection pool settings
-	DB.SetMaxOpenConns(1) // SQLite works best with single connection
-	DB.SetMaxIdleConns(1)
+	db.SetMaxOpenConns(1) // SQLite works best with single connection
+	db.SetMaxIdleConns(1)
 
 	// Test the connection
-	if err := DB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Create tables
-	if err := createTables(); err != nil {
-		return fmt.Errorf("failed to create tables: %w", err)
-	}
-
-	// Load sample data if environment variable is set
-	if os.Getenv("LOAD_SAMPLE_DATA") == "true" {
-		if err := LoadSampleData(DB); err != nil {
-			return fmt.Errorf("failed to load sample data: %w", err)
-		}
-	}
-
-	log.Println("Database initialized successfully")
-	return nil
+	return db, nil
 }
 
-// createTables creates the necessary database tables
-func createTables() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS credit_cards (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		last_four TEXT NOT NULL,
-		statement_day INTEGER NOT NULL,
-		days_until_due INTEGER NOT NULL,
-		credit_limit REAL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS statements (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		card_id INTEGER NOT NULL,
-		statement_date TEXT NOT NULL,
-		due_date TEXT NOT NULL,
-		amount REAL NOT NULL,
-		status TEXT NOT NULL DEFAULT 'pending',
-		notified_statement BOOLEAN DEFAULT 0,
-		notified_payment BOOLEAN DEFAULT 0,
-		reviewed_at DATETIME,
-		scheduled_payment_date TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (card_id) REFERENCES credit_cards(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_statements_card_id ON statements(card_id);
-	CREATE INDEX IF NOT EXISTS idx_statements_status ON statements(status);
-	CREATE INDEX IF NOT EXISTS idx_statements_due_date ON statements(due_date);
-	`
-
-	_, err := DB.Exec(schema)
+// InitDB opens the SQLite database at dbPath and applies pending
+// migrations, recording them and dating any sample data with clk. The caller
+// closes the returned connection.
+func InitDB(dbPath string, clk clock.Clock) (*sql.DB, error) {
+	db, err := Open(dbPath)
 	if err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
+		return nil, err
 	}
-
-	// Run migrations to add new columns to existing databases
-	if err := runMigrations(); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	if err := setup(db, clk); err != nil {
+		db.Close()
+		return nil, err
 	}
-
-	return nil
+	return db, nil
 }
 
-// runMigrations applies database schema migrations
-func runMigrations() error {
-	// Check if reviewed_at column exists
-	var reviewedAtExists bool
-	row := DB.QueryRow(`
-		SELECT COUNT(*)
-		FROM pragma_table_info('statements')
-		WHERE name='reviewed_at'
-	`)
-	var count int
-	if err := row.Scan(&count); err != nil {
-		return fmt.Errorf("failed to check for reviewed_at column: %w", err)
+// setup migrates a freshly opened database and optionally loads sample data
+func setup(db *sql.DB, clk clock.Clock) error {
+	// Refuse to touch a database migrated by a newer binary
+	if err := CheckSchemaVersion(db); err != nil {
+		return err
 	}
-	reviewedAtExists = count > 0
 
-	// Check if scheduled_payment_date column exists
-	var scheduledPaymentDateExists bool
-	row = DB.QueryRow(`
-		SELECT COUNT(*)
-		FROM pragma_table_info('statements')
-		WHERE name='scheduled_payment_date'
-	`)
-	if err := row.Scan(&count); err != nil {
-		return fmt.Errorf("failed to check for scheduled_payment_date column: %w", err)
+	// Apply pending migrations
+	if _, err := MigrateUp(db, 0, clk); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
 	}
-	scheduledPaymentDateExists = count > 0
 
-	// Add reviewed_at column if it doesn't exist
-	if !reviewedAtExists {
-		log.Println("Running migration: adding reviewed_at column to statements table")
-		_, err := DB.Exec("ALTER TABLE statements ADD COLUMN reviewed_at DATETIME")
+	// Report (and optionally remove) rows left behind while foreign keys were
+	// off. Postgres always enforced them.
+	if DialectOf(db) == SQLite {
+		report, err := CheckIntegrity(db)
 		if err != nil {
-			return fmt.Errorf("failed to add reviewed_at column: %w", err)
+			return fmt.Errorf("failed to check database integrity: %w", err)
+		}
+		if report.HasOrphans() {
+			log.Printf("Integrity check found orphaned rows: %s", report)
+			if os.Getenv("REPAIR_ORPHANS") == "true" {
+				removed, cleared, err := RepairOrphans(db)
+				if err != nil {
+					return fmt.Errorf("failed to repair orphaned rows: %w", err)
+				}
+				log.Printf("Removed %d orphaned row(s) and cleared %d missing reference(s)", removed, cleared)
+			} else {
+				log.Println("Set REPAIR_ORPHANS=true to repair them on startup")
+			}
 		}
-		log.Println("Migration completed: reviewed_at column added")
 	}
 
-	// Add scheduled_payment_date column if it doesn't exist
-	if !scheduledPaymentDateExists {
-		log.Println("Running migration: adding scheduled_payment_date column to statements table")
-		_, err := DB.Exec("ALTER TABLE statements ADD COLUMN scheduled_payment_date TEXT")
-		if err != nil {
-			return fmt.Errorf("failed to add scheduled_payment_date column: %w", err)
+	// Load sample data if environment variable is set
+	if os.Getenv("LOAD_SAMPLE_DATA") == "true" {
+		if err := LoadSampleData(db, clk); err != nil {
+			return fmt.Errorf("failed to load sample data: %w", err)
 		}
-		log.Println("Migration completed: scheduled_payment_date column added")
 	}
 
-	return nil
-}
-
-// Close closes the database connection
-func Close() error {
-	if DB != nil {
-		return DB.Close()
-	}
+	log.Println("Database initialized successfully")
 	return nil
 }
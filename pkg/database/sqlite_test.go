@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"os"
 	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
 )
 
 func TestInitDB(t *testing.T) {
@@ -12,24 +14,24 @@ func TestInitDB(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Test database initialization
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
 
-	// Verify DB is not nil
-	if DB == nil {
-		t.Fatal("DB should not be nil after initialization")
+	// Verify db is not nil
+	if db == nil {
+		t.Fatal("db should not be nil after initialization")
 	}
 
 	// Test connection with ping
-	err = DB.Ping()
+	err = db.Ping()
 	if err != nil {
 		t.Fatalf("Failed to ping database: %v", err)
 	}
 
 	// Close the database
-	err = Close()
+	err = db.Close()
 	if err != nil {
 		t.Fatalf("Failed to close database: %v", err)
 	}
@@ -41,15 +43,15 @@ func TestCreateTables(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Initialize database
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Verify credit_cards table exists
 	var tableName string
-	err = DB.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='credit_cards'").Scan(&tableName)
+	err = db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='credit_cards'").Scan(&tableName)
 	if err != nil {
 		t.Fatalf("credit_cards table not found: %v", err)
 	}
@@ -58,7 +60,7 @@ func TestCreateTables(t *testing.T) {
 	}
 
 	// Verify statements table exists
-	err = DB.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='statements'").Scan(&tableName)
+	err = db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='statements'").Scan(&tableName)
 	if err != nil {
 		t.Fatalf("statements table not found: %v", err)
 	}
@@ -66,6 +68,14 @@ func TestCreateTables(t *testing.T) {
 		t.Errorf("Expected table name 'statements', got '%s'", tableName)
 	}
 
+	// Verify scheduler tables exist
+	for _, table := range []string{"statement_alerts", "scheduler_state"} {
+		err = db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name=?", table).Scan(&tableName)
+		if err != nil {
+			t.Errorf("%s table not found: %v", table, err)
+		}
+	}
+
 	// Verify indexes exist
 	indexes := []string{
 		"idx_statements_card_id",
@@ -75,7 +85,7 @@ func TestCreateTables(t *testing.T) {
 
 	for _, indexName := range indexes {
 		var name string
-		err = DB.QueryRow("SELECT name FROM sqlite_master WHERE type='index' AND name=?", indexName).Scan(&name)
+		err = db.QueryRow("SELECT name FROM sqlite_master WHERE type='index' AND name=?", indexName).Scan(&name)
 		if err != nil {
 			t.Errorf("Index %s not found: %v", indexName, err)
 		}
@@ -88,28 +98,28 @@ func TestDatabaseSchema(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Initialize database
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Test credit_cards table schema
-	rows, err := DB.Query("PRAGMA table_info(credit_cards)")
+	rows, err := db.Query("PRAGMA table_info(credit_cards)")
 	if err != nil {
 		t.Fatalf("Failed to get credit_cards schema: %v", err)
 	}
 	defer rows.Close()
 
 	expectedColumns := map[string]bool{
-		"id":             false,
-		"name":           false,
-		"last_four":      false,
-		"statement_day":  false,
-		"days_until_due": false,
-		"credit_limit":   false,
-		"created_at":     false,
-		"updated_at":     false,
+		"id":                 false,
+		"name":               false,
+		"last_four":          false,
+		"statement_day":      false,
+		"days_until_due":     false,
+		"credit_limit_cents": false,
+		"created_at":         false,
+		"updated_at":         false,
 	}
 
 	for rows.Next() {
@@ -142,27 +152,27 @@ func TestDatabaseReopening(t *testing.T) {
 	defer os.Remove(tmpDB)
 
 	// Initialize database first time
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database first time: %v", err)
 	}
 
 	// Close the database
-	err = Close()
+	err = db.Close()
 	if err != nil {
 		t.Fatalf("Failed to close database: %v", err)
 	}
 
 	// Reopen the database
-	err = InitDB(tmpDB)
+	db, err = InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to reopen database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Verify tables still exist
 	var tableName string
-	err = DB.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='credit_cards'").Scan(&tableName)
+	err = db.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name='credit_cards'").Scan(&tableName)
 	if err != nil {
 		t.Fatalf("credit_cards table not found after reopening: %v", err)
 	}
@@ -178,15 +188,15 @@ func TestLoadSampleDataEnvironmentVariable(t *testing.T) {
 	defer os.Unsetenv("LOAD_SAMPLE_DATA")
 
 	// Initialize database
-	err := InitDB(tmpDB)
+	db, err := InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer Close()
+	defer db.Close()
 
 	// Verify sample data was loaded
 	var count int
-	err = DB.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&count)
+	err = db.QueryRow("SELECT COUNT(*) FROM credit_cards").Scan(&count)
 	if err != nil {
 		t.Fatalf("Failed to count credit cards: %v", err)
 	}
@@ -195,3 +205,97 @@ func TestLoadSampleDataEnvironmentVariable(t *testing.T) {
 		t.Errorf("Expected at least 6 credit cards, got %d", count)
 	}
 }
+
+func TestMigrateMoneyColumns(t *testing.T) {
+	// Create a temporary database file
+	tmpDB := "./test_money_migration.db"
+	defer os.Remove(tmpDB)
+
+	// Build a database with the old REAL money columns
+	db, err := sql.Open("sqlite", tmpDB)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE credit_cards (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			last_four TEXT NOT NULL,
+			statement_day INTEGER NOT NULL,
+			days_until_due INTEGER NOT NULL,
+			credit_limit REAL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE statements (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			card_id INTEGER NOT NULL,
+			statement_date TEXT NOT NULL,
+			due_date TEXT NOT NULL,
+			amount REAL NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			notified_statement BOOLEAN DEFAULT 0,
+			notified_payment BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (card_id) REFERENCES credit_cards(id) ON DELETE CASCADE
+		);
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit)
+		VALUES ('With Limit', '1111', 15, 25, 5000.10), ('No Limit', '2222', 1, 21, NULL);
+		INSERT INTO statements (card_id, statement_date, due_date, amount)
+		VALUES (1, '2024-11-15', '2024-12-10', 0.29), (1, '2024-10-15', '2024-11-09', 1250.75),
+		       (2, '2024-11-01', '2024-11-22', 4567.89);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+	db.Close()
+
+	// InitDB migrates the existing database
+	db, err = InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT amount_cents FROM statements ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to query migrated amounts: %v", err)
+	}
+	defer rows.Close()
+
+	expected := []int64{29, 125075, 456789}
+	var got []int64
+	for rows.Next() {
+		var cents int64
+		if err := rows.Scan(&cents); err != nil {
+			t.Fatalf("Failed to scan amount: %v", err)
+		}
+		got = append(got, cents)
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d statements, got %d", len(expected), len(got))
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Statement %d: expected %d cents, got %d", i+1, expected[i], got[i])
+		}
+	}
+
+	var withLimit, noLimit sql.NullInt64
+	db.QueryRow("SELECT credit_limit_cents FROM credit_cards WHERE id = 1").Scan(&withLimit)
+	db.QueryRow("SELECT credit_limit_cents FROM credit_cards WHERE id = 2").Scan(&noLimit)
+	if !withLimit.Valid || withLimit.Int64 != 500010 {
+		t.Errorf("Expected credit limit 500010 cents, got %v", withLimit)
+	}
+	if noLimit.Valid {
+		t.Errorf("Expected NULL credit limit to stay NULL, got %v", noLimit)
+	}
+
+	// The old REAL columns are gone
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('statements') WHERE name = 'amount'").Scan(&count)
+	if count != 0 {
+		t.Error("Expected statements.amount column to be dropped")
+	}
+}
@@ -0,0 +1,223 @@
+// Package extract reads the details of a statement, such as its new balance
+// and due date, out of the text of an issuer's statement. Each issuer has a
+// profile of patterns for the way it words them; text from other issuers is
+// read with generic patterns.
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// The fields a Proposal lists as Missing
+const (
+	FieldLastFour       = "last_four"
+	FieldStatementDate  = "statement_date"
+	FieldDueDate        = "due_date"
+	FieldNewBalance     = "new_balance"
+	FieldMinimumPayment = "minimum_payment"
+)
+
+// Proposal is what a statement's text says about it, to be confirmed
+// before it is stored
+type Proposal struct {
+	// Issuer is the profile that read the text, or empty if none matched
+	Issuer         string       `json:"issuer,omitempty"`
+	LastFour       string       `json:"last_four,omitempty"`
+	StatementDate  string       `json:"statement_date,omitempty"`
+	DueDate        string       `json:"due_date,omitempty"`
+	NewBalance     models.Money `json:"new_balance"`
+	MinimumPayment models.Money `json:"minimum_payment"`
+	// Missing lists the fields that couldn't be found
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Profile is how one issuer words its statements. Each field pattern has
+// one capture group for the value. A field's patterns are tried in order,
+// followed by the generic ones, and the first value that parses is used.
+type Profile struct {
+	Issuer string
+	// Detect matches text written by this issuer
+	Detect         *regexp.Regexp
+	LastFour       []*regexp.Regexp
+	StatementDate  []*regexp.Regexp
+	DueDate        []*regexp.Regexp
+	NewBalance     []*regexp.Regexp
+	MinimumPayment []*regexp.Regexp
+}
+
+// Value patterns substituted for {date} and {amount} in field patterns
+const (
+	datePattern   = `(?:[a-z]{3,9}\.?\s+\d{1,2},?\s+\d{4}|\d{1,2}\s+[a-z]{3,9}\.?,?\s+\d{4}|\d{1,2}/\d{1,2}/(?:\d{4}|\d{2})|\d{4}-\d{2}-\d{2})`
+	amountPattern = `-?\$?\s?-?\d[\d,]*\.\d{2}`
+)
+
+// Patterns compiles case-insensitive field patterns, replacing {date} and
+// {amount} with what dates and amounts look like
+func Patterns(exprs ...string) []*regexp.Regexp {
+	list := make([]*regexp.Regexp, len(exprs))
+	for i, expr := range exprs {
+		expr = strings.NewReplacer("{date}", datePattern, "{amount}", amountPattern).Replace(expr)
+		list[i] = regexp.MustCompile("(?i)" + expr)
+	}
+	return list
+}
+
+// Profiles are the issuers with their own patterns, covering the cards in
+// the sample data
+var Profiles = []Profile{
+	{
+		Issuer:        "TD",
+		Detect:        regexp.MustCompile(`(?i)\bTD Canada Trust\b|\bTD (?:Aeroplan|Cash Back|First Class|Rewards|Platinum)\b|\btd\.com\b`),
+		StatementDate: Patterns(`statement\s+period[:\s]*{date}\s*(?:-|to)\s*({date})`),
+	},
+	{
+		Issuer:        "Amex",
+		Detect:        regexp.MustCompile(`(?i)\bAmerican\s+Express\b|\bAmex\b|americanexpress\.com`),
+		LastFour:      Patterns(`account\s+ending[:\s]*(?:\d+-)?\d*(\d{4})\b`),
+		StatementDate: Patterns(`closing\s+date[:\s]*({date})`),
+	},
+	{
+		Issuer:        "Chase",
+		Detect:        regexp.MustCompile(`(?i)\bChase\b|chase\.com|\bJPMorgan\b`),
+		StatementDate: Patterns(`opening/closing\s+date[:\s]*{date}\s*-\s*({date})`),
+	},
+	{
+		Issuer:        "Capital One",
+		Detect:        regexp.MustCompile(`(?i)\bCapital\s*One\b`),
+		StatementDate: Patterns(`{date}\s*-\s*({date})\s*\|?\s*\d+\s+days\s+in\s+billing\s+cycle`),
+	},
+	{
+		Issuer:        "Discover",
+		Detect:        regexp.MustCompile(`(?i)\bDiscover\b`),
+		StatementDate: Patterns(`open\s+to\s+close\s+date[:\s]*{date}\s*-\s*({date})`),
+	},
+	{
+		Issuer:        "Citi",
+		Detect:        regexp.MustCompile(`(?i)\bCiti(?:bank|cards?)?\b|citi\.com`),
+		StatementDate: Patterns(`billing\s+period[:\s]*{date}\s*-\s*({date})`),
+	},
+}
+
+// generic reads the wording most issuers share
+var generic = Profile{
+	LastFour: Patterns(
+		`(?:account|card)\s+(?:number\s+)?ending(?:\s+in)?[:\s]*(\d{4})\b`,
+		`(?:\d{4}|[x*]{4})[\s-]?(?:[x*]{4}[\s-]?){2}(\d{4})\b`,
+		`\bending\s+in[:\s]*(\d{4})\b`,
+	),
+	StatementDate: Patterns(
+		`statement\s+(?:closing\s+)?date[:\s]*({date})`,
+		`closing\s+date[:\s]*({date})`,
+		`(?:statement|billing)\s+period[:\s]*{date}\s*(?:-|to|through)\s*({date})`,
+	),
+	DueDate: Patterns(
+		`due\s+date[:\s]*({date})`,
+		`payment\s+(?:is\s+)?due(?:\s+by|\s+on)?[:\s]*({date})`,
+	),
+	NewBalance: Patterns(
+		`new\s+balance[:\s]*({amount})`,
+		`(?:statement|closing|total)\s+balance[:\s]*({amount})`,
+	),
+	MinimumPayment: Patterns(
+		`minimum\s+(?:amount\s+)?(?:payment|amount)(?:\s+due)?[:\s]*({amount})`,
+	),
+}
+
+// Extract reads a statement's text with the profile of the first issuer it
+// mentions, or the generic patterns alone
+func Extract(text string) Proposal {
+	for _, p := range Profiles {
+		if p.Detect.MatchString(text) {
+			return p.Extract(text)
+		}
+	}
+	return generic.Extract(text)
+}
+
+// Extract reads a statement's text with the profile's patterns
+func (p Profile) Extract(text string) Proposal {
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	proposal := Proposal{Issuer: p.Issuer}
+
+	if value, ok := find(text, p.StatementDate, generic.StatementDate, validDate); ok {
+		proposal.StatementDate, _ = ParseDate(value)
+	} else {
+		proposal.Missing = append(proposal.Missing, FieldStatementDate)
+	}
+	if value, ok := find(text, p.DueDate, generic.DueDate, validDate); ok {
+		proposal.DueDate, _ = ParseDate(value)
+	} else {
+		proposal.Missing = append(proposal.Missing, FieldDueDate)
+	}
+	if value, ok := find(text, p.NewBalance, generic.NewBalance, validAmount); ok {
+		proposal.NewBalance, _ = models.ParseDisplayMoney(value)
+	} else {
+		proposal.Missing = append(proposal.Missing, FieldNewBalance)
+	}
+	if value, ok := find(text, p.MinimumPayment, generic.MinimumPayment, validAmount); ok {
+		proposal.MinimumPayment, _ = models.ParseDisplayMoney(value)
+	} else {
+		proposal.Missing = append(proposal.Missing, FieldMinimumPayment)
+	}
+	if value, ok := find(text, p.LastFour, generic.LastFour, nil); ok {
+		proposal.LastFour = value
+	} else {
+		proposal.Missing = append(proposal.Missing, FieldLastFour)
+	}
+	return proposal
+}
+
+// find returns the first captured value of the patterns that valid accepts
+func find(text string, own, fallback []*regexp.Regexp, valid func(string) bool) (string, bool) {
+	for _, re := range append(append([]*regexp.Regexp{}, own...), fallback...) {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			if value := strings.TrimSpace(m[1]); valid == nil || valid(value) {
+				return value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func validDate(s string) bool {
+	_, err := ParseDate(s)
+	return err == nil
+}
+
+func validAmount(s string) bool {
+	_, err := models.ParseDisplayMoney(s)
+	return err == nil
+}
+
+// dateLayouts are the ways statements write dates, after ParseDate drops
+// commas and full stops. Numeric dates are read month first.
+var dateLayouts = []string{
+	"January 2 2006",
+	"Jan 2 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"1/2/2006",
+	"1/2/06",
+	"2006-01-02",
+}
+
+// septPattern matches the abbreviation Sept, which time.Parse doesn't know
+var septPattern = regexp.MustCompile(`(?i)\bsept\b`)
+
+// ParseDate reads a date as statements write it, e.g. "November 15, 2024",
+// "Nov. 15, 2024" or "11/15/24", and returns it as YYYY-MM-DD
+func ParseDate(s string) (string, error) {
+	value := strings.Join(strings.Fields(strings.NewReplacer(",", " ", ".", " ").Replace(s)), " ")
+	value = septPattern.ReplaceAllString(value, "Sep")
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(models.DateFormat), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", s)
+}
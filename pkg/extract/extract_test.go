@@ -0,0 +1,128 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract_Issuers(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Proposal
+	}{
+		{
+			name: "TD",
+			text: `TD Aeroplan Visa Infinite
+4520 XXXX XXXX 9876
+STATEMENT PERIOD: October 16, 2024 to November 15, 2024
+STATEMENT DATE: November 15, 2024
+PREVIOUS STATEMENT BALANCE $1,250.75
+NEW BALANCE $892.50
+MINIMUM PAYMENT $10.00
+PAYMENT DUE DATE: December 10, 2024`,
+			want: Proposal{Issuer: "TD", LastFour: "9876", StatementDate: "2024-11-15", DueDate: "2024-12-10",
+				NewBalance: 89250, MinimumPayment: 1000},
+		},
+		{
+			name: "Amex",
+			text: `American Express Cobalt Card
+Prepared for JANE DOE Account Ending 3-71234
+Closing Date 11/28/24
+New Balance $3,421.89
+Minimum Payment Due $35.00
+Payment Due Date 12/23/24`,
+			want: Proposal{Issuer: "Amex", LastFour: "1234", StatementDate: "2024-11-28", DueDate: "2024-12-23",
+				NewBalance: 342189, MinimumPayment: 3500},
+		},
+		{
+			name: "Chase",
+			text: `Chase Sapphire Reserve
+Account Number: XXXX XXXX XXXX 5678
+Opening/Closing Date 10/02/24 - 11/01/24
+New Balance $567.25
+Payment Due Date: 11/22/24
+Minimum Payment Due: $40.00`,
+			want: Proposal{Issuer: "Chase", LastFour: "5678", StatementDate: "2024-11-01", DueDate: "2024-11-22",
+				NewBalance: 56725, MinimumPayment: 4000},
+		},
+		{
+			name: "Capital One",
+			text: `Capital One Quicksilver Card | Visa Signature ending in 4321
+Nov 5, 2024 - Dec 4, 2024 | 30 days in Billing Cycle
+Payment Due Date Dec 19, 2024
+New Balance $15.00
+Minimum Payment Due $15.00`,
+			want: Proposal{Issuer: "Capital One", LastFour: "4321", StatementDate: "2024-12-04", DueDate: "2024-12-19",
+				NewBalance: 1500, MinimumPayment: 1500},
+		},
+		{
+			name: "Discover",
+			text: `Discover it Card
+Account number ending in 8888
+Open to Close Date: 10/06/2024 - 11/05/2024
+New Balance: $2,845.67
+Minimum Payment Due: $57.00
+Payment Due Date: 12/02/2024`,
+			want: Proposal{Issuer: "Discover", LastFour: "8888", StatementDate: "2024-11-05", DueDate: "2024-12-02",
+				NewBalance: 284567, MinimumPayment: 5700},
+		},
+		{
+			name: "Citi",
+			text: `Citi Double Cash Card
+Account number ending in: 2468
+Billing Period: 10/04/24-11/03/24
+New balance $0.00
+Minimum payment due $0.00
+Payment due date Nov. 28, 2024`,
+			want: Proposal{Issuer: "Citi", LastFour: "2468", StatementDate: "2024-11-03", DueDate: "2024-11-28"},
+		},
+		{
+			name: "other issuer",
+			text: `Card ending in 1111
+Statement Closing Date: 15 Sept 2024
+Total balance: $100.00
+Payment is due by: October 9, 2024`,
+			want: Proposal{LastFour: "1111", StatementDate: "2024-09-15", DueDate: "2024-10-09",
+				NewBalance: 10000, Missing: []string{FieldMinimumPayment}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExtract_Missing(t *testing.T) {
+	got := Extract("Thank you for being a customer")
+	want := []string{FieldStatementDate, FieldDueDate, FieldNewBalance, FieldMinimumPayment, FieldLastFour}
+	if !reflect.DeepEqual(got.Missing, want) {
+		t.Errorf("Expected every field to be missing, got %v", got.Missing)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := map[string]string{
+		"November 15, 2024": "2024-11-15",
+		"Nov. 5, 2024":      "2024-11-05",
+		"SEPT 3 2024":       "2024-09-03",
+		"3 January 2025":    "2025-01-03",
+		"12/23/24":          "2024-12-23",
+		"1/2/2025":          "2025-01-02",
+		"2024-11-28":        "2024-11-28",
+	}
+	for input, want := range tests {
+		got, err := ParseDate(input)
+		if err != nil || got != want {
+			t.Errorf("ParseDate(%q): expected %s, got %s (%v)", input, want, got, err)
+		}
+	}
+	if _, err := ParseDate("13/45/2024"); err == nil {
+		t.Error("Expected an error for an invalid date")
+	}
+}
@@ -0,0 +1,162 @@
+// Package followup carries out what follows entering or changing a
+// statement: scheduling the payment autopay makes, announcing the statement
+// and mirroring its scheduled payment into YNAB. The API and the import-ofx
+// command share it, so statements get the same treatment however they
+// arrive.
+package followup
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/notify"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ynab"
+)
+
+// Service follows up on statement changes. Announcements and YNAB syncs run
+// in the background; Wait blocks until they are done.
+type Service struct {
+	statements repository.StatementRepository
+	notifier   *notify.Notifier
+	ynab       *ynab.Syncer
+	clock      clock.Clock
+	pending    sync.WaitGroup
+}
+
+// New creates a Service that schedules payments in statements. A nil
+// notifier disables announcements, a nil syncer disables YNAB sync and a
+// nil clock uses the real time.
+func New(statements repository.StatementRepository, notifier *notify.Notifier, syncer *ynab.Syncer, clk clock.Clock) *Service {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Service{
+		statements: statements,
+		notifier:   notifier,
+		ynab:       syncer,
+		clock:      clk,
+	}
+}
+
+// Entered follows up on a statement whose amount has just been entered: it
+// schedules the autopay payment and announces the statement
+func (s *Service) Entered(ctx context.Context, card models.CreditCard, stmt *models.Statement) {
+	// The issuer pays autopay cards on the due date, so the payment is
+	// already scheduled. The statement is kept if this fails; it just shows
+	// as unscheduled.
+	if card.HasAutopay() {
+		if err := s.ScheduleAutopay(ctx, card, stmt); err != nil {
+			log.Printf("Error scheduling autopay for statement %d: %v", stmt.ID, err)
+		}
+	}
+
+	// Announce the statement in the background so retries don't hold up the caller
+	if s.notifier.Enabled() {
+		statementID := stmt.ID
+		s.background(func() {
+			if err := s.notifier.NotifyStatement(context.Background(), statementID); err != nil {
+				log.Printf("Error sending statement notification: %v", err)
+			}
+		})
+	}
+}
+
+// Updated follows up on an entered statement whose amounts or due date
+// changed: autopay moves to the new due date, and the YNAB transaction is
+// updated to the new amount or removed if nothing is left to pay
+func (s *Service) Updated(ctx context.Context, card models.CreditCard, stmt *models.Statement) {
+	rescheduled := stmt.ScheduledPaymentDate == nil || *stmt.ScheduledPaymentDate != stmt.DueDate
+	if card.HasAutopay() && stmt.Status != models.StatusPaid && rescheduled {
+		if err := s.ScheduleAutopay(ctx, card, stmt); err != nil {
+			log.Printf("Error scheduling autopay for statement %d: %v", stmt.ID, err)
+		}
+		return
+	}
+	s.SyncPayment(stmt.ID)
+}
+
+// Imported follows up on a statement an import entered or updated
+func (s *Service) Imported(ctx context.Context, result importer.Result) {
+	switch {
+	case result.Entered != nil:
+		s.Entered(ctx, result.Card, result.Entered)
+	case result.Updated != nil:
+		s.Updated(ctx, result.Card, result.Updated)
+	}
+}
+
+// ScheduleAutopay schedules the payment autopay makes on stmt's due date,
+// from the card's autopay account when it has one, and updates stmt to match
+func (s *Service) ScheduleAutopay(ctx context.Context, card models.CreditCard, stmt *models.Statement) error {
+	now := s.clock.Now()
+	date := stmt.DueDate
+	if err := s.statements.SchedulePayment(ctx, stmt.ID, date, card.AutopayFundingAccountID, now); err != nil {
+		return err
+	}
+	s.SyncPayment(stmt.ID)
+
+	stmt.ScheduledPaymentDate = &date
+	stmt.ReviewedAt = &now
+	if card.AutopayFundingAccountID != nil {
+		stmt.FundingAccountID = card.AutopayFundingAccountID
+	}
+	return nil
+}
+
+// SyncPayment mirrors a statement's scheduled payment into YNAB in the
+// background. Failures are recorded by the syncer and retried by the
+// scheduler.
+func (s *Service) SyncPayment(statementID int) {
+	if !s.ynab.Enabled() {
+		return
+	}
+	s.background(func() {
+		if err := s.ynab.SchedulePayment(context.Background(), statementID); err != nil {
+			log.Printf("Error syncing payment to YNAB: %v", err)
+		}
+	})
+}
+
+// YNABTransaction returns the ID of a statement's YNAB scheduled
+// transaction, or "" if there isn't one. Deleting the statement deletes the
+// record of it, so read it first and pass it to RemovePayment afterwards.
+func (s *Service) YNABTransaction(ctx context.Context, statementID int) (string, error) {
+	if !s.ynab.Enabled() {
+		return "", nil
+	}
+	return s.ynab.ScheduledTransactionID(ctx, statementID)
+}
+
+// RemovePayment deletes a deleted statement's scheduled transaction from
+// YNAB in the background. There is nothing left to record a failure
+// against, so it is only logged.
+func (s *Service) RemovePayment(ynabID string) {
+	if ynabID == "" || !s.ynab.Enabled() {
+		return
+	}
+	s.background(func() {
+		if err := s.ynab.DeleteScheduledTransaction(context.Background(), ynabID); err != nil {
+			log.Printf("Error removing payment from YNAB: %v", err)
+		}
+	})
+}
+
+// Wait blocks until the announcements and YNAB syncs started so far are
+// done, for callers that exit once they have stored their statements
+func (s *Service) Wait() {
+	s.pending.Wait()
+}
+
+// background runs fn in its own goroutine, tracked for Wait
+func (s *Service) background(fn func()) {
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		fn()
+	}()
+}
@@ -0,0 +1,168 @@
+package followup
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/notify"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+var testNow = time.Date(2024, 11, 16, 9, 0, 0, 0, time.UTC)
+
+// setupFollowup creates a test database with a card and an entered
+// statement for it, returning the database and both
+func setupFollowup(t *testing.T, card models.CreditCard) (*sql.DB, repository.StatementRepository, models.CreditCard, models.Statement) {
+	tmpDB := "./test_followup.db"
+	db, err := database.InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpDB)
+	})
+
+	ctx := context.Background()
+	cards := repository.NewCardRepository(db)
+	statements := repository.NewStatementRepository(db)
+	if err := cards.Create(ctx, &card); err != nil {
+		t.Fatalf("Failed to create card: %v", err)
+	}
+	stmt := models.Statement{
+		CardID:         card.ID,
+		StatementDate:  "2024-11-15",
+		DueDate:        "2024-12-10",
+		Amount:         models.MustParseMoney("500.00"),
+		MinimumPayment: models.MustParseMoney("25.00"),
+		Status:         models.StatusPending,
+	}
+	if err := statements.Create(ctx, &stmt); err != nil {
+		t.Fatalf("Failed to create statement: %v", err)
+	}
+	return db, statements, card, stmt
+}
+
+func TestEntered_SchedulesAutopay(t *testing.T) {
+	card := models.CreditCard{Name: "Visa", LastFour: "1234", StatementDay: 15, DaysUntilDue: 25, Autopay: models.AutopayStatementBalance}
+	_, statements, card, stmt := setupFollowup(t, card)
+
+	s := New(statements, nil, nil, clock.NewFixed(testNow))
+	s.Entered(context.Background(), card, &stmt)
+	s.Wait()
+
+	if stmt.ScheduledPaymentDate == nil || *stmt.ScheduledPaymentDate != "2024-12-10" {
+		t.Errorf("Expected the statement to be scheduled on its due date, got %v", stmt.ScheduledPaymentDate)
+	}
+	stored, err := statements.Get(context.Background(), stmt.ID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stored.ScheduledPaymentDate == nil || *stored.ScheduledPaymentDate != "2024-12-10" {
+		t.Errorf("Expected the payment to be stored on 2024-12-10, got %v", stored.ScheduledPaymentDate)
+	}
+	if stored.ReviewedAt == nil || !stored.ReviewedAt.Equal(testNow) {
+		t.Errorf("Expected the statement to be reviewed at %s, got %v", testNow, stored.ReviewedAt)
+	}
+}
+
+func TestEntered_LeavesManualCardsUnscheduled(t *testing.T) {
+	card := models.CreditCard{Name: "Visa", LastFour: "1234", StatementDay: 15, DaysUntilDue: 25}
+	_, statements, card, stmt := setupFollowup(t, card)
+
+	s := New(statements, nil, nil, clock.NewFixed(testNow))
+	s.Entered(context.Background(), card, &stmt)
+	s.Wait()
+
+	stored, err := statements.Get(context.Background(), stmt.ID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stored.ScheduledPaymentDate != nil {
+		t.Errorf("Expected no payment to be scheduled, got %s", *stored.ScheduledPaymentDate)
+	}
+}
+
+func TestWait_WaitsForAnnouncement(t *testing.T) {
+	card := models.CreditCard{Name: "Visa", LastFour: "1234", StatementDay: 15, DaysUntilDue: 25}
+	db, statements, card, stmt := setupFollowup(t, card)
+
+	var sent int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&sent, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	clk := clock.NewFixed(testNow)
+	notifier := notify.NewNotifier(db, notify.NewDiscordClient(server.URL), clk)
+	s := New(statements, notifier, nil, clk)
+	s.Entered(context.Background(), card, &stmt)
+	s.Wait()
+
+	if atomic.LoadInt32(&sent) != 1 {
+		t.Errorf("Expected the announcement to be sent before Wait returns, got %d messages", sent)
+	}
+}
+
+func TestUpdated_MovesAutopayToNewDueDate(t *testing.T) {
+	card := models.CreditCard{Name: "Visa", LastFour: "1234", StatementDay: 15, DaysUntilDue: 25, Autopay: models.AutopayStatementBalance}
+	_, statements, card, stmt := setupFollowup(t, card)
+	ctx := context.Background()
+
+	s := New(statements, nil, nil, clock.NewFixed(testNow))
+	s.Entered(ctx, card, &stmt)
+
+	// The issuer moved the due date in a later download
+	dueDate := "2024-12-12"
+	updated, err := statements.Update(ctx, stmt.ID, repository.StatementUpdate{DueDate: &dueDate, UpdatedAt: testNow})
+	if err != nil {
+		t.Fatalf("Failed to update statement: %v", err)
+	}
+	s.Updated(ctx, card, &updated)
+	s.Wait()
+
+	stored, err := statements.Get(ctx, stmt.ID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stored.ScheduledPaymentDate == nil || *stored.ScheduledPaymentDate != dueDate {
+		t.Errorf("Expected autopay to move to %s, got %v", dueDate, stored.ScheduledPaymentDate)
+	}
+}
+
+func TestUpdated_LeavesManualScheduleAlone(t *testing.T) {
+	card := models.CreditCard{Name: "Visa", LastFour: "1234", StatementDay: 15, DaysUntilDue: 25}
+	_, statements, card, stmt := setupFollowup(t, card)
+	ctx := context.Background()
+
+	if err := statements.SchedulePayment(ctx, stmt.ID, "2024-12-01", nil, testNow); err != nil {
+		t.Fatalf("Failed to schedule payment: %v", err)
+	}
+	dueDate := "2024-12-12"
+	updated, err := statements.Update(ctx, stmt.ID, repository.StatementUpdate{DueDate: &dueDate, UpdatedAt: testNow})
+	if err != nil {
+		t.Fatalf("Failed to update statement: %v", err)
+	}
+
+	s := New(statements, nil, nil, clock.NewFixed(testNow))
+	s.Updated(ctx, card, &updated)
+	s.Wait()
+
+	stored, err := statements.Get(ctx, stmt.ID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stored.ScheduledPaymentDate == nil || *stored.ScheduledPaymentDate != "2024-12-01" {
+		t.Errorf("Expected the payment to stay on 2024-12-01, got %v", stored.ScheduledPaymentDate)
+	}
+}
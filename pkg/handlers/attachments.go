@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/extract"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/pdf"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// pdfContentType is the content type attachments are stored and served as
+const pdfContentType = "application/pdf"
+
+// AttachmentResponse is an attachment with the statement details read from
+// it
+type AttachmentResponse struct {
+	Attachment models.Attachment `json:"attachment"`
+	// Proposal is what the PDF says about the statement, to be confirmed
+	Proposal extract.Proposal `json:"proposal"`
+	// ExtractError says why no text could be read, e.g. the PDF is encrypted
+	ExtractError string `json:"extract_error,omitempty"`
+	// Preview is what confirming the proposal unchanged would do
+	Preview importer.Result `json:"preview"`
+}
+
+// ConfirmAttachmentRequest corrects the proposal read from an attachment
+// before it is stored; fields left out keep the proposed value
+type ConfirmAttachmentRequest struct {
+	CardID         int           `json:"card_id,omitempty"`
+	StatementDate  string        `json:"statement_date,omitempty"`
+	DueDate        string        `json:"due_date,omitempty"`
+	Amount         *models.Money `json:"amount,omitempty"`
+	MinimumPayment *models.Money `json:"minimum_payment,omitempty"`
+}
+
+// ConfirmAttachmentResponse reports the statement a confirmed attachment
+// was stored as
+type ConfirmAttachmentResponse struct {
+	Attachment models.Attachment `json:"attachment"`
+	Result     importer.Result   `json:"result"`
+}
+
+// attachmentPath parses /api/v1/attachments/{id}[/{action}]
+func attachmentPath(path string) (int, string, error) {
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(pathParts) < 5 || len(pathParts) > 6 {
+		return 0, "", errors.New("Invalid URL")
+	}
+	id, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		return 0, "", errors.New("Invalid attachment ID")
+	}
+	if len(pathParts) == 6 {
+		return id, pathParts[5], nil
+	}
+	return id, "", nil
+}
+
+// attachmentsAvailable writes an error and returns false if attachments
+// aren't stored
+func (h *Handler) attachmentsAvailable(w http.ResponseWriter) bool {
+	if h.attachments == nil {
+		http.Error(w, "Attachments are not available", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+// UploadAttachment stores an issuer's PDF statement, given as the request
+// body or the "file" field of a multipart form, and reads the statement
+// details from it for confirmation. With statement_id the PDF is attached
+// to that statement straight away.
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.attachmentsAvailable(w) {
+		return
+	}
+
+	var stmt *models.Statement
+	if s := r.URL.Query().Get("statement_id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Invalid statement ID", http.StatusBadRequest)
+			return
+		}
+		found, err := h.statements.Get(r.Context(), id)
+		if err == repository.ErrNotFound {
+			http.Error(w, "statement_id does not refer to an existing statement", http.StatusUnprocessableEntity)
+			return
+		} else if err != nil {
+			log.Printf("Error querying statement %d: %v", id, err)
+			http.Error(w, "Failed to store attachment", http.StatusInternalServerError)
+			return
+		}
+		stmt = &found
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxStatementUpload)
+	body, filename, _, err := readUpload(r)
+	if err != nil {
+		log.Printf("Error reading attachment upload: %v", err)
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		log.Printf("Error reading attachment upload: %v", err)
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	if !pdf.IsPDF(data) {
+		http.Error(w, "file is not a PDF", http.StatusBadRequest)
+		return
+	}
+	if filename == "" {
+		filename = "statement.pdf"
+	}
+
+	sum := sha256.Sum256(data)
+	attachment := models.Attachment{
+		Filename:    filename,
+		ContentType: pdfContentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		CreatedAt:   h.clock.Now(),
+		Data:        data,
+	}
+	if stmt != nil {
+		attachment.StatementID = &stmt.ID
+	}
+	if err := h.attachments.Create(r.Context(), &attachment); err != nil {
+		log.Printf("Error storing attachment: %v", err)
+		http.Error(w, "Failed to store attachment", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := h.readAttachment(r.Context(), attachment)
+	if err != nil {
+		log.Printf("Error previewing attachment %d: %v", attachment.ID, err)
+		http.Error(w, "Failed to read attachment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// readAttachment extracts the statement details from an attachment and
+// previews confirming them
+func (h *Handler) readAttachment(ctx context.Context, attachment models.Attachment) (AttachmentResponse, error) {
+	resp := AttachmentResponse{Attachment: attachment}
+	text, err := pdf.ExtractText(attachment.Data)
+	if err != nil {
+		resp.ExtractError = err.Error()
+	}
+	resp.Proposal = extract.Extract(text)
+
+	cardID, err := h.attachmentCard(ctx, attachment)
+	if err != nil {
+		return resp, err
+	}
+	results, err := importer.New(h.cards, h.statements, h.clock).Import(ctx, []importer.Statement{
+		proposedStatement(resp.Proposal, cardID),
+	}, true)
+	if err != nil {
+		return resp, err
+	}
+	resp.Preview = results[0]
+	return resp, nil
+}
+
+// attachmentCard returns the card of the statement an attachment belongs
+// to, or 0 to match the card by its last four digits
+func (h *Handler) attachmentCard(ctx context.Context, attachment models.Attachment) (int, error) {
+	if attachment.StatementID == nil {
+		return 0, nil
+	}
+	stmt, err := h.statements.Get(ctx, *attachment.StatementID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get statement %d: %w", *attachment.StatementID, err)
+	}
+	return stmt.CardID, nil
+}
+
+// proposedStatement is the statement a proposal describes
+func proposedStatement(p extract.Proposal, cardID int) importer.Statement {
+	return importer.Statement{
+		CardID:         cardID,
+		LastFour:       p.LastFour,
+		StatementDate:  p.StatementDate,
+		DueDate:        p.DueDate,
+		Amount:         p.NewBalance,
+		MinimumPayment: p.MinimumPayment,
+	}
+}
+
+// GetAttachment serves an attachment's PDF
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	attachment, ok := h.findAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", attachment.Filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	w.Write(attachment.Data)
+}
+
+// GetAttachmentProposal reads the statement details from a stored
+// attachment again, previewing what confirming them would do now
+func (h *Handler) GetAttachmentProposal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	attachment, ok := h.findAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.readAttachment(r.Context(), attachment)
+	if err != nil {
+		log.Printf("Error previewing attachment %d: %v", attachment.ID, err)
+		http.Error(w, "Failed to read attachment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ConfirmAttachment stores the statement read from an attachment, with any
+// corrections in the request, and attaches the PDF to it. The statement is
+// created, fills in the card's expected placeholder, or updates the
+// statement already entered for that date. The card is the one given, the
+// one of the statement the PDF is attached to, or the one ending in the
+// last four digits read. A statement that can't be stored returns 422 with
+// the reason.
+func (h *Handler) ConfirmAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ConfirmAttachmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	attachment, ok := h.findAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	text, _ := pdf.ExtractText(attachment.Data)
+	cardID, err := h.attachmentCard(r.Context(), attachment)
+	if err != nil {
+		log.Printf("Error confirming attachment %d: %v", attachment.ID, err)
+		http.Error(w, "Failed to confirm attachment", http.StatusInternalServerError)
+		return
+	}
+	if req.CardID != 0 {
+		cardID = req.CardID
+	}
+	stmt := proposedStatement(extract.Extract(text), cardID)
+	if req.StatementDate != "" {
+		stmt.StatementDate = req.StatementDate
+	}
+	if req.DueDate != "" {
+		stmt.DueDate = req.DueDate
+	}
+	if req.Amount != nil {
+		stmt.Amount = *req.Amount
+	}
+	if req.MinimumPayment != nil {
+		stmt.MinimumPayment = *req.MinimumPayment
+	}
+
+	results, err := importer.New(h.cards, h.statements, h.clock).Import(r.Context(), []importer.Statement{stmt}, false)
+	if err != nil {
+		log.Printf("Error confirming attachment %d: %v", attachment.ID, err)
+		http.Error(w, "Failed to confirm attachment", http.StatusInternalServerError)
+		return
+	}
+	result := results[0]
+	if result.Action == importer.ActionSkip {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(ConfirmAttachmentResponse{Attachment: attachment, Result: result})
+		return
+	}
+
+	if err := h.attachments.Link(r.Context(), attachment.ID, result.StatementID); err != nil {
+		log.Printf("Error linking attachment %d: %v", attachment.ID, err)
+		http.Error(w, "Failed to confirm attachment", http.StatusInternalServerError)
+		return
+	}
+	attachment.StatementID = &result.StatementID
+	h.followup.Imported(r.Context(), result)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ConfirmAttachmentResponse{Attachment: attachment, Result: result})
+}
+
+// DeleteAttachment removes an attachment
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.attachmentsAvailable(w) {
+		return
+	}
+
+	id, _, err := attachmentPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.attachments.Delete(r.Context(), id); err == repository.ErrNotFound {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting attachment %d: %v", id, err)
+		http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStatementAttachments lists the attachments of a statement
+func (h *Handler) GetStatementAttachments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pathParts := strings.Split(r.URL.Path, "/")
+	statementID, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		http.Error(w, "Invalid statement ID", http.StatusBadRequest)
+		return
+	}
+
+	attachments := []models.Attachment{}
+	if h.attachments != nil {
+		attachments, err = h.attachments.List(r.Context(), statementID)
+		if err != nil {
+			log.Printf("Error querying attachments of statement %d: %v", statementID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// findAttachment loads the attachment named in the URL, writing an error
+// and returning false if it can't
+func (h *Handler) findAttachment(w http.ResponseWriter, r *http.Request) (models.Attachment, bool) {
+	if !h.attachmentsAvailable(w) {
+		return models.Attachment{}, false
+	}
+	id, _, err := attachmentPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return models.Attachment{}, false
+	}
+
+	attachment, err := h.attachments.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return attachment, false
+	} else if err != nil {
+		log.Printf("Error querying attachment %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return attachment, false
+	}
+	return attachment, true
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// statementPDF writes a one page PDF showing lines of text
+func statementPDF(lines ...string) []byte {
+	var content strings.Builder
+	content.WriteString("BT /F1 11 Tf 72 720 Td 14 TL\n")
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", line)
+	}
+	content.WriteString("ET")
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	buf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	buf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	buf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>\nendobj\n")
+	buf.WriteString("4 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n")
+	fmt.Fprintf(&buf, "5 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", content.Len(), content.String())
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+var tdStatementPDF = statementPDF(
+	"TD Aeroplan Visa Infinite",
+	"4520 XXXX XXXX 9876",
+	"STATEMENT DATE: November 15, 2024",
+	"NEW BALANCE $892.50",
+	"MINIMUM PAYMENT $10.00",
+	"PAYMENT DUE DATE: December 10, 2024",
+)
+
+func setupAttachments(t *testing.T) (*Handler, *sql.DB) {
+	h, db := setupTestDB(t)
+	h.attachments = repository.NewSQLiteAttachmentRepository(db)
+	return h, db
+}
+
+func uploadAttachment(h *Handler, query string, data []byte) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, _ := form.CreateFormFile("file", "td-2024-11.pdf")
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/attachments"+query, &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.UploadAttachment(w, req)
+	return w
+}
+
+func confirmAttachment(h *Handler, id int, body string) (*httptest.ResponseRecorder, ConfirmAttachmentResponse) {
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/attachments/%d/confirm", id), strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ConfirmAttachment(w, req)
+
+	var resp ConfirmAttachmentResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestUploadAttachment(t *testing.T) {
+	h, _ := setupAttachments(t)
+
+	if w := createCard(t, h, `{"name": "TD Aeroplan Visa", "last_four": "9876", "statement_date": "2024-10-15", "due_date": "2024-11-09"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create card: %s", w.Body.String())
+	}
+
+	w := uploadAttachment(h, "", tdStatementPDF)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var upload AttachmentResponse
+	if err := json.NewDecoder(w.Body).Decode(&upload); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	proposal := upload.Proposal
+	if proposal.Issuer != "TD" || proposal.LastFour != "9876" || proposal.StatementDate != "2024-11-15" ||
+		proposal.DueDate != "2024-12-10" || proposal.NewBalance.String() != "892.50" || proposal.MinimumPayment.String() != "10.00" {
+		t.Errorf("Unexpected proposal: %+v", proposal)
+	}
+	if upload.Preview.Action != importer.ActionCreate || upload.Preview.CardName != "TD Aeroplan Visa" {
+		t.Errorf("Expected a new statement to be previewed, got %+v", upload.Preview)
+	}
+	if upload.Attachment.Filename != "td-2024-11.pdf" || upload.Attachment.StatementID != nil || len(upload.Attachment.SHA256) != 64 {
+		t.Errorf("Unexpected attachment: %+v", upload.Attachment)
+	}
+	if stmts, _ := h.statements.List(t.Context()); len(stmts) != 0 {
+		t.Fatalf("Expected nothing stored before confirming, got %d statements", len(stmts))
+	}
+
+	// The stored PDF is served back unchanged
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/attachments/%d", upload.Attachment.ID), nil)
+	w = httptest.NewRecorder()
+	h.GetAttachment(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" || !bytes.Equal(w.Body.Bytes(), tdStatementPDF) {
+		t.Errorf("Expected the PDF back, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// Confirming with a corrected minimum payment stores the statement
+	w, confirmed := confirmAttachment(h, upload.Attachment.ID, `{"minimum_payment": "12.00"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	stmt, err := h.statements.Get(t.Context(), confirmed.Result.StatementID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stmt.Amount.String() != "892.50" || stmt.MinimumPayment.String() != "12.00" || stmt.DueDate != "2024-12-10" {
+		t.Errorf("Unexpected statement: %+v", stmt)
+	}
+	if confirmed.Attachment.StatementID == nil || *confirmed.Attachment.StatementID != stmt.ID {
+		t.Errorf("Expected the attachment to be linked to statement %d, got %+v", stmt.ID, confirmed.Attachment)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/statements/%d/attachments", stmt.ID), nil)
+	w = httptest.NewRecorder()
+	h.GetStatementAttachments(w, req)
+	var list []models.Attachment
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 || list[0].ID != upload.Attachment.ID {
+		t.Errorf("Expected the statement to list the attachment, got %+v", list)
+	}
+
+	// Reading the PDF again finds the statement already entered
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/attachments/%d/proposal", upload.Attachment.ID), nil)
+	w = httptest.NewRecorder()
+	h.GetAttachmentProposal(w, req)
+	var again AttachmentResponse
+	json.NewDecoder(w.Body).Decode(&again)
+	if again.Preview.Action != importer.ActionUpdate || again.Preview.StatementID != stmt.ID {
+		t.Errorf("Expected the entered statement to be previewed, got %+v", again.Preview)
+	}
+}
+
+func TestConfirmAttachment_Unmatched(t *testing.T) {
+	h, _ := setupAttachments(t)
+
+	w := createCard(t, h, `{"name": "Visa", "last_four": "1111", "statement_date": "2024-10-15", "due_date": "2024-11-09"}`)
+	var card models.CreditCard
+	json.NewDecoder(w.Body).Decode(&card)
+
+	w = uploadAttachment(h, "", tdStatementPDF)
+	var upload AttachmentResponse
+	json.NewDecoder(w.Body).Decode(&upload)
+	if upload.Preview.Action != importer.ActionSkip {
+		t.Errorf("Expected no card to match, got %+v", upload.Preview)
+	}
+
+	w, confirmed := confirmAttachment(h, upload.Attachment.ID, "")
+	if w.Code != http.StatusUnprocessableEntity || confirmed.Result.Reason == "" {
+		t.Errorf("Expected status 422 with a reason, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w, confirmed = confirmAttachment(h, upload.Attachment.ID, fmt.Sprintf(`{"card_id": %d, "amount": 900}`, card.ID))
+	if w.Code != http.StatusOK || confirmed.Result.Action != importer.ActionCreate || confirmed.Result.Amount.String() != "900.00" {
+		t.Errorf("Expected the statement to be created for the card given, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAttachment_Invalid(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	if w := uploadAttachment(h, "", tdStatementPDF); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without attachments, got %d", w.Code)
+	}
+
+	h.attachments = repository.NewSQLiteAttachmentRepository(db)
+	if w := uploadAttachment(h, "", []byte("<html>not a statement</html>")); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a file that isn't a PDF, got %d", w.Code)
+	}
+	if w := uploadAttachment(h, "?statement_id=99", tdStatementPDF); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for a missing statement, got %d", w.Code)
+	}
+
+	// A PDF without text is stored with nothing proposed
+	w := uploadAttachment(h, "", statementPDF())
+	var upload AttachmentResponse
+	json.NewDecoder(w.Body).Decode(&upload)
+	if w.Code != http.StatusCreated || len(upload.Proposal.Missing) != 5 {
+		t.Errorf("Expected every field to be missing, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/attachments/%d", upload.Attachment.ID), nil)
+	w = httptest.NewRecorder()
+	h.DeleteAttachment(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/attachments/%d", upload.Attachment.ID), nil)
+	w = httptest.NewRecorder()
+	h.GetAttachment(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after deleting, got %d", w.Code)
+	}
+}
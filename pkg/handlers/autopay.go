@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// validateAutopay checks the autopay fields of a card request and returns
+// the message to report, or "" if they are valid. current is the card's
+// autopay mode before the request, or "" for a new card.
+func validateAutopay(req CreateCardRequest, current string) string {
+	if !models.ValidAutopay(req.Autopay) {
+		return "autopay must be one of none, minimum, statement_balance or fixed_amount"
+	}
+	if req.AutopayAmount < 0 {
+		return "autopay_amount cannot be negative"
+	}
+
+	mode := current
+	if req.Autopay != "" {
+		mode = req.Autopay
+	}
+	if mode == models.AutopayFixedAmount {
+		// A card already on a fixed amount keeps its amount
+		if req.AutopayAmount == 0 && req.Autopay != "" && current != models.AutopayFixedAmount {
+			return "autopay_amount is required for fixed_amount autopay"
+		}
+	} else if req.AutopayAmount != 0 {
+		return "autopay_amount only applies to fixed_amount autopay"
+	}
+	return ""
+}
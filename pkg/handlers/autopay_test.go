@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func createCard(t *testing.T, h *Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cards", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	h.CreateCard(w, req)
+	return w
+}
+
+func TestCreateCard_Autopay(t *testing.T) {
+	h, db := setupFundingTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO funding_accounts (id, name) VALUES (1, 'Chequing')`); err != nil {
+		t.Fatalf("Failed to insert funding account: %v", err)
+	}
+
+	const dates = `"name": "Visa", "last_four": "1234", "statement_date": "2024-11-10", "due_date": "2024-12-05"`
+	w := createCard(t, h, `{`+dates+`, "autopay": "fixed_amount", "autopay_amount": "300.00", "autopay_funding_account_id": 1}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var card models.CreditCard
+	json.NewDecoder(w.Body).Decode(&card)
+	if card.Autopay != models.AutopayFixedAmount || card.AutopayAmount.String() != "300.00" ||
+		card.AutopayFundingAccountID == nil || *card.AutopayFundingAccountID != 1 {
+		t.Errorf("Unexpected autopay settings %+v", card)
+	}
+
+	w = createCard(t, h, `{`+dates+`}`)
+	var manual models.CreditCard
+	json.NewDecoder(w.Body).Decode(&manual)
+	if manual.Autopay != models.AutopayNone {
+		t.Errorf("Expected autopay to default to none, got %q", manual.Autopay)
+	}
+
+	for body, code := range map[string]int{
+		`{` + dates + `, "autopay": "everything"}`:                                http.StatusBadRequest,
+		`{` + dates + `, "autopay": "fixed_amount"}`:                              http.StatusBadRequest,
+		`{` + dates + `, "autopay": "minimum", "autopay_amount": "50.00"}`:        http.StatusBadRequest,
+		`{` + dates + `, "autopay": "minimum", "autopay_funding_account_id": 99}`: http.StatusUnprocessableEntity,
+	} {
+		if w := createCard(t, h, body); w.Code != code {
+			t.Errorf("%s: expected status %d, got %d", body, code, w.Code)
+		}
+	}
+}
+
+func TestUpdateCard_Autopay(t *testing.T) {
+	h, db := setupFundingTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due) VALUES (1, 'Visa', '1234', 10, 25)`); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+
+	update := func(body string) (*httptest.ResponseRecorder, models.CreditCard) {
+		w := httptest.NewRecorder()
+		h.UpdateCard(w, httptest.NewRequest(http.MethodPut, "/api/v1/cards/1", bytes.NewBufferString(body)))
+		var card models.CreditCard
+		json.NewDecoder(w.Body).Decode(&card)
+		return w, card
+	}
+
+	w, card := update(`{"autopay": "fixed_amount", "autopay_amount": "150.00"}`)
+	if w.Code != http.StatusOK || card.AutopayAmount.String() != "150.00" {
+		t.Fatalf("Expected a 150.00 fixed amount, got %d: %+v", w.Code, card)
+	}
+
+	// A card already on a fixed amount can change just the amount
+	if _, card = update(`{"autopay_amount": "200.00"}`); card.AutopayAmount.String() != "200.00" {
+		t.Errorf("Expected the amount to change to 200.00, got %s", card.AutopayAmount)
+	}
+
+	// Switching mode drops the fixed amount
+	_, card = update(`{"autopay": "statement_balance"}`)
+	if card.Autopay != models.AutopayStatementBalance || card.AutopayAmount != 0 {
+		t.Errorf("Expected statement_balance without an amount, got %s with %s", card.Autopay, card.AutopayAmount)
+	}
+
+	if w, _ := update(`{"autopay_amount": "100.00"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an amount without fixed_amount, got %d", w.Code)
+	}
+}
+
+func TestCreateStatement_Autopay(t *testing.T) {
+	h, db := setupFundingTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO funding_accounts (id, name) VALUES (1, 'Chequing')`); err != nil {
+		t.Fatalf("Failed to insert funding account: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due, autopay, autopay_funding_account_id) VALUES
+		(1, 'Visa', '1234', 10, 25, 'statement_balance', 1),
+		(2, 'Amex', '5678', 10, 25, 'none', NULL)
+	`); err != nil {
+		t.Fatalf("Failed to insert cards: %v", err)
+	}
+
+	body := `{"card_id": 1, "statement_date": "2024-11-10", "due_date": "2024-12-05", "amount": "100.00"}`
+	w := httptest.NewRecorder()
+	h.CreateStatement(w, httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewBufferString(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var stmt models.Statement
+	json.NewDecoder(w.Body).Decode(&stmt)
+
+	// Autopay schedules the payment on the due date from the card's account
+	stored, err := h.statements.Get(t.Context(), stmt.ID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stored.ScheduledPaymentDate == nil || *stored.ScheduledPaymentDate != "2024-12-05" {
+		t.Errorf("Expected the payment scheduled on the due date, got %v", stored.ScheduledPaymentDate)
+	}
+	if stored.FundingAccountID == nil || *stored.FundingAccountID != 1 {
+		t.Errorf("Expected funding account 1, got %v", stored.FundingAccountID)
+	}
+	if stmt.ScheduledPaymentDate == nil || *stmt.ScheduledPaymentDate != "2024-12-05" {
+		t.Errorf("Expected the response to include the scheduled date, got %v", stmt.ScheduledPaymentDate)
+	}
+
+	body = `{"card_id": 2, "statement_date": "2024-11-10", "due_date": "2024-12-05", "amount": "100.00"}`
+	w = httptest.NewRecorder()
+	h.CreateStatement(w, httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewBufferString(body)))
+	json.NewDecoder(w.Body).Decode(&stmt)
+	if stored, _ := h.statements.Get(t.Context(), stmt.ID); stored.ScheduledPaymentDate != nil {
+		t.Errorf("Expected no scheduled payment without autopay, got %s", *stored.ScheduledPaymentDate)
+	}
+
+	w = httptest.NewRecorder()
+	h.CreateStatement(w, httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewBufferString(`{"card_id": 9, "statement_date": "2024-11-10", "due_date": "2024-12-05", "amount": "100.00"}`)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an unknown card, got %d", w.Code)
+	}
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/csvimport"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// CSV row statuses
+const (
+	// CSVRowCreated means the row was stored
+	CSVRowCreated = "created"
+	// CSVRowValid means the row passed validation but wasn't stored, on a
+	// dry run or because an atomic import had errors
+	CSVRowValid = "valid"
+	// CSVRowDuplicate means the card or statement already exists, or an
+	// earlier row has it; the row is skipped
+	CSVRowDuplicate = "duplicate"
+	// CSVRowInvalid means the row failed validation; Error says why
+	CSVRowInvalid = "invalid"
+)
+
+// CSVRowResult reports what happened to one row of an import. Row is the
+// line number in the file, counting the header as line 1.
+type CSVRowResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	ID     int    `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CSVImportResponse summarises a CSV import
+type CSVImportResponse struct {
+	Type   string `json:"type"`
+	DryRun bool   `json:"dry_run"`
+	Atomic bool   `json:"atomic"`
+	// DateFormat is the date format used, given or detected
+	DateFormat string         `json:"date_format"`
+	Created    int            `json:"created"`
+	Duplicates int            `json:"duplicates"`
+	Errors     int            `json:"errors"`
+	Rows       []CSVRowResult `json:"rows"`
+}
+
+// csvRow is a row that passed validation, waiting to be stored
+type csvRow struct {
+	result *CSVRowResult
+	card   *models.CreditCard
+	stmt   *models.Statement
+	// stmtCard is the card a statement belongs to
+	stmtCard models.CreditCard
+}
+
+// ImportCSV creates cards or statements (the type query parameter) from a
+// CSV file with a header row, given as the request body or the "file" field
+// of a multipart form. Columns are matched to fields by name; map.<field>
+// query parameters name a different column, e.g. map.amount=Balance. Dates
+// are read in date_format, or a format detected from the file. Each row is
+// validated like a single create and reported on its own; duplicates are
+// skipped. With atomic=true nothing is stored unless every row is valid, and
+// the rows are stored in one transaction. With dry_run=true nothing is
+// stored.
+func (h *Handler) ImportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	kind := query.Get("type")
+	if !csvimport.ValidKind(kind) {
+		http.Error(w, "type must be cards or statements", http.StatusBadRequest)
+		return
+	}
+	dryRun, ok := parseBoolQuery(w, r, "dry_run")
+	if !ok {
+		return
+	}
+	atomic, ok := parseBoolQuery(w, r, "atomic")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxStatementUpload)
+	body, _, _, err := readUpload(r)
+	if err != nil {
+		log.Printf("Error reading CSV upload: %v", err)
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+
+	file, err := csvimport.Read(body, kind, csvMapping(query), query.Get("date_format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := CSVImportResponse{
+		Type:       kind,
+		DryRun:     dryRun,
+		Atomic:     atomic,
+		DateFormat: file.DateFormat.Name,
+		Rows:       make([]CSVRowResult, len(file.Rows)),
+	}
+	var valid []csvRow
+	if kind == csvimport.Cards {
+		valid, err = h.validateCSVCards(r, file, resp.Rows)
+	} else {
+		valid, err = h.validateCSVStatements(r, file, resp.Rows)
+	}
+	if err != nil {
+		log.Printf("Error validating CSV import: %v", err)
+		http.Error(w, "Failed to import CSV", http.StatusInternalServerError)
+		return
+	}
+	resp.count()
+
+	// An atomic import stores nothing unless every row is valid
+	if dryRun || (atomic && resp.Errors > 0) {
+		code := http.StatusOK
+		if !dryRun {
+			code = http.StatusUnprocessableEntity
+		}
+		writeCSVImport(w, code, resp)
+		return
+	}
+
+	if atomic {
+		if err := h.storeCSVRows(r, valid); err != nil {
+			log.Printf("Error storing CSV import: %v", err)
+			http.Error(w, "Failed to import CSV", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		for _, row := range valid {
+			if err := h.storeCSVRows(r, []csvRow{row}); err != nil {
+				log.Printf("Error storing CSV row %d: %v", row.result.Row, err)
+				row.result.Status = CSVRowInvalid
+				row.result.Error = "failed to store row"
+			}
+		}
+	}
+	resp.count()
+
+	writeCSVImport(w, http.StatusOK, resp)
+}
+
+// validateCSVCards checks each card row as CreateCard would and returns the
+// rows to store. results receives the outcome of every row.
+func (h *Handler) validateCSVCards(r *http.Request, file *csvimport.File, results []CSVRowResult) ([]csvRow, error) {
+	existing, err := h.cards.List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, card := range existing {
+		seen[cardKey(card.Name, card.LastFour)] = true
+	}
+
+	now := h.clock.Now()
+	var valid []csvRow
+	for i := range file.Rows {
+		result := &results[i]
+		result.Row = file.Line(i)
+
+		row, err := file.Card(i)
+		if err != nil {
+			result.Status, result.Error = CSVRowInvalid, err.Error()
+			continue
+		}
+		card, err := newCard(CreateCardRequest{
+			Name:             row.Name,
+			LastFour:         row.LastFour,
+			StatementDate:    row.StatementDate,
+			DueDate:          row.DueDate,
+			CreditLimit:      row.CreditLimit,
+			StatementDayRule: row.StatementDayRule,
+			PaymentPolicy:    row.PaymentPolicy,
+			PaymentLeadDays:  row.PaymentLeadDays,
+			Autopay:          row.Autopay,
+			AutopayAmount:    row.AutopayAmount,
+		}, now)
+		if err != nil {
+			result.Status, result.Error = CSVRowInvalid, err.Error()
+			continue
+		}
+		key := cardKey(card.Name, card.LastFour)
+		if seen[key] {
+			result.Status = CSVRowDuplicate
+			continue
+		}
+		seen[key] = true
+
+		result.Status = CSVRowValid
+		valid = append(valid, csvRow{result: result, card: &card})
+	}
+	return valid, nil
+}
+
+// validateCSVStatements checks each statement row as CreateStatement would
+// and returns the rows to store. results receives the outcome of every row.
+func (h *Handler) validateCSVStatements(r *http.Request, file *csvimport.File, results []CSVRowResult) ([]csvRow, error) {
+	cards, err := h.cards.List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	existing, err := h.statements.List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, stmt := range existing {
+		if stmt.Status != models.StatusExpected {
+			seen[statementKey(stmt.CardID, stmt.StatementDate)] = true
+		}
+	}
+
+	now := h.clock.Now()
+	var valid []csvRow
+	for i := range file.Rows {
+		result := &results[i]
+		result.Row = file.Line(i)
+
+		stmt, card, err := file.Statement(i, cards)
+		if err != nil {
+			result.Status, result.Error = CSVRowInvalid, err.Error()
+			continue
+		}
+		if err := prepareStatement(&stmt, now); err != nil {
+			result.Status, result.Error = CSVRowInvalid, err.Error()
+			continue
+		}
+		key := statementKey(stmt.CardID, stmt.StatementDate)
+		if seen[key] {
+			result.Status = CSVRowDuplicate
+			continue
+		}
+		seen[key] = true
+
+		result.Status = CSVRowValid
+		valid = append(valid, csvRow{result: result, stmt: &stmt, stmtCard: card})
+	}
+	return valid, nil
+}
+
+// storeCSVRows stores validated rows in one transaction and marks them
+// created. Statements left unpaid are followed up like entered ones.
+func (h *Handler) storeCSVRows(r *http.Request, rows []csvRow) error {
+	var cards []*models.CreditCard
+	var stmts []*models.Statement
+	for _, row := range rows {
+		if row.card != nil {
+			cards = append(cards, row.card)
+		} else {
+			stmts = append(stmts, row.stmt)
+		}
+	}
+
+	if len(cards) > 0 {
+		if err := h.cards.CreateAll(r.Context(), cards); err != nil {
+			return err
+		}
+	}
+	if len(stmts) > 0 {
+		if err := h.statements.CreateAll(r.Context(), stmts, importer.ChangedBy); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		row.result.Status = CSVRowCreated
+		if row.card != nil {
+			row.result.ID = row.card.ID
+			continue
+		}
+		row.result.ID = row.stmt.ID
+		if row.stmt.Status == models.StatusPending {
+			h.followup.Entered(r.Context(), row.stmtCard, row.stmt)
+		}
+	}
+	return nil
+}
+
+// count totals the row statuses
+func (resp *CSVImportResponse) count() {
+	resp.Created, resp.Duplicates, resp.Errors = 0, 0, 0
+	for _, row := range resp.Rows {
+		switch row.Status {
+		case CSVRowCreated:
+			resp.Created++
+		case CSVRowDuplicate:
+			resp.Duplicates++
+		case CSVRowInvalid:
+			resp.Errors++
+		}
+	}
+}
+
+func writeCSVImport(w http.ResponseWriter, code int, resp CSVImportResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ExportCSV writes every card or statement (the type query parameter) as
+// CSV in the columns ImportCSV reads. A card's statement_date and due_date
+// are its latest predicted cycle. Expected placeholders aren't exported.
+func (h *Handler) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kind := r.URL.Query().Get("type")
+	if !csvimport.ValidKind(kind) {
+		http.Error(w, "type must be cards or statements", http.StatusBadRequest)
+		return
+	}
+
+	cards, err := h.cards.List(r.Context())
+	if err != nil {
+		log.Printf("Error listing cards for export: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	records := [][]string{csvimport.Columns[kind]}
+	if kind == csvimport.Cards {
+		today := clock.Today(h.clock)
+		for _, card := range cards {
+			statementDate := card.LastStatementDate(today)
+			records = append(records, []string{
+				card.Name,
+				card.LastFour,
+				statementDate.Format(models.DateFormat),
+				card.ExpectedDueDate(statementDate).Format(models.DateFormat),
+				optionalMoney(card.CreditLimit),
+				card.StatementDayRule,
+				card.PaymentPolicy,
+				strconv.Itoa(card.PaymentLeadDays),
+				card.Autopay,
+				optionalMoney(card.AutopayAmount),
+			})
+		}
+	} else {
+		statements, err := h.statements.List(r.Context())
+		if err != nil {
+			log.Printf("Error listing statements for export: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		byID := map[int]models.CreditCard{}
+		for _, card := range cards {
+			byID[card.ID] = card
+		}
+		for _, stmt := range statements {
+			if stmt.Status == models.StatusExpected {
+				continue
+			}
+			card := byID[stmt.CardID]
+			balance := ""
+			if stmt.CurrentBalance != nil {
+				balance = stmt.CurrentBalance.String()
+			}
+			records = append(records, []string{
+				strconv.Itoa(stmt.CardID),
+				card.Name,
+				card.LastFour,
+				stmt.StatementDate,
+				stmt.DueDate,
+				stmt.Amount.String(),
+				optionalMoney(stmt.MinimumPayment),
+				balance,
+				stmt.Status,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", kind+".csv"))
+	w.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(records); err != nil {
+		log.Printf("Error writing CSV export: %v", err)
+	}
+}
+
+// csvMapping reads the map.<field>=<column> query parameters, keyed by field
+func csvMapping(query url.Values) map[string]string {
+	mapping := map[string]string{}
+	for key, values := range query {
+		if field, ok := strings.CutPrefix(key, "map."); ok {
+			mapping[field] = values[0]
+		}
+	}
+	return mapping
+}
+
+// optionalMoney formats zero as an empty cell
+func optionalMoney(m models.Money) string {
+	if m == 0 {
+		return ""
+	}
+	return m.String()
+}
+
+func cardKey(name, lastFour string) string {
+	return strings.ToLower(name) + "|" + lastFour
+}
+
+func statementKey(cardID int, statementDate string) string {
+	return strconv.Itoa(cardID) + "|" + statementDate
+}
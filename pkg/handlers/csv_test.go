@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func importCSV(t *testing.T, h *Handler, query string, body string, wantCode int) CSVImportResponse {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import/csv"+query, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ImportCSV(w, req)
+	if w.Code != wantCode {
+		t.Fatalf("Expected status %d, got %d: %s", wantCode, w.Code, w.Body.String())
+	}
+
+	var resp CSVImportResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func rowStatuses(resp CSVImportResponse) []string {
+	var statuses []string
+	for _, row := range resp.Rows {
+		statuses = append(statuses, row.Status)
+	}
+	return statuses
+}
+
+func TestImportCSV_Cards(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	body := `Name,Last Four,Statement Date,Due Date,Credit Limit
+Visa,1234,11/10/2024,12/05/2024,"$5,000.00"
+Amex,9876,11/15/2024,12/10/2024,
+visa,1234,11/10/2024,12/05/2024,
+Broken,12,11/10/2024,12/05/2024,
+`
+	resp := importCSV(t, h, "?type=cards", body, http.StatusOK)
+	if resp.DateFormat != "MM/DD/YYYY" {
+		t.Errorf("Expected MM/DD/YYYY to be detected, got %s", resp.DateFormat)
+	}
+	want := []string{CSVRowCreated, CSVRowCreated, CSVRowDuplicate, CSVRowInvalid}
+	if got := rowStatuses(resp); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	if resp.Created != 2 || resp.Duplicates != 1 || resp.Errors != 1 {
+		t.Errorf("Unexpected totals: %+v", resp)
+	}
+	if resp.Rows[3].Row != 5 || resp.Rows[3].Error == "" {
+		t.Errorf("Expected row 5 to report an error, got %+v", resp.Rows[3])
+	}
+
+	cards, _ := h.cards.List(t.Context())
+	if len(cards) != 2 {
+		t.Fatalf("Expected 2 cards, got %d", len(cards))
+	}
+	for _, card := range cards {
+		if card.Name == "Visa" && (card.StatementDay != 10 || card.CreditLimit.String() != "5000.00") {
+			t.Errorf("Unexpected card: %+v", card)
+		}
+	}
+}
+
+func TestImportCSV_StatementsMapping(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	if w := createCard(t, h, `{"name": "Visa", "last_four": "1234", "statement_date": "2024-11-10", "due_date": "2024-12-05"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create card: %s", w.Body.String())
+	}
+
+	// 25/11/2024 only reads day-first, so the whole file is read that way
+	body := `Card,Closing,Due,New Balance
+1234,10/10/2024,05/11/2024,100.00
+1234,10/11/2024,25/11/2024,"1,250.75"
+1234,10/11/2024,25/11/2024,1250.75
+5555,10/11/2024,25/11/2024,1.00
+`
+	resp := importCSV(t, h, "?type=statements&map.last_four=Card&map.statement_date=closing&map.due_date=Due&map.amount=New+Balance", body, http.StatusOK)
+	if resp.DateFormat != "DD/MM/YYYY" {
+		t.Errorf("Expected DD/MM/YYYY to be detected, got %s", resp.DateFormat)
+	}
+	want := []string{CSVRowCreated, CSVRowCreated, CSVRowDuplicate, CSVRowInvalid}
+	if got := rowStatuses(resp); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+
+	stmt, err := h.statements.Get(t.Context(), resp.Rows[1].ID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stmt.StatementDate != "2024-11-10" || stmt.DueDate != "2024-11-25" || stmt.Amount.String() != "1250.75" {
+		t.Errorf("Unexpected statement: %+v", stmt)
+	}
+}
+
+func TestImportCSV_Atomic(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	body := `name,last_four,statement_date,due_date
+Visa,1234,2024-11-10,2024-12-05
+Amex,98x6,2024-11-15,2024-12-10
+`
+	resp := importCSV(t, h, "?type=cards&atomic=true", body, http.StatusUnprocessableEntity)
+	if resp.Errors != 1 || resp.Rows[0].Status != CSVRowValid {
+		t.Errorf("Expected one error and one valid row, got %+v", resp)
+	}
+	if cards, _ := h.cards.List(t.Context()); len(cards) != 0 {
+		t.Fatalf("Expected nothing stored, got %d cards", len(cards))
+	}
+
+	body = strings.Replace(body, "98x6", "9876", 1)
+	preview := importCSV(t, h, "?type=cards&atomic=true&dry_run=true", body, http.StatusOK)
+	if preview.Created != 0 || preview.Rows[1].Status != CSVRowValid {
+		t.Errorf("Expected a valid preview, got %+v", preview)
+	}
+	if cards, _ := h.cards.List(t.Context()); len(cards) != 0 {
+		t.Fatalf("Expected the dry run to store nothing, got %d cards", len(cards))
+	}
+
+	resp = importCSV(t, h, "?type=cards&atomic=true", body, http.StatusOK)
+	if resp.Created != 2 {
+		t.Errorf("Expected 2 created, got %+v", resp)
+	}
+}
+
+func TestImportCSV_Invalid(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"unknown type", "?type=payments", "name\n"},
+		{"missing column", "?type=cards", "name,last_four,due_date\nVisa,1234,2024-12-05\n"},
+		{"unknown mapped field", "?type=cards&map.nickname=Nick", "name\n"},
+		{"mapped column missing", "?type=cards&map.name=Card", "name,last_four,statement_date,due_date\n"},
+		{"unknown date format", "?type=cards&date_format=julian", "name,last_four,statement_date,due_date\n"},
+		{"empty file", "?type=cards", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/import/csv"+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.ImportCSV(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestExportCSV_RoundTrip(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	body := `name,last_four,statement_date,due_date
+Visa,1234,2024-11-10,2024-12-05
+`
+	importCSV(t, h, "?type=cards", body, http.StatusOK)
+	body = `last_four,statement_date,due_date,amount,minimum_payment
+1234,2024-11-10,2024-12-05,1250.75,25.00
+`
+	importCSV(t, h, "?type=statements", body, http.StatusOK)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export/csv?type=statements", nil)
+	w := httptest.NewRecorder()
+	h.ExportCSV(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("Expected a CSV export, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	exported := w.Body.String()
+	if !strings.Contains(exported, "Visa,1234,2024-11-10,2024-12-05,1250.75,25.00,,pending") {
+		t.Errorf("Unexpected export:\n%s", exported)
+	}
+
+	resp := importCSV(t, h, "?type=statements", exported, http.StatusOK)
+	if resp.Duplicates != 1 || resp.Created != 0 {
+		t.Errorf("Expected re-importing the export to find a duplicate, got %+v", resp)
+	}
+}
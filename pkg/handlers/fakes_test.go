@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/dashboard"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// memStore is an in-memory implementation of the card and statement
+// repositories for handler tests that don't need SQLite
+type memStore struct {
+	mu         sync.Mutex
+	cards      map[int]models.CreditCard
+	statements map[int]models.Statement
+	history    []models.StatusChange
+	payments   []models.Payment
+	nextID     int
+	err        error
+}
+
+func newMemStore() *memStore {
+	return &memStore{cards: map[int]models.CreditCard{}, statements: map[int]models.Statement{}}
+}
+
+// memCards adapts memStore to repository.CardRepository
+type memCards struct{ *memStore }
+
+// memStatements adapts memStore to repository.StatementRepository
+type memStatements struct{ *memStore }
+
+// newMemHandler returns a handler backed by a fresh memStore
+func newMemHandler() (*Handler, *memStore) {
+	store := newMemStore()
+	return New(memCards{store}, memStatements{store}, nil, nil), store
+}
+
+func (s *memStore) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+func (c memCards) List(ctx context.Context) ([]models.CreditCard, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	cards := []models.CreditCard{}
+	for _, card := range c.cards {
+		cards = append(cards, card)
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].Name < cards[j].Name })
+	return cards, nil
+}
+
+func (c memCards) Get(ctx context.Context, id int) (models.CreditCard, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	card, ok := c.cards[id]
+	if !ok {
+		return card, repository.ErrNotFound
+	}
+	return card, nil
+}
+
+func (c memCards) Exists(ctx context.Context, id int) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.cards[id]
+	return ok, c.err
+}
+
+func (c memCards) Create(ctx context.Context, card *models.CreditCard) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	card.ID = c.id()
+	c.cards[card.ID] = *card
+	return nil
+}
+
+func (c memCards) CreateAll(ctx context.Context, cards []*models.CreditCard) error {
+	for _, card := range cards {
+		c.Create(ctx, card)
+	}
+	return nil
+}
+
+func (c memCards) Update(ctx context.Context, id int, update repository.CardUpdate) (models.CreditCard, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	card, ok := c.cards[id]
+	if !ok {
+		return card, repository.ErrNotFound
+	}
+	if update.Name != nil {
+		card.Name = *update.Name
+	}
+	if update.LastFour != nil {
+		card.LastFour = *update.LastFour
+	}
+	if update.StatementDayRule != nil {
+		card.StatementDayRule = *update.StatementDayRule
+	}
+	if update.StatementDay != nil {
+		card.StatementDay = *update.StatementDay
+	}
+	if update.DaysUntilDue != nil {
+		card.DaysUntilDue = *update.DaysUntilDue
+	}
+	if update.CreditLimit != nil {
+		card.CreditLimit = *update.CreditLimit
+	}
+	if update.PaymentPolicy != nil {
+		card.PaymentPolicy = *update.PaymentPolicy
+	}
+	if update.PaymentLeadDays != nil {
+		card.PaymentLeadDays = *update.PaymentLeadDays
+	}
+	card.UpdatedAt = update.UpdatedAt
+	c.cards[id] = card
+	return card, nil
+}
+
+func (c memCards) Delete(ctx context.Context, id int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.cards[id]; !ok {
+		return 0, repository.ErrNotFound
+	}
+	delete(c.cards, id)
+	deleted := 0
+	for stmtID, stmt := range c.statements {
+		if stmt.CardID == id {
+			delete(c.statements, stmtID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s memStatements) List(ctx context.Context) ([]models.Statement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	statements := []models.Statement{}
+	for _, stmt := range s.statements {
+		statements = append(statements, stmt)
+	}
+	sort.Slice(statements, func(i, j int) bool { return statements[i].DueDate > statements[j].DueDate })
+	return statements, nil
+}
+
+func (s memStatements) Get(ctx context.Context, id int) (models.Statement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stmt, ok := s.statements[id]
+	if !ok {
+		return stmt, repository.ErrNotFound
+	}
+	return stmt, nil
+}
+
+func (s memStatements) Create(ctx context.Context, stmt *models.Statement) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stmt.ID = s.id()
+	s.statements[stmt.ID] = *stmt
+	return nil
+}
+
+func (s memStatements) CreateAll(ctx context.Context, stmts []*models.Statement, changedBy string) error {
+	for _, stmt := range stmts {
+		placeholder, err := s.FindExpected(ctx, stmt.CardID, stmt.StatementDate)
+		if err == nil {
+			stmt.ID = placeholder.ID
+			err = s.Fill(ctx, stmt, changedBy)
+		} else {
+			err = s.Create(ctx, stmt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s memStatements) Update(ctx context.Context, id int, update repository.StatementUpdate) (models.Statement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stmt, ok := s.statements[id]
+	if !ok {
+		return stmt, repository.ErrNotFound
+	}
+	if update.CardID != nil {
+		stmt.CardID = *update.CardID
+	}
+	if update.StatementDate != nil {
+		stmt.StatementDate = *update.StatementDate
+	}
+	if update.DueDate != nil {
+		stmt.DueDate = *update.DueDate
+	}
+	if update.Amount != nil {
+		stmt.Amount = *update.Amount
+	}
+	stmt.UpdatedAt = update.UpdatedAt
+	s.statements[id] = stmt
+	return stmt, nil
+}
+
+func (s memStatements) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.statements[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(s.statements, id)
+	return nil
+}
+
+func (s memStatements) ChangeStatus(ctx context.Context, change models.StatusChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stmt, ok := s.statements[change.StatementID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if stmt.Status != change.FromStatus {
+		return repository.ErrStatusConflict
+	}
+	stmt.Status = change.ToStatus
+	stmt.StatusChangedAt = &change.ChangedAt
+	stmt.StatusChangedBy = &change.ChangedBy
+	stmt.UpdatedAt = change.ChangedAt
+	s.statements[change.StatementID] = stmt
+	change.ID = s.id()
+	s.history = append(s.history, change)
+	return nil
+}
+
+func (s memStatements) MarkOverdue(ctx context.Context, today string, changedBy string, at time.Time) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []int{}
+	for id, stmt := range s.statements {
+		if stmt.IsPastDue(today) {
+			stmt.Status = models.StatusOverdue
+			s.statements[id] = stmt
+			s.history = append(s.history, models.StatusChange{ID: s.id(), StatementID: id,
+				FromStatus: models.StatusPending, ToStatus: models.StatusOverdue, ChangedBy: changedBy, ChangedAt: at})
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func (s memStatements) StatusHistory(ctx context.Context, id int) ([]models.StatusChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := []models.StatusChange{}
+	for _, change := range s.history {
+		if change.StatementID == id {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (s memStatements) ListPayments(ctx context.Context, statementID int) ([]models.Payment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payments := []models.Payment{}
+	for _, p := range s.payments {
+		if p.StatementID == statementID {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+func (s memStatements) AddPayment(ctx context.Context, payment *models.Payment) (models.Statement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stmt, ok := s.statements[payment.StatementID]
+	if !ok {
+		return models.Statement{}, repository.ErrNotFound
+	}
+	payment.ID = s.id()
+	s.payments = append(s.payments, *payment)
+	stmt.SetPaidAmount(stmt.PaidAmount + payment.Amount)
+	if stmt.IsCovered() {
+		stmt.Status = models.StatusPaid
+	}
+	s.statements[payment.StatementID] = stmt
+	return stmt, nil
+}
+
+func (s memStatements) DeletePayment(ctx context.Context, statementID, paymentID int, at time.Time) (models.Statement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, p := range s.payments {
+		if p.ID == paymentID && p.StatementID == statementID {
+			s.payments = append(s.payments[:i], s.payments[i+1:]...)
+			stmt := s.statements[statementID]
+			stmt.SetPaidAmount(stmt.PaidAmount - p.Amount)
+			s.statements[statementID] = stmt
+			return stmt, nil
+		}
+	}
+	return models.Statement{}, repository.ErrNotFound
+}
+
+func (s memStatements) SchedulePayment(ctx context.Context, id int, date string, fundingAccountID *int, reviewedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stmt, ok := s.statements[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	stmt.ScheduledPaymentDate = &date
+	if fundingAccountID != nil {
+		stmt.FundingAccountID = fundingAccountID
+		if *fundingAccountID == 0 {
+			stmt.FundingAccountID = nil
+		}
+	}
+	stmt.ReviewedAt = &reviewedAt
+	stmt.UpdatedAt = reviewedAt
+	s.statements[id] = stmt
+	return nil
+}
+
+func (s memStatements) FindExpected(ctx context.Context, cardID int, statementDate string) (models.Statement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	date, err := time.Parse(models.DateFormat, statementDate)
+	if err != nil {
+		return models.Statement{}, err
+	}
+	from := date.AddDate(0, 0, -models.StatementEntryGraceDays).Format(models.DateFormat)
+	to := date.AddDate(0, 0, models.StatementEntryGraceDays).Format(models.DateFormat)
+	for _, stmt := range s.statements {
+		if stmt.CardID == cardID && stmt.Status == models.StatusExpected &&
+			stmt.StatementDate >= from && stmt.StatementDate <= to {
+			return stmt, nil
+		}
+	}
+	return models.Statement{}, repository.ErrNotFound
+}
+
+func (s memStatements) Fill(ctx context.Context, stmt *models.Statement, changedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.statements[stmt.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if existing.Status != models.StatusExpected {
+		return repository.ErrStatusConflict
+	}
+	stmt.CreatedAt = existing.CreatedAt
+	stmt.SetPaidAmount(existing.PaidAmount)
+	s.statements[stmt.ID] = *stmt
+	s.history = append(s.history, models.StatusChange{ID: s.id(), StatementID: stmt.ID,
+		FromStatus: models.StatusExpected, ToStatus: stmt.Status, ChangedBy: changedBy, ChangedAt: stmt.UpdatedAt})
+	return nil
+}
+
+func TestMemHandler_CreateCardAndStatement(t *testing.T) {
+	h, store := newMemHandler()
+
+	body := bytes.NewBufferString(`{"name": "Amex Cobalt", "last_four": "1234", "statement_date": "2024-11-28", "due_date": "2024-12-23"}`)
+	w := httptest.NewRecorder()
+	h.CreateCard(w, httptest.NewRequest(http.MethodPost, "/api/v1/cards", body))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	var card models.CreditCard
+	json.NewDecoder(w.Body).Decode(&card)
+	if card.StatementDay != 28 || card.DaysUntilDue != 25 {
+		t.Errorf("Unexpected card %+v", card)
+	}
+
+	stmt, _ := json.Marshal(models.Statement{CardID: card.ID, StatementDate: "2024-11-28", DueDate: "2024-12-23", Amount: 10000})
+	w = httptest.NewRecorder()
+	h.CreateStatement(w, httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewReader(stmt)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+	if len(store.statements) != 1 {
+		t.Errorf("Expected 1 statement in the store, got %d", len(store.statements))
+	}
+}
+
+func TestMemHandler_RepositoryError(t *testing.T) {
+	h, store := newMemHandler()
+	store.err = errors.New("disk on fire")
+
+	w := httptest.NewRecorder()
+	h.GetCards(w, httptest.NewRequest(http.MethodGet, "/api/v1/cards", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+}
+
+func TestMemHandler_UpdateStatementNotFound(t *testing.T) {
+	h, _ := newMemHandler()
+
+	body := bytes.NewBufferString(`{"status": "paid"}`)
+	w := httptest.NewRecorder()
+	h.UpdateStatement(w, httptest.NewRequest(http.MethodPut, "/api/v1/statements/42", body))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestMemHandler_GetDashboard(t *testing.T) {
+	h, store := newMemHandler()
+	h.clock = clock.NewFixed(time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC))
+	store.cards[1] = models.CreditCard{ID: 1, Name: "Amex Cobalt", LastFour: "1234", StatementDay: 28, DaysUntilDue: 25}
+	store.statements[2] = models.Statement{ID: 2, CardID: 1, StatementDate: "2024-10-28", DueDate: "2024-11-15", Amount: 10000, Status: models.StatusPending}
+
+	w := httptest.NewRecorder()
+	h.GetDashboard(w, httptest.NewRequest(http.MethodGet, "/api/v1/dashboard", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var d dashboard.Dashboard
+	json.NewDecoder(w.Body).Decode(&d)
+	if d.Date != "2024-11-20" {
+		t.Errorf("Expected date 2024-11-20, got %s", d.Date)
+	}
+	if len(d.UpcomingStatements) != 1 || d.UpcomingStatements[0].StatementDate != "2024-11-28" {
+		t.Errorf("Unexpected upcoming statements %+v", d.UpcomingStatements)
+	}
+	if len(d.UnscheduledPayments) != 1 || d.UnscheduledPayments[0].Status != models.StatusOverdue {
+		t.Errorf("Expected the past due statement as overdue, got %+v", d.UnscheduledPayments)
+	}
+}
+
+func TestMemHandler_GetDashboardMethodNotAllowed(t *testing.T) {
+	h, _ := newMemHandler()
+
+	w := httptest.NewRecorder()
+	h.GetDashboard(w, httptest.NewRequest(http.MethodPost, "/api/v1/dashboard", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/planner"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// DefaultOutflowDays is how many days the outflows view covers by default
+const DefaultOutflowDays = 7
+
+// checkFundingAccount writes an error and returns false unless id is a
+// stored funding account; failure is the message used if the check fails
+func (h *Handler) checkFundingAccount(w http.ResponseWriter, r *http.Request, id int, failure string) bool {
+	if h.funding == nil {
+		http.Error(w, "Funding accounts are not available", http.StatusNotImplemented)
+		return false
+	}
+	exists, err := h.funding.Exists(r.Context(), id)
+	if err != nil {
+		log.Printf("Error checking funding account: %v", err)
+		http.Error(w, failure, http.StatusInternalServerError)
+		return false
+	}
+	if !exists {
+		http.Error(w, "funding_account_id does not refer to an existing funding account", http.StatusUnprocessableEntity)
+		return false
+	}
+	return true
+}
+
+// fundingAccountID extracts the ID from a path such as
+// /api/v1/funding-accounts/1 or /api/v1/funding-accounts/1/outflows
+func fundingAccountID(path string) (int, error) {
+	pathParts := strings.Split(path, "/")
+	if len(pathParts) < 5 {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.Atoi(pathParts[4])
+}
+
+// GetFundingAccounts returns every funding account
+func (h *Handler) GetFundingAccounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accounts := []models.FundingAccount{}
+	if h.funding != nil {
+		var err error
+		accounts, err = h.funding.List(r.Context())
+		if err != nil {
+			log.Printf("Error querying funding accounts: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// CreateFundingAccount adds an account payments are made from
+func (h *Handler) CreateFundingAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.funding == nil {
+		http.Error(w, "Funding accounts are not available", http.StatusNotImplemented)
+		return
+	}
+
+	var account models.FundingAccount
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		log.Printf("Error decoding funding account: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	account.Name = strings.TrimSpace(account.Name)
+	account.Institution = strings.TrimSpace(account.Institution)
+	if err := account.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := h.clock.Now()
+	account.ID = 0
+	account.CreatedAt = now
+	account.UpdatedAt = now
+
+	if err := h.funding.Create(r.Context(), &account); err != nil {
+		log.Printf("Error creating funding account: %v", err)
+		http.Error(w, "Failed to create funding account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(account)
+}
+
+// UpdateFundingAccountRequest represents the request body for editing a
+// funding account. Omitted fields are left unchanged.
+type UpdateFundingAccountRequest struct {
+	Name        *string       `json:"name"`
+	Institution *string       `json:"institution"`
+	LastFour    *string       `json:"last_four"`
+	Balance     *models.Money `json:"balance"`
+}
+
+// UpdateFundingAccount edits the provided fields of a funding account, such
+// as its tracked balance
+func (h *Handler) UpdateFundingAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.funding == nil {
+		http.Error(w, "Funding accounts are not available", http.StatusNotImplemented)
+		return
+	}
+
+	id, err := fundingAccountID(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid funding account ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateFundingAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding funding account: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.funding.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Funding account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying funding account %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Validate the account as it will be after the changes
+	update := repository.FundingAccountUpdate{UpdatedAt: h.clock.Now()}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		account.Name = name
+		update.Name = &name
+	}
+	if req.Institution != nil {
+		institution := strings.TrimSpace(*req.Institution)
+		account.Institution = institution
+		update.Institution = &institution
+	}
+	if req.LastFour != nil {
+		account.LastFour = *req.LastFour
+		update.LastFour = req.LastFour
+	}
+	if req.Balance != nil {
+		account.Balance = req.Balance
+		update.Balance = req.Balance
+	}
+	if err := account.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err = h.funding.Update(r.Context(), id, update)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Funding account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating funding account %d: %v", id, err)
+		http.Error(w, "Failed to update funding account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(account)
+}
+
+// DeleteFundingAccount removes a funding account. Its statements are kept
+// without an account.
+func (h *Handler) DeleteFundingAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.funding == nil {
+		http.Error(w, "Funding accounts are not available", http.StatusNotImplemented)
+		return
+	}
+
+	id, err := fundingAccountID(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid funding account ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.funding.Delete(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Funding account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting funding account %d: %v", id, err)
+		http.Error(w, "Failed to delete funding account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Funding account deleted successfully"})
+}
+
+// GetFundingAccountOutflows lists the card payments leaving a funding account
+// by date over the number of days given by the days query parameter
+func (h *Handler) GetFundingAccountOutflows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.funding == nil {
+		http.Error(w, "Funding accounts are not available", http.StatusNotImplemented)
+		return
+	}
+
+	id, err := fundingAccountID(r.URL.Path)
+	if err != nil {
+		http.Error(w, "Invalid funding account ID", http.StatusBadRequest)
+		return
+	}
+
+	days := DefaultOutflowDays
+	if s := r.URL.Query().Get("days"); s != "" {
+		days, err = strconv.Atoi(s)
+		if err != nil || days < 1 || days > 366 {
+			http.Error(w, "days must be between 1 and 366", http.StatusBadRequest)
+			return
+		}
+	}
+
+	account, err := h.funding.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Funding account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying funding account %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	cards, err := h.cards.List(r.Context())
+	if err != nil {
+		log.Printf("Error querying credit cards: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	statements, err := h.statements.List(r.Context())
+	if err != nil {
+		log.Printf("Error querying statements: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	cal, err := h.loadCalendar(r.Context())
+	if err != nil {
+		log.Printf("Error loading holidays: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	paydays, err := h.paydays(r.Context(), cal)
+	if err != nil {
+		log.Printf("Error loading income schedules: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	outflows := planner.AccountOutflows(account, cards, statements, cal, paydays, clock.Today(h.clock), days)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(outflows)
+}
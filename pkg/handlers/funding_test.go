@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/planner"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// setupFundingTestDB creates a test database with funding accounts enabled
+func setupFundingTestDB(t *testing.T) (*Handler, *sql.DB) {
+	h, db := setupTestDB(t)
+	h.funding = repository.NewSQLiteFundingAccountRepository(db)
+	return h, db
+}
+
+func createFundingAccount(t *testing.T, h *Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/funding-accounts", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	h.CreateFundingAccount(w, req)
+	return w
+}
+
+func TestFundingAccounts(t *testing.T) {
+	h, _ := setupFundingTestDB(t)
+
+	w := createFundingAccount(t, h, `{"name": "Chequing", "institution": "Tangerine", "last_four": "0042", "balance": "2500.00"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.FundingAccount
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.ID == 0 || created.Balance == nil || created.Balance.String() != "2500.00" {
+		t.Errorf("Unexpected account %+v", created)
+	}
+
+	path := fmt.Sprintf("/api/v1/funding-accounts/%d", created.ID)
+	w = httptest.NewRecorder()
+	h.UpdateFundingAccount(w, httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"balance": "1800.25"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated models.FundingAccount
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Balance.String() != "1800.25" || updated.LastFour != "0042" {
+		t.Errorf("Expected only the balance to change, got %+v", updated)
+	}
+
+	w = httptest.NewRecorder()
+	h.UpdateFundingAccount(w, httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"last_four": "42"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid last_four, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetFundingAccounts(w, httptest.NewRequest(http.MethodGet, "/api/v1/funding-accounts", nil))
+	var list []models.FundingAccount
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 || list[0].Name != "Chequing" {
+		t.Errorf("Expected the chequing account, got %+v", list)
+	}
+
+	w = httptest.NewRecorder()
+	h.DeleteFundingAccount(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.DeleteFundingAccount(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting twice, got %d", w.Code)
+	}
+}
+
+func TestCreateFundingAccount_Invalid(t *testing.T) {
+	h, _ := setupFundingTestDB(t)
+
+	for _, body := range []string{
+		`{"institution": "Tangerine"}`,
+		`{"name": "Chequing", "last_four": "abcd"}`,
+		`{"name": "Chequing", "balance": "-5.00"}`,
+		`not json`,
+	} {
+		if w := createFundingAccount(t, h, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	// Without a repository accounts can't be stored
+	mem, _ := newMemHandler()
+	if w := createFundingAccount(t, mem, `{"name": "Chequing"}`); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d", w.Code)
+	}
+}
+
+func TestStatementFundingAccount(t *testing.T) {
+	h, db := setupFundingTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due) VALUES (1, 'Visa', '1234', 10, 25)`); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO funding_accounts (id, name) VALUES (1, 'Chequing'), (2, 'Savings')`); err != nil {
+		t.Fatalf("Failed to insert funding accounts: %v", err)
+	}
+
+	body := `{"card_id": 1, "statement_date": "2024-11-10", "due_date": "2024-12-05", "amount": "100.00", "funding_account_id": 99}`
+	w := httptest.NewRecorder()
+	h.CreateStatement(w, httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewBufferString(body)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an unknown funding account, got %d", w.Code)
+	}
+
+	body = `{"card_id": 1, "statement_date": "2024-11-10", "due_date": "2024-12-05", "amount": "100.00", "funding_account_id": 1}`
+	w = httptest.NewRecorder()
+	h.CreateStatement(w, httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewBufferString(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var stmt models.Statement
+	json.NewDecoder(w.Body).Decode(&stmt)
+	if stmt.FundingAccountID == nil || *stmt.FundingAccountID != 1 {
+		t.Errorf("Expected funding account 1, got %v", stmt.FundingAccountID)
+	}
+
+	// Scheduling the payment can move it to another account
+	path := fmt.Sprintf("/api/v1/statements/%d/schedule", stmt.ID)
+	w = httptest.NewRecorder()
+	h.SchedulePayment(w, httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"scheduled_payment_date": "2024-11-28", "funding_account_id": 2}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	stmt, _ = h.statements.Get(t.Context(), stmt.ID)
+	if stmt.FundingAccountID == nil || *stmt.FundingAccountID != 2 {
+		t.Errorf("Expected funding account 2 after scheduling, got %v", stmt.FundingAccountID)
+	}
+
+	w = httptest.NewRecorder()
+	h.SchedulePayment(w, httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"scheduled_payment_date": "2024-11-28", "funding_account_id": 99}`)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 scheduling from an unknown account, got %d", w.Code)
+	}
+
+	// Patching 0 clears the account
+	w = httptest.NewRecorder()
+	h.PatchStatement(w, httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/statements/%d", stmt.ID), bytes.NewBufferString(`{"funding_account_id": 0}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var patched models.Statement
+	json.NewDecoder(w.Body).Decode(&patched)
+	if patched.FundingAccountID != nil {
+		t.Errorf("Expected no funding account, got %v", *patched.FundingAccountID)
+	}
+}
+
+func TestGetFundingAccountOutflows(t *testing.T) {
+	h, db := setupFundingTestDB(t)
+	h.clock = clock.NewFixed(time.Date(2024, time.November, 4, 9, 0, 0, 0, time.UTC))
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due) VALUES (1, 'Visa', '1234', 20, 25), (2, 'Amex', '5678', 22, 25)`); err != nil {
+		t.Fatalf("Failed to insert cards: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO funding_accounts (id, name, balance_cents) VALUES (1, 'Chequing', 100000)`); err != nil {
+		t.Fatalf("Failed to insert funding account: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO statements (id, card_id, statement_date, due_date, amount_cents, status, scheduled_payment_date, funding_account_id) VALUES
+		(1, 1, '2024-10-20', '2024-11-14', 60000, 'pending', '2024-11-06', 1),
+		(2, 2, '2024-10-22', '2024-11-16', 70000, 'pending', '2024-11-08', 1),
+		(3, 2, '2024-10-22', '2024-11-16', 90000, 'pending', '2024-11-08', NULL)
+	`); err != nil {
+		t.Fatalf("Failed to insert statements: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/funding-accounts/1/outflows", nil)
+	w := httptest.NewRecorder()
+	h.GetFundingAccountOutflows(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var outflows planner.Outflows
+	if err := json.NewDecoder(w.Body).Decode(&outflows); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// The week's payments from chequing overdraw it on the 8th
+	if outflows.From != "2024-11-04" || outflows.To != "2024-11-10" || len(outflows.Days) != 2 {
+		t.Fatalf("Expected two days of outflows this week, got %+v", outflows)
+	}
+	if day := outflows.Days[1]; day.Date != "2024-11-08" || day.BalanceAfter.String() != "-300.00" {
+		t.Errorf("Expected the balance to go to -300.00 on 2024-11-08, got %+v", day)
+	}
+	if outflows.Total.String() != "1300.00" || !outflows.Shortfall {
+		t.Errorf("Expected a 1300.00 shortfall, got %s (%v)", outflows.Total, outflows.Shortfall)
+	}
+
+	for path, code := range map[string]int{
+		"/api/v1/funding-accounts/1/outflows?days=0": http.StatusBadRequest,
+		"/api/v1/funding-accounts/abc/outflows":      http.StatusBadRequest,
+		"/api/v1/funding-accounts/9/outflows":        http.StatusNotFound,
+		"/api/v1/funding-accounts/1/outflows?days=1": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		h.GetFundingAccountOutflows(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Errorf("%s: expected status %d, got %d", path, code, w.Code)
+		}
+	}
+}
@@ -1,24 +1,135 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
 	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
-	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/dashboard"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/followup"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ingest"
 	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/notify"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ynab"
 )
 
+// Handler serves the HTTP API using the repositories it is given
+type Handler struct {
+	cards      repository.CardRepository
+	statements repository.StatementRepository
+	notifier   *notify.Notifier
+	clock      clock.Clock
+	calendar   *holidays.Calendar
+	holidays   repository.HolidayRepository
+	income     repository.IncomeRepository
+	funding    repository.FundingAccountRepository
+	ynab       *ynab.Syncer
+	// attachments stores uploaded statement PDFs
+	attachments repository.AttachmentRepository
+	// ingest reads statement emails
+	ingest *ingest.Ingester
+	// followup schedules autopay, announces statements and syncs YNAB
+	followup *followup.Service
+}
+
+// Option configures optional Handler dependencies
+type Option func(*Handler)
+
+// WithCalendar skips the federal holidays of the regions of cal when working
+// out business days. Without it those of holidays.DefaultRegions are skipped.
+func WithCalendar(cal *holidays.Calendar) Option {
+	return func(h *Handler) {
+		h.calendar = cal
+	}
+}
+
+// WithHolidays stores uploaded holiday lists in repo. Without it only the
+// built-in federal holidays are used and uploads are rejected.
+func WithHolidays(repo repository.HolidayRepository) Option {
+	return func(h *Handler) {
+		h.holidays = repo
+	}
+}
+
+// WithIncome stores payday schedules in repo. Without it cards paid on payday
+// fall back to their lead time and plans only use cash on hand.
+func WithIncome(repo repository.IncomeRepository) Option {
+	return func(h *Handler) {
+		h.income = repo
+	}
+}
+
+// WithFundingAccounts stores the accounts payments are made from in repo.
+// Without it statements can't name a funding account.
+func WithFundingAccounts(repo repository.FundingAccountRepository) Option {
+	return func(h *Handler) {
+		h.funding = repo
+	}
+}
+
+// WithAttachments stores uploaded statement PDFs in repo. Without it
+// uploads are rejected.
+func WithAttachments(repo repository.AttachmentRepository) Option {
+	return func(h *Handler) {
+		h.attachments = repo
+	}
+}
+
+// WithIngest reads uploaded statement emails through ingester and follows
+// up on the statements it fills in. Without it uploads are rejected.
+func WithIngest(ingester *ingest.Ingester) Option {
+	return func(h *Handler) {
+		h.ingest = ingester
+		ingester.OnImported = func(ctx context.Context, result importer.Result) {
+			h.followup.Imported(ctx, result)
+		}
+	}
+}
+
+// WithYNAB mirrors scheduled payments into YNAB through syncer
+func WithYNAB(syncer *ynab.Syncer) Option {
+	return func(h *Handler) {
+		h.ynab = syncer
+	}
+}
+
+// New creates a Handler. A nil notifier disables notifications and a nil
+// clock uses the real time.
+func New(cards repository.CardRepository, statements repository.StatementRepository, notifier *notify.Notifier, clk clock.Clock, opts ...Option) *Handler {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	h := &Handler{
+		cards:      cards,
+		statements: statements,
+		notifier:   notifier,
+		clock:      clk,
+		calendar:   holidays.NewCalendar(holidays.DefaultRegions, nil),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.followup = followup.New(statements, notifier, h.ynab, clk)
+	return h
+}
+
 // HealthCheck returns the health status of the API
-func HealthCheck(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]string{
 		"status": "ok",
 		"message": "Credit Card Payment Tracker API is running",
+		"time":    h.clock.Now().Format(time.RFC3339),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -27,127 +138,87 @@ func HealthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetCards returns all credit cards
-func GetCards(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetCards(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	query := `
-		SELECT id, name, last_four, statement_day, days_until_due,
-		       credit_limit, created_at, updated_at
-		FROM credit_cards
-		ORDER BY name
-	`
-
-	rows, err := database.DB.Query(query)
+	cards, err := h.cards.List(r.Context())
 	if err != nil {
 		log.Printf("Error querying credit cards: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	cards := []models.CreditCard{}
-	for rows.Next() {
-		var card models.CreditCard
-		var creditLimit sql.NullFloat64
 
-		err := rows.Scan(
-			&card.ID,
-			&card.Name,
-			&card.LastFour,
-			&card.StatementDay,
-			&card.DaysUntilDue,
-			&creditLimit,
-			&card.CreatedAt,
-			&card.UpdatedAt,
-		)
-		if err != nil {
-			log.Printf("Error scanning credit card: %v", err)
-			continue
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cards)
+}
 
-		// Handle NULL values
-		if creditLimit.Valid {
-			card.CreditLimit = creditLimit.Float64
-		}
+// GetStatements returns all statements
+func (h *Handler) GetStatements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-		cards = append(cards, card)
+	statements, err := h.statements.List(r.Context())
+	if err != nil {
+		log.Printf("Error querying statements: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
+	h.evaluateOverdue(statements)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(cards)
+	json.NewEncoder(w).Encode(statements)
 }
 
-// GetStatements returns all statements
-func GetStatements(w http.ResponseWriter, r *http.Request) {
+// GetDashboard returns upcoming statements, statements needing entry and
+// pending payments with their dates computed for today
+func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	query := `
-		SELECT id, card_id, statement_date, due_date, amount,
-		       status, notified_statement, notified_payment,
-		       reviewed_at, scheduled_payment_date,
-		       created_at, updated_at
-		FROM statements
-		ORDER BY due_date DESC
-	`
+	cards, err := h.cards.List(r.Context())
+	if err != nil {
+		log.Printf("Error querying credit cards: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-	rows, err := database.DB.Query(query)
+	statements, err := h.statements.List(r.Context())
 	if err != nil {
 		log.Printf("Error querying statements: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	statements := []models.Statement{}
-	for rows.Next() {
-		var stmt models.Statement
-		var reviewedAt sql.NullTime
-		var scheduledPaymentDate sql.NullString
-
-		err := rows.Scan(
-			&stmt.ID,
-			&stmt.CardID,
-			&stmt.StatementDate,
-			&stmt.DueDate,
-			&stmt.Amount,
-			&stmt.Status,
-			&stmt.NotifiedStatement,
-			&stmt.NotifiedPayment,
-			&reviewedAt,
-			&scheduledPaymentDate,
-			&stmt.CreatedAt,
-			&stmt.UpdatedAt,
-		)
-		if err != nil {
-			log.Printf("Error scanning statement: %v", err)
-			continue
-		}
 
-		// Handle nullable fields
-		if reviewedAt.Valid {
-			stmt.ReviewedAt = &reviewedAt.Time
-		}
-		if scheduledPaymentDate.Valid {
-			stmt.ScheduledPaymentDate = &scheduledPaymentDate.String
-		}
+	cal, err := h.loadCalendar(r.Context())
+	if err != nil {
+		log.Printf("Error loading holidays: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-		statements = append(statements, stmt)
+	paydays, err := h.paydays(r.Context(), cal)
+	if err != nil {
+		log.Printf("Error loading income schedules: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(statements)
+	json.NewEncoder(w).Encode(dashboard.Build(cards, statements, clock.Today(h.clock), cal, paydays))
 }
 
 // GetCardByID returns a single credit card by ID
-func GetCardByID(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetCardByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -167,44 +238,20 @@ func GetCardByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `
-		SELECT id, name, last_four, statement_day, days_until_due,
-		       credit_limit, created_at, updated_at
-		FROM credit_cards
-		WHERE id = ?
-	`
-
-	var card models.CreditCard
-	var creditLimit sql.NullFloat64
-
-	err = database.DB.QueryRow(query, id).Scan(
-		&card.ID,
-		&card.Name,
-		&card.LastFour,
-		&card.StatementDay,
-		&card.DaysUntilDue,
-		&creditLimit,
-		&card.CreatedAt,
-		&card.UpdatedAt,
-	)
+	card, err := h.cards.Get(r.Context(), id)
 	if err != nil {
 		log.Printf("Error querying credit card by ID %d: %v", id, err)
 		http.Error(w, "Card not found", http.StatusNotFound)
 		return
 	}
 
-	// Handle NULL values
-	if creditLimit.Valid {
-		card.CreditLimit = creditLimit.Float64
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(card)
 }
 
 // CreateStatement creates a new statement
-func CreateStatement(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateStatement(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -217,69 +264,94 @@ func CreateStatement(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate required fields
-	if stmt.CardID == 0 {
-		http.Error(w, "card_id is required", http.StatusBadRequest)
+	// Validate required fields, dates and status
+	if err := prepareStatement(&stmt, h.clock.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if stmt.StatementDate == "" {
-		http.Error(w, "statement_date is required", http.StatusBadRequest)
+
+	// The card must exist; foreign keys would reject it anyway, but with a
+	// 500. Its autopay settings are needed once the statement is stored.
+	card, err := h.cards.Get(r.Context(), stmt.CardID)
+	if err == repository.ErrNotFound {
+		http.Error(w, "card_id does not refer to an existing card", http.StatusUnprocessableEntity)
 		return
 	}
-	if stmt.DueDate == "" {
-		http.Error(w, "due_date is required", http.StatusBadRequest)
+	if err != nil {
+		log.Printf("Error checking card: %v", err)
+		http.Error(w, "Failed to create statement", http.StatusInternalServerError)
 		return
 	}
-	if stmt.Amount <= 0 {
-		http.Error(w, "amount must be greater than 0", http.StatusBadRequest)
+
+	if stmt.FundingAccountID != nil && !h.checkFundingAccount(w, r, *stmt.FundingAccountID, "Failed to create statement") {
 		return
 	}
 
-	// Set defaults
-	if stmt.Status == "" {
-		stmt.Status = "pending"
-	}
-	stmt.CreatedAt = time.Now()
-	stmt.UpdatedAt = time.Now()
-
-	query := `
-		INSERT INTO statements (card_id, statement_date, due_date, amount, status, notified_statement, notified_payment, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-
-	result, err := database.DB.Exec(query,
-		stmt.CardID,
-		stmt.StatementDate,
-		stmt.DueDate,
-		stmt.Amount,
-		stmt.Status,
-		stmt.NotifiedStatement,
-		stmt.NotifiedPayment,
-		stmt.CreatedAt,
-		stmt.UpdatedAt,
-	)
-	if err != nil {
-		log.Printf("Error creating statement: %v", err)
-		http.Error(w, "Failed to create statement", http.StatusInternalServerError)
+	// A statement for a cycle the scheduler predicted fills in its expected
+	// placeholder rather than adding a second row
+	code := http.StatusCreated
+	placeholder, err := h.statements.FindExpected(r.Context(), stmt.CardID, stmt.StatementDate)
+	if err == nil {
+		stmt.ID = placeholder.ID
+		err = h.statements.Fill(r.Context(), &stmt, "user")
+		code = http.StatusOK
+	} else if err == repository.ErrNotFound {
+		err = h.statements.Create(r.Context(), &stmt)
+	}
+	if err == repository.ErrStatusConflict {
+		http.Error(w, "The expected statement was entered at the same time; reload and try again", http.StatusConflict)
 		return
 	}
-
-	id, err := result.LastInsertId()
 	if err != nil {
-		log.Printf("Error getting last insert ID: %v", err)
+		log.Printf("Error creating statement: %v", err)
 		http.Error(w, "Failed to create statement", http.StatusInternalServerError)
 		return
 	}
 
-	stmt.ID = int(id)
+	h.followup.Entered(r.Context(), card, &stmt)
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(stmt)
 }
 
-// UpdateStatement updates a statement's status
-func UpdateStatement(w http.ResponseWriter, r *http.Request) {
+// prepareStatement validates a new statement and fills in its defaults: a
+// pending status, no funding account for 0 and timestamps at now. The card
+// and funding account are left for the caller to look up.
+func prepareStatement(stmt *models.Statement, now time.Time) error {
+	if err := stmt.Validate(); err != nil {
+		return err
+	}
+
+	// A funding account of 0 is the same as none
+	if stmt.FundingAccountID != nil && *stmt.FundingAccountID == 0 {
+		stmt.FundingAccountID = nil
+	}
+
+	if stmt.Status == "" {
+		stmt.Status = models.StatusPending
+	}
+	if !models.ValidStatus(stmt.Status) {
+		return errors.New("status must be one of " + strings.Join(models.Statuses, ", "))
+	}
+	stmt.CreatedAt = now
+	stmt.UpdatedAt = now
+	return nil
+}
+
+// UpdateStatementRequest represents the request body for changing a
+// statement's status
+type UpdateStatementRequest struct {
+	Status string `json:"status"`
+	// Reopen allows moving a paid (or overdue) statement back to unpaid
+	Reopen bool `json:"reopen"`
+	// ChangedBy is recorded in the status history; defaults to "user"
+	ChangedBy string `json:"changed_by"`
+}
+
+// UpdateStatement changes a statement's status, rejecting transitions the
+// status state machine doesn't allow with 409 Conflict
+func (h *Handler) UpdateStatement(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -299,45 +371,519 @@ func UpdateStatement(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+	var req UpdateStatementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Printf("Error decoding updates: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// For now, only support updating status
-	status, ok := updates["status"].(string)
-	if !ok {
+	if req.Status == "" {
 		http.Error(w, "status is required", http.StatusBadRequest)
 		return
 	}
+	if !models.ValidStatus(req.Status) {
+		http.Error(w, "status must be one of "+strings.Join(models.Statuses, ", "), http.StatusBadRequest)
+		return
+	}
+	if req.ChangedBy == "" {
+		req.ChangedBy = "user"
+	}
+
+	stmt, err := h.statements.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Check the transition against the status the client sees, which counts
+	// a pending statement past its due date as overdue
+	storedStatus := stmt.Status
+	stmt.EvaluateOverdue(h.today())
+	if err := models.CheckStatusTransition(stmt.Status, req.Status, req.Reopen); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if storedStatus != req.Status {
+		err = h.statements.ChangeStatus(r.Context(), models.StatusChange{
+			StatementID: id,
+			FromStatus:  storedStatus,
+			ToStatus:    req.Status,
+			ChangedBy:   req.ChangedBy,
+			ChangedAt:   h.clock.Now(),
+		})
+		if err == repository.ErrNotFound {
+			http.Error(w, "Statement not found", http.StatusNotFound)
+			return
+		}
+		if err == repository.ErrStatusConflict {
+			http.Error(w, "Statement status was changed by someone else; reload and try again", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Error updating statement %d: %v", id, err)
+			http.Error(w, "Failed to update statement", http.StatusInternalServerError)
+			return
+		}
+		h.followup.SyncPayment(id)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
+
+// GetStatementHistory returns a statement's status changes, oldest first
+func (h *Handler) GetStatementHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract ID from URL path (e.g., /api/v1/statements/1/history)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		http.Error(w, "Invalid statement ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.statements.Get(r.Context(), id); err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error querying statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := h.statements.StatusHistory(r.Context(), id)
+	if err != nil {
+		log.Printf("Error querying status history for statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// statementPaymentsPath parses /api/v1/statements/{id}/payments[/{paymentID}]
+// and returns the statement ID and, if present, the payment ID
+func statementPaymentsPath(path string) (int, int, error) {
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(pathParts) < 6 || pathParts[5] != "payments" {
+		return 0, 0, errors.New("Invalid URL")
+	}
+
+	statementID, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		return 0, 0, errors.New("Invalid statement ID")
+	}
+	if len(pathParts) == 6 {
+		return statementID, 0, nil
+	}
+
+	paymentID, err := strconv.Atoi(pathParts[6])
+	if err != nil || len(pathParts) > 7 {
+		return 0, 0, errors.New("Invalid payment ID")
+	}
+	return statementID, paymentID, nil
+}
+
+// GetPayments returns the payments made towards a statement
+func (h *Handler) GetPayments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statementID, _, err := statementPaymentsPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.statements.Get(r.Context(), statementID); err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error querying statement %d: %v", statementID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	payments, err := h.statements.ListPayments(r.Context(), statementID)
+	if err != nil {
+		log.Printf("Error querying payments for statement %d: %v", statementID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(payments)
+}
+
+// CreatePayment records a payment towards a statement. The statement is
+// marked paid once its payments cover the amount.
+func (h *Handler) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statementID, _, err := statementPaymentsPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var payment models.Payment
+	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
+		log.Printf("Error decoding payment: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := payment.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payment.ID = 0
+	payment.StatementID = statementID
+	payment.CreatedAt = h.clock.Now()
+
+	_, err = h.statements.AddPayment(r.Context(), &payment)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating payment for statement %d: %v", statementID, err)
+		http.Error(w, "Failed to create payment", http.StatusInternalServerError)
+		return
+	}
+	h.followup.SyncPayment(statementID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payment)
+}
+
+// DeletePayment removes a payment recorded by mistake
+func (h *Handler) DeletePayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statementID, paymentID, err := statementPaymentsPath(r.URL.Path)
+	if err == nil && paymentID == 0 {
+		err = errors.New("Invalid payment ID")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.statements.DeletePayment(r.Context(), statementID, paymentID, h.clock.Now())
+	if err == repository.ErrNotFound {
+		http.Error(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting payment %d: %v", paymentID, err)
+		http.Error(w, "Failed to delete payment", http.StatusInternalServerError)
+		return
+	}
+	h.followup.SyncPayment(statementID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Payment deleted successfully"})
+}
+
+// today returns the current date in DateFormat
+func (h *Handler) today() string {
+	return clock.Today(h.clock).Format(models.DateFormat)
+}
+
+// evaluateOverdue shows pending statements past their due date as overdue
+// without waiting for the scheduler to record the change
+func (h *Handler) evaluateOverdue(statements []models.Statement) {
+	today := h.today()
+	for i := range statements {
+		statements[i].EvaluateOverdue(today)
+	}
+}
+
+// GetStatementByID returns a single statement by ID
+func (h *Handler) GetStatementByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract ID from URL path (e.g., /api/v1/statements/1)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		http.Error(w, "Invalid statement ID", http.StatusBadRequest)
+		return
+	}
+
+	stmt, err := h.statements.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	stmt.EvaluateOverdue(h.today())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stmt)
+}
+
+// PatchStatementRequest represents the request body for editing a statement.
+// Omitted fields are left unchanged.
+type PatchStatementRequest struct {
+	CardID         *int          `json:"card_id"`
+	StatementDate  *string       `json:"statement_date"`
+	DueDate        *string       `json:"due_date"`
+	Amount         *models.Money `json:"amount"`
+	MinimumPayment *models.Money `json:"minimum_payment"`
+	CurrentBalance *models.Money `json:"current_balance"`
+	// FundingAccountID names the account paying the statement; 0 clears it
+	FundingAccountID *int `json:"funding_account_id"`
+}
+
+// PatchStatement edits the provided fields of a statement. The result must
+// pass the same validation as a new statement, and the amount can't change
+// once the statement is paid.
+func (h *Handler) PatchStatement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract ID from URL path (e.g., /api/v1/statements/1)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		http.Error(w, "Invalid statement ID", http.StatusBadRequest)
+		return
+	}
+
+	var req PatchStatementRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		log.Printf("Error decoding statement patch: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.CardID == nil && req.StatementDate == nil && req.DueDate == nil && req.Amount == nil &&
+		req.MinimumPayment == nil && req.CurrentBalance == nil && req.FundingAccountID == nil {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+
+	stmt, err := h.statements.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Apply the changes to a copy and validate the result as a whole, so a
+	// new due date is checked against the existing statement date
+	update := repository.StatementUpdate{UpdatedAt: h.clock.Now()}
+	if req.CardID != nil {
+		stmt.CardID = *req.CardID
+		update.CardID = req.CardID
+	}
+	if req.StatementDate != nil {
+		stmt.StatementDate = *req.StatementDate
+		update.StatementDate = req.StatementDate
+	}
+	if req.DueDate != nil {
+		stmt.DueDate = *req.DueDate
+		update.DueDate = req.DueDate
+	}
+	if req.Amount != nil {
+		if stmt.Status == models.StatusPaid && *req.Amount != stmt.Amount {
+			http.Error(w, "amount cannot be changed after the statement is paid", http.StatusConflict)
+			return
+		}
+		stmt.Amount = *req.Amount
+		update.Amount = req.Amount
+	}
+	if req.MinimumPayment != nil {
+		stmt.MinimumPayment = *req.MinimumPayment
+		update.MinimumPayment = req.MinimumPayment
+	}
+	if req.CurrentBalance != nil {
+		stmt.CurrentBalance = req.CurrentBalance
+		update.CurrentBalance = req.CurrentBalance
+	}
+	if req.FundingAccountID != nil {
+		update.FundingAccountID = req.FundingAccountID
+	}
+
+	if err := stmt.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	query := `
-		UPDATE statements
-		SET status = ?, updated_at = ?
-		WHERE id = ?
-	`
+	if req.CardID != nil {
+		cardExists, err := h.cards.Exists(r.Context(), stmt.CardID)
+		if err != nil {
+			log.Printf("Error checking card: %v", err)
+			http.Error(w, "Failed to update statement", http.StatusInternalServerError)
+			return
+		}
+		if !cardExists {
+			http.Error(w, "card_id does not refer to an existing card", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	if req.FundingAccountID != nil && *req.FundingAccountID != 0 &&
+		!h.checkFundingAccount(w, r, *req.FundingAccountID, "Failed to update statement") {
+		return
+	}
 
-	_, err = database.DB.Exec(query, status, time.Now(), id)
+	// Entering the amount of an expected placeholder fills it in, the same
+	// as creating the statement would
+	filled := stmt.Status == models.StatusExpected
+	if filled {
+		if req.FundingAccountID != nil {
+			stmt.FundingAccountID = req.FundingAccountID
+			if *req.FundingAccountID == 0 {
+				stmt.FundingAccountID = nil
+			}
+		}
+		stmt.Status = models.StatusPending
+		stmt.UpdatedAt = update.UpdatedAt
+		err = h.statements.Fill(r.Context(), &stmt, "user")
+	} else {
+		stmt, err = h.statements.Update(r.Context(), id, update)
+	}
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	if err == repository.ErrStatusConflict {
+		http.Error(w, "The expected statement was entered at the same time; reload and try again", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Printf("Error updating statement %d: %v", id, err)
 		http.Error(w, "Failed to update statement", http.StatusInternalServerError)
 		return
 	}
+	if card, err := h.cards.Get(r.Context(), stmt.CardID); err != nil {
+		log.Printf("Error querying card %d: %v", stmt.CardID, err)
+		h.followup.SyncPayment(id)
+	} else if filled {
+		h.followup.Entered(r.Context(), card, &stmt)
+	} else {
+		h.followup.Updated(r.Context(), card, &stmt)
+	}
+	stmt.EvaluateOverdue(h.today())
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+	json.NewEncoder(w).Encode(stmt)
+}
+
+// DeleteStatement deletes a single statement
+func (h *Handler) DeleteStatement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract ID from URL path (e.g., /api/v1/statements/1)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		http.Error(w, "Invalid statement ID", http.StatusBadRequest)
+		return
+	}
+
+	// The YNAB record is deleted along with the statement, so read it first
+	ynabID, err := h.followup.YNABTransaction(r.Context(), id)
+	if err != nil {
+		log.Printf("Error querying YNAB transaction for statement %d: %v", id, err)
+	}
+
+	err = h.statements.Delete(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting statement %d: %v", id, err)
+		http.Error(w, "Failed to delete statement", http.StatusInternalServerError)
+		return
+	}
+	h.followup.RemovePayment(ynabID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Statement deleted successfully"})
 }
 
 // SchedulePaymentRequest represents the request body for scheduling a payment
 type SchedulePaymentRequest struct {
 	ScheduledPaymentDate string `json:"scheduled_payment_date"`
+	// FundingAccountID is the account the payment comes from; omitted keeps
+	// the statement's current account and 0 clears it
+	FundingAccountID *int `json:"funding_account_id"`
 }
 
-// SchedulePayment schedules a payment for a statement
-func SchedulePayment(w http.ResponseWriter, r *http.Request) {
+// SchedulePayment schedules a payment for a statement, warning if the date
+// is later than the card's payment policy recommends
+func (h *Handler) SchedulePayment(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -377,25 +923,53 @@ func SchedulePayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update statement with reviewed_at (current time) and scheduled_payment_date
-	query := `
-		UPDATE statements
-		SET reviewed_at = ?, scheduled_payment_date = ?, updated_at = ?
-		WHERE id = ?
-	`
+	stmt, err := h.statements.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-	now := time.Now()
-	_, err = database.DB.Exec(query, now, req.ScheduledPaymentDate, now, id)
+	// Warn if the date leaves less time than the card's payment policy asks for
+	rec, err := h.recommendation(r.Context(), stmt)
+	if err != nil {
+		log.Printf("Error recommending payment date for statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if req.FundingAccountID != nil && *req.FundingAccountID != 0 &&
+		!h.checkFundingAccount(w, r, *req.FundingAccountID, "Failed to schedule payment") {
+		return
+	}
+
+	// Update statement with reviewed_at (current time) and scheduled_payment_date
+	now := h.clock.Now()
+	err = h.statements.SchedulePayment(r.Context(), id, req.ScheduledPaymentDate, req.FundingAccountID, now)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Printf("Error scheduling payment for statement %d: %v", id, err)
 		http.Error(w, "Failed to schedule payment", http.StatusInternalServerError)
 		return
 	}
+	h.followup.SyncPayment(id)
 
 	response := map[string]interface{}{
-		"status":                 "scheduled",
-		"reviewed_at":            now.Format(time.RFC3339),
-		"scheduled_payment_date": req.ScheduledPaymentDate,
+		"status":                   "scheduled",
+		"reviewed_at":              now.Format(time.RFC3339),
+		"scheduled_payment_date":   req.ScheduledPaymentDate,
+		"recommended_payment_date": rec.PaymentDate,
+		"warnings":                 rec.Check(req.ScheduledPaymentDate),
+	}
+	if req.FundingAccountID != nil && *req.FundingAccountID != 0 {
+		response["funding_account_id"] = *req.FundingAccountID
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -405,122 +979,154 @@ func SchedulePayment(w http.ResponseWriter, r *http.Request) {
 
 // CreateCardRequest represents the request body for creating a credit card
 type CreateCardRequest struct {
-	Name          string  `json:"name"`
-	LastFour      string  `json:"last_four"`
-	StatementDate string  `json:"statement_date"`
-	DueDate       string  `json:"due_date"`
-	CreditLimit   float64 `json:"credit_limit,omitempty"`
+	Name          string       `json:"name"`
+	LastFour      string       `json:"last_four"`
+	StatementDate string       `json:"statement_date"`
+	DueDate       string       `json:"due_date"`
+	CreditLimit   models.Money `json:"credit_limit,omitempty"`
+	// StatementDayRule says how StatementDate repeats each month; fixed
+	// (the default), last_day or business_day
+	StatementDayRule string `json:"statement_day_rule,omitempty"`
+	// PaymentPolicy decides when statements are paid; lead_time (the
+	// default), statement_day or payday
+	PaymentPolicy string `json:"payment_policy,omitempty"`
+	// PaymentLeadDays is how many business days before the due date a
+	// payment must be made, 5 by default
+	PaymentLeadDays int `json:"payment_lead_days,omitempty"`
+	// Autopay is how much the issuer pays automatically; none (the
+	// default), minimum, statement_balance or fixed_amount
+	Autopay       string       `json:"autopay,omitempty"`
+	AutopayAmount models.Money `json:"autopay_amount,omitempty"`
+	// AutopayFundingAccountID is the account autopay pulls from; 0 clears
+	// it
+	AutopayFundingAccountID *int `json:"autopay_funding_account_id,omitempty"`
 }
 
-// CreateCard creates a new credit card
-func CreateCard(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// validatePaymentPolicy checks the payment policy fields of a card request
+// and returns the message to report, or "" if they are valid
+func validatePaymentPolicy(req CreateCardRequest) string {
+	if !models.ValidPaymentPolicy(req.PaymentPolicy) {
+		return "payment_policy must be one of lead_time, statement_day or payday"
 	}
-
-	var req CreateCardRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Error decoding card: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	if !models.ValidPaymentLeadDays(req.PaymentLeadDays) {
+		return fmt.Sprintf("payment_lead_days must be between 1 and %d", models.MaxPaymentLeadDays)
 	}
+	return ""
+}
 
+// newCard validates a card request and builds the card it describes, created
+// at now. The autopay funding account is left for the caller to check
+// against the stored accounts.
+func newCard(req CreateCardRequest, now time.Time) (models.CreditCard, error) {
 	// Validate required fields
 	if req.Name == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("name is required")
 	}
 	if len(req.Name) < 2 || len(req.Name) > 255 {
-		http.Error(w, "name must be between 2 and 255 characters", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("name must be between 2 and 255 characters")
 	}
 	if req.LastFour == "" {
-		http.Error(w, "last_four is required", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("last_four is required")
 	}
 	if len(req.LastFour) != 4 {
-		http.Error(w, "last_four must be exactly 4 digits", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("last_four must be exactly 4 digits")
 	}
 	// Validate that last_four is numeric
 	if _, err := strconv.Atoi(req.LastFour); err != nil {
-		http.Error(w, "last_four must be numeric", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("last_four must be numeric")
 	}
 	if req.StatementDate == "" {
-		http.Error(w, "statement_date is required", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("statement_date is required")
 	}
 	if req.DueDate == "" {
-		http.Error(w, "due_date is required", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("due_date is required")
 	}
 	if req.CreditLimit < 0 {
-		http.Error(w, "credit_limit must be positive", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("credit_limit must be positive")
+	}
+	if !models.ValidStatementDayRule(req.StatementDayRule) {
+		return models.CreditCard{}, errors.New("statement_day_rule must be one of fixed, last_day or business_day")
+	}
+	if msg := validatePaymentPolicy(req); msg != "" {
+		return models.CreditCard{}, errors.New(msg)
+	}
+	if msg := validateAutopay(req, ""); msg != "" {
+		return models.CreditCard{}, errors.New(msg)
 	}
 
 	// Parse and validate dates
 	statementDate, err := time.Parse("2006-01-02", req.StatementDate)
 	if err != nil {
-		http.Error(w, "statement_date must be a valid date (YYYY-MM-DD)", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("statement_date must be a valid date (YYYY-MM-DD)")
 	}
 	dueDate, err := time.Parse("2006-01-02", req.DueDate)
 	if err != nil {
-		http.Error(w, "due_date must be a valid date (YYYY-MM-DD)", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("due_date must be a valid date (YYYY-MM-DD)")
 	}
 
 	// Validate that due_date is after statement_date
 	if !dueDate.After(statementDate) {
-		http.Error(w, "due_date must be after statement_date", http.StatusBadRequest)
-		return
+		return models.CreditCard{}, errors.New("due_date must be after statement_date")
 	}
 
 	// Calculate statement_day and days_until_due
-	statementDay := statementDate.Day()
+	rule := req.StatementDayRule
+	if rule == "" {
+		rule = models.StatementDayFixed
+	}
+	statementDay, err := models.StatementDayFor(rule, statementDate)
+	if err != nil {
+		return models.CreditCard{}, err
+	}
 	daysUntilDue := int(dueDate.Sub(statementDate).Hours() / 24)
 
-	// Set timestamps
-	now := time.Now()
-
-	// Insert into database
-	query := `
-		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
+	return models.CreditCard{
+		Name:             req.Name,
+		LastFour:         req.LastFour,
+		StatementDay:     statementDay,
+		StatementDayRule: rule,
+		DaysUntilDue:     daysUntilDue,
+		CreditLimit:      req.CreditLimit,
+		PaymentPolicy:    req.PaymentPolicy,
+		PaymentLeadDays:  req.PaymentLeadDays,
+		Autopay:          req.Autopay,
+		AutopayAmount:    req.AutopayAmount,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}, nil
+}
 
-	var result sql.Result
-	if req.CreditLimit > 0 {
-		result, err = database.DB.Exec(query, req.Name, req.LastFour, statementDay, daysUntilDue, req.CreditLimit, now, now)
-	} else {
-		result, err = database.DB.Exec(query, req.Name, req.LastFour, statementDay, daysUntilDue, nil, now, now)
+// CreateCard creates a new credit card
+func (h *Handler) CreateCard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	if err != nil {
-		log.Printf("Error creating card: %v", err)
-		http.Error(w, "Failed to create card", http.StatusInternalServerError)
+
+	var req CreateCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding card: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	id, err := result.LastInsertId()
+	card, err := newCard(req, h.clock.Now())
 	if err != nil {
-		log.Printf("Error getting last insert ID: %v", err)
-		http.Error(w, "Failed to create card", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.AutopayFundingAccountID != nil && *req.AutopayFundingAccountID == 0 {
+		req.AutopayFundingAccountID = nil
+	}
+	if req.AutopayFundingAccountID != nil && !h.checkFundingAccount(w, r, *req.AutopayFundingAccountID, "Failed to create card") {
 		return
 	}
 
-	// Return created card
-	card := models.CreditCard{
-		ID:           int(id),
-		Name:         req.Name,
-		LastFour:     req.LastFour,
-		StatementDay: statementDay,
-		DaysUntilDue: daysUntilDue,
-		CreditLimit:  req.CreditLimit,
-		CreatedAt:    now,
-		UpdatedAt:    now,
+	card.AutopayFundingAccountID = req.AutopayFundingAccountID
+	if err := h.cards.Create(r.Context(), &card); err != nil {
+		log.Printf("Error creating card: %v", err)
+		http.Error(w, "Failed to create card", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -529,7 +1135,7 @@ func CreateCard(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateCard updates an existing credit card
-func UpdateCard(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateCard(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -549,10 +1155,10 @@ func UpdateCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if card exists
-	var exists int
-	err = database.DB.QueryRow("SELECT 1 FROM credit_cards WHERE id = ?", id).Scan(&exists)
-	if err == sql.ErrNoRows {
+	// Check if card exists; its statement day rule is needed to
+	// interpret a new statement_date
+	existing, err := h.cards.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
 		http.Error(w, "Card not found", http.StatusNotFound)
 		return
 	}
@@ -588,20 +1194,37 @@ func UpdateCard(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "credit_limit must be positive", http.StatusBadRequest)
 		return
 	}
+	if !models.ValidStatementDayRule(req.StatementDayRule) {
+		http.Error(w, "statement_day_rule must be one of fixed, last_day or business_day", http.StatusBadRequest)
+		return
+	}
+	if req.StatementDayRule != "" && req.StatementDate == "" {
+		http.Error(w, "statement_day_rule must be provided with statement_date and due_date", http.StatusBadRequest)
+		return
+	}
+	if msg := validatePaymentPolicy(req); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := validateAutopay(req, existing.Autopay); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if req.AutopayFundingAccountID != nil && *req.AutopayFundingAccountID != 0 &&
+		!h.checkFundingAccount(w, r, *req.AutopayFundingAccountID, "Failed to update card") {
+		return
+	}
 
-	// Build update query dynamically based on provided fields
-	updates := []string{}
-	args := []interface{}{}
+	// Collect the provided fields
+	update := repository.CardUpdate{}
 	hasUpdates := false
 
 	if req.Name != "" {
-		updates = append(updates, "name = ?")
-		args = append(args, req.Name)
+		update.Name = &req.Name
 		hasUpdates = true
 	}
 	if req.LastFour != "" {
-		updates = append(updates, "last_four = ?")
-		args = append(args, req.LastFour)
+		update.LastFour = &req.LastFour
 		hasUpdates = true
 	}
 
@@ -623,11 +1246,20 @@ func UpdateCard(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		statementDay := statementDate.Day()
+		rule := existing.StatementDayRule
+		if req.StatementDayRule != "" {
+			rule = req.StatementDayRule
+		}
+		statementDay, err := models.StatementDayFor(rule, statementDate)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		daysUntilDue := int(dueDate.Sub(statementDate).Hours() / 24)
 
-		updates = append(updates, "statement_day = ?", "days_until_due = ?")
-		args = append(args, statementDay, daysUntilDue)
+		update.StatementDay = &statementDay
+		update.StatementDayRule = &rule
+		update.DaysUntilDue = &daysUntilDue
 		hasUpdates = true
 	} else if req.StatementDate != "" || req.DueDate != "" {
 		http.Error(w, "both statement_date and due_date must be provided together", http.StatusBadRequest)
@@ -635,8 +1267,32 @@ func UpdateCard(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.CreditLimit > 0 {
-		updates = append(updates, "credit_limit = ?")
-		args = append(args, req.CreditLimit)
+		update.CreditLimit = &req.CreditLimit
+		hasUpdates = true
+	}
+	if req.PaymentPolicy != "" {
+		update.PaymentPolicy = &req.PaymentPolicy
+		hasUpdates = true
+	}
+	if req.PaymentLeadDays > 0 {
+		update.PaymentLeadDays = &req.PaymentLeadDays
+		hasUpdates = true
+	}
+	if req.Autopay != "" {
+		update.Autopay = &req.Autopay
+		hasUpdates = true
+		// Only a fixed amount autopay keeps an amount
+		if req.Autopay != models.AutopayFixedAmount {
+			var none models.Money
+			update.AutopayAmount = &none
+		}
+	}
+	if req.AutopayAmount > 0 {
+		update.AutopayAmount = &req.AutopayAmount
+		hasUpdates = true
+	}
+	if req.AutopayFundingAccountID != nil {
+		update.AutopayFundingAccountID = req.AutopayFundingAccountID
 		hasUpdates = true
 	}
 
@@ -646,59 +1302,26 @@ func UpdateCard(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Always update updated_at
-	updates = append(updates, "updated_at = ?")
-	args = append(args, time.Now())
-
-	// Add ID to args
-	args = append(args, id)
+	update.UpdatedAt = h.clock.Now()
 
-	query := "UPDATE credit_cards SET " + strings.Join(updates, ", ") + " WHERE id = ?"
-	_, err = database.DB.Exec(query, args...)
-	if err != nil {
-		log.Printf("Error updating card %d: %v", id, err)
-		http.Error(w, "Failed to update card", http.StatusInternalServerError)
+	card, err := h.cards.Update(r.Context(), id, update)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Card not found", http.StatusNotFound)
 		return
 	}
-
-	// Fetch and return updated card
-	querySelect := `
-		SELECT id, name, last_four, statement_day, days_until_due,
-		       credit_limit, created_at, updated_at
-		FROM credit_cards
-		WHERE id = ?
-	`
-
-	var card models.CreditCard
-	var creditLimit sql.NullFloat64
-
-	err = database.DB.QueryRow(querySelect, id).Scan(
-		&card.ID,
-		&card.Name,
-		&card.LastFour,
-		&card.StatementDay,
-		&card.DaysUntilDue,
-		&creditLimit,
-		&card.CreatedAt,
-		&card.UpdatedAt,
-	)
 	if err != nil {
-		log.Printf("Error fetching updated card %d: %v", id, err)
-		http.Error(w, "Failed to fetch updated card", http.StatusInternalServerError)
+		log.Printf("Error updating card %d: %v", id, err)
+		http.Error(w, "Failed to update card", http.StatusInternalServerError)
 		return
 	}
 
-	// Handle NULL values
-	if creditLimit.Valid {
-		card.CreditLimit = creditLimit.Float64
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(card)
 }
 
 // DeleteCard deletes a credit card and its associated statements
-func DeleteCard(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteCard(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -718,35 +1341,18 @@ func DeleteCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Count associated statements
-	var statementCount int
-	err = database.DB.QueryRow("SELECT COUNT(*) FROM statements WHERE card_id = ?", id).Scan(&statementCount)
-	if err != nil {
-		log.Printf("Error counting statements for card %d: %v", id, err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
 	// Delete card (CASCADE will delete statements)
-	result, err := database.DB.Exec("DELETE FROM credit_cards WHERE id = ?", id)
-	if err != nil {
-		log.Printf("Error deleting card %d: %v", id, err)
-		http.Error(w, "Failed to delete card", http.StatusInternalServerError)
+	statementCount, err := h.cards.Delete(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Card not found", http.StatusNotFound)
 		return
 	}
-
-	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		log.Printf("Error getting rows affected: %v", err)
+		log.Printf("Error deleting card %d: %v", id, err)
 		http.Error(w, "Failed to delete card", http.StatusInternalServerError)
 		return
 	}
 
-	if rowsAffected == 0 {
-		http.Error(w, "Card not found", http.StatusNotFound)
-		return
-	}
-
 	response := map[string]interface{}{
 		"message":         "Card deleted successfully",
 		"statements_deleted": statementCount,
@@ -758,7 +1364,7 @@ func DeleteCard(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetSettings returns the current application settings
-func GetSettings(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -778,7 +1384,7 @@ func GetSettings(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateSettings updates the application settings
-func UpdateSettings(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -791,6 +1397,16 @@ func UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The YNAB and IMAP sections are only edited in the file, so keep them
+	current, err := config.LoadConfig("")
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+	cfg.YNAB = current.YNAB
+	cfg.IMAP = current.IMAP
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		log.Printf("Invalid configuration: %v", err)
@@ -805,6 +1421,11 @@ func UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Apply the new webhook URL without requiring a restart
+	if h.notifier != nil {
+		h.notifier.Client().SetWebhookURL(cfg.DiscordWebhookURL)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(cfg)
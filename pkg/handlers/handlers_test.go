@@ -2,42 +2,51 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
 	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
 	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/followup"
 	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/notify"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
 )
 
-func setupTestDB(t *testing.T) string {
+func setupTestDB(t *testing.T) (*Handler, *sql.DB) {
 	tmpDB := "./test_handlers.db"
-	err := database.InitDB(tmpDB)
+	db, err := database.InitDB(tmpDB, clock.Real{})
 	if err != nil {
 		t.Fatalf("Failed to initialize test database: %v", err)
 	}
-	// Enable foreign keys for CASCADE deletes
-	_, err = database.DB.Exec("PRAGMA foreign_keys = ON")
-	if err != nil {
-		t.Fatalf("Failed to enable foreign keys: %v", err)
-	}
-	return tmpDB
-}
-
-func teardownTestDB(tmpDB string) {
-	database.Close()
-	os.Remove(tmpDB)
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpDB)
+	})
+	h := New(
+		repository.NewSQLiteCardRepository(db),
+		repository.NewSQLiteStatementRepository(db),
+		nil,
+		nil,
+	)
+	return h, db
 }
 
 func TestHealthCheck(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+
 	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
 	w := httptest.NewRecorder()
 
-	HealthCheck(w, req)
+	h.HealthCheck(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -67,13 +76,12 @@ func TestHealthCheck(t *testing.T) {
 }
 
 func TestGetCards(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test data
-	_, err := database.DB.Exec(`
-		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit)
-		VALUES ('Test Card', '1234', 15, 25, 5000.00)
+	_, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit_cents)
+		VALUES ('Test Card', '1234', 15, 25, 500000)
 	`)
 	if err != nil {
 		t.Fatalf("Failed to insert test data: %v", err)
@@ -82,7 +90,7 @@ func TestGetCards(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/cards", nil)
 	w := httptest.NewRecorder()
 
-	GetCards(w, req)
+	h.GetCards(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -107,13 +115,12 @@ func TestGetCards(t *testing.T) {
 }
 
 func TestGetCardsMethodNotAllowed(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/cards", nil)
 	w := httptest.NewRecorder()
 
-	GetCards(w, req)
+	h.GetCards(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -124,11 +131,10 @@ func TestGetCardsMethodNotAllowed(t *testing.T) {
 }
 
 func TestGetStatements(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -139,9 +145,9 @@ func TestGetStatements(t *testing.T) {
 	cardID, _ := result.LastInsertId()
 
 	// Insert test statement
-	_, err = database.DB.Exec(`
-		INSERT INTO statements (card_id, statement_date, due_date, amount, status)
-		VALUES (?, '2024-11-01', '2024-11-15', 1250.75, 'pending')
+	_, err = db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, status)
+		VALUES (?, '2024-11-01', '2024-11-15', 125075, 'pending')
 	`, cardID)
 	if err != nil {
 		t.Fatalf("Failed to insert test statement: %v", err)
@@ -150,7 +156,7 @@ func TestGetStatements(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/statements", nil)
 	w := httptest.NewRecorder()
 
-	GetStatements(w, req)
+	h.GetStatements(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -169,19 +175,18 @@ func TestGetStatements(t *testing.T) {
 		t.Errorf("Expected 1 statement, got %d", len(statements))
 	}
 
-	if statements[0].Amount != 1250.75 {
-		t.Errorf("Expected amount 1250.75, got %.2f", statements[0].Amount)
+	if statements[0].Amount != models.MustParseMoney("1250.75") {
+		t.Errorf("Expected amount 1250.75, got %s", statements[0].Amount)
 	}
 }
 
 func TestGetCardByID(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test data
-	result, err := database.DB.Exec(`
-		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit)
-		VALUES ('Test Card', '5678', 20, 25, 3000.00)
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit_cents)
+		VALUES ('Test Card', '5678', 20, 25, 300000)
 	`)
 	if err != nil {
 		t.Fatalf("Failed to insert test data: %v", err)
@@ -192,7 +197,7 @@ func TestGetCardByID(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/cards/%d", cardID), nil)
 	w := httptest.NewRecorder()
 
-	GetCardByID(w, req)
+	h.GetCardByID(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -217,13 +222,12 @@ func TestGetCardByID(t *testing.T) {
 }
 
 func TestGetCardByIDNotFound(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/cards/9999", nil)
 	w := httptest.NewRecorder()
 
-	GetCardByID(w, req)
+	h.GetCardByID(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -234,13 +238,12 @@ func TestGetCardByIDNotFound(t *testing.T) {
 }
 
 func TestGetCardByIDInvalidID(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/cards/invalid", nil)
 	w := httptest.NewRecorder()
 
-	GetCardByID(w, req)
+	h.GetCardByID(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -251,11 +254,10 @@ func TestGetCardByIDInvalidID(t *testing.T) {
 }
 
 func TestCreateStatement(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -270,7 +272,7 @@ func TestCreateStatement(t *testing.T) {
 		CardID:        int(cardID),
 		StatementDate: "2024-11-01",
 		DueDate:       "2024-11-15",
-		Amount:        1500.50,
+		Amount:        models.MustParseMoney("1500.50"),
 	}
 
 	body, err := json.Marshal(stmt)
@@ -282,7 +284,7 @@ func TestCreateStatement(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateStatement(w, req)
+	h.CreateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -301,8 +303,8 @@ func TestCreateStatement(t *testing.T) {
 		t.Error("Expected non-zero ID")
 	}
 
-	if createdStmt.Amount != 1500.50 {
-		t.Errorf("Expected amount 1500.50, got %.2f", createdStmt.Amount)
+	if createdStmt.Amount != models.MustParseMoney("1500.50") {
+		t.Errorf("Expected amount 1500.50, got %s", createdStmt.Amount)
 	}
 
 	if createdStmt.Status != "pending" {
@@ -310,15 +312,73 @@ func TestCreateStatement(t *testing.T) {
 	}
 }
 
+func TestCreateStatement_FillsExpected(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due) VALUES (1, 'Visa', '1234', 15, 25)`); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO statements (id, card_id, statement_date, due_date, amount_cents, status) VALUES
+		(1, 1, '2024-10-15', '2024-11-09', 0, 'expected'),
+		(2, 1, '2024-11-15', '2024-12-10', 0, 'expected')
+	`); err != nil {
+		t.Fatalf("Failed to insert placeholders: %v", err)
+	}
+
+	// The statement closed a day early but belongs to November's cycle
+	body := `{"card_id": 1, "statement_date": "2024-11-14", "due_date": "2024-12-09", "amount": "420.00"}`
+	w := httptest.NewRecorder()
+	h.CreateStatement(w, httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewBufferString(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 filling the placeholder, got %d: %s", w.Code, w.Body.String())
+	}
+	var stmt models.Statement
+	json.NewDecoder(w.Body).Decode(&stmt)
+	if stmt.ID != 2 || stmt.Status != models.StatusPending || stmt.StatementDate != "2024-11-14" || stmt.Amount.String() != "420.00" {
+		t.Errorf("Expected placeholder 2 to be filled, got %+v", stmt)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM statements").Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected no new statement rows, got %d", count)
+	}
+
+	// Placeholders can't be paid before their amount is known
+	w = httptest.NewRecorder()
+	h.UpdateStatement(w, httptest.NewRequest(http.MethodPut, "/api/v1/statements/1", bytes.NewBufferString(`{"status": "paid"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 paying a placeholder, got %d", w.Code)
+	}
+
+	// Patching in the amount fills the October placeholder
+	w = httptest.NewRecorder()
+	h.PatchStatement(w, httptest.NewRequest(http.MethodPatch, "/api/v1/statements/1", bytes.NewBufferString(`{"due_date": "2024-11-10"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without an amount, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.PatchStatement(w, httptest.NewRequest(http.MethodPatch, "/api/v1/statements/1", bytes.NewBufferString(`{"amount": "99.00"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var patched models.Statement
+	json.NewDecoder(w.Body).Decode(&patched)
+	// Its due date has passed, so it reads as overdue once filled
+	if patched.Status != models.StatusOverdue || patched.Amount.String() != "99.00" {
+		t.Errorf("Expected the placeholder to be filled, got %+v", patched)
+	}
+}
+
 func TestCreateStatementInvalidJSON(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewReader([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateStatement(w, req)
+	h.CreateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -329,13 +389,12 @@ func TestCreateStatementInvalidJSON(t *testing.T) {
 }
 
 func TestCreateStatementMissingCardID(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	stmt := models.Statement{
 		StatementDate: "2024-11-01",
 		DueDate:       "2024-11-15",
-		Amount:        1500.50,
+		Amount:        models.MustParseMoney("1500.50"),
 	}
 
 	body, _ := json.Marshal(stmt)
@@ -343,7 +402,7 @@ func TestCreateStatementMissingCardID(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateStatement(w, req)
+	h.CreateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -354,14 +413,13 @@ func TestCreateStatementMissingCardID(t *testing.T) {
 }
 
 func TestCreateStatementInvalidAmount(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	stmt := models.Statement{
 		CardID:        1,
 		StatementDate: "2024-11-01",
 		DueDate:       "2024-11-15",
-		Amount:        -100.00,
+		Amount:        models.MustParseMoney("-100.00"),
 	}
 
 	body, _ := json.Marshal(stmt)
@@ -369,7 +427,7 @@ func TestCreateStatementInvalidAmount(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateStatement(w, req)
+	h.CreateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -380,11 +438,10 @@ func TestCreateStatementInvalidAmount(t *testing.T) {
 }
 
 func TestUpdateStatement(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card and statement
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -394,9 +451,9 @@ func TestUpdateStatement(t *testing.T) {
 
 	cardID, _ := result.LastInsertId()
 
-	result, err = database.DB.Exec(`
-		INSERT INTO statements (card_id, statement_date, due_date, amount, status)
-		VALUES (?, '2024-11-01', '2024-11-15', 1250.75, 'pending')
+	result, err = db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, status)
+		VALUES (?, '2024-11-01', '2024-11-15', 125075, 'pending')
 	`, cardID)
 	if err != nil {
 		t.Fatalf("Failed to insert test statement: %v", err)
@@ -414,7 +471,7 @@ func TestUpdateStatement(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateStatement(w, req)
+	h.UpdateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -425,7 +482,7 @@ func TestUpdateStatement(t *testing.T) {
 
 	// Verify update
 	var status string
-	err = database.DB.QueryRow("SELECT status FROM statements WHERE id = ?", stmtID).Scan(&status)
+	err = db.QueryRow("SELECT status FROM statements WHERE id = ?", stmtID).Scan(&status)
 	if err != nil {
 		t.Fatalf("Failed to query updated statement: %v", err)
 	}
@@ -436,8 +493,7 @@ func TestUpdateStatement(t *testing.T) {
 }
 
 func TestUpdateStatementInvalidID(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	updates := map[string]string{
 		"status": "paid",
@@ -448,7 +504,7 @@ func TestUpdateStatementInvalidID(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateStatement(w, req)
+	h.UpdateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -459,8 +515,7 @@ func TestUpdateStatementInvalidID(t *testing.T) {
 }
 
 func TestUpdateStatementMissingStatus(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	updates := map[string]string{}
 
@@ -469,7 +524,7 @@ func TestUpdateStatementMissingStatus(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateStatement(w, req)
+	h.UpdateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -480,13 +535,12 @@ func TestUpdateStatementMissingStatus(t *testing.T) {
 }
 
 func TestGetStatementsMethodNotAllowed(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/statements", nil)
 	w := httptest.NewRecorder()
 
-	GetStatements(w, req)
+	h.GetStatements(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -497,13 +551,12 @@ func TestGetStatementsMethodNotAllowed(t *testing.T) {
 }
 
 func TestGetCardByIDMethodNotAllowed(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/cards/1", nil)
 	w := httptest.NewRecorder()
 
-	GetCardByID(w, req)
+	h.GetCardByID(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -514,13 +567,12 @@ func TestGetCardByIDMethodNotAllowed(t *testing.T) {
 }
 
 func TestCreateStatementMethodNotAllowed(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/statements", nil)
 	w := httptest.NewRecorder()
 
-	CreateStatement(w, req)
+	h.CreateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -531,13 +583,12 @@ func TestCreateStatementMethodNotAllowed(t *testing.T) {
 }
 
 func TestUpdateStatementMethodNotAllowed(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/statements/1", nil)
 	w := httptest.NewRecorder()
 
-	UpdateStatement(w, req)
+	h.UpdateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -548,13 +599,12 @@ func TestUpdateStatementMethodNotAllowed(t *testing.T) {
 }
 
 func TestCreateStatementMissingStatementDate(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	stmt := models.Statement{
 		CardID:  1,
 		DueDate: "2024-11-15",
-		Amount:  1500.50,
+		Amount:  models.MustParseMoney("1500.50"),
 	}
 
 	body, _ := json.Marshal(stmt)
@@ -562,7 +612,7 @@ func TestCreateStatementMissingStatementDate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateStatement(w, req)
+	h.CreateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -573,13 +623,12 @@ func TestCreateStatementMissingStatementDate(t *testing.T) {
 }
 
 func TestCreateStatementMissingDueDate(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	stmt := models.Statement{
 		CardID:        1,
 		StatementDate: "2024-11-01",
-		Amount:        1500.50,
+		Amount:        models.MustParseMoney("1500.50"),
 	}
 
 	body, _ := json.Marshal(stmt)
@@ -587,7 +636,7 @@ func TestCreateStatementMissingDueDate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateStatement(w, req)
+	h.CreateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -598,14 +647,13 @@ func TestCreateStatementMissingDueDate(t *testing.T) {
 }
 
 func TestUpdateStatementInvalidJSON(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/statements/1", bytes.NewReader([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateStatement(w, req)
+	h.UpdateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -618,15 +666,14 @@ func TestUpdateStatementInvalidJSON(t *testing.T) {
 // --- Tests for CreateCard ---
 
 func TestCreateCard_Success(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Chase Sapphire",
 		LastFour:      "1234",
 		StatementDate: "2024-11-15",
 		DueDate:       "2024-12-10",
-		CreditLimit:   5000.00,
+		CreditLimit:   models.MustParseMoney("5000.00"),
 	}
 
 	body, _ := json.Marshal(cardReq)
@@ -634,7 +681,7 @@ func TestCreateCard_Success(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -664,14 +711,124 @@ func TestCreateCard_Success(t *testing.T) {
 	if card.DaysUntilDue != 25 {
 		t.Errorf("Expected days_until_due 25, got %d", card.DaysUntilDue)
 	}
-	if card.CreditLimit != 5000.00 {
-		t.Errorf("Expected credit_limit 5000.00, got %.2f", card.CreditLimit)
+	if card.CreditLimit != models.MustParseMoney("5000.00") {
+		t.Errorf("Expected credit_limit 5000.00, got %s", card.CreditLimit)
+	}
+}
+
+func TestCreateCard_StatementDayRules(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	testCases := []struct {
+		rule          string
+		statementDate string
+		expectedDay   int
+		expectedRule  string
+	}{
+		{"", "2024-02-29", 29, models.StatementDayFixed},
+		{models.StatementDayLastDay, "2024-02-29", 31, models.StatementDayLastDay},
+		{models.StatementDayBusinessDay, "2024-02-05", 3, models.StatementDayBusinessDay},
+	}
+
+	for _, tc := range testCases {
+		cardReq := CreateCardRequest{
+			Name:             "Chase Sapphire",
+			LastFour:         "1234",
+			StatementDate:    tc.statementDate,
+			DueDate:          "2024-03-25",
+			StatementDayRule: tc.rule,
+		}
+
+		body, _ := json.Marshal(cardReq)
+		w := httptest.NewRecorder()
+		h.CreateCard(w, httptest.NewRequest(http.MethodPost, "/api/v1/cards", bytes.NewReader(body)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Rule %q: expected status 201, got %d: %s", tc.rule, w.Code, w.Body.String())
+		}
+
+		var card models.CreditCard
+		json.NewDecoder(w.Body).Decode(&card)
+		if card.StatementDay != tc.expectedDay || card.StatementDayRule != tc.expectedRule {
+			t.Errorf("Rule %q: expected day %d rule %s, got day %d rule %s", tc.rule, tc.expectedDay, tc.expectedRule, card.StatementDay, card.StatementDayRule)
+		}
+	}
+}
+
+func TestCreateCard_InvalidStatementDayRule(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	for _, cardReq := range []CreateCardRequest{
+		{Name: "Chase Sapphire", LastFour: "1234", StatementDate: "2024-11-15", DueDate: "2024-12-10", StatementDayRule: "weekly"},
+		// The 23rd is a Saturday, so it can't be a business day
+		{Name: "Chase Sapphire", LastFour: "1234", StatementDate: "2024-11-23", DueDate: "2024-12-18", StatementDayRule: models.StatementDayBusinessDay},
+	} {
+		body, _ := json.Marshal(cardReq)
+		w := httptest.NewRecorder()
+		h.CreateCard(w, httptest.NewRequest(http.MethodPost, "/api/v1/cards", bytes.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Rule %q on %s: expected status 400, got %d", cardReq.StatementDayRule, cardReq.StatementDate, w.Code)
+		}
+	}
+}
+
+func TestCreateCard_PaymentPolicy(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	testCases := []struct {
+		policy           string
+		leadDays         int
+		expectedPolicy   string
+		expectedLeadDays int
+	}{
+		{"", 0, models.PaymentPolicyLeadTime, models.DefaultPaymentLeadDays},
+		{models.PaymentPolicyLeadTime, 3, models.PaymentPolicyLeadTime, 3},
+		{models.PaymentPolicyPayday, 0, models.PaymentPolicyPayday, models.DefaultPaymentLeadDays},
+	}
+
+	for _, tc := range testCases {
+		cardReq := CreateCardRequest{
+			Name:            "Chase Sapphire",
+			LastFour:        "1234",
+			StatementDate:   "2024-11-15",
+			DueDate:         "2024-12-10",
+			PaymentPolicy:   tc.policy,
+			PaymentLeadDays: tc.leadDays,
+		}
+
+		body, _ := json.Marshal(cardReq)
+		w := httptest.NewRecorder()
+		h.CreateCard(w, httptest.NewRequest(http.MethodPost, "/api/v1/cards", bytes.NewReader(body)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Policy %q: expected status 201, got %d: %s", tc.policy, w.Code, w.Body.String())
+		}
+
+		var card models.CreditCard
+		json.NewDecoder(w.Body).Decode(&card)
+		if card.PaymentPolicy != tc.expectedPolicy || card.PaymentLeadDays != tc.expectedLeadDays {
+			t.Errorf("Policy %q: expected %s with %d lead days, got %s with %d", tc.policy, tc.expectedPolicy, tc.expectedLeadDays, card.PaymentPolicy, card.PaymentLeadDays)
+		}
+	}
+}
+
+func TestCreateCard_InvalidPaymentPolicy(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	for _, cardReq := range []CreateCardRequest{
+		{Name: "Chase Sapphire", LastFour: "1234", StatementDate: "2024-11-15", DueDate: "2024-12-10", PaymentPolicy: "whenever"},
+		{Name: "Chase Sapphire", LastFour: "1234", StatementDate: "2024-11-15", DueDate: "2024-12-10", PaymentLeadDays: models.MaxPaymentLeadDays + 1},
+		{Name: "Chase Sapphire", LastFour: "1234", StatementDate: "2024-11-15", DueDate: "2024-12-10", PaymentLeadDays: -1},
+	} {
+		body, _ := json.Marshal(cardReq)
+		w := httptest.NewRecorder()
+		h.CreateCard(w, httptest.NewRequest(http.MethodPost, "/api/v1/cards", bytes.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Policy %q with %d lead days: expected status 400, got %d", cardReq.PaymentPolicy, cardReq.PaymentLeadDays, w.Code)
+		}
 	}
 }
 
 func TestCreateCard_MissingName(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		LastFour:      "1234",
@@ -684,7 +841,7 @@ func TestCreateCard_MissingName(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -695,8 +852,7 @@ func TestCreateCard_MissingName(t *testing.T) {
 }
 
 func TestCreateCard_NameTooShort(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "A",
@@ -710,7 +866,7 @@ func TestCreateCard_NameTooShort(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -721,8 +877,7 @@ func TestCreateCard_NameTooShort(t *testing.T) {
 }
 
 func TestCreateCard_LastFourNotFourDigits(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Test Card",
@@ -736,7 +891,7 @@ func TestCreateCard_LastFourNotFourDigits(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -747,8 +902,7 @@ func TestCreateCard_LastFourNotFourDigits(t *testing.T) {
 }
 
 func TestCreateCard_LastFourNotNumeric(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Test Card",
@@ -762,7 +916,7 @@ func TestCreateCard_LastFourNotNumeric(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -773,8 +927,7 @@ func TestCreateCard_LastFourNotNumeric(t *testing.T) {
 }
 
 func TestCreateCard_InvalidStatementDate(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Test Card",
@@ -788,7 +941,7 @@ func TestCreateCard_InvalidStatementDate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -799,8 +952,7 @@ func TestCreateCard_InvalidStatementDate(t *testing.T) {
 }
 
 func TestCreateCard_InvalidDueDate(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Test Card",
@@ -814,7 +966,7 @@ func TestCreateCard_InvalidDueDate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -825,8 +977,7 @@ func TestCreateCard_InvalidDueDate(t *testing.T) {
 }
 
 func TestCreateCard_DueDateNotAfterStatementDate(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Test Card",
@@ -840,7 +991,7 @@ func TestCreateCard_DueDateNotAfterStatementDate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -851,8 +1002,7 @@ func TestCreateCard_DueDateNotAfterStatementDate(t *testing.T) {
 }
 
 func TestCreateCard_DueDateSameAsStatementDate(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Test Card",
@@ -866,7 +1016,7 @@ func TestCreateCard_DueDateSameAsStatementDate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -877,15 +1027,14 @@ func TestCreateCard_DueDateSameAsStatementDate(t *testing.T) {
 }
 
 func TestCreateCard_NegativeCreditLimit(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Test Card",
 		LastFour:      "1234",
 		StatementDate: "2024-11-15",
 		DueDate:       "2024-12-10",
-		CreditLimit:   -1000.00,
+		CreditLimit:   models.MustParseMoney("-1000.00"),
 	}
 
 	body, _ := json.Marshal(cardReq)
@@ -893,7 +1042,7 @@ func TestCreateCard_NegativeCreditLimit(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -904,8 +1053,7 @@ func TestCreateCard_NegativeCreditLimit(t *testing.T) {
 }
 
 func TestCreateCard_WithoutCreditLimit(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	cardReq := CreateCardRequest{
 		Name:          "Test Card",
@@ -919,7 +1067,7 @@ func TestCreateCard_WithoutCreditLimit(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -930,14 +1078,13 @@ func TestCreateCard_WithoutCreditLimit(t *testing.T) {
 }
 
 func TestCreateCard_InvalidJSON(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/cards", bytes.NewReader([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -948,13 +1095,12 @@ func TestCreateCard_InvalidJSON(t *testing.T) {
 }
 
 func TestCreateCard_MethodNotAllowed(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/cards", nil)
 	w := httptest.NewRecorder()
 
-	CreateCard(w, req)
+	h.CreateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -967,13 +1113,12 @@ func TestCreateCard_MethodNotAllowed(t *testing.T) {
 // --- Tests for UpdateCard ---
 
 func TestUpdateCard_Success(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
-		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit)
-		VALUES ('Original Name', '1234', 15, 25, 3000.00)
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit_cents)
+		VALUES ('Original Name', '1234', 15, 25, 300000)
 	`)
 	if err != nil {
 		t.Fatalf("Failed to insert test card: %v", err)
@@ -992,7 +1137,7 @@ func TestUpdateCard_Success(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1016,11 +1161,10 @@ func TestUpdateCard_Success(t *testing.T) {
 }
 
 func TestUpdateCard_UpdateDates(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1041,7 +1185,7 @@ func TestUpdateCard_UpdateDates(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1064,9 +1208,94 @@ func TestUpdateCard_UpdateDates(t *testing.T) {
 	}
 }
 
+func TestUpdateCard_StatementDayRule(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, statement_day_rule, days_until_due)
+		VALUES ('Test Card', '1234', 31, 'last_day', 25)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert test card: %v", err)
+	}
+	cardID, _ := result.LastInsertId()
+
+	// The rule can't change without a statement date to apply it to
+	body, _ := json.Marshal(CreateCardRequest{StatementDayRule: models.StatementDayFixed})
+	w := httptest.NewRecorder()
+	h.UpdateCard(w, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/cards/%d", cardID), bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	// New dates keep the card's existing rule
+	body, _ = json.Marshal(CreateCardRequest{StatementDate: "2024-04-30", DueDate: "2024-05-25"})
+	w = httptest.NewRecorder()
+	h.UpdateCard(w, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/cards/%d", cardID), bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var card models.CreditCard
+	json.NewDecoder(w.Body).Decode(&card)
+	if card.StatementDay != 31 || card.StatementDayRule != models.StatementDayLastDay {
+		t.Errorf("Expected last_day rule kept, got day %d rule %s", card.StatementDay, card.StatementDayRule)
+	}
+
+	body, _ = json.Marshal(CreateCardRequest{StatementDate: "2024-04-30", DueDate: "2024-05-25", StatementDayRule: models.StatementDayFixed})
+	w = httptest.NewRecorder()
+	h.UpdateCard(w, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/cards/%d", cardID), bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	json.NewDecoder(w.Body).Decode(&card)
+	if card.StatementDay != 30 || card.StatementDayRule != models.StatementDayFixed {
+		t.Errorf("Expected fixed day 30, got day %d rule %s", card.StatementDay, card.StatementDayRule)
+	}
+}
+
+func TestUpdateCard_PaymentPolicy(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES ('Test Card', '1234', 15, 25)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert test card: %v", err)
+	}
+	cardID, _ := result.LastInsertId()
+
+	body, _ := json.Marshal(CreateCardRequest{PaymentPolicy: models.PaymentPolicyStatementDay, PaymentLeadDays: 2})
+	w := httptest.NewRecorder()
+	h.UpdateCard(w, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/cards/%d", cardID), bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var card models.CreditCard
+	json.NewDecoder(w.Body).Decode(&card)
+	if card.PaymentPolicy != models.PaymentPolicyStatementDay || card.PaymentLeadDays != 2 {
+		t.Errorf("Expected statement_day with 2 lead days, got %s with %d", card.PaymentPolicy, card.PaymentLeadDays)
+	}
+
+	// Changing other fields keeps the policy
+	body, _ = json.Marshal(CreateCardRequest{Name: "Renamed Card"})
+	w = httptest.NewRecorder()
+	h.UpdateCard(w, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/cards/%d", cardID), bytes.NewReader(body)))
+	json.NewDecoder(w.Body).Decode(&card)
+	if card.PaymentPolicy != models.PaymentPolicyStatementDay || card.PaymentLeadDays != 2 {
+		t.Errorf("Expected the policy to be kept, got %s with %d", card.PaymentPolicy, card.PaymentLeadDays)
+	}
+
+	body, _ = json.Marshal(CreateCardRequest{PaymentPolicy: "whenever"})
+	w = httptest.NewRecorder()
+	h.UpdateCard(w, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/cards/%d", cardID), bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown policy, got %d", w.Code)
+	}
+}
+
 func TestUpdateCard_CardNotFound(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	updateReq := CreateCardRequest{
 		Name: "Updated Name",
@@ -1077,7 +1306,7 @@ func TestUpdateCard_CardNotFound(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1088,11 +1317,10 @@ func TestUpdateCard_CardNotFound(t *testing.T) {
 }
 
 func TestUpdateCard_OnlyStatementDateProvided(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1112,7 +1340,7 @@ func TestUpdateCard_OnlyStatementDateProvided(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1123,8 +1351,7 @@ func TestUpdateCard_OnlyStatementDateProvided(t *testing.T) {
 }
 
 func TestUpdateCard_InvalidID(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	updateReq := CreateCardRequest{
 		Name: "Updated Name",
@@ -1135,7 +1362,7 @@ func TestUpdateCard_InvalidID(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1146,11 +1373,10 @@ func TestUpdateCard_InvalidID(t *testing.T) {
 }
 
 func TestUpdateCard_NoFieldsToUpdate(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1168,7 +1394,7 @@ func TestUpdateCard_NoFieldsToUpdate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1179,13 +1405,12 @@ func TestUpdateCard_NoFieldsToUpdate(t *testing.T) {
 }
 
 func TestUpdateCard_MethodNotAllowed(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/cards/1", nil)
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1198,11 +1423,10 @@ func TestUpdateCard_MethodNotAllowed(t *testing.T) {
 // --- Tests for DeleteCard ---
 
 func TestDeleteCard_Success(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card with statements
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1213,9 +1437,9 @@ func TestDeleteCard_Success(t *testing.T) {
 	cardID, _ := result.LastInsertId()
 
 	// Insert statements
-	_, err = database.DB.Exec(`
-		INSERT INTO statements (card_id, statement_date, due_date, amount, status)
-		VALUES (?, '2024-11-01', '2024-11-15', 1000.00, 'pending')
+	_, err = db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, status)
+		VALUES (?, '2024-11-01', '2024-11-15', 100000, 'pending')
 	`, cardID)
 	if err != nil {
 		t.Fatalf("Failed to insert test statement: %v", err)
@@ -1224,7 +1448,7 @@ func TestDeleteCard_Success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/cards/%d", cardID), nil)
 	w := httptest.NewRecorder()
 
-	DeleteCard(w, req)
+	h.DeleteCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1250,7 +1474,7 @@ func TestDeleteCard_Success(t *testing.T) {
 
 	// Verify card is deleted
 	var count int
-	err = database.DB.QueryRow("SELECT COUNT(*) FROM credit_cards WHERE id = ?", cardID).Scan(&count)
+	err = db.QueryRow("SELECT COUNT(*) FROM credit_cards WHERE id = ?", cardID).Scan(&count)
 	if err != nil {
 		t.Fatalf("Failed to query cards: %v", err)
 	}
@@ -1259,7 +1483,7 @@ func TestDeleteCard_Success(t *testing.T) {
 	}
 
 	// Verify statements are deleted (CASCADE)
-	err = database.DB.QueryRow("SELECT COUNT(*) FROM statements WHERE card_id = ?", cardID).Scan(&count)
+	err = db.QueryRow("SELECT COUNT(*) FROM statements WHERE card_id = ?", cardID).Scan(&count)
 	if err != nil {
 		t.Fatalf("Failed to query statements: %v", err)
 	}
@@ -1269,13 +1493,12 @@ func TestDeleteCard_Success(t *testing.T) {
 }
 
 func TestDeleteCard_NotFound(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/cards/9999", nil)
 	w := httptest.NewRecorder()
 
-	DeleteCard(w, req)
+	h.DeleteCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1286,13 +1509,12 @@ func TestDeleteCard_NotFound(t *testing.T) {
 }
 
 func TestDeleteCard_InvalidID(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/cards/invalid", nil)
 	w := httptest.NewRecorder()
 
-	DeleteCard(w, req)
+	h.DeleteCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1303,13 +1525,12 @@ func TestDeleteCard_InvalidID(t *testing.T) {
 }
 
 func TestDeleteCard_MethodNotAllowed(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, _ := setupTestDB(t)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/cards/1", nil)
 	w := httptest.NewRecorder()
 
-	DeleteCard(w, req)
+	h.DeleteCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1322,6 +1543,8 @@ func TestDeleteCard_MethodNotAllowed(t *testing.T) {
 // --- Tests for GetSettings and UpdateSettings ---
 
 func TestGetSettings_Success(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+
 	// Create a temporary config file
 	tmpConfig := "./test_config.yaml"
 	defer os.Remove(tmpConfig)
@@ -1339,7 +1562,7 @@ func TestGetSettings_Success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/settings", nil)
 	w := httptest.NewRecorder()
 
-	GetSettings(w, req)
+	h.GetSettings(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1360,10 +1583,12 @@ func TestGetSettings_Success(t *testing.T) {
 }
 
 func TestGetSettings_MethodNotAllowed(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+
 	req := httptest.NewRequest(http.MethodPost, "/api/settings", nil)
 	w := httptest.NewRecorder()
 
-	GetSettings(w, req)
+	h.GetSettings(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1374,6 +1599,8 @@ func TestGetSettings_MethodNotAllowed(t *testing.T) {
 }
 
 func TestUpdateSettings_Success(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+
 	tmpConfig := "./test_config_update.yaml"
 	defer os.Remove(tmpConfig)
 
@@ -1389,7 +1616,7 @@ func TestUpdateSettings_Success(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateSettings(w, req)
+	h.UpdateSettings(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1410,6 +1637,8 @@ func TestUpdateSettings_Success(t *testing.T) {
 }
 
 func TestUpdateSettings_InvalidWebhookURL(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+
 	tmpConfig := "./test_config_invalid.yaml"
 	defer os.Remove(tmpConfig)
 
@@ -1425,7 +1654,7 @@ func TestUpdateSettings_InvalidWebhookURL(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateSettings(w, req)
+	h.UpdateSettings(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1436,11 +1665,13 @@ func TestUpdateSettings_InvalidWebhookURL(t *testing.T) {
 }
 
 func TestUpdateSettings_InvalidJSON(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+
 	req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewReader([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateSettings(w, req)
+	h.UpdateSettings(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1451,10 +1682,12 @@ func TestUpdateSettings_InvalidJSON(t *testing.T) {
 }
 
 func TestUpdateSettings_MethodNotAllowed(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+
 	req := httptest.NewRequest(http.MethodDelete, "/api/settings", nil)
 	w := httptest.NewRecorder()
 
-	UpdateSettings(w, req)
+	h.UpdateSettings(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1467,14 +1700,14 @@ func TestUpdateSettings_MethodNotAllowed(t *testing.T) {
 // --- Additional tests for improved coverage ---
 
 func TestGetCards_DatabaseError(t *testing.T) {
-	tmpDB := setupTestDB(t)
+	h, db := setupTestDB(t)
 	// Close DB to simulate error
-	database.Close()
+	db.Close()
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/cards", nil)
 	w := httptest.NewRecorder()
 
-	GetCards(w, req)
+	h.GetCards(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1482,20 +1715,17 @@ func TestGetCards_DatabaseError(t *testing.T) {
 	if resp.StatusCode != http.StatusInternalServerError {
 		t.Errorf("Expected status 500, got %d", resp.StatusCode)
 	}
-
-	// Cleanup
-	os.Remove(tmpDB)
 }
 
 func TestGetStatements_DatabaseError(t *testing.T) {
-	tmpDB := setupTestDB(t)
+	h, db := setupTestDB(t)
 	// Close DB to simulate error
-	database.Close()
+	db.Close()
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/statements", nil)
 	w := httptest.NewRecorder()
 
-	GetStatements(w, req)
+	h.GetStatements(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1503,17 +1733,13 @@ func TestGetStatements_DatabaseError(t *testing.T) {
 	if resp.StatusCode != http.StatusInternalServerError {
 		t.Errorf("Expected status 500, got %d", resp.StatusCode)
 	}
-
-	// Cleanup
-	os.Remove(tmpDB)
 }
 
 func TestUpdateCard_InvalidJSON(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1527,7 +1753,7 @@ func TestUpdateCard_InvalidJSON(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1538,11 +1764,10 @@ func TestUpdateCard_InvalidJSON(t *testing.T) {
 }
 
 func TestUpdateCard_InvalidLastFour(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1561,7 +1786,7 @@ func TestUpdateCard_InvalidLastFour(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1572,11 +1797,10 @@ func TestUpdateCard_InvalidLastFour(t *testing.T) {
 }
 
 func TestUpdateCard_InvalidCreditLimit(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1587,7 +1811,7 @@ func TestUpdateCard_InvalidCreditLimit(t *testing.T) {
 	cardID, _ := result.LastInsertId()
 
 	updateReq := CreateCardRequest{
-		CreditLimit: -1000.00,
+		CreditLimit: models.MustParseMoney("-1000.00"),
 	}
 
 	body, _ := json.Marshal(updateReq)
@@ -1595,7 +1819,7 @@ func TestUpdateCard_InvalidCreditLimit(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1606,13 +1830,12 @@ func TestUpdateCard_InvalidCreditLimit(t *testing.T) {
 }
 
 func TestUpdateCard_UpdateCreditLimit(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
-		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit)
-		VALUES ('Test Card', '1234', 15, 25, 5000.00)
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due, credit_limit_cents)
+		VALUES ('Test Card', '1234', 15, 25, 500000)
 	`)
 	if err != nil {
 		t.Fatalf("Failed to insert test card: %v", err)
@@ -1621,7 +1844,7 @@ func TestUpdateCard_UpdateCreditLimit(t *testing.T) {
 	cardID, _ := result.LastInsertId()
 
 	updateReq := CreateCardRequest{
-		CreditLimit: 7500.00,
+		CreditLimit: models.MustParseMoney("7500.00"),
 	}
 
 	body, _ := json.Marshal(updateReq)
@@ -1629,7 +1852,7 @@ func TestUpdateCard_UpdateCreditLimit(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1644,17 +1867,16 @@ func TestUpdateCard_UpdateCreditLimit(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if card.CreditLimit != 7500.00 {
-		t.Errorf("Expected credit_limit 7500.00, got %.2f", card.CreditLimit)
+	if card.CreditLimit != models.MustParseMoney("7500.00") {
+		t.Errorf("Expected credit_limit 7500.00, got %s", card.CreditLimit)
 	}
 }
 
 func TestUpdateCard_InvalidDates(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1675,7 +1897,7 @@ func TestUpdateCard_InvalidDates(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1686,11 +1908,10 @@ func TestUpdateCard_InvalidDates(t *testing.T) {
 }
 
 func TestUpdateCard_DueDateBeforeStatementDate(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1710,7 +1931,7 @@ func TestUpdateCard_DueDateBeforeStatementDate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1721,14 +1942,13 @@ func TestUpdateCard_DueDateBeforeStatementDate(t *testing.T) {
 }
 
 func TestCreateStatement_InvalidCardID(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	stmt := models.Statement{
 		CardID:        9999,
 		StatementDate: "2024-11-01",
 		DueDate:       "2024-11-15",
-		Amount:        1500.50,
+		Amount:        models.MustParseMoney("1500.50"),
 	}
 
 	body, _ := json.Marshal(stmt)
@@ -1736,22 +1956,27 @@ func TestCreateStatement_InvalidCardID(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	CreateStatement(w, req)
+	h.CreateStatement(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusInternalServerError {
-		t.Errorf("Expected status 500, got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", resp.StatusCode)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM statements").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected no statement to be inserted, got %d", count)
 	}
 }
 
 func TestUpdateCard_NameTooShort(t *testing.T) {
-	tmpDB := setupTestDB(t)
-	defer teardownTestDB(tmpDB)
+	h, db := setupTestDB(t)
 
 	// Insert test card
-	result, err := database.DB.Exec(`
+	result, err := db.Exec(`
 		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
 		VALUES ('Test Card', '1234', 15, 25)
 	`)
@@ -1770,7 +1995,7 @@ func TestUpdateCard_NameTooShort(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
-	UpdateCard(w, req)
+	h.UpdateCard(w, req)
 
 	resp := w.Result()
 	defer resp.Body.Close()
@@ -1779,3 +2004,644 @@ func TestUpdateCard_NameTooShort(t *testing.T) {
 		t.Errorf("Expected status 400, got %d", resp.StatusCode)
 	}
 }
+
+func TestCreateStatement_SendsNotification(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	received := make(chan notify.WebhookMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg notify.WebhookMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+		w.WriteHeader(http.StatusNoContent)
+		received <- msg
+	}))
+	defer server.Close()
+
+	h.notifier = notify.NewNotifier(db, notify.NewDiscordClient(server.URL), nil)
+	h.followup = followup.New(h.statements, h.notifier, nil, nil)
+
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES ('Test Card', '1234', 15, 25)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert test card: %v", err)
+	}
+	cardID, _ := result.LastInsertId()
+
+	body, _ := json.Marshal(models.Statement{
+		CardID:        int(cardID),
+		StatementDate: "2024-11-01",
+		DueDate:       "2024-11-26",
+		Amount:        models.MustParseMoney("500.00"),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.CreateStatement(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", w.Code)
+	}
+
+	select {
+	case msg := <-received:
+		if len(msg.Embeds) != 1 || msg.Embeds[0].Title != "New statement: Test Card" {
+			t.Errorf("Unexpected webhook message: %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for Discord notification")
+	}
+
+	// The flag is set after the webhook responds, so poll briefly for it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var notified bool
+		db.QueryRow("SELECT notified_statement FROM statements WHERE card_id = ?", cardID).Scan(&notified)
+		if notified {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected notified_statement to be set")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSchedulePayment_UsesClock(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	frozen := time.Date(2024, time.February, 28, 9, 30, 0, 0, time.UTC)
+	h.clock = clock.NewFixed(frozen)
+
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES ('Test Card', '1234', 15, 25)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert test card: %v", err)
+	}
+	cardID, _ := result.LastInsertId()
+
+	result, err = db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, status)
+		VALUES (?, '2024-02-15', '2024-03-11', 10000, 'pending')
+	`, cardID)
+	if err != nil {
+		t.Fatalf("Failed to insert test statement: %v", err)
+	}
+	stmtID, _ := result.LastInsertId()
+
+	body := bytes.NewBufferString(`{"scheduled_payment_date": "2024-03-04"}`)
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/statements/%d/schedule", stmtID), body)
+	w := httptest.NewRecorder()
+
+	h.SchedulePayment(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&response)
+	if response["reviewed_at"] != frozen.Format(time.RFC3339) {
+		t.Errorf("Expected reviewed_at %s, got %v", frozen.Format(time.RFC3339), response["reviewed_at"])
+	}
+}
+
+func TestHealthCheck_ReportsClockTime(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+
+	frozen := time.Date(2024, time.February, 28, 12, 0, 0, 0, time.UTC)
+	h.clock = clock.NewFixed(frozen)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	w := httptest.NewRecorder()
+
+	h.HealthCheck(w, req)
+
+	var response map[string]string
+	json.NewDecoder(w.Body).Decode(&response)
+	if response["time"] != "2024-02-28T12:00:00Z" {
+		t.Errorf("Expected time 2024-02-28T12:00:00Z, got %q", response["time"])
+	}
+}
+
+// insertTestStatement inserts a card and a statement with the given status
+// and returns the statement ID
+func insertTestStatement(t *testing.T, db *sql.DB, status string) int64 {
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES ('Test Card', '1234', 15, 25)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert test card: %v", err)
+	}
+	cardID, _ := result.LastInsertId()
+
+	result, err = db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, status)
+		VALUES (?, '2024-11-01', '2024-11-15', 125075, ?)
+	`, cardID, status)
+	if err != nil {
+		t.Fatalf("Failed to insert test statement: %v", err)
+	}
+	stmtID, _ := result.LastInsertId()
+	return stmtID
+}
+
+func patchStatement(h *Handler, id int64, body string) *http.Response {
+	req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/statements/%d", id), bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.PatchStatement(w, req)
+	return w.Result()
+}
+
+func TestGetStatementByID_Success(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/statements/%d", stmtID), nil)
+	w := httptest.NewRecorder()
+	h.GetStatementByID(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var stmt models.Statement
+	if err := json.NewDecoder(resp.Body).Decode(&stmt); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if stmt.ID != int(stmtID) || stmt.Amount != models.MustParseMoney("1250.75") {
+		t.Errorf("Unexpected statement %+v", stmt)
+	}
+}
+
+func TestGetStatementByID_NotFound(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/statements/999", nil)
+	w := httptest.NewRecorder()
+	h.GetStatementByID(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestPatchStatement_PartialUpdate(t *testing.T) {
+	h, db := setupTestDB(t)
+	h.clock = clock.NewFixed(time.Date(2024, time.November, 5, 9, 0, 0, 0, time.UTC))
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	resp := patchStatement(h, stmtID, `{"amount": "980.10"}`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var stmt models.Statement
+	json.NewDecoder(resp.Body).Decode(&stmt)
+	if stmt.Amount != models.MustParseMoney("980.10") {
+		t.Errorf("Expected amount 980.10, got %s", stmt.Amount)
+	}
+	if stmt.StatementDate != "2024-11-01" || stmt.DueDate != "2024-11-15" {
+		t.Errorf("Expected dates to be unchanged, got %s and %s", stmt.StatementDate, stmt.DueDate)
+	}
+	if !stmt.UpdatedAt.Equal(h.clock.Now()) {
+		t.Errorf("Expected updated_at %v, got %v", h.clock.Now(), stmt.UpdatedAt)
+	}
+}
+
+func TestPatchStatement_ValidatesDates(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid due date", `{"due_date": "2024-11-31"}`},
+		{"invalid statement date", `{"statement_date": "11/01/2024"}`},
+		{"due date before existing statement date", `{"due_date": "2024-10-30"}`},
+		{"statement date after existing due date", `{"statement_date": "2024-11-20"}`},
+		{"due date same as statement date", `{"statement_date": "2024-12-01", "due_date": "2024-12-01"}`},
+		{"zero amount", `{"amount": 0}`},
+		{"no fields", `{}`},
+		{"unknown field", `{"status": "paid"}`},
+	}
+
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := patchStatement(h, stmtID, tt.body)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestPatchStatement_BothDates(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	// Moving both dates past the old due date is valid as a whole
+	resp := patchStatement(h, stmtID, `{"statement_date": "2024-12-01", "due_date": "2024-12-26"}`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestPatchStatement_AmountLockedWhenPaid(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "paid")
+
+	resp := patchStatement(h, stmtID, `{"amount": 10}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", resp.StatusCode)
+	}
+
+	// Other fields can still be corrected
+	resp = patchStatement(h, stmtID, `{"due_date": "2024-11-16"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestPatchStatement_UnknownCard(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	resp := patchStatement(h, stmtID, `{"card_id": 999}`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", resp.StatusCode)
+	}
+}
+
+func TestPatchStatement_NotFound(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	resp := patchStatement(h, 999, `{"amount": 10}`)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeleteStatement_Success(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/statements/%d", stmtID), nil)
+	w := httptest.NewRecorder()
+	h.DeleteStatement(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM statements WHERE id = ?", stmtID).Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected statement to be deleted, found %d", count)
+	}
+
+	// A second delete reports not found
+	w = httptest.NewRecorder()
+	h.DeleteStatement(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func putStatementStatus(h *Handler, id int64, body string) *http.Response {
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/statements/%d", id), bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.UpdateStatement(w, req)
+	return w.Result()
+}
+
+func TestUpdateStatement_Transitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       string
+		body       string
+		wantCode   int
+		wantStatus string
+	}{
+		{"pending to paid", "pending", `{"status": "paid"}`, http.StatusOK, "paid"},
+		{"overdue to paid", "overdue", `{"status": "paid"}`, http.StatusOK, "paid"},
+		{"paid to pending", "paid", `{"status": "pending"}`, http.StatusConflict, "paid"},
+		{"paid to pending with reopen", "paid", `{"status": "pending", "reopen": true}`, http.StatusOK, "pending"},
+		{"overdue to pending", "overdue", `{"status": "pending"}`, http.StatusConflict, "overdue"},
+		{"unknown status", "pending", `{"status": "cancelled"}`, http.StatusBadRequest, "pending"},
+		{"same status", "paid", `{"status": "paid"}`, http.StatusOK, "paid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db := setupTestDB(t)
+			// Before the test statement's due date of 2024-11-15
+			h.clock = clock.NewFixed(time.Date(2024, time.November, 10, 9, 0, 0, 0, time.UTC))
+
+			stmtID := insertTestStatement(t, db, tt.from)
+
+			resp := putStatementStatus(h, stmtID, tt.body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, resp.StatusCode)
+			}
+
+			var status string
+			db.QueryRow("SELECT status FROM statements WHERE id = ?", stmtID).Scan(&status)
+			if status != tt.wantStatus {
+				t.Errorf("Expected statement status %s, got %s", tt.wantStatus, status)
+			}
+		})
+	}
+}
+
+func TestUpdateStatement_RecordsHistory(t *testing.T) {
+	h, db := setupTestDB(t)
+	now := time.Date(2024, time.November, 10, 9, 0, 0, 0, time.UTC)
+	h.clock = clock.NewFixed(now)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	resp := putStatementStatus(h, stmtID, `{"status": "paid", "changed_by": "alex"}`)
+	resp.Body.Close()
+	resp = putStatementStatus(h, stmtID, `{"status": "pending", "reopen": true}`)
+	resp.Body.Close()
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/statements/%d/history", stmtID), nil)
+	w := httptest.NewRecorder()
+	h.GetStatementHistory(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var history []models.StatusChange
+	json.NewDecoder(w.Body).Decode(&history)
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 status changes, got %d", len(history))
+	}
+	if history[0].ToStatus != "paid" || history[0].ChangedBy != "alex" || !history[0].ChangedAt.Equal(now) {
+		t.Errorf("Unexpected first change %+v", history[0])
+	}
+	if history[1].FromStatus != "paid" || history[1].ToStatus != "pending" || history[1].ChangedBy != "user" {
+		t.Errorf("Unexpected second change %+v", history[1])
+	}
+
+	stmt, _ := h.statements.Get(req.Context(), int(stmtID))
+	if stmt.StatusChangedBy == nil || *stmt.StatusChangedBy != "user" {
+		t.Errorf("Expected status_changed_by user, got %v", stmt.StatusChangedBy)
+	}
+}
+
+func TestGetStatements_EvaluatesOverdue(t *testing.T) {
+	h, db := setupTestDB(t)
+	// The day after the test statement's due date of 2024-11-15
+	h.clock = clock.NewFixed(time.Date(2024, time.November, 16, 9, 0, 0, 0, time.UTC))
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/statements", nil)
+	w := httptest.NewRecorder()
+	h.GetStatements(w, req)
+
+	var statements []models.Statement
+	json.NewDecoder(w.Body).Decode(&statements)
+	if len(statements) != 1 || statements[0].Status != "overdue" {
+		t.Fatalf("Expected the statement to read as overdue, got %+v", statements)
+	}
+
+	// Reading doesn't write; the scheduler records the change
+	var status string
+	db.QueryRow("SELECT status FROM statements WHERE id = ?", stmtID).Scan(&status)
+	if status != "pending" {
+		t.Errorf("Expected stored status pending, got %s", status)
+	}
+
+	// Reopening isn't needed to pay it
+	resp := putStatementStatus(h, stmtID, `{"status": "paid"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func postPayment(h *Handler, statementID int64, body string) *http.Response {
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/statements/%d/payments", statementID), bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.CreatePayment(w, req)
+	return w.Result()
+}
+
+func TestCreatePayment_PartialThenPaid(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	resp := postPayment(h, stmtID, `{"amount": "1000.00", "payment_date": "2024-11-10", "source_account": "EQ Bank", "confirmation_number": "ABC123"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+
+	var payment models.Payment
+	json.NewDecoder(resp.Body).Decode(&payment)
+	if payment.ID == 0 || payment.StatementID != int(stmtID) || payment.ConfirmationNumber != "ABC123" {
+		t.Errorf("Unexpected payment %+v", payment)
+	}
+
+	stmt, _ := h.statements.Get(context.Background(), int(stmtID))
+	if stmt.Status != "pending" || stmt.PaidAmount != models.MustParseMoney("1000.00") || stmt.RemainingBalance != models.MustParseMoney("250.75") {
+		t.Errorf("Unexpected statement after partial payment: %+v", stmt)
+	}
+
+	resp2 := postPayment(h, stmtID, `{"amount": 250.75, "payment_date": "2024-11-12"}`)
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp2.StatusCode)
+	}
+
+	stmt, _ = h.statements.Get(context.Background(), int(stmtID))
+	if stmt.Status != "paid" || stmt.RemainingBalance != 0 {
+		t.Errorf("Expected statement paid in full, got status %s, remaining %s", stmt.Status, stmt.RemainingBalance)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/statements/%d/payments", stmtID), nil)
+	w := httptest.NewRecorder()
+	h.GetPayments(w, req)
+
+	var payments []models.Payment
+	json.NewDecoder(w.Body).Decode(&payments)
+	if len(payments) != 2 {
+		t.Errorf("Expected 2 payments, got %d", len(payments))
+	}
+}
+
+func TestCreatePayment_Validation(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	for _, body := range []string{
+		`{"amount": 0, "payment_date": "2024-11-10"}`,
+		`{"amount": 10}`,
+		`{"amount": 10, "payment_date": "10/11/2024"}`,
+		`not json`,
+	} {
+		resp := postPayment(h, stmtID, body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, resp.StatusCode)
+		}
+	}
+
+	resp := postPayment(h, 999, `{"amount": 10, "payment_date": "2024-11-10"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing statement, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeletePayment(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	resp := postPayment(h, stmtID, `{"amount": 1250.75, "payment_date": "2024-11-10"}`)
+	var payment models.Payment
+	json.NewDecoder(resp.Body).Decode(&payment)
+	resp.Body.Close()
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/statements/%d/payments/%d", stmtID, payment.ID), nil)
+	w := httptest.NewRecorder()
+	h.DeletePayment(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	stmt, _ := h.statements.Get(context.Background(), int(stmtID))
+	if stmt.Status != "pending" || stmt.PaidAmount != 0 {
+		t.Errorf("Expected statement reopened with nothing paid, got status %s, paid %s", stmt.Status, stmt.PaidAmount)
+	}
+
+	w = httptest.NewRecorder()
+	h.DeletePayment(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// A payment ID is required
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/statements/%d/payments", stmtID), nil)
+	w = httptest.NewRecorder()
+	h.DeletePayment(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestCreateStatement_MinimumPaymentAndCurrentBalance(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES ('Test Card', '1234', 15, 25)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert test card: %v", err)
+	}
+	cardID, _ := result.LastInsertId()
+
+	tests := []struct {
+		name     string
+		extra    string
+		wantCode int
+	}{
+		{"with minimum and balance", `"minimum_payment": 35, "current_balance": "1410.20"`, http.StatusCreated},
+		{"minimum over amount", `"minimum_payment": 2000`, http.StatusBadRequest},
+		{"negative minimum", `"minimum_payment": -5`, http.StatusBadRequest},
+		{"negative current balance", `"current_balance": -1`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"card_id": %d, "statement_date": "2024-11-01", "due_date": "2024-11-26", "amount": 1250.75, %s}`, cardID, tt.extra)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/statements", bytes.NewReader([]byte(body)))
+			w := httptest.NewRecorder()
+			h.CreateStatement(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+
+			var created models.Statement
+			json.NewDecoder(w.Body).Decode(&created)
+			stmt, err := h.statements.Get(context.Background(), created.ID)
+			if err != nil {
+				t.Fatalf("Failed to load statement: %v", err)
+			}
+			if stmt.MinimumPayment != models.MustParseMoney("35.00") {
+				t.Errorf("Expected minimum payment 35.00, got %s", stmt.MinimumPayment)
+			}
+			if stmt.CurrentBalance == nil || *stmt.CurrentBalance != models.MustParseMoney("1410.20") {
+				t.Errorf("Expected current balance 1410.20, got %v", stmt.CurrentBalance)
+			}
+		})
+	}
+}
+
+func TestPatchStatement_MinimumPayment(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	stmtID := insertTestStatement(t, db, "pending")
+
+	resp := patchStatement(h, stmtID, `{"minimum_payment": 40, "current_balance": 1300}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var stmt models.Statement
+	json.NewDecoder(resp.Body).Decode(&stmt)
+	if stmt.MinimumPayment != models.MustParseMoney("40.00") || stmt.CurrentBalance == nil || *stmt.CurrentBalance != models.MustParseMoney("1300.00") {
+		t.Errorf("Unexpected statement %+v", stmt)
+	}
+
+	// Lowering the amount below the minimum is rejected
+	resp2 := patchStatement(h, stmtID, `{"amount": 30}`)
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp2.StatusCode)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+)
+
+// maxHolidayUpload limits the size of an uploaded holiday list
+const maxHolidayUpload = 1 << 20
+
+// loadCalendar loads the business day calendar with any uploaded holidays
+func (h *Handler) loadCalendar(ctx context.Context) (*holidays.Calendar, error) {
+	// Pass a nil interface rather than a typed nil repository
+	if h.holidays == nil {
+		return h.calendar.Load(ctx, nil)
+	}
+	return h.calendar.Load(ctx, h.holidays)
+}
+
+// HolidaysResponse lists the holidays of a year
+type HolidaysResponse struct {
+	Year     int                `json:"year"`
+	Regions  []string           `json:"regions"`
+	Holidays []holidays.Holiday `json:"holidays"`
+}
+
+// GetHolidays returns the built-in and uploaded holidays of the year given
+// by the year query parameter, defaulting to the current year
+func (h *Handler) GetHolidays(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	year := clock.Today(h.clock).Year()
+	if s := r.URL.Query().Get("year"); s != "" {
+		var err error
+		year, err = strconv.Atoi(s)
+		if err != nil || year < 1900 || year > 9999 {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+
+	cal, err := h.loadCalendar(r.Context())
+	if err != nil {
+		log.Printf("Error loading holidays: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(HolidaysResponse{
+		Year:     year,
+		Regions:  cal.Regions(),
+		Holidays: cal.Year(year),
+	})
+}
+
+// UploadHolidays replaces the holidays uploaded under the source query
+// parameter with an ICS or CSV list. The list is either the request body or
+// the "file" field of a multipart form, and its format comes from the format
+// query parameter, the file extension or the content type.
+func (h *Handler) UploadHolidays(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.holidays == nil {
+		http.Error(w, "Holiday uploads are not available", http.StatusNotImplemented)
+		return
+	}
+
+	source := strings.TrimSpace(r.URL.Query().Get("source"))
+	if source == "" {
+		http.Error(w, "source is required", http.StatusBadRequest)
+		return
+	}
+	if holidays.IsRegion(source) {
+		http.Error(w, "source can't be a built-in region name", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHolidayUpload)
+	body, filename, contentType, err := readUpload(r)
+	if err != nil {
+		log.Printf("Error reading holiday upload: %v", err)
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+
+	format := holidayFormat(r.URL.Query().Get("format"), filename, contentType)
+	if format == "" {
+		http.Error(w, "format must be ics or csv", http.StatusBadRequest)
+		return
+	}
+
+	list, err := holidays.Parse(body, format, source)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stored, err := h.holidays.Replace(r.Context(), source, list, h.clock.Now())
+	if err != nil {
+		log.Printf("Error storing holidays from %s: %v", source, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"source": source,
+		"stored": stored,
+	})
+}
+
+// readUpload returns an uploaded file with its name and content type. The
+// file is either the request body or the "file" field of a multipart form.
+func readUpload(r *http.Request) (io.ReadCloser, string, string, error) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != "multipart/form-data" {
+		return r.Body, "", contentType, nil
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", "", err
+	}
+	fileType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	return file, header.Filename, fileType, nil
+}
+
+// holidayFormat picks the format of an upload, preferring an explicit one.
+// It returns "" when the format can't be determined.
+func holidayFormat(explicit, filename, contentType string) string {
+	if explicit != "" {
+		explicit = strings.ToLower(explicit)
+		if explicit == holidays.FormatICS || explicit == holidays.FormatCSV {
+			return explicit
+		}
+		return ""
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ics", ".ical":
+		return holidays.FormatICS
+	case ".csv":
+		return holidays.FormatCSV
+	}
+
+	switch contentType {
+	case "text/calendar":
+		return holidays.FormatICS
+	case "text/csv":
+		return holidays.FormatCSV
+	}
+	return ""
+}
+
+// DeleteHolidays removes the holidays uploaded under the source query
+// parameter
+func (h *Handler) DeleteHolidays(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.holidays == nil {
+		http.Error(w, "Holiday uploads are not available", http.StatusNotImplemented)
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		http.Error(w, "source is required", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.holidays.DeleteSource(r.Context(), source)
+	if err != nil {
+		log.Printf("Error deleting holidays from %s: %v", source, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "No holidays uploaded from that source", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int{"deleted": deleted})
+}
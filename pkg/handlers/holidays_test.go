@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// setupHolidayTestDB creates a test database with holiday uploads enabled
+func setupHolidayTestDB(t *testing.T) (*Handler, *sql.DB) {
+	h, db := setupTestDB(t)
+	h.holidays = repository.NewSQLiteHolidayRepository(db)
+	return h, db
+}
+
+func uploadHolidays(t *testing.T, h *Handler, query, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/holidays?"+query, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.UploadHolidays(w, req)
+	return w
+}
+
+func getHolidays(t *testing.T, h *Handler, year string) HolidaysResponse {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/holidays?year="+year, nil)
+	w := httptest.NewRecorder()
+	h.GetHolidays(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp HolidaysResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestUploadHolidays_CSV(t *testing.T) {
+	h, _ := setupHolidayTestDB(t)
+
+	body := "date,name\n2024-08-05,Civic Holiday\n2024-12-24,Bank closure\n"
+	w := uploadHolidays(t, h, "source=bank&format=csv", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["source"] != "bank" || resp["stored"] != float64(2) {
+		t.Errorf("Unexpected response %v", resp)
+	}
+
+	// Uploaded holidays are listed alongside the built-in ones
+	list := getHolidays(t, h, "2024")
+	var found int
+	for _, holiday := range list.Holidays {
+		if holiday.Source == "bank" {
+			found++
+		}
+		if holiday.Date == "2024-12-25" && holiday.Source != "CA" {
+			t.Errorf("Expected Christmas from the CA rules, got %+v", holiday)
+		}
+	}
+	if found != 2 {
+		t.Errorf("Expected 2 uploaded holidays, got %d", found)
+	}
+
+	// Uploading the same source again replaces its holidays
+	uploadHolidays(t, h, "source=bank&format=csv", "2024-08-05,Civic Holiday\n")
+	found = 0
+	for _, holiday := range getHolidays(t, h, "2024").Holidays {
+		if holiday.Source == "bank" {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Errorf("Expected 1 uploaded holiday after replacing, got %d", found)
+	}
+}
+
+func TestUploadHolidays_ICSMultipart(t *testing.T) {
+	h, _ := setupHolidayTestDB(t)
+
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20240805\r\nSUMMARY:Civic Holiday\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", "ontario.ics")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write([]byte(ics))
+	mw.Close()
+
+	// The format comes from the file extension
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/holidays?source=ontario", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.UploadHolidays(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var found bool
+	for _, holiday := range getHolidays(t, h, "2024").Holidays {
+		if holiday.Date == "2024-08-05" && holiday.Name == "Civic Holiday" && holiday.Source == "ontario" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the uploaded Civic Holiday to be listed")
+	}
+}
+
+func TestUploadHolidays_Invalid(t *testing.T) {
+	h, _ := setupHolidayTestDB(t)
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"missing source", "format=csv", "2024-08-05,Civic Holiday\n"},
+		{"region source", "source=ca&format=csv", "2024-08-05,Civic Holiday\n"},
+		{"unknown format", "source=bank", "2024-08-05,Civic Holiday\n"},
+		{"unsupported format", "source=bank&format=xlsx", "2024-08-05,Civic Holiday\n"},
+		{"bad date", "source=bank&format=csv", "August 5,Civic Holiday\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := uploadHolidays(t, h, tt.query, tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestUploadHolidays_NotConfigured(t *testing.T) {
+	h, _ := newMemHandler()
+
+	if w := uploadHolidays(t, h, "source=bank&format=csv", "2024-08-05,Civic Holiday\n"); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d", w.Code)
+	}
+
+	// The built-in holidays are still listed
+	if list := getHolidays(t, h, "2025"); len(list.Holidays) == 0 || list.Year != 2025 {
+		t.Errorf("Expected built-in holidays for 2025, got %+v", list)
+	}
+}
+
+func TestDeleteHolidays(t *testing.T) {
+	h, _ := setupHolidayTestDB(t)
+
+	uploadHolidays(t, h, "source=bank&format=csv", "2024-08-05,Civic Holiday\n2024-12-24,Bank closure\n")
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/holidays?source=bank", nil)
+	w := httptest.NewRecorder()
+	h.DeleteHolidays(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp map[string]int
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["deleted"] != 2 {
+		t.Errorf("Expected 2 deleted, got %v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	h.DeleteHolidays(w, httptest.NewRequest(http.MethodDelete, "/api/v1/holidays?source=bank", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a source with no holidays, got %d", w.Code)
+	}
+}
+
+func TestGetHolidays_Calendar(t *testing.T) {
+	h := New(nil, nil, nil, nil)
+	if list := getHolidays(t, h, "2024"); len(list.Regions) != 1 || list.Regions[0] != holidays.RegionCA {
+		t.Errorf("Expected the default regions [CA], got %v", list.Regions)
+	}
+
+	h = New(nil, nil, nil, nil, WithCalendar(holidays.NewCalendar([]string{holidays.RegionUS}, nil)))
+	list := getHolidays(t, h, "2024")
+	if len(list.Regions) != 1 || list.Regions[0] != holidays.RegionUS {
+		t.Errorf("Expected the calendar's regions [US], got %v", list.Regions)
+	}
+	for _, holiday := range list.Holidays {
+		if holiday.Source != holidays.RegionUS {
+			t.Errorf("Expected only US holidays, got %+v", holiday)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ofx"
+)
+
+// maxStatementUpload limits the size of an uploaded statement file
+const maxStatementUpload = 10 << 20
+
+// ImportResponse reports what an import did, or would do on a dry run
+type ImportResponse struct {
+	DryRun  bool              `json:"dry_run"`
+	Results []importer.Result `json:"results"`
+}
+
+// ImportOFX creates or updates statements from an OFX or QFX file, matching
+// accounts to cards by their last four digits. The file is either the
+// request body or the "file" field of a multipart form. With dry_run=true
+// nothing is stored and the response previews the changes.
+func (h *Handler) ImportOFX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dryRun, ok := parseBoolQuery(w, r, "dry_run")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxStatementUpload)
+	body, _, _, err := readUpload(r)
+	if err != nil {
+		log.Printf("Error reading OFX upload: %v", err)
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+
+	parsed, err := ofx.Parse(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list := make([]importer.Statement, 0, len(parsed))
+	for _, stmt := range parsed {
+		list = append(list, importer.Statement{
+			LastFour:       stmt.LastFour,
+			StatementDate:  stmt.StatementDate,
+			DueDate:        stmt.DueDate,
+			Amount:         stmt.ClosingBalance,
+			MinimumPayment: stmt.MinimumPayment,
+		})
+	}
+
+	h.importStatements(w, r, list, dryRun)
+}
+
+// importStatements stores list, follows up on the statements entered or
+// updated and writes the results
+func (h *Handler) importStatements(w http.ResponseWriter, r *http.Request, list []importer.Statement, dryRun bool) {
+	results, err := importer.New(h.cards, h.statements, h.clock).Import(r.Context(), list, dryRun)
+	if err != nil {
+		log.Printf("Error importing statements: %v", err)
+		http.Error(w, "Failed to import statements", http.StatusInternalServerError)
+		return
+	}
+
+	for _, result := range results {
+		h.followup.Imported(r.Context(), result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ImportResponse{DryRun: dryRun, Results: results})
+}
+
+// parseBoolQuery reads an optional boolean query parameter, writing an
+// error if it isn't true or false
+func parseBoolQuery(w http.ResponseWriter, r *http.Request, name string) (bool, bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return false, true
+	}
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		http.Error(w, name+" must be true or false", http.StatusBadRequest)
+		return false, false
+	}
+	return value, true
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+)
+
+const testOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<CREDITCARDMSGSRSV1>
+<CCSTMTTRNRS>
+<CCSTMTRS>
+<CCACCTFROM>
+<ACCTID>XXXXXXXXXXXX1234
+</CCACCTFROM>
+<LEDGERBAL>
+<BALAMT>-1250.75
+<DTASOF>20241110
+</LEDGERBAL>
+</CCSTMTRS>
+</CCSTMTTRNRS>
+</CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func importOFX(t *testing.T, h *Handler, query string, body string) ImportResponse {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, _ := form.CreateFormFile("file", "statement.qfx")
+	part.Write([]byte(body))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import/ofx"+query, &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.ImportOFX(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp ImportResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestImportOFX(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	if w := createCard(t, h, `{"name": "Visa", "last_four": "1234", "statement_date": "2024-11-10", "due_date": "2024-12-05"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create card: %s", w.Body.String())
+	}
+
+	preview := importOFX(t, h, "?dry_run=true", testOFX)
+	if !preview.DryRun || len(preview.Results) != 1 || preview.Results[0].Action != importer.ActionCreate {
+		t.Fatalf("Expected a create preview, got %+v", preview)
+	}
+	stmts, _ := h.statements.List(t.Context())
+	if len(stmts) != 0 {
+		t.Fatalf("Expected the dry run to store nothing, got %d statements", len(stmts))
+	}
+
+	resp := importOFX(t, h, "", testOFX)
+	result := resp.Results[0]
+	if result.Action != importer.ActionCreate || result.StatementID == 0 {
+		t.Fatalf("Expected a created statement, got %+v", result)
+	}
+	if result.DueDate != "2024-12-05" || result.Amount.String() != "1250.75" {
+		t.Errorf("Expected 1250.75 due 2024-12-05, got %s due %s", result.Amount, result.DueDate)
+	}
+
+	resp = importOFX(t, h, "", testOFX)
+	if resp.Results[0].Action != importer.ActionUnchanged {
+		t.Errorf("Expected re-import to be unchanged, got %s", resp.Results[0].Action)
+	}
+}
+
+func TestImportOFX_Invalid(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"not ofx", "", "hello"},
+		{"bad dry_run", "?dry_run=maybe", testOFX},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/import/ofx"+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.ImportOFX(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/planner"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// paydays loads the income schedules for the payday payment policy
+func (h *Handler) paydays(ctx context.Context, cal *holidays.Calendar) (recommend.Paydays, error) {
+	// Pass a nil interface rather than a typed nil repository
+	if h.income == nil {
+		return nil, nil
+	}
+	return planner.LoadPaydays(ctx, h.income, cal)
+}
+
+// GetIncomeSchedules returns every payday schedule
+func (h *Handler) GetIncomeSchedules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	schedules := []models.IncomeSchedule{}
+	if h.income != nil {
+		var err error
+		schedules, err = h.income.List(r.Context())
+		if err != nil {
+			log.Printf("Error querying income schedules: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// CreateIncomeSchedule adds a payday schedule
+func (h *Handler) CreateIncomeSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.income == nil {
+		http.Error(w, "Income schedules are not available", http.StatusNotImplemented)
+		return
+	}
+
+	var schedule models.IncomeSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		log.Printf("Error decoding income schedule: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	if err := schedule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := h.clock.Now()
+	schedule.ID = 0
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	if err := h.income.Create(r.Context(), &schedule); err != nil {
+		log.Printf("Error creating income schedule: %v", err)
+		http.Error(w, "Failed to create income schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// DeleteIncomeSchedule removes a payday schedule
+func (h *Handler) DeleteIncomeSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.income == nil {
+		http.Error(w, "Income schedules are not available", http.StatusNotImplemented)
+		return
+	}
+
+	// Extract ID from URL path (e.g., /api/v1/income/1)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		http.Error(w, "Invalid income schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.income.Delete(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Income schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting income schedule %d: %v", id, err)
+		http.Error(w, "Failed to delete income schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Income schedule deleted successfully"})
+}
+
+// GetPlan assigns every unpaid statement to the payday that funds it,
+// starting from the cash on hand given by the cash query parameter
+func (h *Handler) GetPlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var cash models.Money
+	if s := r.URL.Query().Get("cash"); s != "" {
+		var err error
+		cash, err = models.ParseMoney(s)
+		if err != nil {
+			http.Error(w, "cash must be an amount such as 1250.75", http.StatusBadRequest)
+			return
+		}
+	}
+
+	cards, err := h.cards.List(r.Context())
+	if err != nil {
+		log.Printf("Error querying credit cards: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	statements, err := h.statements.List(r.Context())
+	if err != nil {
+		log.Printf("Error querying statements: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	cal, err := h.loadCalendar(r.Context())
+	if err != nil {
+		log.Printf("Error loading holidays: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var schedules []models.IncomeSchedule
+	if h.income != nil {
+		schedules, err = h.income.List(r.Context())
+		if err != nil {
+			log.Printf("Error querying income schedules: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	plan := planner.Build(cards, statements, planner.NewPaydays(schedules, cal), cal, clock.Today(h.clock), cash)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(plan)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/planner"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// setupIncomeTestDB creates a test database with income schedules enabled
+func setupIncomeTestDB(t *testing.T) (*Handler, *sql.DB) {
+	h, db := setupTestDB(t)
+	h.income = repository.NewSQLiteIncomeRepository(db)
+	return h, db
+}
+
+func createIncomeSchedule(t *testing.T, h *Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/income", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	h.CreateIncomeSchedule(w, req)
+	return w
+}
+
+func getPlan(t *testing.T, h *Handler, query string) planner.Plan {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/plan?"+query, nil)
+	w := httptest.NewRecorder()
+	h.GetPlan(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var plan planner.Plan
+	if err := json.NewDecoder(w.Body).Decode(&plan); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return plan
+}
+
+func TestIncomeSchedules(t *testing.T) {
+	h, _ := setupIncomeTestDB(t)
+
+	w := createIncomeSchedule(t, h, `{"name": "Salary", "frequency": "semi_monthly", "anchor_date": "2024-11-15", "second_day": 31, "amount": "2400.00"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.IncomeSchedule
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.ID == 0 || created.SecondDay != 31 || created.Amount.String() != "2400.00" {
+		t.Errorf("Unexpected schedule %+v", created)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetIncomeSchedules(w, httptest.NewRequest(http.MethodGet, "/api/v1/income", nil))
+	var list []models.IncomeSchedule
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 || list[0].Name != "Salary" {
+		t.Errorf("Expected the salary schedule, got %+v", list)
+	}
+
+	path := fmt.Sprintf("/api/v1/income/%d", created.ID)
+	w = httptest.NewRecorder()
+	h.DeleteIncomeSchedule(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.DeleteIncomeSchedule(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting twice, got %d", w.Code)
+	}
+}
+
+func TestCreateIncomeSchedule_Invalid(t *testing.T) {
+	h, _ := setupIncomeTestDB(t)
+
+	for _, body := range []string{
+		`{"frequency": "monthly", "anchor_date": "2024-11-15", "amount": "2400.00"}`,
+		`{"name": "Salary", "frequency": "weekly", "anchor_date": "2024-11-15", "amount": "2400.00"}`,
+		`{"name": "Salary", "frequency": "semi_monthly", "anchor_date": "2024-11-15", "amount": "2400.00"}`,
+		`{"name": "Salary", "frequency": "monthly", "anchor_date": "2024-11-15"}`,
+		`not json`,
+	} {
+		if w := createIncomeSchedule(t, h, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	// Without a repository schedules can't be stored
+	mem, _ := newMemHandler()
+	w := createIncomeSchedule(t, mem, `{"name": "Salary", "frequency": "monthly", "anchor_date": "2024-11-15", "amount": "2400.00"}`)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d", w.Code)
+	}
+}
+
+func TestGetPlan(t *testing.T) {
+	h, db := setupIncomeTestDB(t)
+	h.clock = clock.NewFixed(time.Date(2024, time.November, 4, 9, 0, 0, 0, time.UTC))
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due) VALUES (1, 'Visa', '1234', 28, 25), (2, 'Amex', '5678', 25, 25)`); err != nil {
+		t.Fatalf("Failed to insert cards: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO statements (id, card_id, statement_date, due_date, amount_cents, status) VALUES
+		(1, 1, '2024-10-28', '2024-11-22', 150000, 'pending'),
+		(2, 2, '2024-10-25', '2024-11-19', 90000, 'pending')
+	`); err != nil {
+		t.Fatalf("Failed to insert statements: %v", err)
+	}
+	createIncomeSchedule(t, h, `{"name": "Salary", "frequency": "biweekly", "anchor_date": "2024-11-08", "amount": "2000.00"}`)
+
+	plan := getPlan(t, h, "cash=100.00")
+	if len(plan.Periods) != 2 {
+		t.Fatalf("Expected cash on hand and one payday, got %+v", plan.Periods)
+	}
+
+	// Both statements must be paid by the 12th or 15th, so they land on the
+	// payday of the 8th, which can't cover them
+	payday := plan.Periods[1]
+	if payday.Date != "2024-11-08" || len(payday.Payments) != 2 || payday.TotalDue.String() != "2400.00" {
+		t.Errorf("Expected both statements on 2024-11-08, got %+v", payday)
+	}
+	if !payday.Shortfall || payday.RemainingCash.String() != "-300.00" || plan.Shortfalls != 1 {
+		t.Errorf("Expected a shortfall leaving -300.00, got %+v", payday)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/plan?cash=lots", nil)
+	w := httptest.NewRecorder()
+	h.GetPlan(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid cash, got %d", w.Code)
+	}
+}
+
+func TestGetRecommendation_PaydayPolicy(t *testing.T) {
+	h, db := setupIncomeTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due, payment_policy) VALUES (1, 'Visa', '1234', 10, 25, 'payday')`); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO statements (id, card_id, statement_date, due_date, amount_cents, status) VALUES (1, 1, '2024-11-10', '2024-12-05', 10000, 'pending')`); err != nil {
+		t.Fatalf("Failed to insert statement: %v", err)
+	}
+
+	// Without a schedule the lead time is used
+	if rec := getRecommendation(t, h, 1); rec.PaymentDate != "2024-11-28" {
+		t.Errorf("Expected the lead time date 2024-11-28, got %+v", rec)
+	}
+
+	createIncomeSchedule(t, h, `{"name": "Salary", "frequency": "biweekly", "anchor_date": "2024-11-08", "amount": "2000.00"}`)
+	if rec := getRecommendation(t, h, 1); rec.PaymentDate != "2024-11-22" || rec.Explanation != "Pay on the first payday after the statement date." {
+		t.Errorf("Expected payday 2024-11-22, got %+v", rec)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// RecommendationResponse is a statement's recommended payment date, with
+// warnings about the scheduled date if it has one
+type RecommendationResponse struct {
+	recommend.Recommendation
+	ScheduledPaymentDate *string  `json:"scheduled_payment_date,omitempty"`
+	Warnings             []string `json:"warnings"`
+}
+
+// recommendation works out when stmt should be paid following its card's
+// payment policy
+func (h *Handler) recommendation(ctx context.Context, stmt models.Statement) (recommend.Recommendation, error) {
+	card, err := h.cards.Get(ctx, stmt.CardID)
+	if err != nil {
+		return recommend.Recommendation{}, fmt.Errorf("failed to load card %d: %w", stmt.CardID, err)
+	}
+	cal, err := h.loadCalendar(ctx)
+	if err != nil {
+		return recommend.Recommendation{}, err
+	}
+	paydays, err := h.paydays(ctx, cal)
+	if err != nil {
+		return recommend.Recommendation{}, err
+	}
+	return recommend.ForStatement(card, stmt, cal, paydays)
+}
+
+// GetRecommendation returns the recommended payment date for a statement
+// following its card's payment policy, with an explanation of how it was
+// chosen and warnings about the scheduled date
+func (h *Handler) GetRecommendation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract ID from URL path (e.g., /api/v1/statements/1/recommendation)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 6 {
+		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		http.Error(w, "Invalid statement ID", http.StatusBadRequest)
+		return
+	}
+
+	stmt, err := h.statements.Get(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Statement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	rec, err := h.recommendation(r.Context(), stmt)
+	if err != nil {
+		log.Printf("Error recommending payment date for statement %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := RecommendationResponse{
+		Recommendation:       rec,
+		ScheduledPaymentDate: stmt.ScheduledPaymentDate,
+		Warnings:             []string{},
+	}
+	if stmt.ScheduledPaymentDate != nil {
+		response.Warnings = rec.Check(*stmt.ScheduledPaymentDate)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func getRecommendation(t *testing.T, h *Handler, id int) RecommendationResponse {
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/statements/%d/recommendation", id), nil)
+	w := httptest.NewRecorder()
+	h.GetRecommendation(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp RecommendationResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestGetRecommendation(t *testing.T) {
+	h, db := setupHolidayTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due) VALUES (1, 'Visa', '1234', 7, 25)`); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO statements (id, card_id, statement_date, due_date, amount_cents, status) VALUES (1, 1, '2024-12-07', '2025-01-01', 10000, 'pending')`); err != nil {
+		t.Fatalf("Failed to insert statement: %v", err)
+	}
+
+	// Christmas and Boxing Day don't count towards the 5 business days, and
+	// New Year's Day means the payment can't land on the due date itself
+	rec := getRecommendation(t, h, 1)
+	if rec.Policy != "lead_time" || rec.PaymentDate != "2024-12-23" || rec.BaseDate != "2024-12-25" || !rec.Adjusted {
+		t.Errorf("Unexpected recommendation %+v", rec)
+	}
+	if rec.LatestPaymentDate != "2024-12-31" {
+		t.Errorf("Expected latest payment date 2024-12-31, got %s", rec.LatestPaymentDate)
+	}
+	if !strings.Contains(rec.Explanation, "2024-12-25 (Christmas Day)") {
+		t.Errorf("Expected the explanation to mention Christmas, got %q", rec.Explanation)
+	}
+	if rec.Warnings == nil || len(rec.Warnings) != 0 {
+		t.Errorf("Expected no warnings for an unscheduled statement, got %v", rec.Warnings)
+	}
+
+	// An uploaded bank closure moves it back again
+	uploadHolidays(t, h, "source=bank&format=csv", "2024-12-23,Bank closure\n")
+	if rec := getRecommendation(t, h, 1); rec.PaymentDate != "2024-12-20" || len(rec.Skipped) != 3 {
+		t.Errorf("Expected payment on 2024-12-20 skipping 3 days, got %+v", rec)
+	}
+}
+
+func TestGetRecommendation_CardPolicy(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due, payment_policy) VALUES (1, 'Visa', '1234', 12, 21, 'statement_day')`); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO statements (id, card_id, statement_date, due_date, amount_cents, status, scheduled_payment_date) VALUES (1, 1, '2024-11-12', '2024-12-03', 10000, 'pending', '2024-11-28')`); err != nil {
+		t.Fatalf("Failed to insert statement: %v", err)
+	}
+
+	rec := getRecommendation(t, h, 1)
+	if rec.Policy != "statement_day" || rec.PaymentDate != "2024-11-12" || rec.SafeBy != "2024-11-26" {
+		t.Errorf("Unexpected recommendation %+v", rec)
+	}
+	if rec.ScheduledPaymentDate == nil || *rec.ScheduledPaymentDate != "2024-11-28" {
+		t.Errorf("Expected scheduled date 2024-11-28, got %v", rec.ScheduledPaymentDate)
+	}
+	if len(rec.Warnings) != 1 || !strings.Contains(rec.Warnings[0], "after 2024-11-26") {
+		t.Errorf("Expected a warning about the scheduled date, got %v", rec.Warnings)
+	}
+}
+
+func TestGetRecommendation_NotFound(t *testing.T) {
+	h, _ := newMemHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/statements/99/recommendation", nil)
+	w := httptest.NewRecorder()
+	h.GetRecommendation(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestSchedulePayment_Warnings(t *testing.T) {
+	h, db := setupTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO credit_cards (id, name, last_four, statement_day, days_until_due, payment_lead_days) VALUES (1, 'Visa', '1234', 15, 21, 3)`); err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO statements (id, card_id, statement_date, due_date, amount_cents, status) VALUES (1, 1, '2024-11-15', '2024-12-06', 10000, 'pending')`); err != nil {
+		t.Fatalf("Failed to insert statement: %v", err)
+	}
+
+	tests := []struct {
+		scheduled string
+		warning   string
+	}{
+		{"2024-12-03", ""},
+		{"2024-12-04", "leaving less than 3 business days"},
+		{"2024-12-09", "the payment will be late"},
+	}
+
+	for _, tt := range tests {
+		body := bytes.NewBufferString(fmt.Sprintf(`{"scheduled_payment_date": %q}`, tt.scheduled))
+		w := httptest.NewRecorder()
+		h.SchedulePayment(w, httptest.NewRequest(http.MethodPut, "/api/v1/statements/1/schedule", body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp struct {
+			RecommendedPaymentDate string   `json:"recommended_payment_date"`
+			Warnings               []string `json:"warnings"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp.RecommendedPaymentDate != "2024-12-03" {
+			t.Errorf("%s: expected recommended date 2024-12-03, got %s", tt.scheduled, resp.RecommendedPaymentDate)
+		}
+		switch {
+		case tt.warning == "" && len(resp.Warnings) != 0:
+			t.Errorf("%s: expected no warnings, got %v", tt.scheduled, resp.Warnings)
+		case tt.warning != "" && (len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], tt.warning)):
+			t.Errorf("%s: expected a warning containing %q, got %v", tt.scheduled, tt.warning, resp.Warnings)
+		}
+	}
+
+	// Scheduling an unknown statement is still a 404
+	w := httptest.NewRecorder()
+	h.SchedulePayment(w, httptest.NewRequest(http.MethodPut, "/api/v1/statements/99/schedule", bytes.NewBufferString(`{"scheduled_payment_date": "2024-12-03"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
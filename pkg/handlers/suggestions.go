@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ingest"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// AcceptSuggestionResponse reports the statement an accepted suggestion was
+// stored as
+type AcceptSuggestionResponse struct {
+	Suggestion models.Suggestion `json:"suggestion"`
+	Result     importer.Result   `json:"result"`
+}
+
+// ingestAvailable writes an error and returns false if statement emails
+// aren't read
+func (h *Handler) ingestAvailable(w http.ResponseWriter) bool {
+	if h.ingest == nil {
+		http.Error(w, "Email ingestion is not available", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+// suggestionID parses the ID in /api/v1/suggestions/{id}[/{action}]
+func suggestionID(path string) (int, error) {
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(pathParts) < 5 || len(pathParts) > 6 {
+		return 0, errors.New("Invalid URL")
+	}
+	id, err := strconv.Atoi(pathParts[4])
+	if err != nil {
+		return 0, errors.New("Invalid suggestion ID")
+	}
+	return id, nil
+}
+
+// ImportEmail reads an issuer's statement email, given as an .eml file in
+// the request body or the "file" field of a multipart form. The statement
+// fills in the matching card's expected statement or is queued as a
+// suggestion for review. It returns 201 when a suggestion was recorded and
+// 200 when the email was ignored or read before.
+func (h *Handler) ImportEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.ingestAvailable(w) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxStatementUpload)
+	body, _, _, err := readUpload(r)
+	if err != nil {
+		log.Printf("Error reading email upload: %v", err)
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+
+	outcome, err := h.ingest.Ingest(r.Context(), body, ingest.SourceUpload)
+	if errors.Is(err, ingest.ErrNotEmail) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("Error reading statement email: %v", err)
+		http.Error(w, "Failed to read email", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if outcome.Suggestion != nil && !outcome.Duplicate {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(outcome)
+}
+
+// GetSuggestions lists the suggestions read from statement emails, newest
+// first, optionally only those with the status given as ?status=
+func (h *Handler) GetSuggestions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !models.IsSuggestionStatus(status) {
+		http.Error(w, "status must be one of "+strings.Join(models.SuggestionStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+
+	suggestions := []models.Suggestion{}
+	if h.ingest != nil {
+		var err error
+		suggestions, err = h.ingest.Suggestions(r.Context(), status)
+		if err != nil {
+			log.Printf("Error querying suggestions: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// GetSuggestion returns a single suggestion
+func (h *Handler) GetSuggestion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.ingestAvailable(w) {
+		return
+	}
+
+	id, err := suggestionID(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	suggestion, err := h.ingest.Suggestion(r.Context(), id)
+	if err == repository.ErrNotFound {
+		http.Error(w, "Suggestion not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error querying suggestion %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(suggestion)
+}
+
+// AcceptSuggestion stores a pending suggestion as a statement, with any
+// corrections in the request. A statement that can't be stored returns 422
+// with the reason and leaves the suggestion pending; a suggestion that was
+// already accepted or dismissed returns 409.
+func (h *Handler) AcceptSuggestion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.ingestAvailable(w) {
+		return
+	}
+
+	id, err := suggestionID(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req ingest.Correction
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	suggestion, result, err := h.ingest.Accept(r.Context(), id, req)
+	if !h.suggestionResolved(w, id, err) {
+		return
+	}
+
+	status := http.StatusOK
+	if result.Action == importer.ActionSkip {
+		status = http.StatusUnprocessableEntity
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(AcceptSuggestionResponse{Suggestion: suggestion, Result: result})
+}
+
+// DismissSuggestion marks a pending suggestion as not to be stored
+func (h *Handler) DismissSuggestion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.ingestAvailable(w) {
+		return
+	}
+
+	id, err := suggestionID(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	suggestion, err := h.ingest.Dismiss(r.Context(), id)
+	if !h.suggestionResolved(w, id, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(suggestion)
+}
+
+// suggestionResolved writes the error from accepting or dismissing a
+// suggestion, returning false if there was one
+func (h *Handler) suggestionResolved(w http.ResponseWriter, id int, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "Suggestion not found", http.StatusNotFound)
+	case errors.Is(err, ingest.ErrNotPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error resolving suggestion %d: %v", id, err)
+		http.Error(w, "Failed to update suggestion", http.StatusInternalServerError)
+	}
+	return false
+}
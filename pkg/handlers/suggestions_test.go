@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ingest"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+const tdNoticeEmail = "From: TD Canada Trust <noreply@td.com>\r\n" +
+	"Subject: Your TD credit card statement is ready\r\n" +
+	"Date: Sat, 16 Nov 2024 08:00:00 -0500\r\n" +
+	"Message-ID: <td-2024-11@td.com>\r\n" +
+	"\r\n" +
+	"Your TD Aeroplan Visa Infinite Card ending in 9876 statement is ready.\r\n" +
+	"Balance: $892.50\r\n" +
+	"Minimum Payment: $10.00\r\n" +
+	"Payment Due Date: December 10, 2024\r\n"
+
+const amexNoticeEmail = "From: American Express <AmericanExpress@welcome.aexp.com>\r\n" +
+	"Subject: Your Amex Cobalt Card statement is ready\r\n" +
+	"Message-ID: <amex-2024-11@aexp.com>\r\n" +
+	"\r\n" +
+	"Account Ending: 71234\r\n" +
+	"Closing Date: 11/28/24\r\n" +
+	"Statement Balance: $3,421.89\r\n" +
+	"Payment Due Date: 12/23/24\r\n"
+
+// setupIngest creates a handler reading statement emails, with a TD card
+// ending in 9876 that has an expected statement on 2024-11-15
+func setupIngest(t *testing.T) (*Handler, *sql.DB) {
+	h, db := setupTestDB(t)
+	h.clock = clock.NewFixed(time.Date(2024, 11, 30, 12, 0, 0, 0, time.UTC))
+	WithIngest(ingest.New(h.cards, h.statements, repository.NewSQLiteSuggestionRepository(db), h.clock))(h)
+
+	ctx := context.Background()
+	card := models.CreditCard{Name: "TD Aeroplan Visa", LastFour: "9876", StatementDay: 15, DaysUntilDue: 25}
+	if err := h.cards.Create(ctx, &card); err != nil {
+		t.Fatalf("Failed to create card: %v", err)
+	}
+	placeholder := models.Statement{CardID: card.ID, StatementDate: "2024-11-15", DueDate: "2024-12-10", Status: models.StatusExpected}
+	if err := h.statements.Create(ctx, &placeholder); err != nil {
+		t.Fatalf("Failed to create placeholder: %v", err)
+	}
+	return h, db
+}
+
+func uploadEmail(h *Handler, data string) (*httptest.ResponseRecorder, ingest.Outcome) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, _ := form.CreateFormFile("file", "statement.eml")
+	part.Write([]byte(data))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import/eml", &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.ImportEmail(w, req)
+
+	var outcome ingest.Outcome
+	json.Unmarshal(w.Body.Bytes(), &outcome)
+	return w, outcome
+}
+
+func resolveSuggestion(h *Handler, id int, action, body string) (*httptest.ResponseRecorder, AcceptSuggestionResponse) {
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/suggestions/%d/%s", id, action), strings.NewReader(body))
+	w := httptest.NewRecorder()
+	if action == "accept" {
+		h.AcceptSuggestion(w, req)
+	} else {
+		h.DismissSuggestion(w, req)
+	}
+
+	var resp AcceptSuggestionResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestImportEmail(t *testing.T) {
+	h, _ := setupIngest(t)
+
+	w, outcome := uploadEmail(h, tdNoticeEmail)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	s := outcome.Suggestion
+	if s == nil || s.Status != models.SuggestionFilled || s.StatementID == nil || s.Source != ingest.SourceUpload {
+		t.Fatalf("Expected the expected statement to be filled, got %s", w.Body.String())
+	}
+	stmt, err := h.statements.Get(context.Background(), *s.StatementID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stmt.Status != models.StatusPending || stmt.Amount.String() != "892.50" {
+		t.Errorf("Unexpected statement: %+v", stmt)
+	}
+
+	// The same email is only read once
+	w, outcome = uploadEmail(h, tdNoticeEmail)
+	if w.Code != http.StatusOK || !outcome.Duplicate || outcome.Suggestion.ID != s.ID {
+		t.Errorf("Expected the earlier suggestion with status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w, _ = uploadEmail(h, "%PDF-1.4\n%%EOF\n")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a file that isn't an email, got %d", w.Code)
+	}
+	w, _ = uploadEmail(h, "From: noreply@td.com\r\nContent-Transfer-Encoding: base64\r\n\r\n!!not base64!!\r\n")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an email whose body can't be decoded, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/suggestions?status=filled", nil)
+	rec := httptest.NewRecorder()
+	h.GetSuggestions(rec, req)
+	var suggestions []models.Suggestion
+	json.Unmarshal(rec.Body.Bytes(), &suggestions)
+	if rec.Code != http.StatusOK || len(suggestions) != 1 || suggestions[0].ID != s.ID {
+		t.Errorf("Expected the filled suggestion, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/suggestions?status=done", nil)
+	rec = httptest.NewRecorder()
+	h.GetSuggestions(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown status, got %d", rec.Code)
+	}
+}
+
+func TestAcceptSuggestion(t *testing.T) {
+	h, _ := setupIngest(t)
+
+	// There is no Amex card yet, so the email waits for review
+	w, outcome := uploadEmail(h, amexNoticeEmail)
+	if w.Code != http.StatusCreated || outcome.Suggestion == nil || outcome.Suggestion.Status != models.SuggestionPending {
+		t.Fatalf("Expected a pending suggestion, got %d: %s", w.Code, w.Body.String())
+	}
+	id := outcome.Suggestion.ID
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/suggestions/%d", id), nil)
+	rec := httptest.NewRecorder()
+	h.GetSuggestion(rec, req)
+	var got models.Suggestion
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if rec.Code != http.StatusOK || got.Amount.String() != "3421.89" || got.Reason == "" {
+		t.Errorf("Expected the suggestion with a reason, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	w, resp := resolveSuggestion(h, id, "accept", "")
+	if w.Code != http.StatusUnprocessableEntity || resp.Result.Action != importer.ActionSkip ||
+		resp.Suggestion.Status != models.SuggestionPending {
+		t.Fatalf("Expected status 422 without a card, got %d: %s", w.Code, w.Body.String())
+	}
+
+	card := models.CreditCard{Name: "Amex Cobalt", LastFour: "5555", StatementDay: 28, DaysUntilDue: 25}
+	if err := h.cards.Create(context.Background(), &card); err != nil {
+		t.Fatalf("Failed to create card: %v", err)
+	}
+	w, resp = resolveSuggestion(h, id, "accept", fmt.Sprintf(`{"card_id": %d, "minimum_payment": "35.00"}`, card.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Suggestion.Status != models.SuggestionAccepted || resp.Suggestion.StatementID == nil ||
+		*resp.Suggestion.StatementID != resp.Result.StatementID || resp.Result.Action != importer.ActionCreate {
+		t.Errorf("Expected the suggestion to be accepted as a new statement, got %s", w.Body.String())
+	}
+	stmt, err := h.statements.Get(context.Background(), resp.Result.StatementID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stmt.CardID != card.ID || stmt.StatementDate != "2024-11-28" || stmt.MinimumPayment.String() != "35.00" {
+		t.Errorf("Unexpected statement: %+v", stmt)
+	}
+
+	if w, _ := resolveSuggestion(h, id, "dismiss", ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for an accepted suggestion, got %d", w.Code)
+	}
+	if w, _ := resolveSuggestion(h, 999, "accept", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestSuggestions_Unavailable(t *testing.T) {
+	h, _ := setupTestDB(t)
+
+	if w, _ := uploadEmail(h, tdNoticeEmail); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/suggestions", nil)
+	w := httptest.NewRecorder()
+	h.GetSuggestions(w, req)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("Expected an empty list, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,216 @@
+package holidays
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// maxClosedRun bounds the search for a business day so a bad holiday list
+// can't loop forever
+const maxClosedRun = 366
+
+// Calendar answers whether payments are processed on a day. A nil Calendar
+// only treats weekends as closed.
+type Calendar struct {
+	regions []string
+	custom  map[string][]Holiday
+
+	mu    sync.Mutex
+	years map[int]map[string][]Holiday
+}
+
+// Closure explains why a day isn't a business day
+type Closure struct {
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+}
+
+// Store lists uploaded holidays
+type Store interface {
+	List(ctx context.Context) ([]Holiday, error)
+}
+
+// NewCalendar creates a calendar with the federal holidays of regions and
+// the given uploaded holidays
+func NewCalendar(regions []string, custom []Holiday) *Calendar {
+	c := &Calendar{
+		regions: regions,
+		custom:  map[string][]Holiday{},
+		years:   map[int]map[string][]Holiday{},
+	}
+	for _, h := range custom {
+		c.custom[h.Date] = append(c.custom[h.Date], h)
+	}
+	return c
+}
+
+// Load creates a calendar for the regions of c and the holidays in store. A
+// nil store adds no uploaded holidays.
+func (c *Calendar) Load(ctx context.Context, store Store) (*Calendar, error) {
+	var custom []Holiday
+	if store != nil {
+		var err error
+		custom, err = store.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load holidays: %w", err)
+		}
+	}
+	return NewCalendar(c.Regions(), custom), nil
+}
+
+// Regions returns the regions whose federal holidays are closed
+func (c *Calendar) Regions() []string {
+	if c == nil {
+		return []string{}
+	}
+	return append([]string{}, c.regions...)
+}
+
+// federal returns the built-in holidays of year keyed by date
+func (c *Calendar) federal(year int) map[string][]Holiday {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if days, ok := c.years[year]; ok {
+		return days
+	}
+	days := map[string][]Holiday{}
+	for _, region := range c.regions {
+		holidays, err := Federal(region, year)
+		if err != nil {
+			continue
+		}
+		for _, h := range holidays {
+			days[h.Date] = append(days[h.Date], h)
+		}
+	}
+	c.years[year] = days
+	return days
+}
+
+// On returns the holidays on date
+func (c *Calendar) On(date time.Time) []Holiday {
+	if c == nil {
+		return nil
+	}
+	key := date.Format(models.DateFormat)
+	holidays := append([]Holiday(nil), c.federal(date.Year())[key]...)
+	return append(holidays, c.custom[key]...)
+}
+
+// Year returns every holiday in year ordered by date
+func (c *Calendar) Year(year int) []Holiday {
+	holidays := []Holiday{}
+	if c == nil {
+		return holidays
+	}
+	for _, days := range c.federal(year) {
+		holidays = append(holidays, days...)
+	}
+	prefix := fmt.Sprintf("%04d-", year)
+	for date, days := range c.custom {
+		if strings.HasPrefix(date, prefix) {
+			holidays = append(holidays, days...)
+		}
+	}
+	sort.Slice(holidays, func(i, j int) bool {
+		if holidays[i].Date != holidays[j].Date {
+			return holidays[i].Date < holidays[j].Date
+		}
+		if holidays[i].Source != holidays[j].Source {
+			return holidays[i].Source < holidays[j].Source
+		}
+		return holidays[i].Name < holidays[j].Name
+	})
+	return holidays
+}
+
+// ClosedReason returns why payments aren't processed on date, or "" if it is
+// a business day
+func (c *Calendar) ClosedReason(date time.Time) string {
+	if !models.IsWeekday(date) {
+		return date.Weekday().String()
+	}
+	holidays := c.On(date)
+	if len(holidays) == 0 {
+		return ""
+	}
+	names := []string{}
+	for _, h := range holidays {
+		if !contains(names, h.Name) {
+			names = append(names, h.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// IsBusinessDay reports whether payments are processed on date
+func (c *Calendar) IsBusinessDay(date time.Time) bool {
+	return c.ClosedReason(date) == ""
+}
+
+// PreviousBusinessDay returns the latest business day on or before date and
+// the closed days that were skipped to reach it, latest first
+func (c *Calendar) PreviousBusinessDay(date time.Time) (time.Time, []Closure) {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	closures := []Closure{}
+	for i := 0; i < maxClosedRun; i++ {
+		reason := c.ClosedReason(date)
+		if reason == "" {
+			break
+		}
+		closures = append(closures, Closure{Date: date.Format(models.DateFormat), Reason: reason})
+		date = date.AddDate(0, 0, -1)
+	}
+	return date, closures
+}
+
+// NextBusinessDay returns the earliest business day on or after date and the
+// closed days that were skipped to reach it, earliest first
+func (c *Calendar) NextBusinessDay(date time.Time) (time.Time, []Closure) {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	closures := []Closure{}
+	for i := 0; i < maxClosedRun; i++ {
+		reason := c.ClosedReason(date)
+		if reason == "" {
+			break
+		}
+		closures = append(closures, Closure{Date: date.Format(models.DateFormat), Reason: reason})
+		date = date.AddDate(0, 0, 1)
+	}
+	return date, closures
+}
+
+// BusinessDaysBefore counts back n business days from date, not counting
+// date itself, and returns the day reached with the closed days passed over,
+// latest first
+func (c *Calendar) BusinessDaysBefore(date time.Time, n int) (time.Time, []Closure) {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	closures := []Closure{}
+	for counted, closed := 0, 0; counted < n && closed < maxClosedRun; {
+		date = date.AddDate(0, 0, -1)
+		if reason := c.ClosedReason(date); reason != "" {
+			closures = append(closures, Closure{Date: date.Format(models.DateFormat), Reason: reason})
+			closed++
+			continue
+		}
+		counted++
+		closed = 0
+	}
+	return date, closures
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
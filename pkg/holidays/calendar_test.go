@@ -0,0 +1,174 @@
+package holidays
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCalendar_IsBusinessDay(t *testing.T) {
+	cal := NewCalendar([]string{RegionCA}, []Holiday{{Date: "2024-08-05", Name: "Civic Holiday", Source: "eq-bank"}})
+
+	tests := []struct {
+		date   string
+		reason string
+	}{
+		{"2024-12-24", ""},
+		{"2024-12-25", "Christmas Day"},
+		{"2024-12-28", "Saturday"},
+		{"2024-12-29", "Sunday"},
+		{"2024-08-05", "Civic Holiday"},
+		{"2024-07-04", ""}, // a US holiday only
+	}
+	for _, tt := range tests {
+		if got := cal.ClosedReason(mustDate(tt.date)); got != tt.reason {
+			t.Errorf("%s: expected reason %q, got %q", tt.date, tt.reason, got)
+		}
+		if got := cal.IsBusinessDay(mustDate(tt.date)); got != (tt.reason == "") {
+			t.Errorf("%s: expected business day %v, got %v", tt.date, tt.reason == "", got)
+		}
+	}
+}
+
+func TestCalendar_SharedHolidayNamedOnce(t *testing.T) {
+	cal := NewCalendar([]string{RegionCA, RegionUS}, nil)
+	if got := cal.ClosedReason(mustDate("2024-12-25")); got != "Christmas Day" {
+		t.Errorf("Expected Christmas Day once, got %q", got)
+	}
+	if got := len(cal.On(mustDate("2024-12-25"))); got != 2 {
+		t.Errorf("Expected Christmas in both regions, got %d holidays", got)
+	}
+}
+
+func TestCalendar_PreviousBusinessDay(t *testing.T) {
+	cal := NewCalendar([]string{RegionCA}, nil)
+
+	// Boxing Day 2022 is observed on Tuesday the 27th and Christmas on
+	// Monday the 26th, so the 27th rolls back over the long weekend
+	date, closures := cal.PreviousBusinessDay(mustDate("2022-12-27"))
+	if got := date.Format("2006-01-02"); got != "2022-12-23" {
+		t.Errorf("Expected 2022-12-23, got %s", got)
+	}
+	want := []Closure{
+		{"2022-12-27", "Boxing Day (observed)"},
+		{"2022-12-26", "Christmas Day (observed)"},
+		{"2022-12-25", "Sunday"},
+		{"2022-12-24", "Saturday"},
+	}
+	if len(closures) != len(want) {
+		t.Fatalf("Expected %d closures, got %+v", len(want), closures)
+	}
+	for i := range want {
+		if closures[i] != want[i] {
+			t.Errorf("Closure %d: expected %+v, got %+v", i, want[i], closures[i])
+		}
+	}
+
+	date, closures = cal.PreviousBusinessDay(mustDate("2024-12-24"))
+	if got := date.Format("2006-01-02"); got != "2024-12-24" || len(closures) != 0 {
+		t.Errorf("Expected a business day to stay put, got %s %+v", got, closures)
+	}
+}
+
+func TestCalendar_NextBusinessDay(t *testing.T) {
+	cal := NewCalendar([]string{RegionCA}, nil)
+
+	date, closures := cal.NextBusinessDay(mustDate("2022-12-24"))
+	if got := date.Format("2006-01-02"); got != "2022-12-28" {
+		t.Errorf("Expected 2022-12-28, got %s", got)
+	}
+	if len(closures) != 4 || closures[0].Date != "2022-12-24" || closures[3].Date != "2022-12-27" {
+		t.Errorf("Expected the long weekend earliest first, got %+v", closures)
+	}
+}
+
+func TestCalendar_BusinessDaysBefore(t *testing.T) {
+	cal := NewCalendar([]string{RegionCA}, nil)
+
+	tests := []struct {
+		date   string
+		n      int
+		want   string
+		closed int
+	}{
+		{"2024-12-06", 5, "2024-11-29", 2},
+		{"2024-12-08", 1, "2024-12-06", 1}, // the Sunday itself isn't counted
+		{"2025-01-01", 5, "2024-12-23", 4}, // Boxing Day, Christmas and a weekend
+		{"2024-12-10", 0, "2024-12-10", 0},
+	}
+	for _, tt := range tests {
+		date, closures := cal.BusinessDaysBefore(mustDate(tt.date), tt.n)
+		if got := date.Format("2006-01-02"); got != tt.want || len(closures) != tt.closed {
+			t.Errorf("%d business days before %s: expected %s passing %d closed days, got %s %+v", tt.n, tt.date, tt.want, tt.closed, got, closures)
+		}
+	}
+}
+
+func TestCalendar_Nil(t *testing.T) {
+	var cal *Calendar
+	if cal.IsBusinessDay(mustDate("2024-12-25")) != true {
+		t.Error("Expected a nil calendar to ignore holidays")
+	}
+	if cal.IsBusinessDay(mustDate("2024-12-28")) {
+		t.Error("Expected a nil calendar to close on weekends")
+	}
+	if len(cal.Year(2024)) != 0 {
+		t.Error("Expected no holidays from a nil calendar")
+	}
+}
+
+func TestCalendar_Year(t *testing.T) {
+	cal := NewCalendar([]string{RegionCA}, []Holiday{
+		{Date: "2024-08-05", Name: "Civic Holiday", Source: "eq-bank"},
+		{Date: "2025-08-04", Name: "Civic Holiday", Source: "eq-bank"},
+	})
+	holidays := cal.Year(2024)
+	if len(holidays) != 11 {
+		t.Fatalf("Expected 11 holidays in 2024, got %d", len(holidays))
+	}
+	for i := 1; i < len(holidays); i++ {
+		if holidays[i].Date < holidays[i-1].Date {
+			t.Errorf("Expected holidays ordered by date, got %s before %s", holidays[i-1].Date, holidays[i].Date)
+		}
+	}
+}
+
+type fakeStore struct {
+	holidays []Holiday
+	err      error
+}
+
+func (f fakeStore) List(ctx context.Context) ([]Holiday, error) {
+	return f.holidays, f.err
+}
+
+func TestLoad(t *testing.T) {
+	us := NewCalendar([]string{RegionUS}, nil)
+
+	cal, err := us.Load(context.Background(), fakeStore{holidays: []Holiday{{Date: "2024-08-05", Name: "Civic Holiday"}}})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cal.IsBusinessDay(mustDate("2024-07-04")) || cal.IsBusinessDay(mustDate("2024-08-05")) {
+		t.Error("Expected US and uploaded holidays to be closed")
+	}
+	if !cal.IsBusinessDay(mustDate("2024-07-01")) {
+		t.Error("Expected Canada Day to be a business day with only US holidays")
+	}
+
+	if regions := cal.Regions(); len(regions) != 1 || regions[0] != RegionUS {
+		t.Errorf("Expected the loaded calendar to keep [US], got %v", regions)
+	}
+
+	if _, err := us.Load(context.Background(), fakeStore{err: errors.New("boom")}); err == nil {
+		t.Error("Expected the store error")
+	}
+
+	weekends, err := (*Calendar)(nil).Load(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !weekends.IsBusinessDay(mustDate("2024-07-04")) || len(weekends.Regions()) != 0 {
+		t.Error("Expected a nil calendar to load without federal holidays")
+	}
+}
@@ -0,0 +1,145 @@
+package holidays
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// Federal returns the federal holidays observed in year for region, on the
+// day they are observed when they fall on a weekend
+func Federal(region string, year int) ([]Holiday, error) {
+	switch region {
+	case RegionCA:
+		return canadian(year), nil
+	case RegionUS:
+		return american(year), nil
+	default:
+		return nil, fmt.Errorf("unknown holiday region %q", region)
+	}
+}
+
+// canadian returns the general holidays of the Canada Labour Code. Holidays
+// on a weekend are observed on the following weekday, after any holiday
+// already observed then (Boxing Day moves past Christmas).
+func canadian(year int) []Holiday {
+	easter := easterSunday(year)
+	days := []struct {
+		name string
+		date time.Time
+	}{
+		{"New Year's Day", date(year, time.January, 1)},
+		{"Good Friday", easter.AddDate(0, 0, -2)},
+		{"Victoria Day", lastWeekdayOnOrBefore(date(year, time.May, 24), time.Monday)},
+		{"Canada Day", date(year, time.July, 1)},
+		{"Labour Day", nthWeekday(year, time.September, time.Monday, 1)},
+		{"National Day for Truth and Reconciliation", date(year, time.September, 30)},
+		{"Thanksgiving", nthWeekday(year, time.October, time.Monday, 2)},
+		{"Remembrance Day", date(year, time.November, 11)},
+		{"Christmas Day", date(year, time.December, 25)},
+		{"Boxing Day", date(year, time.December, 26)},
+	}
+
+	holidays := []Holiday{}
+	taken := map[time.Time]bool{}
+	for _, d := range days {
+		if d.name == "National Day for Truth and Reconciliation" && year < 2021 {
+			continue
+		}
+		observed, name := d.date, d.name
+		for !models.IsWeekday(observed) || taken[observed] {
+			observed = observed.AddDate(0, 0, 1)
+		}
+		if !observed.Equal(d.date) {
+			name += " (observed)"
+		}
+		taken[observed] = true
+		holidays = append(holidays, Holiday{Date: observed.Format(models.DateFormat), Name: name, Source: RegionCA})
+	}
+	return holidays
+}
+
+// american returns the US federal holidays (5 U.S.C. 6103). Holidays on a
+// Saturday are observed on the Friday before and on a Sunday the Monday
+// after, so New Year's Day can be observed on December 31st of the year
+// before.
+func american(year int) []Holiday {
+	days := []struct {
+		name  string
+		date  time.Time
+		since int
+	}{
+		{"New Year's Day", date(year, time.January, 1), 0},
+		{"Birthday of Martin Luther King, Jr.", nthWeekday(year, time.January, time.Monday, 3), 1986},
+		{"Washington's Birthday", nthWeekday(year, time.February, time.Monday, 3), 0},
+		{"Memorial Day", lastWeekdayOnOrBefore(date(year, time.May, 31), time.Monday), 0},
+		{"Juneteenth National Independence Day", date(year, time.June, 19), 2021},
+		{"Independence Day", date(year, time.July, 4), 0},
+		{"Labor Day", nthWeekday(year, time.September, time.Monday, 1), 0},
+		{"Columbus Day", nthWeekday(year, time.October, time.Monday, 2), 0},
+		{"Veterans Day", date(year, time.November, 11), 0},
+		{"Thanksgiving Day", nthWeekday(year, time.November, time.Thursday, 4), 0},
+		{"Christmas Day", date(year, time.December, 25), 0},
+		{"New Year's Day", date(year+1, time.January, 1), 0},
+	}
+
+	holidays := []Holiday{}
+	for _, d := range days {
+		if year < d.since {
+			continue
+		}
+		observed, name := d.date, d.name
+		switch observed.Weekday() {
+		case time.Saturday:
+			observed = observed.AddDate(0, 0, -1)
+		case time.Sunday:
+			observed = observed.AddDate(0, 0, 1)
+		}
+		if observed.Year() != year {
+			continue
+		}
+		if !observed.Equal(d.date) {
+			name += " (observed)"
+		}
+		holidays = append(holidays, Holiday{Date: observed.Format(models.DateFormat), Name: name, Source: RegionUS})
+	}
+	return holidays
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// nthWeekday returns the nth given weekday of the month
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	first := date(year, month, 1)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekdayOnOrBefore returns the latest given weekday on or before day
+func lastWeekdayOnOrBefore(day time.Time, weekday time.Weekday) time.Time {
+	offset := (int(day.Weekday()) - int(weekday) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// easterSunday returns Easter Sunday in the Gregorian calendar using the
+// anonymous Gregorian algorithm
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), day)
+}
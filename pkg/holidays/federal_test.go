@@ -0,0 +1,152 @@
+package holidays
+
+import (
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// holidayOn returns the name of the holiday on date in holidays, or ""
+func holidayOn(holidays []Holiday, date string) string {
+	for _, h := range holidays {
+		if h.Date == date {
+			return h.Name
+		}
+	}
+	return ""
+}
+
+func TestFederal_Canada(t *testing.T) {
+	tests := []struct {
+		year int
+		date string
+		name string
+	}{
+		{2024, "2024-01-01", "New Year's Day"},
+		{2024, "2024-03-29", "Good Friday"},
+		{2024, "2024-05-20", "Victoria Day"},
+		{2024, "2024-07-01", "Canada Day"},
+		{2024, "2024-09-02", "Labour Day"},
+		{2024, "2024-09-30", "National Day for Truth and Reconciliation"},
+		{2024, "2024-10-14", "Thanksgiving"},
+		{2024, "2024-11-11", "Remembrance Day"},
+		{2024, "2024-12-25", "Christmas Day"},
+		{2024, "2024-12-26", "Boxing Day"},
+		{2025, "2025-04-18", "Good Friday"},
+		{2025, "2025-05-19", "Victoria Day"},
+		// Canada Day on a Saturday moves to Monday
+		{2023, "2023-07-03", "Canada Day (observed)"},
+		// Christmas on a Sunday takes Monday and pushes Boxing Day to Tuesday
+		{2022, "2022-12-26", "Christmas Day (observed)"},
+		{2022, "2022-12-27", "Boxing Day (observed)"},
+	}
+
+	for _, tt := range tests {
+		holidays, err := Federal(RegionCA, tt.year)
+		if err != nil {
+			t.Fatalf("Federal failed: %v", err)
+		}
+		if got := holidayOn(holidays, tt.date); got != tt.name {
+			t.Errorf("Expected %q on %s, got %q", tt.name, tt.date, got)
+		}
+	}
+
+	holidays, _ := Federal(RegionCA, 2020)
+	if got := holidayOn(holidays, "2020-09-30"); got != "" {
+		t.Errorf("Expected no Truth and Reconciliation holiday before 2021, got %q", got)
+	}
+	holidays, _ = Federal(RegionCA, 2023)
+	if got := holidayOn(holidays, "2023-07-01"); got != "" {
+		t.Errorf("Expected Saturday Canada Day to be observed on Monday, got %q on the 1st", got)
+	}
+}
+
+func TestFederal_UnitedStates(t *testing.T) {
+	tests := []struct {
+		year int
+		date string
+		name string
+	}{
+		{2024, "2024-01-15", "Birthday of Martin Luther King, Jr."},
+		{2024, "2024-02-19", "Washington's Birthday"},
+		{2024, "2024-05-27", "Memorial Day"},
+		{2024, "2024-06-19", "Juneteenth National Independence Day"},
+		{2024, "2024-07-04", "Independence Day"},
+		{2024, "2024-10-14", "Columbus Day"},
+		{2024, "2024-11-28", "Thanksgiving Day"},
+		// Saturday holidays move to Friday, Sunday ones to Monday
+		{2026, "2026-07-03", "Independence Day (observed)"},
+		{2022, "2022-06-20", "Juneteenth National Independence Day (observed)"},
+		{2022, "2022-12-26", "Christmas Day (observed)"},
+		// New Year's Day 2022 was a Saturday, observed on the last day of 2021
+		{2021, "2021-12-31", "New Year's Day (observed)"},
+	}
+
+	for _, tt := range tests {
+		holidays, err := Federal(RegionUS, tt.year)
+		if err != nil {
+			t.Fatalf("Federal failed: %v", err)
+		}
+		if got := holidayOn(holidays, tt.date); got != tt.name {
+			t.Errorf("Expected %q on %s, got %q", tt.name, tt.date, got)
+		}
+	}
+
+	holidays, _ := Federal(RegionUS, 2022)
+	if got := holidayOn(holidays, "2022-01-01"); got != "" {
+		t.Errorf("Expected no holiday on Saturday 2022-01-01, got %q", got)
+	}
+	if got := len(holidays); got != 10 {
+		t.Errorf("Expected 10 holidays in 2022 (New Year's observed in 2021), got %d", got)
+	}
+}
+
+func TestFederal_UnknownRegion(t *testing.T) {
+	if _, err := Federal("MX", 2024); err == nil {
+		t.Error("Expected an error for an unknown region")
+	}
+}
+
+func TestEasterSunday(t *testing.T) {
+	for year, want := range map[int]string{
+		2019: "2019-04-21",
+		2024: "2024-03-31",
+		2025: "2025-04-20",
+		2038: "2038-04-25",
+	} {
+		if got := easterSunday(year).Format(models.DateFormat); got != want {
+			t.Errorf("Easter %d: expected %s, got %s", year, want, got)
+		}
+	}
+}
+
+func TestParseRegions(t *testing.T) {
+	regions, err := ParseRegions(" ca, US ")
+	if err != nil || len(regions) != 2 || regions[0] != RegionCA || regions[1] != RegionUS {
+		t.Errorf("Expected [CA US], got %v (%v)", regions, err)
+	}
+	if regions, err := ParseRegions("none"); err != nil || len(regions) != 0 {
+		t.Errorf("Expected no regions, got %v (%v)", regions, err)
+	}
+	if _, err := ParseRegions("CA,XX"); err == nil {
+		t.Error("Expected an error for an unknown region")
+	}
+}
+
+func TestRegionsFromEnv(t *testing.T) {
+	t.Setenv("HOLIDAY_REGIONS", "")
+	if regions, _ := RegionsFromEnv(); len(regions) != 1 || regions[0] != RegionCA {
+		t.Errorf("Expected default [CA], got %v", regions)
+	}
+	t.Setenv("HOLIDAY_REGIONS", "US")
+	if regions, _ := RegionsFromEnv(); len(regions) != 1 || regions[0] != RegionUS {
+		t.Errorf("Expected [US], got %v", regions)
+	}
+}
+
+// mustDate parses an ISO date for tests
+func mustDate(s string) time.Time {
+	d, _ := time.Parse(models.DateFormat, s)
+	return d
+}
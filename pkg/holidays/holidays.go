@@ -0,0 +1,70 @@
+// Package holidays knows which days banks won't process a payment: weekends,
+// the federal holidays of the configured regions and uploaded holiday lists
+package holidays
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Holiday is a day payments aren't processed
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+	// Source is the region for built-in holidays or the name of the
+	// uploaded list
+	Source string `json:"source"`
+}
+
+// Built-in holiday regions
+const (
+	RegionCA = "CA"
+	RegionUS = "US"
+)
+
+// Regions lists the regions with built-in holiday rules
+var Regions = []string{RegionCA, RegionUS}
+
+// DefaultRegions are used when HOLIDAY_REGIONS isn't set
+var DefaultRegions = []string{RegionCA}
+
+// IsRegion reports whether name is one of Regions
+func IsRegion(name string) bool {
+	for _, r := range Regions {
+		if strings.EqualFold(r, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRegions parses a comma separated list of regions such as "CA,US".
+// "none" disables the built-in holidays.
+func ParseRegions(s string) ([]string, error) {
+	if strings.EqualFold(strings.TrimSpace(s), "none") {
+		return []string{}, nil
+	}
+	regions := []string{}
+	for _, part := range strings.Split(s, ",") {
+		region := strings.ToUpper(strings.TrimSpace(part))
+		if region == "" {
+			continue
+		}
+		if !IsRegion(region) {
+			return nil, fmt.Errorf("unknown holiday region %q (expected one of %s)", part, strings.Join(Regions, ", "))
+		}
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+// RegionsFromEnv returns the regions in HOLIDAY_REGIONS, or DefaultRegions
+// if it isn't set
+func RegionsFromEnv() ([]string, error) {
+	value := os.Getenv("HOLIDAY_REGIONS")
+	if value == "" {
+		return DefaultRegions, nil
+	}
+	return ParseRegions(value)
+}
@@ -0,0 +1,192 @@
+package holidays
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// Upload formats
+const (
+	FormatICS = "ics"
+	FormatCSV = "csv"
+)
+
+// Parse reads a holiday list in format and labels every holiday with source
+func Parse(r io.Reader, format, source string) ([]Holiday, error) {
+	switch format {
+	case FormatICS:
+		return ParseICS(r, source)
+	case FormatCSV:
+		return ParseCSV(r, source)
+	default:
+		return nil, fmt.Errorf("unsupported holiday format %q (expected %s or %s)", format, FormatICS, FormatCSV)
+	}
+}
+
+// ParseICS reads the all-day events of an iCalendar file. Events spanning
+// several days become one holiday per day. Recurrence rules aren't expanded,
+// so each year's holidays must be listed.
+func ParseICS(r io.Reader, source string) ([]Holiday, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays := []Holiday{}
+	var inEvent bool
+	var start, end time.Time
+	var summary string
+	for i, line := range lines {
+		name, value := splitICSLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			inEvent = true
+			start, end, summary = time.Time{}, time.Time{}, ""
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("event ending on line %d has no DTSTART", i+1)
+			}
+			if summary == "" {
+				summary = "Holiday"
+			}
+			// DTEND is exclusive; a missing one means a single day
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				holidays = append(holidays, Holiday{Date: d.Format(models.DateFormat), Name: summary, Source: source})
+			}
+		case !inEvent:
+			// Properties of the calendar itself
+		case name == "DTSTART" || name == "DTEND":
+			date, err := parseICSDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if name == "DTSTART" {
+				start = date
+			} else {
+				end = date
+			}
+		case name == "SUMMARY":
+			summary = unescapeICS(value)
+		}
+	}
+	if len(holidays) == 0 {
+		return nil, errors.New("no events found in calendar")
+	}
+	return holidays, nil
+}
+
+// unfoldICS splits an iCalendar file into logical lines, joining lines that
+// continue with a leading space or tab
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// splitICSLine splits "DTSTART;VALUE=DATE:20241225" into its name and
+// value, dropping any parameters
+func splitICSLine(line string) (string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), ""
+	}
+	name := line[:colon]
+	if semi := strings.Index(name, ";"); semi >= 0 {
+		name = name[:semi]
+	}
+	return strings.ToUpper(name), line[colon+1:]
+}
+
+// parseICSDate reads a DATE (20241225) or DATE-TIME (20241225T000000Z)
+// value, keeping only the date
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
+
+// unescapeICS undoes iCalendar text escaping
+func unescapeICS(s string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(strings.TrimSpace(s))
+}
+
+// csvDateLayouts are the date formats accepted in holiday CSV files
+var csvDateLayouts = []string{models.DateFormat, "2006/01/02", "20060102"}
+
+// ParseCSV reads "date,name" rows. A header row is skipped and the name
+// column is optional.
+func ParseCSV(r io.Reader, source string) ([]Holiday, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	holidays := []Holiday{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		date, ok := parseCSVDate(strings.TrimSpace(record[0]))
+		if !ok {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid date %q (expected YYYY-MM-DD)", line, record[0])
+		}
+		name := "Holiday"
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			name = strings.TrimSpace(record[1])
+		}
+		holidays = append(holidays, Holiday{Date: date.Format(models.DateFormat), Name: name, Source: source})
+	}
+	if len(holidays) == 0 {
+		return nil, errors.New("no holidays found in CSV")
+	}
+	return holidays, nil
+}
+
+func parseCSVDate(value string) (time.Time, bool) {
+	for _, layout := range csvDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,94 @@
+package holidays
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseICS(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//Bank//Holidays//EN\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20240805\r\n" +
+		"SUMMARY:Civic Holiday\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20241224\r\n" +
+		"DTEND;VALUE=DATE:20241227\r\n" +
+		"SUMMARY:Branch closure\\, holidays \r\n" +
+		" period\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART:20250101T050000Z\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	holidays, err := ParseICS(strings.NewReader(ics), "eq-bank")
+	if err != nil {
+		t.Fatalf("ParseICS failed: %v", err)
+	}
+
+	want := []Holiday{
+		{"2024-08-05", "Civic Holiday", "eq-bank"},
+		{"2024-12-24", "Branch closure, holidays period", "eq-bank"},
+		{"2024-12-25", "Branch closure, holidays period", "eq-bank"},
+		{"2024-12-26", "Branch closure, holidays period", "eq-bank"},
+		{"2025-01-01", "Holiday", "eq-bank"},
+	}
+	if len(holidays) != len(want) {
+		t.Fatalf("Expected %d holidays, got %+v", len(want), holidays)
+	}
+	for i := range want {
+		if holidays[i] != want[i] {
+			t.Errorf("Holiday %d: expected %+v, got %+v", i, want[i], holidays[i])
+		}
+	}
+}
+
+func TestParseICS_Invalid(t *testing.T) {
+	for _, ics := range []string{
+		"BEGIN:VCALENDAR\nEND:VCALENDAR\n",
+		"BEGIN:VEVENT\nSUMMARY:No date\nEND:VEVENT\n",
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:2024\nEND:VEVENT\n",
+	} {
+		if _, err := ParseICS(strings.NewReader(ics), "x"); err == nil {
+			t.Errorf("Expected an error for %q", ics)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	csv := "date,name\n2024-08-05,Civic Holiday\n2024/12/24, Christmas Eve\n\n20250102\n"
+
+	holidays, err := ParseCSV(strings.NewReader(csv), "eq-bank")
+	if err != nil {
+		t.Fatalf("ParseCSV failed: %v", err)
+	}
+	want := []Holiday{
+		{"2024-08-05", "Civic Holiday", "eq-bank"},
+		{"2024-12-24", "Christmas Eve", "eq-bank"},
+		{"2025-01-02", "Holiday", "eq-bank"},
+	}
+	if len(holidays) != len(want) {
+		t.Fatalf("Expected %d holidays, got %+v", len(want), holidays)
+	}
+	for i := range want {
+		if holidays[i] != want[i] {
+			t.Errorf("Holiday %d: expected %+v, got %+v", i, want[i], holidays[i])
+		}
+	}
+}
+
+func TestParseCSV_InvalidDate(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("2024-08-05,Civic Holiday\nAugust 5,Oops\n"), "x")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+}
+
+func TestParse_UnknownFormat(t *testing.T) {
+	if _, err := Parse(strings.NewReader(""), "xlsx", "x"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,280 @@
+// Package importer matches statements read from issuer files to cards by
+// their last four digits and creates or updates them
+package importer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// ChangedBy is recorded as the author of status changes made by imports
+const ChangedBy = "import"
+
+// Actions say what an import did, or would do on a dry run, with a statement
+const (
+	// ActionCreate adds a new statement
+	ActionCreate = "create"
+	// ActionFill enters the amount of an expected placeholder
+	ActionFill = "fill"
+	// ActionUpdate changes the amounts or due date of an entered statement
+	ActionUpdate = "update"
+	// ActionUnchanged means the statement is already up to date
+	ActionUnchanged = "unchanged"
+	// ActionSkip means the statement can't be imported; Reason says why
+	ActionSkip = "skip"
+)
+
+// Statement is a statement read from a file, identified by the last four
+// digits of its card
+type Statement struct {
+	// CardID picks the card when it is known; otherwise the card is matched
+	// by LastFour
+	CardID        int
+	LastFour      string
+	StatementDate string
+	// DueDate is predicted from the card when empty
+	DueDate        string
+	Amount         models.Money
+	MinimumPayment models.Money
+}
+
+// Result reports what happened to one imported statement
+type Result struct {
+	LastFour       string       `json:"last_four"`
+	CardID         int          `json:"card_id,omitempty"`
+	CardName       string       `json:"card_name,omitempty"`
+	StatementID    int          `json:"statement_id,omitempty"`
+	StatementDate  string       `json:"statement_date"`
+	DueDate        string       `json:"due_date,omitempty"`
+	Amount         models.Money `json:"amount"`
+	MinimumPayment models.Money `json:"minimum_payment"`
+	Action         string       `json:"action"`
+	Reason         string       `json:"reason,omitempty"`
+
+	// Card and Entered are set when a statement was created or filled, and
+	// Card and Updated when an entered statement was updated, for the
+	// follow-ups of entering or changing a statement
+	Card    models.CreditCard `json:"-"`
+	Entered *models.Statement `json:"-"`
+	Updated *models.Statement `json:"-"`
+}
+
+// Importer stores imported statements
+type Importer struct {
+	cards      repository.CardRepository
+	statements repository.StatementRepository
+	clock      clock.Clock
+}
+
+// New creates an importer that matches against cards and stores into
+// statements. A nil clock uses the real time.
+func New(cards repository.CardRepository, statements repository.StatementRepository, clk clock.Clock) *Importer {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Importer{cards: cards, statements: statements, clock: clk}
+}
+
+// Import matches each statement to the card ending in its last four digits
+// and creates it, fills the card's expected placeholder for that cycle, or
+// updates the statement already entered for that date. With dryRun nothing
+// is stored and the results preview what would happen. Statements that
+// can't be matched or are invalid are skipped.
+func (i *Importer) Import(ctx context.Context, list []Statement, dryRun bool) ([]Result, error) {
+	cards, err := i.cards.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cards: %w", err)
+	}
+	existing, err := i.statements.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statements: %w", err)
+	}
+
+	results := make([]Result, 0, len(list))
+	for _, imported := range list {
+		result, stored, err := i.importOne(ctx, cards, existing, imported, dryRun)
+		if err != nil {
+			return results, err
+		}
+		// Later statements in the same file for the same cycle update this one
+		if stored != nil {
+			existing = append(existing, *stored)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// importOne imports a single statement and returns its result along with
+// the statement as stored, or as it would be stored on a dry run
+func (i *Importer) importOne(ctx context.Context, cards []models.CreditCard, existing []models.Statement, imported Statement, dryRun bool) (Result, *models.Statement, error) {
+	result := Result{
+		LastFour:       imported.LastFour,
+		StatementDate:  imported.StatementDate,
+		DueDate:        imported.DueDate,
+		Amount:         imported.Amount,
+		MinimumPayment: imported.MinimumPayment,
+	}
+	skip := func(reason string) (Result, *models.Statement, error) {
+		result.Action = ActionSkip
+		result.Reason = reason
+		return result, nil, nil
+	}
+
+	card, reason := matchCard(cards, imported.LastFour)
+	if imported.CardID != 0 {
+		card, reason = findCard(cards, imported.CardID)
+	}
+	if reason != "" {
+		return skip(reason)
+	}
+	result.LastFour = card.LastFour
+	result.CardID = card.ID
+	result.CardName = card.Name
+
+	if imported.Amount <= 0 {
+		return skip("nothing is owed on this statement")
+	}
+	if result.DueDate == "" {
+		date, err := time.Parse(models.DateFormat, imported.StatementDate)
+		if err != nil {
+			return skip("statement_date must be a valid date (YYYY-MM-DD)")
+		}
+		result.DueDate = card.ExpectedDueDate(date).Format(models.DateFormat)
+	}
+
+	now := i.clock.Now()
+	stmt := models.Statement{
+		CardID:         card.ID,
+		StatementDate:  imported.StatementDate,
+		DueDate:        result.DueDate,
+		Amount:         imported.Amount,
+		MinimumPayment: imported.MinimumPayment,
+		Status:         models.StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := stmt.Validate(); err != nil {
+		return skip(err.Error())
+	}
+
+	if current, ok := findEntered(existing, card.ID, imported.StatementDate); ok {
+		return i.update(ctx, result, card, current, stmt, dryRun)
+	}
+
+	placeholder, err := i.statements.FindExpected(ctx, card.ID, stmt.StatementDate)
+	switch {
+	case err == nil:
+		result.Action = ActionFill
+		result.StatementID = placeholder.ID
+		stmt.ID = placeholder.ID
+		if !dryRun {
+			err = i.statements.Fill(ctx, &stmt, ChangedBy)
+		}
+	case err == repository.ErrNotFound:
+		result.Action = ActionCreate
+		err = nil
+		if !dryRun {
+			err = i.statements.Create(ctx, &stmt)
+			result.StatementID = stmt.ID
+		}
+	}
+	if err != nil {
+		return result, nil, fmt.Errorf("failed to import %s statement for %s: %w", stmt.StatementDate, card.Name, err)
+	}
+
+	if !dryRun {
+		result.Card = card
+		result.Entered = &stmt
+	}
+	return result, &stmt, nil
+}
+
+// update brings an entered statement in line with the imported one. A
+// minimum payment the file doesn't give is left alone, and a paid
+// statement's amount can't change.
+func (i *Importer) update(ctx context.Context, result Result, card models.CreditCard, current, stmt models.Statement, dryRun bool) (Result, *models.Statement, error) {
+	result.StatementID = current.ID
+
+	update := repository.StatementUpdate{UpdatedAt: stmt.UpdatedAt}
+	changed := false
+	if stmt.DueDate != current.DueDate {
+		update.DueDate = &stmt.DueDate
+		changed = true
+	}
+	if stmt.Amount != current.Amount {
+		update.Amount = &stmt.Amount
+		changed = true
+	}
+	if stmt.MinimumPayment != 0 && stmt.MinimumPayment != current.MinimumPayment {
+		update.MinimumPayment = &stmt.MinimumPayment
+		changed = true
+	}
+
+	if !changed {
+		result.Action = ActionUnchanged
+		return result, nil, nil
+	}
+	if update.Amount != nil && current.Status == models.StatusPaid {
+		result.Action = ActionSkip
+		result.Reason = "amount cannot be changed after the statement is paid"
+		return result, nil, nil
+	}
+	result.Action = ActionUpdate
+	if dryRun {
+		return result, nil, nil
+	}
+
+	updated, err := i.statements.Update(ctx, current.ID, update)
+	if err != nil {
+		return result, nil, fmt.Errorf("failed to update statement %d: %w", current.ID, err)
+	}
+	result.Card = card
+	result.Updated = &updated
+	return result, nil, nil
+}
+
+// matchCard returns the only card ending in lastFour, or a reason it can't
+// be picked
+func matchCard(cards []models.CreditCard, lastFour string) (models.CreditCard, string) {
+	var matches []models.CreditCard
+	for _, card := range cards {
+		if card.LastFour == lastFour {
+			matches = append(matches, card)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return models.CreditCard{}, fmt.Sprintf("no card ends in %s", lastFour)
+	case 1:
+		return matches[0], ""
+	default:
+		return models.CreditCard{}, fmt.Sprintf("%d cards end in %s", len(matches), lastFour)
+	}
+}
+
+// findCard returns the card with the given ID, or a reason it can't be
+// picked
+func findCard(cards []models.CreditCard, id int) (models.CreditCard, string) {
+	for _, card := range cards {
+		if card.ID == id {
+			return card, ""
+		}
+	}
+	return models.CreditCard{}, fmt.Sprintf("card %d does not exist", id)
+}
+
+// findEntered returns the card's entered statement for statementDate
+func findEntered(statements []models.Statement, cardID int, statementDate string) (models.Statement, bool) {
+	for _, stmt := range statements {
+		if stmt.CardID == cardID && stmt.StatementDate == statementDate && stmt.Status != models.StatusExpected {
+			return stmt, true
+		}
+	}
+	return models.Statement{}, false
+}
@@ -0,0 +1,215 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// setupImporter creates a test database with cards ending in 1234 and 5678
+func setupImporter(t *testing.T) (*Importer, repository.StatementRepository, func()) {
+	tmpDB := "./test_importer.db"
+	db, err := database.InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+
+	cards := repository.NewSQLiteCardRepository(db)
+	statements := repository.NewSQLiteStatementRepository(db)
+	for _, card := range []models.CreditCard{
+		{Name: "TD Aeroplan Visa", LastFour: "1234", StatementDay: 15, DaysUntilDue: 21},
+		{Name: "Amex Cobalt", LastFour: "5678", StatementDay: 20, DaysUntilDue: 25},
+	} {
+		if err := cards.Create(context.Background(), &card); err != nil {
+			t.Fatalf("Failed to create card: %v", err)
+		}
+	}
+
+	clk := clock.NewFixed(time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC))
+	return New(cards, statements, clk), statements, func() {
+		db.Close()
+		os.Remove(tmpDB)
+	}
+}
+
+func TestImport_Create(t *testing.T) {
+	imp, statements, cleanup := setupImporter(t)
+	defer cleanup()
+
+	results, err := imp.Import(context.Background(), []Statement{
+		{LastFour: "1234", StatementDate: "2024-11-15", Amount: models.MustParseMoney("1250.75")},
+	}, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	result := results[0]
+	if result.Action != ActionCreate || result.CardName != "TD Aeroplan Visa" {
+		t.Fatalf("Expected a new TD Aeroplan Visa statement, got %+v", result)
+	}
+	// The due date is predicted from the card
+	if result.DueDate != "2024-12-06" {
+		t.Errorf("Expected due date 2024-12-06, got %s", result.DueDate)
+	}
+	if result.Entered == nil {
+		t.Error("Expected the entered statement to be returned")
+	}
+
+	stmt, err := statements.Get(context.Background(), result.StatementID)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stmt.Amount.String() != "1250.75" || stmt.Status != models.StatusPending {
+		t.Errorf("Expected a pending 1250.75 statement, got %s %s", stmt.Status, stmt.Amount)
+	}
+}
+
+func TestImport_UpdateAndUnchanged(t *testing.T) {
+	imp, statements, cleanup := setupImporter(t)
+	defer cleanup()
+
+	imported := Statement{LastFour: "5678", StatementDate: "2024-11-20", DueDate: "2024-12-15", Amount: models.MustParseMoney("820.10")}
+	if _, err := imp.Import(context.Background(), []Statement{imported}, false); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	results, err := imp.Import(context.Background(), []Statement{imported}, false)
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if results[0].Action != ActionUnchanged {
+		t.Errorf("Expected unchanged on re-import, got %s", results[0].Action)
+	}
+
+	imported.Amount = models.MustParseMoney("830.10")
+	imported.MinimumPayment = models.MustParseMoney("35.00")
+	results, err = imp.Import(context.Background(), []Statement{imported}, false)
+	if err != nil {
+		t.Fatalf("Third import failed: %v", err)
+	}
+	if results[0].Action != ActionUpdate {
+		t.Fatalf("Expected update, got %s", results[0].Action)
+	}
+
+	if results[0].Updated == nil || results[0].Updated.Amount.String() != "830.10" || results[0].Card.ID != results[0].CardID {
+		t.Errorf("Expected the updated statement for the follow-ups, got %+v", results[0])
+	}
+
+	stmt, _ := statements.Get(context.Background(), results[0].StatementID)
+	if stmt.Amount.String() != "830.10" || stmt.MinimumPayment.String() != "35.00" {
+		t.Errorf("Expected 830.10 with minimum 35.00, got %s and %s", stmt.Amount, stmt.MinimumPayment)
+	}
+
+	// A paid statement's amount is final
+	if err := statements.ChangeStatus(context.Background(), models.StatusChange{
+		StatementID: stmt.ID, FromStatus: models.StatusPending, ToStatus: models.StatusPaid, ChangedBy: "user",
+	}); err != nil {
+		t.Fatalf("Failed to mark statement paid: %v", err)
+	}
+	imported.Amount = models.MustParseMoney("900.00")
+	results, err = imp.Import(context.Background(), []Statement{imported}, false)
+	if err != nil {
+		t.Fatalf("Fourth import failed: %v", err)
+	}
+	if results[0].Action != ActionSkip || results[0].Reason == "" || results[0].Updated != nil {
+		t.Errorf("Expected the paid statement to be skipped with a reason, got %+v", results[0])
+	}
+	if stmt, _ := statements.Get(context.Background(), results[0].StatementID); stmt.Amount.String() != "830.10" {
+		t.Errorf("Expected the paid amount to stay 830.10, got %s", stmt.Amount)
+	}
+
+	all, _ := statements.List(context.Background())
+	if len(all) != 1 {
+		t.Errorf("Expected 1 statement, got %d", len(all))
+	}
+}
+
+func TestImport_FillsExpected(t *testing.T) {
+	imp, statements, cleanup := setupImporter(t)
+	defer cleanup()
+
+	placeholder := models.Statement{CardID: 1, StatementDate: "2024-11-15", DueDate: "2024-12-06", Status: models.StatusExpected}
+	if err := statements.Create(context.Background(), &placeholder); err != nil {
+		t.Fatalf("Failed to create placeholder: %v", err)
+	}
+
+	// Issuers sometimes close a day or two off the predicted date
+	results, err := imp.Import(context.Background(), []Statement{
+		{LastFour: "1234", StatementDate: "2024-11-16", Amount: models.MustParseMoney("300.00")},
+	}, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if results[0].Action != ActionFill || results[0].StatementID != placeholder.ID {
+		t.Fatalf("Expected the placeholder to be filled, got %+v", results[0])
+	}
+
+	stmt, _ := statements.Get(context.Background(), placeholder.ID)
+	if stmt.Status != models.StatusPending || stmt.StatementDate != "2024-11-16" {
+		t.Errorf("Expected a pending statement dated 2024-11-16, got %s %s", stmt.Status, stmt.StatementDate)
+	}
+}
+
+func TestImport_DryRun(t *testing.T) {
+	imp, statements, cleanup := setupImporter(t)
+	defer cleanup()
+
+	results, err := imp.Import(context.Background(), []Statement{
+		{LastFour: "1234", StatementDate: "2024-11-15", Amount: models.MustParseMoney("100.00")},
+		{LastFour: "9999", StatementDate: "2024-11-15", Amount: models.MustParseMoney("100.00")},
+		{LastFour: "5678", StatementDate: "2024-11-20", Amount: 0},
+		{LastFour: "5678", StatementDate: "2024-11-20", Amount: models.MustParseMoney("10.00"), MinimumPayment: models.MustParseMoney("20.00")},
+		{CardID: 2, StatementDate: "2024-11-20", Amount: models.MustParseMoney("10.00")},
+		{CardID: 99, LastFour: "1234", StatementDate: "2024-11-15", Amount: models.MustParseMoney("10.00")},
+	}, true)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	want := []string{ActionCreate, ActionSkip, ActionSkip, ActionSkip, ActionCreate, ActionSkip}
+	for i, result := range results {
+		if result.Action != want[i] {
+			t.Errorf("Result %d: expected %s, got %s (%s)", i, want[i], result.Action, result.Reason)
+		}
+		if result.Action == ActionSkip && result.Reason == "" {
+			t.Errorf("Result %d: expected a reason for skipping", i)
+		}
+		if result.Entered != nil {
+			t.Errorf("Result %d: expected nothing entered on a dry run", i)
+		}
+	}
+
+	// A card ID picks the card without its last four digits
+	if results[4].CardName != "Amex Cobalt" || results[4].LastFour != "5678" {
+		t.Errorf("Expected card 2 to be picked by ID, got %+v", results[4])
+	}
+
+	all, _ := statements.List(context.Background())
+	if len(all) != 0 {
+		t.Errorf("Expected a dry run to store nothing, got %d statements", len(all))
+	}
+}
+
+func TestMatchCard(t *testing.T) {
+	cards := []models.CreditCard{
+		{ID: 1, LastFour: "1234"},
+		{ID: 2, LastFour: "4321"},
+		{ID: 3, LastFour: "4321"},
+	}
+
+	if card, reason := matchCard(cards, "1234"); reason != "" || card.ID != 1 {
+		t.Errorf("Expected card 1, got %d (%s)", card.ID, reason)
+	}
+	if _, reason := matchCard(cards, "4321"); reason != "2 cards end in 4321" {
+		t.Errorf("Expected an ambiguous match, got %q", reason)
+	}
+	if _, reason := matchCard(cards, "0000"); reason != "no card ends in 0000" {
+		t.Errorf("Expected no match, got %q", reason)
+	}
+}
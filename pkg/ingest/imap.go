@@ -0,0 +1,313 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+)
+
+// pollTimeout limits how long one poll of the mailbox may take
+const pollTimeout = 2 * time.Minute
+
+// maxMessageSize limits the size of a fetched email
+const maxMessageSize = 25 << 20
+
+// Mailbox polls an IMAP mailbox for unread statement emails. How far it
+// has read is kept in scheduler_state, so each email is read once.
+type Mailbox struct {
+	db       *sql.DB
+	dialect  database.Dialect
+	cfg      config.IMAPConfig
+	ingester *Ingester
+}
+
+// NewMailbox creates a mailbox that reads the folder in cfg through
+// ingester and records its position in db
+func NewMailbox(db *sql.DB, cfg config.IMAPConfig, ingester *Ingester) *Mailbox {
+	return &Mailbox{db: db, dialect: database.DialectOf(db), cfg: cfg, ingester: ingester}
+}
+
+// Enabled reports whether a mailbox is configured
+func (m *Mailbox) Enabled() bool {
+	return m != nil && m.ingester != nil && m.cfg.Enabled()
+}
+
+// Poll reads the unread emails that arrived since the last poll and marks
+// those that became suggestions read. Other emails are left unread for
+// whoever else reads the mailbox, but aren't read again. An email that
+// fails to be stored ends the poll and is tried again on the next one.
+func (m *Mailbox) Poll(ctx context.Context) ([]Outcome, error) {
+	ctx, cancel := context.WithTimeout(ctx, pollTimeout)
+	defer cancel()
+
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", m.cfg.Address, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	if _, err := c.read(); err != nil {
+		return nil, fmt.Errorf("failed to read imap greeting: %w", err)
+	}
+	if _, err := c.command("LOGIN %s %s", quote(m.cfg.Username), quote(m.cfg.Password)); err != nil {
+		return nil, err
+	}
+	selected, err := c.command("SELECT %s", quote(m.cfg.Mailbox))
+	if err != nil {
+		return nil, err
+	}
+	validity := uidValidity(selected)
+	last, err := m.lastUID(ctx, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	uids, err := c.searchUnseen(last + 1)
+	if err != nil {
+		return nil, err
+	}
+	var outcomes []Outcome
+	for _, uid := range uids {
+		// UID n:* matches the newest message even when it is older than n
+		if uid <= last {
+			continue
+		}
+		data, err := c.fetch(uid)
+		if err != nil {
+			return outcomes, err
+		}
+		outcome, err := m.ingester.Ingest(ctx, bytes.NewReader(data), SourceIMAP)
+		if errors.Is(err, ErrNotEmail) {
+			log.Printf("Skipping message %d in %s: %v", uid, m.cfg.Mailbox, err)
+		} else if err != nil {
+			return outcomes, fmt.Errorf("failed to ingest message %d: %w", uid, err)
+		} else {
+			outcomes = append(outcomes, outcome)
+		}
+		if outcome.Suggestion != nil {
+			if _, err := c.command("UID STORE %d +FLAGS.SILENT (\\Seen)", uid); err != nil {
+				return outcomes, err
+			}
+		}
+		if err := m.setLastUID(ctx, validity, uid); err != nil {
+			return outcomes, err
+		}
+	}
+
+	// The emails have been read, so a failed logout doesn't matter
+	c.command("LOGOUT")
+	return outcomes, nil
+}
+
+// positionKey is the scheduler_state key holding how far the mailbox has
+// been read, as "uidvalidity:uid"
+func (m *Mailbox) positionKey() string {
+	return fmt.Sprintf("imap:%s@%s/%s", m.cfg.Username, m.cfg.Address, m.cfg.Mailbox)
+}
+
+// lastUID returns the UID of the last message read, or 0 if none has been
+// read since the server last renumbered the mailbox, as it reports by
+// changing its UIDVALIDITY
+func (m *Mailbox) lastUID(ctx context.Context, validity int) (int, error) {
+	var value string
+	err := m.db.QueryRowContext(ctx, m.dialect.Rebind("SELECT value FROM scheduler_state WHERE key = ?"), m.positionKey()).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read mailbox position: %w", err)
+	}
+
+	stored, uid, _ := strings.Cut(value, ":")
+	if stored != strconv.Itoa(validity) {
+		return 0, nil
+	}
+	last, err := strconv.Atoi(uid)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mailbox position %q: %w", value, err)
+	}
+	return last, nil
+}
+
+// setLastUID records uid as the last message read
+func (m *Mailbox) setLastUID(ctx context.Context, validity, uid int) error {
+	_, err := m.db.ExecContext(ctx, m.dialect.Rebind(`
+		INSERT INTO scheduler_state (key, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`), m.positionKey(), fmt.Sprintf("%d:%d", validity, uid), m.ingester.clock.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record mailbox position: %w", err)
+	}
+	return nil
+}
+
+// dial connects to the server, over TLS unless the mailbox is insecure
+func (m *Mailbox) dial(ctx context.Context) (net.Conn, error) {
+	if m.cfg.Insecure {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", m.cfg.Address)
+	}
+	host, _, err := net.SplitHostPort(m.cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	d := tls.Dialer{Config: &tls.Config{ServerName: host}}
+	return d.DialContext(ctx, "tcp", m.cfg.Address)
+}
+
+// imapClient speaks the few IMAP4rev1 commands Poll needs
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is one response line with the literals sent within it
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// command sends a command and returns its untagged responses, or an error
+// if the server doesn't complete it with OK
+func (c *imapClient) command(format string, args ...interface{}) ([]imapResponse, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	command := fmt.Sprintf(format, args...)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, command); err != nil {
+		return nil, err
+	}
+
+	name := strings.Fields(command)[0]
+	var untagged []imapResponse
+	for {
+		resp, err := c.read()
+		if err != nil {
+			return nil, fmt.Errorf("imap %s failed: %w", name, err)
+		}
+		status, ok := strings.CutPrefix(resp.line, tag+" ")
+		if !ok {
+			untagged = append(untagged, resp)
+			continue
+		}
+		if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+			return nil, fmt.Errorf("imap %s failed: %s", name, status)
+		}
+		return untagged, nil
+	}
+}
+
+// read reads one response, including any literals ending its lines
+func (c *imapClient) read() (imapResponse, error) {
+	var resp imapResponse
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		resp.line += line
+
+		size, ok := literalSize(line)
+		if !ok {
+			return resp, nil
+		}
+		if size > maxMessageSize {
+			return resp, fmt.Errorf("message of %d bytes is too large", size)
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+}
+
+// literalSize returns the size of the literal announced at the end of line
+// as {size}
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	start := strings.LastIndex(line, "{")
+	if start < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(line[start+1 : len(line)-1])
+	return size, err == nil && size >= 0
+}
+
+// searchUnseen returns the UIDs of the unread messages from UID from on
+func (c *imapClient) searchUnseen(from int) ([]int, error) {
+	responses, err := c.command("UID SEARCH UID %d:* UNSEEN", from)
+	if err != nil {
+		return nil, err
+	}
+	var uids []int
+	for _, resp := range responses {
+		fields := strings.Fields(resp.line)
+		if len(fields) < 2 || fields[0] != "*" || !strings.EqualFold(fields[1], "SEARCH") {
+			continue
+		}
+		for _, field := range fields[2:] {
+			if uid, err := strconv.Atoi(field); err == nil {
+				uids = append(uids, uid)
+			}
+		}
+	}
+	return uids, nil
+}
+
+// uidValidity returns the UIDVALIDITY a SELECT reported, or 0 if it didn't
+func uidValidity(responses []imapResponse) int {
+	for _, resp := range responses {
+		upper := strings.ToUpper(resp.line)
+		at := strings.Index(upper, "[UIDVALIDITY ")
+		if at < 0 {
+			continue
+		}
+		value, _, _ := strings.Cut(upper[at+len("[UIDVALIDITY "):], "]")
+		if validity, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			return validity
+		}
+	}
+	return 0
+}
+
+// fetch returns a whole message without marking it read
+func (c *imapClient) fetch(uid int) ([]byte, error) {
+	responses, err := c.command("UID FETCH %d (BODY.PEEK[])", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range responses {
+		if len(resp.literals) > 0 && strings.Contains(strings.ToUpper(resp.line), "FETCH") {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap FETCH returned no message %d", uid)
+}
+
+// quote writes s as an IMAP quoted string
+func quote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", "").Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ingest/imaptest"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func TestMailbox_Poll(t *testing.T) {
+	db, ingester, _, entered := setupIngester(t)
+	ctx := context.Background()
+
+	server := imaptest.NewServer(t, "statements@example.com", `pa"ss\word`)
+	// A message whose body can't be decoded doesn't hold up those after it
+	corrupt := server.Deliver([]byte("From: noreply@td.com\r\nContent-Transfer-Encoding: base64\r\n\r\n!!not base64!!\r\n"))
+	td := server.Deliver([]byte(tdNotice))
+	promo := server.Deliver([]byte(promotion))
+	junk := server.Deliver([]byte("not an email"))
+
+	cfg := config.IMAPConfig{
+		Address:  server.Addr,
+		Username: "statements@example.com",
+		Password: `pa"ss\word`,
+		Mailbox:  "INBOX",
+		Insecure: true,
+	}
+	mailbox := NewMailbox(db, cfg, ingester)
+	if !mailbox.Enabled() {
+		t.Fatal("Expected the mailbox to be enabled")
+	}
+
+	outcomes, err := mailbox.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(outcomes) != 2 || outcomes[0].Suggestion == nil || outcomes[0].Suggestion.Status != models.SuggestionFilled ||
+		outcomes[0].Suggestion.Source != SourceIMAP || outcomes[1].Ignored == "" {
+		t.Errorf("Expected the TD statement filled and the promotion ignored, got %+v", outcomes)
+	}
+	if len(*entered) != 1 {
+		t.Errorf("Expected the filled statement to be followed up, got %d", len(*entered))
+	}
+	if !server.Seen(td) {
+		t.Error("Expected the statement email to be marked read")
+	}
+	// Emails that aren't statements are left for whoever else reads the mailbox
+	for _, uid := range []int{corrupt, promo, junk} {
+		if server.Seen(uid) {
+			t.Errorf("Expected message %d to be left unread", uid)
+		}
+	}
+
+	// Only new mail is read on the next poll, even after a restart
+	amex := server.Deliver([]byte(amexNotice))
+	outcomes, err = NewMailbox(db, cfg, ingester).Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(outcomes) != 1 || outcomes[0].Suggestion == nil ||
+		outcomes[0].Suggestion.Status != models.SuggestionPending || !server.Seen(amex) {
+		t.Errorf("Expected only the Amex statement to be queued, got %+v", outcomes)
+	}
+	if outcomes, err := mailbox.Poll(ctx); err != nil || len(outcomes) != 0 {
+		t.Errorf("Expected nothing new, got %+v (%v)", outcomes, err)
+	}
+	if server.Logins() != 3 {
+		t.Errorf("Expected a login per poll, got %d", server.Logins())
+	}
+
+	// Once the server renumbers the mailbox, the unread emails are read again
+	server.Renumber()
+	outcomes, err = mailbox.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(outcomes) != 1 || outcomes[0].Ignored == "" {
+		t.Errorf("Expected the promotion to be read again, got %+v", outcomes)
+	}
+}
+
+func TestMailbox_Errors(t *testing.T) {
+	db, ingester, _, _ := setupIngester(t)
+	server := imaptest.NewServer(t, "statements@example.com", "secret")
+	uid := server.Deliver([]byte(tdNotice))
+
+	cfg := config.IMAPConfig{Address: server.Addr, Username: "statements@example.com", Password: "wrong", Mailbox: "INBOX", Insecure: true}
+	if _, err := NewMailbox(db, cfg, ingester).Poll(context.Background()); err == nil || !strings.Contains(err.Error(), "LOGIN") {
+		t.Errorf("Expected the login to fail, got %v", err)
+	}
+
+	cfg.Password = "secret"
+	cfg.Mailbox = "Statements"
+	if _, err := NewMailbox(db, cfg, ingester).Poll(context.Background()); err == nil || !strings.Contains(err.Error(), "SELECT") {
+		t.Errorf("Expected a missing mailbox to fail, got %v", err)
+	}
+	if server.Seen(uid) {
+		t.Error("Expected the message to be left unread")
+	}
+
+	var none *Mailbox
+	if none.Enabled() || NewMailbox(db, config.IMAPConfig{}, ingester).Enabled() {
+		t.Error("Expected a mailbox without an address to be disabled")
+	}
+}
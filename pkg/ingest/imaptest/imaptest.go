@@ -0,0 +1,374 @@
+// Package imaptest runs an in-memory IMAP server for testing mailbox
+// polling without a mail server. It has a single INBOX and understands the
+// commands a mail client needs to read and flag messages: CAPABILITY, NOOP,
+// LOGIN, SELECT, UID SEARCH, UID FETCH, UID STORE and LOGOUT.
+package imaptest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server is a local IMAP server. It listens on the loopback interface
+// without TLS.
+type Server struct {
+	// Addr is the host:port the server listens on
+	Addr string
+
+	username string
+	password string
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu          sync.Mutex
+	conns       map[net.Conn]bool
+	messages    []*message
+	logins      int
+	uidValidity int
+}
+
+// message is a delivered email
+type message struct {
+	uid  int
+	data []byte
+	seen bool
+}
+
+// NewServer starts a server that accepts username and password, and stops
+// it when the test ends
+func NewServer(t testing.TB, username, password string) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start IMAP server: %v", err)
+	}
+	s := &Server{
+		Addr:        listener.Addr().String(),
+		username:    username,
+		password:    password,
+		listener:    listener,
+		conns:       map[net.Conn]bool{},
+		uidValidity: 1,
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close stops the server, closing any open connections
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Deliver adds an unread email to the INBOX and returns its UID
+func (s *Server) Deliver(data []byte) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid := len(s.messages) + 1
+	s.messages = append(s.messages, &message{uid: uid, data: data})
+	return uid
+}
+
+// Renumber gives the INBOX a new UIDVALIDITY, as servers do when they
+// rebuild a mailbox and the UIDs clients remember no longer apply
+func (s *Server) Renumber() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uidValidity++
+}
+
+// Seen reports whether the email with the given UID has been read
+func (s *Server) Seen(uid int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m := s.find(uid); m != nil {
+		return m.seen
+	}
+	return false
+}
+
+// Logins counts the successful logins
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// find returns the message with the given UID; s.mu must be held
+func (s *Server) find(uid int) *message {
+	for _, m := range s.messages {
+		if m.uid == uid {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			conn.Close()
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// session is the state of one connection
+type session struct {
+	w             *bufio.Writer
+	authenticated bool
+	selected      bool
+}
+
+func (s *Server) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	sess := &session{w: bufio.NewWriter(conn)}
+	sess.reply("* OK IMAP4rev1 imaptest ready")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := fields(strings.TrimRight(line, "\r\n"))
+		if len(args) < 2 {
+			sess.reply("* BAD missing command")
+			continue
+		}
+		tag, command := args[0], strings.ToUpper(args[1])
+		if command == "UID" && len(args) > 2 {
+			command += " " + strings.ToUpper(args[2])
+			args = args[1:]
+		}
+		if s.dispatch(sess, tag, command, args[2:]) {
+			return
+		}
+	}
+}
+
+// dispatch runs one command and reports whether the connection should close
+func (s *Server) dispatch(sess *session, tag, command string, args []string) bool {
+	switch {
+	case command == "CAPABILITY":
+		sess.reply("* CAPABILITY IMAP4rev1")
+	case command == "NOOP":
+	case command == "LOGOUT":
+		sess.reply("* BYE logging out")
+		sess.reply(tag + " OK LOGOUT completed")
+		return true
+	case command == "LOGIN":
+		if len(args) != 2 || args[0] != s.username || args[1] != s.password {
+			sess.reply(tag + " NO [AUTHENTICATIONFAILED] invalid credentials")
+			return false
+		}
+		s.mu.Lock()
+		s.logins++
+		s.mu.Unlock()
+		sess.authenticated = true
+	case !sess.authenticated:
+		sess.reply(tag + " NO not logged in")
+		return false
+	case command == "SELECT" || command == "EXAMINE":
+		if len(args) != 1 || !strings.EqualFold(args[0], "INBOX") {
+			sess.reply(tag + " NO no such mailbox")
+			return false
+		}
+		s.mu.Lock()
+		sess.reply(fmt.Sprintf("* %d EXISTS", len(s.messages)))
+		sess.reply(fmt.Sprintf("* OK [UIDVALIDITY %d] UIDs valid", s.uidValidity))
+		s.mu.Unlock()
+		sess.selected = true
+	case !sess.selected:
+		sess.reply(tag + " NO no mailbox selected")
+		return false
+	case command == "UID SEARCH":
+		s.search(sess, args)
+	case command == "UID FETCH":
+		if len(args) < 2 {
+			sess.reply(tag + " BAD missing arguments")
+			return false
+		}
+		s.fetch(sess, args[0], strings.ToUpper(strings.Join(args[1:], " ")))
+	case command == "UID STORE":
+		if len(args) < 3 || !strings.HasPrefix(strings.ToUpper(args[1]), "+FLAGS") {
+			sess.reply(tag + " BAD only +FLAGS is supported")
+			return false
+		}
+		s.store(sess, args[0], strings.ToUpper(args[1]) == "+FLAGS", strings.Contains(strings.Join(args[2:], " "), `\Seen`))
+	default:
+		sess.reply(tag + " BAD unknown command")
+		return false
+	}
+	sess.reply(tag + " OK " + command + " completed")
+	return false
+}
+
+// search lists the UIDs of every message, narrowed to the unread ones by
+// UNSEEN and to a set of UIDs by UID set
+func (s *Server) search(sess *session, args []string) {
+	unseen, set := false, ""
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "UNSEEN":
+			unseen = true
+		case "UID":
+			if i+1 < len(args) {
+				set = args[i+1]
+				i++
+			}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line := "* SEARCH"
+	for _, m := range s.messages {
+		if (!unseen || !m.seen) && (set == "" || s.inSet(set, m.uid)) {
+			line += " " + strconv.Itoa(m.uid)
+		}
+	}
+	sess.reply(line)
+}
+
+// fetch sends the messages in set. Fetching BODY[] rather than
+// BODY.PEEK[] marks them read.
+func (s *Server) fetch(sess *session, set, items string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.messages {
+		if !s.inSet(set, m.uid) {
+			continue
+		}
+		if !strings.Contains(items, "PEEK") {
+			m.seen = true
+		}
+		fmt.Fprintf(sess.w, "* %d FETCH (UID %d BODY[] {%d}\r\n", i+1, m.uid, len(m.data))
+		sess.w.Write(m.data)
+		sess.reply(")")
+	}
+}
+
+// store adds the \Seen flag to the messages in set, reporting their flags
+// unless silent
+func (s *Server) store(sess *session, set string, report, seen bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.messages {
+		if !s.inSet(set, m.uid) {
+			continue
+		}
+		if seen {
+			m.seen = true
+		}
+		if report {
+			flags := ""
+			if m.seen {
+				flags = `\Seen`
+			}
+			sess.reply(fmt.Sprintf("* %d FETCH (UID %d FLAGS (%s))", i+1, m.uid, flags))
+		}
+	}
+}
+
+// reply writes a response line
+func (sess *session) reply(line string) {
+	sess.w.WriteString(line + "\r\n")
+	sess.w.Flush()
+}
+
+// inSet reports whether uid is in a sequence set such as "1,3:5" or "2:*".
+// As on real servers, * is the highest UID, so "9:*" matches the newest
+// message even when its UID is below 9. s.mu must be held.
+func (s *Server) inSet(set string, uid int) bool {
+	highest := 0
+	if len(s.messages) > 0 {
+		highest = s.messages[len(s.messages)-1].uid
+	}
+	number := func(value string) (int, error) {
+		if value == "*" {
+			return highest, nil
+		}
+		return strconv.Atoi(value)
+	}
+
+	for _, item := range strings.Split(set, ",") {
+		from, to, isRange := strings.Cut(item, ":")
+		low, err := number(from)
+		if err != nil {
+			continue
+		}
+		if !isRange {
+			if low == uid {
+				return true
+			}
+			continue
+		}
+		high, err := number(to)
+		if err != nil {
+			continue
+		}
+		if low > high {
+			low, high = high, low
+		}
+		if uid >= low && uid <= high {
+			return true
+		}
+	}
+	return false
+}
+
+// fields splits a command line into its arguments, unquoting quoted strings
+func fields(line string) []string {
+	var args []string
+	for line = strings.TrimLeft(line, " "); line != ""; line = strings.TrimLeft(line, " ") {
+		if line[0] != '"' {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			args = append(args, line[:end])
+			line = line[end:]
+			continue
+		}
+
+		var arg strings.Builder
+		i := 1
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+			}
+			arg.WriteByte(line[i])
+		}
+		args = append(args, arg.String())
+		line = line[min(i+1, len(line)):]
+	}
+	return args
+}
@@ -0,0 +1,350 @@
+// Package ingest reads issuers' "your statement is ready" emails, uploaded
+// as .eml files or polled from an IMAP mailbox. Each email is read with its
+// issuer's template. When it names one of the cards and the card has an
+// expected statement for that cycle, the statement is filled in; otherwise
+// the email is queued as a suggestion for review.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/extract"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// Where suggestions come from
+const (
+	SourceUpload = "upload"
+	SourceIMAP   = "imap"
+)
+
+// ErrNotPending is returned when accepting or dismissing a suggestion that
+// has already been resolved
+var ErrNotPending = errors.New("suggestion is not pending")
+
+// Ingester turns statement emails into statements and suggestions
+type Ingester struct {
+	cards       repository.CardRepository
+	statements  repository.StatementRepository
+	suggestions repository.SuggestionRepository
+	importer    *importer.Importer
+	clock       clock.Clock
+
+	// OnImported, when set, follows up on each statement an email filled in
+	// or a suggestion was accepted as
+	OnImported func(ctx context.Context, result importer.Result)
+}
+
+// New creates an ingester that matches emails against cards, stores
+// statements into statements and queues the rest into suggestions. A nil
+// clock uses the real time.
+func New(cards repository.CardRepository, statements repository.StatementRepository, suggestions repository.SuggestionRepository, clk clock.Clock) *Ingester {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Ingester{
+		cards:       cards,
+		statements:  statements,
+		suggestions: suggestions,
+		importer:    importer.New(cards, statements, clk),
+		clock:       clk,
+	}
+}
+
+// Outcome reports what reading one email did
+type Outcome struct {
+	// Suggestion is what the email was read as, or nil if it was ignored
+	Suggestion *models.Suggestion `json:"suggestion,omitempty"`
+	// Duplicate is set when the email was read before; Suggestion is what
+	// it was read as then
+	Duplicate bool `json:"duplicate,omitempty"`
+	// Ignored says why the email isn't a statement notice
+	Ignored string `json:"ignored,omitempty"`
+}
+
+// Ingest reads one email from r. A statement it fills in is recorded as a
+// filled suggestion; one that needs review is queued as pending with the
+// reason. Emails without a statement balance are ignored, and an email
+// whose Message-ID was read before returns what it was read as then. Data
+// that isn't an email returns ErrNotEmail.
+func (i *Ingester) Ingest(ctx context.Context, r io.Reader, source string) (Outcome, error) {
+	msg, err := ParseMessage(r)
+	if err != nil {
+		return Outcome{}, err
+	}
+
+	if msg.MessageID != "" {
+		existing, err := i.suggestions.FindByMessageID(ctx, msg.MessageID)
+		if err == nil {
+			return Outcome{Suggestion: &existing, Duplicate: true}, nil
+		}
+		if err != repository.ErrNotFound {
+			return Outcome{}, err
+		}
+	}
+
+	proposal := Read(msg)
+	if missing(proposal, extract.FieldNewBalance) {
+		return Outcome{Ignored: "no statement balance found"}, nil
+	}
+
+	now := i.clock.Now()
+	s := models.Suggestion{
+		Status:         models.SuggestionPending,
+		Source:         source,
+		MessageID:      msg.MessageID,
+		From:           msg.From,
+		Subject:        msg.Subject,
+		Issuer:         proposal.Issuer,
+		LastFour:       proposal.LastFour,
+		StatementDate:  proposal.StatementDate,
+		DueDate:        proposal.DueDate,
+		Amount:         proposal.NewBalance,
+		MinimumPayment: proposal.MinimumPayment,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	received := now
+	if !msg.Date.IsZero() {
+		received = msg.Date
+		utc := received.UTC()
+		s.ReceivedAt = &utc
+	}
+
+	result, err := i.match(ctx, &s, received)
+	if err != nil {
+		return Outcome{}, err
+	}
+	if err := i.suggestions.Create(ctx, &s); err != nil {
+		return Outcome{}, err
+	}
+	if (result.Entered != nil || result.Updated != nil) && i.OnImported != nil {
+		i.OnImported(ctx, result)
+	}
+	return Outcome{Suggestion: &s}, nil
+}
+
+// match picks the card a suggestion is for and fills in the card's expected
+// statement if it has one for the cycle. Otherwise the suggestion is left
+// pending with the reason, or dismissed if the statement was already
+// entered.
+func (i *Ingester) match(ctx context.Context, s *models.Suggestion, received time.Time) (importer.Result, error) {
+	// Notices are sent when the statement closes, so one that doesn't give
+	// the statement date is about the expected statement around the day it
+	// arrived
+	guessDate := s.StatementDate == ""
+	if guessDate {
+		s.StatementDate = received.Format(models.DateFormat)
+	}
+
+	cards, err := i.cards.List(ctx)
+	if err != nil {
+		return importer.Result{}, fmt.Errorf("failed to list cards: %w", err)
+	}
+	card, reason := matchCard(cards, s.LastFour, s.Issuer)
+	if reason != "" {
+		s.Reason = reason
+		return importer.Result{}, nil
+	}
+	s.CardID = &card.ID
+
+	if guessDate {
+		placeholder, err := i.statements.FindExpected(ctx, card.ID, s.StatementDate)
+		if err == nil {
+			s.StatementDate = placeholder.StatementDate
+		} else if err != repository.ErrNotFound {
+			return importer.Result{}, fmt.Errorf("failed to find expected statement: %w", err)
+		}
+	}
+
+	list := []importer.Statement{suggestedStatement(*s)}
+	results, err := i.importer.Import(ctx, list, true)
+	if err != nil {
+		return importer.Result{}, err
+	}
+	preview := results[0]
+	if s.DueDate == "" {
+		s.DueDate = preview.DueDate
+	}
+
+	switch preview.Action {
+	case importer.ActionFill:
+		results, err := i.importer.Import(ctx, list, false)
+		if err != nil {
+			return importer.Result{}, err
+		}
+		s.Status = models.SuggestionFilled
+		s.StatementID = &results[0].StatementID
+		return results[0], nil
+	case importer.ActionUnchanged:
+		s.Status = models.SuggestionDismissed
+		s.StatementID = &preview.StatementID
+		s.Reason = "statement already entered"
+	case importer.ActionUpdate:
+		s.StatementID = &preview.StatementID
+		s.Reason = fmt.Sprintf("%s already has a different statement for %s", card.Name, s.StatementDate)
+	case importer.ActionCreate:
+		s.Reason = fmt.Sprintf("%s has no expected statement around %s", card.Name, s.StatementDate)
+	default:
+		s.Reason = preview.Reason
+	}
+	return importer.Result{}, nil
+}
+
+// matchCard returns the only card ending in lastFour or, when the email
+// doesn't give the card number, the only card named after its issuer
+func matchCard(cards []models.CreditCard, lastFour, issuer string) (models.CreditCard, string) {
+	var matches []models.CreditCard
+	for _, card := range cards {
+		if lastFour != "" && card.LastFour == lastFour {
+			matches = append(matches, card)
+		}
+		if lastFour == "" && issuer != "" && strings.Contains(strings.ToLower(card.Name), strings.ToLower(issuer)) {
+			matches = append(matches, card)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], ""
+	case lastFour == "" && issuer == "":
+		return models.CreditCard{}, "the email doesn't give the card number"
+	case lastFour == "":
+		return models.CreditCard{}, fmt.Sprintf("the email doesn't give the card number and %d cards are named %s", len(matches), issuer)
+	case len(matches) == 0:
+		return models.CreditCard{}, fmt.Sprintf("no card ends in %s", lastFour)
+	default:
+		return models.CreditCard{}, fmt.Sprintf("%d cards end in %s", len(matches), lastFour)
+	}
+}
+
+// suggestedStatement is the statement a suggestion describes
+func suggestedStatement(s models.Suggestion) importer.Statement {
+	stmt := importer.Statement{
+		LastFour:       s.LastFour,
+		StatementDate:  s.StatementDate,
+		DueDate:        s.DueDate,
+		Amount:         s.Amount,
+		MinimumPayment: s.MinimumPayment,
+	}
+	if s.CardID != nil {
+		stmt.CardID = *s.CardID
+	}
+	return stmt
+}
+
+// missing reports whether the proposal lacks field
+func missing(p extract.Proposal, field string) bool {
+	for _, f := range p.Missing {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Suggestions returns the suggestions with the given status, or every
+// suggestion if status is empty, newest first
+func (i *Ingester) Suggestions(ctx context.Context, status string) ([]models.Suggestion, error) {
+	return i.suggestions.List(ctx, status)
+}
+
+// Suggestion returns the suggestion with the given ID or
+// repository.ErrNotFound
+func (i *Ingester) Suggestion(ctx context.Context, id int) (models.Suggestion, error) {
+	return i.suggestions.Get(ctx, id)
+}
+
+// Correction changes what was read from an email before it is stored;
+// fields left out keep the value read
+type Correction struct {
+	CardID         int           `json:"card_id,omitempty"`
+	StatementDate  string        `json:"statement_date,omitempty"`
+	DueDate        string        `json:"due_date,omitempty"`
+	Amount         *models.Money `json:"amount,omitempty"`
+	MinimumPayment *models.Money `json:"minimum_payment,omitempty"`
+}
+
+// Accept stores a pending suggestion as a statement, with any corrections.
+// The statement is created, fills in the card's expected placeholder, or
+// updates the statement already entered for that date. A suggestion that
+// still can't be stored stays pending and the result's Reason says why.
+func (i *Ingester) Accept(ctx context.Context, id int, c Correction) (models.Suggestion, importer.Result, error) {
+	s, err := i.pending(ctx, id)
+	if err != nil {
+		return s, importer.Result{}, err
+	}
+
+	stmt := suggestedStatement(s)
+	if c.CardID != 0 {
+		stmt.CardID = c.CardID
+	}
+	if c.StatementDate != "" {
+		stmt.StatementDate = c.StatementDate
+	}
+	if c.DueDate != "" {
+		stmt.DueDate = c.DueDate
+	}
+	if c.Amount != nil {
+		stmt.Amount = *c.Amount
+	}
+	if c.MinimumPayment != nil {
+		stmt.MinimumPayment = *c.MinimumPayment
+	}
+
+	results, err := i.importer.Import(ctx, []importer.Statement{stmt}, false)
+	if err != nil {
+		return s, importer.Result{}, err
+	}
+	result := results[0]
+	if result.Action == importer.ActionSkip {
+		return s, result, nil
+	}
+
+	s, err = i.resolve(ctx, id, models.SuggestionAccepted, &result.StatementID)
+	if err != nil {
+		return s, result, err
+	}
+	if (result.Entered != nil || result.Updated != nil) && i.OnImported != nil {
+		i.OnImported(ctx, result)
+	}
+	return s, result, nil
+}
+
+// Dismiss marks a pending suggestion as not to be stored
+func (i *Ingester) Dismiss(ctx context.Context, id int) (models.Suggestion, error) {
+	if _, err := i.pending(ctx, id); err != nil {
+		return models.Suggestion{}, err
+	}
+	return i.resolve(ctx, id, models.SuggestionDismissed, nil)
+}
+
+// pending returns the suggestion, or ErrNotPending if it was resolved
+func (i *Ingester) pending(ctx context.Context, id int) (models.Suggestion, error) {
+	s, err := i.suggestions.Get(ctx, id)
+	if err != nil {
+		return s, err
+	}
+	if s.Status != models.SuggestionPending {
+		return s, ErrNotPending
+	}
+	return s, nil
+}
+
+// resolve moves a pending suggestion to status. A suggestion resolved in
+// the meantime returns ErrNotPending.
+func (i *Ingester) resolve(ctx context.Context, id int, status string, statementID *int) (models.Suggestion, error) {
+	s, err := i.suggestions.Resolve(ctx, id, status, statementID, i.clock.Now())
+	if err == repository.ErrNotFound {
+		return s, ErrNotPending
+	}
+	return s, err
+}
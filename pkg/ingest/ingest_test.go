@@ -0,0 +1,288 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/extract"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/importer"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// email writes a plain text email
+func email(from, subject, date, messageID, body string) string {
+	headers := "From: " + from + "\r\nSubject: " + subject + "\r\n"
+	if date != "" {
+		headers += "Date: " + date + "\r\n"
+	}
+	if messageID != "" {
+		headers += "Message-ID: " + messageID + "\r\n"
+	}
+	return headers + "\r\n" + strings.ReplaceAll(body, "\n", "\r\n")
+}
+
+var (
+	tdNotice = email("TD Canada Trust <noreply@td.com>", "Your TD credit card statement is ready",
+		"Sat, 16 Nov 2024 08:00:00 -0500", "<td-2024-11@td.com>",
+		`Your TD Aeroplan Visa Infinite Card ending in 9876 statement is ready.
+Balance: $892.50
+Minimum Payment: $10.00
+Payment Due Date: December 10, 2024`)
+	amexNotice = email("American Express <AmericanExpress@welcome.aexp.com>", "Your Amex Cobalt Card statement is ready",
+		"Fri, 29 Nov 2024 06:12:00 -0500", "<amex-2024-11@aexp.com>",
+		`Account Ending: 71234
+Closing Date: 11/28/24
+Statement Balance: $3,421.89
+Minimum Payment Due: $35.00
+Payment Due Date: 12/23/24`)
+	promotion = email("Offers <offers@td.com>", "Earn 2x points this weekend", "Sat, 16 Nov 2024 09:00:00 -0500",
+		"<promo@td.com>", "Shop with your card ending in 9876 to earn more.")
+)
+
+func TestRead_Issuers(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		text string
+		want extract.Proposal
+	}{
+		{
+			name: "TD",
+			from: "noreply@td.com",
+			text: `Your TD Aeroplan Visa Infinite Card ending in 9876 statement is ready.
+Balance: $892.50
+Minimum Payment: $10.00
+Payment Due Date: December 10, 2024`,
+			want: extract.Proposal{Issuer: "TD", LastFour: "9876", DueDate: "2024-12-10", NewBalance: 89250, MinimumPayment: 1000,
+				Missing: []string{extract.FieldStatementDate}},
+		},
+		{
+			name: "Amex",
+			from: "AmericanExpress@welcome.aexp.com",
+			text: `Account Ending: 71234
+Closing Date: 11/28/24
+Statement Balance: $3,421.89
+Minimum Payment Due: $35.00
+Payment Due Date: 12/23/24`,
+			want: extract.Proposal{Issuer: "Amex", LastFour: "1234", StatementDate: "2024-11-28", DueDate: "2024-12-23",
+				NewBalance: 342189, MinimumPayment: 3500},
+		},
+		{
+			name: "Capital One",
+			from: "capitalone@notification.capitalone.com",
+			text: `Your Quicksilver statement for the billing cycle ending Dec 4, 2024 is ready
+Account ending in 4321
+Statement Balance - $15.00
+Minimum Payment Due - $15.00
+Due Date - Dec 19, 2024`,
+			want: extract.Proposal{Issuer: "Capital One", LastFour: "4321", StatementDate: "2024-12-04", DueDate: "2024-12-19",
+				NewBalance: 1500, MinimumPayment: 1500},
+		},
+		{
+			name: "issuer named in the text",
+			from: "alerts@forwarded.example.net",
+			text: `Your Chase Freedom statement is available. Account ending in 5678.
+Your statement balance is $567.25 and your minimum payment of $40.00 is due on 11/22/2024.`,
+			want: extract.Proposal{Issuer: "Chase", LastFour: "5678", DueDate: "2024-11-22", NewBalance: 56725, MinimumPayment: 4000,
+				Missing: []string{extract.FieldStatementDate}},
+		},
+		{
+			name: "other issuer",
+			from: "no-reply@bank.example",
+			text: `Your card ending in 1111 statement dated October 15, 2024 is ready.
+New balance: $100.00
+Payment is due by October 9, 2024`,
+			want: extract.Proposal{LastFour: "1111", StatementDate: "2024-10-15", DueDate: "2024-10-09", NewBalance: 10000,
+				Missing: []string{extract.FieldMinimumPayment}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Read(Message{From: tt.from, Subject: "Your statement is ready", Text: tt.text})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// setupIngester creates a test database with a TD card ending in 9876 that
+// has an expected statement on 2024-11-15, and an Amex card ending in 1234.
+// It returns the database, the ingester and the statements it followed up on.
+func setupIngester(t *testing.T) (*sql.DB, *Ingester, repository.StatementRepository, *[]models.Statement) {
+	tmpDB := "./test_ingest.db"
+	db, err := database.InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpDB)
+	})
+
+	ctx := context.Background()
+	cards := repository.NewSQLiteCardRepository(db)
+	statements := repository.NewSQLiteStatementRepository(db)
+	for _, card := range []models.CreditCard{
+		{Name: "TD Aeroplan Visa", LastFour: "9876", StatementDay: 15, DaysUntilDue: 25},
+		{Name: "Amex Cobalt", LastFour: "1234", StatementDay: 28, DaysUntilDue: 25},
+	} {
+		if err := cards.Create(ctx, &card); err != nil {
+			t.Fatalf("Failed to create card: %v", err)
+		}
+	}
+	placeholder := models.Statement{CardID: 1, StatementDate: "2024-11-15", DueDate: "2024-12-10", Status: models.StatusExpected}
+	if err := statements.Create(ctx, &placeholder); err != nil {
+		t.Fatalf("Failed to create placeholder: %v", err)
+	}
+
+	clk := clock.NewFixed(time.Date(2024, 11, 30, 12, 0, 0, 0, time.UTC))
+	ingester := New(cards, statements, repository.NewSQLiteSuggestionRepository(db), clk)
+	entered := &[]models.Statement{}
+	ingester.OnImported = func(ctx context.Context, result importer.Result) {
+		if result.Entered != nil {
+			*entered = append(*entered, *result.Entered)
+		}
+	}
+	return db, ingester, statements, entered
+}
+
+func TestIngest_FillsExpected(t *testing.T) {
+	_, ingester, statements, entered := setupIngester(t)
+	ctx := context.Background()
+
+	outcome, err := ingester.Ingest(ctx, strings.NewReader(tdNotice), SourceUpload)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	s := outcome.Suggestion
+	if s == nil || s.Status != models.SuggestionFilled || s.StatementID == nil || *s.StatementID != 1 {
+		t.Fatalf("Expected the expected statement to be filled, got %+v", outcome)
+	}
+	// The notice doesn't give the statement date, so it is the placeholder's
+	if s.StatementDate != "2024-11-15" || s.ReceivedAt == nil || s.Source != SourceUpload || s.Issuer != "TD" {
+		t.Errorf("Unexpected suggestion: %+v", s)
+	}
+
+	stmt, err := statements.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if stmt.Status != models.StatusPending || stmt.Amount.String() != "892.50" || stmt.MinimumPayment.String() != "10.00" {
+		t.Errorf("Unexpected statement: %+v", stmt)
+	}
+	if len(*entered) != 1 || (*entered)[0].ID != 1 {
+		t.Errorf("Expected the filled statement to be followed up, got %+v", *entered)
+	}
+
+	// Reading the same email again changes nothing
+	again, err := ingester.Ingest(ctx, strings.NewReader(tdNotice), SourceIMAP)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if !again.Duplicate || again.Suggestion.ID != s.ID || len(*entered) != 1 {
+		t.Errorf("Expected the earlier suggestion back, got %+v", again)
+	}
+}
+
+func TestIngest_QueuesSuggestions(t *testing.T) {
+	_, ingester, _, entered := setupIngester(t)
+	ctx := context.Background()
+
+	outcome, err := ingester.Ingest(ctx, strings.NewReader(amexNotice), SourceUpload)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	amex := outcome.Suggestion
+	if amex.Status != models.SuggestionPending || amex.CardID == nil || *amex.CardID != 2 ||
+		amex.Reason != "Amex Cobalt has no expected statement around 2024-11-28" {
+		t.Errorf("Expected the Amex statement to wait for review, got %+v", amex)
+	}
+
+	unknown := email("Discover <discover@service.discover.com>", "Your statement is ready", "", "",
+		"Account ending in 5555\nNew Balance: $2,845.67\nPayment Due Date: 12/02/2024")
+	outcome, err = ingester.Ingest(ctx, strings.NewReader(unknown), SourceUpload)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	other := outcome.Suggestion
+	if other.Status != models.SuggestionPending || other.CardID != nil || other.Reason != "no card ends in 5555" {
+		t.Errorf("Expected an unmatched suggestion, got %+v", other)
+	}
+	// Without a Date header the email is taken to have arrived now
+	if other.StatementDate != "2024-11-30" || other.ReceivedAt != nil {
+		t.Errorf("Expected today's statement date, got %+v", other)
+	}
+
+	if outcome, err := ingester.Ingest(ctx, strings.NewReader(promotion), SourceUpload); err != nil || outcome.Suggestion != nil || outcome.Ignored == "" {
+		t.Errorf("Expected the promotion to be ignored, got %+v (%v)", outcome, err)
+	}
+	if _, err := ingester.Ingest(ctx, strings.NewReader("not an email"), SourceUpload); !errors.Is(err, ErrNotEmail) {
+		t.Errorf("Expected ErrNotEmail, got %v", err)
+	}
+	if list, _ := ingester.Suggestions(ctx, models.SuggestionPending); len(list) != 2 {
+		t.Errorf("Expected 2 pending suggestions, got %d", len(list))
+	}
+
+	// Accepting the Amex suggestion stores its statement
+	accepted, result, err := ingester.Accept(ctx, amex.ID, Correction{})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	if result.Action != importer.ActionCreate || accepted.Status != models.SuggestionAccepted || *accepted.StatementID != result.StatementID {
+		t.Errorf("Expected the statement to be created, got %+v and %+v", result, accepted)
+	}
+	if len(*entered) != 1 || (*entered)[0].Amount.String() != "3421.89" {
+		t.Errorf("Expected the accepted statement to be followed up, got %+v", *entered)
+	}
+	if _, _, err := ingester.Accept(ctx, amex.ID, Correction{}); err != ErrNotPending {
+		t.Errorf("Expected ErrNotPending accepting twice, got %v", err)
+	}
+
+	// The unmatched one can't be stored until it is given a card
+	if s, result, err := ingester.Accept(ctx, other.ID, Correction{}); err != nil || result.Action != importer.ActionSkip || s.Status != models.SuggestionPending {
+		t.Errorf("Expected the suggestion to stay pending, got %+v and %+v (%v)", s, result, err)
+	}
+	amount := models.MustParseMoney("2800.00")
+	_, result, err = ingester.Accept(ctx, other.ID, Correction{CardID: 2, StatementDate: "2024-11-05", Amount: &amount})
+	if err != nil || result.Action != importer.ActionCreate || result.CardName != "Amex Cobalt" || result.Amount != amount {
+		t.Errorf("Expected the corrected statement to be created, got %+v (%v)", result, err)
+	}
+
+	if _, err := ingester.Dismiss(ctx, other.ID); err != ErrNotPending {
+		t.Errorf("Expected ErrNotPending dismissing an accepted suggestion, got %v", err)
+	}
+	if _, err := ingester.Dismiss(ctx, 999); err != repository.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestIngest_Dismiss(t *testing.T) {
+	_, ingester, _, _ := setupIngester(t)
+	ctx := context.Background()
+
+	outcome, err := ingester.Ingest(ctx, strings.NewReader(amexNotice), SourceUpload)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	dismissed, err := ingester.Dismiss(ctx, outcome.Suggestion.ID)
+	if err != nil {
+		t.Fatalf("Dismiss failed: %v", err)
+	}
+	if dismissed.Status != models.SuggestionDismissed || dismissed.StatementID != nil {
+		t.Errorf("Unexpected dismissed suggestion: %+v", dismissed)
+	}
+	if _, _, err := ingester.Accept(ctx, dismissed.ID, Correction{}); err != ErrNotPending {
+		t.Errorf("Expected ErrNotPending accepting a dismissed suggestion, got %v", err)
+	}
+}
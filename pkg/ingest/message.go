@@ -0,0 +1,194 @@
+package ingest
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrNotEmail is returned for data that can't be read as an email
+var ErrNotEmail = errors.New("not an email message")
+
+// maxDepth limits how deeply multipart bodies are read
+const maxDepth = 8
+
+// Message is the part of an email that templates read
+type Message struct {
+	MessageID string
+	// From is the sender's address without their name
+	From    string
+	Subject string
+	// Date is when the email was sent, or zero if it doesn't say
+	Date time.Time
+	// Text is the plain text body, or the HTML body without its markup
+	Text string
+}
+
+// ParseMessage reads an RFC 5322 email, such as a .eml file or a message
+// fetched over IMAP
+func ParseMessage(r io.Reader) (Message, error) {
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, fmt.Errorf("%w: %v", ErrNotEmail, err)
+	}
+	if m.Header.Get("From") == "" && m.Header.Get("Subject") == "" {
+		return Message{}, fmt.Errorf("%w: no From or Subject header", ErrNotEmail)
+	}
+
+	decoder := mime.WordDecoder{CharsetReader: charsetReader}
+	msg := Message{
+		MessageID: strings.TrimSpace(m.Header.Get("Message-Id")),
+		From:      m.Header.Get("From"),
+		Subject:   m.Header.Get("Subject"),
+	}
+	if subject, err := decoder.DecodeHeader(msg.Subject); err == nil {
+		msg.Subject = subject
+	}
+	if from, err := (&mail.AddressParser{WordDecoder: &decoder}).Parse(msg.From); err == nil {
+		msg.From = from.Address
+	}
+	msg.Date, _ = m.Header.Date()
+
+	// A body that can't be decoded, such as bad base64 or a multipart body
+	// cut short, won't read any better next time
+	var b bodies
+	if err := b.walk(textproto.MIMEHeader(m.Header), m.Body, 0); err != nil {
+		return Message{}, fmt.Errorf("%w: failed to read body: %v", ErrNotEmail, err)
+	}
+	msg.Text = b.plain
+	if msg.Text == "" {
+		msg.Text = htmlText(b.html)
+	}
+	msg.Text = tidy(msg.Text)
+	return msg, nil
+}
+
+// bodies collects the first plain text and HTML bodies of an email
+type bodies struct {
+	plain, html string
+}
+
+// walk reads a part, descending into multipart ones
+func (b *bodies) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params := "text/plain", map[string]string{}
+	if value := header.Get("Content-Type"); value != "" {
+		var err error
+		if mediaType, params, err = mime.ParseMediaType(value); err != nil {
+			return nil
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxDepth || params["boundary"] == "" {
+			return nil
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := b.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Attached files, such as a PDF of the statement, aren't the notice
+	disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if disposition == "attachment" || (mediaType != "text/plain" && mediaType != "text/html") {
+		return nil
+	}
+	if (mediaType == "text/plain" && b.plain != "") || (mediaType == "text/html" && b.html != "") {
+		return nil
+	}
+
+	data, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	text := decodeCharset(params["charset"], data)
+	if mediaType == "text/plain" {
+		b.plain = text
+	} else {
+		b.html = text
+	}
+	return nil
+}
+
+// transferDecoder undoes a part's Content-Transfer-Encoding
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// decodeCharset converts text to UTF-8. Issuers write in UTF-8, ASCII or
+// Latin-1; Windows-1252 is read as Latin-1, and other charsets as UTF-8.
+func decodeCharset(charset string, data []byte) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	return strings.ToValidUTF8(string(data), "\ufffd")
+}
+
+// charsetReader converts encoded header words for mime.WordDecoder
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decodeCharset(charset, data)), nil
+}
+
+var (
+	// hiddenPattern matches elements whose content isn't shown
+	hiddenPattern = regexp.MustCompile(`(?is)<(?:script|style|head)\b.*?</(?:script|style|head)\s*>`)
+	// breakPattern matches tags that end a line
+	breakPattern = regexp.MustCompile(`(?i)<(?:br|/p|/div|/tr|/li|/h[1-6]|/table)\b[^>]*>`)
+	// tagPattern matches any other tag
+	tagPattern = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// htmlText reduces an HTML body to its text, one line per block, with the
+// cells of a table row on the same line
+func htmlText(s string) string {
+	s = hiddenPattern.ReplaceAllString(s, "")
+	s = breakPattern.ReplaceAllString(s, "\n")
+	s = tagPattern.ReplaceAllString(s, " ")
+	return html.UnescapeString(s)
+}
+
+// tidy collapses the spaces in each line and drops blank lines
+func tidy(s string) string {
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,96 @@
+package ingest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMessage_Multipart(t *testing.T) {
+	raw := strings.ReplaceAll(`From: "TD Canada Trust" <noreply@td.com>
+To: statements@example.com
+Subject: =?UTF-8?B?WW91ciBzdGF0ZW1lbnQgaXMgcmVhZHkg4oCU?= TD Aeroplan
+Date: Sat, 16 Nov 2024 08:00:00 -0500
+Message-ID: <20241116.9876@td.com>
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+Your r=E9sum=E9 of card ending in 9876:
+Balance:   $892.50
+--inner
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: base64
+
+PHA+SWdub3JlZDwvcD4=
+--inner--
+--outer
+Content-Type: text/plain
+Content-Disposition: attachment; filename="terms.txt"
+
+New balance $1.00
+--outer--
+`, "\n", "\r\n")
+
+	msg, err := ParseMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ParseMessage failed: %v", err)
+	}
+	if msg.From != "noreply@td.com" || msg.MessageID != "<20241116.9876@td.com>" {
+		t.Errorf("Unexpected headers: %+v", msg)
+	}
+	if msg.Subject != "Your statement is ready — TD Aeroplan" {
+		t.Errorf("Expected the subject to be decoded, got %q", msg.Subject)
+	}
+	if want := time.Date(2024, 11, 16, 13, 0, 0, 0, time.UTC); !msg.Date.Equal(want) {
+		t.Errorf("Expected date %v, got %v", want, msg.Date)
+	}
+	// The plain text body is preferred and the attachment is left out
+	if want := "Your résumé of card ending in 9876:\nBalance: $892.50"; msg.Text != want {
+		t.Errorf("Expected text %q, got %q", want, msg.Text)
+	}
+}
+
+func TestParseMessage_HTML(t *testing.T) {
+	raw := `From: Chase <no.reply.alerts@chase.com>
+Subject: Your credit card statement is available
+Content-Type: text/html; charset=utf-8
+
+<html><head><style>td { color: blue; }</style></head><body>
+<p>Your statement for the account ending in 5678 is ready.</p>
+<table><tr><td>Statement balance:</td><td>$567.25</td></tr>
+<tr><td>Payment&nbsp;due&nbsp;date:</td><td>11/22/2024</td></tr></table>
+</body></html>`
+
+	msg, err := ParseMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ParseMessage failed: %v", err)
+	}
+	want := "Your statement for the account ending in 5678 is ready.\nStatement balance: $567.25\nPayment due date: 11/22/2024"
+	if msg.Text != want {
+		t.Errorf("Expected text %q, got %q", want, msg.Text)
+	}
+	if !msg.Date.IsZero() {
+		t.Errorf("Expected no date, got %v", msg.Date)
+	}
+}
+
+func TestParseMessage_NotEmail(t *testing.T) {
+	for _, raw := range []string{
+		"%PDF-1.4\n%%EOF\n",
+		"X-Custom: header only\n\nbody",
+		"From: a@example.com\nContent-Transfer-Encoding: base64\n\n!!not base64!!\n",
+		"From: a@example.com\nContent-Type: multipart/alternative; boundary=b\n\n--b\nContent-Type: text/plain\n\nno closing boundary\n",
+	} {
+		if _, err := ParseMessage(strings.NewReader(raw)); !errors.Is(err, ErrNotEmail) {
+			t.Errorf("Expected ErrNotEmail for %q, got %v", raw, err)
+		}
+	}
+}
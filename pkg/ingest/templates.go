@@ -0,0 +1,150 @@
+package ingest
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/extract"
+)
+
+// Template is how one issuer words its "your statement is ready" emails
+type Template struct {
+	Issuer string
+	// Senders are the domains the issuer's emails come from; their
+	// subdomains match too
+	Senders []string
+	// Profile reads the email. Its patterns are tried before the issuer's
+	// statement patterns, the wording notices share and the generic
+	// statement patterns.
+	Profile extract.Profile
+}
+
+// notice is the wording most statement notices share, e.g. "your statement
+// balance is $892.50 and your minimum payment of $10.00 is due December 10"
+var notice = extract.Profile{
+	LastFour: extract.Patterns(
+		`(?:card|account)\s+ending\s+(?:in\s+)?[:\s]*(?:\d+-)?\d*(\d{4})\b`,
+		`ending\s+in[:\s]*(\d{4})\b`,
+	),
+	StatementDate: extract.Patterns(
+		`statement\s+(?:dated|for|of|closing\s+on|closed\s+on)\s+({date})`,
+		`(?:billing\s+)?cycle\s+(?:ended|ending|closed)\s+(?:on\s+)?({date})`,
+	),
+	DueDate: extract.Patterns(
+		`payment\s+due\s+date\s+(?:is|of)\s+({date})`,
+		`\bdue\s+(?:on|by)[:\s]*({date})`,
+		`\bis\s+due\s+({date})`,
+	),
+	NewBalance: extract.Patterns(
+		`(?:statement|new|current)\s+balance\s+(?:is|was|of)[:\s]*({amount})`,
+		`balance\s+due[:\s]*({amount})`,
+	),
+	MinimumPayment: extract.Patterns(
+		`minimum\s+(?:payment|amount)(?:\s+due)?\s+(?:is|of)[:\s]*({amount})`,
+		`minimum\s+due[:\s]*({amount})`,
+	),
+}
+
+// Templates are the issuers whose emails are recognised by sender, covering
+// the cards in the sample data
+var Templates = []Template{
+	{
+		Issuer:  "TD",
+		Senders: []string{"td.com", "tdcanadatrust.com"},
+		Profile: extract.Profile{
+			// TD's notice lists the statement balance as just "Balance"
+			NewBalance: extract.Patterns(`(?m)^balance[:\s]*({amount})`),
+		},
+	},
+	{
+		Issuer:  "Amex",
+		Senders: []string{"americanexpress.com", "aexp.com", "americanexpress.ca"},
+	},
+	{
+		Issuer:  "Chase",
+		Senders: []string{"chase.com", "jpmchase.com"},
+	},
+	{
+		Issuer:  "Capital One",
+		Senders: []string{"capitalone.com", "capitalone.ca"},
+		Profile: extract.Profile{
+			// Capital One sets its figures off with dashes, e.g.
+			// "Statement Balance - $15.00"
+			DueDate:        extract.Patterns(`due\s+date\s*-\s*({date})`),
+			NewBalance:     extract.Patterns(`statement\s+balance\s*-\s*({amount})`),
+			MinimumPayment: extract.Patterns(`minimum\s+payment(?:\s+due)?\s*-\s*({amount})`),
+		},
+	},
+	{
+		Issuer:  "Discover",
+		Senders: []string{"discover.com", "discovercard.com"},
+	},
+	{
+		Issuer:  "Citi",
+		Senders: []string{"citi.com", "citibank.com", "citicards.com"},
+	},
+}
+
+// Read reads a statement notice with the template of the issuer that sent
+// it or, failing that, the issuer its text names. Emails from other issuers
+// are read with the wording notices share.
+func Read(msg Message) extract.Proposal {
+	text := msg.Subject + "\n" + msg.Text
+	if t, ok := templateFor(msg.From, text); ok {
+		return t.profile().Extract(text)
+	}
+	return merge(extract.Profile{}, notice).Extract(text)
+}
+
+// templateFor picks the template by the sender's domain, then by the issuer
+// the text names
+func templateFor(from, text string) (Template, bool) {
+	domain := strings.ToLower(from[strings.LastIndex(from, "@")+1:])
+	for _, t := range Templates {
+		for _, sender := range t.Senders {
+			if domain == sender || strings.HasSuffix(domain, "."+sender) {
+				return t, true
+			}
+		}
+	}
+	for _, t := range Templates {
+		if p, ok := statementProfile(t.Issuer); ok && p.Detect.MatchString(text) {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// profile is the template's patterns followed by the issuer's statement
+// patterns and the shared notice wording
+func (t Template) profile() extract.Profile {
+	p := t.Profile
+	p.Issuer = t.Issuer
+	if statement, ok := statementProfile(t.Issuer); ok {
+		p = merge(p, statement)
+	}
+	return merge(p, notice)
+}
+
+// statementProfile returns extract's profile for an issuer's statements
+func statementProfile(issuer string) (extract.Profile, bool) {
+	for _, p := range extract.Profiles {
+		if p.Issuer == issuer {
+			return p, true
+		}
+	}
+	return extract.Profile{}, false
+}
+
+// merge appends the field patterns of b to those of a
+func merge(a, b extract.Profile) extract.Profile {
+	join := func(x, y []*regexp.Regexp) []*regexp.Regexp {
+		return append(append([]*regexp.Regexp{}, x...), y...)
+	}
+	a.LastFour = join(a.LastFour, b.LastFour)
+	a.StatementDate = join(a.StatementDate, b.StatementDate)
+	a.DueDate = join(a.DueDate, b.DueDate)
+	a.NewBalance = join(a.NewBalance, b.NewBalance)
+	a.MinimumPayment = join(a.MinimumPayment, b.MinimumPayment)
+	return a
+}
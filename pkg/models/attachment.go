@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Attachment is a file kept with a statement, such as the PDF statement
+// downloaded from the issuer. An uploaded attachment has no statement until
+// the details read from it are confirmed.
+type Attachment struct {
+	ID          int    `json:"id"`
+	StatementID *int   `json:"statement_id,omitempty"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// SHA256 is the hex digest of Data
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+	// Data is the file itself; listings leave it empty
+	Data []byte `json:"-"`
+}
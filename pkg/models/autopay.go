@@ -0,0 +1,76 @@
+package models
+
+import "fmt"
+
+// Autopay modes say how much the issuer pulls automatically on the due date
+const (
+	// AutopayNone means payments are made by hand
+	AutopayNone = "none"
+	// AutopayMinimum pays the minimum payment
+	AutopayMinimum = "minimum"
+	// AutopayStatementBalance pays the full statement balance
+	AutopayStatementBalance = "statement_balance"
+	// AutopayFixedAmount pays AutopayAmount, or the balance if that is less
+	AutopayFixedAmount = "fixed_amount"
+)
+
+// AutopayModes lists every valid autopay mode
+var AutopayModes = []string{AutopayNone, AutopayMinimum, AutopayStatementBalance, AutopayFixedAmount}
+
+// ValidAutopay reports whether mode is one of AutopayModes. An empty mode is
+// treated as AutopayNone.
+func ValidAutopay(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, m := range AutopayModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAutopay reports whether the issuer pays the card's statements
+// automatically
+func (c CreditCard) HasAutopay() bool {
+	return c.Autopay != "" && c.Autopay != AutopayNone
+}
+
+// AutopayPayment returns how much autopay will pull for stmt on its due
+// date, taking payments already made into account. It is zero without
+// autopay.
+func (c CreditCard) AutopayPayment(stmt Statement) Money {
+	switch c.Autopay {
+	case AutopayMinimum:
+		return stmt.MinimumOutstanding()
+	case AutopayStatementBalance:
+		return stmt.RemainingBalance
+	case AutopayFixedAmount:
+		if c.AutopayAmount < stmt.RemainingBalance {
+			return c.AutopayAmount
+		}
+		return stmt.RemainingBalance
+	}
+	return 0
+}
+
+// AutopayShortfall returns how much of stmt's remaining balance autopay
+// leaves unpaid. It is zero without autopay, since nothing is expected of it.
+func (c CreditCard) AutopayShortfall(stmt Statement) Money {
+	if !c.HasAutopay() {
+		return 0
+	}
+	return stmt.RemainingBalance - c.AutopayPayment(stmt)
+}
+
+// AutopayWarning describes the part of stmt's remaining balance autopay
+// leaves unpaid, or returns "" if autopay covers it or isn't set up
+func (c CreditCard) AutopayWarning(stmt Statement) string {
+	shortfall := c.AutopayShortfall(stmt)
+	if shortfall <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("Autopay pays %s, leaving %s of the statement balance to pay separately.",
+		c.AutopayPayment(stmt).Display(), shortfall.Display())
+}
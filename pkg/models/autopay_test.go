@@ -0,0 +1,45 @@
+package models
+
+import "testing"
+
+func TestCreditCard_AutopayPayment(t *testing.T) {
+	stmt := Statement{Amount: MustParseMoney("1000.00"), MinimumPayment: MustParseMoney("25.00")}
+	stmt.SetPaidAmount(MustParseMoney("200.00"))
+
+	tests := []struct {
+		name      string
+		card      CreditCard
+		payment   string
+		shortfall string
+	}{
+		{"none", CreditCard{Autopay: AutopayNone}, "0.00", "0.00"},
+		{"unset", CreditCard{}, "0.00", "0.00"},
+		// The minimum is already covered by the 200.00 paid
+		{"minimum", CreditCard{Autopay: AutopayMinimum}, "0.00", "800.00"},
+		{"statement balance", CreditCard{Autopay: AutopayStatementBalance}, "800.00", "0.00"},
+		{"fixed amount", CreditCard{Autopay: AutopayFixedAmount, AutopayAmount: MustParseMoney("300.00")}, "300.00", "500.00"},
+		{"fixed amount over the balance", CreditCard{Autopay: AutopayFixedAmount, AutopayAmount: MustParseMoney("5000.00")}, "800.00", "0.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.card.AutopayPayment(stmt).String(); got != tt.payment {
+				t.Errorf("Expected payment %s, got %s", tt.payment, got)
+			}
+			if got := tt.card.AutopayShortfall(stmt).String(); got != tt.shortfall {
+				t.Errorf("Expected shortfall %s, got %s", tt.shortfall, got)
+			}
+		})
+	}
+}
+
+func TestValidAutopay(t *testing.T) {
+	for _, mode := range append(AutopayModes, "") {
+		if !ValidAutopay(mode) {
+			t.Errorf("Expected %q to be valid", mode)
+		}
+	}
+	if ValidAutopay("full") {
+		t.Error("Expected full to be invalid")
+	}
+}
@@ -2,14 +2,87 @@ package models
 
 import "time"
 
+// StatementEntryGraceDays is how many days before a predicted statement date
+// an entered statement still counts for that cycle, since issuers don't
+// always close on the exact day
+const StatementEntryGraceDays = 7
+
 // CreditCard represents a credit card in the system
 type CreditCard struct {
-	ID           int       `json:"id"`
-	Name         string    `json:"name"`
-	LastFour     string    `json:"last_four"`
-	StatementDay int       `json:"statement_day"`
-	DaysUntilDue int       `json:"days_until_due"`
-	CreditLimit  float64   `json:"credit_limit,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	LastFour         string `json:"last_four"`
+	StatementDay     int    `json:"statement_day"`
+	StatementDayRule string `json:"statement_day_rule"`
+	DaysUntilDue     int    `json:"days_until_due"`
+	CreditLimit      Money  `json:"credit_limit,omitempty"`
+	PaymentPolicy    string `json:"payment_policy"`
+	PaymentLeadDays  int    `json:"payment_lead_days"`
+	// Autopay is the AutopayModes entry the issuer pays statements with
+	Autopay       string `json:"autopay"`
+	AutopayAmount Money  `json:"autopay_amount,omitempty"`
+	// AutopayFundingAccountID is the account autopay pulls from
+	AutopayFundingAccountID *int      `json:"autopay_funding_account_id,omitempty"`
+	CreatedAt               time.Time `json:"created_at"`
+	UpdatedAt               time.Time `json:"updated_at"`
+}
+
+// LeadDays returns how many business days before the due date the card's
+// payments should be made
+func (c CreditCard) LeadDays() int {
+	if c.PaymentLeadDays <= 0 {
+		return DefaultPaymentLeadDays
+	}
+	return c.PaymentLeadDays
+}
+
+// StatementDateIn returns the date the card's statement is expected in the
+// given month according to its StatementDayRule. Every prediction of
+// statement dates goes through here.
+func (c CreditCard) StatementDateIn(year int, month time.Month) time.Time {
+	// Normalise months outside January to December
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	year, month = first.Year(), first.Month()
+
+	last := lastDayOfMonth(year, month)
+	switch c.StatementDayRule {
+	case StatementDayLastDay:
+		return last
+	case StatementDayBusinessDay:
+		return nthBusinessDay(year, month, c.StatementDay)
+	}
+
+	day := c.StatementDay
+	if day > last.Day() {
+		day = last.Day()
+	}
+	if day < 1 {
+		day = 1
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// ExpectedDueDate returns the due date for a statement released on statementDate
+func (c CreditCard) ExpectedDueDate(statementDate time.Time) time.Time {
+	return statementDate.AddDate(0, 0, c.DaysUntilDue)
+}
+
+// NextStatementDate returns the first predicted statement date on or after day
+func (c CreditCard) NextStatementDate(day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	date := c.StatementDateIn(day.Year(), day.Month())
+	if date.Before(day) {
+		date = c.StatementDateIn(day.Year(), day.Month()+1)
+	}
+	return date
+}
+
+// LastStatementDate returns the latest predicted statement date on or before day
+func (c CreditCard) LastStatementDate(day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	date := c.StatementDateIn(day.Year(), day.Month())
+	if date.After(day) {
+		date = c.StatementDateIn(day.Year(), day.Month()-1)
+	}
+	return date
 }
@@ -13,7 +13,7 @@ func TestCreditCardJSONMarshaling(t *testing.T) {
 		LastFour:     "1234",
 		StatementDay: 15,
 		DaysUntilDue: 25,
-		CreditLimit:  5000.00,
+		CreditLimit:  MustParseMoney("5000.00"),
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -53,7 +53,7 @@ func TestCreditCardJSONMarshaling(t *testing.T) {
 	}
 
 	if unmarshaled.CreditLimit != card.CreditLimit {
-		t.Errorf("Expected credit_limit %.2f, got %.2f", card.CreditLimit, unmarshaled.CreditLimit)
+		t.Errorf("Expected credit_limit %s, got %s", card.CreditLimit, unmarshaled.CreditLimit)
 	}
 }
 
@@ -134,7 +134,7 @@ func TestCreditCardWithOptionalFields(t *testing.T) {
 		LastFour:     "8888",
 		StatementDay: 1,
 		DaysUntilDue: 25,
-		CreditLimit:  15000.00,
+		CreditLimit:  MustParseMoney("15000.00"),
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -193,6 +193,74 @@ func TestCreditCardUnmarshalPartial(t *testing.T) {
 
 	// Optional fields should have zero values
 	if card.CreditLimit != 0 {
-		t.Errorf("Expected credit_limit 0, got %.2f", card.CreditLimit)
+		t.Errorf("Expected credit_limit 0, got %s", card.CreditLimit)
+	}
+}
+
+func TestCreditCardStatementDateIn(t *testing.T) {
+	testCases := []struct {
+		name         string
+		statementDay int
+		year         int
+		month        time.Month
+		expected     string
+	}{
+		{"regular day", 15, 2024, time.November, "2024-11-15"},
+		{"31st in 30 day month", 31, 2024, time.April, "2024-04-30"},
+		{"30th in leap February", 30, 2024, time.February, "2024-02-29"},
+		{"29th in non-leap February", 29, 2025, time.February, "2025-02-28"},
+		{"31st in December", 31, 2024, time.December, "2024-12-31"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			card := CreditCard{StatementDay: tc.statementDay}
+			got := card.StatementDateIn(tc.year, tc.month).Format(DateFormat)
+			if got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreditCardExpectedDueDate(t *testing.T) {
+	card := CreditCard{StatementDay: 15, DaysUntilDue: 25}
+	statementDate := time.Date(2024, time.December, 15, 0, 0, 0, 0, time.UTC)
+
+	got := card.ExpectedDueDate(statementDate).Format(DateFormat)
+	if got != "2025-01-09" {
+		t.Errorf("Expected due date 2025-01-09, got %s", got)
+	}
+}
+
+func TestNextAndLastStatementDate(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse(DateFormat, s)
+		return d
+	}
+
+	tests := []struct {
+		statementDay int
+		today        string
+		next, last   string
+	}{
+		{15, "2024-11-10", "2024-11-15", "2024-10-15"},
+		{15, "2024-11-15", "2024-11-15", "2024-11-15"},
+		{15, "2024-11-16", "2024-12-15", "2024-11-15"},
+		{31, "2024-02-10", "2024-02-29", "2024-01-31"},
+		{31, "2024-03-01", "2024-03-31", "2024-02-29"},
+		{5, "2024-12-20", "2025-01-05", "2024-12-05"},
+		{20, "2025-01-10", "2025-01-20", "2024-12-20"},
+	}
+
+	for _, tt := range tests {
+		card := CreditCard{StatementDay: tt.statementDay}
+		today := day(tt.today).Add(15 * time.Hour)
+		if got := card.NextStatementDate(today).Format(DateFormat); got != tt.next {
+			t.Errorf("Day %d on %s: expected next %s, got %s", tt.statementDay, tt.today, tt.next, got)
+		}
+		if got := card.LastStatementDate(today).Format(DateFormat); got != tt.last {
+			t.Errorf("Day %d on %s: expected last %s, got %s", tt.statementDay, tt.today, tt.last, got)
+		}
 	}
 }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// FundingAccount is a bank account card payments are made from, such as a
+// chequing account. Statements and scheduled payments can name the account
+// that pays them.
+type FundingAccount struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Institution string `json:"institution"`
+	LastFour    string `json:"last_four"`
+	// Balance is the account balance as last entered, if it is tracked
+	Balance   *Money    `json:"balance,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks that the account has a name, an optional four digit
+// account suffix and a non-negative tracked balance
+func (a FundingAccount) Validate() error {
+	if a.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(a.Name) > 255 {
+		return errors.New("name must be at most 255 characters")
+	}
+	if a.LastFour != "" {
+		if len(a.LastFour) != 4 {
+			return errors.New("last_four must be exactly 4 digits")
+		}
+		if _, err := strconv.Atoi(a.LastFour); err != nil {
+			return errors.New("last_four must be numeric")
+		}
+	}
+	if a.Balance != nil && *a.Balance < 0 {
+		return errors.New("balance cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestFundingAccount_Validate(t *testing.T) {
+	negative := MustParseMoney("-1.00")
+	balance := MustParseMoney("2500.00")
+
+	tests := []struct {
+		name    string
+		account FundingAccount
+		wantErr bool
+	}{
+		{"valid", FundingAccount{Name: "Chequing", Institution: "Tangerine", LastFour: "0042", Balance: &balance}, false},
+		{"only a name", FundingAccount{Name: "Chequing"}, false},
+		{"missing name", FundingAccount{LastFour: "0042"}, true},
+		{"short last four", FundingAccount{Name: "Chequing", LastFour: "42"}, true},
+		{"non-numeric last four", FundingAccount{Name: "Chequing", LastFour: "abcd"}, true},
+		{"negative balance", FundingAccount{Name: "Chequing", Balance: &negative}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.account.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Income schedule frequencies
+const (
+	// IncomeBiweekly is paid every 14 days from the anchor date
+	IncomeBiweekly = "biweekly"
+	// IncomeSemiMonthly is paid twice a month, on the anchor date's day and
+	// on SecondDay
+	IncomeSemiMonthly = "semi_monthly"
+	// IncomeMonthly is paid on the anchor date's day every month
+	IncomeMonthly = "monthly"
+)
+
+// IncomeFrequencies lists the valid income schedule frequencies
+var IncomeFrequencies = []string{IncomeBiweekly, IncomeSemiMonthly, IncomeMonthly}
+
+// IncomeSchedule is a recurring payday into the account payments are made
+// from. Paydays start on AnchorDate; days past the end of a short month fall
+// on its last day.
+type IncomeSchedule struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Frequency  string `json:"frequency"`
+	AnchorDate string `json:"anchor_date"`
+	// SecondDay is the other day of the month a semi-monthly schedule pays
+	// on, e.g. 15 for the 1st and 15th or 31 for the 15th and last day
+	SecondDay int       `json:"second_day,omitempty"`
+	Amount    Money     `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks that the schedule has a name, a known frequency, a valid
+// anchor date, a positive amount and a second day only when it is
+// semi-monthly
+func (s IncomeSchedule) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	anchor, err := time.Parse(DateFormat, s.AnchorDate)
+	if err != nil {
+		return errors.New("anchor_date must be a valid date (YYYY-MM-DD)")
+	}
+	if s.Amount <= 0 {
+		return errors.New("amount must be greater than 0")
+	}
+
+	switch s.Frequency {
+	case IncomeBiweekly, IncomeMonthly:
+		if s.SecondDay != 0 {
+			return fmt.Errorf("second_day only applies to %s schedules", IncomeSemiMonthly)
+		}
+	case IncomeSemiMonthly:
+		if s.SecondDay < 1 || s.SecondDay > 31 {
+			return errors.New("second_day must be between 1 and 31")
+		}
+		if s.SecondDay == anchor.Day() {
+			return errors.New("second_day must differ from the anchor date's day")
+		}
+	default:
+		return errors.New("frequency must be one of biweekly, semi_monthly or monthly")
+	}
+	return nil
+}
+
+// PaydaysBetween returns the schedule's paydays from from to to inclusive, in
+// order, ignoring weekends and holidays. There are none before the anchor
+// date.
+func (s IncomeSchedule) PaydaysBetween(from, to time.Time) []time.Time {
+	anchor, err := time.Parse(DateFormat, s.AnchorDate)
+	if err != nil {
+		return nil
+	}
+	if from.Before(anchor) {
+		from = anchor
+	}
+
+	var paydays []time.Time
+	switch s.Frequency {
+	case IncomeBiweekly:
+		// Start from the first payday on or after from
+		periods := int(from.Sub(anchor).Hours()/24) / 14
+		for date := anchor.AddDate(0, 0, periods*14); !date.After(to); date = date.AddDate(0, 0, 14) {
+			if !date.Before(from) {
+				paydays = append(paydays, date)
+			}
+		}
+
+	case IncomeSemiMonthly, IncomeMonthly:
+		days := []int{anchor.Day()}
+		if s.Frequency == IncomeSemiMonthly {
+			days = append(days, s.SecondDay)
+			if days[1] < days[0] {
+				days[0], days[1] = days[1], days[0]
+			}
+		}
+		for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(to); month = month.AddDate(0, 1, 0) {
+			for _, day := range days {
+				date := dayOfMonth(month.Year(), month.Month(), day)
+				if date.Before(from) || date.After(to) {
+					continue
+				}
+				// The 30th and 31st are both the last day of February
+				if n := len(paydays); n > 0 && paydays[n-1].Equal(date) {
+					continue
+				}
+				paydays = append(paydays, date)
+			}
+		}
+	}
+	return paydays
+}
+
+// dayOfMonth returns the given day of a month, or its last day if the month
+// is too short
+func dayOfMonth(year int, month time.Month, day int) time.Time {
+	if last := lastDayOfMonth(year, month); day > last.Day() {
+		return last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIncomeSchedule_PaydaysBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule IncomeSchedule
+		from     string
+		to       string
+		expected []string
+	}{
+		{
+			name:     "biweekly",
+			schedule: IncomeSchedule{Frequency: IncomeBiweekly, AnchorDate: "2024-11-01"},
+			from:     "2024-11-10",
+			to:       "2024-12-31",
+			expected: []string{"2024-11-15", "2024-11-29", "2024-12-13", "2024-12-27"},
+		},
+		{
+			name:     "biweekly starts at the anchor",
+			schedule: IncomeSchedule{Frequency: IncomeBiweekly, AnchorDate: "2024-11-15"},
+			from:     "2024-10-01",
+			to:       "2024-11-30",
+			expected: []string{"2024-11-15", "2024-11-29"},
+		},
+		{
+			name:     "semi-monthly 15th and last day",
+			schedule: IncomeSchedule{Frequency: IncomeSemiMonthly, AnchorDate: "2024-01-15", SecondDay: 31},
+			from:     "2024-02-01",
+			to:       "2024-04-15",
+			expected: []string{"2024-02-15", "2024-02-29", "2024-03-15", "2024-03-31", "2024-04-15"},
+		},
+		{
+			name:     "semi-monthly days share the end of February",
+			schedule: IncomeSchedule{Frequency: IncomeSemiMonthly, AnchorDate: "2025-01-30", SecondDay: 31},
+			from:     "2025-02-01",
+			to:       "2025-03-31",
+			expected: []string{"2025-02-28", "2025-03-30", "2025-03-31"},
+		},
+		{
+			name:     "monthly clamps to short months",
+			schedule: IncomeSchedule{Frequency: IncomeMonthly, AnchorDate: "2024-01-31"},
+			from:     "2024-01-01",
+			to:       "2024-04-30",
+			expected: []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, _ := time.Parse(DateFormat, tt.from)
+			to, _ := time.Parse(DateFormat, tt.to)
+
+			var got []string
+			for _, date := range tt.schedule.PaydaysBetween(from, to) {
+				got = append(got, date.Format(DateFormat))
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestIncomeSchedule_Validate(t *testing.T) {
+	valid := IncomeSchedule{Name: "Salary", Frequency: IncomeBiweekly, AnchorDate: "2024-11-01", Amount: MustParseMoney("2500.00")}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid schedule, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(s *IncomeSchedule)
+	}{
+		{"missing name", func(s *IncomeSchedule) { s.Name = "" }},
+		{"unknown frequency", func(s *IncomeSchedule) { s.Frequency = "weekly" }},
+		{"bad anchor date", func(s *IncomeSchedule) { s.AnchorDate = "Nov 1" }},
+		{"no amount", func(s *IncomeSchedule) { s.Amount = 0 }},
+		{"second day on biweekly", func(s *IncomeSchedule) { s.SecondDay = 15 }},
+		{"semi-monthly without second day", func(s *IncomeSchedule) { s.Frequency = IncomeSemiMonthly }},
+		{"semi-monthly same day twice", func(s *IncomeSchedule) { s.Frequency = IncomeSemiMonthly; s.SecondDay = 1 }},
+	}
+	for _, tt := range tests {
+		s := valid
+		tt.modify(&s)
+		if err := s.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
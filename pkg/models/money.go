@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount of currency stored as integer cents so that sums across
+// statements and cards are exact. It marshals to JSON as a decimal number
+// with two places (e.g. 1250.75) and accepts either a JSON number or a
+// decimal string when unmarshaling.
+type Money int64
+
+// ParseMoney parses a decimal string such as "1250.75", "-3" or "0.5" without
+// going through floating point. More than two decimal places is an error.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid amount: empty")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" && (!hasFrac || frac == "") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if hasFrac && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("invalid amount %q: at most 2 decimal places allowed", s)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	var dollars int64
+	if whole != "" {
+		var err error
+		dollars, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil || dollars > (1<<63-1)/100-1 {
+			return 0, fmt.Errorf("invalid amount %q: out of range", s)
+		}
+	}
+
+	frac += strings.Repeat("0", 2-len(frac))
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+
+	total := dollars*100 + cents
+	if negative {
+		total = -total
+	}
+	return Money(total), nil
+}
+
+// ParseDisplayMoney parses an amount the way people and statements write it,
+// with an optional dollar sign and thousands separators, e.g. "$1,250.75" or
+// "-$25.00". It accepts everything Display produces.
+func ParseDisplayMoney(s string) (Money, error) {
+	value := strings.NewReplacer("$", "", ",", "", " ", "").Replace(s)
+	if strings.Count(value, "-") > 1 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return ParseMoney(value)
+}
+
+// MustParseMoney is like ParseMoney but panics on error. It is intended for
+// constants in sample data and tests.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Cents returns the amount in cents
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// String formats the amount as a decimal with two places, e.g. "1250.75"
+func (m Money) String() string {
+	cents := int64(m)
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Display formats the amount for people, with a dollar sign and thousands
+// separators, e.g. "$1,250.75"
+func (m Money) Display() string {
+	cents := int64(m)
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	dollars := fmt.Sprintf("%d", cents/100)
+	for i := len(dollars) - 3; i > 0; i -= 3 {
+		dollars = dollars[:i] + "," + dollars[i:]
+	}
+	return fmt.Sprintf("%s$%s.%02d", sign, dollars, cents%100)
+}
+
+// MarshalJSON encodes the amount as an exact decimal JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or decimal string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as integer cents
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan reads an amount stored as integer cents
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*m = Money(v)
+	case nil:
+		*m = 0
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+// isDigits reports whether s contains only ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		input     string
+		expected  Money
+		expectErr bool
+	}{
+		{"1250.75", 125075, false},
+		{"0.01", 1, false},
+		{"0.1", 10, false},
+		{".5", 50, false},
+		{"100", 10000, false},
+		{"-3.50", -350, false},
+		{"+42.00", 4200, false},
+		{" 7.25 ", 725, false},
+		{"1.005", 0, true},
+		{"1e3", 0, true},
+		{"12.", 0, true},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1,000.00", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseMoney(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %d", tc.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error for %q: %v", tc.input, err)
+			}
+			if got != tc.expected {
+				t.Errorf("Expected %d cents, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	testCases := map[Money]string{
+		0:       "0.00",
+		5:       "0.05",
+		125075:  "1250.75",
+		-350:    "-3.50",
+		1000000: "10000.00",
+	}
+
+	for m, expected := range testCases {
+		if got := m.String(); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	}
+}
+
+func TestMoneyDisplay(t *testing.T) {
+	testCases := map[Money]string{
+		0:          "$0.00",
+		3500:       "$35.00",
+		125075:     "$1,250.75",
+		-350:       "-$3.50",
+		100000000:  "$1,000,000.00",
+		1234567890: "$12,345,678.90",
+	}
+
+	for m, expected := range testCases {
+		if got := m.Display(); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	}
+}
+
+func TestParseDisplayMoney(t *testing.T) {
+	testCases := map[string]Money{
+		"$1,250.75":      125075,
+		"-$25.00":        -2500,
+		"$ 0.99":         99,
+		"1250.75":        125075,
+		"$12,345,678.90": 1234567890,
+	}
+
+	for input, expected := range testCases {
+		got, err := ParseDisplayMoney(input)
+		if err != nil || got != expected {
+			t.Errorf("ParseDisplayMoney(%q): expected %d cents, got %d (%v)", input, expected, got, err)
+		}
+	}
+	for _, m := range []Money{0, 125075, -350, 1234567890} {
+		if got, err := ParseDisplayMoney(m.Display()); err != nil || got != m {
+			t.Errorf("Expected %s to round trip, got %d (%v)", m.Display(), got, err)
+		}
+	}
+	for _, input := range []string{"$1.2.3", "--$5.00", ""} {
+		if _, err := ParseDisplayMoney(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var payload struct {
+		Number Money `json:"number"`
+		String Money `json:"string"`
+	}
+
+	err := json.Unmarshal([]byte(`{"number": 1250.75, "string": "892.50"}`), &payload)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if payload.Number != 125075 {
+		t.Errorf("Expected 125075 cents, got %d", payload.Number)
+	}
+	if payload.String != 89250 {
+		t.Errorf("Expected 89250 cents, got %d", payload.String)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if string(data) != `{"number":1250.75,"string":892.50}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"number": 1.999}`), &payload); err == nil {
+		t.Error("Expected error for amount with more than two decimal places")
+	}
+}
+
+func TestMoneySumIsExact(t *testing.T) {
+	// 0.10 + 0.20 is the classic float64 drift case
+	total := MustParseMoney("0.10") + MustParseMoney("0.20")
+	if total != MustParseMoney("0.30") {
+		t.Errorf("Expected 0.30, got %s", total)
+	}
+
+	var sum Money
+	for i := 0; i < 1000; i++ {
+		sum += MustParseMoney("1250.75")
+	}
+	if sum.String() != "1250750.00" {
+		t.Errorf("Expected 1250750.00, got %s", sum)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	var m Money
+	if err := m.Scan(int64(125075)); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if m != 125075 {
+		t.Errorf("Expected 125075, got %d", m)
+	}
+
+	if err := m.Scan(nil); err != nil || m != 0 {
+		t.Errorf("Expected NULL to scan as 0, got %d (%v)", m, err)
+	}
+
+	if err := m.Scan(1250.75); err == nil {
+		t.Error("Expected error scanning a float")
+	}
+
+	value, err := Money(125075).Value()
+	if err != nil || value != int64(125075) {
+		t.Errorf("Expected Value 125075, got %v (%v)", value, err)
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Payment is a payment made towards a statement. A statement can have several
+// partial payments.
+type Payment struct {
+	ID                 int       `json:"id"`
+	StatementID        int       `json:"statement_id"`
+	Amount             Money     `json:"amount"`
+	PaymentDate        string    `json:"payment_date"`
+	SourceAccount      string    `json:"source_account,omitempty"`
+	ConfirmationNumber string    `json:"confirmation_number,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// Validate checks that the payment has a positive amount and a valid ISO
+// payment date
+func (p Payment) Validate() error {
+	if p.Amount <= 0 {
+		return errors.New("amount must be greater than 0")
+	}
+	if p.PaymentDate == "" {
+		return errors.New("payment_date is required")
+	}
+	if _, err := time.Parse(DateFormat, p.PaymentDate); err != nil {
+		return errors.New("payment_date must be a valid date (YYYY-MM-DD)")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package models
+
+// Payment policies decide when a card's statement should be paid
+const (
+	// PaymentPolicyLeadTime pays PaymentLeadDays business days before the
+	// due date
+	PaymentPolicyLeadTime = "lead_time"
+	// PaymentPolicyStatementDay pays as soon as the statement is released
+	PaymentPolicyStatementDay = "statement_day"
+	// PaymentPolicyPayday pays on the first payday after the statement date
+	// that still leaves PaymentLeadDays business days before the due date
+	PaymentPolicyPayday = "payday"
+)
+
+// PaymentPolicies lists every valid payment policy
+var PaymentPolicies = []string{PaymentPolicyLeadTime, PaymentPolicyStatementDay, PaymentPolicyPayday}
+
+// DefaultPaymentLeadDays is the number of business days a payment is made
+// before the due date when a card doesn't set its own
+const DefaultPaymentLeadDays = 5
+
+// MaxPaymentLeadDays is the longest lead time a card can ask for
+const MaxPaymentLeadDays = 20
+
+// ValidPaymentPolicy reports whether policy is one of PaymentPolicies. An
+// empty policy is treated as PaymentPolicyLeadTime.
+func ValidPaymentPolicy(policy string) bool {
+	if policy == "" {
+		return true
+	}
+	for _, p := range PaymentPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPaymentLeadDays reports whether days is an acceptable lead time. Zero
+// means DefaultPaymentLeadDays.
+func ValidPaymentLeadDays(days int) bool {
+	return days >= 0 && days <= MaxPaymentLeadDays
+}
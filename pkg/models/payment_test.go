@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestPaymentValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		payment Payment
+		wantErr string
+	}{
+		{"valid", Payment{Amount: MustParseMoney("50.00"), PaymentDate: "2024-11-20"}, ""},
+		{"zero amount", Payment{PaymentDate: "2024-11-20"}, "amount must be greater than 0"},
+		{"negative amount", Payment{Amount: -1, PaymentDate: "2024-11-20"}, "amount must be greater than 0"},
+		{"missing date", Payment{Amount: 100}, "payment_date is required"},
+		{"invalid date", Payment{Amount: 100, PaymentDate: "2024-02-30"}, "payment_date must be a valid date (YYYY-MM-DD)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.payment.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestStatementRemainingBalance(t *testing.T) {
+	tests := []struct {
+		amount, paid, want string
+	}{
+		{"100.00", "0.00", "100.00"},
+		{"100.00", "40.25", "59.75"},
+		{"100.00", "100.00", "0.00"},
+		{"100.00", "120.00", "0.00"},
+	}
+
+	for _, tt := range tests {
+		stmt := Statement{Amount: MustParseMoney(tt.amount)}
+		stmt.SetPaidAmount(MustParseMoney(tt.paid))
+		if stmt.RemainingBalance != MustParseMoney(tt.want) {
+			t.Errorf("Amount %s paid %s: expected remaining %s, got %s", tt.amount, tt.paid, tt.want, stmt.RemainingBalance)
+		}
+	}
+}
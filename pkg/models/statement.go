@@ -1,6 +1,16 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// DateFormat is the layout used for all statement and payment dates
+const DateFormat = "2006-01-02"
+
+// MinimumReminderLeadDays is how many days before the due date an unpaid
+// minimum payment triggers an urgent reminder
+const MinimumReminderLeadDays = 3
 
 // Statement represents a credit card statement
 type Statement struct {
@@ -8,12 +18,84 @@ type Statement struct {
 	CardID               int        `json:"card_id"`
 	StatementDate        string     `json:"statement_date"`
 	DueDate              string     `json:"due_date"`
-	Amount               float64    `json:"amount"`
+	Amount               Money      `json:"amount"`
+	MinimumPayment       Money      `json:"minimum_payment"`
+	CurrentBalance       *Money     `json:"current_balance,omitempty"`
 	Status               string     `json:"status"`
+	PaidAmount           Money      `json:"paid_amount"`
+	RemainingBalance     Money      `json:"remaining_balance"`
 	NotifiedStatement    bool       `json:"notified_statement"`
 	NotifiedPayment      bool       `json:"notified_payment"`
+	NotifiedMinimum      bool       `json:"notified_minimum"`
 	ReviewedAt           *time.Time `json:"reviewed_at,omitempty"`
 	ScheduledPaymentDate *string    `json:"scheduled_payment_date,omitempty"`
+	FundingAccountID     *int       `json:"funding_account_id,omitempty"`
+	StatusChangedAt      *time.Time `json:"status_changed_at,omitempty"`
+	StatusChangedBy      *string    `json:"status_changed_by,omitempty"`
 	CreatedAt            time.Time  `json:"created_at"`
 	UpdatedAt            time.Time  `json:"updated_at"`
 }
+
+// SetPaidAmount sets the total paid so far and the remaining balance, which
+// never goes below zero when the statement is overpaid
+func (s *Statement) SetPaidAmount(paid Money) {
+	s.PaidAmount = paid
+	s.RemainingBalance = s.Amount - paid
+	if s.RemainingBalance < 0 {
+		s.RemainingBalance = 0
+	}
+}
+
+// IsCovered reports whether the payments made cover the statement amount
+func (s Statement) IsCovered() bool {
+	return s.Amount > 0 && s.PaidAmount >= s.Amount
+}
+
+// MinimumOutstanding returns how much of the minimum payment is still unpaid
+func (s Statement) MinimumOutstanding() Money {
+	if s.PaidAmount >= s.MinimumPayment {
+		return 0
+	}
+	return s.MinimumPayment - s.PaidAmount
+}
+
+// Validate checks the fields required to store a statement: a card, valid
+// ISO statement and due dates with the due date after the statement date, a
+// positive amount, a minimum payment no larger than the amount and a
+// non-negative current balance
+func (s Statement) Validate() error {
+	if s.CardID == 0 {
+		return errors.New("card_id is required")
+	}
+	if s.StatementDate == "" {
+		return errors.New("statement_date is required")
+	}
+	if s.DueDate == "" {
+		return errors.New("due_date is required")
+	}
+	if s.Amount <= 0 {
+		return errors.New("amount must be greater than 0")
+	}
+	if s.MinimumPayment < 0 {
+		return errors.New("minimum_payment cannot be negative")
+	}
+	if s.MinimumPayment > s.Amount {
+		return errors.New("minimum_payment cannot be more than amount")
+	}
+	if s.CurrentBalance != nil && *s.CurrentBalance < 0 {
+		return errors.New("current_balance cannot be negative")
+	}
+
+	statementDate, err := time.Parse(DateFormat, s.StatementDate)
+	if err != nil {
+		return errors.New("statement_date must be a valid date (YYYY-MM-DD)")
+	}
+	dueDate, err := time.Parse(DateFormat, s.DueDate)
+	if err != nil {
+		return errors.New("due_date must be a valid date (YYYY-MM-DD)")
+	}
+	if !dueDate.After(statementDate) {
+		return errors.New("due_date must be after statement_date")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Statement day rules describe how a card's statement date falls each month
+const (
+	// StatementDayFixed closes on StatementDay, or the last day of months
+	// that are too short
+	StatementDayFixed = "fixed"
+	// StatementDayLastDay closes on the last day of every month
+	StatementDayLastDay = "last_day"
+	// StatementDayBusinessDay closes on the StatementDay-th business day
+	// (Monday to Friday) of the month
+	StatementDayBusinessDay = "business_day"
+)
+
+// StatementDayRules lists every valid statement day rule
+var StatementDayRules = []string{StatementDayFixed, StatementDayLastDay, StatementDayBusinessDay}
+
+// ValidStatementDayRule reports whether rule is one of StatementDayRules. An
+// empty rule is treated as StatementDayFixed.
+func ValidStatementDayRule(rule string) bool {
+	if rule == "" {
+		return true
+	}
+	for _, r := range StatementDayRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// StatementDayFor returns the StatementDay to store for a card following rule
+// whose statement was released on date
+func StatementDayFor(rule string, date time.Time) (int, error) {
+	switch rule {
+	case "", StatementDayFixed:
+		return date.Day(), nil
+	case StatementDayLastDay:
+		return 31, nil
+	case StatementDayBusinessDay:
+		if !IsWeekday(date) {
+			return 0, fmt.Errorf("statement_date must be a business day for the %s rule", rule)
+		}
+		n := 0
+		for day := 1; day <= date.Day(); day++ {
+			if IsWeekday(time.Date(date.Year(), date.Month(), day, 0, 0, 0, 0, time.UTC)) {
+				n++
+			}
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("invalid statement_day_rule %q", rule)
+	}
+}
+
+// IsWeekday reports whether date falls Monday to Friday
+func IsWeekday(date time.Time) bool {
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// lastDayOfMonth returns the last day of the given month
+func lastDayOfMonth(year int, month time.Month) time.Time {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+}
+
+// nthBusinessDay returns the nth weekday of the month, or its last weekday
+// if the month has fewer than n
+func nthBusinessDay(year int, month time.Month, n int) time.Time {
+	if n < 1 {
+		n = 1
+	}
+	last := lastDayOfMonth(year, month)
+	var found time.Time
+	for d := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC); !d.After(last); d = d.AddDate(0, 0, 1) {
+		if !IsWeekday(d) {
+			continue
+		}
+		found = d
+		n--
+		if n == 0 {
+			break
+		}
+	}
+	return found
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatementDateIn_Rules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		day      int
+		year     int
+		month    time.Month
+		expected string
+	}{
+		// Fixed days clamp to the end of short months
+		{"fixed 29th leap year", StatementDayFixed, 29, 2024, time.February, "2024-02-29"},
+		{"fixed 29th common year", StatementDayFixed, 29, 2023, time.February, "2023-02-28"},
+		{"fixed 31st century non-leap", StatementDayFixed, 31, 2100, time.February, "2100-02-28"},
+		{"fixed 31st 400-year leap", StatementDayFixed, 31, 2000, time.February, "2000-02-29"},
+		{"fixed 31st in June", StatementDayFixed, 31, 2024, time.June, "2024-06-30"},
+		{"empty rule is fixed", "", 15, 2024, time.November, "2024-11-15"},
+
+		// Last day ignores the stored day
+		{"last day leap February", StatementDayLastDay, 31, 2024, time.February, "2024-02-29"},
+		{"last day common February", StatementDayLastDay, 31, 2025, time.February, "2025-02-28"},
+		{"last day 1900 February", StatementDayLastDay, 0, 1900, time.February, "1900-02-28"},
+		{"last day April", StatementDayLastDay, 31, 2024, time.April, "2024-04-30"},
+		{"last day December", StatementDayLastDay, 31, 2024, time.December, "2024-12-31"},
+
+		// Business days count Monday to Friday from the 1st
+		{"1st business day on the 1st", StatementDayBusinessDay, 1, 2024, time.February, "2024-02-01"},
+		{"1st business day after a weekend", StatementDayBusinessDay, 1, 2025, time.February, "2025-02-03"},
+		{"3rd business day over a weekend", StatementDayBusinessDay, 3, 2024, time.February, "2024-02-05"},
+		{"21st business day is leap day", StatementDayBusinessDay, 21, 2024, time.February, "2024-02-29"},
+		{"21st business day is leap day 2028", StatementDayBusinessDay, 21, 2028, time.February, "2028-02-29"},
+		{"20th business day 2100", StatementDayBusinessDay, 20, 2100, time.February, "2100-02-26"},
+		{"past the last business day clamps", StatementDayBusinessDay, 23, 2025, time.February, "2025-02-28"},
+		{"15th business day", StatementDayBusinessDay, 15, 2024, time.November, "2024-11-21"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := CreditCard{StatementDay: tt.day, StatementDayRule: tt.rule}
+			got := card.StatementDateIn(tt.year, tt.month).Format(DateFormat)
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStatementDateIn_NormalisesMonth(t *testing.T) {
+	card := CreditCard{StatementDay: 31, StatementDayRule: StatementDayLastDay}
+	if got := card.StatementDateIn(2024, 0).Format(DateFormat); got != "2023-12-31" {
+		t.Errorf("Expected 2023-12-31, got %s", got)
+	}
+	card = CreditCard{StatementDay: 1, StatementDayRule: StatementDayBusinessDay}
+	if got := card.StatementDateIn(2023, 14).Format(DateFormat); got != "2024-02-01" {
+		t.Errorf("Expected 2024-02-01, got %s", got)
+	}
+}
+
+func TestNextAndLastStatementDate_Rules(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse(DateFormat, s)
+		return d
+	}
+
+	tests := []struct {
+		rule       string
+		day        int
+		today      string
+		next, last string
+	}{
+		{StatementDayLastDay, 31, "2024-02-29", "2024-02-29", "2024-02-29"},
+		{StatementDayLastDay, 31, "2024-03-01", "2024-03-31", "2024-02-29"},
+		{StatementDayLastDay, 31, "2025-02-28", "2025-02-28", "2025-02-28"},
+		{StatementDayBusinessDay, 1, "2025-02-01", "2025-02-03", "2025-01-01"},
+		{StatementDayBusinessDay, 21, "2024-03-01", "2024-03-29", "2024-02-29"},
+	}
+
+	for _, tt := range tests {
+		card := CreditCard{StatementDay: tt.day, StatementDayRule: tt.rule}
+		if got := card.NextStatementDate(day(tt.today)).Format(DateFormat); got != tt.next {
+			t.Errorf("%s %d on %s: expected next %s, got %s", tt.rule, tt.day, tt.today, tt.next, got)
+		}
+		if got := card.LastStatementDate(day(tt.today)).Format(DateFormat); got != tt.last {
+			t.Errorf("%s %d on %s: expected last %s, got %s", tt.rule, tt.day, tt.today, tt.last, got)
+		}
+	}
+}
+
+func TestStatementDayFor(t *testing.T) {
+	tests := []struct {
+		rule    string
+		date    string
+		want    int
+		wantErr bool
+	}{
+		{StatementDayFixed, "2024-02-29", 29, false},
+		{"", "2024-11-15", 15, false},
+		{StatementDayLastDay, "2024-02-29", 31, false},
+		{StatementDayBusinessDay, "2024-02-05", 3, false},
+		{StatementDayBusinessDay, "2024-02-29", 21, false},
+		{StatementDayBusinessDay, "2024-11-23", 0, true}, // a Saturday
+		{"weekly", "2024-11-15", 0, true},
+	}
+
+	for _, tt := range tests {
+		date, _ := time.Parse(DateFormat, tt.date)
+		got, err := StatementDayFor(tt.rule, date)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s %s: expected an error", tt.rule, tt.date)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s %s: expected %d, got %d (%v)", tt.rule, tt.date, tt.want, got, err)
+		}
+
+		// The derived day must predict the date it came from
+		card := CreditCard{StatementDay: got, StatementDayRule: tt.rule}
+		if predicted := card.StatementDateIn(date.Year(), date.Month()); !predicted.Equal(date) {
+			t.Errorf("%s %s: day %d predicts %s", tt.rule, tt.date, got, predicted.Format(DateFormat))
+		}
+	}
+}
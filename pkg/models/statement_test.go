@@ -12,7 +12,7 @@ func TestStatementJSONMarshaling(t *testing.T) {
 		CardID:             5,
 		StatementDate:      "2024-11-01",
 		DueDate:            "2024-11-15",
-		Amount:             1250.75,
+		Amount:             MustParseMoney("1250.75"),
 		Status:             "pending",
 		NotifiedStatement:  false,
 		NotifiedPayment:    false,
@@ -51,7 +51,7 @@ func TestStatementJSONMarshaling(t *testing.T) {
 	}
 
 	if unmarshaled.Amount != stmt.Amount {
-		t.Errorf("Expected amount %.2f, got %.2f", stmt.Amount, unmarshaled.Amount)
+		t.Errorf("Expected amount %s, got %s", stmt.Amount, unmarshaled.Amount)
 	}
 
 	if unmarshaled.Status != stmt.Status {
@@ -73,7 +73,7 @@ func TestStatementJSONTags(t *testing.T) {
 		CardID:             2,
 		StatementDate:      "2024-10-01",
 		DueDate:            "2024-10-15",
-		Amount:             500.00,
+		Amount:             MustParseMoney("500.00"),
 		Status:             "paid",
 		NotifiedStatement:  true,
 		NotifiedPayment:    true,
@@ -140,8 +140,8 @@ func TestStatementUnmarshalPartial(t *testing.T) {
 		t.Errorf("Expected due_date '2024-09-30', got '%s'", stmt.DueDate)
 	}
 
-	if stmt.Amount != 2500.00 {
-		t.Errorf("Expected amount 2500.00, got %.2f", stmt.Amount)
+	if stmt.Amount != MustParseMoney("2500.00") {
+		t.Errorf("Expected amount 2500.00, got %s", stmt.Amount)
 	}
 
 	// Fields not in JSON should have zero values
@@ -171,7 +171,7 @@ func TestStatementStatusValues(t *testing.T) {
 			CardID:        1,
 			StatementDate: "2024-11-01",
 			DueDate:       "2024-11-15",
-			Amount:        1000.00,
+			Amount:        MustParseMoney("1000.00"),
 			Status:        status,
 			CreatedAt:     time.Now(),
 			UpdatedAt:     time.Now(),
@@ -213,7 +213,7 @@ func TestStatementBooleanFields(t *testing.T) {
 				CardID:             1,
 				StatementDate:      "2024-11-01",
 				DueDate:            "2024-11-15",
-				Amount:             750.00,
+				Amount:             MustParseMoney("750.00"),
 				Status:             "pending",
 				NotifiedStatement:  tc.notifiedStatement,
 				NotifiedPayment:    tc.notifiedPayment,
@@ -244,12 +244,12 @@ func TestStatementBooleanFields(t *testing.T) {
 }
 
 func TestStatementAmountPrecision(t *testing.T) {
-	amounts := []float64{
-		0.01,
-		1.99,
-		100.50,
-		1250.75,
-		9999.99,
+	amounts := []string{
+		"0.01",
+		"1.99",
+		"100.50",
+		"1250.75",
+		"9999.99",
 	}
 
 	for _, amount := range amounts {
@@ -258,7 +258,7 @@ func TestStatementAmountPrecision(t *testing.T) {
 			CardID:        1,
 			StatementDate: "2024-11-01",
 			DueDate:       "2024-11-15",
-			Amount:        amount,
+			Amount:        MustParseMoney(amount),
 			Status:        "pending",
 			CreatedAt:     time.Now(),
 			UpdatedAt:     time.Now(),
@@ -266,17 +266,87 @@ func TestStatementAmountPrecision(t *testing.T) {
 
 		data, err := json.Marshal(stmt)
 		if err != nil {
-			t.Fatalf("Failed to marshal statement with amount %.2f: %v", amount, err)
+			t.Fatalf("Failed to marshal statement with amount %s: %v", amount, err)
+		}
+
+		// The amount is written exactly as entered
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			t.Fatalf("Failed to unmarshal raw statement: %v", err)
+		}
+		if string(raw["amount"]) != amount {
+			t.Errorf("Expected amount JSON %s, got %s", amount, raw["amount"])
 		}
 
 		var unmarshaled Statement
 		err = json.Unmarshal(data, &unmarshaled)
 		if err != nil {
-			t.Fatalf("Failed to unmarshal statement with amount %.2f: %v", amount, err)
+			t.Fatalf("Failed to unmarshal statement with amount %s: %v", amount, err)
 		}
 
-		if unmarshaled.Amount != amount {
-			t.Errorf("Amount precision lost: expected %.2f, got %.2f", amount, unmarshaled.Amount)
+		if unmarshaled.Amount != stmt.Amount {
+			t.Errorf("Amount precision lost: expected %s, got %s", stmt.Amount, unmarshaled.Amount)
 		}
 	}
 }
+
+func TestStatementValidate(t *testing.T) {
+	valid := Statement{CardID: 1, StatementDate: "2024-02-15", DueDate: "2024-03-11", Amount: 100}
+
+	testCases := []struct {
+		name     string
+		modify   func(s *Statement)
+		expected string
+	}{
+		{"valid", func(s *Statement) {}, ""},
+		{"missing card", func(s *Statement) { s.CardID = 0 }, "card_id is required"},
+		{"missing statement date", func(s *Statement) { s.StatementDate = "" }, "statement_date is required"},
+		{"missing due date", func(s *Statement) { s.DueDate = "" }, "due_date is required"},
+		{"zero amount", func(s *Statement) { s.Amount = 0 }, "amount must be greater than 0"},
+		{"bad statement date", func(s *Statement) { s.StatementDate = "2024-02-30" }, "statement_date must be a valid date (YYYY-MM-DD)"},
+		{"bad due date", func(s *Statement) { s.DueDate = "03/11/2024" }, "due_date must be a valid date (YYYY-MM-DD)"},
+		{"due before statement", func(s *Statement) { s.DueDate = "2024-02-01" }, "due_date must be after statement_date"},
+		{"due on statement date", func(s *Statement) { s.DueDate = "2024-02-15" }, "due_date must be after statement_date"},
+		{"minimum payment", func(s *Statement) { s.MinimumPayment = 35 }, ""},
+		{"minimum equals amount", func(s *Statement) { s.MinimumPayment = 100 }, ""},
+		{"negative minimum", func(s *Statement) { s.MinimumPayment = -1 }, "minimum_payment cannot be negative"},
+		{"minimum over amount", func(s *Statement) { s.MinimumPayment = 101 }, "minimum_payment cannot be more than amount"},
+		{"current balance", func(s *Statement) { b := Money(250); s.CurrentBalance = &b }, ""},
+		{"negative current balance", func(s *Statement) { b := Money(-1); s.CurrentBalance = &b }, "current_balance cannot be negative"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stmt := valid
+			tc.modify(&stmt)
+			err := stmt.Validate()
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("Expected error %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestStatementMinimumOutstanding(t *testing.T) {
+	stmt := Statement{Amount: MustParseMoney("1250.75"), MinimumPayment: MustParseMoney("35.00")}
+
+	if got := stmt.MinimumOutstanding(); got != MustParseMoney("35.00") {
+		t.Errorf("Expected 35.00 outstanding, got %s", got)
+	}
+
+	stmt.SetPaidAmount(MustParseMoney("20.00"))
+	if got := stmt.MinimumOutstanding(); got != MustParseMoney("15.00") {
+		t.Errorf("Expected 15.00 outstanding, got %s", got)
+	}
+
+	stmt.SetPaidAmount(MustParseMoney("50.00"))
+	if got := stmt.MinimumOutstanding(); got != 0 {
+		t.Errorf("Expected nothing outstanding, got %s", got)
+	}
+}
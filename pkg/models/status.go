@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Statement statuses
+const (
+	StatusPending = "pending"
+	StatusPaid    = "paid"
+	StatusOverdue = "overdue"
+	// StatusExpected marks a placeholder for a predicted statement whose
+	// amount hasn't been entered yet. Entering the statement moves it to
+	// one of Statuses.
+	StatusExpected = "expected"
+)
+
+// Statuses lists every status a statement can be given
+var Statuses = []string{StatusPending, StatusPaid, StatusOverdue}
+
+// transitions lists the status changes allowed without reopening. Moving a
+// paid statement back to unpaid, or an overdue one back to pending, is a
+// correction and needs an explicit reopen.
+var transitions = map[string][]string{
+	StatusPending: {StatusPaid, StatusOverdue},
+	StatusOverdue: {StatusPaid},
+	StatusPaid:    {},
+}
+
+// reopenTransitions lists the extra status changes allowed when reopening
+var reopenTransitions = map[string][]string{
+	StatusPaid:    {StatusPending, StatusOverdue},
+	StatusOverdue: {StatusPending},
+}
+
+// StatusChange records who moved a statement between statuses and when
+type StatusChange struct {
+	ID          int       `json:"id"`
+	StatementID int       `json:"statement_id"`
+	FromStatus  string    `json:"from_status"`
+	ToStatus    string    `json:"to_status"`
+	ChangedBy   string    `json:"changed_by"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// ValidStatus reports whether status is one of Statuses
+func ValidStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckStatusTransition returns an error if a statement can't move from one
+// status to another. Staying in the same status is always allowed.
+func CheckStatusTransition(from, to string, reopen bool) error {
+	if !ValidStatus(to) {
+		return fmt.Errorf("invalid status %q", to)
+	}
+	if from == to {
+		return nil
+	}
+	if from == StatusExpected {
+		return errors.New("cannot change the status of an expected statement; enter its amount instead")
+	}
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	if reopen {
+		for _, allowed := range reopenTransitions[from] {
+			if allowed == to {
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot change status from %s to %s", from, to)
+	}
+	if _, ok := reopenTransitions[from]; ok {
+		return fmt.Errorf("cannot change status from %s to %s without reopening", from, to)
+	}
+	return fmt.Errorf("cannot change status from %s to %s", from, to)
+}
+
+// IsPastDue reports whether the statement is still pending after its due
+// date. today is an ISO date, so the comparison is lexical.
+func (s Statement) IsPastDue(today string) bool {
+	return s.Status == StatusPending && s.DueDate < today
+}
+
+// EvaluateOverdue marks a pending statement past its due date as overdue. It
+// only changes the value in memory and reports whether it did.
+func (s *Statement) EvaluateOverdue(today string) bool {
+	if !s.IsPastDue(today) {
+		return false
+	}
+	s.Status = StatusOverdue
+	return true
+}
@@ -0,0 +1,70 @@
+package models
+
+import "testing"
+
+func TestCheckStatusTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		reopen   bool
+		allowed  bool
+	}{
+		{StatusPending, StatusPending, false, true},
+		{StatusPending, StatusPaid, false, true},
+		{StatusPending, StatusOverdue, false, true},
+		{StatusOverdue, StatusPaid, false, true},
+		{StatusOverdue, StatusPending, false, false},
+		{StatusOverdue, StatusPending, true, true},
+		{StatusPaid, StatusPending, false, false},
+		{StatusPaid, StatusOverdue, false, false},
+		{StatusPaid, StatusPending, true, true},
+		{StatusPaid, StatusOverdue, true, true},
+		{StatusPaid, StatusPaid, true, true},
+		{StatusPending, "cancelled", false, false},
+		{"unknown", StatusPaid, false, false},
+		{StatusExpected, StatusPaid, false, false},
+		{StatusExpected, StatusPending, true, false},
+		{StatusPending, StatusExpected, false, false},
+	}
+
+	for _, tt := range tests {
+		err := CheckStatusTransition(tt.from, tt.to, tt.reopen)
+		if tt.allowed && err != nil {
+			t.Errorf("%s -> %s (reopen=%v): expected allowed, got %v", tt.from, tt.to, tt.reopen, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("%s -> %s (reopen=%v): expected an error", tt.from, tt.to, tt.reopen)
+		}
+	}
+}
+
+func TestCheckStatusTransition_ReopenHint(t *testing.T) {
+	err := CheckStatusTransition(StatusPaid, StatusPending, false)
+	if err == nil || err.Error() != "cannot change status from paid to pending without reopening" {
+		t.Errorf("Expected reopen hint, got %v", err)
+	}
+}
+
+func TestEvaluateOverdue(t *testing.T) {
+	tests := []struct {
+		status  string
+		dueDate string
+		want    string
+	}{
+		{StatusPending, "2024-11-14", StatusOverdue},
+		{StatusPending, "2024-11-15", StatusPending}, // due today is not yet overdue
+		{StatusPending, "2024-11-16", StatusPending},
+		{StatusPaid, "2024-11-01", StatusPaid},
+		{StatusOverdue, "2024-11-01", StatusOverdue},
+	}
+
+	for _, tt := range tests {
+		stmt := Statement{Status: tt.status, DueDate: tt.dueDate}
+		changed := stmt.EvaluateOverdue("2024-11-15")
+		if stmt.Status != tt.want {
+			t.Errorf("%s due %s: expected %s, got %s", tt.status, tt.dueDate, tt.want, stmt.Status)
+		}
+		if changed != (tt.status != tt.want) {
+			t.Errorf("%s due %s: unexpected changed=%v", tt.status, tt.dueDate, changed)
+		}
+	}
+}
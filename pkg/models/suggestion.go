@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Suggestion statuses
+const (
+	// SuggestionPending is waiting to be accepted or dismissed
+	SuggestionPending = "pending"
+	// SuggestionFilled filled in a card's expected statement on arrival
+	SuggestionFilled = "filled"
+	// SuggestionAccepted was stored as a statement after review
+	SuggestionAccepted = "accepted"
+	// SuggestionDismissed was rejected, or its statement was already entered
+	SuggestionDismissed = "dismissed"
+)
+
+// SuggestionStatuses lists every status a suggestion can have
+var SuggestionStatuses = []string{SuggestionPending, SuggestionFilled, SuggestionAccepted, SuggestionDismissed}
+
+// Suggestion is a statement read from an issuer's "your statement is ready"
+// email. It fills in the card's expected statement straight away when it
+// can, and otherwise waits for review.
+type Suggestion struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	// Source is where the email came from, "upload" or "imap"
+	Source     string     `json:"source"`
+	MessageID  string     `json:"message_id,omitempty"`
+	From       string     `json:"from"`
+	Subject    string     `json:"subject"`
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+	// Issuer is the template that read the email, or empty if none matched
+	Issuer         string `json:"issuer,omitempty"`
+	CardID         *int   `json:"card_id,omitempty"`
+	LastFour       string `json:"last_four,omitempty"`
+	StatementDate  string `json:"statement_date,omitempty"`
+	DueDate        string `json:"due_date,omitempty"`
+	Amount         Money  `json:"amount"`
+	MinimumPayment Money  `json:"minimum_payment"`
+	// Reason says why the suggestion wasn't filled in automatically
+	Reason string `json:"reason,omitempty"`
+	// StatementID is the statement the suggestion was stored as
+	StatementID *int      `json:"statement_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsSuggestionStatus reports whether status is one of SuggestionStatuses
+func IsSuggestionStatus(status string) bool {
+	for _, s := range SuggestionStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
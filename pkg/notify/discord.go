@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrWebhookNotConfigured is returned when sending without a webhook URL
+var ErrWebhookNotConfigured = errors.New("discord webhook URL is not configured")
+
+// Default retry settings for the Discord client
+const (
+	DefaultMaxRetries  = 3
+	DefaultBaseBackoff = time.Second
+	DefaultMaxBackoff  = 30 * time.Second
+)
+
+// Embed colors used for the different notification types
+const (
+	ColorInfo    = 0x3B82F6
+	ColorWarning = 0xF59E0B
+	ColorDanger  = 0xEF4444
+)
+
+// EmbedField is a name/value pair displayed inside an embed
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// EmbedFooter is the small text displayed at the bottom of an embed
+type EmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// Embed is a Discord rich embed
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       int          `json:"color,omitempty"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+	Footer      *EmbedFooter `json:"footer,omitempty"`
+	Timestamp   string       `json:"timestamp,omitempty"`
+}
+
+// WebhookMessage is the payload posted to a Discord webhook
+type WebhookMessage struct {
+	Content  string  `json:"content,omitempty"`
+	Username string  `json:"username,omitempty"`
+	Embeds   []Embed `json:"embeds,omitempty"`
+}
+
+// StatusError is returned when Discord responds with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("discord webhook returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether the request should be retried
+func (e *StatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// DiscordClient posts messages to a Discord webhook, retrying with backoff
+// when Discord is rate limiting (429) or failing (5xx)
+type DiscordClient struct {
+	HTTPClient  *http.Client
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	mu         sync.RWMutex
+	webhookURL string
+}
+
+// NewDiscordClient creates a Discord client with the default retry settings
+func NewDiscordClient(webhookURL string) *DiscordClient {
+	return &DiscordClient{
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+		MaxRetries:  DefaultMaxRetries,
+		BaseBackoff: DefaultBaseBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+		webhookURL:  webhookURL,
+	}
+}
+
+// WebhookURL returns the webhook URL currently in use
+func (c *DiscordClient) WebhookURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.webhookURL
+}
+
+// SetWebhookURL replaces the webhook URL, e.g. after the settings are updated
+func (c *DiscordClient) SetWebhookURL(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.webhookURL = url
+}
+
+// Enabled reports whether a webhook URL is configured
+func (c *DiscordClient) Enabled() bool {
+	return c.WebhookURL() != ""
+}
+
+// Send posts a message to the webhook. It returns nil only after Discord
+// responds with a 2xx status.
+func (c *DiscordClient) Send(ctx context.Context, msg WebhookMessage) error {
+	url := c.WebhookURL()
+	if url == "" {
+		return ErrWebhookNotConfigured
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook message: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		retryAfter, err := c.post(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return err
+		}
+		if attempt == c.MaxRetries {
+			break
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	return fmt.Errorf("discord webhook failed after %d attempts: %w", c.MaxRetries+1, lastErr)
+}
+
+// post performs a single webhook request and returns how long Discord asked
+// us to wait before retrying, if it said so
+func (c *DiscordClient) post(ctx context.Context, url string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("failed to send webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+
+	return parseRetryAfter(resp, respBody), &StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       string(respBody),
+	}
+}
+
+// backoff returns the exponential backoff delay for the given attempt
+func (c *DiscordClient) backoff(attempt int) time.Duration {
+	wait := c.BaseBackoff << attempt
+	if c.MaxBackoff > 0 && (wait > c.MaxBackoff || wait <= 0) {
+		wait = c.MaxBackoff
+	}
+	return wait
+}
+
+// parseRetryAfter reads the delay requested by a 429 response, either from
+// the Retry-After header or from the retry_after field of the JSON body
+func parseRetryAfter(resp *http.Response, body []byte) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+
+	if header := resp.Header.Get("Retry-After"); header != "" {
+		if seconds, err := strconv.ParseFloat(header, 64); err == nil && seconds > 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	var rateLimit struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.Unmarshal(body, &rateLimit); err == nil && rateLimit.RetryAfter > 0 {
+		return time.Duration(rateLimit.RetryAfter * float64(time.Second))
+	}
+
+	return 0
+}
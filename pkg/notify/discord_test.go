@@ -0,0 +1,181 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client pointed at url with short backoffs
+func newTestClient(url string) *DiscordClient {
+	client := NewDiscordClient(url)
+	client.BaseBackoff = time.Millisecond
+	client.MaxBackoff = 10 * time.Millisecond
+	return client
+}
+
+func TestDiscordClientSend_Success(t *testing.T) {
+	var received WebhookMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected Content-Type 'application/json', got '%s'", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode webhook body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	msg := WebhookMessage{Embeds: []Embed{{Title: "Test", Fields: []EmbedField{{Name: "Card", Value: "Amex"}}}}}
+
+	if err := client.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if len(received.Embeds) != 1 || received.Embeds[0].Title != "Test" {
+		t.Errorf("Expected embed 'Test' to be received, got %+v", received.Embeds)
+	}
+}
+
+func TestDiscordClientSend_NotConfigured(t *testing.T) {
+	client := NewDiscordClient("")
+
+	err := client.Send(context.Background(), WebhookMessage{Content: "hello"})
+	if !errors.Is(err, ErrWebhookNotConfigured) {
+		t.Errorf("Expected ErrWebhookNotConfigured, got %v", err)
+	}
+}
+
+func TestDiscordClientSend_RetriesOnServerError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	if err := client.Send(context.Background(), WebhookMessage{Content: "hello"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestDiscordClientSend_RetriesOnRateLimit(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.01, "global": false}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	if err := client.Send(context.Background(), WebhookMessage{Content: "hello"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestDiscordClientSend_GivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.MaxRetries = 2
+
+	err := client.Send(context.Background(), WebhookMessage{Content: "hello"})
+	if err == nil {
+		t.Fatal("Expected error after exhausting retries")
+	}
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected StatusError with 503, got %v", err)
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestDiscordClientSend_NoRetryOnClientError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message": "Cannot send an empty message"}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	err := client.Send(context.Background(), WebhookMessage{})
+	if err == nil {
+		t.Fatal("Expected error for 400 response")
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+func TestDiscordClientSend_ContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.BaseBackoff = time.Hour
+	client.MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := client.Send(ctx, WebhookMessage{Content: "hello"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestDiscordClientSetWebhookURL(t *testing.T) {
+	client := NewDiscordClient("")
+	if client.Enabled() {
+		t.Error("Expected client without URL to be disabled")
+	}
+
+	client.SetWebhookURL("https://discord.com/api/webhooks/123/abc")
+	if !client.Enabled() {
+		t.Error("Expected client with URL to be enabled")
+	}
+	if client.WebhookURL() != "https://discord.com/api/webhooks/123/abc" {
+		t.Errorf("Unexpected webhook URL %q", client.WebhookURL())
+	}
+}
@@ -0,0 +1,434 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/planner"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// Notifier sends statement notifications to Discord and records on the
+// statement that they were delivered
+type Notifier struct {
+	db       *sql.DB
+	dialect  database.Dialect
+	client   *DiscordClient
+	calendar *holidays.Calendar
+	holidays repository.HolidayRepository
+	income   repository.IncomeRepository
+	clock    clock.Clock
+}
+
+// Option configures optional Notifier dependencies
+type Option func(*Notifier)
+
+// WithCalendar recommends payment dates around the federal holidays of the
+// regions of cal instead of holidays.DefaultRegions
+func WithCalendar(cal *holidays.Calendar) Option {
+	return func(n *Notifier) {
+		n.calendar = cal
+	}
+}
+
+// NewNotifier creates a notifier that reads statements, holidays and paydays
+// from db and sends messages through client. A nil clock uses the real
+// time.
+func NewNotifier(db *sql.DB, client *DiscordClient, clk clock.Clock, opts ...Option) *Notifier {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	n := &Notifier{
+		db:       db,
+		dialect:  database.DialectOf(db),
+		client:   client,
+		calendar: holidays.NewCalendar(holidays.DefaultRegions, nil),
+		holidays: repository.NewHolidayRepository(db),
+		income:   repository.NewIncomeRepository(db),
+		clock:    clk,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// Client returns the underlying Discord client
+func (n *Notifier) Client() *DiscordClient {
+	return n.client
+}
+
+// Enabled reports whether notifications can be sent
+func (n *Notifier) Enabled() bool {
+	return n != nil && n.client != nil && n.client.Enabled()
+}
+
+// NotifyStatement announces a newly entered statement and sets
+// notified_statement once Discord accepts the message
+func (n *Notifier) NotifyStatement(ctx context.Context, statementID int) error {
+	card, stmt, err := n.loadStatement(ctx, statementID)
+	if err != nil {
+		return err
+	}
+	if stmt.NotifiedStatement {
+		return nil
+	}
+
+	rec, err := n.recommend(ctx, card, stmt)
+	if err != nil {
+		return err
+	}
+
+	msg := WebhookMessage{Embeds: []Embed{StatementEmbed(card, stmt, rec)}}
+	if err := n.client.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send statement notification for statement %d: %w", statementID, err)
+	}
+
+	return n.markNotified(ctx, "notified_statement", statementID)
+}
+
+// NotifyPayment sends a payment reminder for a statement, or a check that
+// autopay ran for cards on autopay, and sets notified_payment once Discord
+// accepts the message. today is used to say how far off the due date is.
+func (n *Notifier) NotifyPayment(ctx context.Context, statementID int, today time.Time) error {
+	card, stmt, err := n.loadStatement(ctx, statementID)
+	if err != nil {
+		return err
+	}
+	if stmt.NotifiedPayment {
+		return nil
+	}
+
+	rec, err := n.recommend(ctx, card, stmt)
+	if err != nil {
+		return err
+	}
+
+	embed := PaymentReminderEmbed(card, stmt, rec, today)
+	if card.HasAutopay() {
+		embed = AutopayCheckEmbed(card, stmt, rec, today)
+	}
+	msg := WebhookMessage{Embeds: []Embed{embed}}
+	if err := n.client.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send payment reminder for statement %d: %w", statementID, err)
+	}
+
+	return n.markNotified(ctx, "notified_payment", statementID)
+}
+
+// NotifyMinimumDue sends the urgent reminder for a statement whose minimum
+// payment is still unpaid close to the due date, and sets notified_minimum
+// once Discord accepts the message. Nothing is sent if the minimum has been
+// paid or autopay will pay at least the minimum.
+func (n *Notifier) NotifyMinimumDue(ctx context.Context, statementID int, today time.Time) error {
+	card, stmt, err := n.loadStatement(ctx, statementID)
+	if err != nil {
+		return err
+	}
+	if stmt.NotifiedMinimum || stmt.MinimumOutstanding() == 0 {
+		return nil
+	}
+	if card.HasAutopay() && card.AutopayPayment(stmt) >= stmt.MinimumOutstanding() {
+		return nil
+	}
+
+	rec, err := n.recommend(ctx, card, stmt)
+	if err != nil {
+		return err
+	}
+
+	msg := WebhookMessage{Embeds: []Embed{MinimumDueEmbed(card, stmt, rec, today)}}
+	if err := n.client.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send minimum payment reminder for statement %d: %w", statementID, err)
+	}
+
+	return n.markNotified(ctx, "notified_minimum", statementID)
+}
+
+// NotifyStatementExpected tells the user a card's statement should have been
+// released so they can log in and enter it
+func (n *Notifier) NotifyStatementExpected(ctx context.Context, card models.CreditCard, statementDate time.Time) error {
+	msg := WebhookMessage{Embeds: []Embed{StatementExpectedEmbed(card, statementDate)}}
+	if err := n.client.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send statement expected alert for card %d: %w", card.ID, err)
+	}
+	log.Printf("Discord statement expected alert sent for card %d (%s)", card.ID, statementDate.Format(models.DateFormat))
+	return nil
+}
+
+// loadStatement fetches a statement and the card it belongs to
+func (n *Notifier) loadStatement(ctx context.Context, statementID int) (models.CreditCard, models.Statement, error) {
+	query := `
+		SELECT s.id, s.card_id, s.statement_date, s.due_date, s.amount_cents,
+		       s.minimum_payment_cents, s.current_balance_cents,
+		       (SELECT CAST(COALESCE(SUM(p.amount_cents), 0) AS BIGINT) FROM payments p WHERE p.statement_id = s.id),
+		       s.status, s.notified_statement, s.notified_payment, s.notified_minimum, s.scheduled_payment_date,
+		       c.id, c.name, c.last_four, c.statement_day, c.statement_day_rule, c.days_until_due,
+		       c.payment_policy, c.payment_lead_days, c.autopay, c.autopay_amount_cents
+		FROM statements s
+		JOIN credit_cards c ON c.id = s.card_id
+		WHERE s.id = ?
+	`
+
+	var card models.CreditCard
+	var stmt models.Statement
+	var paid models.Money
+	var currentBalance sql.NullInt64
+	var notifiedMinimum sql.NullBool
+	var scheduledPaymentDate sql.NullString
+
+	err := n.db.QueryRowContext(ctx, n.dialect.Rebind(query), statementID).Scan(
+		&stmt.ID,
+		&stmt.CardID,
+		&stmt.StatementDate,
+		&stmt.DueDate,
+		&stmt.Amount,
+		&stmt.MinimumPayment,
+		&currentBalance,
+		&paid,
+		&stmt.Status,
+		&stmt.NotifiedStatement,
+		&stmt.NotifiedPayment,
+		&notifiedMinimum,
+		&scheduledPaymentDate,
+		&card.ID,
+		&card.Name,
+		&card.LastFour,
+		&card.StatementDay,
+		&card.StatementDayRule,
+		&card.DaysUntilDue,
+		&card.PaymentPolicy,
+		&card.PaymentLeadDays,
+		&card.Autopay,
+		&card.AutopayAmount,
+	)
+	if err != nil {
+		return card, stmt, fmt.Errorf("failed to load statement %d: %w", statementID, err)
+	}
+
+	stmt.SetPaidAmount(paid)
+	stmt.NotifiedMinimum = notifiedMinimum.Bool
+	if currentBalance.Valid {
+		balance := models.Money(currentBalance.Int64)
+		stmt.CurrentBalance = &balance
+	}
+	if scheduledPaymentDate.Valid {
+		stmt.ScheduledPaymentDate = &scheduledPaymentDate.String
+	}
+
+	return card, stmt, nil
+}
+
+// recommend works out the recommended payment date for stmt following the
+// card's payment policy, the built-in and uploaded holidays and the paydays.
+// A statement with invalid dates gets an empty recommendation rather than an
+// error so it is still announced.
+func (n *Notifier) recommend(ctx context.Context, card models.CreditCard, stmt models.Statement) (recommend.Recommendation, error) {
+	cal, err := n.calendar.Load(ctx, n.holidays)
+	if err != nil {
+		return recommend.Recommendation{}, err
+	}
+	paydays, err := planner.LoadPaydays(ctx, n.income, cal)
+	if err != nil {
+		return recommend.Recommendation{}, err
+	}
+	rec, _ := recommend.ForStatement(card, stmt, cal, paydays)
+	return rec, nil
+}
+
+// markNotified flips one of the notification flags on a statement
+func (n *Notifier) markNotified(ctx context.Context, column string, statementID int) error {
+	query := "UPDATE statements SET " + column + " = TRUE, updated_at = ? WHERE id = ?"
+	if _, err := n.db.ExecContext(ctx, n.dialect.Rebind(query), n.clock.Now(), statementID); err != nil {
+		return fmt.Errorf("failed to mark statement %d as notified: %w", statementID, err)
+	}
+	log.Printf("Discord notification sent for statement %d (%s)", statementID, column)
+	return nil
+}
+
+// StatementEmbed builds the embed announcing a new statement
+func StatementEmbed(card models.CreditCard, stmt models.Statement, rec recommend.Recommendation) Embed {
+	description := "A new statement has been recorded. Schedule the payment on or before the recommended date."
+	if card.HasAutopay() {
+		description = fmt.Sprintf("A new statement has been recorded. Autopay will pay %s on the due date.", card.AutopayPayment(stmt).Display())
+		if warning := card.AutopayWarning(stmt); warning != "" {
+			description += " " + warning
+		}
+	}
+
+	return Embed{
+		Title:       fmt.Sprintf("New statement: %s", card.Name),
+		Description: description,
+		Color:       ColorInfo,
+		Fields:      statementFields(card, stmt, rec),
+		Footer:      &EmbedFooter{Text: "Credit Card Payment Tracker"},
+	}
+}
+
+// StatementExpectedEmbed builds the embed prompting the user to enter a
+// statement that is predicted to have been released
+func StatementExpectedEmbed(card models.CreditCard, statementDate time.Time) Embed {
+	return Embed{
+		Title:       fmt.Sprintf("Statement expected: %s", card.Name),
+		Description: "A new statement should be available. Log in to your card account and enter the statement amount and due date.",
+		Color:       ColorInfo,
+		Fields: []EmbedField{
+			{Name: "Card", Value: card.Name, Inline: true},
+			{Name: "Last Four", Value: card.LastFour, Inline: true},
+			{Name: "Statement Date", Value: statementDate.Format(models.DateFormat), Inline: true},
+			{Name: "Expected Due Date", Value: card.ExpectedDueDate(statementDate).Format(models.DateFormat), Inline: true},
+		},
+		Footer: &EmbedFooter{Text: "Credit Card Payment Tracker"},
+	}
+}
+
+// PaymentReminderEmbed builds the embed reminding the user to pay a statement
+func PaymentReminderEmbed(card models.CreditCard, stmt models.Statement, rec recommend.Recommendation, today time.Time) Embed {
+	description := reminderSummary(stmt, today) + " Today is the recommended payment date. Schedule the payment if you haven't already."
+	color := ColorWarning
+	if stmt.ScheduledPaymentDate != nil {
+		description = fmt.Sprintf("%s A payment is scheduled for %s.", reminderSummary(stmt, today), *stmt.ScheduledPaymentDate)
+		color = ColorInfo
+	}
+	if daysUntilDue(stmt, today) < 0 && stmt.MinimumOutstanding() > 0 {
+		color = ColorDanger
+	}
+
+	return Embed{
+		Title:       fmt.Sprintf("Payment reminder: %s", card.Name),
+		Description: description,
+		Color:       color,
+		Fields:      statementFields(card, stmt, rec),
+		Footer:      &EmbedFooter{Text: "Credit Card Payment Tracker"},
+	}
+}
+
+// AutopayCheckEmbed builds the embed sent on the due date of a card on
+// autopay, asking the user to check that the payment went through
+func AutopayCheckEmbed(card models.CreditCard, stmt models.Statement, rec recommend.Recommendation, today time.Time) Embed {
+	description := fmt.Sprintf("%s Autopay should have paid %s on %s; check that it went through and record the payment.",
+		reminderSummary(stmt, today), card.AutopayPayment(stmt).Display(), stmt.DueDate)
+	color := ColorWarning
+	if warning := card.AutopayWarning(stmt); warning != "" {
+		description += " " + warning
+		color = ColorDanger
+	}
+
+	return Embed{
+		Title:       fmt.Sprintf("Verify autopay: %s", card.Name),
+		Description: description,
+		Color:       color,
+		Fields:      statementFields(card, stmt, rec),
+		Footer:      &EmbedFooter{Text: "Credit Card Payment Tracker"},
+	}
+}
+
+// MinimumDueEmbed builds the urgent embed sent when not even the minimum
+// payment has been made close to the due date
+func MinimumDueEmbed(card models.CreditCard, stmt models.Statement, rec recommend.Recommendation, today time.Time) Embed {
+	title := fmt.Sprintf("Minimum payment due: %s", card.Name)
+	if daysUntilDue(stmt, today) < 0 {
+		title = fmt.Sprintf("Minimum payment overdue: %s", card.Name)
+	}
+
+	description := reminderSummary(stmt, today) + " Pay at least the minimum to avoid a late fee."
+	if stmt.ScheduledPaymentDate != nil {
+		description += fmt.Sprintf(" A payment is scheduled for %s; make sure it covers the minimum.", *stmt.ScheduledPaymentDate)
+	}
+
+	return Embed{
+		Title:       title,
+		Description: description,
+		Color:       ColorDanger,
+		Fields:      statementFields(card, stmt, rec),
+		Footer:      &EmbedFooter{Text: "Credit Card Payment Tracker"},
+	}
+}
+
+// reminderSummary describes what is owed and when, e.g. "Minimum $35.00 due
+// in 3 days, full balance $1,250.75."
+func reminderSummary(stmt models.Statement, today time.Time) string {
+	// Recompute the remaining balance in case stmt wasn't loaded from the
+	// database
+	stmt.SetPaidAmount(stmt.PaidAmount)
+	when := dueIn(daysUntilDue(stmt, today))
+
+	switch {
+	case stmt.MinimumOutstanding() > 0:
+		return fmt.Sprintf("Minimum %s %s, full balance %s.",
+			stmt.MinimumOutstanding().Display(), when, stmt.RemainingBalance.Display())
+	case stmt.MinimumPayment > 0:
+		return fmt.Sprintf("Minimum payment made; remaining balance %s %s.", stmt.RemainingBalance.Display(), when)
+	default:
+		return fmt.Sprintf("Full balance %s %s.", stmt.RemainingBalance.Display(), when)
+	}
+}
+
+// daysUntilDue returns the number of days from today to the due date, which
+// is negative once the due date has passed
+func daysUntilDue(stmt models.Statement, today time.Time) int {
+	dueDate, err := time.Parse(models.DateFormat, stmt.DueDate)
+	if err != nil {
+		return 0
+	}
+	day, _ := time.Parse(models.DateFormat, today.Format(models.DateFormat))
+	return int(dueDate.Sub(day).Hours() / 24)
+}
+
+// dueIn phrases a number of days until the due date
+func dueIn(days int) string {
+	switch {
+	case days == 0:
+		return "due today"
+	case days == 1:
+		return "due tomorrow"
+	case days > 1:
+		return fmt.Sprintf("due in %d days", days)
+	case days == -1:
+		return "1 day overdue"
+	default:
+		return fmt.Sprintf("%d days overdue", -days)
+	}
+}
+
+// statementFields returns the embed fields shared by statement notifications
+func statementFields(card models.CreditCard, stmt models.Statement, rec recommend.Recommendation) []EmbedField {
+	recommended := "unknown"
+	if rec.PaymentDate != "" {
+		recommended = rec.PaymentDate
+	}
+
+	fields := []EmbedField{
+		{Name: "Card", Value: card.Name, Inline: true},
+		{Name: "Last Four", Value: card.LastFour, Inline: true},
+		{Name: "Amount", Value: stmt.Amount.Display(), Inline: true},
+	}
+	if stmt.MinimumPayment > 0 {
+		fields = append(fields, EmbedField{Name: "Minimum Payment", Value: stmt.MinimumPayment.Display(), Inline: true})
+	}
+	if stmt.PaidAmount > 0 {
+		fields = append(fields, EmbedField{Name: "Paid So Far", Value: stmt.PaidAmount.Display(), Inline: true})
+	}
+	if stmt.CurrentBalance != nil {
+		fields = append(fields, EmbedField{Name: "Current Balance", Value: stmt.CurrentBalance.Display(), Inline: true})
+	}
+	fields = append(fields,
+		EmbedField{Name: "Due Date", Value: stmt.DueDate, Inline: true},
+		EmbedField{Name: "Recommended Payment Date", Value: recommended, Inline: true},
+	)
+	if rec.Adjusted {
+		fields = append(fields, EmbedField{Name: "Why This Date", Value: rec.Explanation})
+	}
+	if card.HasAutopay() {
+		fields = append(fields, EmbedField{Name: "Autopay", Value: card.AutopayPayment(stmt).Display(), Inline: true})
+	}
+	return fields
+}
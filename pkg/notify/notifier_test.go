@@ -0,0 +1,358 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+)
+
+// setupNotifierDB creates a database with one card and one pending statement
+func setupNotifierDB(t *testing.T) (*sql.DB, int) {
+	tmpDB := "./test_notifier.db"
+	db, err := database.InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpDB)
+	})
+
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES ('Amex Cobalt', '1234', 28, 25)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert test card: %v", err)
+	}
+	cardID, _ := result.LastInsertId()
+
+	result, err = db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, status)
+		VALUES (?, '2024-11-28', '2024-12-23', 342189, 'pending')
+	`, cardID)
+	if err != nil {
+		t.Fatalf("Failed to insert test statement: %v", err)
+	}
+	stmtID, _ := result.LastInsertId()
+
+	return db, int(stmtID)
+}
+
+// notifiedFlags returns the notification flags stored for a statement
+func notifiedFlags(t *testing.T, db *sql.DB, stmtID int) (bool, bool) {
+	var notifiedStatement, notifiedPayment bool
+	err := db.QueryRow("SELECT notified_statement, notified_payment FROM statements WHERE id = ?", stmtID).
+		Scan(&notifiedStatement, &notifiedPayment)
+	if err != nil {
+		t.Fatalf("Failed to query notification flags: %v", err)
+	}
+	return notifiedStatement, notifiedPayment
+}
+
+func TestNotifyStatement_Success(t *testing.T) {
+	db, stmtID := setupNotifierDB(t)
+
+	var received WebhookMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 11, 29, 9, 30, 0, 0, time.UTC)
+	notifier := NewNotifier(db, newTestClient(server.URL), clock.NewFixed(now))
+	if err := notifier.NotifyStatement(context.Background(), stmtID); err != nil {
+		t.Fatalf("NotifyStatement failed: %v", err)
+	}
+
+	notifiedStatement, notifiedPayment := notifiedFlags(t, db, stmtID)
+	if !notifiedStatement {
+		t.Error("Expected notified_statement to be set")
+	}
+	if notifiedPayment {
+		t.Error("Expected notified_payment to remain unset")
+	}
+	var updatedAt time.Time
+	db.QueryRow("SELECT updated_at FROM statements WHERE id = ?", stmtID).Scan(&updatedAt)
+	if !updatedAt.Equal(now) {
+		t.Errorf("Expected updated_at from the notifier's clock %v, got %v", now, updatedAt)
+	}
+
+	if len(received.Embeds) != 1 {
+		t.Fatalf("Expected 1 embed, got %d", len(received.Embeds))
+	}
+
+	fields := map[string]string{}
+	for _, field := range received.Embeds[0].Fields {
+		fields[field.Name] = field.Value
+	}
+
+	expected := map[string]string{
+		"Card":                     "Amex Cobalt",
+		"Last Four":                "1234",
+		"Amount":                   "$3,421.89",
+		"Due Date":                 "2024-12-23",
+		"Recommended Payment Date": "2024-12-16",
+	}
+	for name, value := range expected {
+		if fields[name] != value {
+			t.Errorf("Expected field %s to be %q, got %q", name, value, fields[name])
+		}
+	}
+}
+
+func TestNotifyStatement_FailureLeavesFlagUnset(t *testing.T) {
+	db, stmtID := setupNotifierDB(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.MaxRetries = 1
+	notifier := NewNotifier(db, client, nil)
+
+	if err := notifier.NotifyStatement(context.Background(), stmtID); err == nil {
+		t.Fatal("Expected error when Discord fails")
+	}
+
+	notifiedStatement, _ := notifiedFlags(t, db, stmtID)
+	if notifiedStatement {
+		t.Error("Expected notified_statement to remain unset after failure")
+	}
+}
+
+func TestNotifyPayment_SkipsAlreadyNotified(t *testing.T) {
+	db, stmtID := setupNotifierDB(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(db, newTestClient(server.URL), nil)
+
+	for i := 0; i < 2; i++ {
+		if err := notifier.NotifyPayment(context.Background(), stmtID, time.Date(2024, time.December, 16, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("NotifyPayment failed: %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Expected 1 webhook request, got %d", got)
+	}
+
+	_, notifiedPayment := notifiedFlags(t, db, stmtID)
+	if !notifiedPayment {
+		t.Error("Expected notified_payment to be set")
+	}
+}
+
+func TestNotifierEnabled(t *testing.T) {
+	var nilNotifier *Notifier
+	if nilNotifier.Enabled() {
+		t.Error("Expected nil notifier to be disabled")
+	}
+
+	if NewNotifier(nil, NewDiscordClient(""), nil).Enabled() {
+		t.Error("Expected notifier without webhook URL to be disabled")
+	}
+
+	if !NewNotifier(nil, NewDiscordClient("https://discord.com/api/webhooks/1/a"), nil).Enabled() {
+		t.Error("Expected notifier with webhook URL to be enabled")
+	}
+}
+
+func TestPaymentReminderEmbed_Scheduled(t *testing.T) {
+	scheduled := "2024-12-10"
+	card := models.CreditCard{Name: "TD Aeroplan Visa", LastFour: "9876"}
+	stmt := models.Statement{DueDate: "2024-12-15", Amount: models.MustParseMoney("892.50"), ScheduledPaymentDate: &scheduled}
+
+	embed := PaymentReminderEmbed(card, stmt, recommend.Recommendation{}, time.Date(2024, time.December, 8, 0, 0, 0, 0, time.UTC))
+	if embed.Color != ColorInfo {
+		t.Errorf("Expected info color for scheduled payment, got %#x", embed.Color)
+	}
+	if embed.Title != "Payment reminder: TD Aeroplan Visa" {
+		t.Errorf("Unexpected title %q", embed.Title)
+	}
+}
+
+func TestStatementFields_AdjustedRecommendation(t *testing.T) {
+	card := models.CreditCard{Name: "TD Aeroplan Visa", LastFour: "9876"}
+	// Christmas and Boxing Day fall in the five business days before the
+	// due date
+	stmt := models.Statement{DueDate: "2025-01-01", Amount: models.MustParseMoney("892.50")}
+	rec, err := recommend.ForStatement(card, stmt, holidays.NewCalendar([]string{holidays.RegionCA}, nil), nil)
+	if err != nil {
+		t.Fatalf("ForStatement failed: %v", err)
+	}
+
+	fields := statementFields(card, stmt, rec)
+	values := map[string]string{}
+	for _, f := range fields {
+		values[f.Name] = f.Value
+	}
+	if values["Recommended Payment Date"] != "2024-12-23" {
+		t.Errorf("Expected recommended payment date 2024-12-23, got %q", values["Recommended Payment Date"])
+	}
+	if values["Why This Date"] != rec.Explanation {
+		t.Errorf("Expected explanation %q, got %q", rec.Explanation, values["Why This Date"])
+	}
+
+	// Unadjusted dates don't need explaining
+	if fields := statementFields(card, stmt, recommend.Recommendation{PaymentDate: "2024-12-23"}); fields[len(fields)-1].Name == "Why This Date" {
+		t.Error("Expected no explanation for an unadjusted date")
+	}
+}
+
+func TestStatementFields_Amounts(t *testing.T) {
+	card := models.CreditCard{Name: "Amex Cobalt", LastFour: "1234"}
+	balance := models.MustParseMoney("2171.14")
+	stmt := models.Statement{
+		DueDate:        "2024-12-23",
+		Amount:         models.MustParseMoney("3421.89"),
+		MinimumPayment: models.MustParseMoney("35.00"),
+		PaidAmount:     models.MustParseMoney("1250.75"),
+		CurrentBalance: &balance,
+	}
+
+	values := map[string]string{}
+	for _, f := range statementFields(card, stmt, recommend.Recommendation{}) {
+		values[f.Name] = f.Value
+	}
+	expected := map[string]string{
+		"Amount":          "$3,421.89",
+		"Minimum Payment": "$35.00",
+		"Paid So Far":     "$1,250.75",
+		"Current Balance": "$2,171.14",
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("Expected field %s to be %q, got %q", name, value, values[name])
+		}
+	}
+}
+
+func TestReminderSummary(t *testing.T) {
+	today := time.Date(2024, time.December, 20, 0, 0, 0, 0, time.UTC)
+	base := models.Statement{DueDate: "2024-12-23", Amount: models.MustParseMoney("1250.75")}
+
+	tests := []struct {
+		name     string
+		minimum  string
+		paid     string
+		dueDate  string
+		expected string
+	}{
+		{"minimum unpaid", "35.00", "0.00", "2024-12-23", "Minimum $35.00 due in 3 days, full balance $1,250.75."},
+		{"minimum partly paid", "35.00", "20.00", "2024-12-21", "Minimum $15.00 due tomorrow, full balance $1,230.75."},
+		{"minimum paid", "35.00", "100.00", "2024-12-20", "Minimum payment made; remaining balance $1,150.75 due today."},
+		{"no minimum", "0.00", "0.00", "2024-12-23", "Full balance $1,250.75 due in 3 days."},
+		{"overdue", "35.00", "0.00", "2024-12-18", "Minimum $35.00 2 days overdue, full balance $1,250.75."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := base
+			stmt.DueDate = tt.dueDate
+			stmt.MinimumPayment = models.MustParseMoney(tt.minimum)
+			stmt.PaidAmount = models.MustParseMoney(tt.paid)
+			if got := reminderSummary(stmt, today); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAutopayCheckEmbed(t *testing.T) {
+	card := models.CreditCard{Name: "TD Aeroplan Visa", LastFour: "9876", Autopay: models.AutopayStatementBalance}
+	stmt := models.Statement{DueDate: "2024-12-15", Amount: models.MustParseMoney("892.50")}
+	stmt.SetPaidAmount(0)
+	today := time.Date(2024, time.December, 15, 0, 0, 0, 0, time.UTC)
+
+	embed := AutopayCheckEmbed(card, stmt, recommend.Recommendation{}, today)
+	if embed.Title != "Verify autopay: TD Aeroplan Visa" {
+		t.Errorf("Unexpected title %q", embed.Title)
+	}
+	if embed.Color != ColorWarning {
+		t.Errorf("Expected warning color, got %#x", embed.Color)
+	}
+	if !strings.Contains(embed.Description, "Autopay should have paid $892.50 on 2024-12-15") {
+		t.Errorf("Unexpected description %q", embed.Description)
+	}
+
+	// A fixed amount below the balance leaves a shortfall to flag
+	card.Autopay = models.AutopayFixedAmount
+	card.AutopayAmount = models.MustParseMoney("500.00")
+	embed = AutopayCheckEmbed(card, stmt, recommend.Recommendation{}, today)
+	if embed.Color != ColorDanger {
+		t.Errorf("Expected danger color for a shortfall, got %#x", embed.Color)
+	}
+	if !strings.Contains(embed.Description, "leaving $392.50 of the statement balance") {
+		t.Errorf("Expected the shortfall in the description, got %q", embed.Description)
+	}
+}
+
+func TestMinimumDueEmbed(t *testing.T) {
+	card := models.CreditCard{Name: "TD Aeroplan Visa", LastFour: "9876"}
+	stmt := models.Statement{DueDate: "2024-12-15", Amount: models.MustParseMoney("892.50"), MinimumPayment: models.MustParseMoney("25.00")}
+
+	embed := MinimumDueEmbed(card, stmt, recommend.Recommendation{}, time.Date(2024, time.December, 12, 0, 0, 0, 0, time.UTC))
+	if embed.Color != ColorDanger {
+		t.Errorf("Expected danger color, got %#x", embed.Color)
+	}
+	if embed.Title != "Minimum payment due: TD Aeroplan Visa" {
+		t.Errorf("Unexpected title %q", embed.Title)
+	}
+
+	embed = MinimumDueEmbed(card, stmt, recommend.Recommendation{}, time.Date(2024, time.December, 16, 0, 0, 0, 0, time.UTC))
+	if embed.Title != "Minimum payment overdue: TD Aeroplan Visa" {
+		t.Errorf("Unexpected title %q", embed.Title)
+	}
+}
+
+func TestNotifyMinimumDue_SkipsWhenMinimumPaid(t *testing.T) {
+	db, stmtID := setupNotifierDB(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if _, err := db.Exec("UPDATE statements SET minimum_payment_cents = 3500 WHERE id = ?", stmtID); err != nil {
+		t.Fatalf("Failed to set minimum payment: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO payments (statement_id, amount_cents, payment_date) VALUES (?, 5000, '2024-12-18')
+	`, stmtID); err != nil {
+		t.Fatalf("Failed to insert payment: %v", err)
+	}
+
+	notifier := NewNotifier(db, newTestClient(server.URL), nil)
+	if err := notifier.NotifyMinimumDue(context.Background(), stmtID, time.Date(2024, time.December, 20, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("NotifyMinimumDue failed: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 0 {
+		t.Errorf("Expected no webhook requests, got %d", got)
+	}
+}
@@ -0,0 +1,323 @@
+// Package ofx reads credit card statements from OFX and QFX downloads, in
+// both the SGML (1.x) and XML (2.x) flavours
+package ofx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// maxFileSize limits how much of a file is read
+const maxFileSize = 10 << 20
+
+// Statement is the statement summary of one credit card account in a file
+type Statement struct {
+	// AccountID is the account number as the issuer sent it, often masked
+	AccountID string `json:"account_id"`
+	LastFour  string `json:"last_four"`
+	// StatementDate is the closing date of the statement period
+	StatementDate string `json:"statement_date"`
+	// DueDate is the payment due date, or "" if the file doesn't say
+	DueDate string `json:"due_date,omitempty"`
+	// ClosingBalance is what is owed at the statement date; a credit
+	// balance is negative
+	ClosingBalance models.Money `json:"closing_balance"`
+	// MinimumPayment is zero if the file doesn't say
+	MinimumPayment models.Money `json:"minimum_payment"`
+}
+
+// node is an element of the OFX tree. Leaf elements have a value; aggregates
+// have children.
+type node struct {
+	name     string
+	value    string
+	children []*node
+}
+
+// Parse reads every credit card statement in an OFX or QFX file. Statement
+// closing information (CCSTMTENDRS) is preferred, since it carries the due
+// date and minimum payment; otherwise the ledger balance of the transaction
+// download (CCSTMTRS) is used.
+func Parse(r io.Reader) ([]Statement, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ofx file: %w", err)
+	}
+
+	root, err := parseTree(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	statements := []Statement{}
+	for _, resp := range root.findAll("CCSTMTENDRS") {
+		stmts, err := closingStatements(resp)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmts...)
+	}
+	for _, resp := range root.findAll("CCSTMTRS") {
+		stmt, err := ledgerStatement(resp)
+		if err != nil {
+			return nil, err
+		}
+		if !hasStatement(statements, stmt) {
+			statements = append(statements, stmt)
+		}
+	}
+
+	if len(statements) == 0 {
+		return nil, errors.New("no credit card statements found in ofx file")
+	}
+	return statements, nil
+}
+
+// closingStatements reads the statement closing records of a CCSTMTENDRS
+// response, one per statement period
+func closingStatements(resp *node) ([]Statement, error) {
+	accountID := resp.text("CCACCTFROM", "ACCTID")
+	if accountID == "" {
+		return nil, errors.New("statement closing response has no account ID")
+	}
+
+	var statements []Statement
+	for _, closing := range resp.findAll("CCCLOSING") {
+		stmt := Statement{AccountID: accountID, LastFour: lastFour(accountID)}
+
+		var err error
+		if stmt.StatementDate, err = parseDate(closing.text("DTCLOSE")); err != nil {
+			return nil, fmt.Errorf("account %s: DTCLOSE: %w", accountID, err)
+		}
+		if due := closing.text("DTPMTDUE"); due != "" {
+			if stmt.DueDate, err = parseDate(due); err != nil {
+				return nil, fmt.Errorf("account %s: DTPMTDUE: %w", accountID, err)
+			}
+		}
+		balance, err := parseAmount(closing.text("BALCLOSE"))
+		if err != nil {
+			return nil, fmt.Errorf("account %s: BALCLOSE: %w", accountID, err)
+		}
+		stmt.ClosingBalance = owed(balance)
+		if min := closing.text("MINPMTDUE"); min != "" {
+			if stmt.MinimumPayment, err = parseAmount(min); err != nil {
+				return nil, fmt.Errorf("account %s: MINPMTDUE: %w", accountID, err)
+			}
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, nil
+}
+
+// ledgerStatement reads a CCSTMTRS transaction download, taking the ledger
+// balance as the closing balance. The statement date is the balance's
+// as-of date, falling back to the end of the transaction list.
+func ledgerStatement(resp *node) (Statement, error) {
+	accountID := resp.text("CCACCTFROM", "ACCTID")
+	if accountID == "" {
+		return Statement{}, errors.New("credit card statement has no account ID")
+	}
+	stmt := Statement{AccountID: accountID, LastFour: lastFour(accountID)}
+
+	date := resp.text("LEDGERBAL", "DTASOF")
+	if date == "" {
+		date = resp.text("BANKTRANLIST", "DTEND")
+	}
+	var err error
+	if stmt.StatementDate, err = parseDate(date); err != nil {
+		return Statement{}, fmt.Errorf("account %s: statement date: %w", accountID, err)
+	}
+	balance, err := parseAmount(resp.text("LEDGERBAL", "BALAMT"))
+	if err != nil {
+		return Statement{}, fmt.Errorf("account %s: LEDGERBAL: %w", accountID, err)
+	}
+	stmt.ClosingBalance = owed(balance)
+	return stmt, nil
+}
+
+// hasStatement reports whether statements already has the account's
+// statement for stmt's date, from closing information
+func hasStatement(statements []Statement, stmt Statement) bool {
+	for _, s := range statements {
+		if s.AccountID == stmt.AccountID && s.StatementDate == stmt.StatementDate {
+			return true
+		}
+	}
+	return false
+}
+
+// owed converts an OFX credit card balance, which is negative when money is
+// owed, into the amount owed
+func owed(balance models.Money) models.Money {
+	return -balance
+}
+
+// lastFour returns the last four digits of an account number, ignoring
+// masking characters and separators
+func lastFour(accountID string) string {
+	var digits []byte
+	for i := 0; i < len(accountID); i++ {
+		if accountID[i] >= '0' && accountID[i] <= '9' {
+			digits = append(digits, accountID[i])
+		}
+	}
+	if len(digits) < 4 {
+		return string(digits)
+	}
+	return string(digits[len(digits)-4:])
+}
+
+// parseDate reads the date part of an OFX datetime such as
+// 20241115120000.000[-5:EST]
+func parseDate(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 8 {
+		return "", fmt.Errorf("invalid date %q", s)
+	}
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", s)
+	}
+	return date.Format(models.DateFormat), nil
+}
+
+// parseAmount reads an OFX amount, which may use a comma as the decimal
+// separator
+func parseAmount(s string) (models.Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("missing amount")
+	}
+	if !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return models.ParseMoney(s)
+}
+
+// parseTree builds the element tree under the OFX root. Closing tags are
+// optional for elements with a value, as in SGML, so the same reader handles
+// both versions. Headers, processing instructions and comments are skipped.
+func parseTree(data string) (*node, error) {
+	start := strings.Index(strings.ToUpper(data), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("not an ofx file: no <OFX> element")
+	}
+	data = data[start:]
+
+	root := &node{}
+	stack := []*node{root}
+	for len(data) > 0 {
+		open := strings.IndexByte(data, '<')
+		if open < 0 {
+			break
+		}
+		if text := strings.TrimSpace(data[:open]); text != "" {
+			top := stack[len(stack)-1]
+			top.value += unescape(text)
+		}
+		data = data[open:]
+
+		if strings.HasPrefix(data, "<!--") {
+			end := strings.Index(data, "-->")
+			if end < 0 {
+				break
+			}
+			data = data[end+3:]
+			continue
+		}
+
+		end := strings.IndexByte(data, '>')
+		if end < 0 {
+			return nil, errors.New("invalid ofx file: unterminated tag")
+		}
+		tag := strings.TrimSpace(data[1:end])
+		data = data[end+1:]
+
+		switch {
+		case tag == "" || tag[0] == '?' || tag[0] == '!':
+			continue
+		case tag[0] == '/':
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			// Pop up to and including the element being closed; leaves
+			// without closing tags in between are closed implicitly
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		default:
+			selfClosing := strings.HasSuffix(tag, "/")
+			name := strings.ToUpper(strings.Fields(strings.TrimSuffix(tag, "/"))[0])
+
+			// A leaf with a value ends at the next tag when it isn't closed
+			if top := stack[len(stack)-1]; top.value != "" && len(top.children) == 0 && len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+
+			n := &node{name: name}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			if !selfClosing {
+				stack = append(stack, n)
+			}
+		}
+	}
+
+	if len(root.children) == 0 {
+		return nil, errors.New("not an ofx file: no <OFX> element")
+	}
+	return root, nil
+}
+
+// text returns the value of the first element reached by following path
+// through descendants, or "" if there isn't one
+func (n *node) text(path ...string) string {
+	current := n
+	for _, name := range path {
+		current = current.find(name)
+		if current == nil {
+			return ""
+		}
+	}
+	return current.value
+}
+
+// find returns the first descendant named name, depth first
+func (n *node) find(name string) *node {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+		if found := child.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns every descendant named name, depth first
+func (n *node) findAll(name string) []*node {
+	var found []*node
+	for _, child := range n.children {
+		if child.name == name {
+			found = append(found, child)
+			continue
+		}
+		found = append(found, child.findAll(name)...)
+	}
+	return found
+}
+
+// unescape decodes the character entities OFX allows in values
+func unescape(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&nbsp;", " ", "&quot;", `"`, "&apos;", "'").Replace(s)
+}
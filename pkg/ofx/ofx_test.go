@@ -0,0 +1,172 @@
+package ofx
+
+import (
+	"strings"
+	"testing"
+)
+
+const sgmlFile = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1>
+<SONRS>
+<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+<DTSERVER>20241116083000.000[-5:EST]
+<LANGUAGE>ENG
+</SONRS>
+</SIGNONMSGSRSV1>
+<CREDITCARDMSGSRSV1>
+<CCSTMTTRNRS>
+<TRNUID>1
+<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+<CCSTMTRS>
+<CURDEF>CAD
+<CCACCTFROM>
+<ACCTID>4500XXXXXXXX1234
+</CCACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20241016
+<DTEND>20241115
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20241101
+<TRNAMT>-45.20
+<FITID>1
+<NAME>GROCERY &amp; CO
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>-1250.75
+<DTASOF>20241115120000.000[-5:EST]
+</LEDGERBAL>
+</CCSTMTRS>
+</CCSTMTTRNRS>
+</CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+const xmlFile = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <TRNUID>1</TRNUID>
+      <CCSTMTRS>
+        <CURDEF>USD</CURDEF>
+        <CCACCTFROM><ACCTID>371449635398431</ACCTID></CCACCTFROM>
+        <LEDGERBAL><BALAMT>-820.10</BALAMT><DTASOF>20241120</DTASOF></LEDGERBAL>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+    <CCSTMTENDTRNRS>
+      <TRNUID>2</TRNUID>
+      <CCSTMTENDRS>
+        <CURDEF>USD</CURDEF>
+        <CCACCTFROM><ACCTID>371449635398431</ACCTID></CCACCTFROM>
+        <CCCLOSING>
+          <FITID>c1</FITID>
+          <DTOPEN>20241021</DTOPEN>
+          <DTCLOSE>20241120</DTCLOSE>
+          <DTPMTDUE>20241215</DTPMTDUE>
+          <BALCLOSE>-820.10</BALCLOSE>
+          <MINPMTDUE>35.00</MINPMTDUE>
+        </CCCLOSING>
+      </CCSTMTENDRS>
+    </CCSTMTENDTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParse_SGML(t *testing.T) {
+	statements, err := Parse(strings.NewReader(sgmlFile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("Expected 1 statement, got %d", len(statements))
+	}
+
+	stmt := statements[0]
+	if stmt.LastFour != "1234" {
+		t.Errorf("Expected last four 1234, got %s", stmt.LastFour)
+	}
+	if stmt.StatementDate != "2024-11-15" {
+		t.Errorf("Expected statement date 2024-11-15, got %s", stmt.StatementDate)
+	}
+	if stmt.DueDate != "" {
+		t.Errorf("Expected no due date, got %s", stmt.DueDate)
+	}
+	if stmt.ClosingBalance.String() != "1250.75" {
+		t.Errorf("Expected closing balance 1250.75, got %s", stmt.ClosingBalance)
+	}
+}
+
+func TestParse_XMLClosing(t *testing.T) {
+	statements, err := Parse(strings.NewReader(xmlFile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	// The ledger balance for the same date is covered by the closing record
+	if len(statements) != 1 {
+		t.Fatalf("Expected 1 statement, got %d: %+v", len(statements), statements)
+	}
+
+	stmt := statements[0]
+	if stmt.LastFour != "8431" {
+		t.Errorf("Expected last four 8431, got %s", stmt.LastFour)
+	}
+	if stmt.StatementDate != "2024-11-20" || stmt.DueDate != "2024-12-15" {
+		t.Errorf("Expected dates 2024-11-20 and 2024-12-15, got %s and %s", stmt.StatementDate, stmt.DueDate)
+	}
+	if stmt.ClosingBalance.String() != "820.10" {
+		t.Errorf("Expected closing balance 820.10, got %s", stmt.ClosingBalance)
+	}
+	if stmt.MinimumPayment.String() != "35.00" {
+		t.Errorf("Expected minimum payment 35.00, got %s", stmt.MinimumPayment)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"not ofx", "date,amount\n2024-11-15,10.00\n"},
+		{"no statements", "<OFX><SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</STATUS></SONRS></SIGNONMSGSRSV1></OFX>"},
+		{"bad date", "<OFX><CCSTMTRS><CCACCTFROM><ACCTID>1234</CCACCTFROM><LEDGERBAL><BALAMT>-1.00<DTASOF>2024</LEDGERBAL></CCSTMTRS></OFX>"},
+		{"bad amount", "<OFX><CCSTMTRS><CCACCTFROM><ACCTID>1234</CCACCTFROM><LEDGERBAL><BALAMT>lots<DTASOF>20241115</LEDGERBAL></CCSTMTRS></OFX>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.file)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := map[string]string{
+		"-1250.75": "-1250.75",
+		"+10":      "10.00",
+		"-99,50":   "-99.50",
+	}
+	for in, want := range tests {
+		got, err := parseAmount(in)
+		if err != nil {
+			t.Errorf("parseAmount(%q) failed: %v", in, err)
+			continue
+		}
+		if got.String() != want {
+			t.Errorf("Expected %s for %q, got %s", want, in, got)
+		}
+	}
+}
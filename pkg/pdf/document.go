@@ -0,0 +1,416 @@
+// Package pdf extracts the text of PDF files, such as the statements card
+// issuers offer for download. It reads unencrypted PDF 1.x files whose text
+// is drawn with fonts, including compressed content and object streams.
+// Scanned statements are images and have no text to extract.
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	errEOF = errors.New("unexpected end of data")
+
+	// ErrNotPDF is returned for files without a PDF header
+	ErrNotPDF = errors.New("not a PDF file")
+	// ErrEncrypted is returned for password-protected files
+	ErrEncrypted = errors.New("PDF is encrypted")
+	// ErrTooLarge is returned for files whose streams decompress to more
+	// than maxStreamSize, or more than maxDocumentSize in all
+	ErrTooLarge = errors.New("PDF decompresses to too much data")
+)
+
+// maxDepth bounds the page tree, reference chains and nested forms, so that
+// malformed files with cycles can't loop forever
+const maxDepth = 32
+
+// maxStreamSize and maxDocumentSize bound how much a file's compressed
+// streams may inflate to, so that a small file can't exhaust memory. They
+// are variables so tests can lower them.
+var (
+	maxStreamSize   = 16 << 20
+	maxDocumentSize = 64 << 20
+)
+
+// IsPDF reports whether data has a PDF header near its start
+func IsPDF(data []byte) bool {
+	return bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-"))
+}
+
+// document holds the objects of a PDF file by object number
+type document struct {
+	objects map[int]interface{}
+	trailer Dict
+	// decoded counts the bytes inflated so far, against maxDocumentSize
+	decoded int
+	// tooLarge records that a stream was cut short by the limits
+	tooLarge bool
+}
+
+// objectHeader matches the "12 0 obj" starting each indirect object
+var objectHeader = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+
+// load reads every object in data. Objects are found by scanning the file
+// rather than through its cross-reference table, which is often damaged;
+// when an object appears twice, as after incremental updates, the last one
+// wins.
+func load(data []byte) (*document, error) {
+	if !IsPDF(data) {
+		return nil, ErrNotPDF
+	}
+
+	doc := &document{objects: map[int]interface{}{}, trailer: Dict{}}
+	streamEnd := 0
+	for _, m := range objectHeader.FindAllSubmatchIndex(data, -1) {
+		// Skip matches inside stream data or in the middle of a number
+		if m[0] < streamEnd || (m[0] > 0 && !isWhitespace(data[m[0]-1]) && !isDelimiter(data[m[0]-1])) {
+			continue
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		l := &lexer{data: data, pos: m[1]}
+		obj, err := l.object()
+		if err != nil {
+			continue
+		}
+		if d, ok := obj.(Dict); ok {
+			if s, ok := l.stream(d); ok {
+				obj = s
+				streamEnd = l.pos
+			}
+		}
+		doc.objects[num] = obj
+	}
+
+	// Trailers name the catalog and the encryption dictionary; files with
+	// cross-reference streams keep them in the stream's dictionary instead
+	for i := 0; ; {
+		at := bytes.Index(data[i:], []byte("trailer"))
+		if at < 0 {
+			break
+		}
+		l := &lexer{data: data, pos: i + at + len("trailer")}
+		if d, err := l.object(); err == nil {
+			if d, ok := d.(Dict); ok {
+				doc.mergeTrailer(d)
+			}
+		}
+		i += at + len("trailer")
+	}
+	for _, num := range doc.numbers() {
+		if s, ok := doc.objects[num].(Stream); ok && s.Dict["Type"] == Name("XRef") {
+			doc.mergeTrailer(s.Dict)
+		}
+	}
+	if _, ok := doc.trailer["Encrypt"]; ok {
+		return nil, ErrEncrypted
+	}
+
+	// Objects compressed into object streams don't replace ones stored
+	// directly
+	packed := map[int]interface{}{}
+	for _, num := range doc.numbers() {
+		if s, ok := doc.objects[num].(Stream); ok && s.Dict["Type"] == Name("ObjStm") {
+			doc.unpack(s, packed)
+		}
+	}
+	if doc.tooLarge {
+		return nil, ErrTooLarge
+	}
+	for num, obj := range packed {
+		if _, ok := doc.objects[num]; !ok {
+			doc.objects[num] = obj
+		}
+	}
+	return doc, nil
+}
+
+// stream reads the stream data following the dictionary d, if there is any
+func (l *lexer) stream(d Dict) (Stream, bool) {
+	save := l.pos
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		l.pos = save
+		return Stream{}, false
+	}
+	start := l.pos + len("stream")
+	if start < len(l.data) && l.data[start] == '\r' {
+		start++
+	}
+	if start < len(l.data) && l.data[start] == '\n' {
+		start++
+	}
+
+	// Trust /Length when it is direct and ends where the stream does
+	if n, ok := d["Length"].(int); ok && n >= 0 && n <= len(l.data)-start {
+		rest := bytes.TrimLeft(l.data[start+n:], "\r\n\t ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = start + n
+			return Stream{Dict: d, Data: l.data[start : start+n]}, true
+		}
+	}
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		end = len(l.data) - start
+	}
+	l.pos = start + end
+	data := bytes.TrimSuffix(l.data[start:start+end], []byte("\n"))
+	return Stream{Dict: d, Data: bytes.TrimSuffix(data, []byte("\r"))}, true
+}
+
+func (doc *document) mergeTrailer(d Dict) {
+	for key, value := range d {
+		doc.trailer[key] = value
+	}
+}
+
+// numbers returns the object numbers in ascending order
+func (doc *document) numbers() []int {
+	nums := make([]int, 0, len(doc.objects))
+	for num := range doc.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// unpack adds the objects compressed into the object stream s to objects
+func (doc *document) unpack(s Stream, objects map[int]interface{}) {
+	data, err := doc.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := doc.resolve(s.Dict["N"]).(int)
+	first, _ := doc.resolve(s.Dict["First"]).(int)
+	if first < 0 || first > len(data) {
+		return
+	}
+
+	header := &lexer{data: data}
+	for i := 0; i < n; i++ {
+		numObj, err1 := header.object()
+		offsetObj, err2 := header.object()
+		if err1 != nil || err2 != nil {
+			return
+		}
+		num, ok1 := numObj.(int)
+		offset, ok2 := offsetObj.(int)
+		if !ok1 || !ok2 || offset < 0 || offset >= len(data)-first {
+			continue
+		}
+		l := &lexer{data: data, pos: first + offset}
+		if obj, err := l.object(); err == nil {
+			objects[num] = obj
+		}
+	}
+}
+
+// resolve follows references until it reaches a direct object; missing
+// objects are null
+func (doc *document) resolve(obj interface{}) interface{} {
+	for i := 0; i < maxDepth; i++ {
+		ref, ok := obj.(Ref)
+		if !ok {
+			return obj
+		}
+		obj = doc.objects[ref.Num]
+	}
+	return nil
+}
+
+// dict returns obj, or the dictionary of the stream obj, as a dictionary
+func (doc *document) dict(obj interface{}) Dict {
+	switch v := doc.resolve(obj).(type) {
+	case Dict:
+		return v
+	case Stream:
+		return v.Dict
+	}
+	return nil
+}
+
+// number returns an integer or real object as a float64
+func (doc *document) number(obj interface{}) (float64, bool) {
+	switch v := doc.resolve(obj).(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// decode applies the stream's filters to its data
+func (doc *document) decode(s Stream) ([]byte, error) {
+	var filters []Name
+	switch f := doc.resolve(s.Dict["Filter"]).(type) {
+	case Name:
+		filters = []Name{f}
+	case Array:
+		for _, name := range f {
+			if name, ok := doc.resolve(name).(Name); ok {
+				filters = append(filters, name)
+			}
+		}
+	}
+	if parms := doc.dict(s.Dict["DecodeParms"]); parms != nil {
+		if predictor, ok := doc.number(parms["Predictor"]); ok && predictor > 1 {
+			return nil, fmt.Errorf("unsupported predictor %v", predictor)
+		}
+	}
+
+	data := s.Data
+	for _, filter := range filters {
+		var err error
+		switch filter {
+		case "FlateDecode", "Fl":
+			data, err = doc.inflate(data)
+		case "ASCIIHexDecode", "AHx":
+			l := &lexer{data: append([]byte{'<'}, data...)}
+			data = l.hexString()
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %s", filter)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s stream: %w", filter, err)
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping what it can of a truncated or
+// corrupt stream. It returns ErrTooLarge once the stream or the document
+// inflates past its limit.
+func (doc *document) inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Some writers leave out the zlib header
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+
+	limit := min(maxStreamSize, maxDocumentSize-doc.decoded)
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if len(out) > limit {
+		doc.tooLarge = true
+		return nil, ErrTooLarge
+	}
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	doc.decoded += len(out)
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	out := make([]byte, len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+// page is a page dictionary with the resources it uses, which may be
+// inherited from the page tree
+type page struct {
+	dict      Dict
+	resources Dict
+}
+
+// pages returns the pages in order, walking the page tree from the catalog,
+// or every page object by number if there is no usable tree
+func (doc *document) pages() []page {
+	root := doc.dict(doc.trailer["Root"])
+	if root == nil {
+		for _, num := range doc.numbers() {
+			if d := doc.dict(doc.objects[num]); d["Type"] == Name("Catalog") {
+				root = d
+				break
+			}
+		}
+	}
+
+	var pages []page
+	if root != nil {
+		doc.walkPages(root["Pages"], nil, &pages, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	for _, num := range doc.numbers() {
+		d := doc.dict(doc.objects[num])
+		if d["Type"] != Name("Page") {
+			continue
+		}
+		resources := doc.dict(d["Resources"])
+		for parent, depth := doc.dict(d["Parent"]), 0; resources == nil && parent != nil && depth < maxDepth; depth++ {
+			resources = doc.dict(parent["Resources"])
+			parent = doc.dict(parent["Parent"])
+		}
+		pages = append(pages, page{dict: d, resources: resources})
+	}
+	return pages
+}
+
+func (doc *document) walkPages(node interface{}, resources Dict, pages *[]page, depth int) {
+	d := doc.dict(node)
+	if d == nil || depth > maxDepth {
+		return
+	}
+	if r := doc.dict(d["Resources"]); r != nil {
+		resources = r
+	}
+	kids, ok := doc.resolve(d["Kids"]).(Array)
+	if !ok {
+		*pages = append(*pages, page{dict: d, resources: resources})
+		return
+	}
+	for _, kid := range kids {
+		doc.walkPages(kid, resources, pages, depth+1)
+	}
+}
+
+// contents returns the page's decoded content streams joined together
+func (doc *document) contents(p page) []byte {
+	var streams []interface{}
+	switch c := doc.resolve(p.dict["Contents"]).(type) {
+	case Stream:
+		streams = []interface{}{c}
+	case Array:
+		streams = c
+	}
+
+	var data []byte
+	for _, s := range streams {
+		s, ok := doc.resolve(s).(Stream)
+		if !ok {
+			continue
+		}
+		decoded, err := doc.decode(s)
+		if err != nil {
+			continue
+		}
+		data = append(data, decoded...)
+		data = append(data, '\n')
+	}
+	return data
+}
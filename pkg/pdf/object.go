@@ -0,0 +1,287 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// The PDF object types. Integers are int and reals float64; booleans and
+// null are bool and nil.
+type (
+	// Name is a name object such as /Type, without the slash
+	Name string
+	// Dict is a dictionary object
+	Dict map[Name]interface{}
+	// Array is an array object
+	Array []interface{}
+	// Ref refers to an indirect object
+	Ref struct {
+		Num, Gen int
+	}
+	// Stream is a stream object with its data still encoded
+	Stream struct {
+		Dict Dict
+		Data []byte
+	}
+	// keyword is a bare word such as obj or, in content streams, an operator
+	keyword string
+)
+
+// lexer reads objects from PDF bytes
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace skips whitespace and comments
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isWhitespace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// word reads the regular characters at the current position
+func (l *lexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// object reads the next object. Inside dictionaries and arrays it also
+// returns the closing ">>" and "]" as keywords; at the end of the data it
+// returns errEOF.
+func (l *lexer) object() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEOF
+	}
+
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		l.pos++
+		return Name(decodeName(l.word())), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return l.dict()
+	case c == '<':
+		return l.hexString(), nil
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return keyword(">>"), nil
+	case c == '[':
+		l.pos++
+		return l.array()
+	case c == ']' || c == '{' || c == '}' || c == ')' || c == '>':
+		l.pos++
+		return keyword([]byte{c}), nil
+	}
+
+	word := l.word()
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if n, err := strconv.Atoi(word); err == nil {
+		return l.maybeRef(n), nil
+	}
+	if f, err := strconv.ParseFloat(word, 64); err == nil {
+		return f, nil
+	}
+	return keyword(word), nil
+}
+
+// maybeRef reads "gen R" after the object number n, if it follows
+func (l *lexer) maybeRef(n int) interface{} {
+	save := l.pos
+	l.skipSpace()
+	gen, err := strconv.Atoi(l.word())
+	if err == nil {
+		l.skipSpace()
+		if l.pos < len(l.data) && l.data[l.pos] == 'R' &&
+			(l.pos+1 == len(l.data) || isWhitespace(l.data[l.pos+1]) || isDelimiter(l.data[l.pos+1])) {
+			l.pos++
+			return Ref{Num: n, Gen: gen}
+		}
+	}
+	l.pos = save
+	return n
+}
+
+func (l *lexer) dict() (Dict, error) {
+	d := Dict{}
+	for {
+		key, err := l.object()
+		if err != nil {
+			return d, err
+		}
+		if key == keyword(">>") {
+			return d, nil
+		}
+		name, ok := key.(Name)
+		if !ok {
+			return d, fmt.Errorf("dictionary key is %v, not a name", key)
+		}
+		value, err := l.object()
+		if err != nil {
+			return d, err
+		}
+		if value == keyword(">>") {
+			return d, nil
+		}
+		d[name] = value
+	}
+}
+
+func (l *lexer) array() (Array, error) {
+	a := Array{}
+	for {
+		value, err := l.object()
+		if err != nil {
+			return a, err
+		}
+		if value == keyword("]") {
+			return a, nil
+		}
+		a = append(a, value)
+	}
+}
+
+// literalString reads a (string), handling escapes and balanced parentheses
+func (l *lexer) literalString() []byte {
+	l.pos++
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A backslash at the end of a line continues the string
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					n := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hexString reads a <hex string>; a missing final digit counts as 0
+func (l *lexer) hexString() []byte {
+	l.pos++
+	var out []byte
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if v, ok := hexValue(l.data[l.pos]); ok {
+			digits = append(digits, v)
+		}
+		l.pos++
+	}
+	if l.pos < len(l.data) {
+		l.pos++
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, 0)
+	}
+	for i := 0; i < len(digits); i += 2 {
+		out = append(out, digits[i]<<4|digits[i+1])
+	}
+	return out
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// decodeName expands the #xx escapes of a name
+func decodeName(s string) string {
+	if !bytes.Contains([]byte(s), []byte("#")) {
+		return s
+	}
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i+2 < len(s) {
+			hi, ok1 := hexValue(s[i+1])
+			lo, ok2 := hexValue(s[i+2])
+			if ok1 && ok2 {
+				out = append(out, hi<<4|lo)
+				i += 2
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
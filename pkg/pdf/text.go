@@ -0,0 +1,537 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// ExtractText returns the text of every page of a PDF file, one line of
+// text per line with a blank line between pages. Text is put in reading
+// order from its position on the page, so table rows such as
+// "New Balance $892.50" come out on one line.
+func ExtractText(data []byte) (string, error) {
+	doc, err := load(data)
+	if err != nil {
+		return "", err
+	}
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return "", errors.New("PDF has no pages")
+	}
+
+	var out []string
+	for _, p := range pages {
+		e := &extractor{doc: doc}
+		e.content(doc.contents(p), p.resources, identity, 0)
+		if lines := e.lines(); len(lines) > 0 {
+			out = append(out, strings.Join(lines, "\n"))
+		}
+	}
+	if doc.tooLarge {
+		return "", ErrTooLarge
+	}
+	return strings.Join(out, "\n\n"), nil
+}
+
+// matrix is a PDF transformation matrix [a b c d e f]
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m × n, applying m and then n
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+// graphicsState is the part of the graphics state that positions text
+type graphicsState struct {
+	ctm       matrix
+	font      *font
+	size      float64
+	charSpace float64
+	wordSpace float64
+	scale     float64
+	leading   float64
+	rise      float64
+}
+
+// run is a piece of text drawn in one go, with where it starts and ends on
+// the page
+type run struct {
+	x, y, endX, size float64
+	text             string
+}
+
+// extractor collects the text drawn on a page
+type extractor struct {
+	doc  *document
+	runs []run
+}
+
+// content interprets a content stream, recording the text it shows
+func (e *extractor) content(data []byte, resources Dict, ctm matrix, depth int) {
+	if depth > maxDepth {
+		return
+	}
+	fonts := map[Name]*font{}
+	gs := graphicsState{ctm: ctm, scale: 1}
+	var stack []graphicsState
+	var tm, tlm matrix
+
+	l := &lexer{data: data}
+	var operands []interface{}
+	for {
+		obj, err := l.object()
+		if err != nil {
+			break
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		num := func(i int) float64 {
+			if i < len(operands) {
+				v, _ := e.doc.number(operands[i])
+				return v
+			}
+			return 0
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			gs.ctm = matrix{num(0), num(1), num(2), num(3), num(4), num(5)}.mul(gs.ctm)
+		case "BT":
+			tm, tlm = identity, identity
+		case "Tf":
+			if len(operands) == 2 {
+				name, _ := operands[0].(Name)
+				if _, ok := fonts[name]; !ok {
+					fonts[name] = e.doc.font(e.doc.dict(e.doc.dict(resources["Font"])[name]))
+				}
+				gs.font = fonts[name]
+				gs.size = num(1)
+			}
+		case "Tc":
+			gs.charSpace = num(0)
+		case "Tw":
+			gs.wordSpace = num(0)
+		case "Tz":
+			gs.scale = num(0) / 100
+		case "TL":
+			gs.leading = num(0)
+		case "Ts":
+			gs.rise = num(0)
+		case "Td":
+			tlm = translate(num(0), num(1)).mul(tlm)
+			tm = tlm
+		case "TD":
+			gs.leading = -num(1)
+			tlm = translate(num(0), num(1)).mul(tlm)
+			tm = tlm
+		case "Tm":
+			tlm = matrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+			tm = tlm
+		case "T*":
+			tlm = translate(0, -gs.leading).mul(tlm)
+			tm = tlm
+		case "Tj", "'", "\"":
+			if op != "Tj" {
+				if op == "\"" {
+					gs.wordSpace, gs.charSpace = num(0), num(1)
+				}
+				tlm = translate(0, -gs.leading).mul(tlm)
+				tm = tlm
+			}
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].([]byte); ok {
+					tm = e.show(s, &gs, tm)
+				}
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				items, _ := operands[0].(Array)
+				for _, item := range items {
+					if s, ok := item.([]byte); ok {
+						tm = e.show(s, &gs, tm)
+					} else if adjust, ok := e.doc.number(item); ok {
+						tm = translate(-adjust/1000*gs.size*gs.scale, 0).mul(tm)
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				name, _ := operands[0].(Name)
+				e.form(e.doc.resolve(e.doc.dict(resources["XObject"])[name]), resources, gs.ctm, depth)
+			}
+		case "ID":
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// form draws a form XObject; images and other XObjects have no text
+func (e *extractor) form(obj interface{}, resources Dict, ctm matrix, depth int) {
+	s, ok := obj.(Stream)
+	if !ok || s.Dict["Subtype"] != Name("Form") {
+		return
+	}
+	data, err := e.doc.decode(s)
+	if err != nil {
+		return
+	}
+	if r := e.doc.dict(s.Dict["Resources"]); r != nil {
+		resources = r
+	}
+	if m, ok := e.doc.resolve(s.Dict["Matrix"]).(Array); ok && len(m) == 6 {
+		var fm matrix
+		for i := range fm {
+			fm[i], _ = e.doc.number(m[i])
+		}
+		ctm = fm.mul(ctm)
+	}
+	e.content(data, resources, ctm, depth+1)
+}
+
+// skipInlineImage moves past the data of an inline image, which follows
+// the ID operator and ends at EI
+func (l *lexer) skipInlineImage() {
+	l.pos++
+	for l.pos+2 <= len(l.data) {
+		if l.data[l.pos] == 'E' && l.data[l.pos+1] == 'I' && isWhitespace(l.data[l.pos-1]) &&
+			(l.pos+2 == len(l.data) || isWhitespace(l.data[l.pos+2])) {
+			l.pos += 2
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
+
+// show records the text of s drawn with the current text matrix tm and
+// returns the text matrix after it
+func (e *extractor) show(s []byte, gs *graphicsState, tm matrix) matrix {
+	f := gs.font
+	if f == nil {
+		f = defaultFont
+	}
+	rendering := matrix{gs.size * gs.scale, 0, 0, gs.size, 0, gs.rise}
+	start := rendering.mul(tm).mul(gs.ctm)
+
+	var text strings.Builder
+	for _, g := range f.glyphs(s) {
+		text.WriteString(g.text)
+		advance := g.width/1000*gs.size + gs.charSpace
+		if g.space {
+			advance += gs.wordSpace
+		}
+		tm = translate(advance*gs.scale, 0).mul(tm)
+	}
+	end := rendering.mul(tm).mul(gs.ctm)
+
+	if text.Len() > 0 {
+		e.runs = append(e.runs, run{
+			x:    start[4],
+			y:    start[5],
+			endX: end[4],
+			size: math.Hypot(start[2], start[3]),
+			text: text.String(),
+		})
+	}
+	return tm
+}
+
+// lines groups the runs into lines from the top of the page down, and
+// joins each line's runs from left to right, with a space where there is a
+// gap between them
+func (e *extractor) lines() []string {
+	runs := e.runs
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].y > runs[j].y })
+
+	var lines []string
+	for i := 0; i < len(runs); {
+		j := i + 1
+		for j < len(runs) && runs[i].y-runs[j].y < math.Max(math.Min(runs[i].size, runs[j].size)/2, 1) {
+			j++
+		}
+		line := runs[i:j]
+		sort.SliceStable(line, func(a, b int) bool { return line[a].x < line[b].x })
+
+		var text strings.Builder
+		for k, r := range line {
+			if k > 0 {
+				prev := line[k-1]
+				gap := r.x - prev.endX
+				if gap > r.size*0.15 && !strings.HasSuffix(prev.text, " ") && !strings.HasPrefix(r.text, " ") {
+					text.WriteByte(' ')
+				}
+			}
+			text.WriteString(r.text)
+		}
+		if s := strings.Join(strings.Fields(text.String()), " "); s != "" {
+			lines = append(lines, s)
+		}
+		i = j
+	}
+	return lines
+}
+
+// font decodes the character codes of strings drawn in a font
+type font struct {
+	// codeLen is the number of bytes in each character code
+	codeLen int
+	// toUnicode maps character codes to text, from the font's ToUnicode
+	// CMap
+	toUnicode map[string]string
+	// widths are glyph widths in thousandths of the font size by code
+	widths       map[int]float64
+	defaultWidth float64
+}
+
+// defaultFont is used for text drawn without a font or with one that is
+// missing
+var defaultFont = &font{codeLen: 1, defaultWidth: 500}
+
+// glyph is one character code decoded
+type glyph struct {
+	text  string
+	width float64
+	// space is set for the single-byte code 32, which word spacing applies
+	// to
+	space bool
+}
+
+// font reads a font dictionary
+func (doc *document) font(d Dict) *font {
+	if d == nil {
+		return defaultFont
+	}
+	f := &font{codeLen: 1, widths: map[int]float64{}, defaultWidth: 500}
+
+	if d["Subtype"] == Name("Type0") {
+		f.codeLen = 2
+		f.defaultWidth = 1000
+		if descendants, ok := doc.resolve(d["DescendantFonts"]).(Array); ok && len(descendants) > 0 {
+			cid := doc.dict(descendants[0])
+			if dw, ok := doc.number(cid["DW"]); ok {
+				f.defaultWidth = dw
+			}
+			doc.cidWidths(cid["W"], f.widths)
+		}
+	} else {
+		first, _ := doc.number(d["FirstChar"])
+		if widths, ok := doc.resolve(d["Widths"]).(Array); ok {
+			for i, w := range widths {
+				if w, ok := doc.number(w); ok {
+					f.widths[int(first)+i] = w
+				}
+			}
+		}
+		if missing, ok := doc.number(doc.dict(d["FontDescriptor"])["MissingWidth"]); ok && missing > 0 {
+			f.defaultWidth = missing
+		}
+	}
+
+	if s, ok := doc.resolve(d["ToUnicode"]).(Stream); ok {
+		if data, err := doc.decode(s); err == nil {
+			f.toUnicode = parseCMap(data, &f.codeLen)
+		}
+	}
+	return f
+}
+
+// cidWidths reads the W array of a CID font, which lists widths either as
+// "first [w1 w2 ...]" or "first last w"
+func (doc *document) cidWidths(obj interface{}, widths map[int]float64) {
+	w, _ := doc.resolve(obj).(Array)
+	for i := 0; i+1 < len(w); {
+		first, ok := doc.number(w[i])
+		if !ok {
+			return
+		}
+		if list, ok := doc.resolve(w[i+1]).(Array); ok {
+			for j, width := range list {
+				widths[int(first)+j], _ = doc.number(width)
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last, _ := doc.number(w[i+1])
+		width, _ := doc.number(w[i+2])
+		for c := int(first); c <= int(last) && c-int(first) < 1<<16; c++ {
+			widths[c] = width
+		}
+		i += 3
+	}
+}
+
+// glyphs splits s into character codes and decodes them
+func (f *font) glyphs(s []byte) []glyph {
+	var glyphs []glyph
+	for i := 0; i < len(s); i += f.codeLen {
+		end := min(i+f.codeLen, len(s))
+		code := 0
+		for _, b := range s[i:end] {
+			code = code<<8 | int(b)
+		}
+
+		g := glyph{width: f.defaultWidth, space: f.codeLen == 1 && code == 32}
+		if w, ok := f.widths[code]; ok {
+			g.width = w
+		}
+		if text, ok := f.toUnicode[string(s[i:end])]; ok {
+			g.text = text
+		} else if f.codeLen == 1 {
+			g.text = winAnsi(byte(code))
+		}
+		glyphs = append(glyphs, g)
+	}
+	return glyphs
+}
+
+// winAnsiHigh maps the WinAnsiEncoding codes that differ from Latin-1
+var winAnsiHigh = map[byte]string{
+	0x80: "€", 0x85: "…", 0x91: "‘", 0x92: "’", 0x93: "“", 0x94: "”",
+	0x95: "•", 0x96: "–", 0x97: "—", 0x99: "™",
+}
+
+// winAnsi decodes a code of a simple font without a ToUnicode CMap. Most
+// statements use the standard encoding, where printable ASCII is itself.
+func winAnsi(c byte) string {
+	switch {
+	case c >= 0x20 && c < 0x7f:
+		return string(rune(c))
+	case c >= 0xa0:
+		return string(rune(c))
+	case c == '\t':
+		return " "
+	}
+	return winAnsiHigh[c]
+}
+
+// parseCMap reads the character code mappings of a ToUnicode CMap, and sets
+// codeLen from its code space
+func parseCMap(data []byte, codeLen *int) map[string]string {
+	m := map[string]string{}
+	l := &lexer{data: data}
+	var operands []interface{}
+	section := ""
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return m
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = string(op)
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if lo, ok := operands[0].([]byte); ok && len(lo) > 0 {
+					*codeLen = len(lo)
+				}
+			}
+			section = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].([]byte)
+				dst, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 {
+					m[string(src)] = utf16Text(dst)
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].([]byte)
+				hi, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 && len(lo) == len(hi) {
+					bfrange(m, lo, hi, operands[i+2])
+				}
+			}
+			section = ""
+		}
+		if section == "" || op == keyword(section) {
+			operands = operands[:0]
+		}
+	}
+}
+
+// bfrange maps the codes from lo to hi. dst is either the text of lo, which
+// the following codes increment, or an array with the text of each code.
+func bfrange(m map[string]string, lo, hi []byte, dst interface{}) {
+	start, end := codeValue(lo), codeValue(hi)
+	if end < start || end-start > 1<<16 {
+		return
+	}
+	for c := start; c <= end; c++ {
+		src := make([]byte, len(lo))
+		for i, v := len(src)-1, c; i >= 0; i, v = i-1, v>>8 {
+			src[i] = byte(v)
+		}
+
+		switch dst := dst.(type) {
+		case []byte:
+			if len(dst) == 0 {
+				return
+			}
+			text := bytes.Clone(dst)
+			text[len(text)-1] += byte(c - start)
+			m[string(src)] = utf16Text(text)
+		case Array:
+			if c-start < len(dst) {
+				if text, ok := dst[c-start].([]byte); ok {
+					m[string(src)] = utf16Text(text)
+				}
+			}
+		}
+	}
+}
+
+func codeValue(b []byte) int {
+	v := 0
+	for _, c := range b {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+// utf16Text decodes the UTF-16BE text of a CMap entry
+func utf16Text(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
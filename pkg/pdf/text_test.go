@@ -0,0 +1,232 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a PDF whose objects are numbered from 1, with the catalog
+// first, followed by a cross-reference table and trailer. Empty objects are
+// left out, for objects packed into object streams.
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		if obj == "" {
+			continue
+		}
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// stream writes a stream object with the entries dict
+func stream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(s string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestExtractText(t *testing.T) {
+	// The lines are drawn bottom up and the balance's label and amount
+	// separately, as statement generators often do
+	content := `BT
+/F1 10 Tf
+72 600 Td
+[(Pay)-20(ment Due Date: December 10, 2024)] TJ
+ET
+BT
+/F1 10 Tf
+72 620 Td
+(New Balance) Tj
+400 0 Td
+($892.50) Tj
+ET
+BT
+/F1 14 Tf
+1 0 0 1 72 700 Tm
+(TD Aeroplan Visa \(9876\)) Tj
+12 TL
+T* (Statement Date: November 15, 2024) '
+ET`
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 4 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		stream("/Filter /FlateDecode", deflate(content)),
+	)
+
+	text, err := ExtractText(data)
+	if err != nil {
+		t.Fatalf("ExtractText failed: %v", err)
+	}
+	want := `TD Aeroplan Visa (9876)
+Statement Date: November 15, 2024
+New Balance $892.50
+Payment Due Date: December 10, 2024`
+	if text != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, text)
+	}
+}
+
+func TestExtractText_UnicodeFontsAndForms(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0041>
+<0002> <006D>
+endbfchar
+2 beginbfrange
+<0003> <0004> <0065>
+<0005> <0006> [<0078> <0020>]
+endbfrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	// Object 6 is packed into the object stream 9
+	packed := "<< /Type /Font /Subtype /Type0 /BaseFont /ABCDEF+Arial /Encoding /Identity-H /DescendantFonts [7 0 R] /ToUnicode 8 0 R >>"
+	objStm := fmt.Sprintf("6 0 %s", packed)
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 6 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /XObject << /X1 10 0 R >> >> /Contents 11 0 R >>",
+		stream("/Filter /FlateDecode", deflate("BT /F1 12 Tf 72 700 Td <0001000200030005000600010002000400050005> Tj ET")),
+		"",
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /ABCDEF+Arial /DW 600 /W [1 [722 833] 3 6 556] >>",
+		stream("/Filter /FlateDecode", deflate(cmap)),
+		stream(fmt.Sprintf("/Type /ObjStm /N 1 /First %d /Filter /FlateDecode", len("6 0 ")), deflate(objStm)),
+		stream("/Type /XObject /Subtype /Form /BBox [0 0 612 792] /Resources << /Font << /F2 12 0 R >> >>",
+			[]byte("BT /F2 9 Tf 72 100 Td (Page 2 footer) Tj ET")),
+		stream("", []byte("q 1 0 0 1 0 0 cm /X1 Do Q")),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+	)
+
+	text, err := ExtractText(data)
+	if err != nil {
+		t.Fatalf("ExtractText failed: %v", err)
+	}
+	want := "Amex Amfxx\n\nPage 2 footer"
+	if text != want {
+		t.Errorf("Expected %q, got %q", want, text)
+	}
+}
+
+func TestExtractText_Errors(t *testing.T) {
+	if _, err := ExtractText([]byte("hello")); !errors.Is(err, ErrNotPDF) {
+		t.Errorf("Expected ErrNotPDF, got %v", err)
+	}
+
+	encrypted := bytes.Replace(buildPDF("<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] /Count 0 >>"),
+		[]byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt << /Filter /Standard >>"), 1)
+	if _, err := ExtractText(encrypted); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted, got %v", err)
+	}
+
+	if _, err := ExtractText([]byte("%PDF-1.4\n%%EOF\n")); err == nil {
+		t.Error("Expected an error for a PDF without pages")
+	}
+}
+
+func TestExtractText_BadObjectStreamOffsets(t *testing.T) {
+	// Object streams are untrusted; offsets before or past their data skip
+	// the object rather than panicking
+	for _, first := range []string{"-50", "100000", "9223372036854775807"} {
+		data := buildPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+			stream("", []byte("BT 72 700 Td (Statement) Tj ET")),
+			stream("/Type /ObjStm /N 2 /First "+first, []byte("6 0 7 9223372036854775807 << /Type /Font >>")),
+		)
+		if _, err := ExtractText(data); err != nil {
+			t.Errorf("/First %s: ExtractText failed: %v", first, err)
+		}
+	}
+}
+
+func TestExtractText_TooLarge(t *testing.T) {
+	defer func(stream, document int) {
+		maxStreamSize, maxDocumentSize = stream, document
+	}(maxStreamSize, maxDocumentSize)
+	maxStreamSize, maxDocumentSize = 1000, 1500
+
+	page := func(size int) []byte {
+		content := "BT 72 700 Td (Statement) Tj ET\n" + strings.Repeat(" ", size)
+		return buildPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents [4 0 R 5 0 R] >>",
+			stream("/Filter /FlateDecode", deflate(content)),
+			stream("/Filter /FlateDecode", deflate(content)),
+		)
+	}
+	if _, err := ExtractText(page(500)); err != nil {
+		t.Errorf("Expected streams within the limits to be read, got %v", err)
+	}
+	if _, err := ExtractText(page(1000)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge for a stream over the limit, got %v", err)
+	}
+	if _, err := ExtractText(page(800)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge for a document over the limit, got %v", err)
+	}
+}
+
+func FuzzExtractText(f *testing.F) {
+	f.Add([]byte("%PDF-1.4\n%%EOF\n"))
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 4 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		stream("/Filter /FlateDecode", deflate("BT /F1 10 Tf 72 600 Td [(New)-20( Balance)] TJ <2431> Tj ET")),
+	))
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		stream("/Filter /ASCIIHexDecode", []byte("4254202F4631203132205466203C303030313E20546A204554>")),
+		"",
+		stream("/Type /ObjStm /N 1 /First -50", []byte("5 0 << /Type /Font /Subtype /Type0 /ToUnicode 7 0 R >>")),
+		stream("/Filter /FlateDecode", deflate("1 beginbfchar <0001> <0041> endbfchar")),
+	))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Malformed files may fail, but must not panic
+		ExtractText(data)
+	})
+}
+
+func TestIsPDF(t *testing.T) {
+	if !IsPDF([]byte("%PDF-1.7\n")) {
+		t.Error("Expected a PDF header to be recognised")
+	}
+	if IsPDF([]byte("<html>")) {
+		t.Error("Expected HTML not to be a PDF")
+	}
+}
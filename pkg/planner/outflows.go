@@ -0,0 +1,135 @@
+package planner
+
+import (
+	"sort"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+)
+
+// Outflows lists the card payments leaving a funding account by date, so the
+// account can be checked to cover them
+type Outflows struct {
+	Account models.FundingAccount `json:"account"`
+	From    string                `json:"from"`
+	To      string                `json:"to"`
+	Days    []OutflowDay          `json:"days"`
+	Total   models.Money          `json:"total"`
+	// Shortfall reports whether the account's tracked balance doesn't cover
+	// Total; it is never set when the balance isn't tracked
+	Shortfall bool `json:"shortfall"`
+}
+
+// OutflowDay is every payment leaving the account on one date
+type OutflowDay struct {
+	Date     string       `json:"date"`
+	Payments []Outflow    `json:"payments"`
+	Total    models.Money `json:"total"`
+	// BalanceAfter is the tracked balance left once this and every earlier
+	// day's payments are made
+	BalanceAfter *models.Money `json:"balance_after,omitempty"`
+}
+
+// Outflow is an unpaid statement's remaining balance, or what autopay will
+// pull for it
+type Outflow struct {
+	StatementID int          `json:"statement_id"`
+	CardID      int          `json:"card_id"`
+	CardName    string       `json:"card_name"`
+	LastFour    string       `json:"last_four"`
+	Amount      models.Money `json:"amount"`
+	DueDate     string       `json:"due_date"`
+	// Scheduled is false when no payment is scheduled yet and the date is
+	// the recommended one
+	Scheduled bool `json:"scheduled"`
+}
+
+// AccountOutflows lists the unpaid statements paid from account over days
+// days from today. Scheduled payments use their scheduled date; the rest use
+// the card's recommended date, or today once that has passed. Scheduled
+// dates before today are left out since those payments have already been
+// sent. Cards on autopay only take what autopay pulls, e.g. the minimum.
+func AccountOutflows(account models.FundingAccount, cards []models.CreditCard, statements []models.Statement, cal *holidays.Calendar, paydays recommend.Paydays, today time.Time, days int) Outflows {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.Format(models.DateFormat)
+	to := today.AddDate(0, 0, days-1).Format(models.DateFormat)
+
+	cardByID := make(map[int]models.CreditCard, len(cards))
+	for _, card := range cards {
+		cardByID[card.ID] = card
+	}
+
+	byDate := map[string][]Outflow{}
+	for _, stmt := range statements {
+		if stmt.FundingAccountID == nil || *stmt.FundingAccountID != account.ID {
+			continue
+		}
+		card, ok := cardByID[stmt.CardID]
+		if !ok || stmt.Status == models.StatusPaid || stmt.RemainingBalance <= 0 {
+			continue
+		}
+
+		amount := stmt.RemainingBalance
+		if card.HasAutopay() {
+			amount = card.AutopayPayment(stmt)
+			if amount <= 0 {
+				continue
+			}
+		}
+
+		outflow := Outflow{
+			StatementID: stmt.ID,
+			CardID:      card.ID,
+			CardName:    card.Name,
+			LastFour:    card.LastFour,
+			Amount:      amount,
+			DueDate:     stmt.DueDate,
+		}
+		// Dates are ISO strings, so they can be compared lexically
+		var date string
+		if stmt.ScheduledPaymentDate != nil {
+			date = *stmt.ScheduledPaymentDate
+			outflow.Scheduled = true
+		} else {
+			rec, err := recommend.ForStatement(card, stmt, cal, paydays)
+			if err != nil {
+				continue
+			}
+			date = rec.PaymentDate
+			if date < from {
+				date = from
+			}
+		}
+		if date < from || date > to {
+			continue
+		}
+		byDate[date] = append(byDate[date], outflow)
+	}
+
+	outflows := Outflows{Account: account, From: from, To: to, Days: []OutflowDay{}}
+	dates := make([]string, 0, len(byDate))
+	for date := range byDate {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	for _, date := range dates {
+		payments := byDate[date]
+		sort.Slice(payments, func(i, j int) bool { return payments[i].StatementID < payments[j].StatementID })
+
+		day := OutflowDay{Date: date, Payments: payments}
+		for _, payment := range payments {
+			day.Total += payment.Amount
+		}
+		outflows.Total += day.Total
+		if account.Balance != nil {
+			left := *account.Balance - outflows.Total
+			day.BalanceAfter = &left
+		}
+		outflows.Days = append(outflows.Days, day)
+	}
+	outflows.Shortfall = account.Balance != nil && outflows.Total > *account.Balance
+	return outflows
+}
@@ -0,0 +1,120 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func TestAccountOutflows(t *testing.T) {
+	balance := models.MustParseMoney("1000.00")
+	account := models.FundingAccount{ID: 1, Name: "Chequing", Balance: &balance}
+	other := 2
+
+	cards := []models.CreditCard{
+		{ID: 1, Name: "Visa", LastFour: "1111"},
+		{ID: 2, Name: "Amex", LastFour: "2222"},
+	}
+	scheduled := func(stmt models.Statement, accountID *int, date string) models.Statement {
+		stmt.FundingAccountID = accountID
+		if date != "" {
+			stmt.ScheduledPaymentDate = &date
+		}
+		return stmt
+	}
+	paid := statement(7, 1, "2024-11-20", "100.00", "100.00")
+	paid.Status = models.StatusPaid
+	statements := []models.Statement{
+		scheduled(statement(2, 2, "2024-11-15", "500.00", "0"), &account.ID, "2024-11-06"),
+		scheduled(statement(1, 1, "2024-11-15", "500.00", "200.00"), &account.ID, "2024-11-06"),
+		// Unscheduled and already past its recommended date, so it's due today
+		scheduled(statement(3, 2, "2024-11-08", "400.00", "0"), &account.ID, ""),
+		// Other accounts, payments already sent and those past the window
+		// are left out
+		scheduled(statement(4, 1, "2024-11-15", "900.00", "0"), &other, "2024-11-06"),
+		scheduled(statement(5, 1, "2024-11-05", "900.00", "0"), &account.ID, "2024-11-01"),
+		scheduled(statement(6, 2, "2024-12-01", "900.00", "0"), &account.ID, "2024-11-20"),
+		scheduled(paid, &account.ID, "2024-11-05"),
+	}
+
+	outflows := AccountOutflows(account, cards, statements, nil, nil, date("2024-11-04"), 7)
+	if outflows.From != "2024-11-04" || outflows.To != "2024-11-10" {
+		t.Errorf("Expected the week from 2024-11-04, got %s to %s", outflows.From, outflows.To)
+	}
+
+	expected := []struct {
+		date       string
+		statements []int
+		total      string
+		balance    string
+	}{
+		{"2024-11-04", []int{3}, "400.00", "600.00"},
+		{"2024-11-06", []int{1, 2}, "800.00", "-200.00"},
+	}
+	if len(outflows.Days) != len(expected) {
+		t.Fatalf("Expected %d days, got %+v", len(expected), outflows.Days)
+	}
+	for i, want := range expected {
+		day := outflows.Days[i]
+		if day.Date != want.date || day.Total.String() != want.total || day.BalanceAfter == nil || day.BalanceAfter.String() != want.balance {
+			t.Errorf("Day %d: expected %s total %s leaving %s, got %+v", i, want.date, want.total, want.balance, day)
+		}
+		if len(day.Payments) != len(want.statements) {
+			t.Errorf("Day %d: expected statements %v, got %+v", i, want.statements, day.Payments)
+			continue
+		}
+		for j, payment := range day.Payments {
+			if payment.StatementID != want.statements[j] {
+				t.Errorf("Day %d: expected statements %v, got %+v", i, want.statements, day.Payments)
+				break
+			}
+		}
+	}
+	if outflows.Days[0].Payments[0].Scheduled || !outflows.Days[1].Payments[0].Scheduled {
+		t.Errorf("Expected only the 2024-11-06 payments to be scheduled, got %+v", outflows.Days)
+	}
+	if outflows.Total.String() != "1200.00" || !outflows.Shortfall {
+		t.Errorf("Expected a 1200.00 shortfall, got %s (%v)", outflows.Total, outflows.Shortfall)
+	}
+
+	// Without a tracked balance there is nothing to fall short of
+	account.Balance = nil
+	outflows = AccountOutflows(account, cards, statements, nil, nil, date("2024-11-04"), 7)
+	if outflows.Shortfall || outflows.Days[0].BalanceAfter != nil {
+		t.Errorf("Expected no balance projection, got %+v", outflows)
+	}
+}
+
+func TestAccountOutflows_Autopay(t *testing.T) {
+	balance := models.MustParseMoney("100.00")
+	account := models.FundingAccount{ID: 1, Name: "Chequing", Balance: &balance}
+	cards := []models.CreditCard{
+		{ID: 1, Name: "Visa", Autopay: models.AutopayMinimum},
+		{ID: 2, Name: "Amex", Autopay: models.AutopayFixedAmount, AutopayAmount: models.MustParseMoney("50.00")},
+		{ID: 3, Name: "Citi", Autopay: models.AutopayMinimum},
+	}
+	onDueDate := func(stmt models.Statement, minimum string) models.Statement {
+		stmt.FundingAccountID = &account.ID
+		stmt.ScheduledPaymentDate = &stmt.DueDate
+		stmt.MinimumPayment = models.MustParseMoney(minimum)
+		return stmt
+	}
+	statements := []models.Statement{
+		onDueDate(statement(1, 1, "2024-11-06", "900.00", "0"), "25.00"),
+		onDueDate(statement(2, 2, "2024-11-06", "900.00", "0"), "25.00"),
+		// The minimum has already been paid, so autopay pulls nothing
+		onDueDate(statement(3, 3, "2024-11-06", "900.00", "30.00"), "25.00"),
+	}
+
+	// Only what autopay pulls leaves the account, not the full balances
+	outflows := AccountOutflows(account, cards, statements, nil, nil, date("2024-11-04"), 7)
+	if len(outflows.Days) != 1 || len(outflows.Days[0].Payments) != 2 {
+		t.Fatalf("Expected the Visa and Amex payments, got %+v", outflows.Days)
+	}
+	if got := outflows.Days[0].Payments; got[0].Amount.String() != "25.00" || got[1].Amount.String() != "50.00" {
+		t.Errorf("Expected the minimum and the fixed amount, got %+v", got)
+	}
+	if outflows.Total.String() != "75.00" || outflows.Shortfall {
+		t.Errorf("Expected 75.00 covered by the balance, got %s (%v)", outflows.Total, outflows.Shortfall)
+	}
+}
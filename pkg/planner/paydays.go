@@ -0,0 +1,105 @@
+// Package planner lines statement payments up with paydays, so each payment
+// is made from income that has already landed
+package planner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+)
+
+// paydaySearchDays is how far ahead NextPayday looks, which covers a monthly
+// schedule moved back over a long weekend
+const paydaySearchDays = 45
+
+// Store lists the income schedules paydays come from
+type Store interface {
+	List(ctx context.Context) ([]models.IncomeSchedule, error)
+}
+
+// Payday is a day income lands, with every schedule paying that day
+type Payday struct {
+	Date    string       `json:"date"`
+	Sources []string     `json:"sources"`
+	Amount  models.Money `json:"amount"`
+}
+
+// Paydays works out the paydays of a set of income schedules. A payday that
+// isn't a business day of the calendar is moved back to the previous one, as
+// employers pay early rather than late.
+type Paydays struct {
+	schedules []models.IncomeSchedule
+	cal       *holidays.Calendar
+}
+
+// NewPaydays creates paydays for schedules on business days of cal. A nil
+// calendar only skips weekends.
+func NewPaydays(schedules []models.IncomeSchedule, cal *holidays.Calendar) *Paydays {
+	return &Paydays{schedules: schedules, cal: cal}
+}
+
+// LoadPaydays reads the income schedules from store for the payday payment
+// policy. It returns nil, rather than empty paydays, when there are none so
+// the policy can explain that no schedule is set up.
+func LoadPaydays(ctx context.Context, store Store, cal *holidays.Calendar) (recommend.Paydays, error) {
+	if store == nil {
+		return nil, nil
+	}
+	schedules, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load income schedules: %w", err)
+	}
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+	return NewPaydays(schedules, cal), nil
+}
+
+// Between returns the paydays from from to to inclusive in date order
+func (p *Paydays) Between(from, to time.Time) []Payday {
+	if p == nil {
+		return nil
+	}
+
+	byDate := map[string]*Payday{}
+	for _, s := range p.schedules {
+		// Look past to for paydays moved back into the range
+		for _, nominal := range s.PaydaysBetween(from, to.AddDate(0, 0, 14)) {
+			date, _ := p.cal.PreviousBusinessDay(nominal)
+			if date.Before(from) || date.After(to) {
+				continue
+			}
+			key := date.Format(models.DateFormat)
+			payday, ok := byDate[key]
+			if !ok {
+				payday = &Payday{Date: key, Sources: []string{}}
+				byDate[key] = payday
+			}
+			payday.Sources = append(payday.Sources, s.Name)
+			payday.Amount += s.Amount
+		}
+	}
+
+	paydays := make([]Payday, 0, len(byDate))
+	for _, payday := range byDate {
+		paydays = append(paydays, *payday)
+	}
+	// Dates are ISO strings, so they can be compared lexically
+	sort.Slice(paydays, func(i, j int) bool { return paydays[i].Date < paydays[j].Date })
+	return paydays
+}
+
+// NextPayday returns the first payday on or after date
+func (p *Paydays) NextPayday(date time.Time) (time.Time, bool) {
+	paydays := p.Between(date, date.AddDate(0, 0, paydaySearchDays))
+	if len(paydays) == 0 {
+		return time.Time{}, false
+	}
+	next, err := time.Parse(models.DateFormat, paydays[0].Date)
+	return next, err == nil
+}
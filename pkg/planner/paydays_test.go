@@ -0,0 +1,90 @@
+package planner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// fakeStore returns a fixed list of schedules
+type fakeStore []models.IncomeSchedule
+
+func (s fakeStore) List(ctx context.Context) ([]models.IncomeSchedule, error) {
+	return s, nil
+}
+
+func date(s string) time.Time {
+	d, _ := time.Parse(models.DateFormat, s)
+	return d
+}
+
+func TestPaydays_Between(t *testing.T) {
+	cal := holidays.NewCalendar([]string{holidays.RegionCA}, nil)
+	paydays := NewPaydays([]models.IncomeSchedule{
+		{Name: "Salary", Frequency: models.IncomeBiweekly, AnchorDate: "2024-11-29", Amount: models.MustParseMoney("2000.00")},
+		// The 30th of November is a Saturday, so it lands with the salary
+		{Name: "Rental", Frequency: models.IncomeMonthly, AnchorDate: "2024-11-30", Amount: models.MustParseMoney("900.00")},
+	}, cal)
+
+	got := paydays.Between(date("2024-11-01"), date("2024-12-31"))
+	expected := []struct {
+		date    string
+		sources string
+		amount  string
+	}{
+		{"2024-11-29", "Salary, Rental", "2900.00"},
+		{"2024-12-13", "Salary", "2000.00"},
+		{"2024-12-27", "Salary", "2000.00"},
+		{"2024-12-30", "Rental", "900.00"},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d paydays, got %+v", len(expected), got)
+	}
+	for i, want := range expected {
+		if got[i].Date != want.date || strings.Join(got[i].Sources, ", ") != want.sources || got[i].Amount.String() != want.amount {
+			t.Errorf("Expected payday %d to be %+v, got %+v", i, want, got[i])
+		}
+	}
+}
+
+func TestPaydays_NextPayday(t *testing.T) {
+	cal := holidays.NewCalendar([]string{holidays.RegionCA}, nil)
+	paydays := NewPaydays([]models.IncomeSchedule{
+		{Name: "Salary", Frequency: models.IncomeMonthly, AnchorDate: "2024-11-25", Amount: models.MustParseMoney("4000.00")},
+	}, cal)
+
+	// Christmas moves December's payday back to Christmas Eve
+	next, ok := paydays.NextPayday(date("2024-11-26"))
+	if !ok || next.Format(models.DateFormat) != "2024-12-24" {
+		t.Errorf("Expected the next payday on 2024-12-24, got %s (%v)", next.Format(models.DateFormat), ok)
+	}
+
+	if _, ok := NewPaydays(nil, cal).NextPayday(date("2024-11-26")); ok {
+		t.Error("Expected no payday without schedules")
+	}
+}
+
+func TestLoadPaydays(t *testing.T) {
+	ctx := context.Background()
+
+	// No schedules gives a nil interface, not empty paydays
+	if paydays, err := LoadPaydays(ctx, nil, nil); err != nil || paydays != nil {
+		t.Errorf("Expected nil paydays without a store, got %v (%v)", paydays, err)
+	}
+	if paydays, err := LoadPaydays(ctx, fakeStore{}, nil); err != nil || paydays != nil {
+		t.Errorf("Expected nil paydays without schedules, got %v (%v)", paydays, err)
+	}
+
+	store := fakeStore{{Name: "Salary", Frequency: models.IncomeBiweekly, AnchorDate: "2024-11-01", Amount: models.MustParseMoney("2000.00")}}
+	paydays, err := LoadPaydays(ctx, store, nil)
+	if err != nil || paydays == nil {
+		t.Fatalf("Expected paydays, got %v (%v)", paydays, err)
+	}
+	if next, ok := paydays.NextPayday(date("2024-11-02")); !ok || next.Format(models.DateFormat) != "2024-11-15" {
+		t.Errorf("Expected the next payday on 2024-11-15, got %v", next)
+	}
+}
@@ -0,0 +1,156 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+)
+
+// Plan assigns every unpaid statement to the payday that funds it and
+// projects the cash left after each one
+type Plan struct {
+	Date         string       `json:"date"`
+	StartingCash models.Money `json:"starting_cash"`
+	// Periods starts with the cash on hand today, followed by one period per
+	// payday up to the last statement's pay-by date
+	Periods []Period `json:"periods"`
+	// TotalIncome is the sum of the paydays, not counting StartingCash
+	TotalIncome models.Money `json:"total_income"`
+	TotalDue    models.Money `json:"total_due"`
+	// EndingCash is the projected cash once every planned payment is made
+	EndingCash models.Money `json:"ending_cash"`
+	// Shortfalls counts the periods whose payments exceed their income
+	Shortfalls int `json:"shortfalls"`
+}
+
+// Period is the time from one payday to the next. The first period has no
+// payday; its income is the cash already on hand.
+type Period struct {
+	Date     string           `json:"date"`
+	Payday   bool             `json:"payday"`
+	Sources  []string         `json:"sources"`
+	Income   models.Money     `json:"income"`
+	Payments []PlannedPayment `json:"payments"`
+	TotalDue models.Money     `json:"total_due"`
+	// RemainingCash is the projected cash after the period's income lands and
+	// its payments are made, carried over from earlier periods
+	RemainingCash models.Money `json:"remaining_cash"`
+	// Shortfall reports whether the period's payments exceed its income
+	Shortfall bool `json:"shortfall"`
+}
+
+// PlannedPayment is an unpaid statement's remaining balance
+type PlannedPayment struct {
+	StatementID int          `json:"statement_id"`
+	CardID      int          `json:"card_id"`
+	CardName    string       `json:"card_name"`
+	LastFour    string       `json:"last_four"`
+	Amount      models.Money `json:"amount"`
+	DueDate     string       `json:"due_date"`
+	// PayBy is the last day that leaves the card's lead time before the due
+	// date
+	PayBy string `json:"pay_by"`
+	// Note explains a payment that no payday arrives in time for
+	Note string `json:"note,omitempty"`
+}
+
+// Build plans the unpaid statements from today with cash already on hand.
+// Each statement goes to the earliest payday that still leaves its card's
+// lead time before the due date, or to the cash on hand if no payday does.
+// Statements for cards that aren't in cards are ignored.
+func Build(cards []models.CreditCard, statements []models.Statement, paydays *Paydays, cal *holidays.Calendar, today time.Time, cash models.Money) Plan {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	todayStr := today.Format(models.DateFormat)
+
+	cardByID := make(map[int]models.CreditCard, len(cards))
+	for _, card := range cards {
+		cardByID[card.ID] = card
+	}
+
+	var payments []PlannedPayment
+	horizon := today
+	for _, stmt := range statements {
+		card, ok := cardByID[stmt.CardID]
+		if !ok || stmt.Status == models.StatusPaid || stmt.RemainingBalance <= 0 {
+			continue
+		}
+		// Only the pay-by date is needed, which doesn't depend on paydays
+		rec, err := recommend.ForStatement(card, stmt, cal, nil)
+		if err != nil {
+			continue
+		}
+		if payBy, err := time.Parse(models.DateFormat, rec.SafeBy); err == nil && payBy.After(horizon) {
+			horizon = payBy
+		}
+		payments = append(payments, PlannedPayment{
+			StatementID: stmt.ID,
+			CardID:      card.ID,
+			CardName:    card.Name,
+			LastFour:    card.LastFour,
+			Amount:      stmt.RemainingBalance,
+			DueDate:     stmt.DueDate,
+			PayBy:       rec.SafeBy,
+		})
+	}
+	sort.SliceStable(payments, func(i, j int) bool {
+		if payments[i].PayBy != payments[j].PayBy {
+			return payments[i].PayBy < payments[j].PayBy
+		}
+		return payments[i].StatementID < payments[j].StatementID
+	})
+
+	plan := Plan{
+		Date:         todayStr,
+		StartingCash: cash,
+		Periods: []Period{{
+			Date:     todayStr,
+			Sources:  []string{},
+			Income:   cash,
+			Payments: []PlannedPayment{},
+		}},
+	}
+	for _, payday := range paydays.Between(today, horizon) {
+		plan.Periods = append(plan.Periods, Period{
+			Date:     payday.Date,
+			Payday:   true,
+			Sources:  payday.Sources,
+			Income:   payday.Amount,
+			Payments: []PlannedPayment{},
+		})
+	}
+
+	// Dates are ISO strings, so they can be compared lexically
+	for _, payment := range payments {
+		i := 0
+		if len(plan.Periods) > 1 && plan.Periods[1].Date <= payment.PayBy {
+			i = 1
+		} else if payment.PayBy < todayStr {
+			payment.Note = fmt.Sprintf("Pay as soon as possible; %s has already passed.", payment.PayBy)
+		} else {
+			payment.Note = fmt.Sprintf("No payday arrives by %s, so this is paid from cash on hand.", payment.PayBy)
+		}
+		plan.Periods[i].Payments = append(plan.Periods[i].Payments, payment)
+		plan.Periods[i].TotalDue += payment.Amount
+	}
+
+	remaining := models.Money(0)
+	for i := range plan.Periods {
+		period := &plan.Periods[i]
+		remaining += period.Income - period.TotalDue
+		period.RemainingCash = remaining
+		period.Shortfall = period.TotalDue > period.Income
+		if period.Shortfall {
+			plan.Shortfalls++
+		}
+		if period.Payday {
+			plan.TotalIncome += period.Income
+		}
+		plan.TotalDue += period.TotalDue
+	}
+	plan.EndingCash = remaining
+	return plan
+}
@@ -0,0 +1,112 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func statement(id, cardID int, dueDate, amount, paid string) models.Statement {
+	stmt := models.Statement{
+		ID:      id,
+		CardID:  cardID,
+		DueDate: dueDate,
+		Amount:  models.MustParseMoney(amount),
+		Status:  models.StatusPending,
+	}
+	stmt.SetPaidAmount(models.MustParseMoney(paid))
+	return stmt
+}
+
+func TestBuild(t *testing.T) {
+	cards := []models.CreditCard{
+		{ID: 1, Name: "Visa", LastFour: "1111"},
+		{ID: 2, Name: "Amex", LastFour: "2222"},
+		{ID: 3, Name: "Mastercard", LastFour: "3333"},
+	}
+	statements := []models.Statement{
+		// Must be paid by 2024-11-05, before the first payday
+		statement(1, 1, "2024-11-12", "300.00", "0"),
+		statement(2, 2, "2024-11-20", "1500.00", "0"),
+		// Only the remaining balance is planned
+		statement(3, 3, "2024-11-25", "1000.00", "400.00"),
+		statement(4, 1, "2024-12-05", "400.00", "0"),
+		// Paid statements and unknown cards are left out
+		statement(5, 2, "2024-11-20", "250.00", "250.00"),
+		statement(6, 9, "2024-11-20", "250.00", "0"),
+	}
+	paydays := NewPaydays([]models.IncomeSchedule{
+		{Name: "Salary", Frequency: models.IncomeBiweekly, AnchorDate: "2024-11-08", Amount: models.MustParseMoney("2000.00")},
+	}, nil)
+
+	plan := Build(cards, statements, paydays, nil, date("2024-11-04"), models.MustParseMoney("500.00"))
+
+	expected := []struct {
+		date       string
+		income     string
+		statements []int
+		due        string
+		remaining  string
+		shortfall  bool
+	}{
+		{"2024-11-04", "500.00", []int{1}, "300.00", "200.00", false},
+		{"2024-11-08", "2000.00", []int{2, 3, 4}, "2500.00", "-300.00", true},
+		{"2024-11-22", "2000.00", nil, "0.00", "1700.00", false},
+	}
+	if len(plan.Periods) != len(expected) {
+		t.Fatalf("Expected %d periods, got %+v", len(expected), plan.Periods)
+	}
+	for i, want := range expected {
+		period := plan.Periods[i]
+		if period.Date != want.date || period.Income.String() != want.income || period.Payday != (i > 0) {
+			t.Errorf("Period %d: expected %s with income %s, got %s with %s", i, want.date, want.income, period.Date, period.Income)
+		}
+		var ids []int
+		for _, payment := range period.Payments {
+			ids = append(ids, payment.StatementID)
+		}
+		if len(ids) != len(want.statements) {
+			t.Errorf("Period %d: expected statements %v, got %v", i, want.statements, ids)
+		} else {
+			for j := range ids {
+				if ids[j] != want.statements[j] {
+					t.Errorf("Period %d: expected statements %v, got %v", i, want.statements, ids)
+					break
+				}
+			}
+		}
+		if period.TotalDue.String() != want.due || period.RemainingCash.String() != want.remaining || period.Shortfall != want.shortfall {
+			t.Errorf("Period %d: expected due %s remaining %s shortfall %v, got %s %s %v",
+				i, want.due, want.remaining, want.shortfall, period.TotalDue, period.RemainingCash, period.Shortfall)
+		}
+	}
+
+	first := plan.Periods[0].Payments[0]
+	if first.PayBy != "2024-11-05" || !strings.Contains(first.Note, "No payday arrives by 2024-11-05") {
+		t.Errorf("Expected a note about paying from cash on hand, got %+v", first)
+	}
+	if amount := plan.Periods[1].Payments[1].Amount.String(); amount != "600.00" {
+		t.Errorf("Expected the remaining balance 600.00 to be planned, got %s", amount)
+	}
+	if plan.TotalIncome.String() != "4000.00" || plan.TotalDue.String() != "2800.00" || plan.EndingCash.String() != "1700.00" || plan.Shortfalls != 1 {
+		t.Errorf("Unexpected totals %+v", plan)
+	}
+}
+
+func TestBuild_NoPaydays(t *testing.T) {
+	cards := []models.CreditCard{{ID: 1, Name: "Visa", LastFour: "1111"}}
+	statements := []models.Statement{statement(1, 1, "2024-11-01", "300.00", "0")}
+
+	plan := Build(cards, statements, nil, nil, date("2024-11-04"), 0)
+	if len(plan.Periods) != 1 {
+		t.Fatalf("Expected only the cash on hand period, got %+v", plan.Periods)
+	}
+	period := plan.Periods[0]
+	if !period.Shortfall || period.RemainingCash.String() != "-300.00" {
+		t.Errorf("Expected a shortfall of 300.00, got %+v", period)
+	}
+	if note := period.Payments[0].Note; !strings.Contains(note, "has already passed") {
+		t.Errorf("Expected a note about the late payment, got %q", note)
+	}
+}
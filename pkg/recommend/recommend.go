@@ -0,0 +1,168 @@
+// Package recommend works out when a statement should be paid, so the API,
+// dashboard and notifications all give the same advice
+package recommend
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// Paydays finds when income lands in the account payments are made from
+type Paydays interface {
+	// NextPayday returns the first payday on or after date, or false if
+	// there isn't one
+	NextPayday(date time.Time) (time.Time, bool)
+}
+
+// Recommendation is the suggested payment date for a statement and how it
+// was worked out
+type Recommendation struct {
+	// Policy is the card's payment policy the date follows
+	Policy string `json:"policy"`
+	// PaymentDate is the business day to make the payment on
+	PaymentDate string `json:"payment_date"`
+	// BaseDate is the date the policy gives before moving it to a business
+	// day
+	BaseDate string `json:"base_date"`
+	// SafeBy is the last day that leaves LeadDays business days before the
+	// due date
+	SafeBy   string `json:"safe_by"`
+	LeadDays int    `json:"lead_days"`
+	// LatestPaymentDate is the last business day on or before the due date
+	LatestPaymentDate string `json:"latest_payment_date"`
+	DueDate           string `json:"due_date"`
+	// Adjusted reports whether BaseDate wasn't a business day
+	Adjusted    bool   `json:"adjusted"`
+	Explanation string `json:"explanation"`
+	// Skipped lists the closed days PaymentDate was moved over
+	Skipped []holidays.Closure `json:"skipped,omitempty"`
+}
+
+// ForStatement recommends when to pay stmt following the card's payment
+// policy, on business days of cal. A nil calendar only skips weekends, and
+// without paydays the payday policy falls back to the card's lead time.
+func ForStatement(card models.CreditCard, stmt models.Statement, cal *holidays.Calendar, paydays Paydays) (Recommendation, error) {
+	due, err := time.Parse(models.DateFormat, stmt.DueDate)
+	if err != nil {
+		return Recommendation{}, fmt.Errorf("invalid due date %q: %w", stmt.DueDate, err)
+	}
+
+	leadDays := card.LeadDays()
+	safe, passed := cal.BusinessDaysBefore(due, leadDays)
+	latest, _ := cal.PreviousBusinessDay(due)
+
+	rec := Recommendation{
+		Policy:            card.PaymentPolicy,
+		SafeBy:            safe.Format(models.DateFormat),
+		LeadDays:          leadDays,
+		LatestPaymentDate: latest.Format(models.DateFormat),
+		DueDate:           stmt.DueDate,
+	}
+	if rec.Policy == "" {
+		rec.Policy = models.PaymentPolicyLeadTime
+	}
+	leadTime := fmt.Sprintf("%d business days before the due date", leadDays)
+
+	switch rec.Policy {
+	case models.PaymentPolicyStatementDay:
+		statementDate, err := time.Parse(models.DateFormat, stmt.StatementDate)
+		if err != nil {
+			return Recommendation{}, fmt.Errorf("invalid statement date %q: %w", stmt.StatementDate, err)
+		}
+		date, skipped := cal.NextBusinessDay(statementDate)
+		rec.set(statementDate, date, skipped, "Pay on the statement date", "forward")
+
+	case models.PaymentPolicyPayday:
+		statementDate, err := time.Parse(models.DateFormat, stmt.StatementDate)
+		if err != nil {
+			return Recommendation{}, fmt.Errorf("invalid statement date %q: %w", stmt.StatementDate, err)
+		}
+
+		var payday time.Time
+		var ok bool
+		if paydays != nil {
+			payday, ok = paydays.NextPayday(statementDate)
+		}
+		switch {
+		case paydays == nil:
+			rec.setLeadTime(due, safe, passed, "No payday schedule is set up, so pay "+leadTime)
+		case !ok || payday.After(safe):
+			rec.setLeadTime(due, safe, passed, fmt.Sprintf("No payday falls between the statement date and %s, so pay %s", rec.SafeBy, leadTime))
+		default:
+			date, skipped := cal.PreviousBusinessDay(payday)
+			rec.set(payday, date, skipped, "Pay on the first payday after the statement date", "back")
+		}
+
+	default:
+		rec.setLeadTime(due, safe, passed, "Pay "+leadTime)
+	}
+
+	if closed := cal.ClosedReason(due); closed != "" {
+		rec.Explanation += fmt.Sprintf(" The due date %s is not a business day (%s), so the latest a payment can be processed is %s.",
+			stmt.DueDate, closed, rec.LatestPaymentDate)
+	}
+	return rec, nil
+}
+
+// setLeadTime recommends paying on safe, which was reached by counting back
+// from due over the closed days passed. Weekends are expected when counting
+// business days, so only holidays count as an adjustment.
+func (r *Recommendation) setLeadTime(due, safe time.Time, passed []holidays.Closure, reason string) {
+	base, _ := (*holidays.Calendar)(nil).BusinessDaysBefore(due, r.LeadDays)
+	skipped := []holidays.Closure{}
+	for _, c := range passed {
+		if date, err := time.Parse(models.DateFormat, c.Date); err == nil && models.IsWeekday(date) {
+			skipped = append(skipped, c)
+		}
+	}
+	r.set(base, safe, skipped, reason, "back")
+}
+
+// set records that the policy gave base, which became date after moving in
+// direction over the skipped closed days, and explains it starting with
+// reason
+func (r *Recommendation) set(base, date time.Time, skipped []holidays.Closure, reason, direction string) {
+	r.BaseDate = base.Format(models.DateFormat)
+	r.PaymentDate = date.Format(models.DateFormat)
+	r.Adjusted = len(skipped) > 0
+	r.Skipped = skipped
+
+	r.Explanation = reason + "."
+	if r.Adjusted {
+		r.Explanation = fmt.Sprintf("%s, moved %s from %s to %s because %s.",
+			reason, direction, r.BaseDate, r.PaymentDate, describeClosures(skipped))
+	}
+}
+
+// Check returns warnings about paying on the scheduled date instead of the
+// recommended one. It never returns nil.
+func (r Recommendation) Check(scheduled string) []string {
+	warnings := []string{}
+	// Dates are ISO strings, so they can be compared lexically
+	switch {
+	case scheduled > r.DueDate:
+		warnings = append(warnings, fmt.Sprintf("The scheduled date %s is after the due date %s, so the payment will be late.",
+			scheduled, r.DueDate))
+	case scheduled > r.SafeBy:
+		warnings = append(warnings, fmt.Sprintf("The scheduled date %s is after %s, leaving less than %d business days before the due date %s.",
+			scheduled, r.SafeBy, r.LeadDays, r.DueDate))
+	}
+	return warnings
+}
+
+// describeClosures lists closed days, e.g. "2024-12-22 (Sunday) and
+// 2024-12-21 (Saturday) are not business days"
+func describeClosures(closures []holidays.Closure) string {
+	parts := make([]string, len(closures))
+	for i, c := range closures {
+		parts[i] = fmt.Sprintf("%s (%s)", c.Date, c.Reason)
+	}
+	if len(parts) == 1 {
+		return parts[0] + " is not a business day"
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1] + " are not business days"
+}
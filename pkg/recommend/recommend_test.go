@@ -0,0 +1,201 @@
+package recommend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// fixedPaydays is a list of paydays in date order
+type fixedPaydays []string
+
+func (p fixedPaydays) NextPayday(date time.Time) (time.Time, bool) {
+	for _, s := range p {
+		payday, _ := time.Parse(models.DateFormat, s)
+		if !payday.Before(date) {
+			return payday, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func TestForStatement_LeadTime(t *testing.T) {
+	cal := holidays.NewCalendar([]string{holidays.RegionCA}, nil)
+
+	tests := []struct {
+		name        string
+		leadDays    int
+		dueDate     string
+		paymentDate string
+		latest      string
+		skipped     int
+		explanation string
+	}{
+		{
+			name:        "business day",
+			dueDate:     "2024-12-10",
+			paymentDate: "2024-12-03",
+			latest:      "2024-12-10",
+			explanation: "Pay 5 business days before the due date.",
+		},
+		{
+			name:        "weekend due date",
+			dueDate:     "2024-12-01",
+			paymentDate: "2024-11-25",
+			latest:      "2024-11-29",
+			explanation: "Pay 5 business days before the due date. " +
+				"The due date 2024-12-01 is not a business day (Sunday), so the latest a payment can be processed is 2024-11-29.",
+		},
+		{
+			name:        "holidays",
+			dueDate:     "2025-01-01",
+			paymentDate: "2024-12-23",
+			latest:      "2024-12-31",
+			skipped:     2,
+			explanation: "Pay 5 business days before the due date, moved back from 2024-12-25 to 2024-12-23 because 2024-12-26 (Boxing Day) and 2024-12-25 (Christmas Day) are not business days. " +
+				"The due date 2025-01-01 is not a business day (New Year's Day), so the latest a payment can be processed is 2024-12-31.",
+		},
+		{
+			name:        "card lead time",
+			leadDays:    2,
+			dueDate:     "2024-12-10",
+			paymentDate: "2024-12-06",
+			latest:      "2024-12-10",
+			explanation: "Pay 2 business days before the due date.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := models.CreditCard{PaymentLeadDays: tt.leadDays}
+			rec, err := ForStatement(card, models.Statement{DueDate: tt.dueDate}, cal, nil)
+			if err != nil {
+				t.Fatalf("ForStatement failed: %v", err)
+			}
+			if rec.Policy != models.PaymentPolicyLeadTime {
+				t.Errorf("Expected the lead time policy by default, got %q", rec.Policy)
+			}
+			if rec.PaymentDate != tt.paymentDate || rec.SafeBy != tt.paymentDate {
+				t.Errorf("Expected payment date %s, got %s (safe by %s)", tt.paymentDate, rec.PaymentDate, rec.SafeBy)
+			}
+			if rec.LatestPaymentDate != tt.latest {
+				t.Errorf("Expected latest payment date %s, got %s", tt.latest, rec.LatestPaymentDate)
+			}
+			if len(rec.Skipped) != tt.skipped || rec.Adjusted != (tt.skipped > 0) {
+				t.Errorf("Expected %d skipped days, got %+v", tt.skipped, rec.Skipped)
+			}
+			if rec.Explanation != tt.explanation {
+				t.Errorf("Expected explanation %q, got %q", tt.explanation, rec.Explanation)
+			}
+		})
+	}
+}
+
+func TestForStatement_StatementDay(t *testing.T) {
+	cal := holidays.NewCalendar([]string{holidays.RegionCA}, nil)
+	card := models.CreditCard{PaymentPolicy: models.PaymentPolicyStatementDay}
+
+	rec, err := ForStatement(card, models.Statement{StatementDate: "2024-11-12", DueDate: "2024-12-03"}, cal, nil)
+	if err != nil {
+		t.Fatalf("ForStatement failed: %v", err)
+	}
+	if rec.PaymentDate != "2024-11-12" || rec.SafeBy != "2024-11-26" || rec.Explanation != "Pay on the statement date." {
+		t.Errorf("Unexpected recommendation %+v", rec)
+	}
+
+	// A statement released before a long weekend is paid the next business day
+	rec, _ = ForStatement(card, models.Statement{StatementDate: "2024-11-09", DueDate: "2024-12-02"}, cal, nil)
+	want := "Pay on the statement date, moved forward from 2024-11-09 to 2024-11-12 because 2024-11-09 (Saturday), 2024-11-10 (Sunday) and 2024-11-11 (Remembrance Day) are not business days."
+	if rec.PaymentDate != "2024-11-12" || rec.Explanation != want {
+		t.Errorf("Unexpected recommendation %+v", rec)
+	}
+}
+
+func TestForStatement_Payday(t *testing.T) {
+	cal := holidays.NewCalendar([]string{holidays.RegionCA}, nil)
+	card := models.CreditCard{PaymentPolicy: models.PaymentPolicyPayday}
+	stmt := models.Statement{StatementDate: "2024-11-12", DueDate: "2024-12-06"}
+
+	tests := []struct {
+		name        string
+		paydays     Paydays
+		paymentDate string
+		explanation string
+	}{
+		{
+			name:        "payday in window",
+			paydays:     fixedPaydays{"2024-11-01", "2024-11-15", "2024-11-29"},
+			paymentDate: "2024-11-15",
+			explanation: "Pay on the first payday after the statement date.",
+		},
+		{
+			name:        "payday too late",
+			paydays:     fixedPaydays{"2024-11-01", "2024-12-02"},
+			paymentDate: "2024-11-29",
+			explanation: "No payday falls between the statement date and 2024-11-29, so pay 5 business days before the due date.",
+		},
+		{
+			name:        "no schedule",
+			paymentDate: "2024-11-29",
+			explanation: "No payday schedule is set up, so pay 5 business days before the due date.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := ForStatement(card, stmt, cal, tt.paydays)
+			if err != nil {
+				t.Fatalf("ForStatement failed: %v", err)
+			}
+			if rec.Policy != models.PaymentPolicyPayday || rec.PaymentDate != tt.paymentDate {
+				t.Errorf("Expected payment date %s, got %+v", tt.paymentDate, rec)
+			}
+			if rec.Explanation != tt.explanation {
+				t.Errorf("Expected explanation %q, got %q", tt.explanation, rec.Explanation)
+			}
+		})
+	}
+}
+
+func TestForStatement_InvalidDates(t *testing.T) {
+	if _, err := ForStatement(models.CreditCard{}, models.Statement{DueDate: "soon"}, nil, nil); err == nil {
+		t.Error("Expected an error for an invalid due date")
+	}
+	card := models.CreditCard{PaymentPolicy: models.PaymentPolicyStatementDay}
+	if _, err := ForStatement(card, models.Statement{StatementDate: "", DueDate: "2024-12-06"}, nil, nil); err == nil {
+		t.Error("Expected an error for a missing statement date")
+	}
+}
+
+func TestRecommendation_Check(t *testing.T) {
+	rec, err := ForStatement(models.CreditCard{}, models.Statement{DueDate: "2024-12-06"}, nil, nil)
+	if err != nil {
+		t.Fatalf("ForStatement failed: %v", err)
+	}
+
+	tests := []struct {
+		scheduled string
+		want      string
+	}{
+		{"2024-11-25", ""},
+		{"2024-11-29", ""},
+		{"2024-12-02", "The scheduled date 2024-12-02 is after 2024-11-29, leaving less than 5 business days before the due date 2024-12-06."},
+		{"2024-12-06", "The scheduled date 2024-12-06 is after 2024-11-29, leaving less than 5 business days before the due date 2024-12-06."},
+		{"2024-12-09", "The scheduled date 2024-12-09 is after the due date 2024-12-06, so the payment will be late."},
+	}
+	for _, tt := range tests {
+		warnings := rec.Check(tt.scheduled)
+		if warnings == nil {
+			t.Fatalf("Expected an empty list rather than nil")
+		}
+		got := ""
+		if len(warnings) > 0 {
+			got = warnings[0]
+		}
+		if len(warnings) > 1 || got != tt.want {
+			t.Errorf("%s: expected warning %q, got %q", tt.scheduled, tt.want, warnings)
+		}
+	}
+}
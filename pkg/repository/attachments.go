@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// attachmentColumns are the attachments columns read by scanAttachment; the
+// data column is selected separately
+const attachmentColumns = `id, statement_id, filename, content_type, size_bytes, sha256, created_at`
+
+// SQLAttachmentRepository is an AttachmentRepository backed by SQLite or
+// Postgres. Files are stored in the database so backups and copy-data carry
+// them along.
+type SQLAttachmentRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewSQLiteAttachmentRepository creates an attachment repository using a
+// SQLite db
+func NewSQLiteAttachmentRepository(db *sql.DB) *SQLAttachmentRepository {
+	return &SQLAttachmentRepository{db: db, dialect: database.SQLite}
+}
+
+// NewPostgresAttachmentRepository creates an attachment repository using a
+// Postgres db
+func NewPostgresAttachmentRepository(db *sql.DB) *SQLAttachmentRepository {
+	return &SQLAttachmentRepository{db: db, dialect: database.Postgres}
+}
+
+// NewAttachmentRepository creates an attachment repository using db, choosing the SQL
+// dialect from its driver
+func NewAttachmentRepository(db *sql.DB) *SQLAttachmentRepository {
+	return &SQLAttachmentRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// scanAttachment reads a row selected with attachmentColumns, followed by
+// any extra destinations
+func scanAttachment(row scanner, extra ...interface{}) (models.Attachment, error) {
+	var a models.Attachment
+	var statementID sql.NullInt64
+
+	dest := append([]interface{}{&a.ID, &statementID, &a.Filename, &a.ContentType, &a.Size, &a.SHA256, &a.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return a, err
+	}
+	if statementID.Valid {
+		id := int(statementID.Int64)
+		a.StatementID = &id
+	}
+	return a, nil
+}
+
+// List returns the statement's attachments without their data, oldest first
+func (r *SQLAttachmentRepository) List(ctx context.Context, statementID int) ([]models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(
+		"SELECT "+attachmentColumns+" FROM attachments WHERE statement_id = ? ORDER BY created_at, id"), statementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments of statement %d: %w", statementID, err)
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// Get returns the attachment with its data
+func (r *SQLAttachmentRepository) Get(ctx context.Context, id int) (models.Attachment, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+attachmentColumns+", data FROM attachments WHERE id = ?"), id)
+	var data []byte
+	a, err := scanAttachment(row, &data)
+	if err == sql.ErrNoRows {
+		return a, ErrNotFound
+	}
+	if err != nil {
+		return a, fmt.Errorf("failed to query attachment %d: %w", id, err)
+	}
+	a.Data = data
+	return a, nil
+}
+
+// Create inserts the attachment and sets its ID
+func (r *SQLAttachmentRepository) Create(ctx context.Context, a *models.Attachment) error {
+	err := r.db.QueryRowContext(ctx, r.dialect.Rebind(`
+		INSERT INTO attachments (statement_id, filename, content_type, size_bytes, sha256, data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), a.StatementID, a.Filename, a.ContentType, a.Size, a.SHA256, a.Data, a.CreatedAt).Scan(&a.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert attachment: %w", err)
+	}
+	return nil
+}
+
+// Link attaches the attachment to a statement
+func (r *SQLAttachmentRepository) Link(ctx context.Context, id, statementID int) error {
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("UPDATE attachments SET statement_id = ? WHERE id = ?"), statementID, id)
+	if err != nil {
+		return fmt.Errorf("failed to link attachment %d: %w", id, err)
+	}
+	return checkAffected(result)
+}
+
+// Delete removes the attachment
+func (r *SQLAttachmentRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM attachments WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment %d: %w", id, err)
+	}
+	return checkAffected(result)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func TestAttachmentRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+		repo := &SQLAttachmentRepository{db: cards.db, dialect: cards.dialect}
+		now := time.Date(2024, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+		data := []byte("%PDF-1.4\n\x00\xff binary")
+		upload := models.Attachment{
+			Filename:    "statement.pdf",
+			ContentType: "application/pdf",
+			Size:        int64(len(data)),
+			SHA256:      "abc123",
+			CreatedAt:   now,
+			Data:        data,
+		}
+		if err := repo.Create(ctx, &upload); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if upload.ID == 0 {
+			t.Error("Expected the attachment ID to be set")
+		}
+
+		got, err := repo.Get(ctx, upload.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if !bytes.Equal(got.Data, data) || got.StatementID != nil || got.Filename != "statement.pdf" || got.Size != upload.Size {
+			t.Errorf("Unexpected attachment: %+v", got)
+		}
+		if _, err := repo.Get(ctx, 9999); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from Get, got %v", err)
+		}
+
+		card := createCard(t, cards, "Amex Cobalt")
+		stmt := models.Statement{
+			CardID:        card.ID,
+			StatementDate: "2024-11-15",
+			DueDate:       "2024-12-10",
+			Amount:        models.MustParseMoney("1250.75"),
+			Status:        "pending",
+		}
+		if err := statements.Create(ctx, &stmt); err != nil {
+			t.Fatalf("Create statement failed: %v", err)
+		}
+		if err := repo.Link(ctx, upload.ID, stmt.ID); err != nil {
+			t.Fatalf("Link failed: %v", err)
+		}
+		if err := repo.Link(ctx, 9999, stmt.ID); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from Link, got %v", err)
+		}
+
+		list, err := repo.List(ctx, stmt.ID)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(list) != 1 || list[0].StatementID == nil || *list[0].StatementID != stmt.ID || list[0].Data != nil {
+			t.Errorf("Expected the linked attachment without data, got %+v", list)
+		}
+
+		// Deleting the statement deletes its attachments
+		if err := statements.Delete(ctx, stmt.ID); err != nil {
+			t.Fatalf("Delete statement failed: %v", err)
+		}
+		if _, err := repo.Get(ctx, upload.ID); err != ErrNotFound {
+			t.Errorf("Expected the attachment to be deleted with its statement, got %v", err)
+		}
+		if err := repo.Delete(ctx, upload.ID); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from Delete, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// fundingAccountColumns are the funding_accounts columns read by
+// scanFundingAccount
+const fundingAccountColumns = `id, name, institution, last_four, balance_cents, created_at, updated_at`
+
+// SQLFundingAccountRepository is a FundingAccountRepository backed by SQLite
+// or Postgres
+type SQLFundingAccountRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewSQLiteFundingAccountRepository creates a funding account repository
+// using a SQLite db
+func NewSQLiteFundingAccountRepository(db *sql.DB) *SQLFundingAccountRepository {
+	return &SQLFundingAccountRepository{db: db, dialect: database.SQLite}
+}
+
+// NewPostgresFundingAccountRepository creates a funding account repository
+// using a Postgres db
+func NewPostgresFundingAccountRepository(db *sql.DB) *SQLFundingAccountRepository {
+	return &SQLFundingAccountRepository{db: db, dialect: database.Postgres}
+}
+
+// NewFundingAccountRepository creates a funding account repository using db, choosing the SQL
+// dialect from its driver
+func NewFundingAccountRepository(db *sql.DB) *SQLFundingAccountRepository {
+	return &SQLFundingAccountRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// scanFundingAccount reads a row selected with fundingAccountColumns
+func scanFundingAccount(row scanner) (models.FundingAccount, error) {
+	var account models.FundingAccount
+	var balance sql.NullInt64
+
+	err := row.Scan(&account.ID, &account.Name, &account.Institution, &account.LastFour, &balance,
+		&account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		return account, err
+	}
+	if balance.Valid {
+		b := models.Money(balance.Int64)
+		account.Balance = &b
+	}
+	return account, nil
+}
+
+// List returns every funding account ordered by name
+func (r *SQLFundingAccountRepository) List(ctx context.Context) ([]models.FundingAccount, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+fundingAccountColumns+" FROM funding_accounts ORDER BY name, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funding accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.FundingAccount{}
+	for rows.Next() {
+		account, err := scanFundingAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan funding account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// Get returns the account with the given ID
+func (r *SQLFundingAccountRepository) Get(ctx context.Context, id int) (models.FundingAccount, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+fundingAccountColumns+" FROM funding_accounts WHERE id = ?"), id)
+	account, err := scanFundingAccount(row)
+	if err == sql.ErrNoRows {
+		return account, ErrNotFound
+	}
+	if err != nil {
+		return account, fmt.Errorf("failed to query funding account %d: %w", id, err)
+	}
+	return account, nil
+}
+
+// Exists reports whether an account with the given ID exists
+func (r *SQLFundingAccountRepository) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT EXISTS(SELECT 1 FROM funding_accounts WHERE id = ?)"), id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check funding account %d: %w", id, err)
+	}
+	return exists, nil
+}
+
+// Create inserts the account and sets its ID
+func (r *SQLFundingAccountRepository) Create(ctx context.Context, account *models.FundingAccount) error {
+	err := r.db.QueryRowContext(ctx, r.dialect.Rebind(`
+		INSERT INTO funding_accounts (name, institution, last_four, balance_cents, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), account.Name, account.Institution, account.LastFour, account.Balance,
+		account.CreatedAt, account.UpdatedAt).Scan(&account.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert funding account: %w", err)
+	}
+	return nil
+}
+
+// Update applies the non-nil fields of update and returns the updated account
+func (r *SQLFundingAccountRepository) Update(ctx context.Context, id int, update FundingAccountUpdate) (models.FundingAccount, error) {
+	updates := []string{"updated_at = ?"}
+	args := []interface{}{update.UpdatedAt}
+
+	if update.Name != nil {
+		updates = append(updates, "name = ?")
+		args = append(args, *update.Name)
+	}
+	if update.Institution != nil {
+		updates = append(updates, "institution = ?")
+		args = append(args, *update.Institution)
+	}
+	if update.LastFour != nil {
+		updates = append(updates, "last_four = ?")
+		args = append(args, *update.LastFour)
+	}
+	if update.Balance != nil {
+		updates = append(updates, "balance_cents = ?")
+		args = append(args, *update.Balance)
+	}
+	args = append(args, id)
+
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("UPDATE funding_accounts SET "+strings.Join(updates, ", ")+" WHERE id = ?"), args...)
+	if err != nil {
+		return models.FundingAccount{}, fmt.Errorf("failed to update funding account %d: %w", id, err)
+	}
+	if err := checkAffected(result); err != nil {
+		return models.FundingAccount{}, err
+	}
+	return r.Get(ctx, id)
+}
+
+// Delete removes the account; foreign keys clear it from its statements
+func (r *SQLFundingAccountRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM funding_accounts WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete funding account %d: %w", id, err)
+	}
+	return checkAffected(result)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func TestFundingAccountRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+		repo := &SQLFundingAccountRepository{db: cards.db, dialect: cards.dialect}
+		now := time.Date(2024, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+		balance := models.MustParseMoney("2500.00")
+		chequing := models.FundingAccount{
+			Name:        "Chequing",
+			Institution: "Tangerine",
+			LastFour:    "0042",
+			Balance:     &balance,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := repo.Create(ctx, &chequing); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if chequing.ID == 0 {
+			t.Error("Expected the account ID to be set")
+		}
+		savings := models.FundingAccount{Name: "Bills", CreatedAt: now, UpdatedAt: now}
+		if err := repo.Create(ctx, &savings); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		list, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(list) != 2 || list[0].Name != "Bills" || list[0].Balance != nil || *list[1].Balance != balance {
+			t.Errorf("Expected 2 accounts ordered by name, got %+v", list)
+		}
+
+		newBalance := models.MustParseMoney("1800.25")
+		updated, err := repo.Update(ctx, chequing.ID, FundingAccountUpdate{Balance: &newBalance, UpdatedAt: now})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if updated.Balance == nil || *updated.Balance != newBalance || updated.Institution != "Tangerine" {
+			t.Errorf("Expected only the balance to change, got %+v", updated)
+		}
+		if _, err := repo.Update(ctx, 9999, FundingAccountUpdate{UpdatedAt: now}); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from Update, got %v", err)
+		}
+		if exists, err := repo.Exists(ctx, chequing.ID); err != nil || !exists {
+			t.Errorf("Expected the account to exist, got %v (%v)", exists, err)
+		}
+
+		// Statements and scheduled payments reference the account
+		card := createCard(t, cards, "Amex Cobalt")
+		stmt := models.Statement{
+			CardID:           card.ID,
+			StatementDate:    "2024-11-15",
+			DueDate:          "2024-12-10",
+			Amount:           models.MustParseMoney("1250.75"),
+			Status:           "pending",
+			FundingAccountID: &savings.ID,
+		}
+		if err := statements.Create(ctx, &stmt); err != nil {
+			t.Fatalf("Create statement failed: %v", err)
+		}
+		if err := statements.SchedulePayment(ctx, stmt.ID, "2024-12-03", &chequing.ID, now); err != nil {
+			t.Fatalf("SchedulePayment failed: %v", err)
+		}
+		got, _ := statements.Get(ctx, stmt.ID)
+		if got.FundingAccountID == nil || *got.FundingAccountID != chequing.ID {
+			t.Errorf("Expected the statement to be paid from %d, got %v", chequing.ID, got.FundingAccountID)
+		}
+
+		// Deleting the account keeps the statement
+		if err := repo.Delete(ctx, chequing.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := repo.Delete(ctx, chequing.ID); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+		}
+		got, err = statements.Get(ctx, stmt.ID)
+		if err != nil || got.FundingAccountID != nil {
+			t.Errorf("Expected the statement to remain without an account, got %+v (%v)", got, err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+)
+
+// SQLHolidayRepository is a HolidayRepository backed by SQLite or Postgres
+type SQLHolidayRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewSQLiteHolidayRepository creates a holiday repository using a SQLite db
+func NewSQLiteHolidayRepository(db *sql.DB) *SQLHolidayRepository {
+	return &SQLHolidayRepository{db: db, dialect: database.SQLite}
+}
+
+// NewPostgresHolidayRepository creates a holiday repository using a Postgres db
+func NewPostgresHolidayRepository(db *sql.DB) *SQLHolidayRepository {
+	return &SQLHolidayRepository{db: db, dialect: database.Postgres}
+}
+
+// NewHolidayRepository creates a holiday repository using db, choosing the SQL
+// dialect from its driver
+func NewHolidayRepository(db *sql.DB) *SQLHolidayRepository {
+	return &SQLHolidayRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// List returns every uploaded holiday ordered by date
+func (r *SQLHolidayRepository) List(ctx context.Context) ([]holidays.Holiday, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind("SELECT date, name, source FROM holidays ORDER BY date, source, name"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holidays: %w", err)
+	}
+	defer rows.Close()
+
+	list := []holidays.Holiday{}
+	for rows.Next() {
+		var h holidays.Holiday
+		if err := rows.Scan(&h.Date, &h.Name, &h.Source); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %w", err)
+		}
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
+// Replace swaps the holidays uploaded as source for list in one transaction
+func (r *SQLHolidayRepository) Replace(ctx context.Context, source string, list []holidays.Holiday, at time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, r.dialect.Rebind("DELETE FROM holidays WHERE source = ?"), source); err != nil {
+		return 0, fmt.Errorf("failed to delete holidays from %s: %w", source, err)
+	}
+
+	stored := 0
+	for _, h := range list {
+		result, err := tx.ExecContext(ctx, r.dialect.Rebind(`
+			INSERT INTO holidays (date, name, source, created_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT DO NOTHING
+		`), h.Date, h.Name, source, at)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert holiday %s: %w", h.Date, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to check inserted holiday: %w", err)
+		}
+		stored += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit holidays: %w", err)
+	}
+	return stored, nil
+}
+
+// DeleteSource removes the holidays uploaded as source
+func (r *SQLHolidayRepository) DeleteSource(ctx context.Context, source string) (int, error) {
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM holidays WHERE source = ?"), source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete holidays from %s: %w", source, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check deleted holidays: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+)
+
+func TestHolidayRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+		repo := &SQLHolidayRepository{db: cards.db, dialect: cards.dialect}
+		at := time.Date(2024, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+		stored, err := repo.Replace(ctx, "eq-bank", []holidays.Holiday{
+			{Date: "2024-12-24", Name: "Christmas Eve"},
+			{Date: "2024-08-05", Name: "Civic Holiday"},
+			{Date: "2024-08-05", Name: "Civic Holiday"},
+		}, at)
+		if err != nil {
+			t.Fatalf("Replace failed: %v", err)
+		}
+		if stored != 2 {
+			t.Errorf("Expected duplicates stored once, got %d stored", stored)
+		}
+		if _, err := repo.Replace(ctx, "office", []holidays.Holiday{{Date: "2024-08-05", Name: "Civic Holiday"}}, at); err != nil {
+			t.Fatalf("Replace failed: %v", err)
+		}
+
+		list, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(list) != 3 || list[0].Date != "2024-08-05" || list[0].Source != "eq-bank" || list[2].Name != "Christmas Eve" {
+			t.Errorf("Expected 3 holidays ordered by date, got %+v", list)
+		}
+
+		// Uploading a list again replaces it
+		if _, err := repo.Replace(ctx, "eq-bank", []holidays.Holiday{{Date: "2025-08-04", Name: "Civic Holiday"}}, at); err != nil {
+			t.Fatalf("Replace failed: %v", err)
+		}
+		list, _ = repo.List(ctx)
+		if len(list) != 2 || list[1].Date != "2025-08-04" {
+			t.Errorf("Expected the eq-bank list replaced, got %+v", list)
+		}
+
+		deleted, err := repo.DeleteSource(ctx, "office")
+		if err != nil || deleted != 1 {
+			t.Errorf("Expected 1 holiday deleted, got %d (%v)", deleted, err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// SQLIncomeRepository is an IncomeRepository backed by SQLite or Postgres
+type SQLIncomeRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewSQLiteIncomeRepository creates an income repository using a SQLite db
+func NewSQLiteIncomeRepository(db *sql.DB) *SQLIncomeRepository {
+	return &SQLIncomeRepository{db: db, dialect: database.SQLite}
+}
+
+// NewPostgresIncomeRepository creates an income repository using a Postgres db
+func NewPostgresIncomeRepository(db *sql.DB) *SQLIncomeRepository {
+	return &SQLIncomeRepository{db: db, dialect: database.Postgres}
+}
+
+// NewIncomeRepository creates an income repository using db, choosing the SQL
+// dialect from its driver
+func NewIncomeRepository(db *sql.DB) *SQLIncomeRepository {
+	return &SQLIncomeRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// List returns every income schedule ordered by name
+func (r *SQLIncomeRepository) List(ctx context.Context) ([]models.IncomeSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(`
+		SELECT id, name, frequency, anchor_date, second_day, amount_cents, created_at, updated_at
+		FROM income_schedules
+		ORDER BY name, id
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query income schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.IncomeSchedule{}
+	for rows.Next() {
+		var s models.IncomeSchedule
+		err := rows.Scan(&s.ID, &s.Name, &s.Frequency, &s.AnchorDate, &s.SecondDay, &s.Amount, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan income schedule: %w", err)
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// Create inserts the schedule and sets its ID
+func (r *SQLIncomeRepository) Create(ctx context.Context, schedule *models.IncomeSchedule) error {
+	err := r.db.QueryRowContext(ctx, r.dialect.Rebind(`
+		INSERT INTO income_schedules (name, frequency, anchor_date, second_day, amount_cents, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), schedule.Name, schedule.Frequency, schedule.AnchorDate, schedule.SecondDay, schedule.Amount,
+		schedule.CreatedAt, schedule.UpdatedAt).Scan(&schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert income schedule: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the schedule or returns ErrNotFound
+func (r *SQLIncomeRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM income_schedules WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete income schedule %d: %w", id, err)
+	}
+	return checkAffected(result)
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func TestIncomeRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+		repo := &SQLIncomeRepository{db: cards.db, dialect: cards.dialect}
+		now := time.Date(2024, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+		salary := models.IncomeSchedule{
+			Name:       "Salary",
+			Frequency:  models.IncomeSemiMonthly,
+			AnchorDate: "2024-11-15",
+			SecondDay:  31,
+			Amount:     models.MustParseMoney("2400.50"),
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if err := repo.Create(ctx, &salary); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if salary.ID == 0 {
+			t.Error("Expected the schedule ID to be set")
+		}
+		rental := models.IncomeSchedule{Name: "Rental", Frequency: models.IncomeMonthly, AnchorDate: "2024-11-01", Amount: models.MustParseMoney("900.00")}
+		if err := repo.Create(ctx, &rental); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		list, err := repo.List(ctx)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(list) != 2 || list[0].Name != "Rental" || list[1].SecondDay != 31 || list[1].Amount != salary.Amount {
+			t.Errorf("Expected 2 schedules ordered by name, got %+v", list)
+		}
+
+		if err := repo.Delete(ctx, rental.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := repo.Delete(ctx, rental.ID); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+		}
+		if list, _ := repo.List(ctx); len(list) != 1 {
+			t.Errorf("Expected 1 schedule left, got %d", len(list))
+		}
+	})
+}
@@ -0,0 +1,214 @@
+// Package repository defines the storage interfaces used by the HTTP handlers
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// ErrNotFound is returned when the requested card or statement does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrStatusConflict is returned by ChangeStatus when the statement's status
+// is no longer the one the change was checked against
+var ErrStatusConflict = errors.New("status changed concurrently")
+
+// PaymentsChangedBy is recorded as the author of status changes made because
+// a statement's payments started or stopped covering its amount
+const PaymentsChangedBy = "payments"
+
+// CardUpdate lists the card fields to change; nil fields are left as they are
+type CardUpdate struct {
+	Name             *string
+	LastFour         *string
+	StatementDay     *int
+	StatementDayRule *string
+	DaysUntilDue     *int
+	CreditLimit      *models.Money
+	PaymentPolicy    *string
+	PaymentLeadDays  *int
+	Autopay          *string
+	AutopayAmount    *models.Money
+	// AutopayFundingAccountID names the account autopay pulls from; 0
+	// clears it
+	AutopayFundingAccountID *int
+	UpdatedAt               time.Time
+}
+
+// StatementUpdate lists the statement fields to change; nil fields are left
+// as they are
+type StatementUpdate struct {
+	CardID         *int
+	StatementDate  *string
+	DueDate        *string
+	Amount         *models.Money
+	MinimumPayment *models.Money
+	CurrentBalance *models.Money
+	// FundingAccountID names the account paying the statement; 0 clears it
+	FundingAccountID *int
+	UpdatedAt        time.Time
+}
+
+// FundingAccountUpdate lists the funding account fields to change; nil fields
+// are left as they are
+type FundingAccountUpdate struct {
+	Name        *string
+	Institution *string
+	LastFour    *string
+	Balance     *models.Money
+	UpdatedAt   time.Time
+}
+
+// CardRepository stores credit cards
+type CardRepository interface {
+	// List returns all cards ordered by name
+	List(ctx context.Context) ([]models.CreditCard, error)
+	// Get returns the card with the given ID or ErrNotFound
+	Get(ctx context.Context, id int) (models.CreditCard, error)
+	// Exists reports whether a card with the given ID exists
+	Exists(ctx context.Context, id int) (bool, error)
+	// Create inserts the card and sets its ID
+	Create(ctx context.Context, card *models.CreditCard) error
+	// CreateAll inserts the cards in one transaction and sets their IDs;
+	// if any fails none are stored
+	CreateAll(ctx context.Context, cards []*models.CreditCard) error
+	// Update applies the changes and returns the updated card or ErrNotFound
+	Update(ctx context.Context, id int, update CardUpdate) (models.CreditCard, error)
+	// Delete removes the card and its statements, returning how many
+	// statements were deleted, or ErrNotFound
+	Delete(ctx context.Context, id int) (int, error)
+}
+
+// StatementRepository stores statements
+type StatementRepository interface {
+	// List returns all statements, latest due date first
+	List(ctx context.Context) ([]models.Statement, error)
+	// Get returns the statement with the given ID or ErrNotFound
+	Get(ctx context.Context, id int) (models.Statement, error)
+	// Create inserts the statement and sets its ID
+	Create(ctx context.Context, stmt *models.Statement) error
+	// CreateAll stores the statements in one transaction and reloads them.
+	// Statements for a cycle with an expected placeholder fill it in as
+	// Fill does, recording changedBy. If any fails none are stored.
+	CreateAll(ctx context.Context, stmts []*models.Statement, changedBy string) error
+	// Update applies the changes and returns the updated statement or
+	// ErrNotFound. Lowering the amount to what has been paid marks the
+	// statement paid.
+	Update(ctx context.Context, id int, update StatementUpdate) (models.Statement, error)
+	// Delete removes the statement or returns ErrNotFound
+	Delete(ctx context.Context, id int) error
+	// ChangeStatus moves the statement from change.FromStatus to
+	// change.ToStatus and records the change in its history. It returns
+	// ErrNotFound, or ErrStatusConflict if the status is no longer
+	// change.FromStatus.
+	ChangeStatus(ctx context.Context, change models.StatusChange) error
+	// MarkOverdue moves every pending statement due before today to overdue,
+	// recording changedBy, and returns the IDs of the statements changed
+	MarkOverdue(ctx context.Context, today string, changedBy string, at time.Time) ([]int, error)
+	// StatusHistory returns the statement's status changes, oldest first
+	StatusHistory(ctx context.Context, id int) ([]models.StatusChange, error)
+	// ListPayments returns the payments made towards a statement, oldest first
+	ListPayments(ctx context.Context, statementID int) ([]models.Payment, error)
+	// AddPayment records a payment, sets its ID and returns the updated
+	// statement, which is marked paid once its payments cover the amount.
+	// It returns ErrNotFound if the statement doesn't exist.
+	AddPayment(ctx context.Context, payment *models.Payment) (models.Statement, error)
+	// DeletePayment removes a payment and returns the updated statement. A
+	// statement marked paid by its payments is reopened if they no longer
+	// cover the amount, recording the change at at. It returns ErrNotFound if
+	// the payment doesn't exist.
+	DeletePayment(ctx context.Context, statementID, paymentID int, at time.Time) (models.Statement, error)
+	// SchedulePayment records the scheduled payment date and marks the
+	// statement reviewed at reviewedAt, or returns ErrNotFound. A non-nil
+	// fundingAccountID also sets the account the payment comes from.
+	SchedulePayment(ctx context.Context, id int, date string, fundingAccountID *int, reviewedAt time.Time) error
+	// FindExpected returns the card's expected placeholder whose statement
+	// date is within models.StatementEntryGraceDays of statementDate, or
+	// ErrNotFound
+	FindExpected(ctx context.Context, cardID int, statementDate string) (models.Statement, error)
+	// Fill turns the expected placeholder stmt.ID into the entered
+	// statement: it stores stmt's card, dates, amounts and funding account,
+	// moves it to stmt.Status recording changedBy, and reloads stmt. It
+	// returns ErrNotFound, or ErrStatusConflict if the statement is no
+	// longer expected.
+	Fill(ctx context.Context, stmt *models.Statement, changedBy string) error
+}
+
+// HolidayRepository stores uploaded holiday lists. Built-in federal holidays
+// are computed, not stored.
+type HolidayRepository interface {
+	// List returns every uploaded holiday ordered by date
+	List(ctx context.Context) ([]holidays.Holiday, error)
+	// Replace swaps the holidays uploaded as source for list and returns how
+	// many were stored; duplicates are stored once
+	Replace(ctx context.Context, source string, list []holidays.Holiday, at time.Time) (int, error)
+	// DeleteSource removes the holidays uploaded as source and returns how
+	// many there were
+	DeleteSource(ctx context.Context, source string) (int, error)
+}
+
+// IncomeRepository stores payday schedules
+type IncomeRepository interface {
+	// List returns every income schedule ordered by name
+	List(ctx context.Context) ([]models.IncomeSchedule, error)
+	// Create inserts the schedule and sets its ID
+	Create(ctx context.Context, schedule *models.IncomeSchedule) error
+	// Delete removes the schedule or returns ErrNotFound
+	Delete(ctx context.Context, id int) error
+}
+
+// FundingAccountRepository stores the accounts payments are made from
+type FundingAccountRepository interface {
+	// List returns every funding account ordered by name
+	List(ctx context.Context) ([]models.FundingAccount, error)
+	// Get returns the account with the given ID or ErrNotFound
+	Get(ctx context.Context, id int) (models.FundingAccount, error)
+	// Exists reports whether an account with the given ID exists
+	Exists(ctx context.Context, id int) (bool, error)
+	// Create inserts the account and sets its ID
+	Create(ctx context.Context, account *models.FundingAccount) error
+	// Update applies the changes and returns the updated account or
+	// ErrNotFound
+	Update(ctx context.Context, id int, update FundingAccountUpdate) (models.FundingAccount, error)
+	// Delete removes the account or returns ErrNotFound. Statements paid
+	// from it are kept but no longer name an account.
+	Delete(ctx context.Context, id int) error
+}
+
+// AttachmentRepository stores files kept with statements
+type AttachmentRepository interface {
+	// List returns the statement's attachments without their data, oldest
+	// first
+	List(ctx context.Context, statementID int) ([]models.Attachment, error)
+	// Get returns the attachment with its data or ErrNotFound
+	Get(ctx context.Context, id int) (models.Attachment, error)
+	// Create inserts the attachment and sets its ID
+	Create(ctx context.Context, a *models.Attachment) error
+	// Link attaches the attachment to a statement or returns ErrNotFound.
+	// Deleting the statement deletes its attachments.
+	Link(ctx context.Context, id, statementID int) error
+	// Delete removes the attachment or returns ErrNotFound
+	Delete(ctx context.Context, id int) error
+}
+
+// SuggestionRepository stores statements read from issuers' emails
+type SuggestionRepository interface {
+	// List returns the suggestions with the given status, or every
+	// suggestion if status is empty, newest first
+	List(ctx context.Context, status string) ([]models.Suggestion, error)
+	// Get returns the suggestion with the given ID or ErrNotFound
+	Get(ctx context.Context, id int) (models.Suggestion, error)
+	// FindByMessageID returns the suggestion read from the email with the
+	// given Message-ID or ErrNotFound
+	FindByMessageID(ctx context.Context, messageID string) (models.Suggestion, error)
+	// Create inserts the suggestion and sets its ID
+	Create(ctx context.Context, s *models.Suggestion) error
+	// Resolve moves a pending suggestion to status, recording the statement
+	// it was stored as, and returns it. A suggestion that doesn't exist or
+	// is no longer pending returns ErrNotFound.
+	Resolve(ctx context.Context, id int, status string, statementID *int, at time.Time) (models.Suggestion, error)
+}
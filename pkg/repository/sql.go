@@ -0,0 +1,938 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// cardColumns are the credit_cards columns read by scanCard
+const cardColumns = `id, name, last_four, statement_day, statement_day_rule, days_until_due,
+	       credit_limit_cents, payment_policy, payment_lead_days,
+	       autopay, autopay_amount_cents, autopay_funding_account_id, created_at, updated_at`
+
+// statementColumns are the statements columns read by scanStatement
+const statementColumns = `id, card_id, statement_date, due_date, amount_cents,
+	       minimum_payment_cents, current_balance_cents,
+	       (SELECT CAST(COALESCE(SUM(p.amount_cents), 0) AS BIGINT) FROM payments p WHERE p.statement_id = statements.id),
+	       status, notified_statement, notified_payment, notified_minimum,
+	       reviewed_at, scheduled_payment_date, funding_account_id,
+	       status_changed_at, status_changed_by,
+	       created_at, updated_at`
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// querier is implemented by *sql.DB and *sql.Tx, for queries that run on
+// their own or as part of a larger transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// scanCard reads a row selected with cardColumns
+func scanCard(row scanner) (models.CreditCard, error) {
+	var card models.CreditCard
+	var creditLimit sql.NullInt64
+	var autopayFundingAccountID sql.NullInt64
+
+	err := row.Scan(
+		&card.ID,
+		&card.Name,
+		&card.LastFour,
+		&card.StatementDay,
+		&card.StatementDayRule,
+		&card.DaysUntilDue,
+		&creditLimit,
+		&card.PaymentPolicy,
+		&card.PaymentLeadDays,
+		&card.Autopay,
+		&card.AutopayAmount,
+		&autopayFundingAccountID,
+		&card.CreatedAt,
+		&card.UpdatedAt,
+	)
+	if err != nil {
+		return card, err
+	}
+
+	// Handle NULL values
+	if creditLimit.Valid {
+		card.CreditLimit = models.Money(creditLimit.Int64)
+	}
+	if autopayFundingAccountID.Valid {
+		id := int(autopayFundingAccountID.Int64)
+		card.AutopayFundingAccountID = &id
+	}
+	return card, nil
+}
+
+// scanStatement reads a row selected with statementColumns
+func scanStatement(row scanner) (models.Statement, error) {
+	var stmt models.Statement
+	var paid models.Money
+	var currentBalance sql.NullInt64
+	var notifiedMinimum sql.NullBool
+	var reviewedAt sql.NullTime
+	var scheduledPaymentDate sql.NullString
+	var fundingAccountID sql.NullInt64
+	var statusChangedAt sql.NullTime
+	var statusChangedBy sql.NullString
+
+	err := row.Scan(
+		&stmt.ID,
+		&stmt.CardID,
+		&stmt.StatementDate,
+		&stmt.DueDate,
+		&stmt.Amount,
+		&stmt.MinimumPayment,
+		&currentBalance,
+		&paid,
+		&stmt.Status,
+		&stmt.NotifiedStatement,
+		&stmt.NotifiedPayment,
+		&notifiedMinimum,
+		&reviewedAt,
+		&scheduledPaymentDate,
+		&fundingAccountID,
+		&statusChangedAt,
+		&statusChangedBy,
+		&stmt.CreatedAt,
+		&stmt.UpdatedAt,
+	)
+	if err != nil {
+		return stmt, err
+	}
+	stmt.SetPaidAmount(paid)
+
+	// Handle nullable fields
+	if currentBalance.Valid {
+		balance := models.Money(currentBalance.Int64)
+		stmt.CurrentBalance = &balance
+	}
+	stmt.NotifiedMinimum = notifiedMinimum.Bool
+	if reviewedAt.Valid {
+		stmt.ReviewedAt = &reviewedAt.Time
+	}
+	if scheduledPaymentDate.Valid {
+		stmt.ScheduledPaymentDate = &scheduledPaymentDate.String
+	}
+	if fundingAccountID.Valid {
+		id := int(fundingAccountID.Int64)
+		stmt.FundingAccountID = &id
+	}
+	if statusChangedAt.Valid {
+		stmt.StatusChangedAt = &statusChangedAt.Time
+	}
+	if statusChangedBy.Valid {
+		stmt.StatusChangedBy = &statusChangedBy.String
+	}
+	return stmt, nil
+}
+
+// nullableMoney stores zero as NULL
+func nullableMoney(m models.Money) interface{} {
+	if m == 0 {
+		return nil
+	}
+	return m
+}
+
+// nullableID stores zero as NULL
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// checkAffected returns ErrNotFound if result changed no rows
+func checkAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SQLCardRepository is a CardRepository backed by SQLite or Postgres
+type SQLCardRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewSQLiteCardRepository creates a card repository using a SQLite db
+func NewSQLiteCardRepository(db *sql.DB) *SQLCardRepository {
+	return &SQLCardRepository{db: db, dialect: database.SQLite}
+}
+
+// NewPostgresCardRepository creates a card repository using a Postgres db
+func NewPostgresCardRepository(db *sql.DB) *SQLCardRepository {
+	return &SQLCardRepository{db: db, dialect: database.Postgres}
+}
+
+// NewCardRepository creates a card repository using db, choosing the SQL
+// dialect from its driver
+func NewCardRepository(db *sql.DB) *SQLCardRepository {
+	return &SQLCardRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// List returns all cards ordered by name
+func (r *SQLCardRepository) List(ctx context.Context) ([]models.CreditCard, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind("SELECT "+cardColumns+" FROM credit_cards ORDER BY name"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query credit cards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := []models.CreditCard{}
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan credit card: %w", err)
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+// Get returns the card with the given ID
+func (r *SQLCardRepository) Get(ctx context.Context, id int) (models.CreditCard, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+cardColumns+" FROM credit_cards WHERE id = ?"), id)
+	card, err := scanCard(row)
+	if err == sql.ErrNoRows {
+		return card, ErrNotFound
+	}
+	if err != nil {
+		return card, fmt.Errorf("failed to query credit card %d: %w", id, err)
+	}
+	return card, nil
+}
+
+// Exists reports whether a card with the given ID exists
+func (r *SQLCardRepository) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT EXISTS(SELECT 1 FROM credit_cards WHERE id = ?)"), id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check credit card %d: %w", id, err)
+	}
+	return exists, nil
+}
+
+// Create inserts the card and sets its ID
+func (r *SQLCardRepository) Create(ctx context.Context, card *models.CreditCard) error {
+	return r.insert(ctx, r.db, card)
+}
+
+// CreateAll inserts the cards in one transaction and sets their IDs. Either
+// all of them are stored or none are.
+func (r *SQLCardRepository) CreateAll(ctx context.Context, cards []*models.CreditCard) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, card := range cards {
+		if err := r.insert(ctx, tx, card); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit credit cards: %w", err)
+	}
+	return nil
+}
+
+// insert fills in the card's defaults, inserts it through q and sets its ID
+func (r *SQLCardRepository) insert(ctx context.Context, q querier, card *models.CreditCard) error {
+	if card.StatementDayRule == "" {
+		card.StatementDayRule = models.StatementDayFixed
+	}
+	if card.PaymentPolicy == "" {
+		card.PaymentPolicy = models.PaymentPolicyLeadTime
+	}
+	card.PaymentLeadDays = card.LeadDays()
+	if card.Autopay == "" {
+		card.Autopay = models.AutopayNone
+	}
+
+	// RETURNING works in both SQLite and Postgres, unlike LastInsertId
+	err := q.QueryRowContext(ctx, r.dialect.Rebind(`
+		INSERT INTO credit_cards (name, last_four, statement_day, statement_day_rule, days_until_due, credit_limit_cents,
+		                          payment_policy, payment_lead_days, autopay, autopay_amount_cents, autopay_funding_account_id,
+		                          created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), card.Name, card.LastFour, card.StatementDay, card.StatementDayRule, card.DaysUntilDue, nullableMoney(card.CreditLimit),
+		card.PaymentPolicy, card.PaymentLeadDays, card.Autopay, card.AutopayAmount, card.AutopayFundingAccountID,
+		card.CreatedAt, card.UpdatedAt).Scan(&card.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert credit card: %w", err)
+	}
+	return nil
+}
+
+// Update applies the non-nil fields of update and returns the updated card
+func (r *SQLCardRepository) Update(ctx context.Context, id int, update CardUpdate) (models.CreditCard, error) {
+	updates := []string{"updated_at = ?"}
+	args := []interface{}{update.UpdatedAt}
+
+	if update.Name != nil {
+		updates = append(updates, "name = ?")
+		args = append(args, *update.Name)
+	}
+	if update.LastFour != nil {
+		updates = append(updates, "last_four = ?")
+		args = append(args, *update.LastFour)
+	}
+	if update.StatementDay != nil {
+		updates = append(updates, "statement_day = ?")
+		args = append(args, *update.StatementDay)
+	}
+	if update.StatementDayRule != nil {
+		updates = append(updates, "statement_day_rule = ?")
+		args = append(args, *update.StatementDayRule)
+	}
+	if update.DaysUntilDue != nil {
+		updates = append(updates, "days_until_due = ?")
+		args = append(args, *update.DaysUntilDue)
+	}
+	if update.CreditLimit != nil {
+		updates = append(updates, "credit_limit_cents = ?")
+		args = append(args, nullableMoney(*update.CreditLimit))
+	}
+	if update.PaymentPolicy != nil {
+		updates = append(updates, "payment_policy = ?")
+		args = append(args, *update.PaymentPolicy)
+	}
+	if update.PaymentLeadDays != nil {
+		updates = append(updates, "payment_lead_days = ?")
+		args = append(args, *update.PaymentLeadDays)
+	}
+	if update.Autopay != nil {
+		updates = append(updates, "autopay = ?")
+		args = append(args, *update.Autopay)
+	}
+	if update.AutopayAmount != nil {
+		updates = append(updates, "autopay_amount_cents = ?")
+		args = append(args, *update.AutopayAmount)
+	}
+	if update.AutopayFundingAccountID != nil {
+		updates = append(updates, "autopay_funding_account_id = ?")
+		args = append(args, nullableID(*update.AutopayFundingAccountID))
+	}
+	args = append(args, id)
+
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("UPDATE credit_cards SET "+strings.Join(updates, ", ")+" WHERE id = ?"), args...)
+	if err != nil {
+		return models.CreditCard{}, fmt.Errorf("failed to update credit card %d: %w", id, err)
+	}
+	if err := checkAffected(result); err != nil {
+		return models.CreditCard{}, err
+	}
+
+	return r.Get(ctx, id)
+}
+
+// Delete removes the card; its statements are removed by ON DELETE CASCADE
+func (r *SQLCardRepository) Delete(ctx context.Context, id int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var statementCount int
+	err = tx.QueryRowContext(ctx, r.dialect.Rebind("SELECT COUNT(*) FROM statements WHERE card_id = ?"), id).Scan(&statementCount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count statements for card %d: %w", id, err)
+	}
+
+	result, err := tx.ExecContext(ctx, r.dialect.Rebind("DELETE FROM credit_cards WHERE id = ?"), id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete credit card %d: %w", id, err)
+	}
+	if err := checkAffected(result); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit delete: %w", err)
+	}
+	return statementCount, nil
+}
+
+// SQLStatementRepository is a StatementRepository backed by SQLite or Postgres
+type SQLStatementRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewSQLiteStatementRepository creates a statement repository using a SQLite db
+func NewSQLiteStatementRepository(db *sql.DB) *SQLStatementRepository {
+	return &SQLStatementRepository{db: db, dialect: database.SQLite}
+}
+
+// NewPostgresStatementRepository creates a statement repository using a Postgres db
+func NewPostgresStatementRepository(db *sql.DB) *SQLStatementRepository {
+	return &SQLStatementRepository{db: db, dialect: database.Postgres}
+}
+
+// NewStatementRepository creates a statement repository using db, choosing the SQL
+// dialect from its driver
+func NewStatementRepository(db *sql.DB) *SQLStatementRepository {
+	return &SQLStatementRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// List returns all statements, latest due date first
+func (r *SQLStatementRepository) List(ctx context.Context) ([]models.Statement, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind("SELECT "+statementColumns+" FROM statements ORDER BY due_date DESC"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statements: %w", err)
+	}
+	defer rows.Close()
+
+	statements := []models.Statement{}
+	for rows.Next() {
+		stmt, err := scanStatement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan statement: %w", err)
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, rows.Err()
+}
+
+// Get returns the statement with the given ID
+func (r *SQLStatementRepository) Get(ctx context.Context, id int) (models.Statement, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+statementColumns+" FROM statements WHERE id = ?"), id)
+	stmt, err := scanStatement(row)
+	if err == sql.ErrNoRows {
+		return stmt, ErrNotFound
+	}
+	if err != nil {
+		return stmt, fmt.Errorf("failed to query statement %d: %w", id, err)
+	}
+	return stmt, nil
+}
+
+// Create inserts the statement and sets its ID
+func (r *SQLStatementRepository) Create(ctx context.Context, stmt *models.Statement) error {
+	return r.insert(ctx, r.db, stmt)
+}
+
+// CreateAll stores the statements in one transaction and sets their IDs. A
+// statement for a cycle with an expected placeholder fills it in, as Fill
+// does, recording changedBy. Either all of them are stored or none are.
+func (r *SQLStatementRepository) CreateAll(ctx context.Context, stmts []*models.Statement, changedBy string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range stmts {
+		placeholder, err := r.findExpected(ctx, tx, stmt.CardID, stmt.StatementDate)
+		switch {
+		case err == nil:
+			stmt.ID = placeholder.ID
+			err = r.fill(ctx, tx, stmt, changedBy)
+		case err == ErrNotFound:
+			err = r.insert(ctx, tx, stmt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit statements: %w", err)
+	}
+
+	// Reload to pick up payments made towards filled placeholders
+	for _, stmt := range stmts {
+		stored, err := r.Get(ctx, stmt.ID)
+		if err != nil {
+			return err
+		}
+		*stmt = stored
+	}
+	return nil
+}
+
+// insert inserts the statement through q and sets its ID
+func (r *SQLStatementRepository) insert(ctx context.Context, q querier, stmt *models.Statement) error {
+	err := q.QueryRowContext(ctx, r.dialect.Rebind(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, minimum_payment_cents, current_balance_cents,
+		                        status, notified_statement, notified_payment, funding_account_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`),
+		stmt.CardID,
+		stmt.StatementDate,
+		stmt.DueDate,
+		stmt.Amount,
+		stmt.MinimumPayment,
+		stmt.CurrentBalance,
+		stmt.Status,
+		stmt.NotifiedStatement,
+		stmt.NotifiedPayment,
+		stmt.FundingAccountID,
+		stmt.CreatedAt,
+		stmt.UpdatedAt,
+	).Scan(&stmt.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert statement: %w", err)
+	}
+	return nil
+}
+
+// Update applies the non-nil fields of update and returns the updated statement
+func (r *SQLStatementRepository) Update(ctx context.Context, id int, update StatementUpdate) (models.Statement, error) {
+	updates := []string{"updated_at = ?"}
+	args := []interface{}{update.UpdatedAt}
+
+	if update.CardID != nil {
+		updates = append(updates, "card_id = ?")
+		args = append(args, *update.CardID)
+	}
+	if update.StatementDate != nil {
+		updates = append(updates, "statement_date = ?")
+		args = append(args, *update.StatementDate)
+	}
+	if update.DueDate != nil {
+		updates = append(updates, "due_date = ?")
+		args = append(args, *update.DueDate)
+	}
+	if update.Amount != nil {
+		updates = append(updates, "amount_cents = ?")
+		args = append(args, *update.Amount)
+	}
+	if update.MinimumPayment != nil {
+		updates = append(updates, "minimum_payment_cents = ?")
+		args = append(args, *update.MinimumPayment)
+	}
+	if update.CurrentBalance != nil {
+		updates = append(updates, "current_balance_cents = ?")
+		args = append(args, *update.CurrentBalance)
+	}
+	if update.FundingAccountID != nil {
+		updates = append(updates, "funding_account_id = ?")
+		args = append(args, nullableID(*update.FundingAccountID))
+	}
+	args = append(args, id)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, r.dialect.Rebind("UPDATE statements SET "+strings.Join(updates, ", ")+" WHERE id = ?"), args...)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("failed to update statement %d: %w", id, err)
+	}
+	if err := checkAffected(result); err != nil {
+		return models.Statement{}, err
+	}
+	if update.Amount != nil {
+		if err := r.settle(ctx, tx, id, update.UpdatedAt); err != nil {
+			return models.Statement{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Statement{}, fmt.Errorf("failed to commit statement update: %w", err)
+	}
+	return r.Get(ctx, id)
+}
+
+// Delete removes the statement
+func (r *SQLStatementRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM statements WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete statement %d: %w", id, err)
+	}
+	return checkAffected(result)
+}
+
+// ChangeStatus moves the statement between statuses and records the change.
+// The update only applies while the status is still change.FromStatus, so a
+// concurrent change can't be overwritten unchecked.
+func (r *SQLStatementRepository) ChangeStatus(ctx context.Context, change models.StatusChange) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.changeStatus(ctx, tx, change); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit status change: %w", err)
+	}
+	return nil
+}
+
+// changeStatus applies a single status change inside tx
+func (r *SQLStatementRepository) changeStatus(ctx context.Context, tx *sql.Tx, change models.StatusChange) error {
+	result, err := tx.ExecContext(ctx, r.dialect.Rebind(`
+		UPDATE statements
+		SET status = ?, status_changed_at = ?, status_changed_by = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`), change.ToStatus, change.ChangedAt, change.ChangedBy, change.ChangedAt, change.StatementID, change.FromStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update status of statement %d: %w", change.StatementID, err)
+	}
+	if err := checkAffected(result); err == ErrNotFound {
+		// Tell a missing statement apart from one whose status moved on
+		var exists bool
+		err := tx.QueryRowContext(ctx, r.dialect.Rebind("SELECT EXISTS(SELECT 1 FROM statements WHERE id = ?)"), change.StatementID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check statement %d: %w", change.StatementID, err)
+		}
+		if exists {
+			return ErrStatusConflict
+		}
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, r.dialect.Rebind(`
+		INSERT INTO statement_status_changes (statement_id, from_status, to_status, changed_by, changed_at)
+		VALUES (?, ?, ?, ?, ?)
+	`), change.StatementID, change.FromStatus, change.ToStatus, change.ChangedBy, change.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record status change for statement %d: %w", change.StatementID, err)
+	}
+	return nil
+}
+
+// MarkOverdue moves pending statements due before today to overdue
+func (r *SQLStatementRepository) MarkOverdue(ctx context.Context, today string, changedBy string, at time.Time) ([]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Due dates are ISO strings, so they can be compared lexically
+	rows, err := tx.QueryContext(ctx, r.dialect.Rebind(`
+		SELECT id FROM statements
+		WHERE status = ? AND due_date < ?
+		ORDER BY due_date, id
+	`), models.StatusPending, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query past due statements: %w", err)
+	}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan statement ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read past due statements: %w", err)
+	}
+
+	for _, id := range ids {
+		err := r.changeStatus(ctx, tx, models.StatusChange{
+			StatementID: id,
+			FromStatus:  models.StatusPending,
+			ToStatus:    models.StatusOverdue,
+			ChangedBy:   changedBy,
+			ChangedAt:   at,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit overdue statements: %w", err)
+	}
+	return ids, nil
+}
+
+// StatusHistory returns the statement's status changes, oldest first
+func (r *SQLStatementRepository) StatusHistory(ctx context.Context, id int) ([]models.StatusChange, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(`
+		SELECT id, statement_id, from_status, to_status, changed_by, changed_at
+		FROM statement_status_changes
+		WHERE statement_id = ?
+		ORDER BY changed_at, id
+	`), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status history for statement %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	changes := []models.StatusChange{}
+	for rows.Next() {
+		var change models.StatusChange
+		err := rows.Scan(&change.ID, &change.StatementID, &change.FromStatus, &change.ToStatus, &change.ChangedBy, &change.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan status change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// ListPayments returns the payments made towards a statement, oldest first
+func (r *SQLStatementRepository) ListPayments(ctx context.Context, statementID int) ([]models.Payment, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(`
+		SELECT id, statement_id, amount_cents, payment_date, source_account, confirmation_number, created_at
+		FROM payments
+		WHERE statement_id = ?
+		ORDER BY payment_date, id
+	`), statementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payments for statement %d: %w", statementID, err)
+	}
+	defer rows.Close()
+
+	payments := []models.Payment{}
+	for rows.Next() {
+		var p models.Payment
+		err := rows.Scan(&p.ID, &p.StatementID, &p.Amount, &p.PaymentDate, &p.SourceAccount, &p.ConfirmationNumber, &p.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// AddPayment records a payment and marks the statement paid once covered
+func (r *SQLStatementRepository) AddPayment(ctx context.Context, payment *models.Payment) (models.Statement, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, r.dialect.Rebind("SELECT EXISTS(SELECT 1 FROM statements WHERE id = ?)"), payment.StatementID).Scan(&exists)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("failed to check statement %d: %w", payment.StatementID, err)
+	}
+	if !exists {
+		return models.Statement{}, ErrNotFound
+	}
+
+	err = tx.QueryRowContext(ctx, r.dialect.Rebind(`
+		INSERT INTO payments (statement_id, amount_cents, payment_date, source_account, confirmation_number, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), payment.StatementID, payment.Amount, payment.PaymentDate, payment.SourceAccount, payment.ConfirmationNumber, payment.CreatedAt).Scan(&payment.ID)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("failed to insert payment: %w", err)
+	}
+
+	if err := r.settle(ctx, tx, payment.StatementID, payment.CreatedAt); err != nil {
+		return models.Statement{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Statement{}, fmt.Errorf("failed to commit payment: %w", err)
+	}
+	return r.Get(ctx, payment.StatementID)
+}
+
+// DeletePayment removes a payment and reopens the statement if it was paid
+// by payments that no longer cover it
+func (r *SQLStatementRepository) DeletePayment(ctx context.Context, statementID, paymentID int, at time.Time) (models.Statement, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, r.dialect.Rebind("DELETE FROM payments WHERE id = ? AND statement_id = ?"), paymentID, statementID)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("failed to delete payment %d: %w", paymentID, err)
+	}
+	if err := checkAffected(result); err != nil {
+		return models.Statement{}, err
+	}
+
+	if err := r.settle(ctx, tx, statementID, at); err != nil {
+		return models.Statement{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Statement{}, fmt.Errorf("failed to commit payment deletion: %w", err)
+	}
+	return r.Get(ctx, statementID)
+}
+
+// settle brings the statement's status in line with its payments: it is
+// marked paid once they cover the amount, and reopened if they stop covering
+// it after having marked it paid. Statements marked paid by hand are left
+// alone, since their payments may never have been recorded.
+func (r *SQLStatementRepository) settle(ctx context.Context, tx *sql.Tx, id int, at time.Time) error {
+	stmt, err := scanStatement(tx.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+statementColumns+" FROM statements WHERE id = ?"), id))
+	if err != nil {
+		return fmt.Errorf("failed to query statement %d: %w", id, err)
+	}
+
+	var to string
+	switch {
+	case stmt.IsCovered() && stmt.Status != models.StatusPaid:
+		to = models.StatusPaid
+	case !stmt.IsCovered() && stmt.Status == models.StatusPaid &&
+		stmt.StatusChangedBy != nil && *stmt.StatusChangedBy == PaymentsChangedBy:
+		to = models.StatusPending
+	default:
+		return nil
+	}
+
+	return r.changeStatus(ctx, tx, models.StatusChange{
+		StatementID: id,
+		FromStatus:  stmt.Status,
+		ToStatus:    to,
+		ChangedBy:   PaymentsChangedBy,
+		ChangedAt:   at,
+	})
+}
+
+// SchedulePayment records the scheduled payment date and review time, and
+// the funding account when one is given
+func (r *SQLStatementRepository) SchedulePayment(ctx context.Context, id int, date string, fundingAccountID *int, reviewedAt time.Time) error {
+	updates := "reviewed_at = ?, scheduled_payment_date = ?, updated_at = ?"
+	args := []interface{}{reviewedAt, date, reviewedAt}
+	if fundingAccountID != nil {
+		updates += ", funding_account_id = ?"
+		args = append(args, nullableID(*fundingAccountID))
+	}
+	args = append(args, id)
+
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind("UPDATE statements SET "+updates+" WHERE id = ?"), args...)
+	if err != nil {
+		return fmt.Errorf("failed to schedule payment for statement %d: %w", id, err)
+	}
+	return checkAffected(result)
+}
+
+// FindExpected returns the card's expected placeholder closest to
+// statementDate, within models.StatementEntryGraceDays either side
+func (r *SQLStatementRepository) FindExpected(ctx context.Context, cardID int, statementDate string) (models.Statement, error) {
+	return r.findExpected(ctx, r.db, cardID, statementDate)
+}
+
+// findExpected implements FindExpected, querying through q
+func (r *SQLStatementRepository) findExpected(ctx context.Context, q querier, cardID int, statementDate string) (models.Statement, error) {
+	date, err := time.Parse(models.DateFormat, statementDate)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("invalid statement date %q: %w", statementDate, err)
+	}
+	from := date.AddDate(0, 0, -models.StatementEntryGraceDays).Format(models.DateFormat)
+	to := date.AddDate(0, 0, models.StatementEntryGraceDays).Format(models.DateFormat)
+
+	rows, err := q.QueryContext(ctx, r.dialect.Rebind(`
+		SELECT `+statementColumns+` FROM statements
+		WHERE card_id = ? AND status = ? AND statement_date BETWEEN ? AND ?
+		ORDER BY statement_date
+	`), cardID, models.StatusExpected, from, to)
+	if err != nil {
+		return models.Statement{}, fmt.Errorf("failed to query expected statements: %w", err)
+	}
+	defer rows.Close()
+
+	var found models.Statement
+	best := -1
+	for rows.Next() {
+		stmt, err := scanStatement(rows)
+		if err != nil {
+			return models.Statement{}, fmt.Errorf("failed to scan statement: %w", err)
+		}
+		expected, _ := time.Parse(models.DateFormat, stmt.StatementDate)
+		days := int(expected.Sub(date).Hours() / 24)
+		if days < 0 {
+			days = -days
+		}
+		if best < 0 || days < best {
+			found, best = stmt, days
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.Statement{}, fmt.Errorf("failed to read expected statements: %w", err)
+	}
+	if best < 0 {
+		return models.Statement{}, ErrNotFound
+	}
+	return found, nil
+}
+
+// Fill stores the entered statement over the expected placeholder stmt.ID
+func (r *SQLStatementRepository) Fill(ctx context.Context, stmt *models.Statement, changedBy string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.fill(ctx, tx, stmt, changedBy); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit statement fill: %w", err)
+	}
+
+	filled, err := r.Get(ctx, stmt.ID)
+	if err != nil {
+		return err
+	}
+	*stmt = filled
+	return nil
+}
+
+// fill implements Fill within tx
+func (r *SQLStatementRepository) fill(ctx context.Context, tx *sql.Tx, stmt *models.Statement, changedBy string) error {
+	// Changing the status first makes sure the statement is still a
+	// placeholder
+	err := r.changeStatus(ctx, tx, models.StatusChange{
+		StatementID: stmt.ID,
+		FromStatus:  models.StatusExpected,
+		ToStatus:    stmt.Status,
+		ChangedBy:   changedBy,
+		ChangedAt:   stmt.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, r.dialect.Rebind(`
+		UPDATE statements
+		SET card_id = ?, statement_date = ?, due_date = ?, amount_cents = ?, minimum_payment_cents = ?,
+		    current_balance_cents = ?, funding_account_id = ?, updated_at = ?
+		WHERE id = ?
+	`), stmt.CardID, stmt.StatementDate, stmt.DueDate, stmt.Amount, stmt.MinimumPayment,
+		stmt.CurrentBalance, stmt.FundingAccountID, stmt.UpdatedAt, stmt.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fill statement %d: %w", stmt.ID, err)
+	}
+	// Payments may have been recorded before the amount was known
+	return r.settle(ctx, tx, stmt.ID, stmt.UpdatedAt)
+}
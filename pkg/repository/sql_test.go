@@ -0,0 +1,628 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database/pgtest"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// forEachBackend runs test against SQLite and, when TEST_DATABASE_URL is
+// set, Postgres
+func forEachBackend(t *testing.T, test func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository)) {
+	t.Run("sqlite", func(t *testing.T) {
+		tmpDB := "./test_repository.db"
+		db, err := database.InitDB(tmpDB, clock.Real{})
+		if err != nil {
+			t.Fatalf("Failed to initialize test database: %v", err)
+		}
+		defer func() {
+			db.Close()
+			os.Remove(tmpDB)
+		}()
+		test(t, NewCardRepository(db), NewStatementRepository(db))
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		db := pgtest.Open(t)
+		if _, err := database.MigrateUp(db, 0, clock.Real{}); err != nil {
+			t.Fatalf("Failed to migrate Postgres: %v", err)
+		}
+		test(t, NewCardRepository(db), NewStatementRepository(db))
+	})
+}
+
+func createCard(t *testing.T, cards *SQLCardRepository, name string) models.CreditCard {
+	now := time.Date(2024, time.November, 1, 12, 0, 0, 0, time.UTC)
+	card := models.CreditCard{
+		Name:         name,
+		LastFour:     "1234",
+		StatementDay: 15,
+		DaysUntilDue: 25,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := cards.Create(context.Background(), &card); err != nil {
+		t.Fatalf("Failed to create card: %v", err)
+	}
+	return card
+}
+
+func TestCardRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		amex := createCard(t, cards, "Amex Cobalt")
+		createCard(t, cards, "TD Aeroplan Visa")
+
+		if amex.ID == 0 {
+			t.Fatal("Expected Create to set the card ID")
+		}
+
+		list, err := cards.List(ctx)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(list) != 2 || list[0].Name != "Amex Cobalt" {
+			t.Errorf("Expected 2 cards ordered by name, got %+v", list)
+		}
+
+		got, err := cards.Get(ctx, amex.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.Name != "Amex Cobalt" || got.CreditLimit != 0 {
+			t.Errorf("Unexpected card %+v", got)
+		}
+		if got.PaymentPolicy != models.PaymentPolicyLeadTime || got.PaymentLeadDays != models.DefaultPaymentLeadDays {
+			t.Errorf("Expected the default payment policy, got %q with %d lead days", got.PaymentPolicy, got.PaymentLeadDays)
+		}
+		if got.Autopay != models.AutopayNone || got.AutopayFundingAccountID != nil {
+			t.Errorf("Expected no autopay, got %q from %v", got.Autopay, got.AutopayFundingAccountID)
+		}
+
+		if _, err := cards.Get(ctx, 9999); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		exists, err := cards.Exists(ctx, amex.ID)
+		if err != nil || !exists {
+			t.Errorf("Expected card to exist, got %v (%v)", exists, err)
+		}
+		exists, _ = cards.Exists(ctx, 9999)
+		if exists {
+			t.Error("Expected card 9999 not to exist")
+		}
+	})
+}
+
+func TestCardRepository_Update(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		card := createCard(t, cards, "Amex Cobalt")
+
+		name := "Amex Gold"
+		limit := models.MustParseMoney("5000.00")
+		updatedAt := time.Date(2024, time.November, 2, 12, 0, 0, 0, time.UTC)
+
+		policy := models.PaymentPolicyPayday
+		leadDays := 3
+
+		updated, err := cards.Update(ctx, card.ID, CardUpdate{Name: &name, CreditLimit: &limit, PaymentPolicy: &policy, PaymentLeadDays: &leadDays, UpdatedAt: updatedAt})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if updated.Name != "Amex Gold" || updated.CreditLimit != limit {
+			t.Errorf("Expected name and limit to change, got %+v", updated)
+		}
+		if updated.PaymentPolicy != policy || updated.PaymentLeadDays != 3 {
+			t.Errorf("Expected the payment policy to change, got %q with %d lead days", updated.PaymentPolicy, updated.PaymentLeadDays)
+		}
+		if updated.LastFour != "1234" || updated.StatementDay != 15 {
+			t.Errorf("Expected other fields to be unchanged, got %+v", updated)
+		}
+		if !updated.UpdatedAt.Equal(updatedAt) {
+			t.Errorf("Expected updated_at %v, got %v", updatedAt, updated.UpdatedAt)
+		}
+
+		if _, err := cards.Update(ctx, 9999, CardUpdate{Name: &name}); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		// Autopay from a funding account, then cleared
+		funding := &SQLFundingAccountRepository{db: cards.db, dialect: cards.dialect}
+		account := models.FundingAccount{Name: "Chequing", CreatedAt: updatedAt, UpdatedAt: updatedAt}
+		if err := funding.Create(ctx, &account); err != nil {
+			t.Fatalf("Create funding account failed: %v", err)
+		}
+		autopay := models.AutopayFixedAmount
+		amount := models.MustParseMoney("250.00")
+		updated, err = cards.Update(ctx, card.ID, CardUpdate{Autopay: &autopay, AutopayAmount: &amount, AutopayFundingAccountID: &account.ID, UpdatedAt: updatedAt})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if updated.Autopay != autopay || updated.AutopayAmount != amount || updated.AutopayFundingAccountID == nil || *updated.AutopayFundingAccountID != account.ID {
+			t.Errorf("Expected fixed autopay of 250.00 from %d, got %+v", account.ID, updated)
+		}
+		none := 0
+		updated, _ = cards.Update(ctx, card.ID, CardUpdate{AutopayFundingAccountID: &none, UpdatedAt: updatedAt})
+		if updated.AutopayFundingAccountID != nil {
+			t.Errorf("Expected the autopay account to be cleared, got %v", *updated.AutopayFundingAccountID)
+		}
+	})
+}
+
+func TestCardRepository_Delete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		card := createCard(t, cards, "Amex Cobalt")
+		for _, date := range []string{"2024-10-15", "2024-11-15"} {
+			stmt := models.Statement{CardID: card.ID, StatementDate: date, DueDate: date, Amount: 100, Status: "pending"}
+			if err := statements.Create(ctx, &stmt); err != nil {
+				t.Fatalf("Failed to create statement: %v", err)
+			}
+		}
+
+		deleted, err := cards.Delete(ctx, card.ID)
+		if err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if deleted != 2 {
+			t.Errorf("Expected 2 statements deleted, got %d", deleted)
+		}
+
+		list, _ := statements.List(ctx)
+		if len(list) != 0 {
+			t.Errorf("Expected statements to be deleted with the card, got %d", len(list))
+		}
+
+		if _, err := cards.Delete(ctx, card.ID); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestStatementRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		card := createCard(t, cards, "Amex Cobalt")
+		stmt := models.Statement{
+			CardID:        card.ID,
+			StatementDate: "2024-11-15",
+			DueDate:       "2024-12-10",
+			Amount:        models.MustParseMoney("1250.75"),
+			Status:        "pending",
+		}
+		if err := statements.Create(ctx, &stmt); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		now := time.Date(2024, time.November, 20, 9, 0, 0, 0, time.UTC)
+		if err := statements.SchedulePayment(ctx, stmt.ID, "2024-12-03", nil, now); err != nil {
+			t.Fatalf("SchedulePayment failed: %v", err)
+		}
+		err := statements.ChangeStatus(ctx, models.StatusChange{
+			StatementID: stmt.ID, FromStatus: "pending", ToStatus: "paid", ChangedBy: "test", ChangedAt: now,
+		})
+		if err != nil {
+			t.Fatalf("ChangeStatus failed: %v", err)
+		}
+
+		got, err := statements.Get(ctx, stmt.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.Status != "paid" || got.Amount != stmt.Amount {
+			t.Errorf("Unexpected statement %+v", got)
+		}
+		if got.ScheduledPaymentDate == nil || *got.ScheduledPaymentDate != "2024-12-03" {
+			t.Errorf("Expected scheduled payment date 2024-12-03, got %v", got.ScheduledPaymentDate)
+		}
+		if got.ReviewedAt == nil || !got.ReviewedAt.Equal(now) {
+			t.Errorf("Expected reviewed_at %v, got %v", now, got.ReviewedAt)
+		}
+
+		if _, err := statements.Get(ctx, 9999); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from Get, got %v", err)
+		}
+		err = statements.ChangeStatus(ctx, models.StatusChange{
+			StatementID: 9999, FromStatus: "pending", ToStatus: "paid", ChangedBy: "test", ChangedAt: now,
+		})
+		if err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from ChangeStatus, got %v", err)
+		}
+		if err := statements.SchedulePayment(ctx, 9999, "2024-12-03", nil, now); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from SchedulePayment, got %v", err)
+		}
+	})
+}
+
+func TestStatementRepository_UpdateAndDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		card := createCard(t, cards, "Amex Cobalt")
+		stmt := models.Statement{
+			CardID:        card.ID,
+			StatementDate: "2024-11-15",
+			DueDate:       "2024-12-10",
+			Amount:        models.MustParseMoney("1250.75"),
+			Status:        "pending",
+		}
+		if err := statements.Create(ctx, &stmt); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		// Only the provided fields change
+		dueDate := "2024-12-12"
+		amount := models.MustParseMoney("99.99")
+		now := time.Date(2024, time.November, 20, 9, 0, 0, 0, time.UTC)
+		got, err := statements.Update(ctx, stmt.ID, StatementUpdate{DueDate: &dueDate, Amount: &amount, UpdatedAt: now})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if got.DueDate != dueDate || got.Amount != amount {
+			t.Errorf("Expected due date %s and amount %s, got %+v", dueDate, amount, got)
+		}
+		if got.StatementDate != stmt.StatementDate || got.CardID != card.ID {
+			t.Errorf("Expected untouched fields to keep their values, got %+v", got)
+		}
+
+		if _, err := statements.Update(ctx, 9999, StatementUpdate{Amount: &amount, UpdatedAt: now}); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from Update, got %v", err)
+		}
+
+		if err := statements.Delete(ctx, stmt.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := statements.Get(ctx, stmt.ID); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+		}
+		if err := statements.Delete(ctx, stmt.ID); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from second Delete, got %v", err)
+		}
+	})
+}
+
+func TestStatementRepository_StatusChanges(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		card := createCard(t, cards, "Amex Cobalt")
+		var ids []int
+		for _, dueDate := range []string{"2024-11-10", "2024-11-20", "2024-12-10"} {
+			stmt := models.Statement{
+				CardID:        card.ID,
+				StatementDate: "2024-10-15",
+				DueDate:       dueDate,
+				Amount:        models.MustParseMoney("100.00"),
+				Status:        "pending",
+			}
+			if err := statements.Create(ctx, &stmt); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			ids = append(ids, stmt.ID)
+		}
+
+		// The second statement is paid before it falls due
+		paidAt := time.Date(2024, time.November, 18, 9, 0, 0, 0, time.UTC)
+		err := statements.ChangeStatus(ctx, models.StatusChange{
+			StatementID: ids[1], FromStatus: "pending", ToStatus: "paid", ChangedBy: "alex", ChangedAt: paidAt,
+		})
+		if err != nil {
+			t.Fatalf("ChangeStatus failed: %v", err)
+		}
+
+		// A change checked against a stale status is rejected
+		err = statements.ChangeStatus(ctx, models.StatusChange{
+			StatementID: ids[1], FromStatus: "pending", ToStatus: "overdue", ChangedBy: "alex", ChangedAt: paidAt,
+		})
+		if err != ErrStatusConflict {
+			t.Errorf("Expected ErrStatusConflict, got %v", err)
+		}
+
+		markedAt := time.Date(2024, time.November, 25, 0, 0, 0, 0, time.UTC)
+		marked, err := statements.MarkOverdue(ctx, "2024-11-25", "scheduler", markedAt)
+		if err != nil {
+			t.Fatalf("MarkOverdue failed: %v", err)
+		}
+		if len(marked) != 1 || marked[0] != ids[0] {
+			t.Errorf("Expected only statement %d marked overdue, got %v", ids[0], marked)
+		}
+
+		got, err := statements.Get(ctx, ids[0])
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.Status != "overdue" {
+			t.Errorf("Expected status overdue, got %s", got.Status)
+		}
+		if got.StatusChangedBy == nil || *got.StatusChangedBy != "scheduler" {
+			t.Errorf("Expected status_changed_by scheduler, got %v", got.StatusChangedBy)
+		}
+		if got.StatusChangedAt == nil || !got.StatusChangedAt.Equal(markedAt) {
+			t.Errorf("Expected status_changed_at %v, got %v", markedAt, got.StatusChangedAt)
+		}
+
+		history, err := statements.StatusHistory(ctx, ids[1])
+		if err != nil {
+			t.Fatalf("StatusHistory failed: %v", err)
+		}
+		if len(history) != 1 {
+			t.Fatalf("Expected 1 status change, got %d", len(history))
+		}
+		if history[0].FromStatus != "pending" || history[0].ToStatus != "paid" || history[0].ChangedBy != "alex" || !history[0].ChangedAt.Equal(paidAt) {
+			t.Errorf("Unexpected status change %+v", history[0])
+		}
+
+		// Running again finds nothing new
+		marked, err = statements.MarkOverdue(ctx, "2024-11-25", "scheduler", markedAt)
+		if err != nil {
+			t.Fatalf("MarkOverdue failed: %v", err)
+		}
+		if len(marked) != 0 {
+			t.Errorf("Expected nothing marked on second run, got %v", marked)
+		}
+	})
+}
+
+func TestStatementRepository_Payments(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		card := createCard(t, cards, "Amex Cobalt")
+		stmt := models.Statement{
+			CardID:        card.ID,
+			StatementDate: "2024-11-15",
+			DueDate:       "2024-12-10",
+			Amount:        models.MustParseMoney("1250.75"),
+			Status:        "pending",
+		}
+		if err := statements.Create(ctx, &stmt); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		now := time.Date(2024, time.November, 20, 9, 0, 0, 0, time.UTC)
+		first := models.Payment{
+			StatementID:        stmt.ID,
+			Amount:             models.MustParseMoney("1000.00"),
+			PaymentDate:        "2024-11-20",
+			SourceAccount:      "EQ Bank",
+			ConfirmationNumber: "ABC123",
+			CreatedAt:          now,
+		}
+		got, err := statements.AddPayment(ctx, &first)
+		if err != nil {
+			t.Fatalf("AddPayment failed: %v", err)
+		}
+		if first.ID == 0 {
+			t.Error("Expected payment ID to be set")
+		}
+		if got.Status != "pending" || got.PaidAmount != first.Amount || got.RemainingBalance != models.MustParseMoney("250.75") {
+			t.Errorf("Unexpected statement after partial payment: status %s, paid %s, remaining %s", got.Status, got.PaidAmount, got.RemainingBalance)
+		}
+
+		second := models.Payment{StatementID: stmt.ID, Amount: models.MustParseMoney("250.75"), PaymentDate: "2024-11-25", CreatedAt: now}
+		got, err = statements.AddPayment(ctx, &second)
+		if err != nil {
+			t.Fatalf("AddPayment failed: %v", err)
+		}
+		if got.Status != "paid" || got.RemainingBalance != 0 {
+			t.Errorf("Expected statement paid with nothing remaining, got status %s, remaining %s", got.Status, got.RemainingBalance)
+		}
+		if got.StatusChangedBy == nil || *got.StatusChangedBy != PaymentsChangedBy {
+			t.Errorf("Expected status changed by %s, got %v", PaymentsChangedBy, got.StatusChangedBy)
+		}
+
+		payments, err := statements.ListPayments(ctx, stmt.ID)
+		if err != nil {
+			t.Fatalf("ListPayments failed: %v", err)
+		}
+		if len(payments) != 2 || payments[0].ConfirmationNumber != "ABC123" || payments[0].SourceAccount != "EQ Bank" {
+			t.Errorf("Unexpected payments %+v", payments)
+		}
+
+		// Removing a payment reopens the statement it had paid off
+		got, err = statements.DeletePayment(ctx, stmt.ID, second.ID, now)
+		if err != nil {
+			t.Fatalf("DeletePayment failed: %v", err)
+		}
+		if got.Status != "pending" || got.RemainingBalance != models.MustParseMoney("250.75") {
+			t.Errorf("Expected statement reopened with 250.75 remaining, got status %s, remaining %s", got.Status, got.RemainingBalance)
+		}
+
+		// Lowering the amount to what was paid settles it
+		amount := models.MustParseMoney("1000.00")
+		got, err = statements.Update(ctx, stmt.ID, StatementUpdate{Amount: &amount, UpdatedAt: now})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if got.Status != "paid" {
+			t.Errorf("Expected statement paid after lowering the amount, got %s", got.Status)
+		}
+
+		if _, err := statements.DeletePayment(ctx, stmt.ID, 9999, now); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from DeletePayment, got %v", err)
+		}
+		missing := models.Payment{StatementID: 9999, Amount: 100, PaymentDate: "2024-11-20", CreatedAt: now}
+		if _, err := statements.AddPayment(ctx, &missing); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from AddPayment, got %v", err)
+		}
+	})
+}
+
+func TestStatementRepository_PaymentsKeepManualPaid(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		card := createCard(t, cards, "Amex Cobalt")
+		stmt := models.Statement{
+			CardID:        card.ID,
+			StatementDate: "2024-11-15",
+			DueDate:       "2024-12-10",
+			Amount:        models.MustParseMoney("500.00"),
+			Status:        "pending",
+		}
+		if err := statements.Create(ctx, &stmt); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		now := time.Date(2024, time.November, 20, 9, 0, 0, 0, time.UTC)
+		payment := models.Payment{StatementID: stmt.ID, Amount: models.MustParseMoney("100.00"), PaymentDate: "2024-11-20", CreatedAt: now}
+		if _, err := statements.AddPayment(ctx, &payment); err != nil {
+			t.Fatalf("AddPayment failed: %v", err)
+		}
+
+		// Marked paid by hand; the rest was paid without being recorded
+		err := statements.ChangeStatus(ctx, models.StatusChange{
+			StatementID: stmt.ID, FromStatus: "pending", ToStatus: "paid", ChangedBy: "user", ChangedAt: now,
+		})
+		if err != nil {
+			t.Fatalf("ChangeStatus failed: %v", err)
+		}
+
+		got, err := statements.DeletePayment(ctx, stmt.ID, payment.ID, now)
+		if err != nil {
+			t.Fatalf("DeletePayment failed: %v", err)
+		}
+		if got.Status != "paid" {
+			t.Errorf("Expected statement to stay paid, got %s", got.Status)
+		}
+	})
+}
+
+func TestStatementRepository_FillExpected(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+
+		card := createCard(t, cards, "Amex Cobalt")
+		created := time.Date(2024, time.November, 15, 1, 0, 0, 0, time.UTC)
+		placeholder := models.Statement{
+			CardID:        card.ID,
+			StatementDate: "2024-11-15",
+			DueDate:       "2024-12-10",
+			Status:        models.StatusExpected,
+			CreatedAt:     created,
+			UpdatedAt:     created,
+		}
+		if err := statements.Create(ctx, &placeholder); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		// Issuers don't always close on the predicted day
+		found, err := statements.FindExpected(ctx, card.ID, "2024-11-13")
+		if err != nil || found.ID != placeholder.ID {
+			t.Fatalf("Expected placeholder %d, got %+v (%v)", placeholder.ID, found, err)
+		}
+		if _, err := statements.FindExpected(ctx, card.ID, "2024-12-15"); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound for the next cycle, got %v", err)
+		}
+
+		now := time.Date(2024, time.November, 20, 9, 0, 0, 0, time.UTC)
+		stmt := models.Statement{
+			ID:             placeholder.ID,
+			CardID:         card.ID,
+			StatementDate:  "2024-11-13",
+			DueDate:        "2024-12-08",
+			Amount:         models.MustParseMoney("1250.75"),
+			MinimumPayment: models.MustParseMoney("35.00"),
+			Status:         models.StatusPending,
+			UpdatedAt:      now,
+		}
+		if err := statements.Fill(ctx, &stmt, "test"); err != nil {
+			t.Fatalf("Fill failed: %v", err)
+		}
+		if stmt.Status != models.StatusPending || stmt.StatementDate != "2024-11-13" || stmt.Amount.String() != "1250.75" {
+			t.Errorf("Unexpected filled statement %+v", stmt)
+		}
+		if !stmt.CreatedAt.Equal(created) {
+			t.Errorf("Expected the placeholder's created_at to be kept, got %v", stmt.CreatedAt)
+		}
+
+		history, err := statements.StatusHistory(ctx, stmt.ID)
+		if err != nil {
+			t.Fatalf("StatusHistory failed: %v", err)
+		}
+		if len(history) != 1 || history[0].FromStatus != models.StatusExpected || history[0].ChangedBy != "test" {
+			t.Errorf("Expected the fill in the history, got %+v", history)
+		}
+
+		// A placeholder can only be filled once
+		if err := statements.Fill(ctx, &stmt, "test"); err != ErrStatusConflict {
+			t.Errorf("Expected ErrStatusConflict filling twice, got %v", err)
+		}
+		stmt.ID = 9999
+		if err := statements.Fill(ctx, &stmt, "test"); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if _, err := statements.FindExpected(ctx, card.ID, "2024-11-15"); err != ErrNotFound {
+			t.Errorf("Expected no placeholder once filled, got %v", err)
+		}
+	})
+}
+
+func TestCreateAll(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+		now := time.Date(2024, time.November, 20, 9, 0, 0, 0, time.UTC)
+
+		newCards := []*models.CreditCard{
+			{Name: "TD Aeroplan Visa", LastFour: "1234", StatementDay: 15, DaysUntilDue: 21, CreatedAt: now, UpdatedAt: now},
+			{Name: "Amex Cobalt", LastFour: "5678", StatementDay: 20, DaysUntilDue: 25, CreatedAt: now, UpdatedAt: now},
+		}
+		if err := cards.CreateAll(ctx, newCards); err != nil {
+			t.Fatalf("CreateAll cards failed: %v", err)
+		}
+		if newCards[0].ID == 0 || newCards[1].ID == 0 || newCards[1].PaymentPolicy != models.PaymentPolicyLeadTime {
+			t.Errorf("Expected IDs and defaults to be set, got %+v", newCards)
+		}
+
+		placeholder := models.Statement{CardID: newCards[0].ID, StatementDate: "2024-11-15", DueDate: "2024-12-06",
+			Status: models.StatusExpected, CreatedAt: now, UpdatedAt: now}
+		if err := statements.Create(ctx, &placeholder); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+
+		stmts := []*models.Statement{
+			{CardID: newCards[0].ID, StatementDate: "2024-11-15", DueDate: "2024-12-06", Amount: models.MustParseMoney("100.00"),
+				Status: models.StatusPending, CreatedAt: now, UpdatedAt: now},
+			{CardID: newCards[1].ID, StatementDate: "2024-10-20", DueDate: "2024-11-14", Amount: models.MustParseMoney("50.00"),
+				Status: models.StatusPaid, CreatedAt: now, UpdatedAt: now},
+		}
+		if err := statements.CreateAll(ctx, stmts, "test"); err != nil {
+			t.Fatalf("CreateAll statements failed: %v", err)
+		}
+		if stmts[0].ID != placeholder.ID || stmts[0].Status != models.StatusPending {
+			t.Errorf("Expected the placeholder to be filled, got %+v", stmts[0])
+		}
+		if stmts[1].ID == 0 || stmts[1].Status != models.StatusPaid {
+			t.Errorf("Expected a new paid statement, got %+v", stmts[1])
+		}
+
+		// A failure part way through stores nothing
+		bad := []*models.Statement{
+			{CardID: newCards[1].ID, StatementDate: "2024-11-20", DueDate: "2024-12-15", Amount: models.MustParseMoney("10.00"),
+				Status: models.StatusPending, CreatedAt: now, UpdatedAt: now},
+			{CardID: 9999, StatementDate: "2024-11-20", DueDate: "2024-12-15", Amount: models.MustParseMoney("10.00"),
+				Status: models.StatusPending, CreatedAt: now, UpdatedAt: now},
+		}
+		if err := statements.CreateAll(ctx, bad, "test"); err == nil {
+			t.Fatal("Expected CreateAll to fail for a missing card")
+		}
+		all, err := statements.List(ctx)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(all) != 2 {
+			t.Errorf("Expected the failed batch to be rolled back, got %d statements", len(all))
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+// suggestionColumns are the statement_suggestions columns read by
+// scanSuggestion
+const suggestionColumns = `id, status, source, message_id, sender, subject, received_at, issuer, card_id, last_four,
+	statement_date, due_date, amount_cents, minimum_payment_cents, reason, statement_id, created_at, updated_at`
+
+// SQLSuggestionRepository is a SuggestionRepository backed by SQLite or
+// Postgres
+type SQLSuggestionRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewSQLiteSuggestionRepository creates a suggestion repository using a
+// SQLite db
+func NewSQLiteSuggestionRepository(db *sql.DB) *SQLSuggestionRepository {
+	return &SQLSuggestionRepository{db: db, dialect: database.SQLite}
+}
+
+// NewPostgresSuggestionRepository creates a suggestion repository using a
+// Postgres db
+func NewPostgresSuggestionRepository(db *sql.DB) *SQLSuggestionRepository {
+	return &SQLSuggestionRepository{db: db, dialect: database.Postgres}
+}
+
+// NewSuggestionRepository creates a suggestion repository using db, choosing the SQL
+// dialect from its driver
+func NewSuggestionRepository(db *sql.DB) *SQLSuggestionRepository {
+	return &SQLSuggestionRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// scanSuggestion reads a row selected with suggestionColumns
+func scanSuggestion(row scanner) (models.Suggestion, error) {
+	var s models.Suggestion
+	var receivedAt sql.NullTime
+	var cardID, statementID sql.NullInt64
+
+	err := row.Scan(&s.ID, &s.Status, &s.Source, &s.MessageID, &s.From, &s.Subject, &receivedAt, &s.Issuer, &cardID,
+		&s.LastFour, &s.StatementDate, &s.DueDate, &s.Amount, &s.MinimumPayment, &s.Reason, &statementID,
+		&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return s, err
+	}
+	if receivedAt.Valid {
+		s.ReceivedAt = &receivedAt.Time
+	}
+	if cardID.Valid {
+		id := int(cardID.Int64)
+		s.CardID = &id
+	}
+	if statementID.Valid {
+		id := int(statementID.Int64)
+		s.StatementID = &id
+	}
+	return s, nil
+}
+
+// List returns the suggestions with the given status, or every suggestion
+// if status is empty, newest first
+func (r *SQLSuggestionRepository) List(ctx context.Context, status string) ([]models.Suggestion, error) {
+	query := "SELECT " + suggestionColumns + " FROM statement_suggestions"
+	var args []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(query+" ORDER BY created_at DESC, id DESC"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.Suggestion{}
+	for rows.Next() {
+		s, err := scanSuggestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion: %w", err)
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
+
+// Get returns the suggestion with the given ID
+func (r *SQLSuggestionRepository) Get(ctx context.Context, id int) (models.Suggestion, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+suggestionColumns+" FROM statement_suggestions WHERE id = ?"), id)
+	s, err := scanSuggestion(row)
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to query suggestion %d: %w", id, err)
+	}
+	return s, nil
+}
+
+// FindByMessageID returns the suggestion read from the email with the given
+// Message-ID
+func (r *SQLSuggestionRepository) FindByMessageID(ctx context.Context, messageID string) (models.Suggestion, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+suggestionColumns+" FROM statement_suggestions WHERE message_id = ?"), messageID)
+	s, err := scanSuggestion(row)
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to query suggestion for message %q: %w", messageID, err)
+	}
+	return s, nil
+}
+
+// Create inserts the suggestion and sets its ID
+func (r *SQLSuggestionRepository) Create(ctx context.Context, s *models.Suggestion) error {
+	err := r.db.QueryRowContext(ctx, r.dialect.Rebind(`
+		INSERT INTO statement_suggestions (status, source, message_id, sender, subject, received_at, issuer, card_id,
+			last_four, statement_date, due_date, amount_cents, minimum_payment_cents, reason, statement_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), s.Status, s.Source, s.MessageID, s.From, s.Subject, s.ReceivedAt, s.Issuer, s.CardID,
+		s.LastFour, s.StatementDate, s.DueDate, s.Amount, s.MinimumPayment, s.Reason, s.StatementID, s.CreatedAt, s.UpdatedAt).Scan(&s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert suggestion: %w", err)
+	}
+	return nil
+}
+
+// Resolve moves a pending suggestion to status, recording the statement it
+// was stored as
+func (r *SQLSuggestionRepository) Resolve(ctx context.Context, id int, status string, statementID *int, at time.Time) (models.Suggestion, error) {
+	result, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		UPDATE statement_suggestions SET status = ?, statement_id = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`), status, statementID, at, id, models.SuggestionPending)
+	if err != nil {
+		return models.Suggestion{}, fmt.Errorf("failed to resolve suggestion %d: %w", id, err)
+	}
+	if err := checkAffected(result); err != nil {
+		return models.Suggestion{}, err
+	}
+	return r.Get(ctx, id)
+}
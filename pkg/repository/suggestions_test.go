@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+)
+
+func TestSuggestionRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, cards *SQLCardRepository, statements *SQLStatementRepository) {
+		ctx := context.Background()
+		repo := &SQLSuggestionRepository{db: cards.db, dialect: cards.dialect}
+		now := time.Date(2024, time.November, 16, 9, 30, 0, 0, time.UTC)
+		card := createCard(t, cards, "Amex Cobalt")
+
+		pending := models.Suggestion{
+			Status:         models.SuggestionPending,
+			Source:         "imap",
+			MessageID:      "<abc@americanexpress.com>",
+			From:           "AmericanExpress@welcome.aexp.com",
+			Subject:        "Your statement is ready",
+			ReceivedAt:     &now,
+			Issuer:         "Amex",
+			CardID:         &card.ID,
+			LastFour:       "1234",
+			DueDate:        "2024-12-10",
+			Amount:         models.MustParseMoney("1250.75"),
+			MinimumPayment: models.MustParseMoney("35.00"),
+			Reason:         "statement_date is missing",
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := repo.Create(ctx, &pending); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if pending.ID == 0 {
+			t.Error("Expected the suggestion ID to be set")
+		}
+		// Uploaded emails may not have a Message-ID
+		dismissed := models.Suggestion{Status: models.SuggestionDismissed, Source: "upload", CreatedAt: now.Add(time.Hour), UpdatedAt: now}
+		if err := repo.Create(ctx, &dismissed); err != nil {
+			t.Fatalf("Create without a message ID failed: %v", err)
+		}
+		if err := repo.Create(ctx, &models.Suggestion{Status: models.SuggestionPending, MessageID: pending.MessageID, CreatedAt: now, UpdatedAt: now}); err == nil {
+			t.Error("Expected an error for an email read twice")
+		}
+
+		got, err := repo.FindByMessageID(ctx, pending.MessageID)
+		if err != nil {
+			t.Fatalf("FindByMessageID failed: %v", err)
+		}
+		if got.ID != pending.ID || got.CardID == nil || *got.CardID != card.ID || got.ReceivedAt == nil || !got.ReceivedAt.Equal(now) ||
+			got.Amount != pending.Amount || got.StatementID != nil || got.Reason != pending.Reason {
+			t.Errorf("Unexpected suggestion: %+v", got)
+		}
+		if _, err := repo.FindByMessageID(ctx, "<other@example.com>"); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from FindByMessageID, got %v", err)
+		}
+
+		all, err := repo.List(ctx, "")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(all) != 2 || all[0].ID != dismissed.ID {
+			t.Errorf("Expected both suggestions, newest first, got %+v", all)
+		}
+		if list, _ := repo.List(ctx, models.SuggestionPending); len(list) != 1 || list[0].ID != pending.ID {
+			t.Errorf("Expected only the pending suggestion, got %+v", list)
+		}
+
+		stmt := models.Statement{CardID: card.ID, StatementDate: "2024-11-15", DueDate: "2024-12-10", Amount: pending.Amount, Status: models.StatusPending}
+		if err := statements.Create(ctx, &stmt); err != nil {
+			t.Fatalf("Create statement failed: %v", err)
+		}
+		accepted, err := repo.Resolve(ctx, pending.ID, models.SuggestionAccepted, &stmt.ID, now.Add(2*time.Hour))
+		if err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+		if accepted.Status != models.SuggestionAccepted || accepted.StatementID == nil || *accepted.StatementID != stmt.ID {
+			t.Errorf("Unexpected resolved suggestion: %+v", accepted)
+		}
+		// Only pending suggestions can be resolved
+		if _, err := repo.Resolve(ctx, pending.ID, models.SuggestionDismissed, nil, now); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound resolving twice, got %v", err)
+		}
+
+		// Deleting the statement keeps the suggestion
+		if err := statements.Delete(ctx, stmt.ID); err != nil {
+			t.Fatalf("Delete statement failed: %v", err)
+		}
+		if got, err := repo.Get(ctx, pending.ID); err != nil || got.StatementID != nil {
+			t.Errorf("Expected the suggestion to lose its statement, got %+v (%v)", got, err)
+		}
+		if _, err := repo.Get(ctx, 9999); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound from Get, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,524 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/holidays"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ingest"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/notify"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/planner"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/recommend"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ynab"
+)
+
+// DefaultInterval is how often the scheduler checks for work. Each check only
+// processes days that have not been processed yet, so checking more often
+// than daily is cheap and picks up new days soon after midnight.
+const DefaultInterval = time.Hour
+
+// MaxCatchUpDays limits how far back missed days are replayed after downtime
+const MaxCatchUpDays = 31
+
+// lastRunKey is the scheduler_state key holding the last fully processed day
+const lastRunKey = "last_run_date"
+
+// ChangedBy is recorded as the author of status changes made by the scheduler
+const ChangedBy = "scheduler"
+
+// Scheduler runs the daily statement and payment reminder checks
+type Scheduler struct {
+	db         *sql.DB
+	dialect    database.Dialect
+	statements repository.StatementRepository
+	calendar   *holidays.Calendar
+	holidays   repository.HolidayRepository
+	income     repository.IncomeRepository
+	notifier   *notify.Notifier
+	ynab       *ynab.Syncer
+	mailbox    *ingest.Mailbox
+	interval   time.Duration
+	clock      clock.Clock
+}
+
+// Option configures optional Scheduler dependencies
+type Option func(*Scheduler)
+
+// WithCalendar schedules reminders around the federal holidays of the
+// regions of cal instead of holidays.DefaultRegions
+func WithCalendar(cal *holidays.Calendar) Option {
+	return func(s *Scheduler) {
+		s.calendar = cal
+	}
+}
+
+// WithYNAB retries failed YNAB syncs through syncer on every run
+func WithYNAB(syncer *ynab.Syncer) Option {
+	return func(s *Scheduler) {
+		s.ynab = syncer
+	}
+}
+
+// WithMailbox reads new statement emails from mailbox on every run
+func WithMailbox(mailbox *ingest.Mailbox) Option {
+	return func(s *Scheduler) {
+		s.mailbox = mailbox
+	}
+}
+
+// New creates a scheduler that reads from db, sends through notifier and
+// decides what day it is using clk
+func New(db *sql.DB, notifier *notify.Notifier, clk clock.Clock, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		db:         db,
+		dialect:    database.DialectOf(db),
+		statements: repository.NewStatementRepository(db),
+		calendar:   holidays.NewCalendar(holidays.DefaultRegions, nil),
+		holidays:   repository.NewHolidayRepository(db),
+		income:     repository.NewIncomeRepository(db),
+		notifier:   notifier,
+		interval:   DefaultInterval,
+		clock:      clk,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run performs a check immediately and then on every interval until ctx is
+// cancelled. It returns once the in-flight check has finished.
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("Scheduler started (checking every %s)", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Scheduler run failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce marks past due statements overdue, adds placeholders for
+// statements that should have been released, reads statement emails,
+// retries failed YNAB syncs and then processes every day since the last
+// successful run up to today
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	today := clock.Today(s.clock)
+
+	// Overdue tracking, placeholders, statement emails and YNAB retries
+	// don't depend on notifications being configured
+	if err := s.markOverdue(ctx, today); err != nil {
+		log.Printf("Error marking overdue statements: %v", err)
+	}
+	if err := s.createExpectedStatements(ctx, today); err != nil {
+		log.Printf("Error creating expected statements: %v", err)
+	}
+	// Placeholders are added first so emails can fill them in
+	if s.mailbox.Enabled() {
+		if outcomes, err := s.mailbox.Poll(ctx); err != nil {
+			log.Printf("Error reading statement emails: %v", err)
+		} else if len(outcomes) > 0 {
+			log.Printf("Read %d statement emails", len(outcomes))
+		}
+	}
+	if s.ynab.Enabled() {
+		if failed, err := s.ynab.RetryFailed(ctx); err != nil {
+			log.Printf("Error retrying YNAB syncs: %v", err)
+		} else if failed > 0 {
+			log.Printf("%d YNAB syncs still failing", failed)
+		}
+	}
+
+	if !s.notifier.Enabled() {
+		return nil
+	}
+
+	lastRun, err := s.lastRunDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	// On the first run only today is processed; afterwards every missed day
+	// is replayed, bounded by MaxCatchUpDays
+	start := today
+	if !lastRun.IsZero() {
+		start = lastRun.AddDate(0, 0, 1)
+		if earliest := today.AddDate(0, 0, -MaxCatchUpDays); start.Before(earliest) {
+			start = earliest
+		}
+	}
+
+	var failed bool
+
+	if err := s.checkExpectedStatements(ctx, start, today); err != nil {
+		log.Printf("Error checking expected statements: %v", err)
+		failed = true
+	}
+	if err := s.checkPaymentReminders(ctx, today); err != nil {
+		log.Printf("Error checking payment reminders: %v", err)
+		failed = true
+	}
+	if err := s.checkMinimumReminders(ctx, today); err != nil {
+		log.Printf("Error checking minimum payment reminders: %v", err)
+		failed = true
+	}
+
+	// Leave the last run date alone on failure so the days are retried;
+	// alerts that did go out are not repeated
+	if failed {
+		return fmt.Errorf("scheduler run for %s incomplete", today.Format(models.DateFormat))
+	}
+
+	return s.setLastRunDate(ctx, today)
+}
+
+// markOverdue moves pending statements whose due date has passed to overdue
+func (s *Scheduler) markOverdue(ctx context.Context, today time.Time) error {
+	ids, err := s.statements.MarkOverdue(ctx, today.Format(models.DateFormat), ChangedBy, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		log.Printf("Marked %d statement(s) overdue: %v", len(ids), ids)
+	}
+	return nil
+}
+
+// checkExpectedStatements alerts for every card whose statement is predicted
+// to be released on a day between start and end, inclusive
+func (s *Scheduler) checkExpectedStatements(ctx context.Context, start, end time.Time) error {
+	if start.After(end) {
+		return nil
+	}
+
+	cards, err := s.loadCards(ctx)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, card := range cards {
+		for _, statementDate := range statementDatesBetween(card, start, end) {
+			if err := s.alertExpectedStatement(ctx, card, statementDate); err != nil {
+				log.Printf("Error alerting expected statement for card %d: %v", card.ID, err)
+				lastErr = err
+			}
+		}
+	}
+
+	return lastErr
+}
+
+// alertExpectedStatement sends a statement expected alert unless one was
+// already sent or the statement has already been entered; an expected
+// placeholder doesn't count as entered. Statements entered
+// up to a week before the predicted date count, since issuers don't always
+// close on the exact day.
+func (s *Scheduler) alertExpectedStatement(ctx context.Context, card models.CreditCard, statementDate time.Time) error {
+	date := statementDate.Format(models.DateFormat)
+	earliest := statementDate.AddDate(0, 0, -models.StatementEntryGraceDays).Format(models.DateFormat)
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(`
+		SELECT
+			(SELECT COUNT(*) FROM statement_alerts WHERE card_id = ? AND statement_date = ?) +
+			(SELECT COUNT(*) FROM statements WHERE card_id = ? AND statement_date >= ? AND status <> ?)
+	`), card.ID, date, card.ID, earliest, models.StatusExpected).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check existing alerts: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	if err := s.notifier.NotifyStatementExpected(ctx, card, statementDate); err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, s.dialect.Rebind(`
+		INSERT INTO statement_alerts (card_id, statement_date, sent_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (card_id, statement_date) DO NOTHING
+	`), card.ID, date, s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record statement alert: %w", err)
+	}
+
+	return nil
+}
+
+// createExpectedStatements adds an expected placeholder for each card's
+// latest predicted statement, with the predicted dates and no amount, unless
+// a statement for that cycle already exists. As with the statement expected
+// alerts, statements up to a week before the predicted date count. Each
+// cycle only ever gets one placeholder, so one deleted because the issuer
+// sent no statement stays deleted.
+func (s *Scheduler) createExpectedStatements(ctx context.Context, today time.Time) error {
+	cards, err := s.loadCards(ctx)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, card := range cards {
+		if err := s.createExpectedStatement(ctx, card, card.LastStatementDate(today)); err != nil {
+			log.Printf("Error creating expected statement for card %d: %v", card.ID, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// createExpectedStatement adds the placeholder for card's statement on
+// statementDate if the cycle has no statement yet and hasn't had a
+// placeholder before
+func (s *Scheduler) createExpectedStatement(ctx context.Context, card models.CreditCard, statementDate time.Time) error {
+	date := statementDate.Format(models.DateFormat)
+	earliest := statementDate.AddDate(0, 0, -models.StatementEntryGraceDays).Format(models.DateFormat)
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(`
+		SELECT
+			(SELECT COUNT(*) FROM expected_cycles WHERE card_id = ? AND statement_date = ?) +
+			(SELECT COUNT(*) FROM statements WHERE card_id = ? AND statement_date >= ?)
+	`), card.ID, date, card.ID, earliest).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check existing statements: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	now := s.clock.Now()
+	stmt := models.Statement{
+		CardID:        card.ID,
+		StatementDate: date,
+		DueDate:       card.ExpectedDueDate(statementDate).Format(models.DateFormat),
+		Status:        models.StatusExpected,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.statements.Create(ctx, &stmt); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.dialect.Rebind(`
+		INSERT INTO expected_cycles (card_id, statement_date, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (card_id, statement_date) DO NOTHING
+	`), card.ID, date, now)
+	if err != nil {
+		return fmt.Errorf("failed to record expected cycle: %w", err)
+	}
+	log.Printf("Created expected statement %d for card %d (%s)", stmt.ID, card.ID, stmt.StatementDate)
+	return nil
+}
+
+// checkPaymentReminders sends a reminder for every unpaid statement whose
+// recommended payment date has arrived and that has not been reminded yet.
+// The recommended date depends on the card's payment policy, weekends,
+// holidays and paydays, so it is worked out here rather than compared in SQL.
+// Overdue statements are included so a reminder missed during downtime is
+// still sent after the statement was marked overdue. Cards on autopay are
+// reminded on the due date instead, to check that autopay ran.
+func (s *Scheduler) checkPaymentReminders(ctx context.Context, today time.Time) error {
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`
+		SELECT s.id, s.statement_date, s.due_date, c.payment_policy, c.payment_lead_days, c.autopay
+		FROM statements s
+		JOIN credit_cards c ON c.id = s.card_id
+		WHERE s.status IN ('pending', 'overdue') AND s.notified_payment = FALSE
+		ORDER BY s.due_date
+	`))
+	if err != nil {
+		return fmt.Errorf("failed to query unpaid statements: %w", err)
+	}
+
+	type unpaidStatement struct {
+		card models.CreditCard
+		stmt models.Statement
+	}
+	var unpaid []unpaidStatement
+	for rows.Next() {
+		var u unpaidStatement
+		if err := rows.Scan(&u.stmt.ID, &u.stmt.StatementDate, &u.stmt.DueDate, &u.card.PaymentPolicy, &u.card.PaymentLeadDays, &u.card.Autopay); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan statement: %w", err)
+		}
+		unpaid = append(unpaid, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read unpaid statements: %w", err)
+	}
+	if len(unpaid) == 0 {
+		return nil
+	}
+
+	cal, err := s.calendar.Load(ctx, s.holidays)
+	if err != nil {
+		return err
+	}
+	paydays, err := planner.LoadPaydays(ctx, s.income, cal)
+	if err != nil {
+		return err
+	}
+
+	// Dates are ISO strings, so they can be compared lexically
+	day := today.Format(models.DateFormat)
+	var ids []int
+	for _, u := range unpaid {
+		if u.card.HasAutopay() {
+			if u.stmt.DueDate <= day {
+				ids = append(ids, u.stmt.ID)
+			}
+			continue
+		}
+		rec, err := recommend.ForStatement(u.card, u.stmt, cal, paydays)
+		if err != nil {
+			log.Printf("Skipping payment reminder for statement %d: %v", u.stmt.ID, err)
+			continue
+		}
+		if rec.PaymentDate <= day {
+			ids = append(ids, u.stmt.ID)
+		}
+	}
+
+	var lastErr error
+	for _, id := range ids {
+		if err := s.notifier.NotifyPayment(ctx, id, today); err != nil {
+			log.Printf("Error sending payment reminder for statement %d: %v", id, err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// checkMinimumReminders escalates for every unpaid statement due within
+// MinimumReminderLeadDays whose payments don't yet cover the minimum payment
+func (s *Scheduler) checkMinimumReminders(ctx context.Context, today time.Time) error {
+	cutoff := today.AddDate(0, 0, models.MinimumReminderLeadDays).Format(models.DateFormat)
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`
+		SELECT s.id
+		FROM statements s
+		WHERE s.status IN ('pending', 'overdue') AND s.notified_minimum = FALSE
+		  AND s.minimum_payment_cents > 0 AND s.due_date <= ?
+		  AND s.minimum_payment_cents > (SELECT COALESCE(SUM(p.amount_cents), 0) FROM payments p WHERE p.statement_id = s.id)
+		ORDER BY s.due_date
+	`), cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to query statements with unpaid minimums: %w", err)
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan statement ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read statements with unpaid minimums: %w", err)
+	}
+
+	var lastErr error
+	for _, id := range ids {
+		if err := s.notifier.NotifyMinimumDue(ctx, id, today); err != nil {
+			log.Printf("Error sending minimum payment reminder for statement %d: %v", id, err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// loadCards returns every configured card
+func (s *Scheduler) loadCards(ctx context.Context) ([]models.CreditCard, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`
+		SELECT id, name, last_four, statement_day, statement_day_rule, days_until_due
+		FROM credit_cards
+		ORDER BY id
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query credit cards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := []models.CreditCard{}
+	for rows.Next() {
+		var card models.CreditCard
+		if err := rows.Scan(&card.ID, &card.Name, &card.LastFour, &card.StatementDay, &card.StatementDayRule, &card.DaysUntilDue); err != nil {
+			return nil, fmt.Errorf("failed to scan credit card: %w", err)
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rows.Err()
+}
+
+// lastRunDate returns the last fully processed day, or the zero time if the
+// scheduler has never run
+func (s *Scheduler) lastRunDate(ctx context.Context) (time.Time, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind("SELECT value FROM scheduler_state WHERE key = ?"), lastRunKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last run date: %w", err)
+	}
+
+	date, err := time.Parse(models.DateFormat, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last run date %q: %w", value, err)
+	}
+	return date, nil
+}
+
+// setLastRunDate records day as fully processed
+func (s *Scheduler) setLastRunDate(ctx context.Context, day time.Time) error {
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(`
+		INSERT INTO scheduler_state (key, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`), lastRunKey, day.Format(models.DateFormat), s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record last run date: %w", err)
+	}
+	return nil
+}
+
+// statementDatesBetween returns the card's predicted statement dates that
+// fall between start and end, inclusive
+func statementDatesBetween(card models.CreditCard, start, end time.Time) []time.Time {
+	dates := []time.Time{}
+	month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for !month.After(end) {
+		date := card.StatementDateIn(month.Year(), month.Month())
+		if !date.Before(start) && !date.After(end) {
+			dates = append(dates, date)
+		}
+		month = month.AddDate(0, 1, 0)
+	}
+	return dates
+}
@@ -0,0 +1,615 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ingest"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/ingest/imaptest"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/notify"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// fakeDiscord records the titles of the embeds it receives
+type fakeDiscord struct {
+	mu     sync.Mutex
+	titles []string
+	status int
+}
+
+func (f *fakeDiscord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	var msg notify.WebhookMessage
+	json.NewDecoder(r.Body).Decode(&msg)
+	for _, embed := range msg.Embeds {
+		f.titles = append(f.titles, embed.Title)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeDiscord) Titles() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.titles...)
+}
+
+func (f *fakeDiscord) SetStatus(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+// setupScheduler creates a test database and a scheduler fixed at today
+func setupScheduler(t *testing.T, today string) (*Scheduler, *fakeDiscord, func()) {
+	tmpDB := "./test_scheduler.db"
+	db, err := database.InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+
+	fake := &fakeDiscord{}
+	server := httptest.NewServer(fake)
+
+	client := notify.NewDiscordClient(server.URL)
+	client.BaseBackoff = time.Millisecond
+	client.MaxRetries = 0
+
+	clk := clock.NewFixed(time.Time{})
+	s := New(db, notify.NewNotifier(db, client, clk), clk)
+	setToday(t, s, today)
+
+	return s, fake, func() {
+		server.Close()
+		db.Close()
+		os.Remove(tmpDB)
+	}
+}
+
+// setToday fixes the scheduler's clock at noon on the given date
+func setToday(t *testing.T, s *Scheduler, today string) {
+	date, err := time.Parse(models.DateFormat, today)
+	if err != nil {
+		t.Fatalf("Invalid date %q: %v", today, err)
+	}
+	s.clock.(*clock.Fixed).Set(date.Add(12 * time.Hour))
+}
+
+func insertCard(t *testing.T, db *sql.DB, name string, statementDay, daysUntilDue int) int {
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES (?, '1234', ?, ?)
+	`, name, statementDay, daysUntilDue)
+	if err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+func insertStatement(t *testing.T, db *sql.DB, cardID int, statementDate, dueDate, status string) int {
+	result, err := db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, status)
+		VALUES (?, ?, ?, 10000, ?)
+	`, cardID, statementDate, dueDate, status)
+	if err != nil {
+		t.Fatalf("Failed to insert statement: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+func TestRunOnce_StatementExpected(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-11-15")
+	defer cleanup()
+
+	insertCard(t, s.db, "TD Aeroplan Visa", 15, 25)
+	insertCard(t, s.db, "Amex Cobalt", 28, 25)
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	titles := fake.Titles()
+	if len(titles) != 1 || titles[0] != "Statement expected: TD Aeroplan Visa" {
+		t.Errorf("Expected one alert for TD Aeroplan Visa, got %v", titles)
+	}
+
+	// Running again on the same day must not alert twice
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("Second RunOnce failed: %v", err)
+	}
+	if got := len(fake.Titles()); got != 1 {
+		t.Errorf("Expected no duplicate alerts, got %d alerts", got)
+	}
+}
+
+func TestRunOnce_ExpectedStatements(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-11-15")
+	defer cleanup()
+
+	visa := insertCard(t, s.db, "TD Aeroplan Visa", 15, 25)
+	amex := insertCard(t, s.db, "Amex Cobalt", 28, 25)
+	insertStatement(t, s.db, amex, "2024-10-28", "2024-11-22", "paid")
+
+	for i := 0; i < 2; i++ {
+		if err := s.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+	}
+
+	// Only Visa's cycle is missing a statement, and the placeholder doesn't
+	// stop the alert asking for it
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM statements WHERE status = 'expected'").Scan(&count)
+	if count != 1 {
+		t.Fatalf("Expected one placeholder, got %d", count)
+	}
+	var cardID int
+	var statementDate, dueDate string
+	var amount int64
+	s.db.QueryRow("SELECT card_id, statement_date, due_date, amount_cents FROM statements WHERE status = 'expected'").
+		Scan(&cardID, &statementDate, &dueDate, &amount)
+	if cardID != visa || statementDate != "2024-11-15" || dueDate != "2024-12-10" || amount != 0 {
+		t.Errorf("Unexpected placeholder for card %d: %s due %s, %d cents", cardID, statementDate, dueDate, amount)
+	}
+	if titles := fake.Titles(); len(titles) != 1 || titles[0] != "Statement expected: TD Aeroplan Visa" {
+		t.Errorf("Expected one alert for TD Aeroplan Visa, got %v", titles)
+	}
+
+	// A placeholder deleted because no statement came isn't added again
+	if _, err := s.db.Exec("DELETE FROM statements WHERE status = 'expected'"); err != nil {
+		t.Fatalf("Failed to delete placeholder: %v", err)
+	}
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	s.db.QueryRow("SELECT COUNT(*) FROM statements WHERE card_id = ?", visa).Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected the deleted placeholder to stay deleted, got %d", count)
+	}
+
+	// Placeholders don't depend on Discord being configured
+	s.notifier = notify.NewNotifier(s.db, nil, nil)
+	setToday(t, s, "2024-11-28")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	s.db.QueryRow("SELECT COUNT(*) FROM statements WHERE status = 'expected' AND card_id = ?", amex).Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected a placeholder for Amex Cobalt, got %d", count)
+	}
+}
+
+func TestRunOnce_StatementEmails(t *testing.T) {
+	s, _, cleanup := setupScheduler(t, "2024-11-16")
+	defer cleanup()
+	visa := insertCard(t, s.db, "TD Aeroplan Visa", 15, 25)
+
+	server := imaptest.NewServer(t, "statements@example.com", "secret")
+	uid := server.Deliver([]byte(strings.ReplaceAll(`From: TD Canada Trust <noreply@td.com>
+Subject: Your TD credit card statement is ready
+Date: Sat, 16 Nov 2024 08:00:00 -0500
+Message-ID: <td-2024-11@td.com>
+
+Your TD Aeroplan Visa Infinite Card ending in 1234 statement is ready.
+Balance: $892.50
+Payment Due Date: December 10, 2024
+`, "\n", "\r\n")))
+
+	ingester := ingest.New(repository.NewSQLiteCardRepository(s.db),
+		repository.NewSQLiteStatementRepository(s.db),
+		repository.NewSQLiteSuggestionRepository(s.db), s.clock)
+	s.mailbox = ingest.NewMailbox(s.db, config.IMAPConfig{
+		Address:  server.Addr,
+		Username: "statements@example.com",
+		Password: "secret",
+		Mailbox:  "INBOX",
+		Insecure: true,
+	}, ingester)
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	// The placeholder added in the same run is filled in by the email
+	var status, statementDate string
+	var amount int64
+	s.db.QueryRow("SELECT status, statement_date, amount_cents FROM statements WHERE card_id = ?", visa).
+		Scan(&status, &statementDate, &amount)
+	if status != models.StatusPending || statementDate != "2024-11-15" || amount != 89250 {
+		t.Errorf("Expected the placeholder to be filled, got %s %s with %d cents", status, statementDate, amount)
+	}
+	if !server.Seen(uid) {
+		t.Error("Expected the email to be marked read")
+	}
+}
+
+func TestRunOnce_SkipsStatementAlreadyEntered(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-11-15")
+	defer cleanup()
+
+	cardID := insertCard(t, s.db, "TD Aeroplan Visa", 15, 25)
+	insertStatement(t, s.db, cardID, "2024-11-14", "2024-12-09", "pending")
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	if titles := fake.Titles(); len(titles) != 0 {
+		t.Errorf("Expected no alerts for an entered statement, got %v", titles)
+	}
+}
+
+func TestRunOnce_CatchesUpMissedDays(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-11-10")
+	defer cleanup()
+
+	insertCard(t, s.db, "TD Aeroplan Visa", 15, 25)
+	insertCard(t, s.db, "Amex Cobalt", 12, 25)
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if titles := fake.Titles(); len(titles) != 0 {
+		t.Fatalf("Expected no alerts on Nov 10, got %v", titles)
+	}
+
+	// Server was down from Nov 11 to Nov 19
+	setToday(t, s, "2024-11-20")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	titles := fake.Titles()
+	if len(titles) != 2 {
+		t.Fatalf("Expected 2 catch-up alerts, got %v", titles)
+	}
+	if titles[0] != "Statement expected: TD Aeroplan Visa" || titles[1] != "Statement expected: Amex Cobalt" {
+		t.Errorf("Unexpected catch-up alerts %v", titles)
+	}
+}
+
+func TestRunOnce_FirstRunDoesNotReplayHistory(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-11-20")
+	defer cleanup()
+
+	insertCard(t, s.db, "TD Aeroplan Visa", 15, 25)
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	if titles := fake.Titles(); len(titles) != 0 {
+		t.Errorf("Expected no alerts before the scheduler's first run, got %v", titles)
+	}
+}
+
+func TestRunOnce_PaymentReminder(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-12-02")
+	defer cleanup()
+
+	cardID := insertCard(t, s.db, "Amex Cobalt", 28, 25)
+	dueSoon := insertStatement(t, s.db, cardID, "2024-11-08", "2024-12-09", "pending")
+	insertStatement(t, s.db, cardID, "2024-11-20", "2024-12-15", "pending")
+	insertStatement(t, s.db, cardID, "2024-10-08", "2024-11-02", "paid")
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	titles := fake.Titles()
+	if len(titles) != 1 || titles[0] != "Payment reminder: Amex Cobalt" {
+		t.Fatalf("Expected one payment reminder, got %v", titles)
+	}
+
+	var notified bool
+	s.db.QueryRow("SELECT notified_payment FROM statements WHERE id = ?", dueSoon).Scan(&notified)
+	if !notified {
+		t.Error("Expected notified_payment to be set")
+	}
+
+	// Reminders are never repeated
+	setToday(t, s, "2024-12-03")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if got := len(fake.Titles()); got != 1 {
+		t.Errorf("Expected no repeated reminders, got %d messages", got)
+	}
+}
+
+func TestRunOnce_PaymentReminderBeforeHoliday(t *testing.T) {
+	// Christmas and Boxing Day don't count towards the five business days
+	// before the January 1st due date, so the reminder goes out on the 23rd
+	s, fake, cleanup := setupScheduler(t, "2024-12-20")
+	defer cleanup()
+
+	cardID := insertCard(t, s.db, "Amex Cobalt", 7, 25)
+	insertStatement(t, s.db, cardID, "2024-12-07", "2025-01-01", "pending")
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if got := fake.Titles(); len(got) != 0 {
+		t.Fatalf("Expected no reminder before the recommended date, got %v", got)
+	}
+
+	setToday(t, s, "2024-12-23")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if got := fake.Titles(); len(got) != 1 || got[0] != "Payment reminder: Amex Cobalt" {
+		t.Errorf("Expected a payment reminder on the 23rd, got %v", got)
+	}
+}
+
+func TestRunOnce_PaymentReminderOnPayday(t *testing.T) {
+	// Paid every second Friday from November 8th, so the first payday after
+	// the statement is the 22nd, a week before the lead time would remind
+	s, fake, cleanup := setupScheduler(t, "2024-11-21")
+	defer cleanup()
+
+	cardID := insertCard(t, s.db, "Amex Cobalt", 10, 25)
+	if _, err := s.db.Exec(`UPDATE credit_cards SET payment_policy = 'payday' WHERE id = ?`, cardID); err != nil {
+		t.Fatalf("Failed to set payment policy: %v", err)
+	}
+	if _, err := s.db.Exec(`
+		INSERT INTO income_schedules (name, frequency, anchor_date, amount_cents)
+		VALUES ('Salary', 'biweekly', '2024-11-08', 250000)
+	`); err != nil {
+		t.Fatalf("Failed to insert income schedule: %v", err)
+	}
+	insertStatement(t, s.db, cardID, "2024-11-10", "2024-12-05", "pending")
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if got := fake.Titles(); len(got) != 0 {
+		t.Fatalf("Expected no reminder before payday, got %v", got)
+	}
+
+	setToday(t, s, "2024-11-22")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if got := fake.Titles(); len(got) != 1 || got[0] != "Payment reminder: Amex Cobalt" {
+		t.Errorf("Expected a payment reminder on payday, got %v", got)
+	}
+}
+
+func TestRunOnce_FailureRetriesNextRun(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-11-14")
+	defer cleanup()
+
+	insertCard(t, s.db, "TD Aeroplan Visa", 15, 25)
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	// Discord is down on the statement day
+	fake.SetStatus(http.StatusServiceUnavailable)
+	setToday(t, s, "2024-11-15")
+	if err := s.RunOnce(context.Background()); err == nil {
+		t.Fatal("Expected RunOnce to report failure")
+	}
+
+	// Discord recovers the next day; the missed alert is delivered
+	fake.SetStatus(0)
+	setToday(t, s, "2024-11-16")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	titles := fake.Titles()
+	if len(titles) != 1 || titles[0] != "Statement expected: TD Aeroplan Visa" {
+		t.Errorf("Expected retried alert, got %v", titles)
+	}
+}
+
+func TestRunOnce_NotifierDisabled(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-11-15")
+	defer cleanup()
+
+	insertCard(t, s.db, "TD Aeroplan Visa", 15, 25)
+	s.notifier.Client().SetWebhookURL("")
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if titles := fake.Titles(); len(titles) != 0 {
+		t.Errorf("Expected no alerts when notifications are disabled, got %v", titles)
+	}
+}
+
+func TestRunOnce_MarksOverdue(t *testing.T) {
+	s, _, cleanup := setupScheduler(t, "2024-12-10")
+	defer cleanup()
+
+	// Overdue tracking runs even without notifications
+	s.notifier.Client().SetWebhookURL("")
+
+	cardID := insertCard(t, s.db, "Amex Cobalt", 28, 25)
+	pastDue := insertStatement(t, s.db, cardID, "2024-11-08", "2024-12-09", "pending")
+	dueToday := insertStatement(t, s.db, cardID, "2024-11-15", "2024-12-10", "pending")
+	paid := insertStatement(t, s.db, cardID, "2024-10-08", "2024-11-02", "paid")
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	want := map[int]string{pastDue: "overdue", dueToday: "pending", paid: "paid"}
+	for id, status := range want {
+		var got string
+		s.db.QueryRow("SELECT status FROM statements WHERE id = ?", id).Scan(&got)
+		if got != status {
+			t.Errorf("Statement %d: expected status %s, got %s", id, status, got)
+		}
+	}
+
+	var changedBy string
+	s.db.QueryRow("SELECT changed_by FROM statement_status_changes WHERE statement_id = ?", pastDue).Scan(&changedBy)
+	if changedBy != ChangedBy {
+		t.Errorf("Expected change recorded by %q, got %q", ChangedBy, changedBy)
+	}
+}
+
+func TestRunOnce_RemindsOverdueStatement(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-12-12")
+	defer cleanup()
+
+	// The server was down through the recommended payment date and due date
+	cardID := insertCard(t, s.db, "Amex Cobalt", 28, 25)
+	insertStatement(t, s.db, cardID, "2024-11-08", "2024-12-09", "pending")
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	titles := fake.Titles()
+	if len(titles) != 1 || titles[0] != "Payment reminder: Amex Cobalt" {
+		t.Errorf("Expected a reminder for the overdue statement, got %v", titles)
+	}
+}
+
+func TestRunOnce_AutopayVerification(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-12-02")
+	defer cleanup()
+
+	cardID := insertCard(t, s.db, "Amex Cobalt", 28, 25)
+	s.db.Exec("UPDATE credit_cards SET autopay = 'statement_balance' WHERE id = ?", cardID)
+	stmtID := insertStatement(t, s.db, cardID, "2024-11-08", "2024-12-09", "pending")
+	s.db.Exec("UPDATE statements SET minimum_payment_cents = 3500 WHERE id = ?", stmtID)
+
+	// Autopay covers the minimum, so there's nothing to remind about
+	// before the due date
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if titles := fake.Titles(); len(titles) != 0 {
+		t.Fatalf("Expected no reminders before the due date, got %v", titles)
+	}
+
+	setToday(t, s, "2024-12-09")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	titles := fake.Titles()
+	if len(titles) != 1 || titles[0] != "Verify autopay: Amex Cobalt" {
+		t.Fatalf("Expected one autopay check, got %v", titles)
+	}
+}
+
+func TestRunOnce_MinimumPaymentEscalation(t *testing.T) {
+	s, fake, cleanup := setupScheduler(t, "2024-12-05")
+	defer cleanup()
+
+	cardID := insertCard(t, s.db, "Amex Cobalt", 28, 25)
+	unpaid := insertStatement(t, s.db, cardID, "2024-11-08", "2024-12-08", "pending")
+	minimumPaid := insertStatement(t, s.db, cardID, "2024-11-10", "2024-12-08", "pending")
+	later := insertStatement(t, s.db, cardID, "2024-11-15", "2024-12-20", "pending")
+	for _, id := range []int{unpaid, minimumPaid, later} {
+		s.db.Exec("UPDATE statements SET minimum_payment_cents = 3500, notified_payment = TRUE WHERE id = ?", id)
+	}
+	s.db.Exec("INSERT INTO payments (statement_id, amount_cents, payment_date) VALUES (?, 3500, '2024-12-01')", minimumPaid)
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	titles := fake.Titles()
+	if len(titles) != 1 || titles[0] != "Minimum payment due: Amex Cobalt" {
+		t.Fatalf("Expected one minimum payment reminder, got %v", titles)
+	}
+
+	var notified bool
+	s.db.QueryRow("SELECT notified_minimum FROM statements WHERE id = ?", unpaid).Scan(&notified)
+	if !notified {
+		t.Error("Expected notified_minimum to be set")
+	}
+
+	// The escalation is not repeated
+	setToday(t, s, "2024-12-06")
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if got := len(fake.Titles()); got != 1 {
+		t.Errorf("Expected no repeated reminders, got %d messages", got)
+	}
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	s, _, cleanup := setupScheduler(t, "2024-11-15")
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Scheduler did not stop after cancel")
+	}
+}
+
+func TestStatementDatesBetween(t *testing.T) {
+	card := models.CreditCard{StatementDay: 31}
+	start := time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, time.April, 15, 0, 0, 0, 0, time.UTC)
+
+	dates := statementDatesBetween(card, start, end)
+
+	expected := []string{"2024-01-31", "2024-02-29", "2024-03-31"}
+	if len(dates) != len(expected) {
+		t.Fatalf("Expected %d dates, got %d", len(expected), len(dates))
+	}
+	for i, date := range dates {
+		if got := date.Format(models.DateFormat); got != expected[i] {
+			t.Errorf("Expected date %s, got %s", expected[i], got)
+		}
+	}
+}
+
+func TestStatementDatesBetween_BusinessDayRule(t *testing.T) {
+	card := models.CreditCard{StatementDay: 1, StatementDayRule: models.StatementDayBusinessDay}
+	start := time.Date(2024, time.December, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)
+
+	dates := statementDatesBetween(card, start, end)
+
+	// February 1st 2025 is a Saturday
+	expected := []string{"2025-01-01", "2025-02-03", "2025-03-03"}
+	if len(dates) != len(expected) {
+		t.Fatalf("Expected %d dates, got %d", len(expected), len(dates))
+	}
+	for i, date := range dates {
+		if got := date.Format(models.DateFormat); got != expected[i] {
+			t.Errorf("Expected date %s, got %s", expected[i], got)
+		}
+	}
+}
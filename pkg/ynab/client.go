@@ -0,0 +1,208 @@
+// Package ynab creates and updates scheduled transactions in a YNAB budget
+// through the YNAB API
+package ynab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultBaseURL is the YNAB API endpoint
+const DefaultBaseURL = "https://api.ynab.com/v1"
+
+// FrequencyNever is the frequency of a scheduled transaction that happens once
+const FrequencyNever = "never"
+
+// Account is a YNAB budget account
+type Account struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Closed bool   `json:"closed"`
+	// TransferPayeeID is the payee that makes a transaction a transfer into
+	// this account
+	TransferPayeeID string `json:"transfer_payee_id"`
+	Deleted         bool   `json:"deleted"`
+}
+
+// ScheduledTransaction is a YNAB scheduled transaction. Amounts are in
+// milliunits, so $1.00 is 1000, and outflows are negative.
+type ScheduledTransaction struct {
+	ID        string `json:"id,omitempty"`
+	AccountID string `json:"account_id"`
+	// Date is sent when saving; YNAB returns DateFirst and DateNext
+	Date      string `json:"date,omitempty"`
+	DateFirst string `json:"date_first,omitempty"`
+	DateNext  string `json:"date_next,omitempty"`
+	Frequency string `json:"frequency"`
+	Amount    int64  `json:"amount"`
+	PayeeID   string `json:"payee_id,omitempty"`
+	Memo      string `json:"memo,omitempty"`
+	Deleted   bool   `json:"deleted,omitempty"`
+}
+
+// APIError is returned when YNAB responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	ID         string
+	Name       string
+	Detail     string
+}
+
+func (e *APIError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("ynab returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("ynab returned status %d: %s (%s)", e.StatusCode, e.Name, e.Detail)
+}
+
+// NotFound reports whether YNAB said the resource doesn't exist
+func (e *APIError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// Client calls the YNAB API with a personal access token
+type Client struct {
+	HTTPClient *http.Client
+	BaseURL    string
+	token      string
+}
+
+// NewClient creates a client for the YNAB API using token
+func NewClient(token string) *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		BaseURL:    DefaultBaseURL,
+		token:      token,
+	}
+}
+
+// GetAccount returns an account in the budget
+func (c *Client) GetAccount(ctx context.Context, budgetID, accountID string) (Account, error) {
+	var resp struct {
+		Data struct {
+			Account Account `json:"account"`
+		} `json:"data"`
+	}
+	err := c.do(ctx, http.MethodGet, budgetPath(budgetID, "accounts", accountID), nil, &resp)
+	return resp.Data.Account, err
+}
+
+// ListScheduledTransactions returns every scheduled transaction in the budget
+func (c *Client) ListScheduledTransactions(ctx context.Context, budgetID string) ([]ScheduledTransaction, error) {
+	var resp struct {
+		Data struct {
+			ScheduledTransactions []ScheduledTransaction `json:"scheduled_transactions"`
+		} `json:"data"`
+	}
+	err := c.do(ctx, http.MethodGet, budgetPath(budgetID, "scheduled_transactions"), nil, &resp)
+	return resp.Data.ScheduledTransactions, err
+}
+
+// CreateScheduledTransaction adds a scheduled transaction and returns it as
+// saved
+func (c *Client) CreateScheduledTransaction(ctx context.Context, budgetID string, tx ScheduledTransaction) (ScheduledTransaction, error) {
+	return c.saveScheduledTransaction(ctx, http.MethodPost, budgetPath(budgetID, "scheduled_transactions"), tx)
+}
+
+// UpdateScheduledTransaction replaces the scheduled transaction with the
+// given ID and returns it as saved
+func (c *Client) UpdateScheduledTransaction(ctx context.Context, budgetID, id string, tx ScheduledTransaction) (ScheduledTransaction, error) {
+	return c.saveScheduledTransaction(ctx, http.MethodPut, budgetPath(budgetID, "scheduled_transactions", id), tx)
+}
+
+// DeleteScheduledTransaction removes the scheduled transaction with the
+// given ID
+func (c *Client) DeleteScheduledTransaction(ctx context.Context, budgetID, id string) error {
+	var resp struct {
+		Data struct {
+			ScheduledTransaction ScheduledTransaction `json:"scheduled_transaction"`
+		} `json:"data"`
+	}
+	return c.do(ctx, http.MethodDelete, budgetPath(budgetID, "scheduled_transactions", id), nil, &resp)
+}
+
+// saveScheduledTransaction sends tx wrapped the way YNAB expects
+func (c *Client) saveScheduledTransaction(ctx context.Context, method, path string, tx ScheduledTransaction) (ScheduledTransaction, error) {
+	tx.ID = ""
+	body := map[string]ScheduledTransaction{"scheduled_transaction": tx}
+	var resp struct {
+		Data struct {
+			ScheduledTransaction ScheduledTransaction `json:"scheduled_transaction"`
+		} `json:"data"`
+	}
+	err := c.do(ctx, method, path, body, &resp)
+	return resp.Data.ScheduledTransaction, err
+}
+
+// do sends a request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal ynab request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create ynab request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to send ynab request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errResp struct {
+			Error struct {
+				ID     string `json:"id"`
+				Name   string `json:"name"`
+				Detail string `json:"detail"`
+			} `json:"error"`
+		}
+		if data, err := io.ReadAll(io.LimitReader(resp.Body, 4096)); err == nil && json.Unmarshal(data, &errResp) == nil {
+			apiErr.ID = errResp.Error.ID
+			apiErr.Name = errResp.Error.Name
+			apiErr.Detail = errResp.Error.Detail
+		}
+		return apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode ynab response: %w", err)
+	}
+	return nil
+}
+
+// budgetPath builds a path under a budget, escaping each segment
+func budgetPath(budgetID string, segments ...string) string {
+	path := "/budgets/" + url.PathEscape(budgetID)
+	for _, segment := range segments {
+		path += "/" + url.PathEscape(segment)
+	}
+	return path
+}
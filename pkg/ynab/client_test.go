@@ -0,0 +1,47 @@
+package ynab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "resource_not_found")
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	_, err := client.GetAccount(context.Background(), testBudget, "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got %v", err)
+	}
+	if !apiErr.NotFound() || apiErr.Name != "resource_not_found" {
+		t.Errorf("Expected a not found error, got %v", apiErr)
+	}
+}
+
+func TestClient_EscapesPath(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		writeData(w, http.StatusOK, map[string]interface{}{"account": Account{ID: "a"}})
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+
+	if _, err := client.GetAccount(context.Background(), "my budget", "a/b"); err != nil {
+		t.Fatalf("GetAccount failed: %v", err)
+	}
+	if path != "/budgets/my%20budget/accounts/a%2Fb" {
+		t.Errorf("Expected escaped path, got %s", path)
+	}
+}
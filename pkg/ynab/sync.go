@@ -0,0 +1,352 @@
+package ynab
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/models"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/repository"
+)
+
+// MaxAttempts is how many times in a row a statement's sync may fail before
+// RetryFailed stops retrying it. Scheduling the payment again resets the
+// count.
+const MaxAttempts = 10
+
+// Syncer mirrors scheduled statement payments into YNAB as scheduled
+// transfers from the paying account to the card's account, and records the
+// outcome in ynab_transactions
+type Syncer struct {
+	db         *sql.DB
+	dialect    database.Dialect
+	cards      repository.CardRepository
+	statements repository.StatementRepository
+	client     *Client
+	cfg        config.YNABConfig
+	clock      clock.Clock
+	locks      statementLocks
+}
+
+// NewSyncer creates a syncer that reads statements from db and saves them to
+// the budget in cfg through client
+func NewSyncer(db *sql.DB, client *Client, cfg config.YNABConfig, clk clock.Clock) *Syncer {
+	return &Syncer{
+		db:         db,
+		dialect:    database.DialectOf(db),
+		cards:      repository.NewCardRepository(db),
+		statements: repository.NewStatementRepository(db),
+		client:     client,
+		cfg:        cfg,
+		clock:      clk,
+	}
+}
+
+// Enabled reports whether a YNAB budget is configured
+func (s *Syncer) Enabled() bool {
+	return s != nil && s.client != nil && s.cfg.Enabled()
+}
+
+// SchedulePayment creates or updates the YNAB scheduled transaction for a
+// statement's scheduled payment. Calling it again, e.g. after rescheduling,
+// updates the same transaction rather than adding another. Statements
+// without a scheduled date, without a YNAB account for their card, marked
+// paid or with nothing left to pay have their transaction removed instead.
+// Failures are recorded so RetryFailed can try again. Syncs of the same
+// statement run one at a time.
+func (s *Syncer) SchedulePayment(ctx context.Context, statementID int) error {
+	defer s.locks.lock(statementID)()
+
+	stmt, err := s.statements.Get(ctx, statementID)
+	if err != nil {
+		return fmt.Errorf("failed to get statement %d: %w", statementID, err)
+	}
+	card, err := s.cards.Get(ctx, stmt.CardID)
+	if err != nil {
+		return fmt.Errorf("failed to get card %d: %w", stmt.CardID, err)
+	}
+
+	amount := stmt.RemainingBalance
+	if card.HasAutopay() {
+		amount = card.AutopayPayment(stmt)
+	}
+	cardAccountID := s.cfg.Cards[card.ID]
+	if stmt.ScheduledPaymentDate == nil || cardAccountID == "" || amount <= 0 || stmt.Status == models.StatusPaid {
+		return s.remove(ctx, statementID)
+	}
+
+	id, syncErr := s.save(ctx, card, stmt, cardAccountID, amount)
+	if err := s.record(ctx, statementID, id, syncErr); err != nil {
+		return err
+	}
+	if syncErr != nil {
+		return fmt.Errorf("failed to sync statement %d to ynab: %w", statementID, syncErr)
+	}
+	return nil
+}
+
+// ScheduledTransactionID returns the ID of the YNAB scheduled transaction
+// saved for a statement, or "" if there isn't one. Read it before deleting
+// the statement, which takes its ynab_transactions row with it.
+func (s *Syncer) ScheduledTransactionID(ctx context.Context, statementID int) (string, error) {
+	return s.storedID(ctx, statementID)
+}
+
+// DeleteScheduledTransaction removes a scheduled transaction from YNAB. One
+// that is already gone counts as removed.
+func (s *Syncer) DeleteScheduledTransaction(ctx context.Context, id string) error {
+	err := s.client.DeleteScheduledTransaction(ctx, s.cfg.BudgetID, id)
+	var apiErr *APIError
+	if err != nil && (!errors.As(err, &apiErr) || !apiErr.NotFound()) {
+		return fmt.Errorf("failed to delete ynab scheduled transaction %s: %w", id, err)
+	}
+	return nil
+}
+
+// RetryFailed tries again to sync every statement whose last sync failed,
+// up to MaxAttempts times each. It returns how many are still failing.
+func (s *Syncer) RetryFailed(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`
+		SELECT statement_id FROM ynab_transactions
+		WHERE last_error <> '' AND attempts < ?
+		ORDER BY statement_id
+	`), MaxAttempts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query failed ynab syncs: %w", err)
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan failed ynab sync: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read failed ynab syncs: %w", err)
+	}
+
+	var failed int
+	for _, id := range ids {
+		if err := s.SchedulePayment(ctx, id); err != nil {
+			log.Printf("Error retrying ynab sync: %v", err)
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// save upserts the scheduled transaction and returns its YNAB ID. The
+// transaction saved last time is updated if it still exists; otherwise one
+// carrying the statement's memo marker is reused before a new one is
+// created, so a lost response never leaves a duplicate.
+func (s *Syncer) save(ctx context.Context, card models.CreditCard, stmt models.Statement, cardAccountID string, amount models.Money) (string, error) {
+	fromAccountID := s.cfg.PaymentAccountID
+	if stmt.FundingAccountID != nil && s.cfg.FundingAccounts[*stmt.FundingAccountID] != "" {
+		fromAccountID = s.cfg.FundingAccounts[*stmt.FundingAccountID]
+	}
+	if fromAccountID == "" {
+		return "", fmt.Errorf("no ynab account configured to pay %s from", card.Name)
+	}
+
+	cardAccount, err := s.client.GetAccount(ctx, s.cfg.BudgetID, cardAccountID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get ynab account for %s: %w", card.Name, err)
+	}
+
+	marker := memoMarker(stmt.ID)
+	tx := ScheduledTransaction{
+		AccountID: fromAccountID,
+		Date:      *stmt.ScheduledPaymentDate,
+		Frequency: FrequencyNever,
+		Amount:    -Milliunits(amount),
+		PayeeID:   cardAccount.TransferPayeeID,
+		Memo:      fmt.Sprintf("%s statement %s %s", card.Name, stmt.StatementDate, marker),
+	}
+
+	id, err := s.storedID(ctx, stmt.ID)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		existing, err := s.client.ListScheduledTransactions(ctx, s.cfg.BudgetID)
+		if err != nil {
+			return "", fmt.Errorf("failed to list ynab scheduled transactions: %w", err)
+		}
+		id = findMarked(existing, marker)
+	}
+
+	if id != "" {
+		saved, err := s.client.UpdateScheduledTransaction(ctx, s.cfg.BudgetID, id, tx)
+		if err == nil {
+			return saved.ID, nil
+		}
+		// The transaction was deleted in YNAB, so schedule a new one
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.NotFound() {
+			return id, fmt.Errorf("failed to update ynab scheduled transaction: %w", err)
+		}
+	}
+
+	saved, err := s.client.CreateScheduledTransaction(ctx, s.cfg.BudgetID, tx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create ynab scheduled transaction: %w", err)
+	}
+	return saved.ID, nil
+}
+
+// remove deletes the statement's scheduled transaction, if one was saved,
+// and stops tracking it. A failure keeps the ID so the removal is retried.
+func (s *Syncer) remove(ctx context.Context, statementID int) error {
+	id, err := s.storedID(ctx, statementID)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return s.clearError(ctx, statementID)
+	}
+
+	if syncErr := s.DeleteScheduledTransaction(ctx, id); syncErr != nil {
+		if err := s.record(ctx, statementID, id, syncErr); err != nil {
+			return err
+		}
+		return fmt.Errorf("failed to remove statement %d from ynab: %w", statementID, syncErr)
+	}
+	_, err = s.db.ExecContext(ctx, s.dialect.Rebind(
+		"DELETE FROM ynab_transactions WHERE statement_id = ?",
+	), statementID)
+	if err != nil {
+		return fmt.Errorf("failed to delete ynab transaction for statement %d: %w", statementID, err)
+	}
+	return nil
+}
+
+// storedID returns the YNAB ID last saved for a statement, or "" if there
+// isn't one
+func (s *Syncer) storedID(ctx context.Context, statementID int) (string, error) {
+	var id string
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(
+		"SELECT scheduled_transaction_id FROM ynab_transactions WHERE statement_id = ?",
+	), statementID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query ynab transaction for statement %d: %w", statementID, err)
+	}
+	return id, nil
+}
+
+// record saves the outcome of a sync. A success stores the YNAB ID and resets
+// the attempt count; a failure keeps the last known ID and counts the
+// attempt.
+func (s *Syncer) record(ctx context.Context, statementID int, id string, syncErr error) error {
+	now := s.clock.Now()
+
+	var err error
+	if syncErr == nil {
+		_, err = s.db.ExecContext(ctx, s.dialect.Rebind(`
+			INSERT INTO ynab_transactions (statement_id, scheduled_transaction_id, synced_at, last_error, attempts, updated_at)
+			VALUES (?, ?, ?, '', 0, ?)
+			ON CONFLICT(statement_id) DO UPDATE SET
+				scheduled_transaction_id = excluded.scheduled_transaction_id,
+				synced_at = excluded.synced_at, last_error = '', attempts = 0,
+				updated_at = excluded.updated_at
+		`), statementID, id, now, now)
+	} else {
+		_, err = s.db.ExecContext(ctx, s.dialect.Rebind(`
+			INSERT INTO ynab_transactions (statement_id, scheduled_transaction_id, last_error, attempts, updated_at)
+			VALUES (?, ?, ?, 1, ?)
+			ON CONFLICT(statement_id) DO UPDATE SET
+				last_error = excluded.last_error,
+				attempts = ynab_transactions.attempts + 1,
+				updated_at = excluded.updated_at
+		`), statementID, id, syncErr.Error(), now)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record ynab sync for statement %d: %w", statementID, err)
+	}
+	return nil
+}
+
+// clearError stops retrying a statement that no longer needs syncing
+func (s *Syncer) clearError(ctx context.Context, statementID int) error {
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(
+		"UPDATE ynab_transactions SET last_error = '', attempts = 0 WHERE statement_id = ?",
+	), statementID)
+	if err != nil {
+		return fmt.Errorf("failed to clear ynab sync error for statement %d: %w", statementID, err)
+	}
+	return nil
+}
+
+// statementLocks serializes the syncs of each statement. Two syncs of a
+// statement without a saved transaction would otherwise both miss it and
+// both create one.
+type statementLocks struct {
+	mu    sync.Mutex
+	locks map[int]*statementLock
+}
+
+type statementLock struct {
+	sync.Mutex
+	// users counts the syncs holding or waiting for the lock
+	users int
+}
+
+// lock blocks until no other sync holds the statement and returns the
+// function releasing it
+func (l *statementLocks) lock(statementID int) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[int]*statementLock{}
+	}
+	sl := l.locks[statementID]
+	if sl == nil {
+		sl = &statementLock{}
+		l.locks[statementID] = sl
+	}
+	sl.users++
+	l.mu.Unlock()
+
+	sl.Lock()
+	return func() {
+		sl.Unlock()
+		l.mu.Lock()
+		if sl.users--; sl.users == 0 {
+			delete(l.locks, statementID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Milliunits converts an amount to YNAB milliunits
+func Milliunits(m models.Money) int64 {
+	return m.Cents() * 10
+}
+
+// memoMarker tags the memo of a statement's scheduled transaction so it can
+// be found again if its ID was never recorded
+func memoMarker(statementID int) string {
+	return fmt.Sprintf("[cc-tracker:%d]", statementID)
+}
+
+// findMarked returns the ID of the live transaction carrying marker, or ""
+func findMarked(txs []ScheduledTransaction, marker string) string {
+	for _, tx := range txs {
+		if !tx.Deleted && strings.Contains(tx.Memo, marker) {
+			return tx.ID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,533 @@
+package ynab
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/clock"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/config"
+	"github.com/morey-tech/credit-card-payment-tracker/pkg/database"
+)
+
+const testBudget = "budget-1"
+
+// fakeYNAB serves the YNAB endpoints the syncer uses from memory
+type fakeYNAB struct {
+	mu       sync.Mutex
+	accounts map[string]Account
+	txs      map[string]ScheduledTransaction
+	nextID   int
+	status   int
+	creates  int
+}
+
+func newFakeYNAB() *fakeYNAB {
+	return &fakeYNAB{
+		accounts: map[string]Account{
+			"visa-account": {ID: "visa-account", Name: "Visa", TransferPayeeID: "payee-visa"},
+		},
+		txs: map[string]ScheduledTransaction{},
+	}
+}
+
+func (f *fakeYNAB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if f.status != 0 {
+		writeError(w, f.status, "service_unavailable")
+		return
+	}
+
+	prefix := "/budgets/" + testBudget + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeError(w, http.StatusNotFound, "resource_not_found")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch {
+	case parts[0] == "accounts" && len(parts) == 2 && r.Method == http.MethodGet:
+		account, ok := f.accounts[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "resource_not_found")
+			return
+		}
+		writeData(w, http.StatusOK, map[string]interface{}{"account": account})
+	case parts[0] == "scheduled_transactions" && len(parts) == 1 && r.Method == http.MethodGet:
+		txs := []ScheduledTransaction{}
+		for _, tx := range f.txs {
+			txs = append(txs, tx)
+		}
+		writeData(w, http.StatusOK, map[string]interface{}{"scheduled_transactions": txs})
+	case parts[0] == "scheduled_transactions" && len(parts) == 1 && r.Method == http.MethodPost:
+		tx, ok := decodeTransaction(w, r)
+		if !ok {
+			return
+		}
+		f.nextID++
+		f.creates++
+		tx.ID = fmt.Sprintf("tx-%d", f.nextID)
+		f.save(tx)
+		writeData(w, http.StatusCreated, map[string]interface{}{"scheduled_transaction": f.txs[tx.ID]})
+	case parts[0] == "scheduled_transactions" && len(parts) == 2 && r.Method == http.MethodPut:
+		existing, ok := f.txs[parts[1]]
+		if !ok || existing.Deleted {
+			writeError(w, http.StatusNotFound, "resource_not_found")
+			return
+		}
+		tx, ok := decodeTransaction(w, r)
+		if !ok {
+			return
+		}
+		tx.ID = parts[1]
+		f.save(tx)
+		writeData(w, http.StatusOK, map[string]interface{}{"scheduled_transaction": f.txs[tx.ID]})
+	case parts[0] == "scheduled_transactions" && len(parts) == 2 && r.Method == http.MethodDelete:
+		tx, ok := f.txs[parts[1]]
+		if !ok || tx.Deleted {
+			writeError(w, http.StatusNotFound, "resource_not_found")
+			return
+		}
+		tx.Deleted = true
+		f.txs[tx.ID] = tx
+		writeData(w, http.StatusOK, map[string]interface{}{"scheduled_transaction": tx})
+	default:
+		writeError(w, http.StatusNotFound, "resource_not_found")
+	}
+}
+
+// save stores tx the way YNAB returns it, with the date as date_first
+func (f *fakeYNAB) save(tx ScheduledTransaction) {
+	tx.DateFirst = tx.Date
+	tx.DateNext = tx.Date
+	tx.Date = ""
+	f.txs[tx.ID] = tx
+}
+
+func (f *fakeYNAB) Transactions() []ScheduledTransaction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var txs []ScheduledTransaction
+	for _, tx := range f.txs {
+		if !tx.Deleted {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+func (f *fakeYNAB) SetStatus(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+func decodeTransaction(w http.ResponseWriter, r *http.Request) (ScheduledTransaction, bool) {
+	var body struct {
+		ScheduledTransaction ScheduledTransaction `json:"scheduled_transaction"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ScheduledTransaction.Date == "" {
+		writeError(w, http.StatusBadRequest, "bad_request")
+		return ScheduledTransaction{}, false
+	}
+	return body.ScheduledTransaction, true
+}
+
+func writeData(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func writeError(w http.ResponseWriter, status int, name string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"id": fmt.Sprint(status), "name": name, "detail": name},
+	})
+}
+
+// setupSyncer creates a test database with one card mapped to a YNAB account
+// and a syncer talking to a fake YNAB
+func setupSyncer(t *testing.T) (*Syncer, *fakeYNAB, int, func()) {
+	tmpDB := "./test_ynab.db"
+	db, err := database.InitDB(tmpDB, clock.Real{})
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES ('Visa', '1234', 15, 25)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	cardID, _ := result.LastInsertId()
+
+	fake := newFakeYNAB()
+	server := httptest.NewServer(fake)
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+	cfg := config.YNABConfig{
+		Token:            "test-token",
+		BudgetID:         testBudget,
+		PaymentAccountID: "checking-account",
+		Cards:            map[int]string{int(cardID): "visa-account"},
+	}
+	now := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+	s := NewSyncer(db, client, cfg, clock.NewFixed(now))
+
+	return s, fake, int(cardID), func() {
+		server.Close()
+		db.Close()
+		os.Remove(tmpDB)
+	}
+}
+
+// insertScheduled adds a $250.00 statement with a payment scheduled on date
+func insertScheduled(t *testing.T, db *sql.DB, cardID int, date string) int {
+	result, err := db.Exec(`
+		INSERT INTO statements (card_id, statement_date, due_date, amount_cents, status, scheduled_payment_date)
+		VALUES (?, '2024-11-15', '2024-12-10', 25000, 'pending', ?)
+	`, cardID, date)
+	if err != nil {
+		t.Fatalf("Failed to insert statement: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+func reschedule(t *testing.T, db *sql.DB, statementID int, date string) {
+	if _, err := db.Exec("UPDATE statements SET scheduled_payment_date = ? WHERE id = ?", date, statementID); err != nil {
+		t.Fatalf("Failed to reschedule statement: %v", err)
+	}
+}
+
+func syncState(t *testing.T, db *sql.DB, statementID int) (id, lastError string, attempts int) {
+	err := db.QueryRow(`
+		SELECT scheduled_transaction_id, last_error, attempts FROM ynab_transactions WHERE statement_id = ?
+	`, statementID).Scan(&id, &lastError, &attempts)
+	if err != nil {
+		t.Fatalf("Failed to query ynab_transactions: %v", err)
+	}
+	return id, lastError, attempts
+}
+
+func TestSchedulePayment_CreatesTransfer(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("SchedulePayment failed: %v", err)
+	}
+
+	txs := fake.Transactions()
+	if len(txs) != 1 {
+		t.Fatalf("Expected 1 scheduled transaction, got %d", len(txs))
+	}
+	tx := txs[0]
+	if tx.AccountID != "checking-account" {
+		t.Errorf("Expected account checking-account, got %s", tx.AccountID)
+	}
+	if tx.PayeeID != "payee-visa" {
+		t.Errorf("Expected transfer payee payee-visa, got %s", tx.PayeeID)
+	}
+	if tx.Amount != -250000 {
+		t.Errorf("Expected amount -250000, got %d", tx.Amount)
+	}
+	if tx.DateFirst != "2024-12-05" {
+		t.Errorf("Expected date 2024-12-05, got %s", tx.DateFirst)
+	}
+	if tx.Frequency != FrequencyNever {
+		t.Errorf("Expected frequency never, got %s", tx.Frequency)
+	}
+
+	id, lastError, _ := syncState(t, s.db, stmtID)
+	if id != tx.ID || lastError != "" {
+		t.Errorf("Expected recorded ID %s with no error, got %q, %q", tx.ID, id, lastError)
+	}
+}
+
+func TestSchedulePayment_RescheduleUpdates(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("SchedulePayment failed: %v", err)
+	}
+	reschedule(t, s.db, stmtID, "2024-12-08")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("Second SchedulePayment failed: %v", err)
+	}
+
+	txs := fake.Transactions()
+	if len(txs) != 1 || fake.creates != 1 {
+		t.Fatalf("Expected the transaction to be updated, got %d transactions from %d creates", len(txs), fake.creates)
+	}
+	if txs[0].DateFirst != "2024-12-08" {
+		t.Errorf("Expected date 2024-12-08, got %s", txs[0].DateFirst)
+	}
+}
+
+func TestSchedulePayment_Concurrent(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	// Entering a statement and rescheduling it sync it at the same time
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.SchedulePayment(context.Background(), stmtID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("SchedulePayment failed: %v", err)
+		}
+	}
+
+	if txs := fake.Transactions(); len(txs) != 1 || fake.creates != 1 {
+		t.Errorf("Expected one transaction, got %d from %d creates", len(txs), fake.creates)
+	}
+	if s.locks.locks[stmtID] != nil {
+		t.Error("Expected the statement's lock to be released")
+	}
+}
+
+func TestSchedulePayment_FindsUnrecordedTransaction(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("SchedulePayment failed: %v", err)
+	}
+
+	// Lose the recorded ID, as if the response never arrived
+	if _, err := s.db.Exec("DELETE FROM ynab_transactions"); err != nil {
+		t.Fatalf("Failed to clear ynab_transactions: %v", err)
+	}
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("Second SchedulePayment failed: %v", err)
+	}
+
+	if fake.creates != 1 {
+		t.Errorf("Expected the marked transaction to be reused, got %d creates", fake.creates)
+	}
+}
+
+func TestSchedulePayment_RecreatesDeletedTransaction(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("SchedulePayment failed: %v", err)
+	}
+
+	fake.mu.Lock()
+	for id, tx := range fake.txs {
+		tx.Deleted = true
+		fake.txs[id] = tx
+	}
+	fake.mu.Unlock()
+
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("Second SchedulePayment failed: %v", err)
+	}
+	txs := fake.Transactions()
+	if len(txs) != 1 {
+		t.Fatalf("Expected 1 live transaction, got %d", len(txs))
+	}
+	if id, _, _ := syncState(t, s.db, stmtID); id != txs[0].ID {
+		t.Errorf("Expected recorded ID %s, got %s", txs[0].ID, id)
+	}
+}
+
+func TestSchedulePayment_SkipsUnmappedCard(t *testing.T) {
+	s, fake, _, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	result, err := s.db.Exec(`
+		INSERT INTO credit_cards (name, last_four, statement_day, days_until_due)
+		VALUES ('Amex', '0005', 20, 21)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert card: %v", err)
+	}
+	otherID, _ := result.LastInsertId()
+
+	stmtID := insertScheduled(t, s.db, int(otherID), "2024-12-05")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("SchedulePayment failed: %v", err)
+	}
+	if len(fake.Transactions()) != 0 {
+		t.Error("Expected no transaction for a card without a YNAB account")
+	}
+}
+
+func TestSchedulePayment_RemovesPaidStatement(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("SchedulePayment failed: %v", err)
+	}
+	if _, err := s.db.Exec("UPDATE statements SET status = 'paid' WHERE id = ?", stmtID); err != nil {
+		t.Fatalf("Failed to mark statement paid: %v", err)
+	}
+
+	// YNAB is down at first, so the removal is retried
+	fake.SetStatus(http.StatusServiceUnavailable)
+	if err := s.SchedulePayment(context.Background(), stmtID); err == nil {
+		t.Fatal("Expected the removal to fail while YNAB is down")
+	}
+	if id, lastError, _ := syncState(t, s.db, stmtID); id == "" || lastError == "" {
+		t.Errorf("Expected the failure to keep the ID, got %q, %q", id, lastError)
+	}
+
+	fake.SetStatus(0)
+	if failed, err := s.RetryFailed(context.Background()); err != nil || failed != 0 {
+		t.Fatalf("Expected the retry to succeed, got %d failures: %v", failed, err)
+	}
+	if len(fake.Transactions()) != 0 {
+		t.Error("Expected the scheduled transaction to be removed")
+	}
+	var count int
+	s.db.QueryRow("SELECT COUNT(*) FROM ynab_transactions WHERE statement_id = ?", stmtID).Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected the ynab_transactions row to be removed, got %d", count)
+	}
+}
+
+func TestSchedulePayment_RemovesFullyPaidStatement(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("SchedulePayment failed: %v", err)
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO payments (statement_id, amount_cents, payment_date) VALUES (?, 25000, '2024-11-20')
+	`, stmtID)
+	if err != nil {
+		t.Fatalf("Failed to insert payment: %v", err)
+	}
+
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("Second SchedulePayment failed: %v", err)
+	}
+	if len(fake.Transactions()) != 0 {
+		t.Error("Expected the scheduled transaction to be removed once nothing is left to pay")
+	}
+}
+
+func TestDeleteScheduledTransaction(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	if err := s.SchedulePayment(context.Background(), stmtID); err != nil {
+		t.Fatalf("SchedulePayment failed: %v", err)
+	}
+	id, err := s.ScheduledTransactionID(context.Background(), stmtID)
+	if err != nil || id == "" {
+		t.Fatalf("Expected the saved ID, got %q: %v", id, err)
+	}
+
+	if err := s.DeleteScheduledTransaction(context.Background(), id); err != nil {
+		t.Fatalf("DeleteScheduledTransaction failed: %v", err)
+	}
+	if len(fake.Transactions()) != 0 {
+		t.Error("Expected the scheduled transaction to be removed")
+	}
+	// A transaction that is already gone counts as removed
+	if err := s.DeleteScheduledTransaction(context.Background(), id); err != nil {
+		t.Errorf("Expected no error deleting a deleted transaction, got %v", err)
+	}
+}
+
+func TestRetryFailed(t *testing.T) {
+	s, fake, cardID, cleanup := setupSyncer(t)
+	defer cleanup()
+
+	stmtID := insertScheduled(t, s.db, cardID, "2024-12-05")
+	fake.SetStatus(http.StatusServiceUnavailable)
+	if err := s.SchedulePayment(context.Background(), stmtID); err == nil {
+		t.Fatal("Expected SchedulePayment to fail while YNAB is down")
+	}
+
+	_, lastError, attempts := syncState(t, s.db, stmtID)
+	if lastError == "" || attempts != 1 {
+		t.Errorf("Expected a recorded failure after 1 attempt, got %q after %d", lastError, attempts)
+	}
+
+	failed, err := s.RetryFailed(context.Background())
+	if err != nil {
+		t.Fatalf("RetryFailed failed: %v", err)
+	}
+	if failed != 1 {
+		t.Errorf("Expected 1 still failing, got %d", failed)
+	}
+	if _, _, attempts := syncState(t, s.db, stmtID); attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+
+	fake.SetStatus(0)
+	failed, err = s.RetryFailed(context.Background())
+	if err != nil {
+		t.Fatalf("RetryFailed failed: %v", err)
+	}
+	if failed != 0 {
+		t.Errorf("Expected no failures, got %d", failed)
+	}
+	if len(fake.Transactions()) != 1 {
+		t.Errorf("Expected the retry to create the transaction")
+	}
+	if _, lastError, attempts := syncState(t, s.db, stmtID); lastError != "" || attempts != 0 {
+		t.Errorf("Expected the failure to be cleared, got %q after %d attempts", lastError, attempts)
+	}
+
+	// Nothing is left to retry
+	fake.SetStatus(http.StatusServiceUnavailable)
+	if failed, _ := s.RetryFailed(context.Background()); failed != 0 {
+		t.Errorf("Expected nothing to retry, got %d failures", failed)
+	}
+}
+
+func TestSyncer_Enabled(t *testing.T) {
+	var s *Syncer
+	if s.Enabled() {
+		t.Error("Expected a nil syncer to be disabled")
+	}
+	s = &Syncer{client: NewClient(""), cfg: config.YNABConfig{Token: "t"}}
+	if s.Enabled() {
+		t.Error("Expected a syncer without a budget to be disabled")
+	}
+}
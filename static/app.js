@@ -2,8 +2,7 @@
 const API_BASE = '/api/v1';
 
 // State
-let cardsData = [];
-let statementsData = [];
+let dashboardData = null;
 
 // DOM Elements
 const upcomingStatementsList = document.getElementById('upcoming-statements-list');
@@ -34,48 +33,23 @@ function formatCurrency(amount) {
     return `$${parseFloat(amount).toFixed(2)}`;
 }
 
-function getNextStatementDate(card) {
-    const today = new Date();
-    const currentMonth = today.getMonth();
-    const currentYear = today.getFullYear();
-
-    let nextStatementDate = new Date(currentYear, currentMonth, card.statement_day);
-
-    if (nextStatementDate < today) {
-        nextStatementDate = new Date(currentYear, currentMonth + 1, card.statement_day);
-    }
-
-    return nextStatementDate;
-}
-
-function calculateRecommendedPaymentDate(dueDate) {
-    const date = new Date(dueDate);
-    date.setDate(date.getDate() - 7);
-    return date;
-}
-
 // API Functions
-async function fetchCards() {
+// fetchDashboard loads the dashboard with every date computed by the server
+async function fetchDashboard() {
     try {
-        const response = await fetch(`${API_BASE}/cards`);
-        if (!response.ok) throw new Error('Failed to fetch cards');
-        cardsData = await response.json();
-        return cardsData;
+        const response = await fetch(`${API_BASE}/dashboard`);
+        if (!response.ok) throw new Error('Failed to fetch dashboard');
+        dashboardData = await response.json();
+        return dashboardData;
     } catch (error) {
-        console.error('Error fetching cards:', error);
-        return [];
-    }
-}
-
-async function fetchStatements() {
-    try {
-        const response = await fetch(`${API_BASE}/statements`);
-        if (!response.ok) throw new Error('Failed to fetch statements');
-        statementsData = await response.json();
-        return statementsData;
-    } catch (error) {
-        console.error('Error fetching statements:', error);
-        return [];
+        console.error('Error fetching dashboard:', error);
+        return {
+            upcoming_statements: [],
+            statements_needed: [],
+            missing_amounts: [],
+            unscheduled_payments: [],
+            scheduled_payments: []
+        };
     }
 }
 
@@ -90,7 +64,7 @@ async function createStatement(cardId, statementDate, dueDate, amount) {
                 card_id: cardId,
                 statement_date: statementDate,
                 due_date: dueDate,
-                amount: parseFloat(amount),
+                amount: String(amount),
                 status: 'pending'
             })
         });
@@ -108,128 +82,110 @@ async function createStatement(cardId, statementDate, dueDate, amount) {
 }
 
 // UI Rendering Functions
-function renderUpcomingStatements(cards) {
+function renderUpcomingStatements(upcoming) {
     upcomingStatementsList.innerHTML = '';
 
-    if (cards.length === 0) {
+    if (upcoming.length === 0) {
         upcomingStatementsList.innerHTML = '<li class="text-sm text-secondary">No cards found</li>';
         return;
     }
 
-    const upcomingCards = cards
-        .map(card => ({
-            ...card,
-            nextStatement: getNextStatementDate(card)
-        }))
-        .sort((a, b) => a.nextStatement - b.nextStatement)
-        .slice(0, 5);
-
-    upcomingCards.forEach(card => {
+    // The server sorts upcoming statements soonest first
+    upcoming.slice(0, 5).forEach(item => {
         const li = document.createElement('li');
         li.className = 'status-list-item';
         li.innerHTML = `
-            <span>${card.name}</span>
-            <span class="text-secondary">${formatDate(card.nextStatement)}</span>
+            <span>${item.card_name}</span>
+            <span class="text-secondary">${formatDate(item.statement_date)}</span>
         `;
         upcomingStatementsList.appendChild(li);
     });
 }
 
-function renderActionRequired(cards, statements) {
+function renderActionRequired(statementsNeeded, missingAmounts) {
     actionItemsContainer.innerHTML = '';
 
-    // Find cards that need statement data entry
-    const cardsNeedingData = cards.filter(card => {
-        // Check if there's a recent statement (within last 32 days)
-        const today = new Date();
-        const recentStatements = statements.filter(stmt => {
-            if (stmt.card_id !== card.id) return false;
-            const stmtDate = new Date(stmt.statement_date);
-            const daysDiff = (today - stmtDate) / (1000 * 60 * 60 * 24);
-            return daysDiff <= 32;
-        });
-        return recentStatements.length === 0;
-    });
-
-    if (cardsNeedingData.length === 0) {
+    if (statementsNeeded.length === 0 && missingAmounts.length === 0) {
         actionItemsContainer.innerHTML = '<span class="text-sm text-secondary">All caught up!</span>';
         actionRequiredCard.classList.remove('border-primary');
         actionRequiredCard.classList.add('border-gray');
     } else {
-        cardsNeedingData.forEach(card => {
+        statementsNeeded.forEach(item => {
             const div = document.createElement('div');
             div.className = 'status-list-item mb-2';
-            div.id = `action-card-${card.id}`;
+            div.id = `action-card-${item.card_id}`;
             div.innerHTML = `
-                <span class="font-medium">${card.name}</span>
-                <button onclick="openModal(${card.id}, '${card.name}')" class="btn btn-primary btn-sm">
+                <span class="font-medium">${item.card_name}</span>
+                <button onclick="openModal(${item.card_id}, '${item.card_name}', '${item.expected_statement_date}', '${item.expected_due_date}')" class="btn btn-primary btn-sm">
                     Enter Statement Data
                 </button>
             `;
             actionItemsContainer.appendChild(div);
         });
+
+        // Entering a statement for a placeholder fills it in on the server
+        missingAmounts.forEach(item => {
+            const div = document.createElement('div');
+            div.className = 'status-list-item mb-2';
+            div.id = `missing-amount-${item.statement_id}`;
+            div.innerHTML = `
+                <span class="font-medium">${item.card_name} <span class="text-secondary">${formatDate(item.statement_date)}</span></span>
+                <button onclick="openModal(${item.card_id}, '${item.card_name}', '${item.statement_date}', '${item.due_date}')" class="btn btn-primary btn-sm">
+                    Enter Missing Amount
+                </button>
+            `;
+            actionItemsContainer.appendChild(div);
+        });
     }
 }
 
-function renderPendingPayments(cards, statements) {
+function renderPendingPayments(scheduledPayments) {
     pendingPaymentsList.innerHTML = '';
 
-    // Only show statements that have been reviewed and have a scheduled payment date
-    const scheduledStatements = statements
-        .filter(stmt => stmt.status === 'pending' && stmt.scheduled_payment_date)
-        .sort((a, b) => new Date(a.scheduled_payment_date) - new Date(b.scheduled_payment_date));
-
-    if (scheduledStatements.length === 0) {
+    if (scheduledPayments.length === 0) {
         pendingPaymentsList.innerHTML = '<li class="text-sm text-secondary">No payments scheduled.</li>';
         return;
     }
 
-    scheduledStatements.forEach(stmt => {
-        const card = cards.find(c => c.id === stmt.card_id);
-        if (!card) return;
-
+    scheduledPayments.forEach(payment => {
         const li = document.createElement('li');
         li.className = 'status-list-item';
         li.style.display = 'flex';
         li.style.justifyContent = 'space-between';
         li.style.gap = '1rem';
         li.innerHTML = `
-            <span style="flex: 1; min-width: 0;">${card.name}</span>
-            <span class="font-medium text-white" style="flex-shrink: 0; width: 100px; text-align: left;">${formatCurrency(stmt.amount)}</span>
-            <span class="text-secondary" style="flex-shrink: 0; width: 80px; text-align: right;">${formatDate(stmt.scheduled_payment_date)}</span>
+            <span style="flex: 1; min-width: 0;">${payment.card_name}</span>
+            <span class="font-medium text-white" style="flex-shrink: 0; width: 100px; text-align: left;">${formatCurrency(payment.remaining_balance)}</span>
+            <span class="text-secondary" style="flex-shrink: 0; width: 80px; text-align: right;">${formatDate(payment.scheduled_payment_date)}</span>
         `;
+
+        // Flag payments scheduled later than the card's policy recommends
+        if (payment.warnings && payment.warnings.length > 0) {
+            li.title = payment.warnings.join('\n');
+            li.querySelector('span').textContent = `⚠ ${payment.card_name}`;
+        }
         pendingPaymentsList.appendChild(li);
     });
 }
 
-function renderPendingPaymentCards(cards, statements) {
+function renderPendingPaymentCards(unscheduledPayments) {
     pendingPaymentCardsContainer.innerHTML = '';
 
-    // Only show statements that have NOT been scheduled yet (no scheduled_payment_date)
-    const unscheduledStatements = statements
-        .filter(stmt => stmt.status === 'pending' && !stmt.scheduled_payment_date)
-        .sort((a, b) => new Date(a.due_date) - new Date(b.due_date));
-
-    if (unscheduledStatements.length === 0) {
+    if (unscheduledPayments.length === 0) {
         pendingPaymentCardsContainer.innerHTML = '<div style="padding: 2rem; text-align: center; color: #6b7280;">All payments have been scheduled!</div>';
         return;
     }
 
-    unscheduledStatements.forEach(stmt => {
-        const card = cards.find(c => c.id === stmt.card_id);
-        if (!card) return;
-
-        const recommendedDate = calculateRecommendedPaymentDate(stmt.due_date);
-
+    unscheduledPayments.forEach(payment => {
         const section = document.createElement('section');
         section.className = 'card-detail-section';
-        section.setAttribute('data-statement-id', stmt.id);
+        section.setAttribute('data-statement-id', payment.statement_id);
 
         // Build the header with Record Payment button
         let headerHTML = `
             <div class="card-detail-header" style="display: flex; justify-content: space-between; align-items: center;">
-                <h2 class="card-detail-title">${card.name}</h2>
-                <button onclick="openScheduleModal(${stmt.id}, '${card.name}', '${stmt.due_date}')" class="btn btn-primary">
+                <h2 class="card-detail-title">${payment.card_name}</h2>
+                <button onclick="openScheduleModal(${payment.statement_id}, '${payment.card_name}', '${payment.due_date}', '${payment.recommended_payment_date}')" class="btn btn-primary">
                     Record Payment
                 </button>
             </div>
@@ -242,7 +198,7 @@ function renderPendingPaymentCards(cards, statements) {
         gridHTML += `
             <div class="card-detail-item">
                 <span class="card-detail-label">Statement Amount</span>
-                <span class="card-detail-value">${formatCurrency(stmt.amount)}</span>
+                <span class="card-detail-value">${formatCurrency(payment.amount)}</span>
             </div>
         `;
 
@@ -250,7 +206,7 @@ function renderPendingPaymentCards(cards, statements) {
         gridHTML += `
             <div class="card-detail-item">
                 <span class="card-detail-label">Statement Date</span>
-                <span class="card-detail-value">${formatDate(stmt.statement_date)}</span>
+                <span class="card-detail-value">${formatDate(payment.statement_date)}</span>
             </div>
         `;
 
@@ -258,7 +214,7 @@ function renderPendingPaymentCards(cards, statements) {
         gridHTML += `
             <div class="card-detail-item">
                 <span class="card-detail-label">Official Due Date</span>
-                <span class="card-detail-value">${formatDate(stmt.due_date)}</span>
+                <span class="card-detail-value">${formatDate(payment.due_date)}</span>
             </div>
         `;
 
@@ -266,30 +222,34 @@ function renderPendingPaymentCards(cards, statements) {
         gridHTML += `
             <div class="card-detail-item highlighted">
                 <span class="card-detail-label success">Recommended Payment Date</span>
-                <span class="card-detail-value large">${formatDate(recommendedDate)}</span>
+                <span class="card-detail-value large">${formatDate(payment.recommended_payment_date)}</span>
             </div>
         `;
 
         gridHTML += `</div>`;
 
+        // Explain why the date was moved off a weekend or holiday
+        if (payment.recommendation && payment.recommendation.adjusted) {
+            gridHTML += `<p class="text-secondary" style="margin-top: 0.75rem; font-size: 0.875rem;">${payment.recommendation.explanation}</p>`;
+        }
+
         section.innerHTML = headerHTML + gridHTML;
         pendingPaymentCardsContainer.appendChild(section);
     });
 }
 
 // Modal Functions
-function openModal(cardId, cardName) {
+function openModal(cardId, cardName, expectedStatementDate, expectedDueDate) {
     modal.classList.remove('hidden');
     modalCardName.textContent = cardName;
     cardIdInput.value = cardId;
 
-    // Set default statement date to today
-    const today = new Date().toISOString().split('T')[0];
-    statementDateInput.value = today;
+    // Default to the dates the server expects for this statement
+    statementDateInput.value = expectedStatementDate || '';
+    officialDueDateInput.value = expectedDueDate || '';
 
     // Reset form fields
     statementAmountInput.value = '';
-    officialDueDateInput.value = '';
 }
 
 function closeModal() {
@@ -334,15 +294,12 @@ modal.addEventListener('click', function(event) {
 
 // Initialize
 async function loadData() {
-    const [cards, statements] = await Promise.all([
-        fetchCards(),
-        fetchStatements()
-    ]);
-
-    renderUpcomingStatements(cards);
-    renderActionRequired(cards, statements);
-    renderPendingPayments(cards, statements);
-    renderPendingPaymentCards(cards, statements);
+    const dashboard = await fetchDashboard();
+
+    renderUpcomingStatements(dashboard.upcoming_statements);
+    renderActionRequired(dashboard.statements_needed, dashboard.missing_amounts || []);
+    renderPendingPayments(dashboard.scheduled_payments);
+    renderPendingPaymentCards(dashboard.unscheduled_payments);
 }
 
 // Schedule Payment Modal Elements
@@ -353,19 +310,22 @@ const scheduleDueDate = document.getElementById('scheduleDueDate');
 const scheduleCardName = document.getElementById('schedule-card-name');
 const scheduleOfficialDueDate = document.getElementById('schedule-official-due-date');
 const scheduledPaymentDateInput = document.getElementById('scheduled-payment-date');
+const scheduleRecommendation = document.getElementById('schedule-recommendation');
 
 // Schedule Payment Functions
-function openScheduleModal(statementId, cardName, dueDate) {
+function openScheduleModal(statementId, cardName, dueDate, recommendedDate) {
     scheduleModal.classList.remove('hidden');
     scheduleStatementId.value = statementId;
     scheduleDueDate.value = dueDate;
     scheduleCardName.textContent = cardName;
     scheduleOfficialDueDate.textContent = formatDate(dueDate);
 
-    // Calculate and set recommended payment date (7 days before due date)
-    const recommendedDate = calculateRecommendedPaymentDate(dueDate);
-    const recommendedDateStr = recommendedDate.toISOString().split('T')[0];
-    scheduledPaymentDateInput.value = recommendedDateStr;
+    // Default to the recommended payment date computed by the server
+    scheduledPaymentDateInput.value = recommendedDate;
+
+    // Explain how the card's payment policy picked the date
+    const payment = (dashboardData?.unscheduled_payments || []).find(p => p.statement_id === statementId);
+    scheduleRecommendation.textContent = payment?.recommendation?.explanation || '';
 }
 
 function closeScheduleModal() {
@@ -409,11 +369,16 @@ scheduleForm.addEventListener('submit', async function(event) {
     }
 
     try {
-        await schedulePayment(statementId, scheduledDate);
+        const result = await schedulePayment(statementId, scheduledDate);
 
         // Close modal first
         closeScheduleModal();
 
+        // The payment is scheduled either way, but say if it may be late
+        if (result.warnings && result.warnings.length > 0) {
+            alert(result.warnings.join('\n'));
+        }
+
         // Find the card section to animate
         const cardSection = document.querySelector(`[data-statement-id="${statementId}"]`);
 
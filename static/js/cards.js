@@ -108,6 +108,39 @@ function formatOrdinal(day) {
     return day + "th";
 }
 
+function formatStatementDay(card) {
+    switch (card.statement_day_rule) {
+        case 'last_day':
+            return 'Last day';
+        case 'business_day':
+            return `${formatOrdinal(card.statement_day)} business day`;
+        default:
+            return formatOrdinal(card.statement_day);
+    }
+}
+
+// exampleStatementDate returns the card's statement date in the given month,
+// following the same rules as the server
+function exampleStatementDate(card, year, month) {
+    const lastDay = new Date(Date.UTC(year, month + 1, 0)).getUTCDate();
+    if (card.statement_day_rule === 'last_day') {
+        return new Date(Date.UTC(year, month, lastDay));
+    }
+    if (card.statement_day_rule === 'business_day') {
+        let found = null;
+        let n = card.statement_day;
+        for (let day = 1; day <= lastDay && n > 0; day++) {
+            const date = new Date(Date.UTC(year, month, day));
+            if (date.getUTCDay() !== 0 && date.getUTCDay() !== 6) {
+                found = date;
+                n--;
+            }
+        }
+        return found;
+    }
+    return new Date(Date.UTC(year, month, Math.min(card.statement_day, lastDay)));
+}
+
 function formatLastFour(digits) {
     return `•••• ${digits}`;
 }
@@ -168,7 +201,7 @@ function renderCardsTable() {
                 <div class="font-mono text-gray-light">${formatLastFour(card.last_four)}</div>
             </td>
             <td>
-                <div class="text-gray-light">${formatOrdinal(card.statement_day)}</div>
+                <div class="text-gray-light">${formatStatementDay(card)}</div>
             </td>
             <td>
                 <div class="text-gray-light">${card.days_until_due} days</div>
@@ -240,24 +273,21 @@ function openEditCardModal(cardId) {
     document.getElementById('card-name').value = card.name;
     document.getElementById('last-four').value = card.last_four;
 
-    // For editing, we need to construct example dates based on statement_day and days_until_due
-    // Use current month as example
+    // For editing, we need to construct example dates based on the statement
+    // day rule and days_until_due. Use current month as example
     const today = new Date();
-    const year = today.getFullYear();
-    const month = String(today.getMonth() + 1).padStart(2, '0');
-    const day = String(card.statement_day).padStart(2, '0');
-    document.getElementById('statement-date-input').value = `${year}-${month}-${day}`;
+    const statementDate = exampleStatementDate(card, today.getFullYear(), today.getMonth());
+    document.getElementById('statement-date-input').value = statementDate.toISOString().split('T')[0];
+    document.getElementById('statement-day-rule').value = card.statement_day_rule || 'fixed';
 
-    // Calculate due date from statement day and days_until_due
-    const statementDate = new Date(`${year}-${month}-${day}`);
+    // Calculate due date from statement date and days_until_due
     const dueDate = new Date(statementDate);
-    dueDate.setDate(dueDate.getDate() + card.days_until_due);
-    const dueYear = dueDate.getFullYear();
-    const dueMonth = String(dueDate.getMonth() + 1).padStart(2, '0');
-    const dueDay = String(dueDate.getDate()).padStart(2, '0');
-    document.getElementById('due-date-input').value = `${dueYear}-${dueMonth}-${dueDay}`;
+    dueDate.setUTCDate(dueDate.getUTCDate() + card.days_until_due);
+    document.getElementById('due-date-input').value = dueDate.toISOString().split('T')[0];
 
     document.getElementById('credit-limit').value = card.credit_limit || '';
+    document.getElementById('payment-policy').value = card.payment_policy || 'lead_time';
+    document.getElementById('payment-lead-days').value = card.payment_lead_days || '';
 
     // Clear errors
     clearFormErrors();
@@ -384,11 +414,18 @@ async function handleCardFormSubmit(event) {
             last_four: document.getElementById('last-four').value.trim(),
             statement_date: document.getElementById('statement-date-input').value,
             due_date: document.getElementById('due-date-input').value,
+            statement_day_rule: document.getElementById('statement-day-rule').value,
+            payment_policy: document.getElementById('payment-policy').value,
         };
 
+        const leadDays = parseInt(document.getElementById('payment-lead-days').value);
+        if (leadDays) {
+            cardData.payment_lead_days = leadDays;
+        }
+
         const creditLimit = document.getElementById('credit-limit').value;
         if (creditLimit) {
-            cardData.credit_limit = parseFloat(creditLimit);
+            cardData.credit_limit = String(creditLimit);
         }
 
         if (currentEditingCardId) {